@@ -1,52 +1,178 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/huh"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
+	"github.com/benwilkes9/ralph-cli/internal/agent"
 	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/debuglog"
 	"github.com/benwilkes9/ralph-cli/internal/docker"
+	"github.com/benwilkes9/ralph-cli/internal/doctor"
+	"github.com/benwilkes9/ralph-cli/internal/events"
+	"github.com/benwilkes9/ralph-cli/internal/ghstatus"
 	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/guard"
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	logfile "github.com/benwilkes9/ralph-cli/internal/log"
+	"github.com/benwilkes9/ralph-cli/internal/logview"
 	"github.com/benwilkes9/ralph-cli/internal/loop"
+	"github.com/benwilkes9/ralph-cli/internal/milestone"
+	"github.com/benwilkes9/ralph-cli/internal/notify"
+	"github.com/benwilkes9/ralph-cli/internal/owners"
+	"github.com/benwilkes9/ralph-cli/internal/planreview"
+	"github.com/benwilkes9/ralph-cli/internal/preflight"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/progress"
+	"github.com/benwilkes9/ralph-cli/internal/report"
 	"github.com/benwilkes9/ralph-cli/internal/scaffold"
+	"github.com/benwilkes9/ralph-cli/internal/schedule"
+	"github.com/benwilkes9/ralph-cli/internal/share"
+	"github.com/benwilkes9/ralph-cli/internal/snapshot"
+	"github.com/benwilkes9/ralph-cli/internal/specs"
 	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/statedb"
 	"github.com/benwilkes9/ralph-cli/internal/status"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/swarm"
+	"github.com/benwilkes9/ralph-cli/internal/tokens"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
+	"github.com/benwilkes9/ralph-cli/internal/vcs"
 )
 
 var version = "dev"
 
 func main() {
+	theme := ui.DefaultTheme()
+	root := rootCmd(realOrchestrator{})
+
+	if err := root.Execute(); err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			fmt.Fprintln(os.Stderr, exitErr.msg)
+			os.Exit(exitErr.code)
+		}
+		fmt.Fprintln(os.Stderr, theme.FormatError(err.Error()))
+		os.Exit(1)
+	}
+}
+
+// rootCmd assembles the ralph command tree.
+func rootCmd(orch Orchestrator) *cobra.Command {
 	root := &cobra.Command{
 		Use:           "ralph",
 		Short:         "Autonomous plan/build iteration using Claude Code",
 		Version:       version,
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := enableDebug(cmd); err != nil {
+				return err
+			}
+			if cmd.Hidden {
+				// _loop and _guard run once per iteration and tool call; the
+				// host has already resolved the locale into RALPH_LOCALE.
+				i18n.Set(i18n.Resolve("", os.Getenv))
+				return nil
+			}
+			i18n.Set(selectLocale(cmd.Context()))
+			return nil
+		},
 	}
+	root.PersistentFlags().Bool("debug", false, "log ralph's own decisions (commands run, docker arguments, preflight choices) to stderr; also RALPH_DEBUG=1")
+	root.PersistentFlags().String("debug-file", "", "also append the debug log to this file; implies --debug (also RALPH_DEBUG_FILE)")
 
-	theme := ui.DefaultTheme()
-	orch := realOrchestrator{}
 	root.AddCommand(initCmd())
+	root.AddCommand(doctorCmd())
 	root.AddCommand(planCmd(orch))
 	root.AddCommand(buildCmd(orch))
+	root.AddCommand(resumeCmd(orch))
+	root.AddCommand(replayCmd(orch))
+	root.AddCommand(swarmCmd())
+	root.AddCommand(syncCmd())
+	root.AddCommand(snapshotCmd())
+	root.AddCommand(abortCmd(docker.RunningContainers, docker.Abort))
 	root.AddCommand(statusCmd())
+	root.AddCommand(stateCmd())
+	root.AddCommand(configCmd())
+	root.AddCommand(logsCmd())
+	root.AddCommand(overviewCmd())
+	root.AddCommand(scheduleCmd())
+	root.AddCommand(compareCmd())
+	root.AddCommand(reportCmd())
+	root.AddCommand(lspProgressCmd())
+	root.AddCommand(scratchCmd(docker.RemoveScratch))
+	root.AddCommand(specCmd())
 	root.AddCommand(loopCmd())
+	root.AddCommand(guardCmd())
+	return root
+}
 
-	if err := root.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, theme.FormatError(err.Error()))
-		os.Exit(1)
+// enableDebug turns on debug logging when --debug, --debug-file or their
+// environment variables ask for it.
+func enableDebug(cmd *cobra.Command) error {
+	on, file := debuglog.FromEnv(os.Getenv)
+	flagOn, err := cmd.Flags().GetBool("debug")
+	if err != nil {
+		return fmt.Errorf("reading --debug flag: %w", err)
+	}
+	flagFile, err := cmd.Flags().GetString("debug-file")
+	if err != nil {
+		return fmt.Errorf("reading --debug-file flag: %w", err)
+	}
+	if flagFile != "" {
+		file = flagFile
+	}
+	if !on && !flagOn && file == "" {
+		return nil
 	}
+	if err := debuglog.Enable(cmd.ErrOrStderr(), file); err != nil {
+		return err //nolint:wrapcheck // already says what failed
+	}
+	slog.DebugContext(cmd.Context(), "ralph", "version", version, "command", cmd.CommandPath(), "args", os.Args[1:])
+	return nil
+}
+
+// selectLocale returns the locale ralph's output is shown in: the config's
+// locale when the current repo has one, otherwise the environment's.
+func selectLocale(ctx context.Context) string {
+	var configured string
+	if repoRoot, err := git.RepoRoot(ctx); err == nil {
+		if cfg, err := config.Load(repoRoot); err == nil {
+			configured = cfg.Locale
+		}
+	}
+	return i18n.Resolve(configured, os.Getenv)
+}
+
+// exitError carries a specific process exit code and an unstyled message,
+// for commands whose output is consumed by other programs.
+type exitError struct {
+	code int
+	msg  string
 }
 
+func (e *exitError) Error() string { return e.msg }
+
 func initCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "init",
@@ -58,13 +184,52 @@ func initCmd() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("reading --force flag: %w", err)
 			}
+			ci, err := cmd.Flags().GetString("ci")
+			if err != nil {
+				return fmt.Errorf("reading --ci flag: %w", err)
+			}
+			if ci != "" && !scaffold.ValidCI(ci) {
+				return fmt.Errorf("--ci must be %q, got %q", scaffold.CIGitHub, ci)
+			}
+			lang, err := cmd.Flags().GetString("language")
+			if err != nil {
+				return fmt.Errorf("reading --language flag: %w", err)
+			}
+			pm, err := cmd.Flags().GetString("package-manager")
+			if err != nil {
+				return fmt.Errorf("reading --package-manager flag: %w", err)
+			}
+			agentName, err := cmd.Flags().GetString("agent")
+			if err != nil {
+				return fmt.Errorf("reading --agent flag: %w", err)
+			}
+			if !agent.Valid(agentName) {
+				return fmt.Errorf("--agent must be %q, %q, %q or %q, got %q", agent.NameClaude, agent.NameCodex, agent.NameGemini, agent.NameAider, agentName)
+			}
+			yes, err := cmd.Flags().GetBool("yes")
+			if err != nil {
+				return fmt.Errorf("reading --yes flag: %w", err)
+			}
+			runCmd, err := cmd.Flags().GetString("run-cmd")
+			if err != nil {
+				return fmt.Errorf("reading --run-cmd flag: %w", err)
+			}
+			goal, err := cmd.Flags().GetString("goal")
+			if err != nil {
+				return fmt.Errorf("reading --goal flag: %w", err)
+			}
+			specsDir, err := cmd.Flags().GetString("specs-dir")
+			if err != nil {
+				return fmt.Errorf("reading --specs-dir flag: %w", err)
+			}
 
-			repoRoot, err := git.RepoRoot()
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
 			if err != nil {
 				return fmt.Errorf("finding repo root: %w", err)
 			}
 
-			branch, err := git.Branch()
+			branch, err := git.Branch(ctx)
 			if err != nil {
 				return fmt.Errorf("getting current branch: %w", err)
 			}
@@ -77,27 +242,77 @@ func initCmd() *cobra.Command {
 			fmt.Fprintln(w)                 //nolint:errcheck // display-only
 
 			info := scaffold.Detect(repoRoot)
+			if err := scaffold.Override(repoRoot, info, scaffold.Language(lang), scaffold.PackageManager(pm)); err != nil {
+				return fmt.Errorf("overriding detected language: %w", err)
+			}
+			if scaffold.LoadPreviousAnswers(repoRoot, info) {
+				fmt.Fprintf(w, "%s\n\n", theme.Muted.Render(i18n.T("Previous answers from .ralph/config.yaml are preselected."))) //nolint:errcheck // display-only
+			}
+			if agentName != "" {
+				info.Agent = agentName
+			}
+			scaffold.Answer(info, runCmd, goal, specsDir, branch)
 
-			_, isTerminal := cmd.InOrStdin().(*os.File)
-			if err := scaffold.RunPrompts(info, &scaffold.PromptOptions{
+			// Piped or redirected input is a file too; only a terminal gets
+			// the interactive prompts.
+			f, isFile := cmd.InOrStdin().(*os.File)
+			isTerminal := isFile && term.IsTerminal(int(f.Fd())) //nolint:gosec // file descriptors fit in an int
+			promptOpts := &scaffold.PromptOptions{
 				In:         cmd.InOrStdin(),
 				Out:        w,
 				Accessible: !isTerminal,
 				Branch:     branch,
-			}); err != nil {
-				return fmt.Errorf("running prompts: %w", err)
+			}
+			if yes {
+				if err := scaffold.AcceptDefaults(info); err != nil {
+					return fmt.Errorf("accepting defaults: %w", err)
+				}
+			} else {
+				scaffold.PrintDetection(w, info, theme)
+				if err := scaffold.ConfirmDetection(repoRoot, info, promptOpts); err != nil {
+					return fmt.Errorf("confirming detected settings: %w", err)
+				}
+				if err := scaffold.RunPrompts(info, promptOpts); err != nil {
+					return fmt.Errorf("running prompts: %w", err)
+				}
+			}
+			// Only offer the workflow interactively; scripted runs opt in with --ci.
+			if ci == "" && isTerminal && !yes {
+				want, err := scaffold.AskCI(promptOpts)
+				if err != nil {
+					return fmt.Errorf("running prompts: %w", err)
+				}
+				if want {
+					ci = scaffold.CIGitHub
+				}
 			}
 
 			result, err := scaffold.Generate(repoRoot, branch, info, force)
 			if err != nil {
 				return fmt.Errorf("generating scaffold: %w", err)
 			}
+			if ci != "" {
+				if err := scaffold.GenerateCI(repoRoot, ci, info, force, result); err != nil {
+					return fmt.Errorf("generating CI workflow: %w", err)
+				}
+			}
 
 			scaffold.PrintSummary(w, result, theme)
+			if ci != "" {
+				scaffold.PrintCISecrets(w, theme)
+			}
 			return nil
 		},
 	}
 	cmd.Flags().Bool("force", false, "Overwrite existing scaffold files")
+	cmd.Flags().String("ci", "", `Also generate a CI workflow that runs ralph build ("github")`)
+	cmd.Flags().String("language", "", "Override the detected language: python, node, go, rust or java")
+	cmd.Flags().String("package-manager", "", "Override the detected package manager: uv, poetry, npm, yarn, pnpm, go, cargo, maven or gradle")
+	cmd.Flags().String("agent", "", "Coding agent to run the loop with: claude (default), codex, gemini or aider")
+	cmd.Flags().BoolP("yes", "y", false, "Don't prompt: take --run-cmd, --goal and --specs-dir, then previous answers, then the defaults")
+	cmd.Flags().String("run-cmd", "", "Command that runs the project (preselected in the prompt)")
+	cmd.Flags().String("goal", "", "One-sentence project goal (preselected in the prompt)")
+	cmd.Flags().String("specs-dir", "", `Specs directory: "specs" or "docs/specs" get the branch appended; any other path is used as-is`)
 	return cmd
 }
 
@@ -117,13 +332,65 @@ func validateRelativePath(flag, path string) error {
 // Orchestrator abstracts the docker plan/build workflow so planCmd and buildCmd
 // can be tested without a real Docker daemon.
 type Orchestrator interface {
-	BuildAndRun(w io.Writer, theme *ui.Theme, mode string, maxIter int, branch, planFile, specsDir string) error
+	BuildAndRun(ctx context.Context, w io.Writer, theme *ui.Theme, launch *docker.LaunchOptions) error
 }
 
 type realOrchestrator struct{}
 
-func (r realOrchestrator) BuildAndRun(w io.Writer, theme *ui.Theme, mode string, maxIter int, branch, planFile, specsDir string) error {
-	return docker.BuildAndRun(w, theme, mode, maxIter, branch, planFile, specsDir) //nolint:wrapcheck // thin adapter
+// eventPoll is how often a run's logs are checked for progress to publish
+// to the event stream.
+const eventPoll = 2 * time.Second
+
+// BuildAndRun runs the loop, publishing its progress to the per-user event
+// stream that ralph overview reads. Publishing is best-effort: a run whose
+// events can't be written still goes ahead.
+func (r realOrchestrator) BuildAndRun(ctx context.Context, w io.Writer, theme *ui.Theme, launch *docker.LaunchOptions) error {
+	var pub *events.Publisher
+	if dir, err := events.Dir(); err == nil {
+		// BuildAndRun reports a missing repo itself.
+		repoRoot, _ := git.RepoRoot(ctx) //nolint:errcheck // see above
+
+		pub, _ = events.Start(dir, events.Event{ //nolint:errcheck // best-effort
+			Project:       repoRoot,
+			Branch:        launch.Branch,
+			Mode:          launch.Mode,
+			MaxIterations: launch.MaxIterations,
+		}, nil)
+		pub.Watch(filepath.Join(repoRoot, "logs"), costRates(repoRoot), eventPoll)
+	}
+	err := docker.BuildAndRun(ctx, w, theme, launch)
+	pub.Finish(err)
+	mirrorState(ctx, w, theme)
+	return err //nolint:wrapcheck // thin adapter
+}
+
+// costRates is the pricing table for logs that reported no cost:
+// cost.pricing over the defaults, or the defaults alone without a config.
+func costRates(repoRoot string) pricing.Table {
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		return pricing.Default
+	}
+	return cfg.Cost.Table()
+}
+
+// mirrorState exports the run history to the state_db SQLite database, when
+// one is configured, after each run. Best-effort: a failed export is
+// reported but doesn't fail the run.
+//
+//nolint:errcheck // display-only writes to terminal
+func mirrorState(ctx context.Context, w io.Writer, theme *ui.Theme) {
+	repoRoot, err := git.RepoRoot(ctx)
+	if err != nil {
+		return
+	}
+	cfg, err := config.Load(repoRoot)
+	if err != nil || cfg.StateDB == "" {
+		return
+	}
+	if err := exportState(context.WithoutCancel(ctx), repoRoot, cfg.StateDB, nil); err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Could not update %s: %s", cfg.StateDB, err)))
+	}
 }
 
 // runParams holds resolved parameters shared by planCmd and buildCmd.
@@ -133,10 +400,146 @@ type runParams struct {
 	planFile string
 	specsDir string
 	repoRoot string
+	timeouts git.Timeouts
+	tags     []string
+	note     string
+	step     bool
+	profile  string
+
+	experiment  string
+	chaos       float64
+	chaosSeed   int64
+	contextWarn int64  // build context size in bytes worth a warning; 0 = no check
+	onClaudeErr string // --continue-on-claude-error policy; empty = abort
+	repo        vcs.VCS
+	share       config.Share // where finished runs are uploaded when share.auto is set
+	planReview  bool         // github.plan_review: plans go up as pull requests and builds wait for approval
+	offline     bool         // --offline: no remote checks, pushes, image build or uploads
+	native      bool         // --no-docker: run the loop on the host
+	output      string       // --output json: the loop prints NDJSON events; empty = text
+	maxCost     float64      // --max-cost in USD; 0 = cost.max_cost
+	pkg         string       // --package: the workspace package directory the run is scoped to; empty = whole repo
+	splitTasks  int          // phases.plan.split_tasks: plans with more tasks are worth splitting into milestones
+	swarm       bool         // run by ralph swarm, concurrently with other branches
+
+	stalePlan  string        // phases.build.stale_plan: warn, block or off
+	planMaxAge time.Duration // phases.build.plan_max_age_days: a plan older than this, with unchanged specs, is stale
+
+	planApproval *state.Approval // set by build once the plan's pull request is approved
+
+	// specsDirFor maps a sanitized branch to its specs directory. It is nil
+	// when --specs or specs_dir_exact pins one directory for every branch,
+	// in which case there is no parent branch to inherit from.
+	specsDirFor func(string) string
+}
+
+// launchOptions builds the docker launch settings for the given mode.
+func (p *runParams) launchOptions(mode string) *docker.LaunchOptions {
+	return &docker.LaunchOptions{
+		Mode:          mode,
+		MaxIterations: p.maxVal,
+		Branch:        p.branch,
+		PlanFile:      p.planFile,
+		SpecsDir:      p.specsDir,
+		Tags:          p.tags,
+		Note:          p.note,
+		Step:          p.step,
+		Profile:       p.profile,
+		Experiment:    p.experiment,
+		Chaos:         p.chaos,
+		ChaosSeed:     p.chaosSeed,
+		OnClaudeError: p.onClaudeErr,
+		VCS:           p.repo,
+		PlanApproval:  p.planApproval,
+		Offline:       p.offline,
+		Native:        p.native,
+		Output:        p.output,
+		MaxCost:       p.maxCost,
+		Package:       p.pkg,
+		Swarm:         p.swarm,
+	}
+}
+
+// hostWriter is where plan and build print their own messages: stdout, or
+// stderr with --output json, so that stdout carries only the loop's events.
+func hostWriter(cmd *cobra.Command) io.Writer {
+	if format, _ := cmd.Flags().GetString("output"); format == loop.OutputJSON { //nolint:errcheck // defined on plan and build
+		return cmd.ErrOrStderr()
+	}
+	return cmd.OutOrStdout()
+}
+
+// maxNoteLen caps --note so it stays a one-line description.
+const maxNoteLen = 500
+
+// safeTag matches run tags: they are joined with commas into RALPH_TAGS, so
+// only a conservative character set is allowed.
+var safeTag = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// addRunLabelFlags registers --tag and --note on a run command.
+func addRunLabelFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("tag", nil, "label this run (repeatable), e.g. --tag nightly")
+	cmd.Flags().String("note", "", "free-form description stored with the run")
+}
+
+// addChaosFlags registers the hidden --chaos and --chaos-seed flags, which
+// make the loop inject push failures, claude exits and truncated streams to
+// exercise its recovery paths. Bare --chaos uses defaultChaosRate.
+func addChaosFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64("chaos", 0, "inject random failures at this rate (0-1) to test recovery")
+	cmd.Flags().Lookup("chaos").NoOptDefVal = defaultChaosRate
+	cmd.Flags().Int64("chaos-seed", 0, "seed for --chaos, to reproduce a run (0 = random)")
+	_ = cmd.Flags().MarkHidden("chaos")
+	_ = cmd.Flags().MarkHidden("chaos-seed")
+}
+
+// defaultChaosRate is the --chaos rate when the flag is given without one.
+const defaultChaosRate = "0.3"
+
+// readChaos extracts and validates --chaos and --chaos-seed, picking a
+// random seed when none was given.
+func readChaos(cmd *cobra.Command) (rate float64, seed int64, err error) {
+	rate, err = cmd.Flags().GetFloat64("chaos")
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading --chaos flag: %w", err)
+	}
+	if rate < 0 || rate > 1 {
+		return 0, 0, fmt.Errorf("--chaos must be between 0 and 1, got %g", rate)
+	}
+	seed, err = cmd.Flags().GetInt64("chaos-seed")
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading --chaos-seed flag: %w", err)
+	}
+	if rate > 0 && seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rate, seed, nil
+}
+
+// readRunLabels extracts and validates --tag and --note.
+func readRunLabels(cmd *cobra.Command) (tags []string, note string, err error) {
+	tags, err = cmd.Flags().GetStringArray("tag")
+	if err != nil {
+		return nil, "", fmt.Errorf("reading --tag flag: %w", err)
+	}
+	for _, t := range tags {
+		if !safeTag.MatchString(t) {
+			return nil, "", fmt.Errorf("--tag %q must contain only letters, digits, '.', '_' or '-'", t)
+		}
+	}
+	note, err = cmd.Flags().GetString("note")
+	if err != nil {
+		return nil, "", fmt.Errorf("reading --note flag: %w", err)
+	}
+	if len(note) > maxNoteLen {
+		return nil, "", fmt.Errorf("--note must be at most %d characters, got %d", maxNoteLen, len(note))
+	}
+	return tags, note, nil
 }
 
 // resolveRunParams extracts flags, resolves the branch, checks protection,
-// sanitizes the branch name, loads config, and computes paths.
+// sanitizes the branch name, loads config, and computes paths. Leaving a
+// protected branch for a new one comes after every other check.
 func resolveRunParams(cmd *cobra.Command) (*runParams, error) {
 	maxVal, err := cmd.Flags().GetInt("max")
 	if err != nil {
@@ -151,29 +554,140 @@ func resolveRunParams(cmd *cobra.Command) (*runParams, error) {
 			return nil, err
 		}
 	}
+	tags, note, err := readRunLabels(cmd)
+	if err != nil {
+		return nil, err
+	}
+	step, err := cmd.Flags().GetBool("step")
+	if err != nil {
+		return nil, fmt.Errorf("reading --step flag: %w", err)
+	}
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return nil, fmt.Errorf("reading --profile flag: %w", err)
+	}
+	chaos, chaosSeed, err := readChaos(cmd)
+	if err != nil {
+		return nil, err
+	}
+	onClaudeErr, err := cmd.Flags().GetString("continue-on-claude-error")
+	if err != nil {
+		return nil, fmt.Errorf("reading --continue-on-claude-error flag: %w", err)
+	}
+	policy, err := loop.ParseClaudeErrorPolicy(onClaudeErr)
+	if err != nil {
+		return nil, fmt.Errorf("--continue-on-claude-error: %w", err)
+	}
+	if policy == (loop.ClaudeErrorPolicy{}) {
+		onClaudeErr = "" // abort is the container's default
+	}
+	if profile != "" && !safeTag.MatchString(profile) {
+		return nil, fmt.Errorf("--profile %q must contain only letters, digits, '.', '_' or '-'", profile)
+	}
+	newBranch, err := cmd.Flags().GetString("branch")
+	if err != nil {
+		return nil, fmt.Errorf("reading --branch flag: %w", err)
+	}
+	offline, err := cmd.Flags().GetBool("offline")
+	if err != nil {
+		return nil, fmt.Errorf("reading --offline flag: %w", err)
+	}
+	native, err := cmd.Flags().GetBool("no-docker")
+	if err != nil {
+		return nil, fmt.Errorf("reading --no-docker flag: %w", err)
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return nil, fmt.Errorf("reading --output flag: %w", err)
+	}
+	if !loop.ValidOutput(output) {
+		return nil, fmt.Errorf("--output must be %q or %q, got %q", loop.OutputText, loop.OutputJSON, output)
+	}
+	tui, err := cmd.Flags().GetBool("tui")
+	if err != nil {
+		return nil, fmt.Errorf("reading --tui flag: %w", err)
+	}
+	if tui {
+		switch {
+		case output != loop.OutputText:
+			return nil, fmt.Errorf("--tui can't be combined with --output %s", output)
+		case step:
+			return nil, fmt.Errorf("--tui can't be combined with --step, which prompts between iterations")
+		}
+		f, isFile := cmd.OutOrStdout().(*os.File)
+		if isFile && !term.IsTerminal(int(f.Fd())) { //nolint:gosec // file descriptors fit in an int
+			return nil, fmt.Errorf("--tui needs a terminal; use --output json in CI")
+		}
+		output = loop.OutputTUI
+	}
+	if output == loop.OutputText {
+		output = "" // the container's default
+	}
 
-	repoRoot, err := git.RepoRoot()
+	maxCost, err := cmd.Flags().GetFloat64("max-cost")
 	if err != nil {
-		return nil, fmt.Errorf("finding repo root: %w", err)
+		return nil, fmt.Errorf("reading --max-cost flag: %w", err)
 	}
-	cfg, err := config.Load(repoRoot)
+
+	ctx := cmd.Context()
+	repo, cfg, err := openRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Cost.CheckCap("--max-cost", maxCost); err != nil {
+		return nil, err //nolint:wrapcheck // names the flag itself
+	}
+	repoRoot := repo.Root()
+
+	pkgName, err := cmd.Flags().GetString("package")
 	if err != nil {
-		return nil, fmt.Errorf("loading config: %w", err)
+		return nil, fmt.Errorf("reading --package flag: %w", err)
+	}
+	var pkgDir string
+	if pkgName != "" {
+		pkg, err := scaffold.Detect(repoRoot).FindPackage(pkgName)
+		if err != nil {
+			return nil, fmt.Errorf("--package: %w", err)
+		}
+		pkgDir = pkg.Dir
 	}
 
-	branch, err := git.Branch()
+	branch, err := repo.Branch(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("getting current branch: %w", err)
 	}
-	if git.IsProtectedBranch(branch, cfg.ProtectedBranches) {
-		return nil, fmt.Errorf("ralph %s must be run on a feature branch, not %q", cmd.Name(), branch)
+	protected := git.IsProtectedBranch(branch, cfg.ProtectedBranches)
+	if !protected && newBranch != "" && newBranch != branch {
+		return nil, fmt.Errorf("--branch only applies when starting on a protected branch; already on %q", branch)
+	}
+
+	experiment, err := cmd.Flags().GetString("experiment")
+	if err != nil {
+		return nil, fmt.Errorf("reading --experiment flag: %w", err)
+	}
+	if err := checkExperiment(cfg, repoRoot, cmd.Name(), experiment); err != nil {
+		return nil, err
+	}
+
+	// Last, so a run refused for anything above leaves the user where they were.
+	if protected {
+		if branch, err = leaveProtectedBranch(cmd, repo, cfg, branch, newBranch); err != nil {
+			return nil, err
+		}
 	}
 
 	sanitized := git.SanitizeBranch(branch)
+	var specsDirFor func(string) string
 	if specsDir == "" {
-		specsDir = cfg.SpecsDirForBranch(sanitized)
+		mode := cmd.Name()
+		specsDir = cfg.SpecsDirForPhase(mode, sanitized)
+		if !cfg.SpecsDirExact {
+			specsDirFor = func(b string) string { return cfg.SpecsDirForPhase(mode, b) }
+		}
 	}
 	planFile := cfg.PlanPathForBranch(sanitized)
+	slog.DebugContext(ctx, "run params", "mode", cmd.Name(), "branch", branch, "plan", planFile, "specs", specsDir,
+		"max_iterations", maxVal, "max_cost", maxCost, "package", pkgDir, "offline", offline, "native", native, "experiment", experiment)
 
 	return &runParams{
 		maxVal:   maxVal,
@@ -181,17 +695,276 @@ func resolveRunParams(cmd *cobra.Command) (*runParams, error) {
 		planFile: planFile,
 		specsDir: specsDir,
 		repoRoot: repoRoot,
+		timeouts: gitTimeouts(cfg),
+		tags:     tags,
+		note:     note,
+		step:     step,
+		profile:  profile,
+
+		experiment:  experiment,
+		chaos:       chaos,
+		chaosSeed:   chaosSeed,
+		contextWarn: int64(cfg.Docker.ContextWarnMB) << 20,
+		onClaudeErr: onClaudeErr,
+		specsDirFor: specsDirFor,
+		repo:        repo,
+		share:       cfg.Share,
+		planReview:  cfg.GitHub.PlanReview,
+		offline:     offline,
+		native:      native,
+		output:      output,
+		maxCost:     maxCost,
+		pkg:         pkgDir,
+		splitTasks:  cfg.Phases.Plan.SplitTasks,
+		swarm:       os.Getenv(swarm.MemberEnv) == "1",
+		stalePlan:   cfg.Phases.Build.StalePlan,
+		planMaxAge:  time.Duration(cfg.Phases.Build.PlanMaxAgeDays) * 24 * time.Hour,
 	}, nil
 }
 
+// leaveProtectedBranch creates the feature branch name and switches to it,
+// so a run started on a protected branch can go ahead. Without a name the
+// user is asked for one, suggested from the first spec; with no terminal to
+// ask on, the run is refused.
+//
+//nolint:errcheck // display-only writes to terminal
+func leaveProtectedBranch(cmd *cobra.Command, repo vcs.VCS, cfg *config.Config, protected, name string) (string, error) {
+	w := cmd.OutOrStdout()
+	if name == "" {
+		f, isFile := cmd.InOrStdin().(*os.File)
+		if isFile && !term.IsTerminal(int(f.Fd())) { //nolint:gosec // file descriptors fit in an int
+			return "", fmt.Errorf("ralph %s must be run on a feature branch, not %q; pass --branch <name> to create one", cmd.Name(), protected)
+		}
+		dir := filepath.Join(repo.Root(), cfg.SpecsDirForPhase(cmd.Name(), git.SanitizeBranch(protected)))
+		suggested := specs.SuggestBranch(dir, filepath.Dir(dir))
+		var err error
+		if name, err = specs.PromptBranch(cmd.InOrStdin(), w, !isFile, protected, suggested); err != nil {
+			return "", fmt.Errorf("prompting for a branch name: %w", err)
+		}
+	}
+	if git.IsProtectedBranch(name, cfg.ProtectedBranches) {
+		return "", fmt.Errorf("%q is a protected branch too", name)
+	}
+	if err := repo.CreateBranch(cmd.Context(), name); err != nil {
+		return "", fmt.Errorf("creating branch %q: %w", name, err)
+	}
+	fmt.Fprintf(w, "%s Created branch %s from %s\n", ui.DefaultTheme().Success.Render("✓"), name, protected)
+	return name, nil
+}
+
+// openRepo opens the version control backend for the working directory and
+// loads the project config from its root. The vcs config key overrides the
+// detected backend.
+func openRepo(ctx context.Context) (vcs.VCS, *config.Config, error) {
+	repo, err := vcs.Open(ctx, vcs.KindAuto)
+	if err != nil {
+		return nil, nil, fmt.Errorf("finding repo root: %w", err)
+	}
+	cfg, err := config.Load(repo.Root())
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading config: %w", err)
+	}
+	if cfg.VCS != "" && cfg.VCS != vcs.KindAuto && cfg.VCS != repo.Kind() {
+		if repo, err = vcs.New(ctx, cfg.VCS, repo.Root()); err != nil {
+			return nil, nil, fmt.Errorf("opening %s repo: %w", cfg.VCS, err)
+		}
+	}
+	return repo, cfg, nil
+}
+
+// checkExperiment verifies that name is a configured experiment and that any
+// prompt it substitutes for mode exists. An empty name is always valid.
+func checkExperiment(cfg *config.Config, repoRoot, mode, name string) error {
+	if name == "" {
+		return nil
+	}
+	e, ok := cfg.Experiments[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.Experiments))
+		for n := range cfg.Experiments {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown experiment %q: no experiments defined in .ralph/config.yaml", name)
+		}
+		return fmt.Errorf("unknown experiment %q (defined: %s)", name, strings.Join(names, ", "))
+	}
+	if prompt := e.Prompt(mode, ""); prompt != "" {
+		if _, err := os.Stat(filepath.Join(repoRoot, prompt)); err != nil {
+			return fmt.Errorf("experiment %s: prompt %s: %w", name, prompt, err)
+		}
+	}
+	return nil
+}
+
+// inheritSpecs offers to seed an empty specs directory from the branch this
+// one was forked from. --inherit-specs answers non-interactively.
+func inheritSpecs(cmd *cobra.Command, p *runParams, theme *ui.Theme) error {
+	method, err := cmd.Flags().GetString("inherit-specs")
+	if err != nil {
+		return fmt.Errorf("reading --inherit-specs flag: %w", err)
+	}
+	if method != "" && !specs.ValidMethod(method) {
+		return fmt.Errorf("--inherit-specs must be %s, %s or %s, got %q", specs.MethodCopy, specs.MethodLink, specs.MethodNone, method)
+	}
+	if p.specsDirFor == nil || method == specs.MethodNone || specs.HasSpecs(filepath.Join(p.repoRoot, p.specsDir)) {
+		return nil
+	}
+
+	parent, err := specs.FindParent(cmd.Context(), p.repoRoot, p.branch, p.specsDirFor)
+	if err != nil {
+		return fmt.Errorf("finding parent branch specs: %w", err)
+	}
+	if parent == nil {
+		return nil
+	}
+
+	w := cmd.OutOrStdout()
+	if method == "" {
+		// Without a terminal there is no one to ask; inheriting then takes
+		// --inherit-specs.
+		f, isFile := cmd.InOrStdin().(*os.File)
+		if isFile && !term.IsTerminal(int(f.Fd())) { //nolint:gosec // file descriptors fit in an int
+			return nil
+		}
+		method, err = specs.Prompt(cmd.InOrStdin(), w, !isFile, parent, p.specsDir)
+		if err != nil {
+			return fmt.Errorf("prompting for parent specs: %w", err)
+		}
+	}
+
+	switch method {
+	case specs.MethodCopy:
+		err = specs.Copy(p.repoRoot, parent, p.specsDir)
+	case specs.MethodLink:
+		err = specs.Link(p.repoRoot, parent, p.specsDir)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err //nolint:wrapcheck // specs errors already have context
+	}
+	fmt.Fprintf(w, "  %s Specs inherited from %s (%s)\n", theme.Success.Render("✓"), parent.Branch, method) //nolint:errcheck // display-only
+	return nil
+}
+
+// checkBuildContext warns when the image build context would include heavy
+// directories or exceed docker.context_warn_mb, and offers to add the
+// missing directories to the ignore file. Large contexts slow every run.
+// Runs without Docker build no image, so there is nothing to check.
+//
+//nolint:errcheck // display-only writes to terminal
+func checkBuildContext(cmd *cobra.Command, p *runParams, theme *ui.Theme) error {
+	if p.native {
+		return nil
+	}
+	w := cmd.OutOrStdout()
+	report, err := docker.CheckContext(p.repoRoot, docker.DefaultDockerfile, p.contextWarn)
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Could not check the build context: %s", err)))
+		return nil
+	}
+	if report.OverLimit {
+		fmt.Fprintf(w, "%s build context is over %s after %s; every run sends it to docker\n",
+			theme.Warning.Render("⚠"), formatMB(p.contextWarn), report.IgnoreFile)
+	}
+	if len(report.Missing) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(w, "%s %s doesn't ignore %s\n",
+		theme.Warning.Render("⚠"), report.IgnoreFile, strings.Join(report.Missing, ", "))
+	// Only offer the fix when someone can answer; CI runs just get the warning.
+	f, isFile := cmd.InOrStdin().(*os.File)
+	if isFile && !term.IsTerminal(int(f.Fd())) { //nolint:gosec // file descriptors fit in an int
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("  Add them to %s to speed up every run.", report.IgnoreFile)))
+		return nil
+	}
+	add := true
+	form := huh.NewForm(huh.NewGroup(
+		huh.NewConfirm().
+			Title(fmt.Sprintf("Add them to %s?", report.IgnoreFile)).
+			Value(&add),
+	)).WithAccessible(!isFile).
+		WithTheme(ui.HuhTheme()).
+		WithInput(cmd.InOrStdin()).
+		WithOutput(w)
+	if err := form.Run(); err != nil {
+		return fmt.Errorf("prompting for .dockerignore additions: %w", err)
+	}
+	if !add {
+		return nil
+	}
+	if err := docker.AddIgnores(p.repoRoot, report.IgnoreFile, report.Missing); err != nil {
+		return err //nolint:wrapcheck // docker errors already have context
+	}
+	fmt.Fprintf(w, "  %s Added %s to %s\n", theme.Success.Render("✓"), strings.Join(report.Missing, ", "), report.IgnoreFile)
+	return nil
+}
+
+// checkPathCollisions stops a run whose specs directory or plan path already
+// holds files ralph didn't create, since preflight commits them and the
+// agent may overwrite them, unless --allow-existing is set or the user
+// confirms. With no terminal to ask on, the run is refused.
+//
+//nolint:errcheck // display-only writes to terminal
+func checkPathCollisions(cmd *cobra.Command, p *runParams, w io.Writer, theme *ui.Theme) error {
+	collisions, err := preflight.CheckCollisions(p.repoRoot, p.specsDir, p.planFile)
+	if err != nil {
+		return err //nolint:wrapcheck // preflight errors already have context
+	}
+	if len(collisions) == 0 {
+		return nil
+	}
+	allow, err := cmd.Flags().GetBool("allow-existing")
+	if err != nil {
+		return fmt.Errorf("reading --allow-existing flag: %w", err)
+	}
+	for _, c := range collisions {
+		fmt.Fprintf(w, "%s %s\n", theme.Warning.Render("⚠"), c)
+	}
+	if allow {
+		return nil
+	}
+	refused := errors.New("not using paths that hold files ralph didn't create; pass --allow-existing to use them anyway, " +
+		"or pick others with --specs or phases.plan.output and phases.plan.filename_template")
+	f, isFile := cmd.InOrStdin().(*os.File)
+	if isFile && !term.IsTerminal(int(f.Fd())) { //nolint:gosec // file descriptors fit in an int
+		return refused
+	}
+	use := false
+	form := huh.NewForm(huh.NewGroup(
+		huh.NewConfirm().
+			Title("Use them anyway? Ralph commits them and the agent may overwrite them.").
+			Value(&use),
+	)).WithAccessible(!isFile).
+		WithTheme(ui.HuhTheme()).
+		WithInput(cmd.InOrStdin()).
+		WithOutput(w)
+	if err := form.Run(); err != nil {
+		return fmt.Errorf("prompting about existing paths: %w", err)
+	}
+	if !use {
+		return refused
+	}
+	return nil
+}
+
+// formatMB renders a byte count in whole megabytes, e.g. "100 MB".
+func formatMB(n int64) string {
+	return fmt.Sprintf("%d MB", n>>20)
+}
+
 func planCmd(orch Orchestrator) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "plan",
-		Short: "Run planning loop (generates branch-specific plan)",
-		Args:  cobra.NoArgs,
+		Use:     "plan",
+		Aliases: []string{"p"},
+		Short:   "Run planning loop (generates branch-specific plan)",
+		Args:    cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			theme := ui.DefaultTheme()
-			w := cmd.OutOrStdout()
+			w := hostWriter(cmd)
 			fmt.Fprintln(w, theme.Banner()) //nolint:errcheck // display-only
 			fmt.Fprintln(w)                 //nolint:errcheck // display-only
 
@@ -199,6 +972,12 @@ func planCmd(orch Orchestrator) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if err := checkPathCollisions(cmd, p, w, theme); err != nil {
+				return err
+			}
+			if err := inheritSpecs(cmd, p, theme); err != nil {
+				return err
+			}
 
 			// Ensure specs and plans directories exist with .gitkeep so
 			// preflight can track and auto-commit them.
@@ -232,84 +1011,2560 @@ func planCmd(orch Orchestrator) *cobra.Command {
 				return fmt.Errorf("no .md specs found in %s/ — add at least one spec before running plan", p.specsDir)
 			}
 
-			return orch.BuildAndRun(w, theme, "plan", p.maxVal, p.branch, p.planFile, p.specsDir)
+			if err := checkBuildContext(cmd, p, theme); err != nil {
+				return err
+			}
+
+			ctx := git.WithTimeouts(cmd.Context(), p.timeouts)
+			if err := orch.BuildAndRun(ctx, w, theme, p.launchOptions("plan")); err != nil {
+				return err
+			}
+			if p.planReview {
+				if p.offline {
+					fmt.Fprintln(w, theme.Muted.Render(i18n.T("Offline: \"ralph sync\" opens the plan pull request for review."))) //nolint:errcheck // display-only
+				} else if err := requestPlanReview(ctx, w, theme, p); err != nil {
+					return err
+				}
+			}
+			warnSyncIssues(ctx, w, theme, p)
+			suggestSplit(w, theme, p)
+			shareLatestRun(ctx, w, theme, p)
+			return nil
 		},
 	}
+	cmd.AddCommand(planSplitCmd())
 	cmd.Flags().IntP("max", "n", 0, "maximum iterations (0 = use config default)")
 	cmd.Flags().String("specs", "", "specs directory (overrides specs_dir in config)")
+	cmd.Flags().String("branch", "", "when on a protected branch, create this feature branch and run there (default: ask)")
+	cmd.Flags().String("inherit-specs", "", "when this branch has no specs, reuse the parent branch's: copy, link or none (default: ask)")
+	cmd.Flags().Bool("offline", false, "skip remote checks, pushes and the image build; push later with ralph sync")
+	cmd.Flags().Bool("no-docker", false, "run the loop directly on this machine instead of in a container (e.g. inside a devcontainer)")
+	cmd.Flags().String("output", loop.OutputText, "loop progress format: text, or json for one event per line (NDJSON) for CI")
+	cmd.Flags().Bool("tui", false, "show a live dashboard of the task, iteration, context, cost and recent tool calls instead of scrolling output")
+	cmd.Flags().Float64("max-cost", 0, "stop starting iterations once the run has spent this many US dollars (0 = cost.max_cost)")
+	cmd.Flags().String("package", "", "scope the run to one workspace package, by name or directory (see the Packages section of AGENTS.md)")
+	cmd.Flags().Bool("allow-existing", false, "use the specs directory and plan path even if they hold files ralph didn't create")
+	cmd.Flags().Bool("step", false, "pause after each iteration to review the diff and cost before continuing")
+	cmd.Flags().String("profile", "", "credential profile from .ralph/profiles.yaml (overrides .env)")
+	cmd.Flags().String("experiment", "", "run with a prompt/model variant from experiments in config")
+	cmd.Flags().String("continue-on-claude-error", loop.OnClaudeErrorAbort, "when claude fails: abort, skip-iteration or retry-N")
+	addRunLabelFlags(cmd)
+	addChaosFlags(cmd)
 	return cmd
 }
 
-func buildCmd(orch Orchestrator) *cobra.Command {
+// suggestSplit points at ralph plan split when the plan just written has
+// more tasks than phases.plan.split_tasks and isn't split yet.
+func suggestSplit(w io.Writer, theme *ui.Theme, p *runParams) {
+	planPath := filepath.Join(p.repoRoot, p.planFile)
+	if p.splitTasks <= 0 || len(milestone.List(planPath)) > 0 {
+		return
+	}
+	tasks, err := status.ParsePlan(planPath)
+	if err != nil || len(tasks) <= p.splitTasks {
+		return
+	}
+	fmt.Fprintf(w, "%s the plan has %d tasks; \"ralph plan split\" breaks it into milestones of up to %d to build one at a time\n", //nolint:errcheck // display-only
+		theme.Warning.Render("⚠"), len(tasks), p.splitTasks)
+}
+
+// planSplitCmd splits the branch's plan into milestone plan files and
+// leaves an index in its place.
+func planSplitCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "build",
-		Short: "Run build loop (implements tasks one at a time)",
-		Args:  cobra.NoArgs,
+		Use:   "split",
+		Short: "Split an oversized plan into milestone plan files",
+		Long: `Split the branch's plan into milestone plan files of at most --max-tasks
+tasks each (phases.plan.split_tasks by default), keeping its "##" sections
+together where they fit. The plan file becomes an index of the milestones.
+ralph build then works through them in order, or targets one with --milestone.`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			theme := ui.DefaultTheme()
 			w := cmd.OutOrStdout()
-			fmt.Fprintln(w, theme.Banner()) //nolint:errcheck // display-only
+			ctx := cmd.Context()
+			repo, cfg, err := openRepo(ctx)
+			if err != nil {
+				return err
+			}
+			maxTasks, err := cmd.Flags().GetInt("max-tasks")
+			if err != nil {
+				return fmt.Errorf("reading --max-tasks flag: %w", err)
+			}
+			if maxTasks == 0 {
+				maxTasks = cfg.Phases.Plan.SplitTasks
+			}
+			branch, err := repo.Branch(ctx)
+			if err != nil {
+				return fmt.Errorf("getting current branch: %w", err)
+			}
+			planFile := cfg.PlanPathForBranch(git.SanitizeBranch(branch))
+			planPath := filepath.Join(repo.Root(), planFile)
+			if _, err := os.Stat(planPath); os.IsNotExist(err) {
+				return fmt.Errorf("plan file %q not found; run \"ralph plan\" first", planFile)
+			}
+			res, err := milestone.Write(planPath, maxTasks)
+			if err != nil {
+				return fmt.Errorf("splitting %s: %w", planFile, err)
+			}
+			fmt.Fprintf(w, "%s Split %s into %d milestones:\n", theme.Success.Render("✓"), planFile, len(res.Milestones)) //nolint:errcheck // display-only
+			for i, m := range res.Milestones {
+				fmt.Fprintf(w, "  %d. %s %s\n", i+1, filepath.Base(m.Path), theme.Muted.Render(fmt.Sprintf("(%d tasks)", len(m.Tasks)))) //nolint:errcheck // display-only
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Int("max-tasks", 0, "most tasks per milestone (0 = phases.plan.split_tasks)")
+	return cmd
+}
+
+// pickMilestone points a build at one milestone of a split plan: the one
+// --milestone names, or else the first with tasks left (the last when all
+// are done, so the build reports nothing to do). Unsplit plans are built
+// whole.
+func pickMilestone(cmd *cobra.Command, p *runParams, w io.Writer, theme *ui.Theme) error {
+	n, err := cmd.Flags().GetInt("milestone")
+	if err != nil {
+		return fmt.Errorf("reading --milestone flag: %w", err)
+	}
+	paths := milestone.List(filepath.Join(p.repoRoot, p.planFile))
+	switch {
+	case n < 0:
+		return fmt.Errorf("--milestone must be positive, got %d", n)
+	case n > 0 && len(paths) == 0:
+		return fmt.Errorf("--milestone %d: %s isn't split into milestones; run \"ralph plan split\" first", n, p.planFile)
+	case n > len(paths):
+		return fmt.Errorf("--milestone %d: %s has %d milestones", n, p.planFile, len(paths))
+	case n == 0 && len(paths) == 0:
+		return nil
+	case n == 0:
+		if n, err = milestone.Active(paths); err != nil {
+			return fmt.Errorf("parsing milestones: %w", err)
+		}
+		if n == 0 {
+			n = len(paths)
+		}
+	}
+	p.planFile = milestone.Path(p.planFile, n)
+	fmt.Fprintf(w, "%s %d of %d (%s)\n", theme.Muted.Render(i18n.T("Milestone:")), n, len(paths), p.planFile) //nolint:errcheck // display-only
+	return nil
+}
+
+func buildCmd(orch Orchestrator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "build",
+		Aliases: []string{"b"},
+		Short:   "Run build loop (implements tasks one at a time)",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			theme := ui.DefaultTheme()
+			w := hostWriter(cmd)
+			fmt.Fprintln(w, theme.Banner()) //nolint:errcheck // display-only
 			fmt.Fprintln(w)                 //nolint:errcheck // display-only
 
 			p, err := resolveRunParams(cmd)
 			if err != nil {
 				return err
 			}
+			if err := pickMilestone(cmd, p, w, theme); err != nil {
+				return err
+			}
+			if err := checkPathCollisions(cmd, p, w, theme); err != nil {
+				return err
+			}
+
+			planPath := filepath.Join(p.repoRoot, p.planFile)
+			if _, err := os.Stat(planPath); os.IsNotExist(err) {
+				return fmt.Errorf("plan file %q not found; run \"ralph plan\" first", p.planFile)
+			}
+			done, err := planComplete(cmd, planPath)
+			if err != nil {
+				return err
+			}
+			if done > 0 {
+				fmt.Fprintln(w, theme.Success.Render(i18n.Tf("✓ All %d tasks complete; nothing to build.", done))) //nolint:errcheck // display-only
+				fmt.Fprintln(w, theme.Muted.Render(i18n.T("Use --force to run anyway.")))                          //nolint:errcheck // display-only
+				return nil
+			}
+
+			if err := checkPlanFreshness(cmd, p, w, theme); err != nil {
+				return err
+			}
+
+			if p.planReview {
+				if p.offline {
+					return fmt.Errorf("github.plan_review needs GitHub to check the plan's approval; build online or turn plan_review off")
+				}
+				if p.planApproval, err = checkPlanApproval(cmd.Context(), w, theme, p); err != nil {
+					return err
+				}
+			}
+
+			if err := checkBuildContext(cmd, p, theme); err != nil {
+				return err
+			}
+
+			ctx := git.WithTimeouts(cmd.Context(), p.timeouts)
+			if err := orch.BuildAndRun(ctx, w, theme, p.launchOptions("build")); err != nil {
+				return err
+			}
+			warnSyncIssues(ctx, w, theme, p)
+			shareLatestRun(ctx, w, theme, p)
+			return nil
+		},
+	}
+	cmd.Flags().IntP("max", "n", 0, "maximum iterations (0 = use config default)")
+	cmd.Flags().String("specs", "", "specs directory (overrides specs_dir in config)")
+	cmd.Flags().Bool("step", false, "pause after each iteration to review the diff and cost before continuing")
+	cmd.Flags().String("profile", "", "credential profile from .ralph/profiles.yaml (overrides .env)")
+	cmd.Flags().String("experiment", "", "run with a prompt/model variant from experiments in config")
+	cmd.Flags().String("continue-on-claude-error", loop.OnClaudeErrorAbort, "when claude fails: abort, skip-iteration or retry-N")
+	cmd.Flags().Bool("force", false, "run even when every task in the plan is complete")
+	cmd.Flags().Int("milestone", 0, "build this milestone of a split plan (default: the first with tasks left)")
+	cmd.Flags().Bool("offline", false, "skip remote checks, pushes and the image build; push later with ralph sync")
+	cmd.Flags().Bool("no-docker", false, "run the loop directly on this machine instead of in a container (e.g. inside a devcontainer)")
+	cmd.Flags().String("output", loop.OutputText, "loop progress format: text, or json for one event per line (NDJSON) for CI")
+	cmd.Flags().Bool("tui", false, "show a live dashboard of the task, iteration, context, cost and recent tool calls instead of scrolling output")
+	cmd.Flags().Float64("max-cost", 0, "stop starting iterations once the run has spent this many US dollars (0 = cost.max_cost)")
+	cmd.Flags().String("package", "", "scope the run to one workspace package, by name or directory (see the Packages section of AGENTS.md)")
+	cmd.Flags().Bool("allow-existing", false, "use the specs directory and plan path even if they hold files ralph didn't create")
+	cmd.Flags().String("branch", "", "when on a protected branch, create this feature branch and run there (default: ask)")
+	addRunLabelFlags(cmd)
+	addChaosFlags(cmd)
+	return cmd
+}
+
+// planComplete returns the number of tasks in the plan when all of them are
+// done, so build can stop before launching a container with nothing to do.
+// It returns 0 when work remains, the plan lists no tasks, or --force is set.
+func planComplete(cmd *cobra.Command, planPath string) (int, error) {
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return 0, fmt.Errorf("reading --force flag: %w", err)
+	}
+	if force {
+		return 0, nil
+	}
+	tasks, err := status.ParsePlan(planPath)
+	if err != nil {
+		return 0, fmt.Errorf("parsing plan: %w", err)
+	}
+	if len(tasks) == 0 || status.ActiveTask(tasks) != nil {
+		return 0, nil
+	}
+	return len(tasks), nil
+}
+
+// checkPlanFreshness warns when the plan may be out of date with its specs:
+// they were committed after it, or it has gone plan_max_age_days without a
+// change while they stayed the same. With phases.build.stale_plan: block it
+// stops the build instead, unless --force is passed.
+//
+//nolint:errcheck // display-only writes to terminal
+func checkPlanFreshness(cmd *cobra.Command, p *runParams, w io.Writer, theme *ui.Theme) error {
+	if p.stalePlan == config.StalePlanOff {
+		return nil
+	}
+	reason, err := planStaleness(cmd.Context(), p)
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Could not check whether the plan is current: %s", err)))
+		return nil
+	}
+	if reason == "" {
+		return nil
+	}
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return fmt.Errorf("reading --force flag: %w", err)
+	}
+	if p.stalePlan == config.StalePlanBlock && !force {
+		return fmt.Errorf("the plan may be stale: %s; run \"ralph plan\" to refresh it, or pass --force to build anyway", reason)
+	}
+	fmt.Fprintf(w, "%s plan may be stale: %s\n", theme.Warning.Render("⚠"), reason)
+	fmt.Fprintln(w, theme.Muted.Render(`  Run "ralph plan" to refresh it before building.`))
+	return nil
+}
+
+// planStaleness compares the commit times of the plan and specs; see
+// specs.Stale.
+func planStaleness(ctx context.Context, p *runParams) (string, error) {
+	planChanged, err := git.LastChanged(ctx, p.planFile)
+	if err != nil {
+		return "", fmt.Errorf("reading the plan's history: %w", err)
+	}
+	specsChanged, err := git.LastChanged(ctx, p.specsDir)
+	if err != nil {
+		return "", fmt.Errorf("reading the specs' history: %w", err)
+	}
+	return specs.Stale(planChanged, specsChanged, time.Now(), p.planMaxAge), nil
+}
+
+// resumeCmd relaunches the branch's last run when it was cancelled, crashed,
+// failed or hit its iteration limit or cost cap, with the same mode, paths
+// and labels and the rest of its budget. The new run records which run it
+// continues.
+func resumeCmd(orch Orchestrator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Continue this branch's last cancelled, crashed or max-iterations run",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			theme := ui.DefaultTheme()
+			w := cmd.OutOrStdout()
+			maxFlag, err := cmd.Flags().GetInt("max")
+			if err != nil {
+				return fmt.Errorf("reading --max flag: %w", err)
+			}
+			maxCost, err := cmd.Flags().GetFloat64("max-cost")
+			if err != nil {
+				return fmt.Errorf("reading --max-cost flag: %w", err)
+			}
+			ctx := cmd.Context()
+			repo, cfg, err := openRepo(ctx)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Cost.CheckCap("--max-cost", maxCost); err != nil {
+				return err //nolint:wrapcheck // names the flag itself
+			}
+			branch, err := repo.Branch(ctx)
+			if err != nil {
+				return fmt.Errorf("getting current branch: %w", err)
+			}
+			st, err := state.Load(filepath.Join(repo.Root(), state.DefaultPath))
+			if err != nil {
+				return fmt.Errorf("loading state: %w", err)
+			}
+			n := st.LatestOnBranch(branch)
+			if n == 0 {
+				return fmt.Errorf("no run recorded on %s to resume", branch)
+			}
+			launch, err := resumeLaunch(st, n, maxFlag, maxCost)
+			if err != nil {
+				return err
+			}
+			launch.VCS = repo
+			sanitized := git.SanitizeBranch(branch)
+			if launch.PlanFile == "" {
+				launch.PlanFile = cfg.PlanPathForBranch(sanitized)
+			}
+			if launch.SpecsDir == "" {
+				launch.SpecsDir = cfg.SpecsDirForPhase(launch.Mode, sanitized)
+			}
+
+			fmt.Fprintln(w, theme.Banner()) //nolint:errcheck // display-only
+			fmt.Fprintln(w)                 //nolint:errcheck // display-only
+			left := "no iteration limit"
+			if launch.MaxIterations > 0 {
+				left = fmt.Sprintf("%d iteration(s) left", launch.MaxIterations)
+			}
+			if launch.MaxCost > 0 {
+				left += ", " + cfg.Cost.Display().Format(launch.MaxCost, 2) + " to spend"
+			}
+			fmt.Fprintf(w, "%s Resuming %s run #%d on %s, %s\n", //nolint:errcheck // display-only
+				theme.Info.Render("↻"), launch.Mode, n, branch, left)
+
+			ctx = git.WithTimeouts(ctx, gitTimeouts(cfg))
+			return orch.BuildAndRun(ctx, w, theme, launch)
+		},
+	}
+	cmd.Flags().IntP("max", "n", 0, "iterations to run (default: what's left of the original run's budget)")
+	cmd.Flags().Float64("max-cost", 0, "US dollars the resumed run may spend (default: what's left of the original run's cap)")
+	return cmd
+}
+
+// resumeLaunch builds the launch settings that continue run n. The budget
+// is the original run's limit less the iterations every run in its lineage
+// used, and its cost cap less what they spent; maxFlag and maxCost override
+// them.
+func resumeLaunch(st *state.State, n, maxFlag int, maxCost float64) (*docker.LaunchOptions, error) {
+	last := st.Runs[n-1]
+	if !last.Resumable() {
+		return nil, fmt.Errorf("run #%d ended %s; only cancelled, crashed, failed, max-iterations or cost-limit runs can be resumed", n, strings.ReplaceAll(string(last.Status), "_", " "))
+	}
+	lineage := st.Lineage(n)
+	budget := st.Runs[lineage[0]-1].MaxIterations
+	costCap := st.Runs[lineage[0]-1].MaxCost
+	used := 0
+	var spent float64
+	for _, i := range lineage {
+		used += st.Runs[i-1].Iterations
+		spent += st.Runs[i-1].TotalCost
+	}
+	remaining := maxFlag
+	if remaining == 0 && budget > 0 {
+		remaining = budget - used
+		if remaining <= 0 {
+			return nil, fmt.Errorf("run #%d used all %d iterations of its budget; pass -n to run more", n, budget)
+		}
+	}
+	if maxCost == 0 && costCap > 0 {
+		maxCost = costCap - spent
+		if maxCost <= 0 {
+			return nil, fmt.Errorf("run #%d spent all $%.2f of its cost cap; pass --max-cost to spend more", n, costCap)
+		}
+	}
+	var tags []string
+	for _, t := range last.Tags {
+		if t != "chaos" { // chaos isn't carried over
+			tags = append(tags, t)
+		}
+	}
+	return &docker.LaunchOptions{
+		Mode:          last.Mode,
+		MaxIterations: remaining,
+		Branch:        last.Branch,
+		PlanFile:      last.PlanFile,
+		SpecsDir:      last.SpecsDir,
+		Tags:          tags,
+		Note:          last.Note,
+		Profile:       last.Profile,
+		Experiment:    last.Experiment,
+		Package:       last.Package,
+		MaxCost:       maxCost,
+		ResumeOf:      n,
+	}, nil
+}
+
+// replayCmd runs a recorded run's prompts again, in order, on a new branch
+// from the current commit, so a model or prompt change can be judged on the
+// same task history.
+func replayCmd(orch Orchestrator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay a recorded run's prompts on a new branch",
+		Long: "Replay a recorded run's prompts on a new branch: --onto is created from the current commit\n" +
+			"and the loop sends the run's prompts there in order, one per iteration, with today's model\n" +
+			"settings. --run is a number as listed by `ralph status --history` (1 = oldest). The replay\n" +
+			"uses the run's plan file and specs, and is recorded as a new run tagged replay, so\n" +
+			"`ralph status --history` and `ralph report` show both side by side. --current-prompts sends the\n" +
+			"current prompt files instead, replaying only each iteration's task and operator feedback, to\n" +
+			"judge a prompt change.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			n, err := cmd.Flags().GetInt("run")
+			if err != nil {
+				return fmt.Errorf("reading --run flag: %w", err)
+			}
+			onto, err := cmd.Flags().GetString("onto")
+			if err != nil {
+				return fmt.Errorf("reading --onto flag: %w", err)
+			}
+			current, err := cmd.Flags().GetBool("current-prompts")
+			if err != nil {
+				return fmt.Errorf("reading --current-prompts flag: %w", err)
+			}
+
+			ctx := cmd.Context()
+			repo, cfg, err := openRepo(ctx)
+			if err != nil {
+				return err
+			}
+			st, err := state.Load(filepath.Join(repo.Root(), state.DefaultPath))
+			if err != nil {
+				return fmt.Errorf("loading state: %w", err)
+			}
+			records, err := recordedPrompts(st, filepath.Join(repo.Root(), "logs"), n)
+			if err != nil {
+				return err
+			}
+			run := st.Runs[n-1]
+
+			if git.IsProtectedBranch(onto, cfg.ProtectedBranches) {
+				return fmt.Errorf("--onto %q is a protected branch; replay onto a new feature branch", onto)
+			}
+			from, err := repo.Branch(ctx)
+			if err != nil {
+				return fmt.Errorf("getting current branch: %w", err)
+			}
+			if err := repo.CreateBranch(ctx, onto); err != nil {
+				return fmt.Errorf("creating branch %q: %w", onto, err)
+			}
+
+			launch := &docker.LaunchOptions{
+				Mode:           run.Mode,
+				MaxIterations:  len(records),
+				Branch:         onto,
+				PlanFile:       run.PlanFile,
+				SpecsDir:       run.SpecsDir,
+				Tags:           []string{"replay"},
+				Note:           fmt.Sprintf("replay of run #%d", n),
+				Profile:        run.Profile,
+				Package:        run.Package,
+				VCS:            repo,
+				ReplayOf:       n,
+				CurrentPrompts: current,
+			}
+			sanitized := git.SanitizeBranch(run.Branch)
+			if launch.PlanFile == "" {
+				launch.PlanFile = cfg.PlanPathForBranch(sanitized)
+			}
+			if launch.SpecsDir == "" {
+				launch.SpecsDir = cfg.SpecsDirForPhase(launch.Mode, sanitized)
+			}
+
+			w := cmd.OutOrStdout()
+			theme := ui.DefaultTheme()
+			fmt.Fprintln(w, theme.Banner()) //nolint:errcheck // display-only
+			fmt.Fprintln(w)                 //nolint:errcheck // display-only
+
+			fmt.Fprintf(w, "%s Replaying %d prompt(s) of %s run #%d onto %s, branched from %s\n", //nolint:errcheck // display-only
+				theme.Info.Render("↻"), len(records), run.Mode, n, onto, from)
+
+			ctx = git.WithTimeouts(ctx, gitTimeouts(cfg))
+			return orch.BuildAndRun(ctx, w, theme, launch)
+		},
+	}
+	cmd.Flags().Int("run", 0, "run to replay, as numbered by ralph status --history (1 = oldest)")
+	cmd.Flags().String("onto", "", "new branch to create from the current commit and replay on")
+	cmd.Flags().Bool("current-prompts", false, "send the current prompt files, replaying only each iteration's task and feedback")
+	_ = cmd.MarkFlagRequired("run")  //nolint:errcheck // the flag is defined above
+	_ = cmd.MarkFlagRequired("onto") //nolint:errcheck // the flag is defined above
+	return cmd
+}
+
+// recordedPrompts loads the prompts run n of st recorded in logsDir,
+// refusing a run that recorded none.
+func recordedPrompts(st *state.State, logsDir string, n int) ([]loop.PromptRecord, error) {
+	if n < 1 || n > len(st.Runs) {
+		return nil, fmt.Errorf("no run #%d; ralph status --history lists %d", n, len(st.Runs))
+	}
+	records, err := loop.LoadPromptRecords(logsDir, st.Runs[n-1].StartedAt.Format(logfile.TimeLayout))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already names the prompt
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("run #%d has no recorded prompts to replay; runs record them in logs/ as they go", n)
+	}
+	return records, nil
+}
+
+// syncCmd pushes what offline runs committed locally: the branch, then any
+// additional repos, updates the issues the specs were imported from, and
+// opens the plan pull request when plan review is on and there isn't one yet.
+// Issues that can't be updated only warn, so plan review still goes ahead.
+func syncCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: "Push commits made by offline runs and sync plan progress to linked issues",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			w := cmd.OutOrStdout()
+			theme := ui.DefaultTheme()
+			repo, cfg, err := openRepo(ctx)
+			if err != nil {
+				return err
+			}
+			ctx = git.WithTimeouts(ctx, gitTimeouts(cfg))
+			branch, err := repo.Branch(ctx)
+			if err != nil {
+				return fmt.Errorf("getting current branch: %w", err)
+			}
+			if err := syncBranch(ctx, w, theme, repo, branch); err != nil {
+				return err
+			}
+			for _, dir := range cfg.AdditionalDirs {
+				if err := syncAdditionalDir(ctx, w, theme, dir, branch); err != nil {
+					return err
+				}
+			}
+			// The issues follow the plan, which was made from the plan
+			// phase's specs.
+			p := &runParams{
+				branch: branch, repoRoot: repo.Root(), repo: repo,
+				planFile: cfg.PlanPathForBranch(git.SanitizeBranch(branch)),
+				specsDir: cfg.SpecsDirForPhase(string(loop.ModePlan), git.SanitizeBranch(branch)),
+			}
+			warnSyncIssues(ctx, w, theme, p)
+			if !cfg.GitHub.PlanReview {
+				return nil
+			}
+			if _, err := os.Stat(filepath.Join(p.repoRoot, p.planFile)); err != nil {
+				return nil //nolint:nilerr // no plan yet, nothing to review
+			}
+			client, err := planReviewClient(ctx, p)
+			if err != nil {
+				return err
+			}
+			_, err = client.Check(ctx, branch)
+			if err == nil {
+				return nil // already up for review
+			}
+			if !errors.Is(err, planreview.ErrNoPR) {
+				return fmt.Errorf("checking plan pull request: %w", err)
+			}
+			return requestPlanReview(ctx, w, theme, p)
+		},
+	}
+}
+
+// snapshotCmd saves and restores the whole workspace, so a bad run can be
+// rolled back in one step.
+func snapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save the workspace, or roll it back to a saved snapshot",
+		Long: "A snapshot records the branch head, uncommitted changes and untracked files (not ignored ones)\n" +
+			"inside the git directory. With safety.snapshot: true one is taken before every run. The newest\n" +
+			fmt.Sprintf("%d are kept.", snapshot.DefaultKeep),
+	}
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Save the workspace now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			root, err := snapshotRepo(cmd.Context())
+			if err != nil {
+				return err
+			}
+			reason, err := cmd.Flags().GetString("reason")
+			if err != nil {
+				return fmt.Errorf("reading --reason flag: %w", err)
+			}
+			s, err := snapshot.Create(cmd.Context(), root, reason, time.Now(), snapshot.DefaultKeep)
+			if err != nil {
+				return err //nolint:wrapcheck // snapshot errors already have context
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s Saved snapshot %s\n", ui.DefaultTheme().Success.Render("✓"), s) //nolint:errcheck // display-only
+			return nil
+		},
+	}
+	createCmd.Flags().String("reason", "", "note recorded with the snapshot")
+	cmd.AddCommand(createCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List saved snapshots, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			root, err := snapshotRepo(cmd.Context())
+			if err != nil {
+				return err
+			}
+			all, err := snapshot.List(cmd.Context(), root)
+			if err != nil {
+				return err //nolint:wrapcheck // snapshot errors already have context
+			}
+			w := cmd.OutOrStdout()
+			if len(all) == 0 {
+				fmt.Fprintln(w, ui.DefaultTheme().Muted.Render("No snapshots.")) //nolint:errcheck // display-only
+			}
+			for i := range all {
+				fmt.Fprintln(w, all[i].String()) //nolint:errcheck // display-only
+			}
+			return nil
+		},
+	})
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore [id]",
+		Short: "Roll the workspace back to a snapshot (default: the newest)",
+		Long: "Resets the snapshot's branch to the commit it was at, discards uncommitted changes and untracked\n" +
+			"files, then puts back the ones the snapshot saved. Ignored files are left alone, and commits\n" +
+			"already pushed stay on the remote.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: runSnapshotRestore,
+	}
+	restoreCmd.Flags().BoolP("yes", "y", false, "don't ask for confirmation")
+	cmd.AddCommand(restoreCmd)
+	return cmd
+}
+
+// snapshotRepo returns the root of the git repo snapshots are taken of.
+func snapshotRepo(ctx context.Context) (string, error) {
+	repo, err := vcs.Open(ctx, vcs.KindAuto)
+	if err != nil {
+		return "", fmt.Errorf("finding repo root: %w", err)
+	}
+	if repo.Kind() != vcs.KindGit {
+		return "", fmt.Errorf("snapshots need a git repo, not %s", repo.Kind())
+	}
+	return repo.Root(), nil
+}
+
+// runSnapshotRestore rolls the workspace back after confirming, since
+// everything done since the snapshot is discarded.
+//
+//nolint:errcheck // display-only writes to terminal
+func runSnapshotRestore(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	w := cmd.OutOrStdout()
+	theme := ui.DefaultTheme()
+	root, err := snapshotRepo(ctx)
+	if err != nil {
+		return err
+	}
+	id := ""
+	if len(args) == 1 {
+		id = args[0]
+	}
+	s, err := snapshot.Find(ctx, root, id)
+	if err != nil {
+		return err //nolint:wrapcheck // snapshot errors already have context
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("reading --yes flag: %w", err)
+	}
+
+	fmt.Fprintf(w, "Restoring %s\n", s)
+	fmt.Fprintln(w, theme.Warning.Render("Commits, uncommitted changes and untracked files since then are discarded."))
+	if !yes {
+		refused := errors.New("not restoring; pass --yes to restore without asking")
+		f, isFile := cmd.InOrStdin().(*os.File)
+		if isFile && !term.IsTerminal(int(f.Fd())) { //nolint:gosec // file descriptors fit in an int
+			return refused
+		}
+		ok := false
+		form := huh.NewForm(huh.NewGroup(
+			huh.NewConfirm().
+				Title("Restore the snapshot?").
+				Value(&ok),
+		)).WithAccessible(!isFile).
+			WithTheme(ui.HuhTheme()).
+			WithInput(cmd.InOrStdin()).
+			WithOutput(w)
+		if err := form.Run(); err != nil {
+			return fmt.Errorf("prompting to restore: %w", err)
+		}
+		if !ok {
+			return refused
+		}
+	}
+	if err := snapshot.Restore(ctx, root, s); err != nil {
+		return err //nolint:wrapcheck // snapshot errors already have context
+	}
+	fmt.Fprintf(w, "%s Restored snapshot %s\n", theme.Success.Render("✓"), s.ID)
+	if s.Branch != "" {
+		fmt.Fprintln(w, theme.Muted.Render("  If the run pushed, roll origin back with: git push --force-with-lease origin "+s.Branch))
+	}
+	return nil
+}
+
+// syncBranch pushes branch, creating it on the remote if needed.
+//
+//nolint:errcheck // display-only writes to terminal
+func syncBranch(ctx context.Context, w io.Writer, theme *ui.Theme, repo vcs.VCS, branch string) error {
+	exists, err := repo.BranchExistsOnRemote(ctx, branch)
+	if err != nil {
+		return fmt.Errorf("checking remote branch: %w", err)
+	}
+	if !exists {
+		if err := repo.PushSetUpstream(ctx, branch); err != nil {
+			return fmt.Errorf("pushing %s: %w", branch, err)
+		}
+		fmt.Fprintf(w, "  %s Pushed %s to origin\n", theme.Success.Render("✓"), branch)
+		return nil
+	}
+	n, err := repo.UnpushedCount(ctx, branch)
+	if err != nil {
+		return fmt.Errorf("counting unpushed commits: %w", err)
+	}
+	if n == 0 {
+		fmt.Fprintf(w, "  %s %s is up to date\n", theme.Success.Render("✓"), branch)
+		return nil
+	}
+	if err := repo.Push(ctx, branch); err != nil {
+		return fmt.Errorf("pushing %s: %w", branch, err)
+	}
+	fmt.Fprintf(w, "  %s Pushed %d commit(s) to %s\n", theme.Success.Render("✓"), n, branch)
+	return nil
+}
+
+// syncAdditionalDir pushes branch in an additional repo.
+//
+//nolint:errcheck // display-only writes to terminal
+func syncAdditionalDir(ctx context.Context, w io.Writer, theme *ui.Theme, dir, branch string) error {
+	exists, err := git.BranchExistsOnRemoteIn(ctx, dir, branch)
+	if err != nil {
+		return fmt.Errorf("checking remote branch in %s: %w", filepath.Base(dir), err)
+	}
+	push := git.PushIn
+	if !exists {
+		push = git.PushSetUpstreamIn
+	}
+	if err := push(ctx, dir, branch); err != nil {
+		return fmt.Errorf("pushing %s in %s: %w", branch, filepath.Base(dir), err)
+	}
+	fmt.Fprintf(w, "  %s Pushed %s in %s\n", theme.Success.Render("✓"), branch, filepath.Base(dir))
+	return nil
+}
+
+func statusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "status",
+		Aliases: []string{"st"},
+		Short:   "Progress summary — tasks done, costs, pass/fail",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			history, err := cmd.Flags().GetBool("history")
+			if err != nil {
+				return fmt.Errorf("reading --history flag: %w", err)
+			}
+			tag, err := cmd.Flags().GetString("tag")
+			if err != nil {
+				return fmt.Errorf("reading --tag flag: %w", err)
+			}
+			badge, err := cmd.Flags().GetBool("badge")
+			if err != nil {
+				return fmt.Errorf("reading --badge flag: %w", err)
+			}
+			allBranches, err := cmd.Flags().GetBool("all-branches")
+			if err != nil {
+				return fmt.Errorf("reading --all-branches flag: %w", err)
+			}
+			if allBranches && (history || tag != "" || badge) {
+				return fmt.Errorf("--all-branches can't be combined with --history, --tag or --badge")
+			}
+
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+
+			st, err := state.Load(filepath.Join(repoRoot, state.DefaultPath))
+			if err != nil {
+				return fmt.Errorf("loading state: %w", err)
+			}
+
+			if history || tag != "" {
+				status.RenderHistory(cmd.OutOrStdout(), st.RunsWithTag(tag), cfg.Cost.Display(), ui.DefaultTheme())
+				return nil
+			}
+
+			branch, err := git.Branch(ctx)
+			if err != nil {
+				return fmt.Errorf("getting branch: %w", err)
+			}
+
+			if allBranches {
+				plans, err := status.CollectPlans(repoRoot, cfg.PlansDir(), st.Runs)
+				if err != nil {
+					return err //nolint:wrapcheck // already wrapped by status
+				}
+				status.RenderPortfolio(cmd.OutOrStdout(), cfg.Project, cfg.PlansDir(), plans, branch, cfg.Cost.Display(), ui.DefaultTheme())
+				return nil
+			}
+
+			planPath := cfg.PlanPathForBranch(git.SanitizeBranch(branch))
+
+			tasks, err := status.ParsePlan(planPath)
+			if err != nil {
+				return fmt.Errorf("parsing plan: %w", err)
+			}
+
+			runs, err := status.ParseLogs(".ralph/logs", cfg.Cost.Table())
+			if err != nil {
+				return fmt.Errorf("parsing logs: %w", err)
+			}
+			status.AttachModes(runs, st.Runs)
+
+			if badge {
+				data, err := status.NewBadge(tasks, runs, cfg.Cost.Display()).JSON()
+				if err != nil {
+					return err //nolint:wrapcheck // already wrapped by status
+				}
+				if _, err := cmd.OutOrStdout().Write(data); err != nil {
+					return fmt.Errorf("writing badge: %w", err)
+				}
+				return nil
+			}
+
+			status.Render(cmd.OutOrStdout(), cfg.Project, branch, tasks, runs, st.LastRun(), cfg.Cost.Display(), ui.DefaultTheme())
+			return nil
+		},
+	}
+	cmd.Flags().Bool("history", false, "list every recorded run instead of the summary")
+	cmd.Flags().String("tag", "", "only list runs with this tag (implies --history)")
+	cmd.Flags().Bool("badge", false, "print a shields.io endpoint JSON badge (tasks complete, cost) instead of the summary")
+	cmd.Flags().Bool("all-branches", false, "show every plan in the plans directory, e.g. those a release branch merged in, with each branch's progress and cost")
+	return cmd
+}
+
+// stateCmd groups commands that work with the recorded run history.
+func stateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Work with the run history in " + state.DefaultPath,
+	}
+	cmd.AddCommand(stateExportCmd())
+	return cmd
+}
+
+// configCmd groups commands that check and explain .ralph/config.yaml.
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Check and inspect .ralph/config.yaml",
+	}
+	cmd.AddCommand(configValidateCmd(), configShowCmd())
+	return cmd
+}
+
+// configValidateCmd reports every problem in the config with its line, and
+// warns about keys ralph ignores.
+func configValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Report config errors with line numbers and warn about unknown keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			w := cmd.OutOrStdout()
+			theme := ui.DefaultTheme()
+			repoRoot, err := git.RepoRoot(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			errs, warnings, err := config.Check(repoRoot)
+			if err != nil {
+				return err //nolint:wrapcheck // already says it was reading the config
+			}
+			for _, issue := range warnings {
+				fmt.Fprintf(w, "%s %s\n", theme.Warning.Render("warning:"), configIssue(issue)) //nolint:errcheck // display-only
+			}
+			for _, issue := range errs {
+				fmt.Fprintf(w, "%s %s\n", theme.Error.Render("error:"), configIssue(issue)) //nolint:errcheck // display-only
+			}
+			if len(errs) > 0 {
+				return fmt.Errorf(".ralph/config.yaml has %d error(s)", len(errs))
+			}
+			fmt.Fprintf(w, "%s .ralph/config.yaml is valid\n", theme.Success.Render("✓")) //nolint:errcheck // display-only
+			return nil
+		},
+	}
+}
+
+// configIssue formats issue as "file:line: message", like a compiler.
+func configIssue(issue config.Issue) string {
+	if issue.Line == 0 {
+		return ".ralph/config.yaml: " + issue.Message
+	}
+	return fmt.Sprintf(".ralph/config.yaml:%d: %s", issue.Line, issue.Message)
+}
+
+// configShowCmd prints the config as written, or with --effective as ralph
+// uses it, defaults filled in.
+func configShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the config; --effective fills in every default",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			effective, err := cmd.Flags().GetBool("effective")
+			if err != nil {
+				return fmt.Errorf("reading --effective flag: %w", err)
+			}
+			repoRoot, err := git.RepoRoot(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return fmt.Errorf("loading config (ralph config validate lists every problem): %w", err)
+			}
+			out, err := os.ReadFile(filepath.Join(repoRoot, ".ralph", "config.yaml"))
+			if err != nil {
+				return fmt.Errorf("reading config: %w", err)
+			}
+			if effective {
+				if out, err = cfg.Effective(); err != nil {
+					return err //nolint:wrapcheck // already says it was encoding the config
+				}
+			}
+			_, err = cmd.OutOrStdout().Write(out)
+			return err //nolint:wrapcheck // stdout
+		},
+	}
+	cmd.Flags().Bool("effective", false, "print the config ralph runs with, every default filled in")
+	return cmd
+}
+
+func stateExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export runs and iterations to a SQLite database for querying",
+		Long: "Write every recorded run and its iterations into a SQLite database, replacing\n" +
+			"the tables of a previous export, so the history can be queried with SQL.\n" +
+			"Needs the sqlite3 shell on PATH; --sql prints the script instead.\n" +
+			"Set state_db in .ralph/config.yaml to refresh the database after every run.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dbPath, err := cmd.Flags().GetString("sqlite")
+			if err != nil {
+				return fmt.Errorf("reading --sqlite flag: %w", err)
+			}
+			sql, err := cmd.Flags().GetBool("sql")
+			if err != nil {
+				return fmt.Errorf("reading --sql flag: %w", err)
+			}
+
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			if sql {
+				return exportState(ctx, repoRoot, "", cmd.OutOrStdout())
+			}
+			if dbPath == "" {
+				cfg, err := config.Load(repoRoot)
+				if err != nil {
+					return fmt.Errorf("loading config: %w", err)
+				}
+				dbPath = cfg.StateDB
+			}
+			if dbPath == "" {
+				return errors.New("pass --sqlite <path>, set state_db in .ralph/config.yaml, or use --sql")
+			}
+			if err := exportState(ctx, repoRoot, dbPath, nil); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s Exported run history to %s\n", ui.DefaultTheme().Success.Render("✓"), dbPath) //nolint:errcheck // display-only
+			return nil
+		},
+	}
+	cmd.Flags().String("sqlite", "", "SQLite database to write, e.g. .ralph/ralph.db (default: state_db from config)")
+	cmd.Flags().Bool("sql", false, "print the SQL script to stdout instead of running sqlite3")
+	return cmd
+}
+
+// exportState writes the repo's run history, with per-iteration figures from
+// the log index, to the SQLite database at dbPath (relative to the repo
+// root), or as a SQL script to sql when it is set.
+func exportState(ctx context.Context, repoRoot, dbPath string, sql io.Writer) error {
+	st, err := state.Load(filepath.Join(repoRoot, state.DefaultPath))
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+	logsDir := filepath.Join(repoRoot, ".ralph", "logs")
+	if _, err := status.ParseLogs(logsDir, costRates(repoRoot)); err != nil { // brings the index up to date
+		return fmt.Errorf("parsing logs: %w", err)
+	}
+	logs := status.LoadIndex(logsDir).Logs
+
+	if sql != nil {
+		statedb.Write(sql, st, logs)
+		return nil
+	}
+	if !filepath.IsAbs(dbPath) {
+		dbPath = filepath.Join(repoRoot, dbPath)
+	}
+	return statedb.Export(ctx, dbPath, st, logs) //nolint:wrapcheck // already wrapped by statedb
+}
+
+// swarmStopGrace is how long a swarm member's loop gets to stop its
+// container and record its run after an interrupt.
+const swarmStopGrace = 30 * time.Second
+
+// swarmCmd runs plan or build loops for several branches at once, each in a
+// worktree beside the repo, and shows their combined progress.
+func swarmCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "swarm",
+		Short: "Run plan or build loops for several branches at once",
+		Long: "Run plan or build loops for several branches at once. Each branch gets a git worktree\n" +
+			"in <repo>-swarm/ beside the repo and its own ralph process and container; output goes\n" +
+			"to " + swarm.LogDir + "/<branch>.log. `ralph swarm status` sums up their tasks and cost.",
+	}
+	cmd.AddCommand(swarmRunCmd(loop.ModePlan), swarmRunCmd(loop.ModeBuild), swarmStatusCmd(), swarmCleanCmd())
+	return cmd
+}
+
+func swarmRunCmd(mode loop.Mode) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   string(mode) + " [branch...]",
+		Short: fmt.Sprintf("Run %s loops for several branches concurrently", mode),
+		Long: fmt.Sprintf("Run ralph %s in a worktree for each branch, creating branches that don't exist\n"+
+			"from HEAD. --specs adds a branch for each spec group: each directory under specs_dir\n"+
+			"holding a .md spec. Worktrees only get committed files, so commit .ralph/ and the specs\n"+
+			"first; .env and .ralph/profiles.yaml are copied in.", mode),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			maxIter, err := cmd.Flags().GetInt("max")
+			if err != nil {
+				return fmt.Errorf("reading --max flag: %w", err)
+			}
+			parallel, err := cmd.Flags().GetInt("parallel")
+			if err != nil {
+				return fmt.Errorf("reading --parallel flag: %w", err)
+			}
+			fromSpecs, err := cmd.Flags().GetBool("specs")
+			if err != nil {
+				return fmt.Errorf("reading --specs flag: %w", err)
+			}
+
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			branches := args
+			if fromSpecs {
+				groups, err := specGroups(repoRoot, cfg, mode)
+				if err != nil {
+					return err
+				}
+				branches = append(branches, groups...)
+			}
+			if len(branches) == 0 {
+				return fmt.Errorf("no branches to run: name them, or pass --specs to use one per spec group")
+			}
+			for _, b := range branches {
+				if git.IsProtectedBranch(b, cfg.ProtectedBranches) {
+					return fmt.Errorf("ralph swarm won't run on protected branch %q", b)
+				}
+			}
+			tracked, err := git.IsTracked(ctx, ".ralph/config.yaml")
+			if err != nil {
+				return fmt.Errorf("checking .ralph/config.yaml: %w", err)
+			}
+			if !tracked {
+				return fmt.Errorf("commit .ralph/ first: worktrees only get committed files (ralph plan commits it on its first run)")
+			}
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("locating the ralph binary: %w", err)
+			}
+
+			manifestPath := filepath.Join(repoRoot, swarm.ManifestPath)
+			man, err := swarm.Load(manifestPath)
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by swarm
+			}
+			members := make([]*swarm.Member, 0, len(branches))
+			for _, b := range branches {
+				path, err := swarm.Worktree(ctx, repoRoot, b)
+				if err != nil {
+					return err //nolint:wrapcheck // already wrapped by swarm
+				}
+				members = append(members, &swarm.Member{
+					Branch: b, Path: path, Mode: string(mode),
+					Log: filepath.Join(repoRoot, swarm.LogDir, git.SanitizeBranch(b)+".log"),
+				})
+			}
+
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+			w := cmd.OutOrStdout()
+			theme := ui.DefaultTheme()
+			var saveErr error
+			failed := 0
+			launch := func(ctx context.Context, m *swarm.Member, out io.Writer) error {
+				return runSwarmMember(ctx, exe, m, maxIter, out)
+			}
+			swarm.Run(ctx, members, parallel, nil, launch, func(m *swarm.Member) {
+				man.Put(m)
+				if err := man.Save(manifestPath); err != nil && saveErr == nil {
+					saveErr = err
+				}
+				switch {
+				case m.Finished.IsZero():
+					fmt.Fprintf(w, "%s %s  %s\n", theme.Info.Render("▶"), m.Branch, theme.Muted.Render(m.Path)) //nolint:errcheck // display-only
+				case m.Error != "":
+					failed++
+					fmt.Fprintf(w, "%s %s  %s\n", theme.Error.Render("✗"), m.Branch, theme.Muted.Render(m.Error)) //nolint:errcheck // display-only
+				default:
+					fmt.Fprintf(w, "%s %s\n", theme.Success.Render("✓"), m.Branch) //nolint:errcheck // display-only
+				}
+			})
+			if saveErr != nil {
+				return saveErr
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d swarm branches failed; see ralph swarm status and %s", failed, len(members), swarm.LogDir)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntP("max", "n", 0, "maximum iterations per branch (0 = use config default)")
+	cmd.Flags().IntP("parallel", "p", 0, "most loops running at once (0 = all)")
+	cmd.Flags().Bool("specs", false, "add a branch for each spec group under specs_dir")
+	return cmd
+}
+
+// runSwarmMember runs ralph plan or build in m's worktree. On interrupt the
+// child gets SIGINT rather than SIGKILL, so it can stop its container and
+// record its run.
+func runSwarmMember(ctx context.Context, exe string, m *swarm.Member, maxIter int, out io.Writer) error {
+	args := []string{m.Mode}
+	if maxIter > 0 {
+		args = append(args, "-n", strconv.Itoa(maxIter))
+	}
+	c := exec.CommandContext(ctx, exe, args...) //nolint:gosec // ralph re-running itself
+	c.Dir = m.Path
+	c.Env = append(os.Environ(), swarm.MemberEnv+"=1")
+	c.Stdout, c.Stderr = out, out
+	c.Cancel = func() error { return c.Process.Signal(os.Interrupt) }
+	c.WaitDelay = swarmStopGrace
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("ralph %s: %w", m.Mode, err)
+	}
+	return nil
+}
+
+// specGroups returns the spec groups under the mode's specs directory: each
+// subdirectory holding a .md spec, named as the branch it is for.
+func specGroups(repoRoot string, cfg *config.Config, mode loop.Mode) ([]string, error) {
+	if cfg.SpecsDirExact {
+		return nil, fmt.Errorf("--specs needs per-branch spec directories, but specs_dir_exact is set")
+	}
+	base := cfg.SpecsRootForPhase(string(mode))
+	entries, err := os.ReadDir(filepath.Join(repoRoot, base))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", base, err)
+	}
+	var groups []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		specs, err := filepath.Glob(filepath.Join(repoRoot, base, e.Name(), "*.md"))
+		if err == nil && len(specs) > 0 {
+			groups = append(groups, e.Name())
+		}
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no spec groups in %s: expected a directory of .md specs per branch", base)
+	}
+	return groups, nil
+}
+
+func swarmStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show each swarm branch's tasks, runs and cost, with totals",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			man, err := swarm.Load(filepath.Join(repoRoot, swarm.ManifestPath))
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by swarm
+			}
+			sums := make([]swarm.Summary, 0, len(man.Members))
+			for i := range man.Members {
+				s, err := swarm.Summarize(&man.Members[i], cfg)
+				if err != nil {
+					return err //nolint:wrapcheck // already wrapped by swarm
+				}
+				sums = append(sums, s)
+			}
+			swarm.Render(cmd.OutOrStdout(), sums, cfg.Cost.Display(), ui.DefaultTheme())
+			return nil
+		},
+	}
+}
+
+func swarmCleanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove the swarm's worktrees (branches and their commits are kept)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				return fmt.Errorf("reading --force flag: %w", err)
+			}
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			manifestPath := filepath.Join(repoRoot, swarm.ManifestPath)
+			man, err := swarm.Load(manifestPath)
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by swarm
+			}
+			w := cmd.OutOrStdout()
+			var kept []swarm.Member
+			for _, m := range man.Members {
+				if !m.Started.IsZero() && m.Finished.IsZero() && !force {
+					fmt.Fprintf(w, "Keeping %s: its loop is still running (--force removes it anyway)\n", m.Branch) //nolint:errcheck // display-only
+					kept = append(kept, m)
+					continue
+				}
+				if err := git.RemoveWorktreeIn(ctx, repoRoot, m.Path, force); err != nil {
+					return fmt.Errorf("removing worktree for %s: %w", m.Branch, err)
+				}
+				fmt.Fprintf(w, "Removed %s\n", m.Path) //nolint:errcheck // display-only
+			}
+			man.Members = kept
+			return man.Save(manifestPath) //nolint:wrapcheck // already wrapped by swarm
+		},
+	}
+	cmd.Flags().Bool("force", false, "remove worktrees with uncommitted changes or a loop still running")
+	return cmd
+}
+
+// followPoll is how often ralph logs --follow checks for new output.
+const followPoll = 250 * time.Millisecond
+
+// logsCmd lists the iteration logs, re-renders one as it was shown live, or
+// follows the logs of a run in progress.
+func logsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs [log]",
+		Short: "List, replay or follow iteration logs",
+		Long: "List the iteration logs in .ralph/logs with their start time, cost and the run\n" +
+			"iteration that wrote them (runs are numbered as in `ralph status --history`).\n" +
+			"--replay renders a log as it was shown while the iteration ran; log is a file name\n" +
+			"from the list, and the newest log is used when it is omitted. --follow renders the\n" +
+			"newest log as it is written and moves on to each new iteration until interrupted.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			last, err := cmd.Flags().GetInt("last")
+			if err != nil {
+				return fmt.Errorf("reading --last flag: %w", err)
+			}
+			follow, err := cmd.Flags().GetBool("follow")
+			if err != nil {
+				return fmt.Errorf("reading --follow flag: %w", err)
+			}
+			replay, err := cmd.Flags().GetBool("replay")
+			if err != nil {
+				return fmt.Errorf("reading --replay flag: %w", err)
+			}
+			if follow && (replay || len(args) > 0) {
+				return fmt.Errorf("--follow always follows the newest log; it can't be combined with --replay or a log name")
+			}
+			if len(args) > 0 && !replay {
+				return fmt.Errorf("pass --replay to render %s", args[0])
+			}
+
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			logsDir := filepath.Join(repoRoot, ".ralph", "logs")
+			theme := ui.DefaultTheme()
+			if follow {
+				ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+				defer stop()
+				return logview.Follow(ctx, logsDir, cmd.OutOrStdout(), theme, followPoll) //nolint:wrapcheck // already wrapped by logview
+			}
+
+			st, err := state.Load(filepath.Join(repoRoot, state.DefaultPath))
+			if err != nil {
+				return fmt.Errorf("loading state: %w", err)
+			}
+			// The list works without a config; costs are then shown in dollars.
+			var cur pricing.Currency
+			rates := pricing.Default
+			if cfg, err := config.Load(repoRoot); err == nil {
+				cur, rates = cfg.Cost.Display(), cfg.Cost.Table()
+			}
+			entries, err := logview.List(logsDir, st.Runs, rates)
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by logview
+			}
+			if replay {
+				var name string
+				if len(args) > 0 {
+					name = args[0]
+				}
+				path, err := logview.Find(entries, name)
+				if err != nil {
+					return err //nolint:wrapcheck // already wrapped by logview
+				}
+				return logview.Replay(path, cmd.OutOrStdout(), theme) //nolint:wrapcheck // already wrapped by logview
+			}
+
+			if last > 0 && len(entries) > last {
+				entries = entries[len(entries)-last:]
+			}
+			logview.Render(cmd.OutOrStdout(), entries, cur, theme)
+			return nil
+		},
+	}
+	cmd.Flags().IntP("last", "n", 0, "list only the N newest logs")
+	cmd.Flags().BoolP("follow", "f", false, "render the newest log as it is written, then each new iteration's")
+	cmd.Flags().Bool("replay", false, "render a log (default: the newest) as it was shown live")
+	return cmd
+}
+
+func overviewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "overview",
+		Short: "Show every ralph run on this machine",
+		Long: "Show the status of every plan and build run on this machine, across repos: its\n" +
+			"project, branch, iteration, cost in dollars and running time. Each run publishes\n" +
+			"events to ~/.ralph/events ($RALPH_EVENTS_DIR overrides it). --all also lists runs\n" +
+			"that have ended in the last day; --follow prints each new event until interrupted.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			all, err := cmd.Flags().GetBool("all")
+			if err != nil {
+				return fmt.Errorf("reading --all flag: %w", err)
+			}
+			follow, err := cmd.Flags().GetBool("follow")
+			if err != nil {
+				return fmt.Errorf("reading --follow flag: %w", err)
+			}
+			dir, err := events.Dir()
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by events
+			}
+			theme := ui.DefaultTheme()
+			if follow {
+				ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+				defer stop()
+				return events.Follow(ctx, dir, cmd.OutOrStdout(), theme, followPoll) //nolint:wrapcheck // already wrapped by events
+			}
+
+			runs, err := events.Load(dir)
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by events
+			}
+			if !all {
+				runs = slices.DeleteFunc(runs, func(r events.Run) bool { return !r.Active() })
+			}
+			events.Render(cmd.OutOrStdout(), runs, time.Now(), theme)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("all", false, "include runs that have ended")
+	cmd.Flags().BoolP("follow", "f", false, "print each new event from any run until interrupted")
+	return cmd
+}
+
+// doctorCmd checks that this machine and repo can run ralph.
+func doctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that this machine and repo are ready to run ralph",
+		Long: "Check git and the repo, the config and prompt files, credentials in .env, the docker\n" +
+			"daemon, the ralph image and the agent CLI inside it. Each problem comes with a hint on\n" +
+			"fixing it; the command fails if any check does.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("getting working directory: %w", err)
+			}
+			reports := doctor.Run(cmd.Context(), doctor.NewEnv(dir), doctor.Checks())
+			doctor.Render(cmd.OutOrStdout(), reports, ui.DefaultTheme())
+			if n := doctor.Failed(reports); n > 0 {
+				return fmt.Errorf("%d check(s) failed; see the hints above", n)
+			}
+			return nil
+		},
+	}
+}
+
+// schedulePoll is how often ralph schedule run checks for due schedules.
+const schedulePoll = 30 * time.Second
+
+// scheduleCmd manages recurring runs and the daemon that starts them.
+func scheduleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Run ralph commands at times given by cron expressions",
+		Long: "Run ralph commands at times given by cron expressions, e.g. a build every weeknight.\n" +
+			"Schedules are kept in " + schedule.Path + "; `ralph schedule run` starts them when they\n" +
+			"are due, one at a time, with output in " + schedule.LogDir + "/.",
+	}
+	cmd.AddCommand(scheduleAddCmd(), scheduleListCmd(), scheduleRemoveCmd(), scheduleRunCmd())
+	return cmd
+}
+
+func scheduleAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add <cron> <command> [flags...]",
+		Short: "Schedule a ralph command",
+		Long: "Schedule a ralph command (" + strings.Join(schedule.Commands, ", ") + ") with its flags, e.g.\n" +
+			"  ralph schedule add \"0 22 * * 1-5\" build --max 10\n" +
+			"The cron expression has five fields: minute, hour, day of month, month and day of\n" +
+			"week, in local time. Macros such as @daily and @hourly are accepted too.",
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := git.RepoRoot(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			path := filepath.Join(repoRoot, schedule.Path)
+			f, err := schedule.Load(path)
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by schedule
+			}
+			s, err := f.Add(args[0], args[1:], time.Now())
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by schedule
+			}
+			if err := f.Save(path); err != nil {
+				return err //nolint:wrapcheck // already wrapped by schedule
+			}
+			theme := ui.DefaultTheme()
+			fmt.Fprintf(cmd.OutOrStdout(), "%s schedule %d: ralph %s, next at %s\n", //nolint:errcheck // display-only
+				theme.Success.Render("✓"), s.ID, strings.Join(s.Args, " "), s.Next(time.Now()).Format(schedule.TimeLayout))
+			fmt.Fprintln(cmd.OutOrStdout(), theme.Muted.Render("Start ralph schedule run to have it run.")) //nolint:errcheck // display-only
+			return nil
+		},
+	}
+	// Flags after the command belong to it, not to add.
+	cmd.Flags().SetInterspersed(false)
+	return cmd
+}
+
+func scheduleListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List schedules with their next and last runs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			repoRoot, err := git.RepoRoot(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			f, err := schedule.Load(filepath.Join(repoRoot, schedule.Path))
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by schedule
+			}
+			schedule.Render(cmd.OutOrStdout(), f, time.Now(), ui.DefaultTheme())
+			return nil
+		},
+	}
+}
+
+func scheduleRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <id>",
+		Short: "Remove a schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("schedule id %q is not a number", args[0])
+			}
+			repoRoot, err := git.RepoRoot(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			path := filepath.Join(repoRoot, schedule.Path)
+			f, err := schedule.Load(path)
+			if err != nil {
+				return err //nolint:wrapcheck // already wrapped by schedule
+			}
+			if !f.Remove(id) {
+				return fmt.Errorf("no schedule %d; see ralph schedule list", id)
+			}
+			if err := f.Save(path); err != nil {
+				return err //nolint:wrapcheck // already wrapped by schedule
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed schedule %d\n", id) //nolint:errcheck // display-only
+			return nil
+		},
+	}
+}
+
+func scheduleRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Start scheduled runs when they are due, until interrupted",
+		Long: "Start scheduled runs when they are due, one at a time, until interrupted. Each\n" +
+			"result is printed; --notify also runs a shell command after each run, with\n" +
+			"RALPH_SCHEDULE_ID, RALPH_SCHEDULE_COMMAND, RALPH_SCHEDULE_STATUS (ok or failed),\n" +
+			"RALPH_SCHEDULE_ERROR and RALPH_SCHEDULE_LOG set. Runs missed while the daemon was\n" +
+			"down are not caught up.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			notify, err := cmd.Flags().GetString("notify")
+			if err != nil {
+				return fmt.Errorf("reading --notify flag: %w", err)
+			}
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("locating the ralph binary: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+			w := cmd.OutOrStdout()
+			theme := ui.DefaultTheme()
+			d := &schedule.Daemon{
+				Path:   filepath.Join(repoRoot, schedule.Path),
+				LogDir: filepath.Join(repoRoot, schedule.LogDir),
+				Launch: func(ctx context.Context, s *schedule.Schedule, out io.Writer) error {
+					fmt.Fprintf(w, "%s schedule %d: ralph %s\n", theme.Info.Render("▶"), s.ID, strings.Join(s.Args, " ")) //nolint:errcheck // display-only
+					return runScheduled(ctx, exe, repoRoot, s, out)
+				},
+				Done: func(r *schedule.Result) {
+					if r.Err != nil {
+						fmt.Fprintf(w, "%s schedule %d: %s  %s\n", theme.Error.Render("✗"), r.Schedule.ID, r.Err, theme.Muted.Render(r.Log)) //nolint:errcheck // display-only
+					} else {
+						fmt.Fprintf(w, "%s schedule %d  %s\n", theme.Success.Render("✓"), r.Schedule.ID, theme.Muted.Render(r.Log)) //nolint:errcheck // display-only
+					}
+					if notify == "" {
+						return
+					}
+					if err := schedule.Notify(ctx, notify, r, w); err != nil {
+						fmt.Fprintln(w, theme.Warning.Render(err.Error())) //nolint:errcheck // display-only
+					}
+				},
+				Warn: func(err error) {
+					fmt.Fprintln(w, theme.Warning.Render(err.Error())) //nolint:errcheck // display-only
+				},
+			}
+			fmt.Fprintln(w, theme.Muted.Render("Waiting for scheduled runs; press Ctrl+C to stop.")) //nolint:errcheck // display-only
+
+			d.Run(ctx, schedulePoll)
+			return nil
+		},
+	}
+	cmd.Flags().String("notify", "", "shell command to run after each scheduled run")
+	return cmd
+}
+
+// runScheduled runs s's ralph command in the repo. Like a swarm member, the
+// child gets SIGINT on interrupt so it can stop its container.
+func runScheduled(ctx context.Context, exe, repoRoot string, s *schedule.Schedule, out io.Writer) error {
+	c := exec.CommandContext(ctx, exe, s.Args...) //nolint:gosec // ralph re-running itself
+	c.Dir = repoRoot
+	c.Stdout, c.Stderr = out, out
+	c.Cancel = func() error { return c.Process.Signal(os.Interrupt) }
+	c.WaitDelay = swarmStopGrace
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("ralph %s: %w", s.Args[0], err)
+	}
+	return nil
+}
+
+// compareCmd aggregates recorded runs side by side. Experiment variants are
+// the only grouping so far; the flag leaves room for others.
+func compareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compare",
+		Short: "Compare cost, iterations and staleness across recorded runs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			byExperiment, err := cmd.Flags().GetBool("experiment")
+			if err != nil {
+				return fmt.Errorf("reading --experiment flag: %w", err)
+			}
+			if !byExperiment {
+				return fmt.Errorf("nothing to compare: pass --experiment to group runs by experiment variant")
+			}
+
+			repoRoot, err := git.RepoRoot(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			st, err := state.Load(filepath.Join(repoRoot, state.DefaultPath))
+			if err != nil {
+				return fmt.Errorf("loading state: %w", err)
+			}
+
+			// Compare works without a config; costs are then shown in dollars.
+			var cur pricing.Currency
+			if cfg, err := config.Load(repoRoot); err == nil {
+				cur = cfg.Cost.Display()
+			}
+			status.RenderComparison(cmd.OutOrStdout(), status.CompareExperiments(st.Runs), cur, ui.DefaultTheme())
+			return nil
+		},
+	}
+	cmd.Flags().Bool("experiment", false, "group runs by experiment variant (runs without one form the baseline)")
+	return cmd
+}
+
+// reportCmd renders a recorded run for people outside the terminal, as a
+// standalone HTML page or uploaded to the share bucket with its JSON summary.
+func reportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report [run]",
+		Short: "Render a recorded run as a standalone HTML page",
+		Long: "Render a recorded run as a standalone HTML page: iteration timeline, tool calls, cost per\n" +
+			"iteration and the commits made. run is a number as listed by `ralph status --history`\n" +
+			"(1 = oldest); the latest run is used when it is omitted. --upload puts the page and a\n" +
+			"JSON summary in the bucket configured under share: and prints a link to them.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			html, err := cmd.Flags().GetBool("html")
+			if err != nil {
+				return fmt.Errorf("reading --html flag: %w", err)
+			}
+			upload, err := cmd.Flags().GetBool("upload")
+			if err != nil {
+				return fmt.Errorf("reading --upload flag: %w", err)
+			}
+			if !html && !upload {
+				return fmt.Errorf("no report format: pass --html or --upload")
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return fmt.Errorf("reading --output flag: %w", err)
+			}
+
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			// The report works without a config; costs are then shown in dollars.
+			cfg, cfgErr := config.Load(repoRoot)
+			if upload && cfgErr != nil {
+				return fmt.Errorf("loading config: %w", cfgErr)
+			}
+			r, cur, err := loadReport(ctx, repoRoot, cfg, args)
+			if err != nil {
+				return err
+			}
+
+			if upload {
+				if err := uploadReport(ctx, cmd.OutOrStdout(), repoRoot, cfg, r, cur); err != nil {
+					return err
+				}
+				if !html {
+					return nil
+				}
+			}
+			if output == "" {
+				return report.HTML(cmd.OutOrStdout(), r, cur) //nolint:wrapcheck // already wrapped by report
+			}
+			var buf bytes.Buffer
+			if err := report.HTML(&buf, r, cur); err != nil {
+				return err //nolint:wrapcheck // already wrapped by report
+			}
+			if err := os.WriteFile(output, buf.Bytes(), 0o644); err != nil { //nolint:gosec // shareable report chosen by the user
+				return fmt.Errorf("writing report: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote report for run #%d to %s\n", r.Number, output) //nolint:errcheck // display-only
+			return nil
+		},
+	}
+	cmd.Flags().Bool("html", false, "render the run as a standalone HTML page")
+	cmd.Flags().Bool("upload", false, "upload the page and a JSON summary to the share: bucket and print links")
+	cmd.Flags().StringP("output", "o", "", "write the report to this file instead of stdout")
+	return cmd
+}
+
+// loadReport gathers the run args picks, with its logs and commits, and
+// the currency to show its costs in. cfg may be nil.
+func loadReport(ctx context.Context, repoRoot string, cfg *config.Config, args []string) (*report.Report, pricing.Currency, error) {
+	var cur pricing.Currency
+	st, err := state.Load(filepath.Join(repoRoot, state.DefaultPath))
+	if err != nil {
+		return nil, cur, fmt.Errorf("loading state: %w", err)
+	}
+	n, err := selectRun(st, args)
+	if err != nil {
+		return nil, cur, err
+	}
+	run := st.Runs[n-1]
+
+	r := &report.Report{Number: n, Run: run, Generated: time.Now()}
+	if cfg != nil {
+		cur = cfg.Cost.Display()
+		r.Project = cfg.Project
+	}
+	if r.Iterations, err = report.Iterations(repoRoot, &run); err != nil {
+		return nil, cur, fmt.Errorf("reading run logs: %w", err)
+	}
+	finished := run.FinishedAt
+	if finished.IsZero() {
+		finished = time.Now()
+	}
+	if r.Commits, err = git.CommitsBetween(ctx, run.StartedAt, finished); err != nil {
+		return nil, cur, fmt.Errorf("listing the run's commits: %w", err)
+	}
+	return r, cur, nil
+}
+
+// uploadReport puts r's HTML page and JSON summary in the share bucket,
+// under prefix/project/branch/<run start time>/, and prints links to them.
+// Credentials come from the environment, then from the repo's .env.
+//
+//nolint:errcheck // display-only writes to terminal
+func uploadReport(ctx context.Context, w io.Writer, repoRoot string, cfg *config.Config, r *report.Report, cur pricing.Currency) error {
+	if !cfg.Share.Enabled() {
+		return fmt.Errorf("nowhere to upload: configure share.provider and share.bucket in .ralph/config.yaml")
+	}
+	lookup, err := hostEnv(repoRoot)
+	if err != nil {
+		return err
+	}
+	keyEnv, secretEnv := cfg.Share.CredentialEnv()
+	client, err := share.New(cfg.Share.Provider, cfg.Share.Bucket, lookup(keyEnv), lookup(secretEnv))
+	if err != nil {
+		return fmt.Errorf("uploading report: %w", err)
+	}
+	client.Region = cfg.Share.Region
+	client.Endpoint = cfg.Share.Endpoint
+	client.LinkExpiry = cfg.Share.LinkExpiry
+	if cfg.Share.Provider == share.ProviderS3 {
+		client.SessionToken = lookup("AWS_SESSION_TOKEN")
+	}
+
+	var page, summary bytes.Buffer
+	if err := report.HTML(&page, r, cur); err != nil {
+		return err //nolint:wrapcheck // already wrapped by report
+	}
+	if err := report.JSON(&summary, r); err != nil {
+		return err //nolint:wrapcheck // already wrapped by report
+	}
+
+	branch, err := git.Branch(ctx)
+	if err != nil {
+		return fmt.Errorf("getting current branch: %w", err)
+	}
+	runID := r.Run.StartedAt.UTC().Format("20060102-150405")
+	files := []struct {
+		name, contentType string
+		body              []byte
+	}{
+		{"report.html", "text/html; charset=utf-8", page.Bytes()},
+		{"summary.json", "application/json", summary.Bytes()},
+	}
+	links := make([]string, 0, len(files))
+	for _, f := range files {
+		key := share.Key(cfg.Share.Prefix, r.Project, git.SanitizeBranch(branch), runID, f.name)
+		if err := client.Put(ctx, key, f.contentType, f.body); err != nil {
+			return err //nolint:wrapcheck // names the object
+		}
+		link, err := client.Link(key)
+		if err != nil {
+			return err //nolint:wrapcheck // invalid endpoint, already described
+		}
+		links = append(links, link)
+	}
+
+	theme := ui.DefaultTheme()
+	fmt.Fprintf(w, "%s Uploaded run #%d\n", theme.Success.Render("✓"), r.Number)
+	fmt.Fprintf(w, "  %s  %s\n", theme.Muted.Render("Report "), links[0])
+	fmt.Fprintf(w, "  %s  %s\n", theme.Muted.Render("Summary"), links[1])
+	if cfg.Share.LinkExpiry > 0 {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("  Links expire in %s.", cfg.Share.LinkExpiry)))
+	}
+	return nil
+}
+
+// hostEnv returns a lookup for credentials on the host: the environment
+// first, then the repo's .env.
+func hostEnv(repoRoot string) (func(string) string, error) {
+	dotEnv, err := docker.LoadEnvFile(filepath.Join(repoRoot, ".env"))
+	if err != nil {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+	return func(name string) string {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+		return dotEnv[name]
+	}, nil
+}
+
+// planReviewClient returns a GitHub client for the repo's origin, using
+// GITHUB_PAT from the environment or .env.
+func planReviewClient(ctx context.Context, p *runParams) (*planreview.Client, error) {
+	lookup, err := hostEnv(p.repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := docker.DetectRepo(ctx, p.repo)
+	if err != nil {
+		return nil, fmt.Errorf("plan review: %w", err)
+	}
+	client, err := planreview.New(slug, lookup("GITHUB_PAT"))
+	if err != nil {
+		return nil, fmt.Errorf("plan review: %w", err)
+	}
+	return client, nil
+}
+
+// requestPlanReview puts the plan the run just wrote up for review on the
+// branch's pull request, opening a draft one when there is none.
+//
+//nolint:errcheck // display-only writes to terminal
+func requestPlanReview(ctx context.Context, w io.Writer, theme *ui.Theme, p *runParams) error {
+	plan, err := os.ReadFile(filepath.Join(p.repoRoot, p.planFile))
+	if err != nil {
+		return fmt.Errorf("reading plan for review: %w", err)
+	}
+	client, err := planReviewClient(ctx, p)
+	if err != nil {
+		return err
+	}
+	pr, created, err := client.Request(ctx, p.branch, string(plan))
+	if err != nil {
+		return fmt.Errorf("requesting plan review: %w", err)
+	}
+	verb := "Posted the plan on"
+	if created {
+		verb = "Opened draft"
+	}
+	fmt.Fprintf(w, "%s %s PR #%d for review: %s\n", theme.Success.Render("✓"), verb, pr.Number, pr.URL)
+	fmt.Fprintln(w, theme.Muted.Render("  ralph build starts once a reviewer approves it."))
+	return nil
+}
+
+// syncIssues updates the GitHub issues the run's specs were imported from
+// with the plan's progress: a checklist of its tasks, and a comment naming
+// those done since the last sync. Without linked issues or a plan it does
+// nothing.
+//
+//nolint:errcheck // display-only writes to terminal
+func syncIssues(ctx context.Context, w io.Writer, theme *ui.Theme, p *runParams) error {
+	planPath := filepath.Join(p.repoRoot, p.planFile)
+	if _, err := os.Stat(planPath); err != nil {
+		return nil //nolint:nilerr // no plan yet, nothing to sync
+	}
+	refs, err := specs.LinkedIssues(filepath.Join(p.repoRoot, p.specsDir), "")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("issue sync: %w", err)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	for i := range refs {
+		if refs[i].Repo != "" {
+			continue
+		}
+		// "#N" names an issue in this repo.
+		if refs[i].Repo, err = docker.DetectRepo(ctx, p.repo); err != nil {
+			return fmt.Errorf("issue sync: %w", err)
+		}
+	}
+	tasks, err := status.ParsePlan(planPath)
+	if err != nil {
+		return fmt.Errorf("issue sync: %w", err)
+	}
+	lookup, err := hostEnv(p.repoRoot)
+	if err != nil {
+		return err
+	}
+	synced := map[specs.IssueRef]bool{}
+	for _, ref := range refs {
+		if synced[ref] {
+			continue // the same issue linked as "#N" and by URL
+		}
+		synced[ref] = true
+		client, err := planreview.New(ref.Repo, lookup("GITHUB_PAT"))
+		if err != nil {
+			return fmt.Errorf("issue sync: %w", err)
+		}
+		res, err := client.SyncIssue(ctx, ref.Number, p.branch, tasks)
+		if err != nil {
+			return fmt.Errorf("syncing issue %s: %w", ref, err)
+		}
+		if !res.Updated {
+			fmt.Fprintf(w, "  %s Issue %s is up to date\n", theme.Success.Render("✓"), ref)
+			continue
+		}
+		fmt.Fprintf(w, "  %s Updated issue %s: %d of %d tasks done\n", theme.Success.Render("✓"), ref, res.Done, res.Total)
+	}
+	return nil
+}
+
+// warnSyncIssues is syncIssues after a run or a sync, where a failure to
+// reach GitHub is worth a warning but not failing what already happened.
+func warnSyncIssues(ctx context.Context, w io.Writer, theme *ui.Theme, p *runParams) {
+	if p.offline {
+		return
+	}
+	if err := syncIssues(ctx, w, theme, p); err != nil {
+		fmt.Fprintln(w, theme.Warning.Render(err.Error())) //nolint:errcheck // display-only
+	}
+}
+
+// checkPlanApproval refuses a build until the plan's pull request has an
+// approving review and no outstanding request for changes.
+//
+//nolint:errcheck // display-only writes to terminal
+func checkPlanApproval(ctx context.Context, w io.Writer, theme *ui.Theme, p *runParams) (*state.Approval, error) {
+	client, err := planReviewClient(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	st, err := client.Check(ctx, p.branch)
+	if errors.Is(err, planreview.ErrNoPR) {
+		return nil, fmt.Errorf("plan review is on but %s has no open pull request; run \"ralph plan\" to open one", p.branch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("checking plan approval: %w", err)
+	}
+	if len(st.ChangesRequested) > 0 {
+		return nil, fmt.Errorf("plan has changes requested by %s on %s; update it with \"ralph plan\"", strings.Join(st.ChangesRequested, ", "), st.PR.URL)
+	}
+	if !st.Approved() {
+		return nil, fmt.Errorf("plan is awaiting approval on %s", st.PR.URL)
+	}
+	fmt.Fprintf(w, "%s Plan approved by %s on PR #%d\n", theme.Success.Render("✓"), strings.Join(st.Approvers, ", "), st.PR.Number)
+	return &state.Approval{PR: st.PR.Number, Approvers: st.Approvers}, nil
+}
+
+// shareLatestRun uploads the run that just finished when share.auto is set.
+// Failures are reported but don't fail the run.
+func shareLatestRun(ctx context.Context, w io.Writer, theme *ui.Theme, p *runParams) {
+	if !p.share.Auto || !p.share.Enabled() || p.offline {
+		return
+	}
+	if err := uploadLatestRun(ctx, w, p.repoRoot); err != nil {
+		fmt.Fprintln(w, theme.Warning.Render(fmt.Sprintf("⚠ Report upload failed: %s", err))) //nolint:errcheck // display-only
+	}
+}
+
+// uploadLatestRun uploads the most recently recorded run.
+func uploadLatestRun(ctx context.Context, w io.Writer, repoRoot string) error {
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	r, cur, err := loadReport(ctx, repoRoot, cfg, nil)
+	if err != nil {
+		return err
+	}
+	return uploadReport(ctx, w, repoRoot, cfg, r, cur)
+}
+
+// selectRun returns the 1-based number of the run args picks: the one
+// given, else the latest.
+func selectRun(st *state.State, args []string) (int, error) {
+	if len(st.Runs) == 0 {
+		return 0, fmt.Errorf("no runs recorded yet")
+	}
+	if len(args) == 0 || args[0] == "last" {
+		return len(st.Runs), nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(st.Runs) {
+		return 0, fmt.Errorf("run must be a number from 1 to %d as listed by `ralph status --history`, got %q", len(st.Runs), args[0])
+	}
+	return n, nil
+}
+
+// lspProgressCmd streams progress snapshots as JSON-RPC notifications on
+// stdout for editor extensions. It exits when stdin closes (the editor went
+// away) or on interrupt.
+func lspProgressCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp-progress",
+		Short: "Stream task, iteration, and cost progress as JSON over stdio",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			interval, err := cmd.Flags().GetDuration("interval")
+			if err != nil {
+				return fmt.Errorf("reading --interval flag: %w", err)
+			}
+
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			branch, err := git.Branch(ctx)
+			if err != nil {
+				return fmt.Errorf("getting branch: %w", err)
+			}
+
+			src := &progress.Sources{
+				Branch:    branch,
+				PlanFile:  filepath.Join(repoRoot, cfg.PlanPathForBranch(git.SanitizeBranch(branch))),
+				LogsDir:   filepath.Join(repoRoot, ".ralph", "logs"),
+				StateFile: filepath.Join(repoRoot, state.DefaultPath),
+				Pricing:   cfg.Cost.Table(),
+			}
+
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+			go func() {
+				io.Copy(io.Discard, cmd.InOrStdin()) //nolint:errcheck // EOF or error both mean the client is gone
+				stop()
+			}()
+
+			return progress.Watch(ctx, src, interval, cmd.OutOrStdout()) //nolint:wrapcheck // progress errors already have context
+		},
+	}
+	cmd.Flags().Duration("interval", progress.DefaultInterval, "how often to poll for changes")
+	return cmd
+}
+
+// scratchCmd groups scratchpad maintenance subcommands. removeVolume is
+// injected so tests don't need Docker.
+func scratchCmd(removeVolume func(project, sanitizedBranch string) error) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scratch",
+		Short: "Manage the agent scratchpad (" + docker.ScratchDir + ")",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "clean",
+		Short: "Delete this branch's scratchpad volume",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			repoRoot, err := git.RepoRoot(ctx)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			branch, err := git.Branch(ctx)
+			if err != nil {
+				return fmt.Errorf("getting current branch: %w", err)
+			}
+
+			sanitized := git.SanitizeBranch(branch)
+			if err := removeVolume(cfg.Project, sanitized); err != nil {
+				return fmt.Errorf("removing scratchpad: %w", err)
+			}
+			theme := ui.DefaultTheme()
+			fmt.Fprintf(cmd.OutOrStdout(), "  %s Removed %s\n", //nolint:errcheck // display-only
+				theme.Success.Render("✓"), docker.ScratchVolume(cfg.Project, sanitized))
+			return nil
+		},
+	})
+	return cmd
+}
+
+// abortCmd stops the project's running ralph containers the way Ctrl+C
+// would, for a run started in another terminal or in the background.
+func abortCmd(
+	running func(ctx context.Context, projectDir string) ([]docker.Container, error),
+	abort func(ctx context.Context, c docker.Container, projectDir string, timeout time.Duration) (bool, error),
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "abort",
+		Short: "Stop this project's running ralph container, letting the loop save its state first",
+		Long: "Interrupts the loop in the container like Ctrl+C: it records the run as cancelled and pushes\n" +
+			"what it has committed. A container still running after --timeout is killed, and its run is\n" +
+			"recorded as cancelled so it isn't retried as a crash.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			w := cmd.OutOrStdout()
+			theme := ui.DefaultTheme()
+			timeout, err := cmd.Flags().GetDuration("timeout")
+			if err != nil {
+				return fmt.Errorf("reading --timeout flag: %w", err)
+			}
+			branch, err := cmd.Flags().GetString("branch")
+			if err != nil {
+				return fmt.Errorf("reading --branch flag: %w", err)
+			}
+			repo, err := vcs.Open(ctx, vcs.KindAuto)
+			if err != nil {
+				return fmt.Errorf("finding repo root: %w", err)
+			}
+			root := repo.Root()
+
+			all, err := running(ctx, root)
+			if err != nil {
+				return err //nolint:wrapcheck // docker errors already have context
+			}
+			var containers []docker.Container
+			for _, c := range all {
+				if branch == "" || c.Branch == branch {
+					containers = append(containers, c)
+				}
+			}
+			if len(containers) == 0 {
+				if branch != "" {
+					return fmt.Errorf("no ralph container running %s on %s", root, branch)
+				}
+				return fmt.Errorf("no ralph container running %s", root)
+			}
+
+			for _, c := range containers {
+				fmt.Fprintf(w, "Stopping %s %s (%s)...\n", c.Mode, c.Branch, c.ID) //nolint:errcheck // display-only
+				forced, err := abort(ctx, c, root, timeout)
+				if err != nil {
+					return err //nolint:wrapcheck // docker errors already have context
+				}
+				if forced {
+					fmt.Fprintf(w, "  %s %s\n", theme.Warning.Render("!"), //nolint:errcheck // display-only
+						fmt.Sprintf("Killed after %s without exiting; run recorded as cancelled", timeout))
+				} else {
+					fmt.Fprintf(w, "  %s Stopped\n", theme.Success.Render("✓")) //nolint:errcheck // display-only
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Duration("timeout", docker.DefaultAbortTimeout, "how long to wait for the loop to save its state before killing the container")
+	cmd.Flags().String("branch", "", "only stop the container running this branch")
+	return cmd
+}
+
+// specCmd groups spec maintenance subcommands.
+func specCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "spec",
+		Short: "Manage this branch's specs",
+	}
+	importCmd := &cobra.Command{
+		Use:     "import <path|url>",
+		Aliases: []string{"i"},
+		Short:   "Import documents into this branch's specs as markdown and commit them",
+		Long: "Copies .md and .txt files, and converts .docx (built in) and .pdf (via pdftotext, when installed),\n" +
+			"from a file, a directory (recursively) or an http(s) URL into the branch's specs directory.\n" +
+			"File names are normalized to lowercase-kebab-case .md.",
+		Args: cobra.ExactArgs(1),
+		RunE: runSpecImport,
+	}
+	importCmd.Flags().String("specs", "", "specs directory (overrides specs_dir in config)")
+	importCmd.Flags().Bool("force", false, "overwrite specs that already exist")
+	importCmd.Flags().Bool("no-commit", false, "write the files but don't commit them")
+	cmd.AddCommand(importCmd)
 
-			planPath := filepath.Join(p.repoRoot, p.planFile)
-			if _, err := os.Stat(planPath); os.IsNotExist(err) {
-				return fmt.Errorf("plan file %q not found; run \"ralph plan\" first", p.planFile)
-			}
+	testsCmd := &cobra.Command{
+		Use:   "tests <spec.md>",
+		Short: "Generate failing acceptance tests from a spec's acceptance criteria and commit them",
+		Long: "Reads the bullets under the spec's \"Acceptance\" heading (or its checklist items) and writes one\n" +
+			"failing test per criterion in the project's test framework (pytest, go test, cargo test, or\n" +
+			"vitest/jest/node:test), so the build loop has to make them pass. The spec is looked up in the\n" +
+			"branch's specs directory when the path doesn't exist as given.",
+		Args: cobra.ExactArgs(1),
+		RunE: runSpecTests,
+	}
+	testsCmd.Flags().String("specs", "", "specs directory (overrides specs_dir in config)")
+	testsCmd.Flags().Bool("force", false, "overwrite the test file if it already exists")
+	testsCmd.Flags().Bool("no-commit", false, "write the test file but don't commit it")
+	cmd.AddCommand(testsCmd)
 
-			return orch.BuildAndRun(w, theme, "build", p.maxVal, p.branch, p.planFile, p.specsDir)
-		},
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check this branch's specs for bad names, frontmatter, broken links and oversized files",
+		Long: "Checks every .md spec: lowercase-kebab-case names, LF line endings, frontmatter with a title and\n" +
+			"only known fields (title, status, depends_on, owner, tags), relative links that resolve, and a\n" +
+			"size limit. --fix renames files, normalizes line endings, adds missing frontmatter titles and\n" +
+			"lowercases statuses. Exits non-zero while issues remain.",
+		Args: cobra.NoArgs,
+		RunE: runSpecLint,
 	}
-	cmd.Flags().IntP("max", "n", 0, "maximum iterations (0 = use config default)")
-	cmd.Flags().String("specs", "", "specs directory (overrides specs_dir in config)")
+	lintCmd.Flags().String("specs", "", "specs directory (overrides specs_dir in config)")
+	lintCmd.Flags().Bool("fix", false, "repair the issues that can be fixed automatically")
+	lintCmd.Flags().Int("max-size", specs.DefaultMaxSpecSize>>10, "largest allowed spec, in KB")
+	cmd.AddCommand(lintCmd)
 	return cmd
 }
 
-func statusCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "status",
-		Short: "Progress summary — tasks done, costs, pass/fail",
-		RunE: func(cmd *cobra.Command, _ []string) error {
-			repoRoot, err := git.RepoRoot()
-			if err != nil {
-				return fmt.Errorf("finding repo root: %w", err)
-			}
+//nolint:errcheck // display-only writes
+func runSpecLint(cmd *cobra.Command, _ []string) error {
+	specsDir, err := cmd.Flags().GetString("specs")
+	if err != nil {
+		return fmt.Errorf("reading --specs flag: %w", err)
+	}
+	if specsDir != "" {
+		if err := validateRelativePath("specs", specsDir); err != nil {
+			return err
+		}
+	}
+	fix, err := cmd.Flags().GetBool("fix")
+	if err != nil {
+		return fmt.Errorf("reading --fix flag: %w", err)
+	}
+	maxKB, err := cmd.Flags().GetInt("max-size")
+	if err != nil {
+		return fmt.Errorf("reading --max-size flag: %w", err)
+	}
+	if maxKB <= 0 {
+		return fmt.Errorf("--max-size must be positive, got %d", maxKB)
+	}
 
-			cfg, err := config.Load(repoRoot)
-			if err != nil {
-				return fmt.Errorf("loading config: %w", err)
-			}
+	ctx := cmd.Context()
+	repoRoot, err := git.RepoRoot(ctx)
+	if err != nil {
+		return fmt.Errorf("finding repo root: %w", err)
+	}
+	if specsDir == "" {
+		cfg, err := config.Load(repoRoot)
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		branch, err := git.Branch(ctx)
+		if err != nil {
+			return fmt.Errorf("getting current branch: %w", err)
+		}
+		specsDir = cfg.SpecsDirForBranch(git.SanitizeBranch(branch))
+	}
+	dir := filepath.Join(repoRoot, specsDir)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("specs directory %s: %w", specsDir, err)
+	}
 
-			branch, err := git.Branch()
-			if err != nil {
-				return fmt.Errorf("getting branch: %w", err)
-			}
+	res, err := specs.Lint(repoRoot, dir, int64(maxKB)<<10, fix)
+	if err != nil {
+		return fmt.Errorf("linting specs: %w", err)
+	}
 
-			planPath := cfg.PlanPathForBranch(git.SanitizeBranch(branch))
+	theme := ui.DefaultTheme()
+	w := cmd.OutOrStdout()
+	prefix := filepath.Clean(specsDir) + string(filepath.Separator) // issues are relative to the specs dir
+	for i := range res.Fixed {
+		fmt.Fprintf(w, "  %s %s\n", theme.Success.Render("fixed"), prefix+res.Fixed[i].String())
+	}
+	for i := range res.Issues {
+		fmt.Fprintf(w, "  %s %s\n", theme.Error.Render("✗"), prefix+res.Issues[i].String())
+	}
+	if len(res.Issues) == 0 {
+		fmt.Fprintf(w, "%s %d spec(s) in %s look good\n", theme.Success.Render("✓"), res.Specs, specsDir)
+		return nil
+	}
+	msg := fmt.Sprintf("%d issue(s) in %s", len(res.Issues), specsDir)
+	if n := res.Fixable(); n > 0 {
+		msg += fmt.Sprintf("; %d can be fixed with --fix", n)
+	}
+	return errors.New(msg)
+}
 
-			tasks, err := status.ParsePlan(planPath)
-			if err != nil {
-				return fmt.Errorf("parsing plan: %w", err)
-			}
+//nolint:errcheck // display-only writes
+func runSpecTests(cmd *cobra.Command, args []string) error {
+	specsDir, err := cmd.Flags().GetString("specs")
+	if err != nil {
+		return fmt.Errorf("reading --specs flag: %w", err)
+	}
+	if specsDir != "" {
+		if err := validateRelativePath("specs", specsDir); err != nil {
+			return err
+		}
+	}
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return fmt.Errorf("reading --force flag: %w", err)
+	}
+	noCommit, err := cmd.Flags().GetBool("no-commit")
+	if err != nil {
+		return fmt.Errorf("reading --no-commit flag: %w", err)
+	}
 
-			runs, err := status.ParseLogs(".ralph/logs")
-			if err != nil {
-				return fmt.Errorf("parsing logs: %w", err)
-			}
+	ctx := cmd.Context()
+	repoRoot, err := git.RepoRoot(ctx)
+	if err != nil {
+		return fmt.Errorf("finding repo root: %w", err)
+	}
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	ctx = git.WithTimeouts(ctx, gitTimeouts(cfg))
+	branch, err := git.Branch(ctx)
+	if err != nil {
+		return fmt.Errorf("getting current branch: %w", err)
+	}
+	if git.IsProtectedBranch(branch, cfg.ProtectedBranches) {
+		return fmt.Errorf("ralph spec tests must be run on a feature branch, not %q", branch)
+	}
+	if specsDir == "" {
+		specsDir = cfg.SpecsDirForBranch(git.SanitizeBranch(branch))
+	}
 
-			st, err := state.Load(filepath.Join(repoRoot, state.DefaultPath))
-			if err != nil {
-				return fmt.Errorf("loading state: %w", err)
-			}
+	relSpec, err := findSpec(repoRoot, specsDir, args[0])
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(repoRoot, relSpec)) //nolint:gosec // spec inside the repo
+	if err != nil {
+		return fmt.Errorf("reading spec: %w", err)
+	}
+	criteria := specs.AcceptanceCriteria(string(data))
+	if len(criteria) == 0 {
+		return fmt.Errorf("no acceptance criteria found in %s; list them as bullets under an \"Acceptance Criteria\" heading or as - [ ] checklist items", relSpec)
+	}
 
-			status.Render(cmd.OutOrStdout(), cfg.Project, branch, tasks, runs, st.LastRun(), ui.DefaultTheme())
-			return nil
-		},
+	skel, err := scaffold.AcceptanceTests(repoRoot, scaffold.Detect(repoRoot), relSpec, criteria)
+	if err != nil {
+		return fmt.Errorf("generating tests: %w", err)
+	}
+	target := filepath.Join(repoRoot, skel.Path)
+	if _, err := os.Stat(target); err == nil && !force {
+		return fmt.Errorf("%s already exists; pass --force to overwrite it", skel.Path)
+	}
+	if !noCommit {
+		// The commit must contain only the generated tests.
+		staged, err := git.HasStagedChanges(ctx)
+		if err != nil {
+			return fmt.Errorf("checking staged changes: %w", err)
+		}
+		if staged {
+			return errors.New("there are staged changes; commit or unstage them first, or pass --no-commit")
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return fmt.Errorf("creating test directory: %w", err)
+	}
+	if err := os.WriteFile(target, []byte(skel.Content), 0o600); err != nil {
+		return fmt.Errorf("writing tests: %w", err)
+	}
+
+	theme := ui.DefaultTheme()
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "  %s %s %s\n", theme.Success.Render("✓"), skel.Path,
+		theme.Muted.Render(fmt.Sprintf("(%d failing test(s) from %s)", skel.Tests, relSpec)))
+	if noCommit {
+		return nil
+	}
+
+	if err := git.Add(ctx, target); err != nil {
+		return fmt.Errorf("staging tests: %w", err)
+	}
+	staged, err := git.HasStagedChanges(ctx)
+	if err != nil {
+		return fmt.Errorf("checking staged changes: %w", err)
+	}
+	if !staged {
+		fmt.Fprintln(w, theme.Muted.Render(i18n.T("Tests unchanged; nothing to commit.")))
+		return nil
+	}
+	msg := fmt.Sprintf("test(acceptance): scaffold failing tests for %s", filepath.ToSlash(relSpec))
+	if err := git.Commit(ctx, msg); err != nil {
+		return fmt.Errorf("committing tests: %w", err)
+	}
+	fmt.Fprintf(w, "Committed %s; the build loop must make these tests pass\n", skel.Path)
+	return nil
+}
+
+// findSpec resolves a spec argument to a path relative to repoRoot: the
+// path as given if it exists, otherwise the name inside the branch's specs
+// directory. The spec must be inside the repo.
+func findSpec(repoRoot, specsDir, arg string) (string, error) {
+	root, err := filepath.EvalSymlinks(repoRoot)
+	if err != nil {
+		return "", fmt.Errorf("resolving repo root: %w", err)
+	}
+	for _, c := range []string{arg, filepath.Join(repoRoot, specsDir, arg)} {
+		real, err := filepath.EvalSymlinks(c)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(real); err != nil || info.IsDir() {
+			continue
+		}
+		abs, err := filepath.Abs(real)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("spec %s is outside the repository", arg)
+		}
+		return rel, nil
+	}
+	return "", fmt.Errorf("spec %s not found (also looked in %s)", arg, specsDir)
+}
+
+//nolint:errcheck // display-only writes
+func runSpecImport(cmd *cobra.Command, args []string) error {
+	specsDir, err := cmd.Flags().GetString("specs")
+	if err != nil {
+		return fmt.Errorf("reading --specs flag: %w", err)
+	}
+	if specsDir != "" {
+		if err := validateRelativePath("specs", specsDir); err != nil {
+			return err
+		}
+	}
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return fmt.Errorf("reading --force flag: %w", err)
+	}
+	noCommit, err := cmd.Flags().GetBool("no-commit")
+	if err != nil {
+		return fmt.Errorf("reading --no-commit flag: %w", err)
+	}
+
+	ctx := cmd.Context()
+	repoRoot, err := git.RepoRoot(ctx)
+	if err != nil {
+		return fmt.Errorf("finding repo root: %w", err)
+	}
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	ctx = git.WithTimeouts(ctx, gitTimeouts(cfg))
+	branch, err := git.Branch(ctx)
+	if err != nil {
+		return fmt.Errorf("getting current branch: %w", err)
+	}
+	if git.IsProtectedBranch(branch, cfg.ProtectedBranches) {
+		return fmt.Errorf("ralph spec import must be run on a feature branch, not %q", branch)
+	}
+	if specsDir == "" {
+		specsDir = cfg.SpecsDirForBranch(git.SanitizeBranch(branch))
+	}
+	if !noCommit {
+		// The import commit must contain only the specs.
+		staged, err := git.HasStagedChanges(ctx)
+		if err != nil {
+			return fmt.Errorf("checking staged changes: %w", err)
+		}
+		if staged {
+			return errors.New("there are staged changes; commit or unstage them first, or pass --no-commit")
+		}
+	}
+
+	dest := filepath.Join(repoRoot, specsDir)
+	res, err := specs.Import(ctx, args[0], dest, force)
+	if err != nil {
+		return fmt.Errorf("importing specs: %w", err)
+	}
+
+	theme := ui.DefaultTheme()
+	w := cmd.OutOrStdout()
+	for _, im := range res.Imported {
+		fmt.Fprintf(w, "  %s %s → %s\n", theme.Success.Render("✓"), im.Source, filepath.Join(specsDir, im.Dest))
+	}
+	for _, sk := range res.Skipped {
+		fmt.Fprintf(w, "  %s %s %s\n", theme.Warning.Render("·"), sk.Source, theme.Muted.Render("("+sk.Reason+")"))
+	}
+	if len(res.Imported) == 0 {
+		return errors.New("no specs imported")
+	}
+	if noCommit {
+		return nil
+	}
+
+	if err := git.Add(ctx, dest); err != nil {
+		return fmt.Errorf("staging specs: %w", err)
+	}
+	staged, err := git.HasStagedChanges(ctx)
+	if err != nil {
+		return fmt.Errorf("checking staged changes: %w", err)
+	}
+	if !staged {
+		fmt.Fprintln(w, theme.Muted.Render(i18n.T("Specs unchanged; nothing to commit.")))
+		return nil
 	}
+	msg := fmt.Sprintf("docs(specs): import %s", strings.Join(res.SortedDests(), ", "))
+	if err := git.Commit(ctx, msg); err != nil {
+		return fmt.Errorf("committing specs: %w", err)
+	}
+	fmt.Fprintf(w, "Committed %d spec(s) to %s\n", len(res.Imported), specsDir)
+	return nil
+}
+
+// gitTimeouts maps the git section of the config onto per-command timeouts.
+func gitTimeouts(cfg *config.Config) git.Timeouts {
+	return git.Timeouts{Local: cfg.Git.Timeout, Network: cfg.Git.NetworkTimeout, Hooks: cfg.Git.HookTimeout}
 }
 
 // loopCmd is the hidden _loop command invoked inside Docker containers.
@@ -346,16 +3601,88 @@ func loopCmd() *cobra.Command {
 	return cmd
 }
 
-func runLoop(mode loop.Mode, maxFlag int) error {
-	repoRoot, err := git.RepoRoot()
-	if err != nil {
-		return fmt.Errorf("finding repo root: %w", err)
+// guardHookExitCode tells Claude Code to block the tool call and feed stderr
+// back to the model.
+const guardHookExitCode = 2
+
+// guardCmd is the hidden _guard command Claude Code runs as a PreToolUse hook
+// inside containers. It reads the hook payload on stdin and exits 2 when the
+// Bash command matches a guardrails.blocked_commands pattern. It fails
+// closed: when the config can't be loaded or the payload can't be read, the
+// call is blocked too, with the reason on stderr.
+func guardCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "_guard",
+		Short:  "Internal: PreToolUse hook that blocks destructive commands",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			reason, err := checkToolCall(cmd.Context(), cmd.InOrStdin())
+			if err != nil {
+				reason = guard.Unavailable(err)
+			}
+			if reason != "" {
+				return &exitError{code: guardHookExitCode, msg: reason}
+			}
+			return nil
+		},
 	}
+}
 
+// checkToolCall matches the hook payload on r against the repo's
+// guardrails, returning the reason to block it or "".
+func checkToolCall(ctx context.Context, r io.Reader) (string, error) {
+	repoRoot, err := git.RepoRoot(ctx)
+	if err != nil {
+		return "", fmt.Errorf("finding repo root: %w", err)
+	}
 	cfg, err := config.Load(repoRoot)
 	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
+		return "", fmt.Errorf("loading config: %w", err)
+	}
+	patterns, err := guard.Compile(cfg.Guardrails.BlockedCommands)
+	if err != nil {
+		return "", fmt.Errorf("compiling guardrails: %w", err)
 	}
+	reason, err := guard.Check(r, patterns)
+	if err != nil {
+		return "", fmt.Errorf("checking tool call: %w", err)
+	}
+	return reason, nil
+}
+
+// skipHooks is the audit note the loop records when git.hooks.skip turns the
+// repo's commit hooks off for the agent, or "" when they run.
+func skipHooks(h config.Hooks) string {
+	if !h.Skip {
+		return ""
+	}
+	return h.Reason
+}
+
+// chaosFromEnv parses RALPH_CHAOS and RALPH_CHAOS_SEED as forwarded by the host.
+func chaosFromEnv(rate, seed string) (*loop.Chaos, error) {
+	r, err := strconv.ParseFloat(rate, 64)
+	if err != nil || r < 0 || r > 1 {
+		return nil, fmt.Errorf("RALPH_CHAOS must be a rate between 0 and 1, got %q", rate)
+	}
+	s, err := strconv.ParseInt(seed, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("RALPH_CHAOS_SEED must be an integer, got %q", seed)
+	}
+	return &loop.Chaos{Rate: r, Seed: s}, nil
+}
+
+func runLoop(mode loop.Mode, maxFlag int) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	repo, cfg, err := openRepo(ctx)
+	if err != nil {
+		return err
+	}
+	repoRoot := repo.Root()
+	ctx = git.WithTimeouts(ctx, gitTimeouts(cfg))
 
 	var phase config.PhaseConfig
 	if mode == loop.ModePlan {
@@ -369,7 +3696,7 @@ func runLoop(mode loop.Mode, maxFlag int) error {
 		maxIterations = maxFlag
 	}
 
-	branch, err := git.Branch()
+	branch, err := repo.Branch(ctx)
 	if err != nil {
 		return fmt.Errorf("getting current branch: %w", err)
 	}
@@ -381,13 +3708,12 @@ func runLoop(mode loop.Mode, maxFlag int) error {
 	}
 	specsDir := os.Getenv("SPECS_DIR")
 	if specsDir == "" {
-		specsDir = cfg.SpecsDirForBranch(git.SanitizeBranch(branch))
+		specsDir = cfg.SpecsDirForPhase(string(mode), git.SanitizeBranch(branch))
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-
 	opts := &loop.Options{
 		Mode:          mode,
+		VCS:           repo,
 		PromptFile:    phase.Prompt,
 		MaxIterations: maxIterations,
 		FreshContext:  phase.FreshContext,
@@ -396,11 +3722,155 @@ func runLoop(mode loop.Mode, maxFlag int) error {
 		StateFile:     state.DefaultPath,
 		PlanFile:      planFile,
 		SpecsDir:      specsDir,
+		RequireTaskID: cfg.Commits.RequireTaskID,
+		AmendTaskID:   cfg.Commits.Amend,
+		SkipHooks:     skipHooks(cfg.Git.Hooks),
+		ModelRoutes:   phase.Models,
+		Timeout:       phase.IterationTimeout,
+		MaxTimeouts:   phase.MaxTimeouts,
 	}
+	a, err := agent.New(cfg.Agent)
+	if err != nil {
+		return fmt.Errorf("selecting agent: %w", err)
+	}
+	opts.Agent = a
 
 	if envDirs := os.Getenv("ADDITIONAL_DIRS"); envDirs != "" {
 		opts.AdditionalDirs = strings.Split(envDirs, ",")
 	}
+	if envTags := os.Getenv("RALPH_TAGS"); envTags != "" {
+		opts.Tags = strings.Split(envTags, ",")
+	}
+	opts.Note = os.Getenv("RALPH_NOTE")
+	if rate := os.Getenv("RALPH_CHAOS"); rate != "" {
+		chaos, err := chaosFromEnv(rate, os.Getenv("RALPH_CHAOS_SEED"))
+		if err != nil {
+			return err
+		}
+		opts.Chaos = chaos
+		opts.Tags = append(opts.Tags, "chaos")
+	}
+	opts.Profile = os.Getenv("RALPH_PROFILE")
+	if pr := os.Getenv("RALPH_PLAN_PR"); pr != "" {
+		n, err := strconv.Atoi(pr)
+		if err != nil {
+			return fmt.Errorf("RALPH_PLAN_PR: %w", err)
+		}
+		opts.PlanApproval = &state.Approval{PR: n, Approvers: strings.Split(os.Getenv("RALPH_PLAN_APPROVERS"), ",")}
+	}
+	if policy := os.Getenv("RALPH_ON_CLAUDE_ERROR"); policy != "" {
+		p, err := loop.ParseClaudeErrorPolicy(policy)
+		if err != nil {
+			return fmt.Errorf("RALPH_ON_CLAUDE_ERROR: %w", err)
+		}
+		opts.OnClaudeError = p
+	}
+	if name := os.Getenv("RALPH_EXPERIMENT"); name != "" {
+		e, ok := cfg.Experiments[name]
+		if !ok {
+			return fmt.Errorf("unknown experiment %q", name)
+		}
+		opts.Experiment = name
+		opts.PromptFile = e.Prompt(string(mode), opts.PromptFile)
+		opts.Model = e.Model
+		if e.Model != "" {
+			opts.ModelRoutes = nil // the variant's model is what's being compared
+		}
+	}
+	if scratch := os.Getenv("SCRATCH_DIR"); scratch != "" {
+		opts.ScratchDir = scratch
+		opts.ScratchLimit = int64(cfg.Docker.ScratchLimitMB) << 20
+	}
+	opts.Workspace = repoRoot
+	opts.WorkspaceSkip = []string{".git", ".jj", ".hg", opts.LogsDir, docker.ScratchDir}
+	if cfg.Docker.DepsDir != "" {
+		opts.WorkspaceSkip = append(opts.WorkspaceSkip, cfg.Docker.DepsDir) // a volume, filled by the install command
+	}
+	opts.DiskWarn = int64(cfg.Docker.DiskWarnMB) << 20
+	opts.DiskLimit = int64(cfg.Docker.DiskLimitMB) << 20
+	if os.Getenv("RALPH_STEP") != "" {
+		opts.StepIn = os.Stdin
+	}
+	if len(cfg.Guardrails.BlockedCommands) > 0 {
+		opts.Settings = guard.Settings()
+	}
+	if msg := guardrailWarning(cfg); msg != "" {
+		fmt.Fprintln(os.Stderr, ui.DefaultTheme().Warning.Render(msg)) //nolint:errcheck // display-only
+	}
+	redactor, err := stream.NewRedactor(docker.SecretValues(os.Getenv),
+		append(slices.Clone(stream.DefaultSecretPatterns), cfg.Guardrails.RedactPatterns...))
+	if err != nil {
+		return fmt.Errorf("building secret redaction: %w", err)
+	}
+	opts.Redactor = redactor
+	opts.Filters = cfg.StreamFilters
+	if cfg.Codeowners.Enabled() {
+		rules, _, err := owners.Load(repoRoot)
+		if err != nil {
+			return fmt.Errorf("loading CODEOWNERS: %w", err)
+		}
+		if rules == nil {
+			return fmt.Errorf("codeowners is configured but no CODEOWNERS file was found (looked in %s)", strings.Join(owners.Locations, ", "))
+		}
+		opts.Owners = owners.NewPolicy(rules, cfg.Codeowners.Protect, cfg.Codeowners.Careful)
+	}
+	opts.TokenCounter = tokens.New(cfg.TokenCount, os.Getenv("ANTHROPIC_API_KEY"))
+	opts.ContextWarn = cfg.ContextWarn
+	opts.NoPromptCache = cfg.PromptCache == config.PromptCacheOff
+	opts.Instructions = filepath.Join(repoRoot, "AGENTS.md")
+	opts.ParamWidth = paramWidth(cfg.ToolParamWidth, os.Stdout)
+	opts.Pricing = cfg.Cost.Table()
+	opts.Currency = cfg.Cost.Display()
+	opts.Offline = os.Getenv("RALPH_OFFLINE") == "1"
+	opts.PushInterval = cfg.Git.PushDebounce.Interval
+	opts.PushCommits = cfg.Git.PushDebounce.Commits
+	opts.SummaryRows = cfg.Summary.Rows
+	if opts.Output = os.Getenv("RALPH_OUTPUT"); opts.Output != "" && !loop.ValidOutput(opts.Output) && opts.Output != loop.OutputTUI {
+		return fmt.Errorf("RALPH_OUTPUT must be %q, %q or %q, got %q", loop.OutputText, loop.OutputJSON, loop.OutputTUI, opts.Output)
+	}
+	opts.MaxCost = cfg.Cost.MaxCost
+	if v := os.Getenv("RALPH_MAX_COST"); v != "" {
+		if opts.MaxCost, err = strconv.ParseFloat(v, 64); err != nil {
+			return fmt.Errorf("RALPH_MAX_COST: %w", err)
+		}
+		if err := cfg.Cost.CheckCap("RALPH_MAX_COST", opts.MaxCost); err != nil {
+			return err //nolint:wrapcheck // names the variable itself
+		}
+	}
+	opts.Package = os.Getenv("RALPH_PACKAGE")
+	if slug := os.Getenv("RALPH_REPO"); slug != "" {
+		opts.Origin = &state.Origin{Repo: slug, Remote: os.Getenv("RALPH_REMOTE"), Workspace: os.Getenv("RALPH_WORKSPACE")}
+	}
+	if n := os.Getenv("RALPH_RESUME_OF"); n != "" {
+		if opts.ResumeOf, err = strconv.Atoi(n); err != nil {
+			return fmt.Errorf("RALPH_RESUME_OF: %w", err)
+		}
+	}
+	if n := os.Getenv("RALPH_REPLAY_OF"); n != "" {
+		if opts.ReplayOf, err = strconv.Atoi(n); err != nil {
+			return fmt.Errorf("RALPH_REPLAY_OF: %w", err)
+		}
+		st, err := state.Load(filepath.Join(repoRoot, state.DefaultPath))
+		if err != nil {
+			return fmt.Errorf("loading state: %w", err)
+		}
+		if opts.Replay, err = recordedPrompts(st, opts.LogsDir, opts.ReplayOf); err != nil {
+			return err
+		}
+		opts.MaxIterations = len(opts.Replay)
+		opts.CurrentPrompts = os.Getenv("RALPH_REPLAY_CURRENT_PROMPTS") == "1"
+	}
+	if !opts.Offline {
+		opts.Notifier = notify.New(cfg.Notifications.WebhookURL)
+	}
+	if kind := cfg.GitHub.Report; kind != "" && kind != ghstatus.KindOff && !opts.Offline {
+		slug, err := docker.DetectRepo(ctx, repo)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, ui.DefaultTheme().Muted.Render(fmt.Sprintf("GitHub status reporting disabled: %s", err))) //nolint:errcheck // display-only
+		} else {
+			opts.Reporter = ghstatus.New(kind, slug, os.Getenv(ghstatus.TokenEnv(kind)), "ralph/"+string(mode))
+		}
+	}
 
 	loopErr := loop.Run(ctx, opts, os.Stdout, ui.DefaultTheme())
 	stop()
@@ -414,3 +3884,28 @@ func runLoop(mode loop.Mode, maxFlag int) error {
 	}
 	return nil
 }
+
+// guardrailWarning is the startup warning for a run whose agent can't
+// enforce its blocked commands, or "" when they are enforced or there are
+// none. Config validation refuses patterns set explicitly for such an
+// agent, so this is the defaults going unenforced.
+func guardrailWarning(cfg *config.Config) string {
+	if len(cfg.Guardrails.BlockedCommands) == 0 || agent.EnforcesGuardrails(cfg.Agent) {
+		return ""
+	}
+	return fmt.Sprintf("Guardrails are off: agent %q can't run ralph's guard hook, so blocked commands are not enforced. "+
+		"Set guardrails.blocked_commands to [] to acknowledge, or use agent %q.", cfg.Agent, agent.NameClaude)
+}
+
+// paramWidth resolves tool_param_width: a set width is used as-is, and 0
+// fits the tool parameters to the terminal on out, if it is one.
+func paramWidth(configured int, out *os.File) int {
+	if configured != 0 {
+		return configured
+	}
+	cols, _, err := term.GetSize(int(out.Fd())) //nolint:gosec // file descriptors fit in an int
+	if err != nil {
+		return stream.DefaultParamWidth
+	}
+	return stream.ParamWidthFor(cols)
+}
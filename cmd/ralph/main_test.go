@@ -2,15 +2,36 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benwilkes9/ralph-cli/internal/agent"
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/debuglog"
+	"github.com/benwilkes9/ralph-cli/internal/docker"
+	"github.com/benwilkes9/ralph-cli/internal/events"
+	"github.com/benwilkes9/ralph-cli/internal/guard"
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	logfile "github.com/benwilkes9/ralph-cli/internal/log"
+	"github.com/benwilkes9/ralph-cli/internal/loop"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/swarm"
 	"github.com/benwilkes9/ralph-cli/internal/testutil"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
@@ -28,10 +49,26 @@ type fakeOrchestrator struct {
 type fakeCall struct {
 	mode, branch, planFile, specsDir string
 	maxIter                          int
+	tags                             []string
+	note                             string
+	step                             bool
+	profile                          string
+	experiment                       string
+	chaos                            float64
+	chaosSeed                        int64
+	onClaudeError                    string
+	offline                          bool
+	resumeOf                         int
+	native                           bool
+	output                           string
+	maxCost                          float64
+	pkg                              string
+	replayOf                         int
+	currentPrompts                   bool
 }
 
-func (f *fakeOrchestrator) BuildAndRun(_ io.Writer, _ *ui.Theme, mode string, maxIter int, branch, planFile, specsDir string) error {
-	f.calls = append(f.calls, fakeCall{mode, branch, planFile, specsDir, maxIter})
+func (f *fakeOrchestrator) BuildAndRun(_ context.Context, _ io.Writer, _ *ui.Theme, launch *docker.LaunchOptions) error {
+	f.calls = append(f.calls, fakeCall{launch.Mode, launch.Branch, launch.PlanFile, launch.SpecsDir, launch.MaxIterations, launch.Tags, launch.Note, launch.Step, launch.Profile, launch.Experiment, launch.Chaos, launch.ChaosSeed, launch.OnClaudeError, launch.Offline, launch.ResumeOf, launch.Native, launch.Output, launch.MaxCost, launch.Package, launch.ReplayOf, launch.CurrentPrompts})
 	return f.err
 }
 
@@ -74,6 +111,9 @@ func initRepoWithConfigYAML(t *testing.T, yaml string) string {
 	ralph := filepath.Join(dir, ".ralph")
 	require.NoError(t, os.MkdirAll(ralph, 0o750))
 	require.NoError(t, os.WriteFile(filepath.Join(ralph, "config.yaml"), []byte(yaml), 0o600))
+	// As generated by ralph init, so runs don't stop to offer additions.
+	require.NoError(t, os.MkdirAll(filepath.Join(ralph, "docker"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(ralph, "docker", "Dockerfile.dockerignore"), []byte(".git\nlogs\n.ralph/logs\n"), 0o600))
 	return dir
 }
 
@@ -123,6 +163,7 @@ func TestResolveRunParams_DefaultPaths(t *testing.T) {
 	testutil.Chdir(t, dir)
 
 	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
 
 	p, err := resolveRunParams(cmd)
 	require.NoError(t, err)
@@ -137,6 +178,7 @@ func TestResolveRunParams_CustomSpecs(t *testing.T) {
 	testutil.Chdir(t, dir)
 
 	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
 	require.NoError(t, cmd.Flags().Set("specs", "custom/path"))
 
 	p, err := resolveRunParams(cmd)
@@ -158,6 +200,7 @@ func TestResolveRunParams_ProtectedBranch(t *testing.T) {
 	testutil.Chdir(t, dir)
 
 	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
 	_, err := resolveRunParams(cmd)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "feature branch")
@@ -168,6 +211,7 @@ func TestResolveRunParams_SpecsDirFromConfig(t *testing.T) {
 	testutil.Chdir(t, dir)
 
 	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
 
 	p, err := resolveRunParams(cmd)
 	require.NoError(t, err)
@@ -180,6 +224,7 @@ func TestResolveRunParams_SpecsDirExactFromConfig(t *testing.T) {
 	testutil.Chdir(t, dir)
 
 	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
 
 	p, err := resolveRunParams(cmd)
 	require.NoError(t, err)
@@ -187,11 +232,31 @@ func TestResolveRunParams_SpecsDirExactFromConfig(t *testing.T) {
 	assert.Equal(t, "my/exact/path", p.specsDir)
 }
 
+func TestResolveRunParams_PhaseSpecsDir(t *testing.T) {
+	dir := initRepoWithConfigYAML(t, "project: test\nspecs_dir: docs/requirements\nphases:\n  plan:\n    specs_dir: research\n")
+	testutil.Chdir(t, dir)
+
+	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
+	p, err := resolveRunParams(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "research/feature-test", p.specsDir)
+	assert.Equal(t, "research/parent", p.specsDirFor("parent"))
+	assert.Equal(t, "research/feature-test", p.launchOptions("plan").SpecsDir)
+
+	cmd = buildCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
+	p, err = resolveRunParams(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "docs/requirements/feature-test", p.specsDir)
+}
+
 func TestResolveRunParams_FlagOverridesConfigSpecsDir(t *testing.T) {
 	dir := initRepoWithConfigYAML(t, "project: test\nspecs_dir: docs/requirements\n")
 	testutil.Chdir(t, dir)
 
 	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
 	require.NoError(t, cmd.Flags().Set("specs", "override/path"))
 
 	p, err := resolveRunParams(cmd)
@@ -200,6 +265,54 @@ func TestResolveRunParams_FlagOverridesConfigSpecsDir(t *testing.T) {
 	assert.Equal(t, "override/path", p.specsDir)
 }
 
+// --- checkBuildContext ---
+
+func TestCheckBuildContext_AddsMissing(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules"), 0o750))
+
+	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetIn(strings.NewReader("y\n"))
+	p, err := resolveRunParams(cmd)
+	require.NoError(t, err)
+
+	require.NoError(t, checkBuildContext(cmd, p, ui.DefaultTheme()))
+	assert.Contains(t, out.String(), "doesn't ignore node_modules")
+	data, err := os.ReadFile(filepath.Join(dir, ".ralph", "docker", "Dockerfile.dockerignore"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "\nnode_modules\n")
+}
+
+func TestCheckBuildContext_NonInteractiveWarnsOnly(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".venv"), 0o750))
+	stdin, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = stdin.Close() })
+
+	cmd := buildCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetIn(stdin)
+	p, err := resolveRunParams(cmd)
+	require.NoError(t, err)
+	p.contextWarn = 1
+
+	require.NoError(t, checkBuildContext(cmd, p, ui.DefaultTheme()))
+	assert.Contains(t, out.String(), "build context is over 0 MB")
+	assert.Contains(t, out.String(), "doesn't ignore .venv")
+	assert.Contains(t, out.String(), "to speed up every run")
+	data, err := os.ReadFile(filepath.Join(dir, ".ralph", "docker", "Dockerfile.dockerignore"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), ".venv")
+}
+
 // --- initCmd ---
 
 func TestInitCmd_NotInGitRepo(t *testing.T) {
@@ -237,13 +350,137 @@ func TestInitCmd_CreatesScaffold(t *testing.T) {
 	testutil.Chdir(t, dir)
 
 	cmd := initCmd()
-	cmd.SetIn(&byteReader{strings.NewReader("1\n1\n1\n")})
+	cmd.SetIn(&byteReader{strings.NewReader("y\n1\n1\n1\n")})
 	cmd.SetOut(&bytes.Buffer{})
 
 	require.NoError(t, cmd.Execute())
 	assert.DirExists(t, filepath.Join(dir, ".ralph"))
 }
 
+func TestInitCmd_CIGitHub(t *testing.T) {
+	dir := t.TempDir()
+	testutil.RunGit(t, dir, "init", "--initial-branch=main")
+	testutil.RunGit(t, dir, "config", "user.name", "test")
+	testutil.RunGit(t, dir, "config", "user.email", "test@test.com")
+	testutil.RunGit(t, dir, "config", "commit.gpgsign", "false")
+	testutil.RunGit(t, dir, "commit", "--allow-empty", "-m", "init")
+	testutil.RunGit(t, dir, "checkout", "-b", "my-feature")
+	testutil.Chdir(t, dir)
+
+	cmd := initCmd()
+	cmd.SetArgs([]string{"--ci", "github"})
+	cmd.SetIn(&byteReader{strings.NewReader("y\n1\n1\n1\n")})
+	out := &bytes.Buffer{}
+	cmd.SetOut(out)
+
+	require.NoError(t, cmd.Execute())
+	assert.FileExists(t, filepath.Join(dir, ".github", "workflows", "ralph.yml"))
+	assert.Contains(t, out.String(), "RALPH_GITHUB_PAT")
+}
+
+func TestInitCmd_RejectsUnknownCI(t *testing.T) {
+	cmd := initCmd()
+	cmd.SetArgs([]string{"--ci", "gitlab"})
+
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--ci must be")
+}
+
+func TestInitCmd_LanguageOverride(t *testing.T) {
+	dir := initSimpleRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module tools\n\ngo 1.24\n"), 0o600))
+	testutil.Chdir(t, dir)
+
+	cmd := initCmd()
+	cmd.SetArgs([]string{"--package-manager", "pnpm"})
+	cmd.SetIn(&byteReader{strings.NewReader("y\n1\n1\n1\n")})
+	cmd.SetOut(&bytes.Buffer{})
+
+	require.NoError(t, cmd.Execute())
+	data, err := os.ReadFile(filepath.Join(dir, ".ralph", "config.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "pnpm")
+	assert.NotContains(t, string(data), "go test")
+}
+
+func TestInitCmd_RejectsMismatchedPackageManager(t *testing.T) {
+	testutil.Chdir(t, initSimpleRepo(t))
+
+	cmd := initCmd()
+	cmd.SetArgs([]string{"--language", "go", "--package-manager", "npm"})
+	cmd.SetOut(&bytes.Buffer{})
+
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "npm is for node, not go")
+}
+
+func TestInitCmd_YesSkipsPrompts(t *testing.T) {
+	dir := initSimpleRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module tools\n\ngo 1.24\n"), 0o600))
+	testutil.Chdir(t, dir)
+
+	cmd := initCmd()
+	cmd.SetArgs([]string{"--yes", "--goal", "A tidy CLI", "--specs-dir", "design/specs"})
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(&bytes.Buffer{})
+
+	require.NoError(t, cmd.Execute())
+	data, err := os.ReadFile(filepath.Join(dir, ".ralph", "config.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `goal: "A tidy CLI"`)
+	assert.Contains(t, string(data), `run_cmd: "go run ./cmd/`)
+	assert.Contains(t, string(data), `specs_dir: "design/specs"`)
+	assert.Contains(t, string(data), "specs_dir_exact: true")
+}
+
+func TestInitCmd_YesNeedsRunCmdWhenUndetected(t *testing.T) {
+	dir := initSimpleRepo(t)
+	testutil.Chdir(t, dir)
+
+	cmd := initCmd()
+	cmd.SetArgs([]string{"--yes"})
+	cmd.SetOut(&bytes.Buffer{})
+	require.ErrorContains(t, cmd.Execute(), "pass --run-cmd")
+
+	cmd = initCmd()
+	cmd.SetArgs([]string{"-y", "--run-cmd", "make serve"})
+	cmd.SetOut(&bytes.Buffer{})
+	require.NoError(t, cmd.Execute())
+	data, err := os.ReadFile(filepath.Join(dir, ".ralph", "config.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `run_cmd: "make serve"`)
+	assert.Contains(t, string(data), `specs_dir: "specs"`)
+}
+
+func TestInitCmd_Agent(t *testing.T) {
+	dir := initSimpleRepo(t)
+	testutil.Chdir(t, dir)
+
+	cmd := initCmd()
+	cmd.SetArgs([]string{"--agent", "codex"})
+	cmd.SetIn(&byteReader{strings.NewReader("y\n1\n1\n1\n")})
+	cmd.SetOut(&bytes.Buffer{})
+
+	require.NoError(t, cmd.Execute())
+	data, err := os.ReadFile(filepath.Join(dir, ".ralph", "config.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "agent: codex")
+	dockerfile, err := os.ReadFile(filepath.Join(dir, ".ralph", "docker", "Dockerfile"))
+	require.NoError(t, err)
+	assert.Contains(t, string(dockerfile), "@openai/codex")
+	assert.NotContains(t, string(dockerfile), "claude-code")
+	env, err := os.ReadFile(filepath.Join(dir, ".env.example"))
+	require.NoError(t, err)
+	assert.Contains(t, string(env), "OPENAI_API_KEY=")
+
+	cmd = initCmd()
+	cmd.SetArgs([]string{"--agent", "copilot"})
+	cmd.SetOut(&bytes.Buffer{})
+	require.ErrorContains(t, cmd.Execute(), "--agent must be")
+}
+
 // --- planCmd ---
 
 func TestPlanCmd_EmptySpecsDir(t *testing.T) {
@@ -280,6 +517,128 @@ func TestPlanCmd_CreatesDirectoriesAndCallsOrchestrator(t *testing.T) {
 	assert.Equal(t, "feature-test", fake.calls[0].branch)
 }
 
+func TestPlanCmd_SpecsDirCollision(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	specsDir := filepath.Join(dir, "specs", "feature-test")
+	require.NoError(t, os.MkdirAll(specsDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(specsDir, "feature.md"), []byte("# Spec"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(specsDir, "seed.sql"), []byte("insert into t values (1);"), 0o600))
+	testutil.Chdir(t, dir)
+
+	fake := &fakeOrchestrator{}
+	cmd := planCmd(fake)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetIn(strings.NewReader("")) // no terminal to ask on
+	err := cmd.Execute()
+	require.ErrorContains(t, err, "--allow-existing")
+	assert.Contains(t, out.String(), "specs/feature-test: holds 1 file that is not specs (seed.sql)")
+	assert.Empty(t, fake.calls)
+	assert.NoFileExists(t, filepath.Join(specsDir, ".gitkeep"), "nothing is written before the check")
+
+	cmd = planCmd(fake)
+	cmd.SetArgs([]string{"--allow-existing"})
+	require.NoError(t, cmd.Execute())
+	assert.Len(t, fake.calls, 1)
+}
+
+func TestPlanCmd_InheritsParentSpecs(t *testing.T) {
+	tests := []struct {
+		method string
+		link   bool
+	}{
+		{"copy", false},
+		{"link", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			dir := initRepoWithConfig(t)
+			parentSpecs := filepath.Join(dir, "specs", "feature-test")
+			require.NoError(t, os.MkdirAll(parentSpecs, 0o750))
+			require.NoError(t, os.WriteFile(filepath.Join(parentSpecs, "feature.md"), []byte("# Spec"), 0o600))
+			testutil.RunGit(t, dir, "checkout", "-b", "feature-followup")
+			testutil.Chdir(t, dir)
+
+			fake := &fakeOrchestrator{}
+			cmd := planCmd(fake)
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetArgs([]string{"--inherit-specs", tt.method})
+
+			require.NoError(t, cmd.Execute())
+			assert.Contains(t, out.String(), "Specs inherited from feature-test")
+
+			child := filepath.Join(dir, "specs", "feature-followup")
+			assert.FileExists(t, filepath.Join(child, "feature.md"))
+			fi, err := os.Lstat(child)
+			require.NoError(t, err)
+			assert.Equal(t, tt.link, fi.Mode()&os.ModeSymlink != 0)
+			require.Len(t, fake.calls, 1)
+		})
+	}
+}
+
+func TestPlanCmd_InheritSkipsUnrelatedBranches(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	for _, b := range []string{"feature-test", "gh-pages"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "specs", b), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "specs", b, "feature.md"), []byte("# Spec"), 0o600))
+	}
+	testutil.RunGit(t, dir, "checkout", "--orphan", "gh-pages")
+	testutil.RunGit(t, dir, "commit", "--allow-empty", "-m", "pages")
+	testutil.RunGit(t, dir, "checkout", "feature-test")
+	testutil.RunGit(t, dir, "checkout", "-b", "feature-followup")
+	testutil.Chdir(t, dir)
+
+	fake := &fakeOrchestrator{}
+	cmd := planCmd(fake)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--inherit-specs", "copy"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "Specs inherited from feature-test")
+}
+
+func TestPlanCmd_InheritNotOfferedWithoutTerminal(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	parentSpecs := filepath.Join(dir, "specs", "feature-test")
+	require.NoError(t, os.MkdirAll(parentSpecs, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(parentSpecs, "feature.md"), []byte("# Spec"), 0o600))
+	testutil.RunGit(t, dir, "checkout", "-b", "feature-followup")
+	testutil.Chdir(t, dir)
+
+	// Redirected stdin is a file, but not a terminal.
+	stdin, err := os.Open(filepath.Join(parentSpecs, "feature.md"))
+	require.NoError(t, err)
+	t.Cleanup(func() { stdin.Close() }) //nolint:errcheck,gosec // read-only test file
+
+	fake := &fakeOrchestrator{}
+	cmd := planCmd(fake)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetIn(stdin)
+	require.ErrorContains(t, cmd.Execute(), "no .md specs found")
+	assert.NotContains(t, out.String(), "feature-test", "no prompt to inherit")
+	assert.NoFileExists(t, filepath.Join(dir, "specs", "feature-followup", "feature.md"))
+}
+
+func TestPlanCmd_InheritSpecsNone(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	parentSpecs := filepath.Join(dir, "specs", "feature-test")
+	require.NoError(t, os.MkdirAll(parentSpecs, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(parentSpecs, "feature.md"), []byte("# Spec"), 0o600))
+	testutil.RunGit(t, dir, "checkout", "-b", "feature-followup")
+	testutil.Chdir(t, dir)
+
+	fake := &fakeOrchestrator{}
+	cmd := planCmd(fake)
+	cmd.SetArgs([]string{"--inherit-specs", "none"})
+
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no .md specs found")
+}
+
 func TestPlanCmd_ProtectedBranch(t *testing.T) {
 	dir := t.TempDir()
 	testutil.RunGit(t, dir, "init", "--initial-branch=main")
@@ -298,6 +657,76 @@ func TestPlanCmd_ProtectedBranch(t *testing.T) {
 	assert.Empty(t, fake.calls)
 }
 
+func TestPlanCmd_BranchFlagLeavesProtectedBranch(t *testing.T) {
+	dir := initSimpleRepo(t)
+	testutil.RunGit(t, dir, "checkout", "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".ralph"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".ralph", "config.yaml"), []byte("project: test\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "specs", "feat-x"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "specs", "feat-x", "spec.md"), []byte("# Spec\n"), 0o600))
+	testutil.Chdir(t, dir)
+
+	fake := &fakeOrchestrator{}
+	cmd := planCmd(fake)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--branch", "feat-x"})
+	require.NoError(t, cmd.Execute())
+
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, "feat-x", fake.calls[0].branch)
+	assert.Contains(t, out.String(), "Created branch feat-x from main")
+}
+
+func TestPlanCmd_InvalidRunLeavesProtectedBranch(t *testing.T) {
+	dir := initSimpleRepo(t)
+	testutil.RunGit(t, dir, "checkout", "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".ralph"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".ralph", "config.yaml"), []byte("project: test\n"), 0o600))
+	testutil.Chdir(t, dir)
+
+	fake := &fakeOrchestrator{}
+	cmd := planCmd(fake)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--branch", "feat-x", "--experiment", "nope"})
+	require.ErrorContains(t, cmd.Execute(), "unknown experiment")
+
+	assert.Empty(t, fake.calls)
+	branches, err := exec.CommandContext(context.Background(), "git", "-C", dir, "branch", "--list").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "  feature-test\n* main\n", string(branches), "no branch created, still on main")
+}
+
+func TestResolveRunParams_AsksForBranchNamedAfterSpec(t *testing.T) {
+	dir := initSimpleRepo(t)
+	testutil.RunGit(t, dir, "checkout", "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".ralph"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".ralph", "config.yaml"), []byte("project: test\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "specs", "main"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "specs", "main", "User Auth.md"), []byte("# Auth\n"), 0o600))
+	testutil.Chdir(t, dir)
+
+	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
+	cmd.SetIn(&byteReader{strings.NewReader("\n")})
+	cmd.SetOut(&bytes.Buffer{})
+	p, err := resolveRunParams(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "user-auth", p.branch)
+	assert.Equal(t, "specs/user-auth", p.specsDir)
+}
+
+func TestResolveRunParams_BranchFlagOffProtectedBranch(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	cmd := planCmd(&fakeOrchestrator{})
+	cmd.SetContext(context.Background())
+	require.NoError(t, cmd.Flags().Set("branch", "other"))
+	_, err := resolveRunParams(cmd)
+	require.ErrorContains(t, err, "already on \"feature-test\"")
+}
+
 // --- buildCmd ---
 
 func TestBuildCmd_MissingPlanFile(t *testing.T) {
@@ -346,6 +775,27 @@ func TestStatusCmd_RendersOutput(t *testing.T) {
 	assert.Contains(t, out.String(), "feature-test") // branch name
 }
 
+func TestStatusCmd_Badge(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n\n### Task 1: A\n- [x] done\n\n### Task 2: B\n- [ ] todo\n"), 0o600))
+
+	cmd := statusCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--badge"})
+	require.NoError(t, cmd.Execute())
+
+	var badge map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &badge), "output must be bare JSON: %s", out.String())
+	assert.Equal(t, "ralph", badge["label"])
+	assert.Equal(t, "1/2 tasks (50%) · $0.00", badge["message"])
+	assert.Equal(t, "yellowgreen", badge["color"])
+}
+
 // --- loopCmd ---
 
 func TestLoopCmd_InvalidMode(t *testing.T) {
@@ -355,3 +805,1499 @@ func TestLoopCmd_InvalidMode(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "unknown mode")
 }
+
+// --- lspProgressCmd ---
+
+func TestLspProgressCmd_ExitsWhenStdinCloses(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	cmd := lspProgressCmd()
+	cmd.SetIn(strings.NewReader("")) // client closes immediately
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), `"method":"ralph/progress"`)
+	assert.Contains(t, out.String(), `"branch":"feature-test"`)
+}
+
+// --- run labels ---
+
+func TestBuildCmd_PassesTagsAndNote(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetArgs([]string{"--tag", "nightly", "--tag", "v2", "--note", "attempt with new prompt"})
+
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, []string{"nightly", "v2"}, fake.calls[0].tags)
+	assert.Equal(t, "attempt with new prompt", fake.calls[0].note)
+}
+
+func TestBuildCmd_PassesStep(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetArgs([]string{"--step"})
+
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.True(t, fake.calls[0].step)
+}
+
+func TestBuildCmd_Chaos(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetArgs([]string{"--chaos", "--chaos-seed", "42"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.InDelta(t, 0.3, fake.calls[0].chaos, 0)
+	assert.Equal(t, int64(42), fake.calls[0].chaosSeed)
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{"--chaos=0.5"})
+	require.NoError(t, cmd.Execute())
+	assert.InDelta(t, 0.5, fake.calls[1].chaos, 0)
+	assert.NotZero(t, fake.calls[1].chaosSeed, "a random seed is picked")
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{"--chaos=1.5"})
+	require.ErrorContains(t, cmd.Execute(), "--chaos must be between 0 and 1")
+
+	assert.True(t, buildCmd(fake).Flags().Lookup("chaos").Hidden)
+}
+
+func TestBuildCmd_ContinueOnClaudeError(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetArgs([]string{"--continue-on-claude-error", "retry-2"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, "retry-2", fake.calls[0].onClaudeError)
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{})
+	require.NoError(t, cmd.Execute())
+	assert.Empty(t, fake.calls[1].onClaudeError, "abort is not forwarded")
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{"--continue-on-claude-error", "ignore"})
+	require.ErrorContains(t, cmd.Execute(), "abort, skip-iteration or retry-N")
+}
+
+func TestBuildCmd_Output(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"--output", "json"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, "json", fake.calls[0].output)
+	assert.Empty(t, stdout.String(), "stdout is left to the loop's events")
+	assert.NotEmpty(t, stderr.String())
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{})
+	require.NoError(t, cmd.Execute())
+	assert.Empty(t, fake.calls[1].output, "text is not forwarded")
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{"--output", "yaml"})
+	require.ErrorContains(t, cmd.Execute(), `--output must be "text" or "json"`)
+}
+
+func TestBuildCmd_TUI(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--tui"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, "tui", fake.calls[0].output)
+
+	for args, want := range map[string]string{
+		"--tui --output json": "--tui can't be combined with --output json",
+		"--tui --step":        "--tui can't be combined with --step",
+	} {
+		cmd = buildCmd(fake)
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs(strings.Fields(args))
+		require.ErrorContains(t, cmd.Execute(), want, args)
+	}
+	assert.Len(t, fake.calls, 1)
+}
+
+func TestBuildCmd_MaxCost(t *testing.T) {
+	dir := initRepoWithConfigYAML(t, "project: test\ncost:\n  max_cost: 5\n  max_cost_ceiling: 20\n")
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--max-cost", "12.5"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.InDelta(t, 12.5, fake.calls[0].maxCost, 0)
+
+	cmd = buildCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--max-cost", "50"})
+	require.ErrorContains(t, cmd.Execute(), "--max-cost $50.00 is over cost.max_cost_ceiling $20.00")
+	assert.Len(t, fake.calls, 1, "nothing is launched over the ceiling")
+}
+
+func TestBuildCmd_Package(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--package", "web"})
+	require.ErrorContains(t, cmd.Execute(), "--package: no workspace packages found")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.work"), []byte("go 1.25\n\nuse ./apps/web\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "apps", "web"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "apps", "web", "go.mod"), []byte("module example.com/web\n"), 0o600))
+
+	for _, name := range []string{"example.com/web", "apps/web"} {
+		cmd = buildCmd(fake)
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{"--package", name})
+		require.NoError(t, cmd.Execute())
+		assert.Equal(t, "apps/web", fake.calls[len(fake.calls)-1].pkg)
+	}
+
+	cmd = buildCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--package", "api"})
+	require.ErrorContains(t, cmd.Execute(), `no package "api" in go.work`)
+	assert.Len(t, fake.calls, 2)
+}
+
+func TestChaosFromEnv(t *testing.T) {
+	c, err := chaosFromEnv("0.25", "7")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.25, c.Rate, 0)
+	assert.Equal(t, int64(7), c.Seed)
+
+	_, err = chaosFromEnv("lots", "7")
+	require.Error(t, err)
+	_, err = chaosFromEnv("0.25", "")
+	require.Error(t, err)
+}
+
+func TestBuildCmd_PlanComplete(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n\n### Task 1 - Setup\n- [x] done\n\n### Task 2 - Widget\n- [x] done\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{})
+	require.NoError(t, cmd.Execute())
+	assert.Empty(t, fake.calls, "no container is launched")
+	assert.Contains(t, out.String(), "All 2 tasks complete")
+
+	cmd = buildCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--force"})
+	require.NoError(t, cmd.Execute())
+	assert.Len(t, fake.calls, 1)
+
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n\n### Task 1 - Setup\n- [x] done\n\n### Task 2 - Widget\n- [ ] todo\n"), 0o600))
+	cmd = buildCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{})
+	require.NoError(t, cmd.Execute())
+	assert.Len(t, fake.calls, 2)
+}
+
+func TestBuildCmd_StalePlan(t *testing.T) {
+	dir := initRepoWithConfigYAML(t, "project: test\nphases:\n  build:\n    stale_plan: block\n")
+	testutil.Chdir(t, dir)
+
+	commit := func(path, content, date string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		t.Setenv("GIT_COMMITTER_DATE", date)
+		testutil.RunGit(t, dir, "add", "-A")
+		testutil.RunGit(t, dir, "commit", "-m", filepath.Base(path))
+	}
+	build := func(args ...string) (string, error) {
+		fake := &fakeOrchestrator{}
+		cmd := buildCmd(fake)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		if err == nil {
+			assert.Len(t, fake.calls, 1)
+		}
+		return out.String(), err
+	}
+	specPath := filepath.Join(dir, "specs", "feature-test", "auth.md")
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+
+	commit(specPath, "# Auth\n", "2026-01-01T12:00:00Z")
+	commit(planPath, "# Plan\n\n### Task 1 - Setup\n- [ ] todo\n", "2026-01-05T12:00:00Z")
+	_, err := build()
+	require.ErrorContains(t, err, "the plan was last updated", "old plan, specs unchanged since")
+	require.ErrorContains(t, err, "pass --force")
+
+	commit(specPath, "# Auth\n\nWith SSO.\n", "2026-01-07T12:00:00Z")
+	_, err = build()
+	require.ErrorContains(t, err, "the specs changed on 2026-01-07, after the plan was last updated on 2026-01-05")
+
+	out, err := build("--force")
+	require.NoError(t, err)
+	assert.Contains(t, out, "plan may be stale")
+}
+
+func TestBuildCmd_Profile(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetArgs([]string{"--profile", "work"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, "work", fake.calls[0].profile)
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{"--profile", "../etc"})
+	require.Error(t, cmd.Execute())
+}
+
+func TestBuildCmd_PlanReviewRequiresApproval(t *testing.T) {
+	dir := initRepoWithConfigYAML(t, "project: test\ngithub:\n  plan_review: true\n")
+	testutil.Chdir(t, dir)
+	testutil.RunGit(t, dir, "remote", "add", "origin", "https://github.com/o/r.git")
+	t.Setenv("GITHUB_PAT", "")
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n- [ ] task\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	err := buildCmd(fake).Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GITHUB_PAT")
+	assert.Empty(t, fake.calls, "build must not start without an approved plan")
+}
+
+func TestBuildCmd_Offline(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n- [ ] task\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetArgs([]string{"--offline"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.True(t, fake.calls[0].offline)
+}
+
+func TestPlanCmd_NoDocker(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	specsDir := filepath.Join(dir, "specs", "feature-test")
+	require.NoError(t, os.MkdirAll(specsDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(specsDir, "feature.md"), []byte("# Spec"), 0o600))
+	testutil.Chdir(t, dir)
+
+	fake := &fakeOrchestrator{}
+	cmd := planCmd(fake)
+	cmd.SetArgs([]string{"--no-docker"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.True(t, fake.calls[0].native)
+	assert.Equal(t, "feature-test", fake.calls[0].branch)
+	assert.Equal(t, ".ralph/plans/IMPLEMENTATION_PLAN_feature-test.md", fake.calls[0].planFile)
+}
+
+func TestPlanSplitAndBuildMilestone(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planRel := ".ralph/plans/IMPLEMENTATION_PLAN_feature-test.md"
+	planPath := filepath.Join(dir, planRel)
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte(`# Plan
+
+### Task 1: One
+- [x] done
+
+### Task 2: Two
+- [ ] todo
+
+### Task 3: Three
+- [ ] todo
+`), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetArgs([]string{"--milestone", "1"})
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "isn't split")
+
+	split := planCmd(fake)
+	var out bytes.Buffer
+	split.SetOut(&out)
+	split.SetArgs([]string{"split", "--max-tasks", "1"})
+	require.NoError(t, split.Execute())
+	assert.Contains(t, out.String(), "into 3 milestones")
+	assert.FileExists(t, filepath.Join(dir, ".ralph/plans/IMPLEMENTATION_PLAN_feature-test-m3.md"))
+
+	require.NoError(t, buildCmd(fake).Execute())
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, ".ralph/plans/IMPLEMENTATION_PLAN_feature-test-m2.md", fake.calls[0].planFile, "first milestone with tasks left")
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{"--milestone", "3"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 2)
+	assert.Equal(t, ".ralph/plans/IMPLEMENTATION_PLAN_feature-test-m3.md", fake.calls[1].planFile)
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{"--milestone", "4"})
+	err = cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has 3 milestones")
+}
+
+func TestResumeCmd(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	stateFile := filepath.Join(dir, state.DefaultPath)
+
+	fake := &fakeOrchestrator{}
+	err := resumeCmd(fake).Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no run recorded on feature-test")
+
+	require.NoError(t, state.Save(stateFile, &state.State{Runs: []state.RunRecord{
+		{Mode: "build", Branch: "feature-test", Status: state.StatusCancelled, Iterations: 3, MaxIterations: 10},
+		{Mode: "plan", Branch: "other", Status: state.StatusCancelled},
+		{Mode: "build", Branch: "feature-test", Status: state.StatusMaxIterations, Iterations: 5, MaxIterations: 7, ResumeOf: 1,
+			PlanFile: "plans/p.md", SpecsDir: "specs/x", Tags: []string{"nightly", "chaos"}, Profile: "work"},
+	}}))
+	require.NoError(t, resumeCmd(fake).Execute())
+	require.Len(t, fake.calls, 1)
+	call := fake.calls[0]
+	assert.Equal(t, "build", call.mode)
+	assert.Equal(t, 2, call.maxIter, "10 budgeted, 3+5 used across the lineage")
+	assert.Equal(t, "plans/p.md", call.planFile)
+	assert.Equal(t, "specs/x", call.specsDir)
+	assert.Equal(t, []string{"nightly"}, call.tags)
+	assert.Equal(t, "work", call.profile)
+	assert.Equal(t, 3, call.resumeOf)
+
+	cmd := resumeCmd(fake)
+	cmd.SetArgs([]string{"-n", "7"})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, 7, fake.calls[1].maxIter)
+
+	st, err := state.Load(stateFile)
+	require.NoError(t, err)
+	st.Runs[2].Iterations = 7
+	require.NoError(t, state.Save(stateFile, st))
+	err = resumeCmd(fake).Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pass -n")
+
+	st.Runs = append(st.Runs, state.RunRecord{Mode: "build", Branch: "feature-test", Status: state.StatusCompleted})
+	require.NoError(t, state.Save(stateFile, st))
+	err = resumeCmd(fake).Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ended completed")
+}
+
+func TestResumeCmd_CostCap(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	stateFile := filepath.Join(dir, state.DefaultPath)
+	require.NoError(t, state.Save(stateFile, &state.State{Runs: []state.RunRecord{
+		{Mode: "build", Branch: "feature-test", Status: state.StatusCancelled, Iterations: 2, TotalCost: 1.5, MaxCost: 5},
+		{Mode: "build", Branch: "feature-test", Status: state.StatusCostLimit, Iterations: 3, TotalCost: 2.5, MaxCost: 3.5, ResumeOf: 1},
+	}}))
+
+	fake := &fakeOrchestrator{}
+	cmd := resumeCmd(fake)
+	cmd.SetOut(io.Discard)
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.InDelta(t, 1.0, fake.calls[0].maxCost, 1e-9, "$5 cap, $1.50+$2.50 spent across the lineage")
+
+	cmd = resumeCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--max-cost", "4"})
+	require.NoError(t, cmd.Execute())
+	assert.InDelta(t, 4.0, fake.calls[1].maxCost, 1e-9)
+
+	st, err := state.Load(stateFile)
+	require.NoError(t, err)
+	st.Runs[1].TotalCost = 3.5
+	require.NoError(t, state.Save(stateFile, st))
+	cmd = resumeCmd(fake)
+	cmd.SetOut(io.Discard)
+	require.ErrorContains(t, cmd.Execute(), "spent all $5.00 of its cost cap; pass --max-cost")
+}
+
+func TestReplayCmd(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	started := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	require.NoError(t, state.Save(filepath.Join(dir, state.DefaultPath), &state.State{Runs: []state.RunRecord{
+		{Mode: "build", Branch: "feature-test", StartedAt: started.Add(-time.Hour), Status: state.StatusCompleted},
+		{Mode: "build", Branch: "feature-test", StartedAt: started, Status: state.StatusCompleted,
+			PlanFile: "plans/p.md", SpecsDir: "specs/x", Profile: "work"},
+	}}))
+	runDir := filepath.Join(dir, "logs", started.Format(logfile.TimeLayout))
+	require.NoError(t, os.MkdirAll(runDir, 0o750))
+	for i := 1; i <= 2; i++ {
+		require.NoError(t, os.WriteFile(loop.PromptArtifactPath(filepath.Join(dir, "logs"), started.Format(logfile.TimeLayout), i), []byte(`{"prompt": "Build."}`), 0o600))
+	}
+
+	fake := &fakeOrchestrator{}
+	cmd := replayCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--run", "1", "--onto", "replay-a"})
+	require.ErrorContains(t, cmd.Execute(), "run #1 has no recorded prompts")
+
+	cmd = replayCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--run", "2", "--onto", "main"})
+	require.ErrorContains(t, cmd.Execute(), "protected branch")
+
+	cmd = replayCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--run", "2", "--onto", "replay-a", "--current-prompts"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	call := fake.calls[0]
+	assert.Equal(t, "build", call.mode)
+	assert.Equal(t, "replay-a", call.branch)
+	assert.Equal(t, 2, call.maxIter, "one iteration per recorded prompt")
+	assert.Equal(t, "plans/p.md", call.planFile)
+	assert.Equal(t, "specs/x", call.specsDir)
+	assert.Equal(t, "work", call.profile)
+	assert.Equal(t, []string{"replay"}, call.tags)
+	assert.Equal(t, 2, call.replayOf)
+	assert.True(t, call.currentPrompts)
+
+	head, err := exec.CommandContext(context.Background(), "git", "-C", dir, "branch", "--show-current").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "replay-a", strings.TrimSpace(string(head)))
+
+	cmd = replayCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--run", "2", "--onto", "replay-a"})
+	require.ErrorContains(t, cmd.Execute(), `creating branch "replay-a"`)
+
+	cmd = replayCmd(fake)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--run", "9", "--onto", "replay-b"})
+	require.ErrorContains(t, cmd.Execute(), "no run #9")
+}
+
+func TestSyncCmd_PushesLocalCommits(t *testing.T) {
+	bare, clone := testutil.InitBareAndClone(t)
+	testutil.Chdir(t, clone)
+	ralph := filepath.Join(clone, ".ralph")
+	require.NoError(t, os.MkdirAll(ralph, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(ralph, "config.yaml"), []byte("project: test\n"), 0o600))
+	testutil.RunGit(t, clone, "checkout", "-b", "feature-offline")
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "offline work")
+
+	var out bytes.Buffer
+	cmd := syncCmd()
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "Pushed feature-offline to origin")
+
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "more offline work")
+	out.Reset()
+	cmd = syncCmd()
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "Pushed 1 commit(s) to feature-offline")
+
+	out.Reset()
+	cmd = syncCmd()
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "feature-offline is up to date")
+
+	testutil.RunGit(t, bare, "rev-parse", "--verify", "feature-offline")
+}
+
+func TestSyncCmd_IssueSyncFailureWarns(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	testutil.Chdir(t, clone)
+	ralph := filepath.Join(clone, ".ralph")
+	require.NoError(t, os.MkdirAll(ralph, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(ralph, "config.yaml"),
+		[]byte("project: test\nphases:\n  plan:\n    specs_dir: research\n"), 0o600))
+	testutil.RunGit(t, clone, "checkout", "-b", "feature-issues")
+	// Linked from the plan phase's specs; the origin isn't on GitHub, so
+	// "#1" can't be resolved to a repo.
+	specsDir := filepath.Join(clone, "research", "feature-issues")
+	require.NoError(t, os.MkdirAll(specsDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(specsDir, "auth.md"), []byte("---\nissue: \"#1\"\n---\n# Auth\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(ralph, "plans"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(ralph, "plans", "IMPLEMENTATION_PLAN_feature-issues.md"), []byte("- [ ] Task\n"), 0o600))
+
+	var out bytes.Buffer
+	cmd := syncCmd()
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "Pushed feature-issues to origin")
+	assert.Contains(t, out.String(), "issue sync:")
+}
+
+func TestSnapshotCmd(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	testutil.Chdir(t, clone)
+	testutil.RunGit(t, clone, "checkout", "-b", "feature-snap")
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "notes.txt"), []byte("keep me\n"), 0o600))
+
+	var out bytes.Buffer
+	cmd := snapshotCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"create", "--reason", "by hand"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "Saved snapshot")
+	assert.Contains(t, out.String(), "feature-snap")
+	assert.Contains(t, out.String(), "1 untracked file(s), by hand")
+
+	testutil.RunGit(t, clone, "add", "-A")
+	testutil.RunGit(t, clone, "commit", "-m", "bad run")
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "notes.txt"), []byte("clobbered\n"), 0o600))
+
+	out.Reset()
+	cmd = snapshotCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"list"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "by hand")
+
+	out.Reset()
+	cmd = snapshotCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"restore", "--yes"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "Restored snapshot")
+	assert.Contains(t, out.String(), "git push --force-with-lease origin feature-snap")
+	data, err := os.ReadFile(filepath.Join(clone, "notes.txt")) //nolint:gosec // test file
+	require.NoError(t, err)
+	assert.Equal(t, "keep me\n", string(data))
+
+	cmd = snapshotCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"restore", "nope", "--yes"})
+	require.ErrorContains(t, cmd.Execute(), `no snapshot "nope"`)
+}
+
+func TestBuildCmd_Experiment(t *testing.T) {
+	dir := initRepoWithConfigYAML(t, `project: test
+experiments:
+  fast-sonnet:
+    build_prompt: .ralph/prompts/build-terse.md
+    model: sonnet
+  opus-only:
+    model: opus
+`)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetArgs([]string{"--experiment", "fast-sonnet"})
+	err := cmd.Execute()
+	require.Error(t, err, "variant prompt must exist")
+	assert.Contains(t, err.Error(), "build-terse.md")
+
+	promptPath := filepath.Join(dir, ".ralph", "prompts", "build-terse.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(promptPath), 0o750))
+	require.NoError(t, os.WriteFile(promptPath, []byte("terse\n"), 0o600))
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{"--experiment", "fast-sonnet"})
+	require.NoError(t, cmd.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, "fast-sonnet", fake.calls[0].experiment)
+
+	cmd = buildCmd(fake)
+	cmd.SetArgs([]string{"--experiment", "nope"})
+	err = cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "defined: fast-sonnet, opus-only")
+}
+
+func TestBuildCmd_RejectsUnsafeTag(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	fake := &fakeOrchestrator{}
+	cmd := buildCmd(fake)
+	cmd.SetArgs([]string{"--tag", "a,b"})
+
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--tag")
+	assert.Empty(t, fake.calls)
+}
+
+func TestStatusCmd_HistoryFilteredByTag(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	require.NoError(t, state.Save(filepath.Join(dir, state.DefaultPath), &state.State{Runs: []state.RunRecord{
+		{Mode: "plan", Note: "untagged run"},
+		{Mode: "build", Tags: []string{"nightly"}, Note: "nightly run"},
+	}}))
+
+	cmd := statusCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--tag", "nightly"})
+
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "nightly run")
+	assert.NotContains(t, out.String(), "untagged run")
+}
+
+// --- compareCmd ---
+
+func TestCompareCmd_Experiment(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	require.NoError(t, state.Save(filepath.Join(dir, state.DefaultPath), &state.State{Runs: []state.RunRecord{
+		{Mode: "build", Iterations: 3, TotalCost: 1.5, Status: state.StatusCompleted},
+		{Mode: "build", Experiment: "fast-sonnet", Iterations: 2, TotalCost: 0.4, Status: state.StatusCompleted},
+	}}))
+
+	cmd := compareCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--experiment"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "(baseline)")
+	assert.Contains(t, out.String(), "fast-sonnet")
+	assert.Contains(t, out.String(), "$0.4000")
+
+	cmd = compareCmd()
+	cmd.SetArgs([]string{})
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--experiment")
+}
+
+func TestOverviewCmd(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(events.DirEnv, dir)
+	pub, err := events.Start(dir, events.Event{Project: "/home/me/shop", Branch: "feat/cart", Mode: "build", MaxIterations: 3}, nil)
+	require.NoError(t, err)
+	done, err := events.Start(dir, events.Event{Project: "/home/me/api", Branch: "fix/auth", Mode: "plan"}, nil)
+	require.NoError(t, err)
+	done.Finish(nil)
+	defer pub.Finish(nil)
+
+	run := func(args ...string) string {
+		cmd := overviewCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		require.NoError(t, cmd.Execute())
+		return out.String()
+	}
+
+	out := run()
+	assert.Contains(t, out, "feat/cart")
+	assert.Contains(t, out, "0/3")
+	assert.NotContains(t, out, "fix/auth", "ended runs need --all")
+
+	out = run("--all")
+	assert.Contains(t, out, "fix/auth")
+	assert.Contains(t, out, "Total: 1 running of 2")
+}
+
+func TestDoctorCmd(t *testing.T) {
+	dir := initSimpleRepo(t)
+	testutil.Chdir(t, dir)
+
+	cmd := doctorCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs(nil)
+	err := cmd.Execute()
+	require.ErrorContains(t, err, "check(s) failed")
+	assert.Contains(t, out.String(), ".ralph/config.yaml not found")
+	assert.Contains(t, out.String(), `run "ralph init"`)
+}
+
+func TestScheduleCmd(t *testing.T) {
+	dir := initSimpleRepo(t)
+	testutil.Chdir(t, dir)
+
+	run := func(args ...string) (string, error) {
+		cmd := scheduleCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("add", "0 22 * * 1-5", "build", "--max", "10")
+	require.NoError(t, err)
+	assert.Contains(t, out, "schedule 1: ralph build --max 10")
+	_, err = run("add", "@daily", "apply")
+	require.ErrorContains(t, err, "a schedule runs one of")
+
+	out, err = run("list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "0 22 * * 1-5")
+	assert.Contains(t, out, "not run yet")
+
+	_, err = run("remove", "1")
+	require.NoError(t, err)
+	_, err = run("remove", "1")
+	require.ErrorContains(t, err, "no schedule 1")
+	out, err = run("list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "No schedules yet")
+}
+
+func TestConfigCmd(t *testing.T) {
+	dir := initRepoWithConfigYAML(t, "project: test\nphases:\n  build:\n    max_itrations: 3\n")
+	testutil.Chdir(t, dir)
+
+	run := func(args ...string) (string, error) {
+		cmd := configCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("validate")
+	require.NoError(t, err)
+	assert.Contains(t, out, `.ralph/config.yaml:4: unknown key "max_itrations" is ignored`)
+	assert.Contains(t, out, "is valid")
+
+	out, err = run("show")
+	require.NoError(t, err)
+	assert.Contains(t, out, "max_itrations: 3", "as written")
+
+	out, err = run("show", "--effective")
+	require.NoError(t, err)
+	assert.Contains(t, out, "max_iterations: 20", "the default the typo left in place")
+	assert.NotContains(t, out, "max_itrations")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".ralph", "config.yaml"), []byte("project: test\nphases:\n  build:\n    max_iterations: 500\n"), 0o600))
+	out, err = run("validate")
+	require.ErrorContains(t, err, "has 1 error(s)")
+	assert.Contains(t, out, ".ralph/config.yaml:4: phases.build.max_iterations exceeds maximum (100)")
+
+	_, err = run("show", "--effective")
+	require.ErrorContains(t, err, "ralph config validate")
+}
+
+func TestStateExportCmd(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	st := &state.State{Runs: []state.RunRecord{{Mode: "build", Status: state.StatusCompleted, TotalCost: 1.5, LogFiles: []string{"logs/20260210-140000.jsonl"}}}}
+	require.NoError(t, state.Save(filepath.Join(dir, state.DefaultPath), st))
+
+	run := func(args ...string) (string, error) {
+		cmd := stateCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("export", "--sql")
+	require.NoError(t, err)
+	assert.Contains(t, out, "INSERT INTO runs VALUES (1, 'build', 'completed'")
+	assert.Contains(t, out, "INSERT INTO iterations VALUES (1, 1, 1, 'logs/20260210-140000.jsonl'")
+
+	_, err = run("export")
+	require.ErrorContains(t, err, "pass --sqlite")
+
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	out, err = run("export", "--sqlite", ".ralph/ralph.db")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Exported run history to .ralph/ralph.db")
+	assert.FileExists(t, filepath.Join(dir, ".ralph", "ralph.db"))
+}
+
+func TestLogsCmd(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	logsDir := filepath.Join(dir, ".ralph", "logs")
+	require.NoError(t, os.MkdirAll(logsDir, 0o750))
+	tool := `{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/workspace/go.mod"}}]}}`
+	for _, name := range []string{"20260210-140000.jsonl", "20260210-141000.jsonl"} {
+		require.NoError(t, os.WriteFile(filepath.Join(logsDir, name), []byte(tool+"\n"+`{"type":"result","total_cost_usd":0.5}`+"\n"), 0o600))
+	}
+	require.NoError(t, state.Save(filepath.Join(dir, state.DefaultPath), &state.State{Runs: []state.RunRecord{
+		{Mode: "plan", LogFiles: []string{"logs/20260210-140000.jsonl"}},
+		{Mode: "build", LogFiles: []string{"logs/20260210-141000.jsonl"}},
+	}}))
+
+	run := func(args ...string) (string, error) {
+		cmd := logsCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run()
+	require.NoError(t, err)
+	assert.Contains(t, out, "run #1 plan · iteration 1/1")
+	assert.Contains(t, out, "run #2 build · iteration 1/1")
+
+	out, err = run("--last", "1")
+	require.NoError(t, err)
+	assert.NotContains(t, out, "20260210-140000.jsonl")
+	assert.Contains(t, out, "20260210-141000.jsonl")
+
+	out, err = run("--replay", "20260210-140000")
+	require.NoError(t, err)
+	assert.Contains(t, out, "· Read /workspace/go.mod")
+
+	_, err = run("20260210-140000")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--replay")
+}
+
+func TestSwarmCmd(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	run := func(args ...string) (string, error) {
+		cmd := swarmCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	_, err := run("build")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--specs")
+
+	_, err = run("build", "main")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "protected branch")
+
+	out, err := run("status")
+	require.NoError(t, err)
+	assert.Contains(t, out, "No swarm yet")
+
+	wt := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(wt, ".ralph"), 0o750))
+	require.NoError(t, state.Save(filepath.Join(wt, state.DefaultPath), &state.State{Runs: []state.RunRecord{{Mode: "build", TotalCost: 2, Status: state.StatusCompleted}}}))
+	man := &swarm.Manifest{Members: []swarm.Member{{Branch: "feature-a", Path: wt, Mode: "build", Started: time.Now(), Finished: time.Now()}}}
+	require.NoError(t, man.Save(filepath.Join(dir, swarm.ManifestPath)))
+	out, err = run("status")
+	require.NoError(t, err)
+	assert.Contains(t, out, "feature-a")
+	assert.Contains(t, out, "$2.0000")
+}
+
+// --- guardCmd ---
+
+func TestGuardCmd(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	tests := []struct {
+		name    string
+		command string
+		blocked bool
+	}{
+		{"allowed", "go test ./...", false},
+		{"force push", "git push --force origin main", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := guardCmd()
+			cmd.SetIn(strings.NewReader(`{"tool_name":"Bash","tool_input":{"command":"` + tt.command + `"}}`))
+
+			err := cmd.Execute()
+			if !tt.blocked {
+				require.NoError(t, err)
+				return
+			}
+			var exitErr *exitError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, guardHookExitCode, exitErr.code)
+			assert.Contains(t, exitErr.msg, guard.BlockedPrefix)
+		})
+	}
+}
+
+func TestGuardCmd_FailsClosed(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".ralph", "config.yaml"), []byte("phases: [\n"), 0o600))
+
+	cmd := guardCmd()
+	cmd.SetIn(strings.NewReader(`{"tool_name":"Bash","tool_input":{"command":"go test ./..."}}`))
+	err := cmd.Execute()
+
+	var exitErr *exitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, guardHookExitCode, exitErr.code)
+	assert.Contains(t, exitErr.msg, guard.BlockedPrefix)
+	assert.Contains(t, exitErr.msg, "loading config")
+}
+
+func TestGuardrailWarning(t *testing.T) {
+	cfg := &config.Config{Agent: agent.NameClaude, Guardrails: config.Guardrails{BlockedCommands: guard.DefaultBlockedCommands}}
+	assert.Empty(t, guardrailWarning(cfg))
+
+	cfg.Agent = agent.NameCodex
+	msg := guardrailWarning(cfg)
+	assert.Contains(t, msg, "Guardrails are off")
+	assert.Contains(t, msg, `"codex"`)
+
+	cfg.Guardrails.BlockedCommands = []string{}
+	assert.Empty(t, guardrailWarning(cfg))
+}
+
+// --- scratchCmd ---
+
+func TestSpecImport(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "Auth Flow.md"), []byte("# Auth\n"), 0o600))
+
+	cmd := specCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"import", src})
+	require.NoError(t, cmd.Execute())
+
+	assert.FileExists(t, filepath.Join(dir, "specs", "feature-test", "auth-flow.md"))
+	assert.Contains(t, out.String(), "Committed 1 spec(s)")
+	log, err := exec.CommandContext(context.Background(), "git", "-C", dir, "log", "-1", "--format=%s", "--name-only").Output()
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "docs(specs): import auth-flow.md")
+	assert.Contains(t, string(log), "specs/feature-test/auth-flow.md")
+}
+
+func TestSpecImport_RefusesWithStagedChanges(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "wip.go"), []byte("package wip\n"), 0o600))
+	testutil.RunGit(t, dir, "add", "wip.go")
+	src := filepath.Join(t.TempDir(), "spec.md")
+	require.NoError(t, os.WriteFile(src, []byte("# Spec\n"), 0o600))
+
+	cmd := specCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"import", src})
+	require.ErrorContains(t, cmd.Execute(), "staged changes")
+
+	cmd = specCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"import", "--no-commit", src})
+	require.NoError(t, cmd.Execute())
+	assert.FileExists(t, filepath.Join(dir, "specs", "feature-test", "spec.md"))
+}
+
+func TestSpecTests(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/x\n\ngo 1.22\n"), 0o600))
+	specs := filepath.Join(dir, "specs", "feature-test")
+	require.NoError(t, os.MkdirAll(specs, 0o750))
+	spec := "# Login\n\n## Acceptance Criteria\n\n- Users can log in\n- Bad passwords are rejected\n"
+	require.NoError(t, os.WriteFile(filepath.Join(specs, "login.md"), []byte(spec), 0o600))
+
+	cmd := specCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"tests", "login.md"})
+	require.NoError(t, cmd.Execute())
+
+	data, err := os.ReadFile(filepath.Join(dir, "acceptance", "login_test.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "func TestLogin_BadPasswordsAreRejected(t *testing.T) {")
+	assert.Contains(t, out.String(), "2 failing test(s)")
+	log, err := exec.CommandContext(context.Background(), "git", "-C", dir, "log", "-1", "--format=%s", "--name-only").Output()
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "test(acceptance): scaffold failing tests for specs/feature-test/login.md")
+	assert.Contains(t, string(log), "acceptance/login_test.go")
+
+	cmd = specCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"tests", "login.md"})
+	require.ErrorContains(t, cmd.Execute(), "--force")
+}
+
+func TestSpecTests_NoCriteria(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "spec.md"), []byte("# Spec\n"), 0o600))
+
+	cmd := specCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"tests", "spec.md"})
+	require.ErrorContains(t, cmd.Execute(), "acceptance criteria")
+}
+
+func TestSpecLint(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	specs := filepath.Join(dir, "specs", "feature-test")
+	require.NoError(t, os.MkdirAll(specs, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(specs, "Login Flow.md"), []byte("# Login\n\nSee [api](api.md).\n"), 0o600))
+
+	cmd := specCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"lint"})
+	err := cmd.Execute()
+	require.ErrorContains(t, err, "3 issue(s)")
+	assert.ErrorContains(t, err, "2 can be fixed with --fix")
+	assert.Contains(t, out.String(), filepath.Join("specs", "feature-test", "Login Flow.md")+":3: link: api.md does not exist")
+
+	out.Reset()
+	cmd = specCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"lint", "--fix"})
+	require.ErrorContains(t, cmd.Execute(), "1 issue(s)")
+	assert.Contains(t, out.String(), "renamed to login-flow.md")
+	assert.FileExists(t, filepath.Join(specs, "login-flow.md"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(specs, "api.md"), []byte("---\ntitle: API\n---\n"), 0o600))
+	out.Reset()
+	cmd = specCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"lint"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "2 spec(s)")
+}
+
+func TestScratchClean(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	var gotProject, gotBranch string
+	cmd := scratchCmd(func(project, branch string) error {
+		gotProject, gotBranch = project, branch
+		return nil
+	})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"clean"})
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, "test", gotProject)
+	assert.Equal(t, "feature-test", gotBranch)
+	assert.Contains(t, out.String(), "ralph-scratch-test-feature-test")
+}
+
+func TestAbortCmd(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	containers := []docker.Container{{ID: "abc123", Branch: "feature-test", Mode: "build"}, {ID: "def456", Branch: "other", Mode: "plan"}}
+	var stopped []string
+	newCmd := func() (*cobra.Command, *bytes.Buffer) {
+		cmd := abortCmd(
+			func(_ context.Context, projectDir string) ([]docker.Container, error) {
+				assert.Equal(t, dir, projectDir)
+				return containers, nil
+			},
+			func(_ context.Context, c docker.Container, _ string, timeout time.Duration) (bool, error) {
+				stopped = append(stopped, c.ID)
+				return timeout < time.Second, nil
+			},
+		)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		return cmd, &out
+	}
+
+	cmd, out := newCmd()
+	cmd.SetArgs([]string{"--branch", "feature-test"})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, []string{"abc123"}, stopped)
+	assert.Contains(t, out.String(), "Stopping build feature-test (abc123)")
+	assert.Contains(t, out.String(), "Stopped")
+
+	stopped = nil
+	cmd, out = newCmd()
+	cmd.SetArgs([]string{"--timeout", "10ms"})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, []string{"abc123", "def456"}, stopped)
+	assert.Contains(t, out.String(), "Killed after 10ms")
+
+	cmd, _ = newCmd()
+	cmd.SetArgs([]string{"--branch", "nope"})
+	require.ErrorContains(t, cmd.Execute(), "no ralph container running")
+}
+
+func TestRootCmd_Aliases(t *testing.T) {
+	root := rootCmd(&fakeOrchestrator{})
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"p"}, "plan"},
+		{[]string{"b"}, "build"},
+		{[]string{"st"}, "status"},
+		{[]string{"spec", "i"}, "import"},
+	}
+	for _, tt := range tests {
+		cmd, _, err := root.Find(tt.args)
+		require.NoError(t, err, tt.args)
+		assert.Equal(t, tt.want, cmd.Name(), tt.args)
+	}
+}
+
+func TestRootCmd_AliasKeepsFlags(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	fake := &fakeOrchestrator{}
+	root := rootCmd(fake)
+	root.SetOut(io.Discard)
+	root.SetArgs([]string{"b", "-n", "3", "--step"})
+	require.NoError(t, root.Execute())
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, "build", fake.calls[0].mode)
+	assert.Equal(t, 3, fake.calls[0].maxIter)
+	assert.True(t, fake.calls[0].step)
+}
+
+func TestRootCmd_Debug(t *testing.T) {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	t.Setenv(debuglog.EnvVar, "")
+	t.Setenv(debuglog.EnvFile, "")
+
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+	planPath := filepath.Join(dir, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-test.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(planPath), 0o750))
+	require.NoError(t, os.WriteFile(planPath, []byte("# Plan\n"), 0o600))
+
+	root := rootCmd(&fakeOrchestrator{})
+	var stderr bytes.Buffer
+	root.SetOut(io.Discard)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"build", "-n", "2"})
+	require.NoError(t, root.Execute())
+	assert.Empty(t, stderr.String(), "off by default")
+
+	logFile := filepath.Join(t.TempDir(), "debug.log")
+	root = rootCmd(&fakeOrchestrator{})
+	root.SetOut(io.Discard)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"build", "-n", "2", "--debug-file", logFile})
+	require.NoError(t, root.Execute())
+	out := stderr.String()
+	assert.Contains(t, out, "msg=ralph")
+	assert.Contains(t, out, `msg=exec cmd="[git -C `, "git commands are traced")
+	assert.Contains(t, out, `msg="run params" mode=build branch=feature-test`)
+	data, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.Equal(t, out, string(data))
+}
+
+func TestRootCmd_LocaleOfInternalCommands(t *testing.T) {
+	t.Cleanup(func() { i18n.Set(i18n.English) })
+	for _, v := range []string{"RALPH_LOCALE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		t.Setenv(v, "")
+	}
+	dir := initRepoWithConfigYAML(t, "project: test\nlocale: es\n")
+	testutil.Chdir(t, dir)
+
+	locale := func(hidden bool) string {
+		var got string
+		root := rootCmd(&fakeOrchestrator{})
+		root.AddCommand(&cobra.Command{Use: "probe", Hidden: hidden, RunE: func(*cobra.Command, []string) error {
+			got = i18n.Current()
+			return nil
+		}})
+		root.SetArgs([]string{"probe"})
+		require.NoError(t, root.Execute())
+		return got
+	}
+	assert.Equal(t, "es", locale(false), "commands take the config's locale")
+	assert.Equal(t, "en", locale(true), "internal commands don't read the config")
+	t.Setenv("RALPH_LOCALE", "es")
+	assert.Equal(t, "es", locale(true), "but take the host's from the environment")
+}
+
+func TestRootCmd_AliasCompletion(t *testing.T) {
+	root := rootCmd(&fakeOrchestrator{})
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{cobra.ShellCompRequestCmd, "st", "--hi"})
+	require.NoError(t, root.Execute())
+	assert.Contains(t, out.String(), "--history")
+}
+
+// --- reportCmd ---
+
+func TestReportCmd_HTML(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	started := time.Now().Add(-time.Hour)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "logs"), 0o750))
+	logName := started.UTC().Format("20060102-150405") + ".jsonl"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logs", logName),
+		[]byte(`{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"widget.go"}}]}}`+"\n"+`{"type":"result","total_cost_usd":0.3}`+"\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "widget.go"), []byte("package widget\n"), 0o600))
+	testutil.RunGit(t, dir, "add", "widget.go")
+	testutil.RunGit(t, dir, "commit", "-m", "feat: add widget")
+
+	require.NoError(t, state.Save(filepath.Join(dir, state.DefaultPath), &state.State{Runs: []state.RunRecord{
+		{Mode: "plan", StartedAt: started.Add(-24 * time.Hour), FinishedAt: started.Add(-23 * time.Hour), Status: state.StatusCompleted},
+		{Mode: "build", StartedAt: started, FinishedAt: time.Now().Add(time.Minute), Iterations: 1, TotalCost: 0.3,
+			Status: state.StatusMaxIterations, LogFiles: []string{"logs/" + logName}},
+	}}))
+
+	out := filepath.Join(t.TempDir(), "run.html")
+	cmd := reportCmd()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"--html", "-o", out})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, buf.String(), "Wrote report for run #2")
+
+	page, err := os.ReadFile(out) //nolint:gosec // test temp file
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "Ralph build run #2 · test")
+	assert.Contains(t, string(page), "widget.go")
+	assert.Contains(t, string(page), "feat: add widget")
+	assert.Contains(t, string(page), "$0.30")
+
+	cmd = reportCmd()
+	buf.Reset()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"--html", "1"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, buf.String(), "Ralph plan run #1")
+	assert.Contains(t, buf.String(), "No commits were made")
+}
+
+func TestReportCmd_Upload(t *testing.T) {
+	var puts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		puts = append(puts, r.Method+" "+r.URL.Path)
+	}))
+	defer srv.Close()
+
+	dir := initRepoWithConfigYAML(t, "project: web\nshare:\n  provider: s3\n  bucket: runs\n  prefix: ralph\n  endpoint: "+srv.URL+"\n")
+	testutil.Chdir(t, dir)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	started := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	require.NoError(t, state.Save(filepath.Join(dir, state.DefaultPath), &state.State{Runs: []state.RunRecord{
+		{Mode: "build", StartedAt: started, FinishedAt: started.Add(time.Hour), Status: state.StatusCompleted},
+	}}))
+
+	cmd := reportCmd()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"--upload"})
+	require.NoError(t, cmd.Execute())
+
+	prefix := "/runs/ralph/web/feature-test/20260301-093000/"
+	assert.Equal(t, []string{"PUT " + prefix + "report.html", "PUT " + prefix + "summary.json"}, puts)
+	assert.Contains(t, buf.String(), srv.URL+prefix+"report.html")
+}
+
+func TestReportCmd_Errors(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	cmd := reportCmd()
+	cmd.SetArgs([]string{})
+	require.ErrorContains(t, cmd.Execute(), "pass --html")
+
+	cmd = reportCmd()
+	cmd.SetArgs([]string{"--html"})
+	require.ErrorContains(t, cmd.Execute(), "no runs recorded")
+
+	require.NoError(t, state.Save(filepath.Join(dir, state.DefaultPath), &state.State{Runs: []state.RunRecord{{Mode: "build"}}}))
+	cmd = reportCmd()
+	cmd.SetArgs([]string{"--html", "2"})
+	require.ErrorContains(t, cmd.Execute(), "from 1 to 1")
+}
+
+func TestParamWidth(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	require.NoError(t, err)
+	defer f.Close() //nolint:errcheck // test cleanup
+
+	assert.Equal(t, 100, paramWidth(100, f))
+	assert.Equal(t, -1, paramWidth(-1, f))
+	assert.Equal(t, stream.DefaultParamWidth, paramWidth(0, f), "not a terminal")
+}
+
+func TestStatusCmd_AllBranches(t *testing.T) {
+	dir := initRepoWithConfig(t)
+	testutil.Chdir(t, dir)
+
+	plans := filepath.Join(dir, ".ralph", "plans")
+	require.NoError(t, os.MkdirAll(plans, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(plans, "IMPLEMENTATION_PLAN_feature-test.md"), []byte("### Task 1: A\n- [x] done\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(plans, "IMPLEMENTATION_PLAN_feat-search.md"), []byte("### Task 1: Index\n- [ ] todo\n"), 0o600))
+
+	cmd := statusCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--all-branches"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "2 branch(es): 1/2 tasks complete (50%)")
+	assert.Contains(t, out.String(), "feat-search")
+	assert.Contains(t, out.String(), "→ Index")
+
+	cmd = statusCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--all-branches", "--history"})
+	require.Error(t, cmd.Execute())
+}
+
+// TestRunLoop_OutputJSONKeepsStdoutToEvents runs the loop in a child
+// process, since runLoop exits it, and checks that in json mode nothing but
+// events reaches stdout.
+func TestRunLoop_OutputJSONKeepsStdoutToEvents(t *testing.T) {
+	if os.Getenv("RALPH_TEST_RUN_LOOP") == "1" {
+		if err := runLoop(loop.ModeBuild, 1); err != nil {
+			fmt.Fprintln(os.Stderr, err) //nolint:errcheck // reported to the parent test
+			os.Exit(1)
+		}
+		return
+	}
+
+	// GitHub reporting with no remote to report to makes runLoop warn
+	// before the loop starts.
+	dir := initRepoWithConfigYAML(t, "project: test\ngithub:\n  report: status\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".ralph", "prompts"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".ralph", "prompts", "build.md"), []byte("Build it.\n"), 0o600))
+
+	bin := t.TempDir()
+	fakeClaude := "#!/bin/sh\ncat >/dev/null\necho 'not json: claude talking to its terminal' >&2\n" +
+		`echo '{"type":"result","usage":{"input_tokens":10,"output_tokens":5},"total_cost_usd":0.01}'` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "claude"), []byte(fakeClaude), 0o700)) //nolint:gosec // test stub must be executable
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=^TestRunLoop_OutputJSONKeepsStdoutToEvents$") //nolint:gosec // re-runs this test binary
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"RALPH_TEST_RUN_LOOP=1",
+		"RALPH_OUTPUT="+loop.OutputJSON,
+		"PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	_ = cmd.Run() //nolint:errcheck // runLoop ends the process with 130 once the loop is done
+
+	assert.Contains(t, stderr.String(), "GitHub status reporting disabled")
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var e loop.Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e), "stdout line %q (stderr: %s)", line, stderr.String())
+		types = append(types, e.Type)
+	}
+	assert.Contains(t, types, loop.EventRunStart)
+	assert.Contains(t, types, loop.EventSummary)
+}
@@ -0,0 +1,192 @@
+// Package agent abstracts the coding agent CLI the loop drives: how to run
+// it non-interactively with the prompt on stdin, and how to turn its output
+// into ralph's stream events, so display, stats, logs and cost work the
+// same whichever agent does the work.
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+// Agents accepted by the agent config key.
+const (
+	NameClaude = "claude"
+	NameCodex  = "codex"
+	NameGemini = "gemini"
+	NameAider  = "aider"
+)
+
+// Valid reports whether name is a supported agent. Empty means claude.
+func Valid(name string) bool {
+	switch name {
+	case "", NameClaude, NameCodex, NameGemini, NameAider:
+		return true
+	}
+	return false
+}
+
+// Invocation is what one iteration asks of the agent.
+type Invocation struct {
+	Model          string   // empty = the agent's default
+	AdditionalDirs []string // other repos the agent may work in
+	Settings       string   // claude --settings JSON (guardrail hooks); other agents ignore it
+	Instructions   string   // static repo instructions (AGENTS.md) for the agent's cached system prompt; empty = none
+	NoPromptCache  bool     // the run opted out of prompt caching; agents that cache by default leave it to the environment
+}
+
+// Agent is a coding agent CLI.
+type Agent interface {
+	// Name is the agent's config name, e.g. "codex".
+	Name() string
+	// Command returns the argv for one non-interactive run that reads the
+	// prompt from stdin.
+	Command(inv Invocation) []string
+	// Events translates the agent's stdout into stream events. Token usage,
+	// and cost when the agent reports it, arrive on a result event.
+	Events(r io.Reader) stream.Source
+}
+
+// New returns the agent called name; empty means claude.
+func New(name string) (Agent, error) {
+	switch name {
+	case "", NameClaude:
+		return Claude{}, nil
+	case NameCodex:
+		return Codex{}, nil
+	case NameGemini:
+		return Gemini{}, nil
+	case NameAider:
+		return Aider{}, nil
+	}
+	return nil, fmt.Errorf("unknown agent %q (want %s, %s, %s or %s)", name, NameClaude, NameCodex, NameGemini, NameAider)
+}
+
+// Event types and content blocks of Claude's stream-json, which every
+// agent's output is translated into.
+const (
+	eventAssistant = "assistant"
+	eventUser      = "user"
+	eventResult    = "result"
+	contentText    = "text"
+	contentToolUse = "tool_use"
+	contentResult  = "tool_result"
+	toolBash       = "Bash"
+)
+
+func textEvent(text string) *stream.Event {
+	return &stream.Event{Type: eventAssistant, Message: &stream.Message{
+		Role:    "assistant",
+		Content: []stream.ContentBlock{{Type: contentText, Text: text}},
+	}}
+}
+
+func toolUseEvent(id, name string, input any) *stream.Event {
+	raw, _ := json.Marshal(input) //nolint:errcheck // maps of strings always marshal
+	return &stream.Event{Type: eventAssistant, Message: &stream.Message{
+		Role:    "assistant",
+		Content: []stream.ContentBlock{{Type: contentToolUse, ID: id, Name: name, Input: raw}},
+	}}
+}
+
+// toolResultEvent reports a finished tool call. Output is also passed as
+// command output so test summaries in it are counted.
+func toolResultEvent(id, output string, exitCode int, failed bool) *stream.Event {
+	content, _ := json.Marshal(output) //nolint:errcheck // strings always marshal
+	return &stream.Event{
+		Type: eventUser,
+		Message: &stream.Message{
+			Role:    "user",
+			Content: []stream.ContentBlock{{Type: contentResult, ToolUseID: id, Content: content, IsError: failed || exitCode != 0}},
+		},
+		ToolUseResult: &stream.ToolUseResult{Stdout: output, ExitCode: exitCode},
+	}
+}
+
+func resultEvent(usage *stream.Usage, costUSD float64) *stream.Event {
+	return &stream.Event{Type: eventResult, Usage: usage, TotalCostUSD: costUSD}
+}
+
+// translator turns an agent's output, line by line, into stream events.
+type translator struct {
+	r       *bufio.Reader
+	line    func(line []byte) []*stream.Event
+	end     func() []*stream.Event // called once at EOF; nil = nothing to add
+	pending []*stream.Event
+	done    bool
+}
+
+func newTranslator(r io.Reader, line func([]byte) []*stream.Event, end func() []*stream.Event) *translator {
+	return &translator{r: bufio.NewReaderSize(r, 64*1024), line: line, end: end}
+}
+
+// Next returns the next translated event, or io.EOF once the output and
+// anything held back for the end have been returned. Lines longer than
+// stream.DefaultMaxLineSize are skipped.
+func (t *translator) Next() (*stream.Event, error) {
+	for len(t.pending) == 0 {
+		if t.done {
+			return nil, io.EOF
+		}
+		line, err := t.r.ReadBytes('\n')
+		if len(line) <= stream.DefaultMaxLineSize+1 {
+			if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+				t.pending = append(t.pending, t.line(line)...)
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("reading agent output: %w", err)
+			}
+			t.done = true
+			if t.end != nil {
+				t.pending = append(t.pending, t.end()...)
+			}
+		}
+	}
+	evt := t.pending[0]
+	t.pending = t.pending[1:]
+	return evt, nil
+}
+
+// CredentialEnv returns the environment variables an agent other than
+// claude can authenticate with; any one of them is enough. Claude's
+// credentials are resolved by the docker package.
+func CredentialEnv(name string) []string {
+	switch name {
+	case NameCodex:
+		return []string{"OPENAI_API_KEY"}
+	case NameGemini:
+		return []string{"GEMINI_API_KEY"}
+	case NameAider:
+		return []string{"ANTHROPIC_API_KEY", "OPENAI_API_KEY", "GEMINI_API_KEY"}
+	}
+	return nil
+}
+
+// EnforcesGuardrails reports whether the agent runs ralph's guard hook
+// before each tool call. Only claude has tool hooks; the other agents run
+// every command unchecked, whatever guardrails.blocked_commands says.
+func EnforcesGuardrails(name string) bool {
+	return name == "" || name == NameClaude
+}
+
+// Domains returns the API hosts an agent other than claude needs through
+// the container firewall, beyond the default allowlist.
+func Domains(name string) []string {
+	switch name {
+	case NameCodex:
+		return []string{"api.openai.com"}
+	case NameGemini:
+		return []string{"generativelanguage.googleapis.com"}
+	case NameAider:
+		return []string{"api.openai.com", "generativelanguage.googleapis.com"}
+	}
+	return nil
+}
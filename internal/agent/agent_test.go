@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+// drain returns every event src yields.
+func drain(t *testing.T, src stream.Source) []*stream.Event {
+	t.Helper()
+	var events []*stream.Event
+	for {
+		evt, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return events
+		}
+		require.NoError(t, err)
+		events = append(events, evt)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"", NameClaude, NameCodex, NameGemini, NameAider} {
+		a, err := New(name)
+		require.NoError(t, err, name)
+		assert.True(t, Valid(name))
+		if name != "" {
+			assert.Equal(t, name, a.Name())
+		}
+	}
+	a, err := New("")
+	require.NoError(t, err)
+	assert.Equal(t, NameClaude, a.Name())
+
+	_, err = New("copilot")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown agent")
+	assert.False(t, Valid("copilot"))
+}
+
+func TestClaude_Command(t *testing.T) {
+	args := Claude{}.Command(Invocation{})
+	assert.Equal(t, "claude", args[0])
+	assert.Contains(t, args, "-p")
+	assert.Contains(t, args, "--dangerously-skip-permissions")
+	assert.Contains(t, args, "--output-format=stream-json")
+	assert.Contains(t, strings.Join(args, " "), "--model "+ClaudeDefaultModel)
+	assert.NotContains(t, args, "--settings")
+
+	joined := strings.Join(Claude{}.Command(Invocation{Model: "sonnet"}), " ")
+	assert.Contains(t, joined, "--model sonnet")
+	assert.NotContains(t, joined, ClaudeDefaultModel)
+}
+
+func TestClaude_Command_AdditionalDirsAndSettings(t *testing.T) {
+	args := Claude{}.Command(Invocation{
+		AdditionalDirs: []string{"/workspace/repo-a", "/workspace/repo-b"},
+		Settings:       `{"hooks":{}}`,
+	})
+	joined := strings.Join(args, " ")
+	assert.Contains(t, joined, "--add-dir /workspace/repo-a --add-dir /workspace/repo-b")
+	assert.Equal(t, []string{"--settings", `{"hooks":{}}`}, args[len(args)-2:])
+}
+
+func TestCommand_PromptCache(t *testing.T) {
+	args := Claude{}.Command(Invocation{Instructions: "# Conventions"})
+	assert.Equal(t, []string{"--append-system-prompt", "# Conventions"}, args[len(args)-2:])
+	assert.NotContains(t, Claude{}.Command(Invocation{}), "--append-system-prompt")
+
+	assert.Contains(t, Aider{}.Command(Invocation{}), "--cache-prompts")
+	assert.NotContains(t, Aider{}.Command(Invocation{NoPromptCache: true}), "--cache-prompts")
+
+	for _, a := range []Agent{Codex{}, Gemini{}} {
+		assert.NotContains(t, strings.Join(a.Command(Invocation{Instructions: "# Conventions"}), " "), "Conventions", a.Name())
+	}
+}
+
+func TestOtherAgents_Command(t *testing.T) {
+	inv := Invocation{Model: "m1", AdditionalDirs: []string{"/w/a", "/w/b"}, Settings: `{"hooks":{}}`}
+
+	codex := strings.Join(Codex{}.Command(inv), " ")
+	assert.True(t, strings.HasPrefix(codex, "codex exec --json"))
+	assert.Contains(t, codex, "--model m1")
+	assert.Contains(t, codex, "--add-dir /w/a --add-dir /w/b")
+	assert.True(t, strings.HasSuffix(codex, " -"))
+
+	gemini := strings.Join(Gemini{}.Command(inv), " ")
+	assert.Contains(t, gemini, "--output-format stream-json")
+	assert.Contains(t, gemini, "--include-directories /w/a,/w/b")
+
+	aider := strings.Join(Aider{}.Command(inv), " ")
+	assert.Contains(t, aider, "--message-file /dev/stdin")
+	assert.Contains(t, aider, "--model m1")
+
+	for _, a := range []Agent{Codex{}, Gemini{}, Aider{}} {
+		assert.NotContains(t, strings.Join(a.Command(inv), " "), "hooks", a.Name())
+		assert.NotContains(t, a.Command(Invocation{}), "--model", a.Name())
+	}
+}
+
+func TestTranslators_FeedStats(t *testing.T) {
+	codex := `{"type":"thread.started","thread_id":"t1"}
+{"type":"item.started","item":{"id":"i1","type":"command_execution","command":"go test ./...","status":"in_progress"}}
+{"type":"item.completed","item":{"id":"i1","type":"command_execution","command":"go test ./...","aggregated_output":"FAIL\tpkg\n","exit_code":1,"status":"failed"}}
+{"type":"item.completed","item":{"id":"i2","type":"agent_message","text":"Fixed it."}}
+{"type":"turn.completed","usage":{"input_tokens":1000,"cached_input_tokens":400,"output_tokens":50}}
+`
+	stats, err := stream.ProcessSource(Codex{}.Events(strings.NewReader(codex)))
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.ToolCalls)
+	assert.Equal(t, 600, stats.InputTokens)
+	assert.Equal(t, 400, stats.CacheReadTokens)
+	assert.Equal(t, 50, stats.OutputTokens)
+	assert.Zero(t, stats.Cost)
+}
+
+func TestEnforcesGuardrails(t *testing.T) {
+	assert.True(t, EnforcesGuardrails(""))
+	assert.True(t, EnforcesGuardrails(NameClaude))
+	for _, name := range []string{NameCodex, NameGemini, NameAider} {
+		assert.False(t, EnforcesGuardrails(name), name)
+	}
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+// Aider runs aider with a single message and plain-text output. Aider
+// commits its own edits, and prints token counts and cost after each
+// reply, which are totalled on the result event.
+type Aider struct{}
+
+// Name returns "aider".
+func (Aider) Name() string { return NameAider }
+
+// Command builds the aider invocation, reading the message from stdin.
+// Additional directories aren't passed: aider works on one repo.
+func (Aider) Command(inv Invocation) []string {
+	args := []string{
+		"aider",
+		"--yes-always",
+		"--no-pretty",
+		"--no-stream",
+		"--no-fancy-input",
+		"--no-check-update",
+		"--no-show-release-notes",
+		"--message-file", "/dev/stdin",
+	}
+	if inv.Model != "" {
+		args = append(args, "--model", inv.Model)
+	}
+	if !inv.NoPromptCache {
+		args = append(args, "--cache-prompts")
+	}
+	return args
+}
+
+var (
+	// aiderTokens matches aider's per-reply report, e.g. "Tokens: 12k sent,
+	// 1.2k cache hit, 850 received. Cost: $0.05 message, $0.10 session."
+	aiderTokens = regexp.MustCompile(`^Tokens: ([\d.]+k?) sent(?:, ([\d.]+k?) cache write)?(?:, ([\d.]+k?) cache hit)?, ([\d.]+k?) received\.(?: Cost: \$([\d.]+) message)?`)
+	// aiderEdit matches the line aider prints after changing a file.
+	aiderEdit = regexp.MustCompile(`^Applied edit to (.+)$`)
+)
+
+// Events turns aider's output into text events, one per line, with applied
+// edits shown as Edit calls.
+func (Aider) Events(r io.Reader) stream.Source {
+	var (
+		usage stream.Usage
+		cost  float64
+		seen  bool
+	)
+	return newTranslator(r, func(line []byte) []*stream.Event {
+		text := strings.TrimRight(string(line), " \t")
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		if m := aiderTokens.FindStringSubmatch(text); m != nil {
+			seen = true
+			cacheWrite, cacheHit := aiderCount(m[2]), aiderCount(m[3])
+			usage.InputTokens += aiderCount(m[1]) - cacheWrite - cacheHit
+			usage.CacheCreationInputTokens += cacheWrite
+			usage.CacheReadInputTokens += cacheHit
+			usage.OutputTokens += aiderCount(m[4])
+			if c, err := strconv.ParseFloat(m[5], 64); err == nil {
+				cost += c
+			}
+			return nil
+		}
+		if m := aiderEdit.FindStringSubmatch(text); m != nil {
+			return []*stream.Event{toolUseEvent("", "Edit", map[string]string{"file_path": m[1]})}
+		}
+		return []*stream.Event{textEvent(text)}
+	}, func() []*stream.Event {
+		if !seen {
+			return nil
+		}
+		return []*stream.Event{resultEvent(&usage, cost)}
+	})
+}
+
+// aiderCount parses a token count as aider prints it, e.g. "850" or "1.2k".
+func aiderCount(s string) int {
+	mult := 1.0
+	if strings.HasSuffix(s, "k") {
+		s, mult = strings.TrimSuffix(s, "k"), 1000
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int(n * mult)
+}
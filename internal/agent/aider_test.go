@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+func TestAider_Events(t *testing.T) {
+	out := `Aider v0.86.1
+I'll add the handler.
+
+Applied edit to internal/api/handler.go
+Commit 1a2b3c4 feat: add handler
+Tokens: 12k sent, 2.5k cache hit, 850 received. Cost: $0.05 message, $0.05 session.
+Tokens: 1.5k sent, 100 received. Cost: $0.01 message, $0.06 session.
+`
+	var buf bytes.Buffer
+	stats, err := stream.ProcessSource(Aider{}.Events(strings.NewReader(out)), stream.NewFormatter(&buf, ui.PlainTheme()))
+	require.NoError(t, err)
+
+	text := buf.String()
+	assert.Contains(t, text, "I'll add the handler.")
+	assert.Contains(t, text, "· Edit internal/api/handler.go")
+	assert.NotContains(t, text, "Tokens:")
+
+	assert.InDelta(t, 0.06, stats.Cost, 1e-9)
+	assert.Equal(t, 11_000, stats.InputTokens)
+	assert.Equal(t, 2_500, stats.CacheReadTokens)
+	assert.Equal(t, 950, stats.OutputTokens)
+}
+
+func TestAiderCount(t *testing.T) {
+	assert.Equal(t, 850, aiderCount("850"))
+	assert.Equal(t, 1_200, aiderCount("1.2k"))
+	assert.Equal(t, 0, aiderCount(""))
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"io"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+// ClaudeDefaultModel is the claude model used unless an experiment
+// overrides it.
+const ClaudeDefaultModel = "opus"
+
+// Claude runs Claude Code, whose stream-json output is ralph's native
+// event format.
+type Claude struct{}
+
+// Name returns "claude".
+func (Claude) Name() string { return NameClaude }
+
+// Command builds the claude -p invocation.
+func (Claude) Command(inv Invocation) []string {
+	model := inv.Model
+	if model == "" {
+		model = ClaudeDefaultModel
+	}
+	args := make([]string, 0, 12+2*len(inv.AdditionalDirs))
+	args = append(args,
+		"claude",
+		"-p",
+		"--dangerously-skip-permissions",
+		"--output-format=stream-json",
+		"--model", model,
+		"--verbose",
+	)
+	for _, dir := range inv.AdditionalDirs {
+		args = append(args, "--add-dir", dir)
+	}
+	if inv.Settings != "" {
+		args = append(args, "--settings", inv.Settings)
+	}
+	if inv.Instructions != "" {
+		// The system prompt is the first thing cached, so instructions that
+		// only change between runs are read from cache on every iteration.
+		args = append(args, "--append-system-prompt", inv.Instructions)
+	}
+	return args
+}
+
+// Events parses claude's stream-json.
+func (Claude) Events(r io.Reader) stream.Source {
+	return stream.NewParser(r)
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+// Codex runs OpenAI's Codex CLI with `codex exec --json`. Codex reports
+// tokens but not cost, so cost comes from the pricing table.
+type Codex struct{}
+
+// Name returns "codex".
+func (Codex) Name() string { return NameCodex }
+
+// Command builds the codex exec invocation; "-" reads the prompt from stdin.
+// The container is the sandbox, so codex's own is bypassed.
+func (Codex) Command(inv Invocation) []string {
+	args := []string{"codex", "exec", "--json", "--dangerously-bypass-approvals-and-sandbox", "--skip-git-repo-check"}
+	if inv.Model != "" {
+		args = append(args, "--model", inv.Model)
+	}
+	for _, dir := range inv.AdditionalDirs {
+		args = append(args, "--add-dir", dir)
+	}
+	return append(args, "-")
+}
+
+// codexEvent is one line of codex exec --json output.
+type codexEvent struct {
+	Type    string      `json:"type"`
+	Item    *codexItem  `json:"item"`
+	Usage   *codexUsage `json:"usage"`
+	Message string      `json:"message"` // error events
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error"` // turn.failed events
+}
+
+type codexItem struct {
+	ID               string `json:"id"`
+	Type             string `json:"type"`
+	Text             string `json:"text"`
+	Command          string `json:"command"`
+	AggregatedOutput string `json:"aggregated_output"`
+	ExitCode         *int   `json:"exit_code"`
+	Status           string `json:"status"`
+	Changes          []struct {
+		Path string `json:"path"`
+		Kind string `json:"kind"`
+	} `json:"changes"`
+	Server string `json:"server"`
+	Tool   string `json:"tool"`
+	Query  string `json:"query"`
+}
+
+type codexUsage struct {
+	InputTokens       int `json:"input_tokens"`
+	CachedInputTokens int `json:"cached_input_tokens"`
+	OutputTokens      int `json:"output_tokens"`
+}
+
+// Events translates codex's JSONL. Commands become Bash tool calls, file
+// changes Edit calls, and the usage of every turn is totalled on one
+// result event at the end.
+func (Codex) Events(r io.Reader) stream.Source {
+	var usage stream.Usage
+	seen := false
+	return newTranslator(r, func(line []byte) []*stream.Event {
+		var evt codexEvent
+		if err := json.Unmarshal(line, &evt); err != nil {
+			return nil // skip malformed lines
+		}
+		switch evt.Type {
+		case "item.started":
+			if evt.Item != nil && evt.Item.Type == "command_execution" {
+				return []*stream.Event{toolUseEvent(evt.Item.ID, toolBash, map[string]string{"command": evt.Item.Command})}
+			}
+		case "item.completed":
+			if evt.Item != nil {
+				return codexItemEvents(evt.Item)
+			}
+		case "turn.completed":
+			if u := evt.Usage; u != nil {
+				seen = true
+				usage.InputTokens += u.InputTokens - u.CachedInputTokens
+				usage.CacheReadInputTokens += u.CachedInputTokens
+				usage.OutputTokens += u.OutputTokens
+			}
+		case "turn.failed":
+			if evt.Error != nil {
+				return []*stream.Event{textEvent("codex: " + evt.Error.Message)}
+			}
+		case "error":
+			return []*stream.Event{textEvent("codex: " + evt.Message)}
+		}
+		return nil
+	}, func() []*stream.Event {
+		if !seen {
+			return nil
+		}
+		return []*stream.Event{resultEvent(&usage, 0)}
+	})
+}
+
+func codexItemEvents(item *codexItem) []*stream.Event {
+	switch item.Type {
+	case "agent_message":
+		if strings.TrimSpace(item.Text) != "" {
+			return []*stream.Event{textEvent(item.Text)}
+		}
+	case "command_execution":
+		code := 0
+		if item.ExitCode != nil {
+			code = *item.ExitCode
+		}
+		return []*stream.Event{toolResultEvent(item.ID, item.AggregatedOutput, code, item.Status == "failed")}
+	case "file_change":
+		events := make([]*stream.Event, 0, len(item.Changes))
+		for _, c := range item.Changes {
+			events = append(events, toolUseEvent(item.ID, "Edit", map[string]string{"file_path": c.Path}))
+		}
+		return events
+	case "mcp_tool_call":
+		return []*stream.Event{toolUseEvent(item.ID, item.Server+"."+item.Tool, map[string]string{})}
+	case "web_search":
+		return []*stream.Event{toolUseEvent(item.ID, "WebSearch", map[string]string{"query": item.Query})}
+	}
+	return nil
+}
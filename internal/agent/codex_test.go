@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+func TestCodex_Events(t *testing.T) {
+	out := `{"type":"item.started","item":{"id":"i1","type":"command_execution","command":"make test","status":"in_progress"}}
+not json
+{"type":"item.completed","item":{"id":"i1","type":"command_execution","command":"make test","aggregated_output":"boom","exit_code":2,"status":"failed"}}
+{"type":"item.completed","item":{"id":"i2","type":"file_change","changes":[{"path":"main.go","kind":"update"}]}}
+{"type":"item.completed","item":{"id":"i3","type":"agent_message","text":"Done."}}
+{"type":"turn.failed","error":{"message":"rate limited"}}
+`
+	var buf bytes.Buffer
+	_, err := stream.ProcessSource(Codex{}.Events(strings.NewReader(out)), stream.NewFormatter(&buf, ui.PlainTheme()))
+	require.NoError(t, err)
+
+	text := buf.String()
+	assert.Contains(t, text, "· Bash make test")
+	assert.Contains(t, text, "✗ exit 2")
+	assert.Contains(t, text, "· Edit main.go")
+	assert.Contains(t, text, "Done.")
+	assert.Contains(t, text, "codex: rate limited")
+}
+
+func TestCodex_Events_NoUsageNoResult(t *testing.T) {
+	events := drain(t, Codex{}.Events(strings.NewReader(`{"type":"item.completed","item":{"id":"i1","type":"agent_message","text":"hi"}}`)))
+	require.Len(t, events, 1)
+	assert.Equal(t, eventAssistant, events[0].Type)
+}
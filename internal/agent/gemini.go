@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+// Gemini runs Google's Gemini CLI with --output-format stream-json. Gemini
+// reports tokens but not cost, so cost comes from the pricing table.
+type Gemini struct{}
+
+// Name returns "gemini".
+func (Gemini) Name() string { return NameGemini }
+
+// Command builds the gemini invocation. With stdin not a terminal, gemini
+// takes the prompt from it and runs headless; --yolo approves every tool
+// call, as the container is the sandbox.
+func (Gemini) Command(inv Invocation) []string {
+	args := []string{"gemini", "--output-format", "stream-json", "--yolo"}
+	if inv.Model != "" {
+		args = append(args, "--model", inv.Model)
+	}
+	if len(inv.AdditionalDirs) > 0 {
+		args = append(args, "--include-directories", strings.Join(inv.AdditionalDirs, ","))
+	}
+	return args
+}
+
+// geminiEvent is one line of gemini's stream-json output.
+type geminiEvent struct {
+	Type       string          `json:"type"`
+	Role       string          `json:"role"`
+	Content    string          `json:"content"`
+	Delta      bool            `json:"delta"`
+	ToolName   string          `json:"tool_name"`
+	ToolID     string          `json:"tool_id"`
+	Parameters json.RawMessage `json:"parameters"`
+	Status     string          `json:"status"`
+	Output     string          `json:"output"`
+	Message    string          `json:"message"`
+	Error      *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Stats *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		Cached       int `json:"cached"`
+	} `json:"stats"`
+}
+
+// geminiTools maps gemini's tool names to Claude's, so the formatter
+// treats shell commands and edits alike for every agent.
+var geminiTools = map[string]string{
+	"run_shell_command":   toolBash,
+	"replace":             "Edit",
+	"write_file":          "Write",
+	"read_file":           "Read",
+	"glob":                "Glob",
+	"search_file_content": "Grep",
+}
+
+// Events translates gemini's stream-json. Assistant text streamed in deltas
+// is joined into one message, flushed before the next non-text event.
+func (Gemini) Events(r io.Reader) stream.Source {
+	var text strings.Builder
+	flush := func(events []*stream.Event) []*stream.Event {
+		if strings.TrimSpace(text.String()) == "" {
+			text.Reset()
+			return events
+		}
+		events = append([]*stream.Event{textEvent(text.String())}, events...)
+		text.Reset()
+		return events
+	}
+	return newTranslator(r, func(line []byte) []*stream.Event {
+		var evt geminiEvent
+		if err := json.Unmarshal(line, &evt); err != nil {
+			return nil // skip malformed lines
+		}
+		switch evt.Type {
+		case "message":
+			if evt.Role == "assistant" {
+				if !evt.Delta {
+					events := flush(nil)
+					text.WriteString(evt.Content)
+					return events
+				}
+				text.WriteString(evt.Content)
+			}
+			return nil
+		case "tool_use":
+			name := geminiTools[evt.ToolName]
+			if name == "" {
+				name = evt.ToolName
+			}
+			input := evt.Parameters
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			return flush([]*stream.Event{toolUseEvent(evt.ToolID, name, input)})
+		case "tool_result":
+			output := evt.Output
+			if evt.Error != nil && output == "" {
+				output = evt.Error.Message
+			}
+			return flush([]*stream.Event{toolResultEvent(evt.ToolID, output, 0, evt.Status == "error")})
+		case "error":
+			return flush([]*stream.Event{textEvent("gemini: " + evt.Message)})
+		case "result":
+			if evt.Stats == nil {
+				return flush(nil)
+			}
+			return flush([]*stream.Event{resultEvent(&stream.Usage{
+				InputTokens:          evt.Stats.InputTokens - evt.Stats.Cached,
+				CacheReadInputTokens: evt.Stats.Cached,
+				OutputTokens:         evt.Stats.OutputTokens,
+			}, 0)})
+		}
+		return flush(nil)
+	}, func() []*stream.Event {
+		return flush(nil)
+	})
+}
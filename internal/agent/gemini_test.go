@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+func TestGemini_Events(t *testing.T) {
+	out := `{"type":"init","session_id":"s1","model":"gemini-2.5-pro"}
+{"type":"message","role":"user","content":"Build the next task."}
+{"type":"message","role":"assistant","content":"Running ","delta":true}
+{"type":"message","role":"assistant","content":"the tests.","delta":true}
+{"type":"tool_use","tool_name":"run_shell_command","tool_id":"c1","parameters":{"command":"npm test"}}
+{"type":"tool_result","tool_id":"c1","status":"error","output":"1 failing"}
+{"type":"message","role":"assistant","content":"All fixed.","delta":true}
+{"type":"result","status":"success","stats":{"total_tokens":1500,"input_tokens":1200,"output_tokens":300,"cached":200}}
+`
+	var buf bytes.Buffer
+	stats, err := stream.ProcessSource(Gemini{}.Events(strings.NewReader(out)), stream.NewFormatter(&buf, ui.PlainTheme()))
+	require.NoError(t, err)
+
+	text := buf.String()
+	assert.Contains(t, text, "Running the tests.\n")
+	assert.Contains(t, text, "· Bash npm test")
+	assert.Contains(t, text, "✗ failed")
+	assert.Contains(t, text, "All fixed.")
+	assert.NotContains(t, text, "Build the next task.")
+	assert.Less(t, strings.Index(text, "Running the tests."), strings.Index(text, "· Bash"))
+
+	assert.Equal(t, 1, stats.ToolCalls)
+	assert.Equal(t, 1000, stats.InputTokens)
+	assert.Equal(t, 200, stats.CacheReadTokens)
+	assert.Equal(t, 300, stats.OutputTokens)
+}
+
+func TestGemini_Events_FlushesTextAtEOF(t *testing.T) {
+	events := drain(t, Gemini{}.Events(strings.NewReader(`{"type":"message","role":"assistant","content":"partial","delta":true}`)))
+	require.Len(t, events, 1)
+	assert.Equal(t, "partial", events[0].Message.Content[0].Text)
+}
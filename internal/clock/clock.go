@@ -0,0 +1,51 @@
+// Package clock is the time source for code that stamps run records and
+// names files by time, so tests can pin it instead of racing the wall clock.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock.
+type System struct{}
+
+// Now returns the current local time.
+func (System) Now() time.Time { return time.Now() }
+
+// Or returns c, or the wall clock when c is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return System{}
+	}
+	return c
+}
+
+// Fake is a Clock for tests. It returns T, then moves T on by Step, so a
+// zero Step keeps time still. Safe for concurrent use.
+type Fake struct {
+	mu   sync.Mutex
+	T    time.Time
+	Step time.Duration
+}
+
+// Now returns the fake time and advances it by Step.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.T
+	f.T = f.T.Add(f.Step)
+	return t
+}
+
+// Advance moves the fake time on by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.T = f.T.Add(d)
+}
@@ -0,0 +1,28 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	f := &Fake{T: start, Step: time.Second}
+	assert.Equal(t, start, f.Now())
+	assert.Equal(t, start.Add(time.Second), f.Now())
+
+	f.Advance(time.Minute)
+	assert.Equal(t, start.Add(2*time.Second+time.Minute), f.Now())
+
+	still := &Fake{T: start}
+	assert.Equal(t, still.Now(), still.Now())
+}
+
+func TestOr(t *testing.T) {
+	assert.IsType(t, System{}, Or(nil))
+	f := &Fake{}
+	assert.Same(t, f, Or(f))
+	assert.WithinDuration(t, time.Now(), System{}.Now(), time.Second)
+}
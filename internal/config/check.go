@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is a problem found in the config file. Line is 1-based; 0 when it
+// can't be tied to a line.
+type Issue struct {
+	Line    int
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return i.Message
+	}
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+// Check parses the config at repoRoot the way Load does, but reports what
+// it finds instead of stopping at the first problem: errors are what makes
+// Load fail, warnings are keys Load silently ignores, usually misspelt or
+// misplaced ones. Validation errors point at the key they name.
+func Check(repoRoot string) (errs, warnings []Issue, err error) {
+	data, err := readConfig(repoRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []Issue{yamlIssue(err.Error())}, nil, nil
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var strict Config
+	if err := dec.Decode(&strict); err != nil && !errors.Is(err, io.EOF) { // EOF: an empty file
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			return []Issue{yamlIssue(err.Error())}, nil, nil
+		}
+		for _, msg := range te.Errors {
+			issue := yamlIssue(msg)
+			if m := unknownField.FindStringSubmatch(issue.Message); m != nil {
+				issue.Message = fmt.Sprintf("unknown key %q is ignored", m[1])
+				warnings = append(warnings, issue)
+			} else {
+				errs = append(errs, issue)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs, warnings, nil
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return []Issue{yamlIssue(err.Error())}, warnings, nil
+	}
+	if err := cfg.validate(); err != nil {
+		errs = append(errs, Issue{Line: keyLine(&root, err.Error()), Message: err.Error()})
+	}
+	return errs, warnings, nil
+}
+
+// readConfig reads .ralph/config.yaml, refusing one over maxConfigSize.
+func readConfig(repoRoot string) ([]byte, error) {
+	path := filepath.Join(repoRoot, ".ralph", "config.yaml")
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	if info.Size() > maxConfigSize {
+		return nil, fmt.Errorf("config file too large: %d bytes (max %d)", info.Size(), maxConfigSize)
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // the repo's own config
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	return data, nil
+}
+
+// unknownField matches the error KnownFields gives for a key with no field.
+var unknownField = regexp.MustCompile(`^field (\S+) not found in type `)
+
+// yamlLine matches the "line N: " yaml.v3 starts its messages with.
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// yamlIssue turns a yaml.v3 message into an Issue, moving its line number
+// into Line.
+func yamlIssue(msg string) Issue {
+	m := yamlLine.FindStringSubmatch(msg)
+	if m == nil {
+		return Issue{Message: strings.TrimPrefix(msg, "yaml: ")}
+	}
+	n, _ := strconv.Atoi(m[1]) //nolint:errcheck // matched digits
+	return Issue{Line: n, Message: msg[len(m[0]):]}
+}
+
+// keyPath matches the dotted config key a validation message starts with,
+// e.g. "phases.plan.max_iterations".
+var keyPath = regexp.MustCompile(`^[a-z_]+(?:\.[A-Za-z0-9_-]+)*`)
+
+// keyLine returns the line of the deepest key in the document that the
+// validation message names, or 0 when it names none.
+func keyLine(root *yaml.Node, msg string) int {
+	path := keyPath.FindString(msg)
+	if path == "" || len(root.Content) == 0 {
+		return 0
+	}
+	node, line := root.Content[0], 0
+	for _, key := range strings.Split(path, ".") {
+		keyNode, value := mappingValue(node, key)
+		if keyNode == nil {
+			break
+		}
+		line, node = keyNode.Line, value
+	}
+	return line
+}
+
+// mappingValue returns key's key and value nodes in a mapping node, or nils.
+func mappingValue(node *yaml.Node, key string) (keyNode, value *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// Effective returns the config as ralph uses it, with every default filled
+// in, as YAML.
+func (c *Config) Effective() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2) // as ralph init writes it
+	if err := enc.Encode(c); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheck_Valid(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+
+	errs, warnings, err := Check(dir)
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+	assert.Empty(t, warnings)
+
+	writeConfig(t, dir, "")
+	errs, _, err = Check(dir)
+	require.NoError(t, err)
+	assert.Empty(t, errs, "an empty file loads")
+}
+
+func TestCheck_UnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `project: test
+phases:
+  plan:
+    promt: .ralph/prompts/plan.md
+experiments:
+  terse:
+    model: sonnet
+colour: blue
+`)
+
+	errs, warnings, err := Check(dir)
+	require.NoError(t, err)
+	assert.Empty(t, errs, "unknown keys don't stop Load")
+	assert.Equal(t, []Issue{
+		{Line: 4, Message: `unknown key "promt" is ignored`},
+		{Line: 8, Message: `unknown key "colour" is ignored`},
+	}, warnings)
+}
+
+func TestCheck_Errors(t *testing.T) {
+	dir := t.TempDir()
+	for yaml, want := range map[string]Issue{
+		"project: test\nphases:\n  build:\n    max_iterations: lots\n": {Line: 4, Message: "cannot unmarshal !!str `lots` into int"},
+		"project: test\nphases:\n  plan:\n    max_iterations: 500\n":   {Line: 4, Message: "phases.plan.max_iterations exceeds maximum (100)"},
+		"project: test\ncost:\n  max_cost: -1\n":                       {Line: 3, Message: "cost.max_cost must be non-negative"},
+		"project: test\nagent: copilot\n":                              {Line: 2, Message: `agent must be "claude", "codex", "gemini" or "aider", got "copilot"`},
+		"project: test\nphases: [\n":                                   {Line: 2, Message: "did not find expected node content"},
+	} {
+		writeConfig(t, dir, yaml)
+		errs, _, err := Check(dir)
+		require.NoError(t, err, yaml)
+		assert.Equal(t, []Issue{want}, errs, yaml)
+	}
+
+	_, _, err := Check(t.TempDir())
+	require.ErrorContains(t, err, "reading config")
+}
+
+func TestEffective(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig+"phases:\n  build:\n    iteration_timeout: 30m\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+
+	out, err := cfg.Effective()
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "\n  plan:\n    prompt: .ralph/prompts/plan.md\n")
+
+	var back Config
+	require.NoError(t, yaml.Unmarshal(out, &back))
+	assert.Equal(t, 20, back.Phases.Build.MaxIterations)
+	assert.Equal(t, 30*time.Minute, back.Phases.Build.IterationTimeout)
+	assert.Equal(t, []string{"main", "master"}, back.ProtectedBranches)
+}
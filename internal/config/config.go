@@ -2,11 +2,26 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"net/url"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/benwilkes9/ralph-cli/internal/agent"
+	"github.com/benwilkes9/ralph-cli/internal/ghstatus"
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/guard"
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/share"
+	"github.com/benwilkes9/ralph-cli/internal/summary"
+	"github.com/benwilkes9/ralph-cli/internal/tokens"
+	"github.com/benwilkes9/ralph-cli/internal/vcs"
 )
 
 // ErrDuplicateBasename is returned when two additional directories share the same basename.
@@ -25,6 +40,33 @@ type Config struct {
 	Phases            Phases       `yaml:"phases"`
 	Network           Network      `yaml:"network,omitempty"`
 	Docker            Docker       `yaml:"docker,omitempty"`
+	Git               Git          `yaml:"git,omitempty"`
+	Guardrails        Guardrails   `yaml:"guardrails,omitempty"`
+	Safety            Safety       `yaml:"safety,omitempty"`
+	Commits           Commits      `yaml:"commits,omitempty"`
+	Codeowners        Codeowners   `yaml:"codeowners,omitempty"`
+	TokenCount        string       `yaml:"token_count,omitempty"`          // off | estimate | api: count the prompt before each iteration
+	ContextWarn       int          `yaml:"context_warn_percent,omitempty"` // warn when prompt, plan and specs fill more of the context window
+	PromptCache       string       `yaml:"prompt_cache,omitempty"`         // on | off: order the prompt for the agent's prompt cache; empty = on
+	ToolParamWidth    int          `yaml:"tool_param_width,omitempty"`     // runes of a tool call's parameter shown in the stream; 0 = fit the terminal, -1 = all
+	StreamFilters     []string     `yaml:"stream_filters,omitempty"`       // commands each stream event passes through before it is shown
+	VCS               string       `yaml:"vcs,omitempty"`                  // auto | git | jj | hg: version control backend; empty = auto
+	Locale            string       `yaml:"locale,omitempty"`               // en | es: language of ralph's output; empty = LC_ALL, LC_MESSAGES or LANG
+	StateDB           string       `yaml:"state_db,omitempty"`             // SQLite database the run history is exported to after every run; empty = off
+	GitHub            GitHub       `yaml:"github,omitempty"`
+	Cost              Cost         `yaml:"cost,omitempty"`
+	Share             Share        `yaml:"share,omitempty"`
+	Init              InitAnswers  `yaml:"init,omitempty"`
+
+	Notifications Notifications `yaml:"notifications,omitempty"`
+	Summary       Summary       `yaml:"summary,omitempty"`
+
+	Experiments map[string]Experiment `yaml:"experiments,omitempty"` // variant name → overrides, selected with --experiment
+}
+
+// Summary configures the job summary shown when a run ends.
+type Summary struct {
+	Rows []string `yaml:"rows,omitempty"` // summary.Rows names, in display order; empty = summary.DefaultRows
 }
 
 // Backpressure defines the commands used to validate code quality between iterations.
@@ -41,7 +83,323 @@ type Network struct {
 
 // Docker holds Docker-specific settings.
 type Docker struct {
-	DepsDir string `yaml:"deps_dir,omitempty"` // relative to project root, e.g. "node_modules"
+	DepsDir        string `yaml:"deps_dir,omitempty"`         // relative to project root, e.g. "node_modules"
+	ScratchLimitMB int    `yaml:"scratch_limit_mb,omitempty"` // size cap for .ralph/scratch; oldest files are pruned past it
+	ContextWarnMB  int    `yaml:"context_warn_mb,omitempty"`  // warn when the image build context is larger
+	DiskWarnMB     int    `yaml:"disk_warn_mb,omitempty"`     // warn when the workspace grows by more during a run
+	DiskLimitMB    int    `yaml:"disk_limit_mb,omitempty"`    // stop the run when the workspace grows by more; 0 = never
+	ImageReport    bool   `yaml:"image_report,omitempty"`     // after each build, report the image's size, layers and known vulnerabilities
+	ImageWarnMB    int    `yaml:"image_warn_mb,omitempty"`    // warn when the image is larger
+	ImageWarnVulns int    `yaml:"image_warn_vulns,omitempty"` // warn when a scan finds more high or critical vulnerabilities; 0 = any
+
+	// Hardening beyond no-new-privileges, each passed to docker run only
+	// when set.
+	ReadOnly       bool    `yaml:"read_only,omitempty"`       // read-only root filesystem; home, /etc, /tmp and /run stay writable
+	SeccompProfile string  `yaml:"seccomp_profile,omitempty"` // seccomp profile JSON, relative to the repo root
+	PidsLimit      int     `yaml:"pids_limit,omitempty"`      // most processes the container may run; 0 = docker's default
+	Memory         string  `yaml:"memory,omitempty"`          // memory limit, e.g. "4g"; empty = none
+	CPUs           float64 `yaml:"cpus,omitempty"`            // CPU limit, e.g. 2 or 1.5; 0 = none
+}
+
+// dockerMemory matches a docker --memory value: a number with an optional
+// b, k, m or g unit.
+var dockerMemory = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
+
+// DefaultScratchLimitMB caps the agent scratchpad when docker.scratch_limit_mb is unset.
+const DefaultScratchLimitMB = 100
+
+// DefaultContextWarnMB is the build context size warned about when
+// docker.context_warn_mb is unset.
+const DefaultContextWarnMB = 100
+
+// Prompt cache settings.
+const (
+	PromptCacheOn  = "on"  // static prompt first, AGENTS.md in the cached system prompt
+	PromptCacheOff = "off" // dynamic header first, and claude's prompt caching disabled
+)
+
+// DefaultSplitTasks is phases.plan.split_tasks when unset.
+const DefaultSplitTasks = 30
+
+// DefaultContextWarnPercent is the share of the model's context window that
+// the prompt, plan and specs may fill before an iteration warns.
+const DefaultContextWarnPercent = 60
+
+// DefaultDiskWarnMB is the workspace growth warned about when
+// docker.disk_warn_mb is unset.
+const DefaultDiskWarnMB = 500
+
+// DefaultImageWarnMB is the image size warned about when
+// docker.image_warn_mb is unset.
+const DefaultImageWarnMB = 2048
+
+// Git holds limits for git subprocesses. Durations use Go syntax, e.g. "30s".
+type Git struct {
+	Timeout        time.Duration `yaml:"timeout,omitempty"`         // per local command (rev-parse, add, commit)
+	NetworkTimeout time.Duration `yaml:"network_timeout,omitempty"` // per remote command (push, pull, ls-remote)
+	HookTimeout    time.Duration `yaml:"hook_timeout,omitempty"`    // per command that runs repo hooks (commit, merge, push)
+
+	// DirtyTree is what plan and build do with uncommitted changes outside
+	// the scaffold files, which would otherwise be visible to the agent in
+	// the mounted workspace: DirtyTreeWarn (the default), DirtyTreeFail,
+	// DirtyTreeStash or DirtyTreeCommit.
+	DirtyTree string `yaml:"dirty_tree,omitempty"`
+
+	PushDebounce PushDebounce `yaml:"push_debounce,omitempty"`
+	Hooks        Hooks        `yaml:"hooks,omitempty"`
+}
+
+// Hooks controls the repo's commit hooks (pre-commit, husky) on the agent's
+// commits. They run by default; Skip turns them off for the agent, and
+// Reason, which Skip requires, is recorded with every run as an audit note.
+type Hooks struct {
+	Skip   bool   `yaml:"skip,omitempty"`
+	Reason string `yaml:"reason,omitempty"`
+}
+
+// PushDebounce holds back the push after each iteration, so remote CI isn't
+// triggered every few minutes. Commits are queued until either limit is
+// reached, and whatever is queued is pushed when the run ends. With neither
+// set every iteration pushes.
+type PushDebounce struct {
+	Interval time.Duration `yaml:"interval,omitempty"` // push at most this often, e.g. "15m"
+	Commits  int           `yaml:"commits,omitempty"`  // push once this many commits are queued
+}
+
+// Dirty tree settings.
+const (
+	DirtyTreeWarn   = "warn"   // list the changes and run with them
+	DirtyTreeFail   = "fail"   // refuse to run
+	DirtyTreeStash  = "stash"  // stash them for the run and restore them after
+	DirtyTreeCommit = "commit" // commit them with a WIP message first
+)
+
+// Commits configures how build iterations label their commits.
+type Commits struct {
+	// RequireTaskID injects the active plan task id (e.g. "T2.1") into the
+	// prompt and warns when an iteration's commits don't mention "[T2.1]".
+	RequireTaskID bool `yaml:"require_task_id,omitempty"`
+	// Amend prefixes the iteration's last commit with the task id when it is
+	// missing and the commit has not been pushed yet.
+	Amend bool `yaml:"amend,omitempty"`
+}
+
+// Codeowners maps CODEOWNERS owners to how the agent treats their paths.
+// Owners are written as in CODEOWNERS, e.g. "@org/security" or an email.
+type Codeowners struct {
+	// Protect lists owners whose paths the agent must not touch. An
+	// iteration whose commits change one is discarded before pushing.
+	Protect []string `yaml:"protect,omitempty"`
+	// Careful lists owners whose paths are flagged in the prompt as
+	// needing extra care.
+	Careful []string `yaml:"careful,omitempty"`
+}
+
+// Enabled reports whether any owners are configured.
+func (c *Codeowners) Enabled() bool {
+	return len(c.Protect) > 0 || len(c.Careful) > 0
+}
+
+// GitHub configures how runs report progress to GitHub.
+type GitHub struct {
+	// Report publishes run progress on the branch head: "status" for a
+	// commit status, "check" for a check run with a markdown summary
+	// (needs a GitHub App installation token in GITHUB_APP_TOKEN). Empty or
+	// "off" disables it.
+	Report string `yaml:"report,omitempty"`
+	// PlanReview opens a draft pull request (or comments on the open one)
+	// with each generated plan, and makes build wait until a reviewer
+	// approves it. Needs GITHUB_PAT with pull request access.
+	PlanReview bool `yaml:"plan_review,omitempty"`
+}
+
+// Notifications configures where run lifecycle events are posted.
+type Notifications struct {
+	// WebhookURL receives a JSON POST on run start, each finished
+	// iteration, stale abort, budget exceeded and run finish. Its host is
+	// added to the container's allowed domains.
+	WebhookURL string `yaml:"webhook_url,omitempty"`
+}
+
+// Domains returns the hosts the loop posts notifications to.
+func (n Notifications) Domains() []string {
+	u, err := url.Parse(n.WebhookURL)
+	if n.WebhookURL == "" || err != nil || u.Hostname() == "" {
+		return nil
+	}
+	return []string{u.Hostname()}
+}
+
+func (n Notifications) validate() error {
+	if n.WebhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(n.WebhookURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("notifications.webhook_url must be an http(s) URL")
+	}
+	return nil
+}
+
+// Cost configures how run costs are computed and shown. Costs are always
+// recorded in US dollars; Currency only changes how they are displayed.
+type Cost struct {
+	Currency string  `yaml:"currency,omitempty"` // ISO 4217 code, e.g. "EUR"; default USD
+	USDRate  float64 `yaml:"usd_rate,omitempty"` // units of Currency per US dollar
+	// Pricing adds or overrides per-model rates (USD per million tokens)
+	// used when the API reports no cost, e.g. behind a gateway. Keys are
+	// model names or fragments of them, e.g. "sonnet".
+	Pricing pricing.Table `yaml:"pricing,omitempty"`
+
+	MaxCost        float64 `yaml:"max_cost,omitempty"`         // USD a plan or build run may spend before it stops; 0 = no cap
+	MaxCostCeiling float64 `yaml:"max_cost_ceiling,omitempty"` // highest cap max_cost or --max-cost may set; 0 = none
+}
+
+// CheckCap reports whether usd is a cap a run may use: non-negative and not
+// over MaxCostCeiling. flag names where usd came from for the error.
+func (c *Cost) CheckCap(flag string, usd float64) error {
+	if usd < 0 {
+		return fmt.Errorf("%s must be non-negative", flag)
+	}
+	if c.MaxCostCeiling > 0 && usd > c.MaxCostCeiling {
+		return fmt.Errorf("%s $%.2f is over cost.max_cost_ceiling $%.2f", flag, usd, c.MaxCostCeiling)
+	}
+	return nil
+}
+
+// Display returns the currency costs are shown in.
+func (c *Cost) Display() pricing.Currency {
+	return pricing.Currency{Code: c.Currency, PerUSD: c.USDRate}
+}
+
+// Table returns the built-in pricing table with the configured overrides.
+func (c *Cost) Table() pricing.Table {
+	return pricing.Default.With(c.Pricing)
+}
+
+// Share configures uploading run reports to object storage, so a run can be
+// linked to. GCS buckets are reached with HMAC keys through its S3 API.
+type Share struct {
+	Provider     string        `yaml:"provider,omitempty"` // s3 | gcs; empty = off
+	Bucket       string        `yaml:"bucket,omitempty"`
+	Prefix       string        `yaml:"prefix,omitempty"`         // key prefix, e.g. "ralph/"
+	Region       string        `yaml:"region,omitempty"`         // S3 region; default us-east-1
+	Endpoint     string        `yaml:"endpoint,omitempty"`       // S3-compatible API root, e.g. a MinIO URL
+	AccessKeyEnv string        `yaml:"access_key_env,omitempty"` // env var holding the access key id
+	SecretKeyEnv string        `yaml:"secret_key_env,omitempty"` // env var holding the secret key
+	LinkExpiry   time.Duration `yaml:"link_expiry,omitempty"`    // presign links for this long (max 168h); 0 = plain URLs
+	Auto         bool          `yaml:"auto,omitempty"`           // upload after every plan and build run
+}
+
+// Enabled reports whether a provider is configured.
+func (s *Share) Enabled() bool {
+	return s.Provider != ""
+}
+
+// CredentialEnv returns the env vars holding the access key id and secret,
+// defaulting to the provider's conventional names.
+func (s *Share) CredentialEnv() (accessKey, secretKey string) {
+	accessKey, secretKey = share.DefaultCredentialEnv(s.Provider)
+	if s.AccessKeyEnv != "" {
+		accessKey = s.AccessKeyEnv
+	}
+	if s.SecretKeyEnv != "" {
+		secretKey = s.SecretKeyEnv
+	}
+	return accessKey, secretKey
+}
+
+func (s *Share) validate() error {
+	if !share.ValidProvider(s.Provider) {
+		return fmt.Errorf("share.provider must be %q or %q, got %q", share.ProviderS3, share.ProviderGCS, s.Provider)
+	}
+	if s.Enabled() && s.Bucket == "" {
+		return fmt.Errorf("share.bucket is required when share.provider is set")
+	}
+	if s.LinkExpiry < 0 || s.LinkExpiry > share.MaxLinkExpiry {
+		return fmt.Errorf("share.link_expiry must be between 0 and %s, got %s", share.MaxLinkExpiry, s.LinkExpiry)
+	}
+	return nil
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func (c *Cost) validate() error {
+	if c.Currency != "" && !currencyCode.MatchString(c.Currency) {
+		return fmt.Errorf("cost.currency must be an ISO 4217 code like \"EUR\", got %q", c.Currency)
+	}
+	if c.USDRate < 0 {
+		return fmt.Errorf("cost.usd_rate must be non-negative")
+	}
+	if c.Currency != "" && c.Currency != pricing.USD && c.USDRate == 0 {
+		return fmt.Errorf("cost.usd_rate is required with cost.currency %s (units of %s per US dollar)", c.Currency, c.Currency)
+	}
+	if c.MaxCostCeiling < 0 {
+		return fmt.Errorf("cost.max_cost_ceiling must be non-negative")
+	}
+	if err := c.CheckCap("cost.max_cost", c.MaxCost); err != nil {
+		return err
+	}
+	for model, r := range c.Pricing {
+		if r.Input < 0 || r.Output < 0 || r.CacheWrite < 0 || r.CacheRead < 0 {
+			return fmt.Errorf("cost.pricing.%s: rates must be non-negative", model)
+		}
+	}
+	return nil
+}
+
+// InitAnswers records the answers given to `ralph init` that aren't
+// otherwise kept in the config, so a re-run can offer them as defaults.
+type InitAnswers struct {
+	RunCmd string `yaml:"run_cmd,omitempty"`
+	Goal   string `yaml:"goal,omitempty"`
+}
+
+// Experiment is a prompt/model variant that plan and build can run with
+// --experiment, so outcomes can be compared with `ralph compare --experiment`.
+// Empty fields fall back to the phase defaults.
+type Experiment struct {
+	PlanPrompt  string `yaml:"plan_prompt,omitempty"`  // replaces phases.plan.prompt
+	BuildPrompt string `yaml:"build_prompt,omitempty"` // replaces phases.build.prompt
+	Model       string `yaml:"model,omitempty"`        // claude --model, e.g. "sonnet"
+}
+
+// Prompt returns the variant's prompt for mode ("plan" or "build"), or
+// fallback when it doesn't override that phase.
+func (e *Experiment) Prompt(mode, fallback string) string {
+	p := e.BuildPrompt
+	if mode == "plan" {
+		p = e.PlanPrompt
+	}
+	if p == "" {
+		return fallback
+	}
+	return p
+}
+
+// experimentName restricts variant names and models to characters that are
+// safe in env vars and on the claude command line.
+var experimentName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Guardrails configures the PreToolUse hook that blocks destructive Bash
+// commands inside the container.
+type Guardrails struct {
+	// BlockedCommands are regexps matched against each Bash command.
+	// Omitted = built-in defaults; an explicit empty list disables the hook.
+	BlockedCommands []string `yaml:"blocked_commands"`
+	// SecretsRotated are secret files (e.g. ".env") found in recent history
+	// whose keys have been rotated, so the history no longer blocks runs.
+	SecretsRotated []string `yaml:"secrets_rotated,omitempty"`
+	// RedactPatterns are regexps for secrets masked in the agent's output and
+	// logs, on top of the .env values and well-known key formats.
+	RedactPatterns []string `yaml:"redact_patterns,omitempty"`
+}
+
+// Safety configures what ralph saves so a run can be undone.
+type Safety struct {
+	// Snapshot saves the workspace (branch head, uncommitted changes and
+	// untracked files) before each run, for ralph snapshot restore.
+	Snapshot bool `yaml:"snapshot,omitempty"`
 }
 
 // Phases groups the plan and build phase configurations.
@@ -52,30 +410,58 @@ type Phases struct {
 
 // PhaseConfig holds settings for a single loop phase (plan or build).
 type PhaseConfig struct {
-	Prompt        string `yaml:"prompt"`
-	Output        string `yaml:"output,omitempty"`
-	MaxIterations int    `yaml:"max_iterations"`
-	FreshContext  bool   `yaml:"fresh_context,omitempty"`
+	Prompt string `yaml:"prompt"`
+	Output string `yaml:"output,omitempty"`
+	// FilenameTemplate names the plan file inside Output (plan phase only),
+	// e.g. "PLAN-{branch}.md". Variables: {branch}, {project}, {date}.
+	FilenameTemplate string `yaml:"filename_template,omitempty"`
+	MaxIterations    int    `yaml:"max_iterations"`
+	FreshContext     bool   `yaml:"fresh_context,omitempty"`
+	// SpecsDir overrides the top-level specs_dir for this phase, e.g.
+	// research notes for plan and implementation specs for build. It
+	// follows specs_dir_exact like specs_dir does.
+	SpecsDir string `yaml:"specs_dir,omitempty"`
+	// SplitTasks is the plan size past which ralph suggests splitting the
+	// plan into milestones, and the most tasks a milestone gets (plan phase
+	// only).
+	SplitTasks int `yaml:"split_tasks,omitempty"`
+	// Models routes build iterations to a model by the active task's label
+	// or category (refactor, tests, docs), e.g. cheaper models for mechanical
+	// work (build phase only). Unmatched tasks use the default model.
+	Models map[string]string `yaml:"models,omitempty"`
+	// IterationTimeout kills an iteration's agent once it has run this
+	// long, so a hung process can't stall the loop; the loop moves on to the
+	// next iteration. 0 = no limit.
+	IterationTimeout time.Duration `yaml:"iteration_timeout,omitempty"`
+	// MaxTimeouts is how many iterations in a row may time out before the
+	// run aborts. 0 = 3.
+	MaxTimeouts int `yaml:"max_timeouts,omitempty"`
+	// StalePlan is what a build does when the plan may be out of date with
+	// its specs (build phase only): warn, block or off. Empty = warn.
+	StalePlan string `yaml:"stale_plan,omitempty"`
+	// PlanMaxAgeDays is how old a plan may get, with its specs unchanged,
+	// before a build calls it stale (build phase only). 0 = 14.
+	PlanMaxAgeDays int `yaml:"plan_max_age_days,omitempty"`
 }
 
+// Stale plan policies for phases.build.stale_plan.
+const (
+	StalePlanWarn  = "warn"
+	StalePlanBlock = "block"
+	StalePlanOff   = "off"
+)
+
+// DefaultPlanMaxAgeDays is phases.build.plan_max_age_days when unset.
+const DefaultPlanMaxAgeDays = 14
+
 // maxConfigSize is the maximum config file size we'll read (64 KiB).
 const maxConfigSize = 64 * 1024
 
 // Load reads .ralph/config.yaml from the given repo root.
 func Load(repoRoot string) (*Config, error) {
-	path := filepath.Join(repoRoot, ".ralph", "config.yaml")
-
-	info, err := os.Stat(path)
-	if err != nil {
-		return nil, fmt.Errorf("reading config: %w", err)
-	}
-	if info.Size() > maxConfigSize {
-		return nil, fmt.Errorf("config file too large: %d bytes (max %d)", info.Size(), maxConfigSize)
-	}
-
-	data, err := os.ReadFile(path)
+	data, err := readConfig(repoRoot)
 	if err != nil {
-		return nil, fmt.Errorf("reading config: %w", err)
+		return nil, err
 	}
 
 	var cfg Config
@@ -92,6 +478,9 @@ func Load(repoRoot string) (*Config, error) {
 }
 
 func (c *Config) validate() error {
+	if !agent.Valid(c.Agent) {
+		return fmt.Errorf("agent must be %q, %q, %q or %q, got %q", agent.NameClaude, agent.NameCodex, agent.NameGemini, agent.NameAider, c.Agent)
+	}
 	if c.Phases.Plan.MaxIterations < 0 {
 		return fmt.Errorf("phases.plan.max_iterations must be non-negative")
 	}
@@ -104,6 +493,103 @@ func (c *Config) validate() error {
 	if c.Phases.Build.MaxIterations > 100 {
 		return fmt.Errorf("phases.build.max_iterations exceeds maximum (100)")
 	}
+	if c.Phases.Plan.SplitTasks < 0 {
+		return fmt.Errorf("phases.plan.split_tasks must be non-negative")
+	}
+	for name, p := range map[string]*PhaseConfig{"plan": &c.Phases.Plan, "build": &c.Phases.Build} {
+		if p.IterationTimeout < 0 {
+			return fmt.Errorf("phases.%s.iteration_timeout must be non-negative", name)
+		}
+		if p.MaxTimeouts < 0 {
+			return fmt.Errorf("phases.%s.max_timeouts must be non-negative", name)
+		}
+	}
+	for _, r := range c.Summary.Rows {
+		if !slices.Contains(summary.Rows, r) {
+			return fmt.Errorf("summary.rows: unknown row %q, want one of %s", r, strings.Join(summary.Rows, ", "))
+		}
+	}
+	for _, p := range c.ProtectedBranches {
+		if _, err := git.MatchProtected("", p); err != nil {
+			return fmt.Errorf("protected_branches: %w", err)
+		}
+	}
+	if c.Phases.Plan.StalePlan != "" || c.Phases.Plan.PlanMaxAgeDays != 0 {
+		return fmt.Errorf("phases.plan: stale_plan and plan_max_age_days apply to the build phase only")
+	}
+	switch c.Phases.Build.StalePlan {
+	case "", StalePlanWarn, StalePlanBlock, StalePlanOff:
+	default:
+		return fmt.Errorf("phases.build.stale_plan must be %q, %q or %q, got %q",
+			StalePlanWarn, StalePlanBlock, StalePlanOff, c.Phases.Build.StalePlan)
+	}
+	if c.Phases.Build.PlanMaxAgeDays < 0 {
+		return fmt.Errorf("phases.build.plan_max_age_days must be non-negative")
+	}
+	if len(c.Phases.Plan.Models) > 0 {
+		return fmt.Errorf("phases.plan.models: model routing applies to build tasks only")
+	}
+	for label, model := range c.Phases.Build.Models {
+		if label == "" || strings.TrimSpace(model) == "" {
+			return fmt.Errorf("phases.build.models: %q: want a task label mapped to a model", label)
+		}
+	}
+
+	if c.Git.Timeout < 0 {
+		return fmt.Errorf("git.timeout must be non-negative")
+	}
+	if c.Git.NetworkTimeout < 0 {
+		return fmt.Errorf("git.network_timeout must be non-negative")
+	}
+	if c.Git.HookTimeout < 0 {
+		return fmt.Errorf("git.hook_timeout must be non-negative")
+	}
+	if c.Git.PushDebounce.Interval < 0 {
+		return fmt.Errorf("git.push_debounce.interval must be non-negative")
+	}
+	if c.Git.PushDebounce.Commits < 0 {
+		return fmt.Errorf("git.push_debounce.commits must be non-negative")
+	}
+	switch c.Git.DirtyTree {
+	case "", DirtyTreeWarn, DirtyTreeFail, DirtyTreeStash, DirtyTreeCommit:
+	default:
+		return fmt.Errorf("git.dirty_tree must be %q, %q, %q or %q, got %q",
+			DirtyTreeWarn, DirtyTreeFail, DirtyTreeStash, DirtyTreeCommit, c.Git.DirtyTree)
+	}
+	if c.Git.Hooks.Skip && strings.TrimSpace(c.Git.Hooks.Reason) == "" {
+		return fmt.Errorf("git.hooks.skip requires git.hooks.reason, which each run records")
+	}
+
+	if c.Docker.ScratchLimitMB < 0 {
+		return fmt.Errorf("docker.scratch_limit_mb must be non-negative")
+	}
+	if c.Docker.ContextWarnMB < 0 {
+		return fmt.Errorf("docker.context_warn_mb must be non-negative")
+	}
+	if c.Docker.DiskWarnMB < 0 {
+		return fmt.Errorf("docker.disk_warn_mb must be non-negative")
+	}
+	if c.Docker.DiskLimitMB < 0 {
+		return fmt.Errorf("docker.disk_limit_mb must be non-negative")
+	}
+	if c.Docker.ImageWarnMB < 0 {
+		return fmt.Errorf("docker.image_warn_mb must be non-negative")
+	}
+	if c.Docker.ImageWarnVulns < 0 {
+		return fmt.Errorf("docker.image_warn_vulns must be non-negative")
+	}
+	if c.Docker.SeccompProfile == "unconfined" {
+		return fmt.Errorf("docker.seccomp_profile must be a profile file; unconfined would remove docker's default filter")
+	}
+	if c.Docker.PidsLimit < 0 {
+		return fmt.Errorf("docker.pids_limit must be non-negative")
+	}
+	if c.Docker.Memory != "" && !dockerMemory.MatchString(c.Docker.Memory) {
+		return fmt.Errorf("docker.memory must be a size such as 512m or 4g, got %q", c.Docker.Memory)
+	}
+	if c.Docker.CPUs < 0 {
+		return fmt.Errorf("docker.cpus must be non-negative")
+	}
 
 	if c.Docker.DepsDir != "" {
 		clean := filepath.Clean(c.Docker.DepsDir)
@@ -113,21 +599,162 @@ func (c *Config) validate() error {
 		}
 	}
 
+	if err := c.validatePlanTemplate(); err != nil {
+		return err
+	}
+
+	if c.Commits.Amend && !c.Commits.RequireTaskID {
+		return fmt.Errorf("commits.amend requires commits.require_task_id")
+	}
+
+	if _, err := guard.Compile(c.Guardrails.BlockedCommands); err != nil {
+		return fmt.Errorf("guardrails.blocked_commands: %w", err)
+	}
+	if len(c.Guardrails.BlockedCommands) > 0 && !agent.EnforcesGuardrails(c.Agent) {
+		return fmt.Errorf("guardrails.blocked_commands can't be enforced by agent %q, which has no tool hooks; use agent %q, or set it to [] to run without guardrails", c.Agent, agent.NameClaude)
+	}
+	for i, f := range c.StreamFilters {
+		if strings.TrimSpace(f) == "" {
+			return fmt.Errorf("stream_filters[%d] is empty", i)
+		}
+	}
+	for _, p := range c.Guardrails.RedactPatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("guardrails.redact_patterns: %q: %w", p, err)
+		}
+	}
+
+	for field, owners := range map[string][]string{"protect": c.Codeowners.Protect, "careful": c.Codeowners.Careful} {
+		for _, o := range owners {
+			if !strings.Contains(o, "@") || strings.ContainsAny(o, " \t") {
+				return fmt.Errorf("codeowners.%s: %q is not a CODEOWNERS owner (want @user, @org/team or an email)", field, o)
+			}
+		}
+	}
+
+	if !vcs.ValidKind(c.VCS) {
+		return fmt.Errorf("vcs must be %q, %q, %q or %q, got %q", vcs.KindAuto, vcs.KindGit, vcs.KindJujutsu, vcs.KindMercurial, c.VCS)
+	}
+	if !tokens.ValidMode(c.TokenCount) {
+		return fmt.Errorf("token_count must be %q, %q or %q, got %q", tokens.ModeOff, tokens.ModeEstimate, tokens.ModeAPI, c.TokenCount)
+	}
+	if c.PromptCache != "" && c.PromptCache != PromptCacheOn && c.PromptCache != PromptCacheOff {
+		return fmt.Errorf("prompt_cache must be %q or %q, got %q", PromptCacheOn, PromptCacheOff, c.PromptCache)
+	}
+	if c.ToolParamWidth < -1 {
+		return fmt.Errorf("tool_param_width must be 0 (fit the terminal), -1 (no limit) or a positive width, got %d", c.ToolParamWidth)
+	}
+	if !i18n.Valid(c.Locale) {
+		return fmt.Errorf("locale must be one of %s, got %q", strings.Join(i18n.Supported, ", "), c.Locale)
+	}
+	if c.ContextWarn < 0 || c.ContextWarn > 100 {
+		return fmt.Errorf("context_warn_percent must be between 1 and 100, got %d", c.ContextWarn)
+	}
+
+	if !ghstatus.ValidKind(c.GitHub.Report) {
+		return fmt.Errorf("github.report must be %q, %q or %q, got %q", ghstatus.KindOff, ghstatus.KindStatus, ghstatus.KindCheck, c.GitHub.Report)
+	}
+
+	if err := c.Notifications.validate(); err != nil {
+		return err
+	}
+	if err := c.Cost.validate(); err != nil {
+		return err
+	}
+	if err := c.Share.validate(); err != nil {
+		return err
+	}
+
 	if err := c.validateAdditionalDirs(); err != nil {
 		return err
 	}
 
-	if c.SpecsDir != "" {
-		clean := filepath.Clean(c.SpecsDir)
+	if err := c.validateExperiments(); err != nil {
+		return err
+	}
+
+	for field, dir := range map[string]string{
+		"specs_dir":              c.SpecsDir,
+		"phases.plan.specs_dir":  c.Phases.Plan.SpecsDir,
+		"phases.build.specs_dir": c.Phases.Build.SpecsDir,
+	} {
+		if dir == "" {
+			continue
+		}
+		clean := filepath.Clean(dir)
 		if filepath.IsAbs(clean) || clean == "." || clean == ".." ||
 			strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
-			return fmt.Errorf("specs_dir must be a relative path within the project, got %q", c.SpecsDir)
+			return fmt.Errorf("%s must be a relative path within the project, got %q", field, dir)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateExperiments() error {
+	for name, e := range c.Experiments {
+		if !experimentName.MatchString(name) {
+			return fmt.Errorf("experiments: name %q must contain only letters, digits, '.', '_' or '-'", name)
+		}
+		if e.PlanPrompt == "" && e.BuildPrompt == "" && e.Model == "" {
+			return fmt.Errorf("experiments.%s: set at least one of plan_prompt, build_prompt or model", name)
+		}
+		for field, p := range map[string]string{"plan_prompt": e.PlanPrompt, "build_prompt": e.BuildPrompt} {
+			if p != "" && !withinProject(p) {
+				return fmt.Errorf("experiments.%s.%s must be a relative path within the project, got %q", name, field, p)
+			}
+		}
+		if e.Model != "" && !experimentName.MatchString(e.Model) {
+			return fmt.Errorf("experiments.%s.model %q must contain only letters, digits, '.', '_' or '-'", name, e.Model)
 		}
 	}
+	return nil
+}
+
+// planTemplateChars restricts plan filename templates to characters that are
+// safe in paths and free of glob metacharacters.
+var planTemplateChars = regexp.MustCompile(`^[a-zA-Z0-9._/{}-]+$`)
+
+// planTemplateVar matches a {variable} in a plan filename template.
+var planTemplateVar = regexp.MustCompile(`\{([^{}]*)\}`)
 
+func (c *Config) validatePlanTemplate() error {
+	tmpl := c.Phases.Plan.FilenameTemplate
+	if tmpl == "" {
+		return nil
+	}
+	if !planTemplateChars.MatchString(tmpl) {
+		return fmt.Errorf("phases.plan.filename_template %q may only contain letters, digits, '.', '_', '-', '/' and {variables}", tmpl)
+	}
+	for _, m := range planTemplateVar.FindAllStringSubmatch(tmpl, -1) {
+		switch m[1] {
+		case "branch", "project", "date":
+		default:
+			return fmt.Errorf("phases.plan.filename_template: unknown variable {%s} (want {branch}, {project} or {date})", m[1])
+		}
+	}
+	if strings.ContainsAny(planTemplateVar.ReplaceAllString(tmpl, ""), "{}") {
+		return fmt.Errorf("phases.plan.filename_template %q has an unbalanced brace", tmpl)
+	}
+	if !strings.Contains(tmpl, "{branch}") {
+		return fmt.Errorf("phases.plan.filename_template must include {branch} so each branch gets its own plan")
+	}
+	if !withinProject(tmpl) || strings.HasSuffix(tmpl, "/") {
+		return fmt.Errorf("phases.plan.filename_template must be a relative file path within the project, got %q", tmpl)
+	}
+	if c.Phases.Plan.Output != "" && !strings.HasSuffix(c.Phases.Plan.Output, "/") {
+		return fmt.Errorf("phases.plan.filename_template requires phases.plan.output to be a directory (ending in /)")
+	}
 	return nil
 }
 
+// withinProject reports whether path is relative and stays inside the project.
+func withinProject(path string) bool {
+	clean := filepath.Clean(path)
+	return !filepath.IsAbs(clean) && clean != "." && clean != ".." &&
+		!strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
 func (c *Config) validateAdditionalDirs() error {
 	seen := make(map[string]bool, len(c.AdditionalDirs))
 	basenames := make(map[string]bool, len(c.AdditionalDirs))
@@ -166,7 +793,10 @@ func (c *Config) validateAdditionalDirs() error {
 
 func (c *Config) applyDefaults() {
 	if c.Agent == "" {
-		c.Agent = "claude"
+		c.Agent = agent.NameClaude
+	}
+	if c.Guardrails.BlockedCommands == nil {
+		c.Guardrails.BlockedCommands = guard.DefaultBlockedCommands
 	}
 	if c.ProtectedBranches == nil {
 		c.ProtectedBranches = []string{"main", "master"}
@@ -180,19 +810,78 @@ func (c *Config) applyDefaults() {
 	if c.Phases.Plan.MaxIterations == 0 {
 		c.Phases.Plan.MaxIterations = 5
 	}
+	if c.Phases.Plan.SplitTasks == 0 {
+		c.Phases.Plan.SplitTasks = DefaultSplitTasks
+	}
 	if c.Phases.Build.Prompt == "" {
 		c.Phases.Build.Prompt = ".ralph/prompts/build.md"
 	}
 	if c.Phases.Build.MaxIterations == 0 {
 		c.Phases.Build.MaxIterations = 20
 	}
+	if c.Docker.ScratchLimitMB == 0 {
+		c.Docker.ScratchLimitMB = DefaultScratchLimitMB
+	}
+	if c.Docker.ContextWarnMB == 0 {
+		c.Docker.ContextWarnMB = DefaultContextWarnMB
+	}
+	if c.Docker.DiskWarnMB == 0 {
+		c.Docker.DiskWarnMB = DefaultDiskWarnMB
+	}
+	if c.Phases.Build.StalePlan == "" {
+		c.Phases.Build.StalePlan = StalePlanWarn
+	}
+	if c.Git.DirtyTree == "" {
+		c.Git.DirtyTree = DirtyTreeWarn
+	}
+	if c.Phases.Build.PlanMaxAgeDays == 0 {
+		c.Phases.Build.PlanMaxAgeDays = DefaultPlanMaxAgeDays
+	}
+	if c.Docker.ImageWarnMB == 0 {
+		c.Docker.ImageWarnMB = DefaultImageWarnMB
+	}
+	if c.ContextWarn == 0 {
+		c.ContextWarn = DefaultContextWarnPercent
+	}
+	if c.PromptCache == "" {
+		c.PromptCache = PromptCacheOn
+	}
 }
 
 // SpecsDirForBranch returns the resolved specs directory path.
 // When SpecsDirExact is true, SpecsDir is returned as-is.
 // Otherwise the sanitized branch is appended: e.g. "specs" → "specs/my-feature".
 func (c *Config) SpecsDirForBranch(sanitizedBranch string) string {
+	return c.specsDir(c.SpecsDir, sanitizedBranch)
+}
+
+// SpecsDirForPhase is SpecsDirForBranch for the "plan" or "build" phase,
+// using phases.<mode>.specs_dir when it is set.
+func (c *Config) SpecsDirForPhase(mode, sanitizedBranch string) string {
+	return c.specsDir(c.SpecsRootForPhase(mode), sanitizedBranch)
+}
+
+// SpecsRootForPhase returns the directory the "plan" or "build" phase's
+// per-branch specs directories are in, e.g. "specs".
+func (c *Config) SpecsRootForPhase(mode string) string {
 	base := c.SpecsDir
+	switch mode {
+	case "plan":
+		if c.Phases.Plan.SpecsDir != "" {
+			base = c.Phases.Plan.SpecsDir
+		}
+	case "build":
+		if c.Phases.Build.SpecsDir != "" {
+			base = c.Phases.Build.SpecsDir
+		}
+	}
+	if base == "" {
+		base = "specs"
+	}
+	return base
+}
+
+func (c *Config) specsDir(base, sanitizedBranch string) string {
 	if base == "" {
 		base = "specs"
 	}
@@ -205,12 +894,17 @@ func (c *Config) SpecsDirForBranch(sanitizedBranch string) string {
 // PlanPathForBranch returns the branch-specific plan file path.
 // sanitizedBranch must already be sanitized via git.SanitizeBranch.
 // If the configured output is a directory (ends with /), the plan file is
-// placed inside it as IMPLEMENTATION_PLAN_{sanitized-branch}.md.
+// placed inside it, named by phases.plan.filename_template or else
+// IMPLEMENTATION_PLAN_{sanitized-branch}.md.
 // If the user set a custom file path, the branch suffix is inserted before
 // the extension.
 func (c *Config) PlanPathForBranch(sanitizedBranch string) string {
 	output := c.Phases.Plan.Output
 
+	if tmpl := c.Phases.Plan.FilenameTemplate; tmpl != "" {
+		return c.templatePlanPath(output+tmpl, sanitizedBranch, time.Now())
+	}
+
 	if strings.HasSuffix(output, "/") {
 		return output + "IMPLEMENTATION_PLAN_" + sanitizedBranch + ".md"
 	}
@@ -220,3 +914,51 @@ func (c *Config) PlanPathForBranch(sanitizedBranch string) string {
 	base := strings.TrimSuffix(output, ext)
 	return base + "_" + sanitizedBranch + ext
 }
+
+// PlansDir returns the directory plan files are written to, e.g.
+// ".ralph/plans", or for a custom plan file path the directory it is in.
+func (c *Config) PlansDir() string {
+	output := c.Phases.Plan.Output
+	if strings.HasSuffix(output, "/") {
+		return strings.TrimSuffix(output, "/")
+	}
+	return filepath.Dir(output)
+}
+
+// planDateLayout formats {date} in plan filename templates.
+const planDateLayout = "2006-01-02"
+
+// templatePlanPath renders a plan filename template. A {date} is the day
+// the plan was first written: an existing plan matching the template with
+// any date is reused (the latest, if several), so the path doesn't change
+// the next day. Otherwise it is now's date.
+func (c *Config) templatePlanPath(tmpl, sanitizedBranch string, now time.Time) string {
+	path := strings.NewReplacer(
+		"{branch}", sanitizedBranch,
+		"{project}", projectSlug(c.Project),
+	).Replace(tmpl)
+	if !strings.Contains(path, "{date}") {
+		return path
+	}
+
+	pattern := strings.ReplaceAll(path, "{date}", "[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]")
+	if matches, _ := filepath.Glob(pattern); len(matches) > 0 { //nolint:errcheck // pattern is built from validated parts
+		sort.Strings(matches)
+		return matches[len(matches)-1]
+	}
+	return strings.ReplaceAll(path, "{date}", now.Format(planDateLayout))
+}
+
+// projectSlug makes a project name safe for use in a file name.
+func projectSlug(project string) string {
+	slug := strings.Map(func(r rune) rune {
+		if r == '.' || r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, project)
+	if strings.Trim(slug, ".") == "" {
+		return "project"
+	}
+	return slug
+}
@@ -5,9 +5,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/guard"
 )
 
 const minimalConfig = "project: test\n"
@@ -54,6 +57,51 @@ phases:
 	assert.ErrorContains(t, err, "non-negative")
 }
 
+func TestLoad_ProtectedBranchPatterns(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `
+protected_branches:
+  - main
+  - release/*
+  - "re:hotfix-[0-9]+"
+`)
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main", "release/*", "re:hotfix-[0-9]+"}, cfg.ProtectedBranches)
+}
+
+func TestLoad_SummaryRows(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `
+summary:
+  rows: [iterations, commits, cost]
+`)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"iterations", "commits", "cost"}, cfg.Summary.Rows)
+
+	writeConfig(t, dir, `
+summary:
+  rows: [iterations, velocity]
+`)
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `unknown row "velocity"`)
+}
+
+func TestLoad_InvalidProtectedBranchPattern(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `
+protected_branches:
+  - "re:hotfix-("
+`)
+
+	_, err := Load(dir)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "protected_branches")
+}
+
 func TestLoad_ExcessiveIterations(t *testing.T) {
 	dir := t.TempDir()
 	writeConfig(t, dir, `
@@ -200,6 +248,53 @@ func TestPlanPathForBranch_CustomDir(t *testing.T) {
 	assert.Equal(t, "plans/IMPLEMENTATION_PLAN_fix-bug-123.md", got)
 }
 
+func TestPlanPathForBranch_Template(t *testing.T) {
+	cfg := &Config{Project: "My API", Phases: Phases{Plan: PhaseConfig{FilenameTemplate: "{project}/PLAN-{branch}.md"}}}
+	cfg.applyDefaults()
+
+	got := cfg.PlanPathForBranch("feat-login")
+	assert.Equal(t, ".ralph/plans/My-API/PLAN-feat-login.md", got)
+}
+
+func TestPlanPathForBranch_TemplateDate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := &Config{Phases: Phases{Plan: PhaseConfig{Output: "docs/plans/", FilenameTemplate: "{date}-{branch}.md"}}}
+	now := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "docs/plans/2026-03-14-feat-login.md", cfg.templatePlanPath(cfg.Phases.Plan.Output+cfg.Phases.Plan.FilenameTemplate, "feat-login", now))
+
+	// An existing plan keeps its date on later days.
+	require.NoError(t, os.MkdirAll("docs/plans", 0o750))
+	require.NoError(t, os.WriteFile("docs/plans/2026-03-01-feat-login.md", []byte("# Plan\n"), 0o600))
+	require.NoError(t, os.WriteFile("docs/plans/2026-03-01-other.md", []byte("# Plan\n"), 0o600))
+	assert.Equal(t, "docs/plans/2026-03-01-feat-login.md", cfg.PlanPathForBranch("feat-login"))
+}
+
+func TestLoad_PlanTemplateValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"unknown variable", "phases:\n  plan:\n    filename_template: \"{user}-{branch}.md\"\n", "unknown variable {user}"},
+		{"missing branch", "phases:\n  plan:\n    filename_template: PLAN.md\n", "must include {branch}"},
+		{"traversal", "phases:\n  plan:\n    filename_template: \"../{branch}.md\"\n", "within the project"},
+		{"absolute", "phases:\n  plan:\n    filename_template: \"/tmp/{branch}.md\"\n", "within the project"},
+		{"glob chars", "phases:\n  plan:\n    filename_template: \"*{branch}.md\"\n", "may only contain"},
+		{"unbalanced", "phases:\n  plan:\n    filename_template: \"{branch.md\"\n", "unbalanced"},
+		{"file output", "phases:\n  plan:\n    output: plan.md\n    filename_template: \"{branch}.md\"\n", "to be a directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfig(t, dir, minimalConfig+tt.yaml)
+			_, err := Load(dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
 func TestSpecsDirForBranch_Default(t *testing.T) {
 	cfg := &Config{}
 	assert.Equal(t, "specs/my-feature", cfg.SpecsDirForBranch("my-feature"))
@@ -215,6 +310,30 @@ func TestSpecsDirForBranch_Exact(t *testing.T) {
 	assert.Equal(t, "my/exact/path", cfg.SpecsDirForBranch("my-feature"))
 }
 
+func TestSpecsDirForPhase(t *testing.T) {
+	cfg := &Config{SpecsDir: "specs", Phases: Phases{Plan: PhaseConfig{SpecsDir: "research"}}}
+	assert.Equal(t, "research/my-feature", cfg.SpecsDirForPhase("plan", "my-feature"))
+	assert.Equal(t, "specs/my-feature", cfg.SpecsDirForPhase("build", "my-feature"))
+
+	cfg.Phases.Build.SpecsDir = "impl"
+	cfg.SpecsDirExact = true
+	assert.Equal(t, "research", cfg.SpecsDirForPhase("plan", "my-feature"))
+	assert.Equal(t, "impl", cfg.SpecsDirForPhase("build", "my-feature"))
+}
+
+func TestLoad_PhaseSpecsDirMustBeRelative(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `
+project: test
+phases:
+  build:
+    specs_dir: ../outside
+`)
+
+	_, err := Load(dir)
+	require.ErrorContains(t, err, "phases.build.specs_dir")
+}
+
 func TestLoad_SpecsDir(t *testing.T) {
 	dir := t.TempDir()
 	writeConfig(t, dir, `
@@ -367,3 +486,542 @@ func writeConfig(t *testing.T, dir, content string) {
 		t.Fatal(err)
 	}
 }
+
+func TestLoad_GitTimeouts(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\ngit:\n  timeout: 45s\n  network_timeout: 3m\n  hook_timeout: 30m\n")
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 45*time.Second, cfg.Git.Timeout)
+	assert.Equal(t, 3*time.Minute, cfg.Git.NetworkTimeout)
+	assert.Equal(t, 30*time.Minute, cfg.Git.HookTimeout)
+	assert.Equal(t, DirtyTreeWarn, cfg.Git.DirtyTree)
+
+	writeConfig(t, dir, "project: test\ngit:\n  dirty_tree: stash\n  push_debounce:\n    interval: 15m\n    commits: 5\n")
+	cfg, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, DirtyTreeStash, cfg.Git.DirtyTree)
+	assert.Equal(t, PushDebounce{Interval: 15 * time.Minute, Commits: 5}, cfg.Git.PushDebounce)
+
+	writeConfig(t, dir, "project: test\ngit:\n  hooks:\n    skip: true\n    reason: hooks need a database\n")
+	cfg, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, Hooks{Skip: true, Reason: "hooks need a database"}, cfg.Git.Hooks)
+}
+
+func TestLoad_GitTimeoutsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"negative timeout", "project: test\ngit:\n  timeout: -1s\n", "git.timeout"},
+		{"negative network timeout", "project: test\ngit:\n  network_timeout: -1m\n", "git.network_timeout"},
+		{"negative hook timeout", "project: test\ngit:\n  hook_timeout: -1m\n", "git.hook_timeout"},
+		{"not a duration", "project: test\ngit:\n  timeout: soon\n", "parsing config"},
+		{"negative push interval", "project: test\ngit:\n  push_debounce:\n    interval: -1m\n", "git.push_debounce.interval"},
+		{"negative push commits", "project: test\ngit:\n  push_debounce:\n    commits: -2\n", "git.push_debounce.commits"},
+		{"unknown dirty tree setting", "project: test\ngit:\n  dirty_tree: ignore\n", "git.dirty_tree"},
+		{"skipped hooks without a reason", "project: test\ngit:\n  hooks:\n    skip: true\n", "git.hooks.reason"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfig(t, dir, tt.yaml)
+			_, err := Load(dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestLoad_GuardrailsDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, guard.DefaultBlockedCommands, cfg.Guardrails.BlockedCommands)
+}
+
+func TestLoad_GuardrailsCustomAndDisabled(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nguardrails:\n  blocked_commands:\n    - 'docker\\s+system\\s+prune'\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`docker\s+system\s+prune`}, cfg.Guardrails.BlockedCommands)
+
+	writeConfig(t, dir, "project: test\nguardrails:\n  blocked_commands: []\n")
+	cfg, err = Load(dir)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Guardrails.BlockedCommands, "explicit empty list disables guardrails")
+}
+
+func TestLoad_GuardrailsInvalidPattern(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nguardrails:\n  blocked_commands:\n    - '('\n")
+
+	_, err := Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "guardrails.blocked_commands")
+}
+
+func TestLoad_RedactPatterns(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nguardrails:\n  redact_patterns:\n    - 'acme_[a-z0-9]{16}'\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme_[a-z0-9]{16}"}, cfg.Guardrails.RedactPatterns)
+
+	writeConfig(t, dir, "project: test\nguardrails:\n  redact_patterns:\n    - '[z-a]'\n")
+	_, err = Load(dir)
+	require.ErrorContains(t, err, "guardrails.redact_patterns")
+}
+
+func TestLoad_BuildModels(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nphases:\n  build:\n    models:\n      docs: claude-haiku-4-5\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"docs": "claude-haiku-4-5"}, cfg.Phases.Build.Models)
+
+	writeConfig(t, dir, "project: test\nphases:\n  build:\n    models:\n      docs: ''\n")
+	_, err = Load(dir)
+	require.ErrorContains(t, err, "phases.build.models")
+
+	writeConfig(t, dir, "project: test\nphases:\n  plan:\n    models:\n      docs: claude-haiku-4-5\n")
+	_, err = Load(dir)
+	require.ErrorContains(t, err, "build tasks only")
+}
+
+func TestLoad_IterationTimeout(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nphases:\n  build:\n    iteration_timeout: 45m\n    max_timeouts: 2\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 45*time.Minute, cfg.Phases.Build.IterationTimeout)
+	assert.Equal(t, 2, cfg.Phases.Build.MaxTimeouts)
+
+	writeConfig(t, dir, "project: test\nphases:\n  plan:\n    iteration_timeout: -1m\n")
+	_, err = Load(dir)
+	require.ErrorContains(t, err, "phases.plan.iteration_timeout must be non-negative")
+}
+
+func TestLoad_Commits(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\ncommits:\n  require_task_id: true\n  amend: true\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.True(t, cfg.Commits.RequireTaskID)
+	assert.True(t, cfg.Commits.Amend)
+
+	writeConfig(t, dir, "project: test\ncommits:\n  amend: true\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "commits.amend requires commits.require_task_id")
+}
+
+func TestLoad_ScratchLimit(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultScratchLimitMB, cfg.Docker.ScratchLimitMB)
+
+	writeConfig(t, dir, "project: test\ndocker:\n  scratch_limit_mb: -1\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "docker.scratch_limit_mb")
+}
+
+func TestLoad_ContextWarn(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultContextWarnMB, cfg.Docker.ContextWarnMB)
+
+	writeConfig(t, dir, "project: test\ndocker:\n  context_warn_mb: -1\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "docker.context_warn_mb")
+}
+
+func TestLoad_DockerHardening(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\ndocker:\n  read_only: true\n  seccomp_profile: .ralph/docker/seccomp.json\n  pids_limit: 512\n  memory: 4g\n  cpus: 1.5\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.True(t, cfg.Docker.ReadOnly)
+	assert.Equal(t, ".ralph/docker/seccomp.json", cfg.Docker.SeccompProfile)
+	assert.Equal(t, 512, cfg.Docker.PidsLimit)
+	assert.Equal(t, "4g", cfg.Docker.Memory)
+	assert.InDelta(t, 1.5, cfg.Docker.CPUs, 0)
+
+	for yaml, want := range map[string]string{
+		"seccomp_profile: unconfined": "docker.seccomp_profile",
+		"pids_limit: -1":              "docker.pids_limit",
+		"memory: lots":                "docker.memory",
+		"cpus: -2":                    "docker.cpus",
+	} {
+		writeConfig(t, dir, "project: test\ndocker:\n  "+yaml+"\n")
+		_, err = Load(dir)
+		require.Error(t, err, yaml)
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestLoad_DiskLimits(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDiskWarnMB, cfg.Docker.DiskWarnMB)
+	assert.Zero(t, cfg.Docker.DiskLimitMB)
+
+	writeConfig(t, dir, "project: test\ndocker:\n  disk_limit_mb: -1\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "docker.disk_limit_mb")
+}
+
+func TestLoad_ImageReport(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.False(t, cfg.Docker.ImageReport)
+	assert.Equal(t, DefaultImageWarnMB, cfg.Docker.ImageWarnMB)
+
+	writeConfig(t, dir, "project: test\ndocker:\n  image_report: true\n  image_warn_mb: 800\n  image_warn_vulns: 5\n")
+	cfg, err = Load(dir)
+	require.NoError(t, err)
+	assert.True(t, cfg.Docker.ImageReport)
+	assert.Equal(t, 800, cfg.Docker.ImageWarnMB)
+	assert.Equal(t, 5, cfg.Docker.ImageWarnVulns)
+
+	writeConfig(t, dir, "project: test\ndocker:\n  image_warn_vulns: -1\n")
+	_, err = Load(dir)
+	require.ErrorContains(t, err, "docker.image_warn_vulns")
+}
+
+func TestLoad_StreamFilters(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nstream_filters:\n  - .ralph/filters/mask.sh\n  - jq -c .\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".ralph/filters/mask.sh", "jq -c ."}, cfg.StreamFilters)
+
+	writeConfig(t, dir, "project: test\nstream_filters:\n  - jq -c .\n  - \" \"\n")
+	_, err = Load(dir)
+	require.ErrorContains(t, err, "stream_filters[1] is empty")
+}
+
+func TestLoad_StalePlan(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, StalePlanWarn, cfg.Phases.Build.StalePlan)
+	assert.Equal(t, DefaultPlanMaxAgeDays, cfg.Phases.Build.PlanMaxAgeDays)
+
+	writeConfig(t, dir, "project: test\nphases:\n  build:\n    stale_plan: block\n    plan_max_age_days: 30\n")
+	cfg, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, StalePlanBlock, cfg.Phases.Build.StalePlan)
+	assert.Equal(t, 30, cfg.Phases.Build.PlanMaxAgeDays)
+
+	for yaml, want := range map[string]string{
+		"phases:\n  build:\n    stale_plan: fail\n":      "phases.build.stale_plan",
+		"phases:\n  build:\n    plan_max_age_days: -1\n": "phases.build.plan_max_age_days",
+		"phases:\n  plan:\n    stale_plan: block\n":      "build phase only",
+	} {
+		writeConfig(t, dir, "project: test\n"+yaml)
+		_, err := Load(dir)
+		require.ErrorContains(t, err, want)
+	}
+}
+
+func TestLoad_Notifications(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nnotifications:\n  webhook_url: https://hooks.slack.com/services/T0/B0/x\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hooks.slack.com"}, cfg.Notifications.Domains())
+
+	writeConfig(t, dir, "project: test\nnotifications:\n  webhook_url: hooks.slack.com/services\n")
+	_, err = Load(dir)
+	require.ErrorContains(t, err, "notifications.webhook_url")
+
+	assert.Nil(t, Notifications{}.Domains())
+}
+
+func TestLoad_Share(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nshare:\n  provider: gcs\n  bucket: runs\n  link_expiry: 24h\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.True(t, cfg.Share.Enabled())
+	assert.Equal(t, 24*time.Hour, cfg.Share.LinkExpiry)
+	key, secret := cfg.Share.CredentialEnv()
+	assert.Equal(t, "GCS_ACCESS_KEY_ID", key)
+	assert.Equal(t, "GCS_SECRET_ACCESS_KEY", secret)
+
+	for yaml, want := range map[string]string{
+		"share:\n  provider: azure\n":                                "share.provider",
+		"share:\n  provider: s3\n":                                   "share.bucket",
+		"share:\n  provider: s3\n  bucket: b\n  link_expiry: 200h\n": "share.link_expiry",
+	} {
+		writeConfig(t, dir, "project: test\n"+yaml)
+		_, err := Load(dir)
+		require.ErrorContains(t, err, want)
+	}
+}
+
+func TestLoad_Experiments(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `project: test
+experiments:
+  fast-sonnet:
+    build_prompt: .ralph/prompts/build-terse.md
+    model: sonnet
+`)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	e := cfg.Experiments["fast-sonnet"]
+	assert.Equal(t, "sonnet", e.Model)
+	assert.Equal(t, ".ralph/prompts/build-terse.md", e.Prompt("build", "default.md"))
+	assert.Equal(t, "default.md", e.Prompt("plan", "default.md"))
+}
+
+func TestLoad_Codeowners(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig+"codeowners:\n  protect: [\"@acme/security\"]\n  careful: [\"@acme/platform\", dev@acme.io]\n")
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.True(t, cfg.Codeowners.Enabled())
+	assert.Equal(t, []string{"@acme/security"}, cfg.Codeowners.Protect)
+	assert.Equal(t, []string{"@acme/platform", "dev@acme.io"}, cfg.Codeowners.Careful)
+
+	writeConfig(t, dir, minimalConfig+"codeowners:\n  protect: [security]\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "codeowners.protect")
+}
+
+func TestLoad_ExperimentsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"bad name", "experiments:\n  \"a b\":\n    model: sonnet\n", "must contain only letters"},
+		{"empty variant", "experiments:\n  empty: {}\n", "set at least one of"},
+		{"prompt traversal", "experiments:\n  x:\n    plan_prompt: ../outside.md\n", "experiments.x.plan_prompt"},
+		{"bad model", "experiments:\n  x:\n    model: \"opus; rm\"\n", "experiments.x.model"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfig(t, dir, minimalConfig+tt.yaml)
+			_, err := Load(dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoad_VCS(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig+"vcs: jj\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "jj", cfg.VCS)
+
+	writeConfig(t, dir, minimalConfig+"vcs: svn\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "vcs must be")
+}
+
+func TestLoad_TokenCount(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig+"token_count: api\n")
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "api", cfg.TokenCount)
+
+	writeConfig(t, dir, minimalConfig+"token_count: always\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "token_count")
+}
+
+func TestLoad_Agent(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nagent: gemini\n")
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini", cfg.Agent)
+
+	writeConfig(t, dir, "project: test\nagent: copilot\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent must be")
+}
+
+func TestLoad_GuardrailsNeedClaude(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "project: test\nagent: codex\nguardrails:\n  blocked_commands: ['\\brm\\b']\n")
+	_, err := Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't be enforced by agent \"codex\"")
+
+	writeConfig(t, dir, "project: test\nagent: codex\nguardrails:\n  blocked_commands: []\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Guardrails.BlockedCommands)
+
+	writeConfig(t, dir, "project: test\nagent: codex\n")
+	cfg, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, guard.DefaultBlockedCommands, cfg.Guardrails.BlockedCommands, "defaults still apply, with a warning at startup")
+}
+
+func TestLoad_ContextWarnPercent(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultContextWarnPercent, cfg.ContextWarn)
+
+	writeConfig(t, dir, minimalConfig+"context_warn_percent: 150\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "context_warn_percent")
+}
+
+func TestLoad_GitHubReport(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig+"github:\n  report: check\n")
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "check", cfg.GitHub.Report)
+
+	writeConfig(t, dir, minimalConfig+"github:\n  report: comment\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "github.report")
+}
+
+func TestLoad_PlanSplitTasks(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSplitTasks, cfg.Phases.Plan.SplitTasks)
+
+	writeConfig(t, dir, "project: x\nphases:\n  plan:\n    split_tasks: -1\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "split_tasks")
+}
+
+func TestLoad_PromptCache(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, PromptCacheOn, cfg.PromptCache)
+
+	writeConfig(t, dir, minimalConfig+"prompt_cache: off\n")
+	cfg, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, PromptCacheOff, cfg.PromptCache)
+
+	writeConfig(t, dir, minimalConfig+"prompt_cache: sometimes\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "prompt_cache")
+}
+
+func TestLoad_Locale(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig+"locale: es\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "es", cfg.Locale)
+
+	writeConfig(t, dir, minimalConfig+"locale: fr\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "locale must be one of en, es")
+}
+
+func TestLoad_ToolParamWidth(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig+"tool_param_width: -1\n")
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, -1, cfg.ToolParamWidth)
+
+	writeConfig(t, dir, minimalConfig+"tool_param_width: -5\n")
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tool_param_width")
+}
+
+func TestLoad_GitHubPlanReview(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig)
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.False(t, cfg.GitHub.PlanReview)
+
+	writeConfig(t, dir, minimalConfig+"github:\n  plan_review: true\n")
+	cfg, err = Load(dir)
+	require.NoError(t, err)
+	assert.True(t, cfg.GitHub.PlanReview)
+}
+
+func TestLoad_Cost(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, minimalConfig+`cost:
+  currency: EUR
+  usd_rate: 0.92
+  pricing:
+    gateway-model: {input: 2, output: 8, cache_write: 2.5, cache_read: 0.2}
+`)
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "€0.92", cfg.Cost.Display().Format(1, 2))
+	r, ok := cfg.Cost.Table().Lookup("gateway-model-v2")
+	require.True(t, ok)
+	assert.InDelta(t, 8.0, r.Output, 0)
+	_, ok = cfg.Cost.Table().Lookup("sonnet")
+	assert.True(t, ok, "built-in rates stay available")
+
+	c := Cost{MaxCostCeiling: 20}
+	require.NoError(t, c.CheckCap("--max-cost", 20))
+	require.EqualError(t, c.CheckCap("--max-cost", 25), "--max-cost $25.00 is over cost.max_cost_ceiling $20.00")
+
+	for yaml, want := range map[string]string{
+		"cost:\n  currency: euro\n":                       "cost.currency",
+		"cost:\n  currency: EUR\n":                        "cost.usd_rate is required",
+		"cost:\n  pricing:\n    sonnet: {input: -1}\n":    "cost.pricing.sonnet",
+		"cost:\n  max_cost: -1\n":                         "cost.max_cost must be non-negative",
+		"cost:\n  max_cost: 50\n  max_cost_ceiling: 20\n": "cost.max_cost $50.00 is over cost.max_cost_ceiling $20.00",
+	} {
+		writeConfig(t, dir, minimalConfig+yaml)
+		_, err = Load(dir)
+		require.Error(t, err, yaml)
+		assert.Contains(t, err.Error(), want)
+	}
+}
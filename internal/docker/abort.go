@@ -0,0 +1,124 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+// Labels ralph puts on the containers it runs, so ralph abort can find the
+// one running a project.
+const (
+	LabelDir     = "ralph.dir" // the host project root, symlinks resolved
+	LabelProject = "ralph.project"
+	LabelBranch  = "ralph.branch"
+	LabelMode    = "ralph.mode"
+)
+
+// DefaultAbortTimeout is how long ralph abort waits for the loop to save
+// its state and exit before killing the container.
+const DefaultAbortTimeout = 30 * time.Second
+
+// abortPoll is how often Abort checks whether the container has exited.
+var abortPoll = 500 * time.Millisecond
+
+// Container is a running ralph container.
+type Container struct {
+	ID     string
+	Branch string
+	Mode   string
+}
+
+// RunningContainers returns the ralph containers running projectDir.
+func RunningContainers(ctx context.Context, projectDir string) ([]Container, error) {
+	return runningContainers(ctx, commandOutput, projectDir)
+}
+
+func runningContainers(ctx context.Context, output outputFunc, projectDir string) ([]Container, error) {
+	format := fmt.Sprintf(`{{.ID}}\t{{.Label %q}}\t{{.Label %q}}`, LabelBranch, LabelMode)
+	out, err := output(ctx, "docker", "ps", "--filter", "label="+LabelDir+"="+resolveMountSource(projectDir), "--format", format)
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+	var containers []Container
+	for line := range strings.Lines(string(out)) {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if fields[0] == "" {
+			continue
+		}
+		c := Container{ID: fields[0]}
+		if len(fields) == 3 {
+			c.Branch, c.Mode = fields[1], fields[2]
+		}
+		containers = append(containers, c)
+	}
+	return containers, nil
+}
+
+// Abort stops c the way Ctrl+C does: every process in the container but
+// its init gets SIGINT, so the loop records the run as cancelled and
+// pushes what it has. If the container is still running after timeout,
+// the run is recorded as cancelled in projectDir's state.json and the
+// container is killed, which keeps the host from relaunching it as a
+// crash. Abort reports whether it had to kill the container.
+func Abort(ctx context.Context, c Container, projectDir string, timeout time.Duration) (bool, error) {
+	return abort(ctx, commandOutput, c, projectDir, timeout)
+}
+
+func abort(ctx context.Context, output outputFunc, c Container, projectDir string, timeout time.Duration) (bool, error) {
+	// docker kill --signal would reach only the container's init, runuser,
+	// which doesn't pass SIGINT on to the loop.
+	if _, err := output(ctx, "docker", "exec", c.ID, "bash", "-c", "kill -INT -1"); err != nil {
+		if !running(ctx, output, c.ID) {
+			return false, nil
+		}
+		return false, fmt.Errorf("interrupting container %s: %w", c.ID, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !running(ctx, output, c.ID) {
+			return false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, fmt.Errorf("waiting for container %s: %w", c.ID, ctx.Err())
+		case <-time.After(abortPoll):
+		}
+	}
+
+	cancelRun(filepath.Join(projectDir, state.DefaultPath), c)
+	if _, err := output(ctx, "docker", "kill", c.ID); err != nil && running(ctx, output, c.ID) {
+		return true, fmt.Errorf("killing container %s: %w", c.ID, err)
+	}
+	return true, nil
+}
+
+// running reports whether container id is still running.
+func running(ctx context.Context, output outputFunc, id string) bool {
+	out, err := output(ctx, "docker", "ps", "--quiet", "--filter", "id="+id)
+	return err == nil && strings.TrimSpace(string(out)) != ""
+}
+
+// cancelRun records the run c's loop left running as cancelled, so the
+// host sees it ended rather than crashed. Best-effort.
+func cancelRun(path string, c Container) {
+	st, err := state.Load(path)
+	if err != nil {
+		return
+	}
+	for i := len(st.Runs) - 1; i >= 0; i-- {
+		r := &st.Runs[i]
+		if r.Status != state.StatusRunning || (c.Branch != "" && r.Branch != "" && r.Branch != c.Branch) {
+			continue
+		}
+		r.Status = state.StatusCancelled
+		r.FinishedAt = time.Now()
+		_ = state.Save(path, st) //nolint:errcheck // best-effort
+		return
+	}
+}
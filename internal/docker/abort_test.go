@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+// fakeDocker answers docker commands for a container that exits after
+// it has been polled alive runningFor times.
+type fakeDocker struct {
+	calls      []string
+	ps         string
+	runningFor int
+	execErr    error
+}
+
+func (f *fakeDocker) output(_ context.Context, _ string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, strings.Join(args, " "))
+	switch {
+	case args[0] == "exec":
+		return nil, f.execErr
+	case args[0] == "ps" && args[1] == "--quiet":
+		if f.runningFor == 0 {
+			return nil, nil
+		}
+		f.runningFor--
+		return []byte("abc123\n"), nil
+	case args[0] == "ps":
+		return []byte(f.ps), nil
+	}
+	f.runningFor = 0 // docker kill
+	return nil, nil
+}
+
+func fastPoll(t *testing.T) {
+	t.Helper()
+	old := abortPoll
+	abortPoll = time.Millisecond
+	t.Cleanup(func() { abortPoll = old })
+}
+
+func TestRunningContainers(t *testing.T) {
+	f := &fakeDocker{ps: "abc123\tfeat/login\tbuild\ndef456\t\t\n"}
+	got, err := runningContainers(context.Background(), f.output, "/home/user/project")
+	require.NoError(t, err)
+	assert.Equal(t, []Container{{ID: "abc123", Branch: "feat/login", Mode: "build"}, {ID: "def456"}}, got)
+	assert.Contains(t, f.calls[0], "--filter label=ralph.dir=/home/user/project")
+
+	got, err = runningContainers(context.Background(), (&fakeDocker{}).output, "/home/user/project")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestAbort_Graceful(t *testing.T) {
+	fastPoll(t)
+	f := &fakeDocker{runningFor: 2}
+	forced, err := abort(context.Background(), f.output, Container{ID: "abc123"}, t.TempDir(), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, forced)
+	assert.Equal(t, "exec abc123 bash -c kill -INT -1", f.calls[0])
+	assert.NotContains(t, f.calls, "kill abc123")
+}
+
+func TestAbort_ForceKillsAndCancelsRun(t *testing.T) {
+	fastPoll(t)
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, state.DefaultPath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(statePath), 0o750))
+	require.NoError(t, state.Save(statePath, &state.State{Runs: []state.RunRecord{
+		{Mode: "build", Branch: "feat/login", Status: state.StatusRunning},
+		{Mode: "build", Branch: "other", Status: state.StatusRunning},
+	}}))
+
+	f := &fakeDocker{runningFor: 1 << 30}
+	forced, err := abort(context.Background(), f.output, Container{ID: "abc123", Branch: "feat/login"}, dir, 20*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, forced)
+	assert.Equal(t, "kill abc123", f.calls[len(f.calls)-1])
+
+	st, err := state.Load(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, state.StatusCancelled, st.Runs[0].Status)
+	assert.False(t, st.Runs[0].FinishedAt.IsZero())
+	assert.Equal(t, state.StatusRunning, st.Runs[1].Status, "another branch's run is left alone")
+}
+
+func TestAbort_AlreadyExited(t *testing.T) {
+	f := &fakeDocker{execErr: errors.New("no such container")}
+	forced, err := abort(context.Background(), f.output, Container{ID: "abc123"}, t.TempDir(), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, forced)
+
+	f = &fakeDocker{execErr: errors.New("permission denied"), runningFor: 1}
+	_, err = abort(context.Background(), f.output, Container{ID: "abc123"}, t.TempDir(), time.Minute)
+	require.ErrorContains(t, err, "permission denied")
+}
+
+func TestRunWithRunner_Labels(t *testing.T) {
+	r := &fakeRunner{}
+	require.NoError(t, runWithRunner(r, baseRunOpts()))
+	call := strings.Join(r.calls[0], " ")
+	assert.Contains(t, call, "--label ralph.dir=/home/user/project")
+	assert.Contains(t, call, "--label ralph.project=myproject")
+	assert.Contains(t, call, "--label ralph.branch=main")
+	assert.Contains(t, call, "--label ralph.mode=build")
+}
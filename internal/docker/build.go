@@ -1,7 +1,10 @@
 package docker
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os/exec"
 )
 
 // Default values for docker build.
@@ -11,9 +14,9 @@ const (
 	DefaultContext    = "."
 )
 
-// Build runs docker build with BuildKit enabled.
-func Build(dockerfile, tag, contextDir string) error {
-	return buildWithRunner(defaultRunner{}, dockerfile, tag, contextDir)
+// Build runs docker build with BuildKit enabled, writing its output to w.
+func Build(w io.Writer, dockerfile, tag, contextDir string) error {
+	return buildWithRunner(defaultRunner{stdout: w}, dockerfile, tag, contextDir)
 }
 
 func buildWithRunner(runner CommandRunner, dockerfile, tag, contextDir string) error {
@@ -32,3 +35,10 @@ func buildWithRunner(runner CommandRunner, dockerfile, tag, contextDir string) e
 	}
 	return nil
 }
+
+// ImageExists reports whether tag is in the local image store, so an
+// offline run can reuse it instead of building.
+func ImageExists(tag string) bool {
+	cmd := exec.CommandContext(context.Background(), "docker", "image", "inspect", tag) //nolint:gosec // tag is a ralph constant
+	return cmd.Run() == nil
+}
@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// HeavyPaths are directories that bloat the build context when they aren't
+// ignored. The image only copies the entrypoint, so none of them are needed.
+var HeavyPaths = []string{".git", "node_modules", ".venv", "target", ".ralph/logs"}
+
+// ContextReport describes how much of a build context docker would send.
+type ContextReport struct {
+	IgnoreFile string   // ignore file docker reads, relative to the context; may not exist yet
+	Missing    []string // HeavyPaths present in the context but not ignored
+	Size       int64    // bytes sent, counted up to the limit
+	OverLimit  bool     // Size exceeded the limit; counting stopped there
+}
+
+// errOverLimit stops the context walk once the limit is passed.
+var errOverLimit = errors.New("context over limit")
+
+// CheckContext reads the ignore file docker uses for dockerfile (both
+// relative to contextDir) and reports heavy paths it doesn't cover and
+// whether the remaining context is larger than limit bytes. A limit of 0
+// skips the size check.
+func CheckContext(contextDir, dockerfile string, limit int64) (*ContextReport, error) {
+	report := &ContextReport{IgnoreFile: IgnoreFile(contextDir, dockerfile)}
+	patterns, err := readIgnore(filepath.Join(contextDir, report.IgnoreFile))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range HeavyPaths {
+		if _, err := os.Stat(filepath.Join(contextDir, p)); err == nil && !ignored(patterns, p) {
+			report.Missing = append(report.Missing, p)
+		}
+	}
+
+	if limit <= 0 {
+		return report, nil
+	}
+	err = filepath.WalkDir(contextDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr // unreadable entries aren't sent either
+		}
+		rel, err := filepath.Rel(contextDir, p)
+		if err != nil || rel == "." {
+			return nil //nolint:nilerr // the root itself is never ignored
+		}
+		if ignored(patterns, filepath.ToSlash(rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return nil //nolint:nilerr // vanished since listing
+			}
+			report.Size += info.Size()
+			if report.Size > limit {
+				report.OverLimit = true
+				return errOverLimit
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errOverLimit) {
+		return nil, fmt.Errorf("measuring build context: %w", err)
+	}
+	return report, nil
+}
+
+// IgnoreFile returns the ignore file docker reads for dockerfile, relative
+// to contextDir: "<dockerfile>.dockerignore" next to the Dockerfile, else
+// ".dockerignore" at the context root. When neither exists it returns the
+// Dockerfile-specific one, which only affects ralph's image.
+func IgnoreFile(contextDir, dockerfile string) string {
+	specific := filepath.Clean(dockerfile) + ".dockerignore"
+	if _, err := os.Stat(filepath.Join(contextDir, specific)); err == nil {
+		return specific
+	}
+	if _, err := os.Stat(filepath.Join(contextDir, ".dockerignore")); err == nil {
+		return ".dockerignore"
+	}
+	return specific
+}
+
+// AddIgnores appends paths to the ignore file under contextDir, creating it
+// if needed.
+func AddIgnores(contextDir, ignoreFile string, paths []string) error {
+	full := filepath.Join(contextDir, ignoreFile)
+	existing, err := os.ReadFile(full) //nolint:gosec // ignore file under the repo root
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading %s: %w", ignoreFile, err)
+	}
+
+	var b strings.Builder
+	b.Write(existing)
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString("# Added by ralph to keep the build context small\n")
+	for _, p := range paths {
+		b.WriteString(p + "\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(full), 0o750); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(ignoreFile), err)
+	}
+	if err := os.WriteFile(full, []byte(b.String()), 0o644); err != nil { //nolint:gosec // committed project file
+		return fmt.Errorf("writing %s: %w", ignoreFile, err)
+	}
+	return nil
+}
+
+// ignorePattern is one parsed .dockerignore line.
+type ignorePattern struct {
+	glob   string
+	negate bool
+}
+
+func readIgnore(file string) ([]ignorePattern, error) {
+	f, err := os.Open(file) //nolint:gosec // ignore file under the repo root
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", filepath.Base(file), err)
+	}
+	defer f.Close() //nolint:errcheck // read-only
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := ignorePattern{}
+		if strings.HasPrefix(line, "!") {
+			p.negate, line = true, strings.TrimSpace(line[1:])
+		}
+		p.glob = path.Clean(strings.Trim(filepath.ToSlash(line), "/"))
+		patterns = append(patterns, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filepath.Base(file), err)
+	}
+	return patterns, nil
+}
+
+// ignored reports whether the slash-separated path rel is excluded. As in
+// docker, a pattern matching a directory excludes everything under it, the
+// last matching pattern wins, and a leading "**/" matches at any depth.
+func ignored(patterns []ignorePattern, rel string) bool {
+	out := false
+	for _, p := range patterns {
+		if matches(p.glob, rel) {
+			out = !p.negate
+		}
+	}
+	return out
+}
+
+func matches(glob, rel string) bool {
+	parts := strings.Split(rel, "/")
+	anyDepth := strings.HasPrefix(glob, "**/")
+	glob = strings.TrimPrefix(glob, "**/")
+	for start := range parts {
+		if start > 0 && !anyDepth {
+			break
+		}
+		for end := start + 1; end <= len(parts); end++ {
+			if ok, _ := path.Match(glob, strings.Join(parts[start:end], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o600))
+}
+
+func TestCheckContext_Missing(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), 10)
+	writeFile(t, filepath.Join(dir, "node_modules", "x", "index.js"), 10)
+	writeFile(t, filepath.Join(dir, ".ralph", "logs", "a.jsonl"), 10)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".ralph", "docker"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".ralph", "docker", "Dockerfile.dockerignore"), []byte("# ignore\n.git\n/node_modules/\n"), 0o600))
+
+	report, err := CheckContext(dir, DefaultDockerfile, 0)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(".ralph", "docker", "Dockerfile.dockerignore"), report.IgnoreFile)
+	assert.Equal(t, []string{".ralph/logs"}, report.Missing)
+}
+
+func TestCheckContext_RootIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "target", "debug", "app"), 10)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("**/target\n"), 0o600))
+
+	report, err := CheckContext(dir, DefaultDockerfile, 0)
+	require.NoError(t, err)
+	assert.Equal(t, ".dockerignore", report.IgnoreFile)
+	assert.Empty(t, report.Missing)
+}
+
+func TestCheckContext_Size(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "data", "big.bin"), 2000)
+	writeFile(t, filepath.Join(dir, "src", "main.go"), 100)
+
+	report, err := CheckContext(dir, DefaultDockerfile, 1000)
+	require.NoError(t, err)
+	assert.True(t, report.OverLimit)
+
+	require.NoError(t, AddIgnores(dir, report.IgnoreFile, []string{"data"}))
+	report, err = CheckContext(dir, DefaultDockerfile, 1000)
+	require.NoError(t, err)
+	assert.False(t, report.OverLimit)
+	assert.Equal(t, int64(100+len("# Added by ralph to keep the build context small\ndata\n")), report.Size)
+}
+
+func TestAddIgnores_Appends(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(".git"), 0o600))
+
+	require.NoError(t, AddIgnores(dir, ".dockerignore", []string{".venv", "target"}))
+
+	data, err := os.ReadFile(filepath.Join(dir, ".dockerignore"))
+	require.NoError(t, err)
+	assert.Equal(t, ".git\n# Added by ralph to keep the build context small\n.venv\ntarget\n", string(data))
+}
+
+func TestIgnored(t *testing.T) {
+	patterns := []ignorePattern{{glob: "logs"}, {glob: "*.pyc"}, {glob: "**/__pycache__"}, {glob: "logs/keep.txt", negate: true}}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"logs", true},
+		{"logs/a.jsonl", true},
+		{"logs/keep.txt", false},
+		{"src/logs", false}, // patterns are anchored at the context root
+		{"a.pyc", true},
+		{"pkg/a.pyc", false},
+		{"pkg/__pycache__/a.pyc", true},
+		{"src/main.py", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ignored(patterns, tt.path), tt.path)
+	}
+}
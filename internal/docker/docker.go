@@ -1,15 +1,30 @@
 package docker
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"golang.org/x/term"
+
+	"github.com/benwilkes9/ralph-cli/internal/agent"
 	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/debuglog"
+	"github.com/benwilkes9/ralph-cli/internal/ghstatus"
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
 	"github.com/benwilkes9/ralph-cli/internal/preflight"
+	"github.com/benwilkes9/ralph-cli/internal/snapshot"
+	"github.com/benwilkes9/ralph-cli/internal/state"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
+	"github.com/benwilkes9/ralph-cli/internal/vcs"
 )
 
 // requiredEnvVars lists env vars required beyond auth credentials
@@ -22,9 +37,15 @@ var requiredEnvVars = []string{"GITHUB_PAT"}
 var allowedEnvVars = map[string]bool{
 	"ANTHROPIC_API_KEY":       true,
 	"CLAUDE_CODE_OAUTH_TOKEN": true,
+	"OPENAI_API_KEY":          true, // codex, aider
+	"GEMINI_API_KEY":          true, // gemini, aider
 	"GITHUB_PAT":              true,
+	"GITHUB_APP_TOKEN":        true, // github.report: check
 }
 
+// allowedEnvList names allowedEnvVars in error messages.
+const allowedEnvList = "ANTHROPIC_API_KEY, CLAUDE_CODE_OAUTH_TOKEN, OPENAI_API_KEY, GEMINI_API_KEY, GITHUB_PAT, GITHUB_APP_TOKEN"
+
 // AuthMethod indicates how the container authenticates with Claude.
 type AuthMethod int
 
@@ -51,64 +72,178 @@ func ResolveAuth(env map[string]string) (AuthMethod, error) {
 	}
 }
 
+// ResolveAgentAuth returns which of a non-claude agent's credential env
+// vars are set, to be forwarded into the container.
+func ResolveAgentAuth(name string, env map[string]string) ([]string, error) {
+	vars := agent.CredentialEnv(name)
+	var set []string
+	for _, v := range vars {
+		if env[v] != "" || os.Getenv(v) != "" {
+			set = append(set, v)
+		}
+	}
+	if len(set) == 0 {
+		return nil, fmt.Errorf("missing auth for %s: set %s", name, strings.Join(vars, " or "))
+	}
+	return set, nil
+}
+
+// LaunchOptions carries the per-invocation settings resolved by the CLI.
+type LaunchOptions struct {
+	Mode          string // "plan" or "build"
+	MaxIterations int
+	Branch        string
+	PlanFile      string
+	SpecsDir      string
+	Tags          []string // free-form labels recorded on the run
+	Note          string   // free-form description recorded on the run
+	Step          bool     // pause after each iteration for operator review
+	Profile       string   // named credential set from profiles.yaml; empty = .env only
+	Experiment    string   // variant from config experiments; empty = phase defaults
+	Chaos         float64  // failure-injection rate for --chaos; 0 = off
+	ChaosSeed     int64    // seed for Chaos
+	OnClaudeError string   // --continue-on-claude-error policy; empty = abort
+	VCS           vcs.VCS  // the repo's version control backend
+
+	PlanApproval   *state.Approval // reviewers who approved the plan, recorded on the run; nil = not reviewed
+	Offline        bool            // no remote git, pushes or image build; the cached image is reused
+	ResumeOf       int             // run number in state.json that ralph resume continues; 0 = a fresh run
+	Native         bool            // run the loop on the host instead of in a container (--no-docker)
+	Output         string          // --output format of the loop's progress; empty = text
+	MaxCost        float64         // --max-cost in USD; 0 = cost.max_cost
+	Package        string          // --package directory the run is scoped to; empty = whole repo
+	ReplayOf       int             // run number in state.json whose recorded prompts ralph replay sends; 0 = not a replay
+	CurrentPrompts bool            // a replay sends the current prompt files with the recorded tasks and feedback
+	Swarm          bool            // a ralph swarm member, running alongside other branches' containers
+}
+
 // BuildAndRun orchestrates the full Docker workflow: detect repo, load env,
-// validate, build image, run container with bind mount.
-func BuildAndRun(w io.Writer, theme *ui.Theme, mode string, maxIterations int, branch, planFile, specsDir string) error {
-	repo, err := DetectRepo()
+// validate, build image, run container with bind mount. With Native set it
+// stops short of Docker and runs the loop on the host.
+func BuildAndRun(ctx context.Context, w io.Writer, theme *ui.Theme, launch *LaunchOptions) error {
+	branch, planFile, specsDir := launch.Branch, launch.PlanFile, launch.SpecsDir
+
+	origin, err := DetectOrigin(ctx, launch.VCS)
 	if err != nil {
 		return fmt.Errorf("detecting repo: %w", err)
 	}
+	repo := origin.Repo
 
 	env, err := LoadEnvFile(".env")
 	if err != nil {
 		return fmt.Errorf("loading .env: %w", err)
 	}
 
+	// Profile credentials take precedence over .env.
+	if launch.Profile != "" {
+		profileEnv, err := LoadProfile(DefaultProfilesPath, launch.Profile)
+		if err != nil {
+			return err
+		}
+		for k, v := range profileEnv {
+			env[k] = v
+		}
+		fmt.Fprintf(w, "%s %s\n", theme.Muted.Render("Profile:"), theme.Info.Render(launch.Profile)) //nolint:errcheck // display-only
+	}
+	if launch.Experiment != "" {
+		fmt.Fprintf(w, "%s %s\n", theme.Muted.Render("Experiment:"), theme.Info.Render(launch.Experiment)) //nolint:errcheck // display-only
+	}
+
 	for k, v := range env {
 		if !allowedEnvVars[k] {
-			return fmt.Errorf("disallowed env var in .env: %s (allowed: ANTHROPIC_API_KEY, CLAUDE_CODE_OAUTH_TOKEN, GITHUB_PAT)", k)
+			return fmt.Errorf("disallowed env var in .env: %s (allowed: %s)", k, allowedEnvList)
 		}
 		if err := os.Setenv(k, v); err != nil {
 			return fmt.Errorf("setting env %s: %w", k, err)
 		}
 	}
 
-	auth, err := ResolveAuth(env)
-	if err != nil {
+	if err := ValidateEnv(env, requiredEnvVars); err != nil {
 		return err
 	}
 
-	if err := ValidateEnv(env, requiredEnvVars); err != nil {
-		return err
+	// Load config early to access AdditionalDirs for preflight validation.
+	// The mount source is the resolved repo root, not the working directory,
+	// which may be a subdirectory or reached through a symlink.
+	repoRootForCfg := launch.VCS.Root()
+	cfgEarly, err := config.Load(repoRootForCfg)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf(`".ralph/config.yaml" not found, run "ralph init" first`)
+	}
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
 	}
 
-	if err := preflight.Check(branch, specsDir, planFile); err != nil {
+	// Secrets are checked before preflight commits or pushes anything.
+	if err := preflight.CheckSecrets(ctx, launch.VCS, cfgEarly.Guardrails.SecretsRotated); err != nil {
 		return err //nolint:wrapcheck // preflight errors already have context
 	}
-
-	// Load config early to access AdditionalDirs for preflight validation.
-	repoRootForCfg, err := filepath.Abs(".")
+	if cfgEarly.Safety.Snapshot {
+		if err := takeSnapshot(ctx, w, theme, launch); err != nil {
+			return err
+		}
+	}
+	stash, err := preflight.CheckDirtyTree(ctx, w, launch.VCS, cfgEarly.Git.DirtyTree, specsDir, planFile)
 	if err != nil {
-		return fmt.Errorf("resolving project dir for config: %w", err)
+		return err //nolint:wrapcheck // preflight errors already have context
+	}
+	defer restoreStash(ctx, w, theme, stash)
+	if launch.Offline {
+		fmt.Fprintln(w, theme.Warning.Render("Offline: skipping remote checks, pushes and the image build; run \"ralph sync\" when back online.")) //nolint:errcheck // display-only
+		err = preflight.Prepare(ctx, w, launch.VCS, specsDir, planFile)
+	} else {
+		err = checkToken(ctx, w, theme, origin, cfgEarly)
+		if err == nil {
+			err = preflight.Check(ctx, w, launch.VCS, branch, specsDir, planFile)
+		}
 	}
-	cfgEarly, err := config.Load(repoRootForCfg)
 	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
+		return err //nolint:wrapcheck // preflight errors already have context
+	}
+
+	// Claude authenticates with an API key or OAuth token; other agents
+	// with their own provider's key.
+	var auth AuthMethod
+	var agentEnv []string
+	if cfgEarly.Agent == agent.NameClaude {
+		auth, err = ResolveAuth(env)
+	} else {
+		agentEnv, err = ResolveAgentAuth(cfgEarly.Agent, env)
 	}
+	if err != nil {
+		return err
+	}
+	slog.DebugContext(ctx, "auth", "agent", cfgEarly.Agent, "oauth", auth == AuthOAuth, "agent_env", agentEnv)
 
 	if len(cfgEarly.AdditionalDirs) > 0 {
-		if err := preflight.CheckAdditionalDirs(branch, cfgEarly.AdditionalDirs); err != nil {
+		check := preflight.CheckAdditionalDirs
+		if launch.Offline {
+			check = preflight.PrepareAdditionalDirs
+		}
+		if err := check(ctx, w, branch, cfgEarly.AdditionalDirs); err != nil {
 			return err //nolint:wrapcheck // preflight errors already have context
 		}
 	}
 
-	if err := Build(DefaultDockerfile, DefaultTag, DefaultContext); err != nil {
-		return err
+	slog.DebugContext(ctx, "launch", "mode", launch.Mode, "native", launch.Native, "offline", launch.Offline, "max_iterations", launch.MaxIterations, "resume_of", launch.ResumeOf)
+	if launch.Native {
+		return startNative(w, theme, launch, cfgEarly, origin)
+	}
+
+	if !launch.Offline {
+		if err := Build(w, DefaultDockerfile, DefaultTag, DefaultContext); err != nil {
+			return err
+		}
+		if cfgEarly.Docker.ImageReport {
+			reportImage(ctx, w, theme, cfgEarly)
+		}
+	} else if !ImageExists(DefaultTag) {
+		return fmt.Errorf("offline: no cached %s image; run once online to build it", DefaultTag)
 	}
 
 	cfg := cfgEarly
 	repoRoot := repoRootForCfg
-	allowedDomains := AllowedDomains(cfg.Network.ExtraAllowedDomains)
+	allowedDomains := AllowedDomains(slices.Concat(agent.Domains(cfg.Agent), cfg.Network.ExtraAllowedDomains, cfg.Notifications.Domains()))
 
 	fmt.Fprintf(w, "%s %s  %s %s\n", //nolint:errcheck // display-only
 		theme.Muted.Render("Repo:"), repo,
@@ -117,28 +252,126 @@ func BuildAndRun(w io.Writer, theme *ui.Theme, mode string, maxIterations int, b
 	for _, dir := range cfg.AdditionalDirs {
 		fmt.Fprintf(w, "%s %s → /workspace/%s\n", theme.Muted.Render("Mount:"), dir, filepath.Base(dir)) //nolint:errcheck // display-only
 	}
+	var depsBranch string
+	if launch.Swarm {
+		depsBranch = git.SanitizeBranch(branch)
+	}
 	if cfg.Docker.DepsDir != "" {
-		fmt.Fprintf(w, "%s ralph-deps-%s → %s\n", //nolint:errcheck // display-only
-			theme.Muted.Render("Deps volume:"), cfg.Project, cfg.Docker.DepsDir)
+		fmt.Fprintf(w, "%s %s → %s\n", //nolint:errcheck // display-only
+			theme.Muted.Render("Deps volume:"), DepsVolume(cfg.Project, depsBranch), cfg.Docker.DepsDir)
 	}
+	scratchVol := ScratchVolume(cfg.Project, git.SanitizeBranch(branch))
+	fmt.Fprintf(w, "%s %s → %s\n", //nolint:errcheck // display-only
+		theme.Muted.Render("Scratch volume:"), scratchVol, ScratchDir)
 	fmt.Fprintf(w, "%s %s\n", //nolint:errcheck // display-only
 		theme.Muted.Render("Network allowlist:"), strings.Join(allowedDomains, ", "))
 	fmt.Fprintln(w, theme.Muted.Render("Workspace is shared — changes appear on the host in real time.")) //nolint:errcheck // display-only
 
+	seccomp, err := seccompProfile(repoRoot, cfg.Docker.SeccompProfile)
+	if err != nil {
+		return err
+	}
+
 	runOpts := &RunOptions{
 		ImageTag:       DefaultTag,
-		Mode:           mode,
-		MaxIter:        maxIterations,
+		Mode:           launch.Mode,
+		MaxIter:        launch.MaxIterations,
 		Branch:         branch,
 		ProjectDir:     repoRoot,
 		PlanFile:       planFile,
 		SpecsDir:       specsDir,
 		AllowedDomains: allowedDomains,
 		DepsDir:        cfg.Docker.DepsDir,
+		DepsBranch:     depsBranch,
 		ProjectName:    cfg.Project,
+		ScratchVolume:  scratchVol,
 		Auth:           auth,
+		AgentEnv:       agentEnv,
+		AppToken:       cfg.GitHub.Report == ghstatus.KindCheck,
 		AdditionalDirs: cfg.AdditionalDirs,
+		Tags:           launch.Tags,
+		Note:           launch.Note,
+		Step:           launch.Step,
+		Profile:        launch.Profile,
+		Experiment:     launch.Experiment,
+		Chaos:          launch.Chaos,
+		ChaosSeed:      launch.ChaosSeed,
+		OnClaudeError:  launch.OnClaudeError,
+		PlanApproval:   launch.PlanApproval,
+		Offline:        launch.Offline,
+		Output:         launch.Output,
+		MaxCost:        launch.MaxCost,
+		Package:        launch.Package,
+		ReplayOf:       launch.ReplayOf,
+		CurrentPrompts: launch.CurrentPrompts,
+		ResumeOf:       launch.ResumeOf,
+		Origin:         origin,
+		Locale:         i18n.Current(),
+		Debug:          debuglog.Enabled(),
+		ReadOnly:       cfg.Docker.ReadOnly,
+		SeccompProfile: seccomp,
+		PidsLimit:      cfg.Docker.PidsLimit,
+		Memory:         cfg.Docker.Memory,
+		CPUs:           cfg.Docker.CPUs,
+	}
+	runOpts.HostUID, runOpts.HostGID = hostIDs()
+	runOpts.NoTTY = !term.IsTerminal(int(os.Stdin.Fd())) //nolint:gosec // file descriptors fit in an int
+
+	return runSupervised(defaultRunner{}, runOpts, w, theme)
+}
+
+// restoreStash puts back the changes git.dirty_tree: stash set aside before
+// the run, even when it was interrupted.
+func restoreStash(ctx context.Context, w io.Writer, theme *ui.Theme, stash preflight.Stash) {
+	if len(stash.Files) == 0 {
+		return
+	}
+	if err := stash.Restore(context.WithoutCancel(ctx)); err != nil {
+		fmt.Fprintln(w, theme.Warning.Render(err.Error())) //nolint:errcheck // display-only
+		return
+	}
+	fmt.Fprintln(w, theme.Muted.Render("Restored the uncommitted changes stashed before the run.")) //nolint:errcheck // display-only
+}
+
+// takeSnapshot saves the workspace under safety.snapshot before preflight
+// stashes, commits or pushes anything, so the whole run can be undone.
+func takeSnapshot(ctx context.Context, w io.Writer, theme *ui.Theme, launch *LaunchOptions) error {
+	if launch.VCS.Kind() != vcs.KindGit {
+		fmt.Fprintln(w, theme.Warning.Render("safety.snapshot needs a git repo; no snapshot taken.")) //nolint:errcheck // display-only
+		return nil
+	}
+	s, err := snapshot.Create(ctx, launch.VCS.Root(), "before ralph "+launch.Mode, time.Now(), snapshot.DefaultKeep)
+	if err != nil {
+		return err //nolint:wrapcheck // snapshot errors already have context
 	}
+	fmt.Fprintf(w, "%s %s %s\n", theme.Muted.Render("Snapshot:"), theme.Info.Render(s.ID), //nolint:errcheck // display-only
+		theme.Muted.Render("(undo the run with: ralph snapshot restore "+s.ID+")"))
+	return nil
+}
 
-	return Run(runOpts)
+// checkToken probes GITHUB_PAT, and GITHUB_APP_TOKEN for github.report:
+// check, for what the run will do with them before preflight pushes
+// anything. Remotes not on github.com are left to git, and
+// a GitHub that can't be reached only warns.
+func checkToken(ctx context.Context, w io.Writer, theme *ui.Theme, origin *state.Origin, cfg *config.Config) error {
+	if !strings.Contains(origin.Remote, "github.com") {
+		slog.DebugContext(ctx, "preflight: token not checked", "remote", origin.Remote)
+		return nil
+	}
+	probe := preflight.TokenProbe{
+		Repo:     origin.Repo,
+		Token:    os.Getenv(ghstatus.StatusTokenEnv),
+		AppToken: os.Getenv(ghstatus.CheckTokenEnv),
+		Needs: preflight.TokenNeeds{
+			PullRequests: cfg.GitHub.PlanReview,
+			Statuses:     cfg.GitHub.Report == ghstatus.KindStatus,
+			Checks:       cfg.GitHub.Report == ghstatus.KindCheck,
+		},
+	}
+	err := probe.Check(ctx)
+	if errors.Is(err, preflight.ErrTokenUnchecked) {
+		fmt.Fprintln(w, theme.Warning.Render(err.Error())) //nolint:errcheck // display-only
+		return nil
+	}
+	return err //nolint:wrapcheck // preflight errors already have context
 }
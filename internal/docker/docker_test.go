@@ -0,0 +1,27 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/ghstatus"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+func TestCheckToken_CheckReportNeedsAppToken(t *testing.T) {
+	t.Setenv(ghstatus.CheckTokenEnv, "")
+	origin := &state.Origin{Repo: "o/r", Remote: "https://github.com/o/r.git"}
+	cfg := &config.Config{GitHub: config.GitHub{Report: ghstatus.KindCheck}}
+
+	err := checkToken(context.Background(), io.Discard, ui.DefaultTheme(), origin, cfg)
+	require.ErrorContains(t, err, "github.report: check needs a GitHub App installation token in GITHUB_APP_TOKEN")
+
+	t.Setenv(ghstatus.CheckTokenEnv, "github_pat_abc")
+	err = checkToken(context.Background(), io.Discard, ui.DefaultTheme(), origin, cfg)
+	require.ErrorContains(t, err, "GITHUB_APP_TOKEN is a personal access token")
+}
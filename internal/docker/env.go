@@ -1,15 +1,29 @@
 package docker
 
 import (
-	"bufio"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/agent"
 )
 
+// MaxEnvFileSize is the largest env file LoadEnvFile reads. Real .env files
+// are a few kilobytes; anything bigger is almost certainly the wrong file.
+const MaxEnvFileSize = 1 << 20
+
+// envKey matches a valid variable name.
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // LoadEnvFile parses a KEY=VALUE env file. It skips blank lines and # comments,
-// and strips surrounding quotes from values. Returns an empty map (not error) if
-// the file does not exist.
+// accepts an "export " prefix, and strips surrounding quotes from values.
+// Quoted values may span lines; in double quotes, \" \\ and \n are escapes.
+// Malformed lines are errors naming the line number, as is a file over
+// MaxEnvFileSize. Returns an empty map (not error) if the file does not exist.
 func LoadEnvFile(path string) (map[string]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -20,33 +34,109 @@ func LoadEnvFile(path string) (map[string]string, error) {
 	}
 	defer f.Close() //nolint:errcheck // read-only
 
+	data, err := io.ReadAll(io.LimitReader(f, MaxEnvFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading env file: %w", err)
+	}
+	if len(data) > MaxEnvFileSize {
+		return nil, fmt.Errorf("env file %s is over the %d KB limit", path, MaxEnvFileSize>>10)
+	}
+	env, err := parseEnv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return env, nil
+}
+
+// parseEnv parses the contents of an env file.
+func parseEnv(data string) (map[string]string, error) {
 	env := map[string]string{}
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
 
 		key, value, ok := strings.Cut(line, "=")
 		if !ok {
-			continue
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
 		}
-
 		key = strings.TrimSpace(key)
+		if !envKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNo, key)
+		}
 		value = strings.TrimSpace(value)
-		value = stripQuotes(value)
-
-		if key != "" {
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
 			env[key] = value
+			continue
+		}
+
+		// A quoted value runs to its closing quote, on this line or a later one.
+		quoted := value
+		for {
+			v, rest, closed := unquote(quoted)
+			if closed {
+				if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+					return nil, fmt.Errorf("line %d: unexpected text after quoted value of %s", lineNo, key)
+				}
+				env[key] = v
+				break
+			}
+			if i++; i >= len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value of %s", lineNo, key)
+			}
+			quoted += "\n" + lines[i]
 		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("reading env file: %w", err)
 	}
 	return env, nil
 }
 
+// unquote reads a value starting with a quote, returning its contents and
+// the text after the closing quote. closed is false when s has no closing
+// quote yet.
+func unquote(s string) (value, rest string, closed bool) {
+	q := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == q:
+			return b.String(), s[i+1:], true
+		case q == '"' && c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte(c)
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", false
+}
+
+// SecretValues returns the values of the credentials that may be loaded
+// from .env, as set in the environment, for masking in output.
+func SecretValues(getenv func(string) string) []string {
+	var values []string
+	for key := range allowedEnvVars {
+		if v := getenv(key); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 // ValidateEnv checks that all required keys are present in the env map or in
 // os.Getenv as a fallback. Returns an error listing any missing keys.
 func ValidateEnv(env map[string]string, required []string) error {
@@ -66,11 +156,23 @@ func ValidateEnv(env map[string]string, required []string) error {
 	return nil
 }
 
-func stripQuotes(s string) string {
-	if len(s) >= 2 {
-		if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
-			return s[1 : len(s)-1]
+// CheckCredentials checks env, loaded from .env with the environment as a
+// fallback, as a run would: only allowed names, the required vars, and a
+// credential for the agent.
+func CheckCredentials(env map[string]string, agentName string) error {
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		if !allowedEnvVars[k] {
+			return fmt.Errorf("disallowed env var in .env: %s (allowed: %s)", k, allowedEnvList)
 		}
 	}
-	return s
+	if err := ValidateEnv(env, requiredEnvVars); err != nil {
+		return err
+	}
+	var err error
+	if agentName == agent.NameClaude {
+		_, err = ResolveAuth(env)
+	} else {
+		_, err = ResolveAgentAuth(agentName, env)
+	}
+	return err
 }
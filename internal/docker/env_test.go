@@ -3,6 +3,7 @@ package docker
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,6 +48,49 @@ func TestLoadEnvFile(t *testing.T) {
 		assert.Equal(t, "a=b=c", env["KEY"])
 	})
 
+	t.Run("export prefix", func(t *testing.T) {
+		f := writeTemp(t, "export FOO=bar\nexport\tBAZ='qux'\nexported=yes\n")
+		env, err := LoadEnvFile(f)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"FOO": "bar", "BAZ": "qux", "exported": "yes"}, env)
+	})
+
+	t.Run("multi-line quoted values", func(t *testing.T) {
+		f := writeTemp(t, "KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nSINGLE='a\n  b'\nNEXT=1\n")
+		env, err := LoadEnvFile(f)
+		require.NoError(t, err)
+		assert.Equal(t, "-----BEGIN KEY-----\nabc\n-----END KEY-----", env["KEY"])
+		assert.Equal(t, "a\n  b", env["SINGLE"])
+		assert.Equal(t, "1", env["NEXT"])
+	})
+
+	t.Run("double-quote escapes", func(t *testing.T) {
+		f := writeTemp(t, `KEY="say \"hi\"\nback\\slash \d" # comment`+"\n"+`RAW='no \n escapes'`)
+		env, err := LoadEnvFile(f)
+		require.NoError(t, err)
+		assert.Equal(t, "say \"hi\"\nback\\slash \\d", env["KEY"])
+		assert.Equal(t, `no \n escapes`, env["RAW"])
+	})
+
+	t.Run("malformed lines report their number", func(t *testing.T) {
+		for content, want := range map[string]string{
+			"A=1\njust text\n":       "line 2: expected KEY=VALUE",
+			"A=1\n\n1BAD=x\n":        `line 3: invalid variable name "1BAD"`,
+			"A=1\nB=\"open\nstill\n": "line 2: unterminated quoted value of B",
+			"A=\"x\" trailing\n":     "line 1: unexpected text after quoted value of A",
+			"export A B=1\n":         `line 1: invalid variable name "A B"`,
+		} {
+			_, err := LoadEnvFile(writeTemp(t, content))
+			require.ErrorContains(t, err, want, content)
+		}
+	})
+
+	t.Run("file over the size limit", func(t *testing.T) {
+		f := writeTemp(t, "KEY="+strings.Repeat("x", MaxEnvFileSize)+"\n")
+		_, err := LoadEnvFile(f)
+		require.ErrorContains(t, err, "over the 1024 KB limit")
+	})
+
 	t.Run("missing file returns empty map", func(t *testing.T) {
 		env, err := LoadEnvFile(filepath.Join(t.TempDir(), "nope"))
 		require.NoError(t, err)
@@ -54,6 +98,11 @@ func TestLoadEnvFile(t *testing.T) {
 	})
 }
 
+func TestSecretValues(t *testing.T) {
+	env := map[string]string{"GITHUB_PAT": "ghp_x", "ANTHROPIC_API_KEY": "sk-ant-x", "HOME": "/root"}
+	assert.ElementsMatch(t, []string{"ghp_x", "sk-ant-x"}, SecretValues(func(k string) string { return env[k] }))
+}
+
 func TestValidateEnv(t *testing.T) {
 	t.Run("all present in map", func(t *testing.T) {
 		env := map[string]string{"A": "1", "B": "2"}
@@ -73,6 +122,17 @@ func TestValidateEnv(t *testing.T) {
 	})
 }
 
+func TestCheckCredentials(t *testing.T) {
+	for _, k := range []string{"ANTHROPIC_API_KEY", "CLAUDE_CODE_OAUTH_TOKEN", "OPENAI_API_KEY", "GITHUB_PAT"} {
+		t.Setenv(k, "")
+	}
+	require.NoError(t, CheckCredentials(map[string]string{"ANTHROPIC_API_KEY": "k", "GITHUB_PAT": "p"}, "claude"))
+	require.ErrorContains(t, CheckCredentials(map[string]string{"ANTHROPIC_API_KEY": "k"}, "claude"), "GITHUB_PAT")
+	require.ErrorContains(t, CheckCredentials(map[string]string{"GITHUB_PAT": "p"}, "claude"), "missing auth")
+	require.ErrorContains(t, CheckCredentials(map[string]string{"GITHUB_PAT": "p", "ANTHROPIC_API_KEY": "k"}, "codex"), "OPENAI_API_KEY")
+	require.ErrorContains(t, CheckCredentials(map[string]string{"PATH": "/tmp"}, "claude"), "disallowed env var in .env: PATH")
+}
+
 func TestAllowedEnvVars(t *testing.T) {
 	t.Run("allowed keys pass", func(t *testing.T) {
 		allowed := []string{"ANTHROPIC_API_KEY", "CLAUDE_CODE_OAUTH_TOKEN", "GITHUB_PAT"}
@@ -139,6 +199,24 @@ func TestResolveAuth(t *testing.T) {
 	}
 }
 
+func TestResolveAgentAuth(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("GEMINI_API_KEY", "")
+
+	got, err := ResolveAgentAuth("codex", map[string]string{"OPENAI_API_KEY": "test-value"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"OPENAI_API_KEY"}, got)
+
+	_, err = ResolveAgentAuth("gemini", map[string]string{"OPENAI_API_KEY": "test-value"})
+	require.ErrorContains(t, err, "missing auth for gemini: set GEMINI_API_KEY")
+
+	t.Setenv("GEMINI_API_KEY", "from-shell")
+	got, err = ResolveAgentAuth("aider", map[string]string{"ANTHROPIC_API_KEY": "test-value"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ANTHROPIC_API_KEY", "GEMINI_API_KEY"}, got)
+}
+
 func writeTemp(t *testing.T, content string) string {
 	t.Helper()
 	p := filepath.Join(t.TempDir(), ".env")
@@ -2,8 +2,11 @@ package docker
 
 import (
 	"context"
+	"io"
 	"os"
 	"os/exec"
+
+	"github.com/benwilkes9/ralph-cli/internal/debuglog"
 )
 
 // CommandRunner abstracts subprocess invocation for testing.
@@ -11,13 +14,42 @@ type CommandRunner interface {
 	Run(name string, args ...string) error
 }
 
-type defaultRunner struct{}
+// defaultRunner runs commands attached to ralph's own terminal.
+type defaultRunner struct {
+	stdout io.Writer // nil = os.Stdout; stderr for output that isn't the loop's under --output json
+}
 
-func (defaultRunner) Run(name string, args ...string) error {
+func (r defaultRunner) Run(name string, args ...string) error {
 	cmd := exec.CommandContext(context.Background(), name, args...) //nolint:gosec // args are validated by callers
 	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
+	if r.stdout != nil {
+		cmd.Stdout = r.stdout
+	}
+	cmd.Stderr = os.Stderr
+	done := debuglog.Exec(context.Background(), name, args...)
+	err := cmd.Run()
+	done(err)
+	return err //nolint:wrapcheck // callers wrap with context
+}
+
+// hostRunner runs commands in dir with env added to ralph's own
+// environment, for loops run without a container.
+type hostRunner struct {
+	dir string
+	env []string
+}
+
+func (r hostRunner) Run(name string, args ...string) error {
+	cmd := exec.CommandContext(context.Background(), name, args...) //nolint:gosec // name is ralph's own binary
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), r.env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	return cmd.Run() //nolint:wrapcheck // callers wrap with context
+	done := debuglog.Exec(context.Background(), name, args...)
+	err := cmd.Run()
+	done(err)
+	return err //nolint:wrapcheck // callers wrap with context
 }
@@ -0,0 +1,164 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// ImageReport describes a built image: its size and, when a scanner is
+// installed, how many high and critical vulnerabilities it has.
+type ImageReport struct {
+	Size     int64 // bytes
+	Layers   int
+	Scanner  string // "trivy" or "grype"; "" when neither is installed
+	Critical int
+	High     int
+	ScanErr  error // the scan failed; the counts are unset
+}
+
+// Scanners are the vulnerability scanners ralph uses, in order of preference.
+var Scanners = []string{"trivy", "grype"}
+
+// outputFunc runs a command and returns its stdout.
+type outputFunc func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+func commandOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output() //nolint:gosec,wrapcheck // fixed commands on ralph's image; callers wrap
+}
+
+// InspectImage reports tag's size and layer count, and scans it with the
+// first of Scanners on PATH.
+func InspectImage(ctx context.Context, tag string) (*ImageReport, error) {
+	return inspectImage(ctx, commandOutput, exec.LookPath, tag)
+}
+
+func inspectImage(ctx context.Context, output outputFunc, lookPath func(string) (string, error), tag string) (*ImageReport, error) {
+	out, err := output(ctx, "docker", "image", "inspect", "--format", "{{.Size}} {{len .RootFS.Layers}}", tag)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting %s: %w", tag, err)
+	}
+	r := &ImageReport{}
+	size, layers, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+	if r.Size, err = strconv.ParseInt(size, 10, 64); err != nil {
+		return nil, fmt.Errorf("inspecting %s: unexpected size %q", tag, size)
+	}
+	if r.Layers, err = strconv.Atoi(layers); err != nil {
+		return nil, fmt.Errorf("inspecting %s: unexpected layer count %q", tag, layers)
+	}
+
+	for _, s := range Scanners {
+		if _, err := lookPath(s); err == nil {
+			r.Scanner = s
+			break
+		}
+	}
+	var severities []string
+	switch r.Scanner {
+	case "trivy":
+		out, err = output(ctx, "trivy", "image", "--quiet", "--format", "json", "--severity", "HIGH,CRITICAL", tag)
+		if err == nil {
+			severities, err = trivySeverities(out)
+		}
+	case "grype":
+		out, err = output(ctx, "grype", "--quiet", "-o", "json", tag)
+		if err == nil {
+			severities, err = grypeSeverities(out)
+		}
+	}
+	if err != nil {
+		r.ScanErr = fmt.Errorf("%s: %w", r.Scanner, err)
+	}
+	for _, s := range severities {
+		switch strings.ToLower(s) {
+		case "critical":
+			r.Critical++
+		case "high":
+			r.High++
+		}
+	}
+	return r, nil
+}
+
+// trivySeverities lists the severity of each finding in trivy's JSON report.
+func trivySeverities(data []byte) ([]string, error) {
+	var report struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				Severity string `json:"Severity"`
+			} `json:"Vulnerabilities"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parsing report: %w", err)
+	}
+	var severities []string
+	for _, res := range report.Results {
+		for _, v := range res.Vulnerabilities {
+			severities = append(severities, v.Severity)
+		}
+	}
+	return severities, nil
+}
+
+// grypeSeverities lists the severity of each match in grype's JSON report.
+func grypeSeverities(data []byte) ([]string, error) {
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				Severity string `json:"severity"`
+			} `json:"vulnerability"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parsing report: %w", err)
+	}
+	severities := make([]string, 0, len(report.Matches))
+	for _, m := range report.Matches {
+		severities = append(severities, m.Vulnerability.Severity)
+	}
+	return severities, nil
+}
+
+// reportImage prints the report for the image just built. A failed
+// inspection is shown rather than stopping the run.
+func reportImage(ctx context.Context, w io.Writer, theme *ui.Theme, cfg *config.Config) {
+	r, err := InspectImage(ctx, DefaultTag)
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Could not report on the image: %s", err))) //nolint:errcheck // display-only
+		return
+	}
+	RenderImageReport(w, theme, DefaultTag, r, cfg.Docker.ImageWarnMB, cfg.Docker.ImageWarnVulns)
+}
+
+// RenderImageReport prints r, warning when the image is over warnMB or has
+// more than warnVulns high and critical vulnerabilities.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderImageReport(w io.Writer, theme *ui.Theme, tag string, r *ImageReport, warnMB, warnVulns int) {
+	fmt.Fprintf(w, "%s %s %d MB, %d layers\n", theme.Muted.Render("Image:"), tag, r.Size>>20, r.Layers)
+	if r.Size > int64(warnMB)<<20 {
+		fmt.Fprintf(w, "%s image is over %d MB; slim the base image or clean caches in %s (docker.image_warn_mb)\n",
+			theme.Warning.Render("⚠"), warnMB, DefaultDockerfile)
+	}
+	switch {
+	case r.Scanner == "":
+		fmt.Fprintln(w, theme.Muted.Render("Vulnerabilities: not scanned; install "+strings.Join(Scanners, " or ")+" to scan"))
+	case r.ScanErr != nil:
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Vulnerabilities: scan failed: %s", r.ScanErr)))
+	default:
+		fmt.Fprintf(w, "%s %d critical, %d high %s\n",
+			theme.Muted.Render("Vulnerabilities:"), r.Critical, r.High, theme.Muted.Render("("+r.Scanner+")"))
+		if n := r.Critical + r.High; n > warnVulns {
+			fmt.Fprintf(w, "%s %d high or critical vulnerabilities; update the base image in %s (docker.image_warn_vulns)\n",
+				theme.Warning.Render("⚠"), n, DefaultDockerfile)
+		}
+	}
+}
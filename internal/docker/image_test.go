@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// fakeOutput answers commands by program name.
+func fakeOutput(outputs map[string]string) outputFunc {
+	return func(_ context.Context, name string, _ ...string) ([]byte, error) {
+		out, ok := outputs[name]
+		if !ok {
+			return nil, errors.New("exit status 1")
+		}
+		return []byte(out), nil
+	}
+}
+
+func onPath(names ...string) func(string) (string, error) {
+	return func(file string) (string, error) {
+		for _, n := range names {
+			if n == file {
+				return "/usr/bin/" + n, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestInspectImage(t *testing.T) {
+	trivy := `{"Results":[{"Vulnerabilities":[{"Severity":"CRITICAL"},{"Severity":"HIGH"}]},{"Vulnerabilities":[{"Severity":"HIGH"}]}]}`
+	grype := `{"matches":[{"vulnerability":{"severity":"High"}},{"vulnerability":{"severity":"Medium"}}]}`
+	inspect := "1610612736 14\n"
+
+	r, err := inspectImage(context.Background(), fakeOutput(map[string]string{"docker": inspect, "trivy": trivy, "grype": grype}), onPath("trivy", "grype"), DefaultTag)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1610612736), r.Size)
+	assert.Equal(t, 14, r.Layers)
+	assert.Equal(t, "trivy", r.Scanner, "trivy is preferred")
+	assert.Equal(t, 1, r.Critical)
+	assert.Equal(t, 2, r.High)
+
+	r, err = inspectImage(context.Background(), fakeOutput(map[string]string{"docker": inspect, "grype": grype}), onPath("grype"), DefaultTag)
+	require.NoError(t, err)
+	assert.Equal(t, "grype", r.Scanner)
+	assert.Equal(t, 0, r.Critical)
+	assert.Equal(t, 1, r.High)
+
+	r, err = inspectImage(context.Background(), fakeOutput(map[string]string{"docker": inspect}), onPath("trivy"), DefaultTag)
+	require.NoError(t, err)
+	require.ErrorContains(t, r.ScanErr, "trivy")
+
+	r, err = inspectImage(context.Background(), fakeOutput(map[string]string{"docker": inspect}), onPath(), DefaultTag)
+	require.NoError(t, err)
+	assert.Empty(t, r.Scanner)
+	require.NoError(t, r.ScanErr)
+
+	_, err = inspectImage(context.Background(), fakeOutput(nil), onPath(), DefaultTag)
+	require.ErrorContains(t, err, "inspecting ralph-loop")
+}
+
+func TestRenderImageReport(t *testing.T) {
+	theme := ui.DefaultTheme()
+	render := func(r *ImageReport, warnMB, warnVulns int) string {
+		var buf bytes.Buffer
+		RenderImageReport(&buf, theme, DefaultTag, r, warnMB, warnVulns)
+		return buf.String()
+	}
+
+	out := render(&ImageReport{Size: 3 << 30, Layers: 20, Scanner: "trivy", Critical: 1, High: 2}, 2048, 0)
+	assert.Contains(t, out, "ralph-loop 3072 MB, 20 layers")
+	assert.Contains(t, out, "image is over 2048 MB")
+	assert.Contains(t, out, "1 critical, 2 high")
+	assert.Contains(t, out, "3 high or critical vulnerabilities")
+
+	out = render(&ImageReport{Size: 500 << 20, Layers: 9, Scanner: "grype", High: 2}, 2048, 5)
+	assert.NotContains(t, out, "⚠", "under both thresholds")
+
+	out = render(&ImageReport{Size: 500 << 20, Layers: 9}, 2048, 0)
+	assert.True(t, strings.Contains(out, "install trivy or grype"))
+}
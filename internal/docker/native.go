@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/agent"
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/debuglog"
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// startNative runs the loop on the host instead of in a container, for
+// users already inside a devcontainer where nested Docker isn't available.
+// Preflight, env validation and auth have already run; there is no image,
+// firewall, deps volume or scratch volume, and git pushes with the host's
+// own credentials.
+func startNative(w io.Writer, theme *ui.Theme, launch *LaunchOptions, cfg *config.Config, origin *state.Origin) error {
+	a, err := agent.New(cfg.Agent)
+	if err != nil {
+		return fmt.Errorf("selecting agent: %w", err)
+	}
+	bin := a.Command(agent.Invocation{})[0]
+	if _, err := exec.LookPath(bin); err != nil {
+		return fmt.Errorf("--no-docker runs %s on the host, but it is not on PATH: %w", bin, err)
+	}
+
+	fmt.Fprintf(w, "%s %s  %s %s\n", //nolint:errcheck // display-only
+		theme.Muted.Render("Repo:"), origin.Repo,
+		theme.Muted.Render("Branch:"), theme.Info.Render(launch.Branch))
+	fmt.Fprintln(w, theme.Warning.Render("No Docker: the agent runs on this machine as you, without the container's network allowlist.")) //nolint:errcheck // display-only
+
+	opts := &RunOptions{
+		Mode:           launch.Mode,
+		MaxIter:        launch.MaxIterations,
+		Branch:         launch.Branch,
+		ProjectDir:     origin.Workspace,
+		PlanFile:       launch.PlanFile,
+		SpecsDir:       launch.SpecsDir,
+		AdditionalDirs: cfg.AdditionalDirs,
+		Tags:           launch.Tags,
+		Note:           launch.Note,
+		Step:           launch.Step,
+		Profile:        launch.Profile,
+		Experiment:     launch.Experiment,
+		Chaos:          launch.Chaos,
+		ChaosSeed:      launch.ChaosSeed,
+		OnClaudeError:  launch.OnClaudeError,
+		PlanApproval:   launch.PlanApproval,
+		Offline:        launch.Offline,
+		Output:         launch.Output,
+		MaxCost:        launch.MaxCost,
+		Package:        launch.Package,
+		ReplayOf:       launch.ReplayOf,
+		CurrentPrompts: launch.CurrentPrompts,
+		ResumeOf:       launch.ResumeOf,
+		Origin:         origin,
+		Locale:         i18n.Current(),
+		Debug:          debuglog.Enabled(),
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating the ralph binary: %w", err)
+	}
+	return runNative(hostRunner{dir: origin.Workspace, env: nativeEnv(opts)}, exe, opts)
+}
+
+// runNative runs ralph's hidden _loop command, the one a container's
+// entrypoint would run, as a child process.
+func runNative(runner CommandRunner, exe string, opts *RunOptions) error {
+	if err := runner.Run(exe, "_loop", opts.Mode, strconv.Itoa(opts.MaxIter)); err != nil {
+		return fmt.Errorf("running loop: %w", err)
+	}
+	return nil
+}
+
+// nativeEnv is the environment a container would get from docker run -e,
+// with the additional repos at their host paths rather than mount points.
+func nativeEnv(opts *RunOptions) []string {
+	env := []string{
+		"BRANCH=" + opts.Branch,
+		"PLAN_FILE=" + opts.PlanFile,
+		"SPECS_DIR=" + opts.SpecsDir,
+	}
+	if len(opts.AdditionalDirs) > 0 {
+		env = append(env, "ADDITIONAL_DIRS="+strings.Join(opts.AdditionalDirs, ","))
+	}
+	return append(env, loopEnv(opts)...)
+}
@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunNative(t *testing.T) {
+	r := &fakeRunner{}
+	require.NoError(t, runNative(r, "/usr/local/bin/ralph", baseRunOpts()))
+	assert.Equal(t, [][]string{{"/usr/local/bin/ralph", "_loop", "build", "5"}}, r.calls)
+
+	r = &fakeRunner{errFor: map[string]error{"/usr/local/bin/ralph": errors.New("exit status 1")}}
+	err := runNative(r, "/usr/local/bin/ralph", baseRunOpts())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "running loop")
+}
+
+func TestNativeEnv(t *testing.T) {
+	opts := baseRunOpts()
+	opts.AdditionalDirs = []string{"/home/user/repo-a", "/home/user/repo-b"}
+	opts.Offline = true
+	opts.Tags = []string{"nightly"}
+	opts.Output = "json"
+	opts.MaxCost = 7.5
+	opts.Package = "apps/web"
+
+	env := nativeEnv(opts)
+	assert.Contains(t, env, "BRANCH=main")
+	assert.Contains(t, env, "PLAN_FILE=.ralph/plans/PLAN.md")
+	assert.Contains(t, env, "SPECS_DIR=specs")
+	assert.Contains(t, env, "ADDITIONAL_DIRS=/home/user/repo-a,/home/user/repo-b", "host paths, not mount points")
+	assert.Contains(t, env, "RALPH_OFFLINE=1")
+	assert.Contains(t, env, "RALPH_TAGS=nightly")
+	assert.Contains(t, env, "RALPH_OUTPUT=json")
+	assert.Contains(t, env, "RALPH_MAX_COST=7.5")
+	assert.Contains(t, env, "RALPH_PACKAGE=apps/web")
+	for _, kv := range env {
+		assert.NotContains(t, kv, "ALLOWED_DOMAINS")
+		assert.NotContains(t, kv, "SCRATCH_DIR")
+	}
+}
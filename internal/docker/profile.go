@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfilesPath holds named credential sets, selected with --profile.
+// It contains secrets and is gitignored by ralph init.
+const DefaultProfilesPath = ".ralph/profiles.yaml"
+
+// profilesFile is the on-disk shape of DefaultProfilesPath:
+//
+//	profiles:
+//	  work:
+//	    ANTHROPIC_API_KEY: sk-ant-...
+//	    GITHUB_PAT: ghp_...
+type profilesFile struct {
+	Profiles map[string]map[string]string `yaml:"profiles"`
+}
+
+// LoadProfile returns the credentials of the named profile. Only the env
+// vars allowed in .env may appear in a profile.
+func LoadProfile(path, name string) (map[string]string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is the fixed profiles file
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("profile %q requested but %s does not exist", name, path)
+		}
+		return nil, fmt.Errorf("reading profiles: %w", err)
+	}
+
+	var pf profilesFile
+	if err := yaml.Unmarshal(data, &pf); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	env, ok := pf.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(pf.Profiles))
+		for n := range pf.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %q not found in %s (available: %s)", name, path, strings.Join(names, ", "))
+	}
+	for k := range env {
+		if !allowedEnvVars[k] {
+			return nil, fmt.Errorf("disallowed env var in profile %q: %s (allowed: %s)", name, k, allowedEnvList)
+		}
+	}
+	return env, nil
+}
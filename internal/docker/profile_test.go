@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleProfiles = `profiles:
+  work:
+    ANTHROPIC_API_KEY: sk-work
+    GITHUB_PAT: ghp-work
+  personal:
+    CLAUDE_CODE_OAUTH_TOKEN: oauth-personal
+    GITHUB_PAT: ghp-personal
+`
+
+func TestLoadProfile(t *testing.T) {
+	t.Run("selects named profile", func(t *testing.T) {
+		env, err := LoadProfile(writeTemp(t, sampleProfiles), "work")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"ANTHROPIC_API_KEY": "sk-work", "GITHUB_PAT": "ghp-work"}, env)
+	})
+
+	t.Run("unknown profile lists available", func(t *testing.T) {
+		_, err := LoadProfile(writeTemp(t, sampleProfiles), "oss")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `profile "oss" not found`)
+		assert.Contains(t, err.Error(), "personal, work")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadProfile(filepath.Join(t.TempDir(), "profiles.yaml"), "work")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not exist")
+	})
+
+	t.Run("disallowed key", func(t *testing.T) {
+		_, err := LoadProfile(writeTemp(t, "profiles:\n  work:\n    AWS_SECRET_ACCESS_KEY: x\n"), "work")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "disallowed env var")
+	})
+}
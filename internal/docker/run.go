@@ -4,8 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/debuglog"
+	"github.com/benwilkes9/ralph-cli/internal/ghstatus"
+	"github.com/benwilkes9/ralph-cli/internal/loop"
+	"github.com/benwilkes9/ralph-cli/internal/state"
 )
 
 // RunOptions configures a docker run invocation.
@@ -17,11 +23,42 @@ type RunOptions struct {
 	ProjectDir     string // host project root for bind mount
 	PlanFile       string
 	SpecsDir       string
-	AllowedDomains []string   // merged default + extra
-	DepsDir        string     // relative path for dep volume overlay (e.g. "node_modules"), empty = none
-	ProjectName    string     // for volume naming
-	Auth           AuthMethod // which credential to pass into the container
-	AdditionalDirs []string   // host paths to additional repos
+	AllowedDomains []string        // merged default + extra
+	DepsDir        string          // relative path for dep volume overlay (e.g. "node_modules"), empty = none
+	DepsBranch     string          // sanitized branch with its own deps volume (swarm members); empty = the project's shared one
+	ProjectName    string          // for volume naming
+	ScratchVolume  string          // named volume mounted at ScratchDir, empty = none
+	Auth           AuthMethod      // which credential to pass into the container
+	AgentEnv       []string        // credentials passed instead of Auth's when the agent isn't claude
+	AppToken       bool            // pass GITHUB_APP_TOKEN, which github.report: check posts with
+	AdditionalDirs []string        // host paths to additional repos
+	Tags           []string        // run labels, forwarded as RALPH_TAGS
+	Note           string          // run description, forwarded as RALPH_NOTE
+	Step           bool            // step mode, forwarded as RALPH_STEP
+	Profile        string          // credential profile name, forwarded as RALPH_PROFILE
+	Experiment     string          // experiment variant, forwarded as RALPH_EXPERIMENT
+	Chaos          float64         // failure-injection rate, forwarded as RALPH_CHAOS with RALPH_CHAOS_SEED
+	ChaosSeed      int64           // seed for Chaos
+	OnClaudeError  string          // claude error policy, forwarded as RALPH_ON_CLAUDE_ERROR; empty = abort
+	PlanApproval   *state.Approval // forwarded as RALPH_PLAN_PR and RALPH_PLAN_APPROVERS
+	Offline        bool            // forwarded as RALPH_OFFLINE: the loop keeps commits local
+	Output         string          // loop progress format, forwarded as RALPH_OUTPUT; empty = text
+	MaxCost        float64         // cost cap in USD, forwarded as RALPH_MAX_COST; 0 = cost.max_cost
+	Package        string          // workspace package directory, forwarded as RALPH_PACKAGE; empty = whole repo
+	ReplayOf       int             // run whose prompts are replayed, forwarded as RALPH_REPLAY_OF
+	CurrentPrompts bool            // forwarded as RALPH_REPLAY_CURRENT_PROMPTS
+	ResumeOf       int             // run being resumed, forwarded as RALPH_RESUME_OF
+	Origin         *state.Origin   // the host clone, forwarded as RALPH_REPO, RALPH_REMOTE and RALPH_WORKSPACE
+	Locale         string          // output language, forwarded as RALPH_LOCALE so the loop needn't resolve it again
+	HostUID        int             // host user the container's claude user is remapped to; 0 = no mapping
+	HostGID        int             // host group for HostUID
+	NoTTY          bool            // stdin isn't a terminal (e.g. CI), so no -t
+	ReadOnly       bool            // --read-only root filesystem, keeping what the entrypoint and agent write to writable
+	SeccompProfile string          // absolute path of a seccomp profile; empty = docker's default
+	PidsLimit      int             // --pids-limit; 0 = docker's default
+	Memory         string          // --memory, e.g. "4g"; empty = none
+	CPUs           float64         // --cpus; 0 = none
+	Debug          bool            // forwarded as RALPH_DEBUG: the loop logs its decisions too
 }
 
 // Run executes docker run with the given options, attaching stdin/stdout/stderr.
@@ -35,28 +72,85 @@ func runWithRunner(runner CommandRunner, opts *RunOptions) error {
 		authEnv = "CLAUDE_CODE_OAUTH_TOKEN"
 	}
 
+	tty := "-it"
+	switch {
+	case opts.NoTTY:
+		tty = "-i" // docker refuses -t without a terminal
+	case opts.Output == loop.OutputJSON:
+		tty = "-i" // a terminal merges the container's stderr into the events on stdout
+	}
 	args := []string{
-		"run", "--rm", "-it",
+		"run", "--rm", tty,
+		"--restart", "no", // crashes are detected and retried host-side by runSupervised
+		"--label", LabelDir + "=" + resolveMountSource(opts.ProjectDir), // found by ralph abort
+		"--label", LabelProject + "=" + opts.ProjectName,
+		"--label", LabelBranch + "=" + opts.Branch,
+		"--label", LabelMode + "=" + opts.Mode,
 		"--security-opt", "no-new-privileges",
 		"--cap-add", "NET_ADMIN",
-		"-e", authEnv,
 		"-e", "GITHUB_PAT",
 		"-e", "BRANCH=" + opts.Branch,
 		"-e", "PLAN_FILE=" + opts.PlanFile,
 		"-e", "SPECS_DIR=" + opts.SpecsDir,
 		"-e", "ALLOWED_DOMAINS=" + strings.Join(opts.AllowedDomains, ","),
-		"-v", bindMount(opts.ProjectDir, "/workspace/repo"),
+		"-v", bindMount(resolveMountSource(opts.ProjectDir), "/workspace/repo"),
+	}
+	if opts.AppToken {
+		args = append(args, "-e", ghstatus.CheckTokenEnv)
+	}
+
+	// Lockdown beyond no-new-privileges, from the docker config.
+	if opts.SeccompProfile != "" {
+		args = append(args, "--security-opt", "seccomp="+opts.SeccompProfile)
+	}
+	if opts.ReadOnly {
+		// What the container must still write stays writable: /home/claude
+		// (git and agent config) and /etc (the host user remap) as anonymous
+		// volumes, seeded from the image and removed with the container, and
+		// /tmp and /run (iptables' lock) as tmpfs.
+		args = append(args,
+			"--read-only",
+			"-v", "/home/claude",
+			"-v", "/etc",
+			"--tmpfs", "/tmp",
+			"--tmpfs", "/run",
+		)
+	}
+	if opts.PidsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(opts.PidsLimit))
+	}
+	if opts.Memory != "" {
+		args = append(args, "--memory", opts.Memory)
+	}
+	if opts.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(opts.CPUs, 'f', -1, 64))
+	}
+
+	if len(opts.AgentEnv) == 0 {
+		args = append(args, "-e", authEnv)
+	}
+	for _, name := range opts.AgentEnv {
+		args = append(args, "-e", name)
 	}
 
 	if opts.DepsDir != "" {
 		args = append(args,
-			"-v", depsVolume(opts.ProjectName)+":/workspace/repo/"+opts.DepsDir,
+			"-v", DepsVolume(opts.ProjectName, opts.DepsBranch)+":/workspace/repo/"+opts.DepsDir,
 			"-e", "DEPS_DIR="+opts.DepsDir,
 		)
 	}
 
+	if opts.ScratchVolume != "" {
+		args = append(args,
+			"-v", opts.ScratchVolume+":/workspace/repo/"+ScratchDir,
+			"-e", "SCRATCH_DIR="+ScratchDir,
+		)
+	}
+
 	for _, dir := range opts.AdditionalDirs {
-		args = append(args, "-v", bindMount(dir, "/workspace/"+filepath.Base(dir)))
+		// The container path keeps the configured basename even when dir is
+		// a symlink to a differently named directory.
+		args = append(args, "-v", bindMount(resolveMountSource(dir), "/workspace/"+filepath.Base(dir)))
 	}
 	if len(opts.AdditionalDirs) > 0 {
 		cPaths := make([]string, 0, len(opts.AdditionalDirs))
@@ -66,6 +160,19 @@ func runWithRunner(runner CommandRunner, opts *RunOptions) error {
 		args = append(args, "-e", "ADDITIONAL_DIRS="+strings.Join(cPaths, ","))
 	}
 
+	// The entrypoint needs root for the firewall, so rather than docker
+	// --user it remaps the claude user to these IDs before dropping privileges.
+	if opts.HostUID > 0 {
+		args = append(args,
+			"-e", "HOST_UID="+strconv.Itoa(opts.HostUID),
+			"-e", "HOST_GID="+strconv.Itoa(opts.HostGID),
+		)
+	}
+
+	for _, kv := range loopEnv(opts) {
+		args = append(args, "-e", kv)
+	}
+
 	// If a cross-compiled Linux binary exists alongside the host binary,
 	// mount it into the container to override the registry-installed version.
 	// This lets local (unpublished) changes take effect inside Docker.
@@ -86,12 +193,126 @@ func runWithRunner(runner CommandRunner, opts *RunOptions) error {
 	return nil
 }
 
+// loopEnv returns the RALPH_* settings the loop reads from its environment,
+// as KEY=VALUE pairs, for whichever of them the run sets.
+func loopEnv(opts *RunOptions) []string {
+	var env []string
+	if len(opts.Tags) > 0 {
+		env = append(env, "RALPH_TAGS="+strings.Join(opts.Tags, ","))
+	}
+	if opts.Note != "" {
+		env = append(env, "RALPH_NOTE="+opts.Note)
+	}
+	if opts.Step {
+		env = append(env, "RALPH_STEP=1")
+	}
+	if opts.Profile != "" {
+		env = append(env, "RALPH_PROFILE="+opts.Profile)
+	}
+	if opts.Experiment != "" {
+		env = append(env, "RALPH_EXPERIMENT="+opts.Experiment)
+	}
+	if opts.Chaos > 0 {
+		env = append(env,
+			"RALPH_CHAOS="+strconv.FormatFloat(opts.Chaos, 'g', -1, 64),
+			"RALPH_CHAOS_SEED="+strconv.FormatInt(opts.ChaosSeed, 10),
+		)
+	}
+	if opts.OnClaudeError != "" {
+		env = append(env, "RALPH_ON_CLAUDE_ERROR="+opts.OnClaudeError)
+	}
+	if opts.Offline {
+		env = append(env, "RALPH_OFFLINE=1")
+	}
+	if opts.Output != "" {
+		env = append(env, "RALPH_OUTPUT="+opts.Output)
+	}
+	if opts.MaxCost > 0 {
+		env = append(env, "RALPH_MAX_COST="+strconv.FormatFloat(opts.MaxCost, 'g', -1, 64))
+	}
+	if opts.Package != "" {
+		env = append(env, "RALPH_PACKAGE="+opts.Package)
+	}
+	if opts.ResumeOf > 0 {
+		env = append(env, "RALPH_RESUME_OF="+strconv.Itoa(opts.ResumeOf))
+	}
+	if opts.ReplayOf > 0 {
+		env = append(env, "RALPH_REPLAY_OF="+strconv.Itoa(opts.ReplayOf))
+		if opts.CurrentPrompts {
+			env = append(env, "RALPH_REPLAY_CURRENT_PROMPTS=1")
+		}
+	}
+	if a := opts.PlanApproval; a != nil {
+		env = append(env,
+			"RALPH_PLAN_PR="+strconv.Itoa(a.PR),
+			"RALPH_PLAN_APPROVERS="+strings.Join(a.Approvers, ","),
+		)
+	}
+	if opts.Debug {
+		env = append(env, debuglog.EnvVar+"=1")
+	}
+	if opts.Locale != "" {
+		env = append(env, "RALPH_LOCALE="+opts.Locale)
+	}
+	if o := opts.Origin; o != nil {
+		env = append(env,
+			"RALPH_REPO="+o.Repo,
+			"RALPH_REMOTE="+o.Remote,
+			"RALPH_WORKSPACE="+o.Workspace,
+		)
+	}
+	return env
+}
+
+// hostIDs returns the invoking user's UID and GID when bind-mounted files
+// would otherwise end up owned by the container user. Docker Desktop on
+// macOS and Windows already maps ownership, and root needs no mapping, so
+// those cases return zeros.
+func hostIDs() (uid, gid int) {
+	if runtime.GOOS != "linux" || os.Getuid() <= 0 {
+		return 0, 0
+	}
+	return os.Getuid(), os.Getgid()
+}
+
+// seccompProfile resolves docker.seccomp_profile against the repo root,
+// failing if the file is missing so docker isn't left to fail on it.
+func seccompProfile(repoRoot, profile string) (string, error) {
+	if profile == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(profile) {
+		profile = filepath.Join(repoRoot, profile)
+	}
+	if _, err := os.Stat(profile); err != nil {
+		return "", fmt.Errorf("docker.seccomp_profile: %w", err)
+	}
+	return profile, nil
+}
+
 func bindMount(hostDir, containerDir string) string {
 	return hostDir + ":" + containerDir
 }
 
-func depsVolume(projectName string) string {
-	return "ralph-deps-" + projectName
+// resolveMountSource resolves symlinks in a bind mount source, since Docker
+// Desktop only shares real paths. Unresolvable paths are returned as-is for
+// docker to report.
+func resolveMountSource(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		return resolved
+	}
+	return dir
+}
+
+// DepsVolume returns the named volume mounted over deps_dir. Runs of a
+// project share one, so dependencies survive across branches; a swarm
+// member passes its sanitized branch and gets its own, since members
+// install concurrently and their branches may need different dependencies.
+func DepsVolume(projectName, sanitizedBranch string) string {
+	if sanitizedBranch == "" {
+		return "ralph-deps-" + projectName
+	}
+	return "ralph-deps-" + projectName + "-" + sanitizedBranch
 }
 
 // findLinuxBinary returns the path to a cross-compiled Linux ralph binary
@@ -2,10 +2,15 @@ package docker
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
 )
 
 func baseRunOpts() *RunOptions {
@@ -36,6 +41,76 @@ func TestRunWithRunner_SecurityOptions(t *testing.T) {
 	assert.Contains(t, call, "no-new-privileges")
 }
 
+func TestRunWithRunner_Hardening(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.ReadOnly = true
+	opts.SeccompProfile = "/home/user/project/.ralph/docker/seccomp.json"
+	opts.PidsLimit = 512
+	opts.Memory = "4g"
+	opts.CPUs = 1.5
+	require.NoError(t, runWithRunner(r, opts))
+
+	call := strings.Join(r.calls[0], " ")
+	assert.Contains(t, call, "--security-opt no-new-privileges")
+	assert.Contains(t, call, "--security-opt seccomp=/home/user/project/.ralph/docker/seccomp.json")
+	assert.Contains(t, call, "--read-only -v /home/claude -v /etc --tmpfs /tmp --tmpfs /run")
+	assert.Contains(t, call, "--pids-limit 512")
+	assert.Contains(t, call, "--memory 4g")
+	assert.Contains(t, call, "--cpus 1.5")
+	assert.Less(t, strings.Index(call, "--read-only"), strings.Index(call, "ralph-loop"), "flags come before the image")
+}
+
+func TestRunWithRunner_NoHardening(t *testing.T) {
+	r := &fakeRunner{}
+	require.NoError(t, runWithRunner(r, baseRunOpts()))
+
+	call := r.calls[0]
+	for _, flag := range []string{"--read-only", "--tmpfs", "--pids-limit", "--memory", "--cpus"} {
+		assert.NotContains(t, call, flag)
+	}
+	for _, arg := range call {
+		assert.NotContains(t, arg, "seccomp=")
+	}
+}
+
+func TestSeccompProfile(t *testing.T) {
+	root := t.TempDir()
+	got, err := seccompProfile(root, "")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = seccompProfile(root, "seccomp.json")
+	require.ErrorContains(t, err, "docker.seccomp_profile")
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "seccomp.json"), []byte("{}"), 0o600))
+	got, err = seccompProfile(root, "seccomp.json")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "seccomp.json"), got)
+}
+
+func TestRunWithRunner_NoTTY(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.NoTTY = true
+	require.NoError(t, runWithRunner(r, opts))
+
+	call := r.calls[0]
+	assert.Contains(t, call, "-i")
+	assert.NotContains(t, call, "-it")
+}
+
+func TestRunWithRunner_OutputJSONNoTTY(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.Output = "json"
+	require.NoError(t, runWithRunner(r, opts))
+
+	call := r.calls[0]
+	assert.Contains(t, call, "-i")
+	assert.NotContains(t, call, "-it", "stderr must stay off the events on stdout")
+}
+
 func TestRunWithRunner_CapAddNetAdmin(t *testing.T) {
 	r := &fakeRunner{}
 	require.NoError(t, runWithRunner(r, baseRunOpts()))
@@ -57,6 +132,17 @@ func TestRunWithRunner_EnvVars(t *testing.T) {
 	assert.Contains(t, call, "SPECS_DIR=specs")
 }
 
+func TestRunWithRunner_AppToken(t *testing.T) {
+	r := &fakeRunner{}
+	require.NoError(t, runWithRunner(r, baseRunOpts()))
+	assert.NotContains(t, r.calls[0], "GITHUB_APP_TOKEN", "only check reporting needs it")
+
+	opts := baseRunOpts()
+	opts.AppToken = true
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[1], "GITHUB_APP_TOKEN")
+}
+
 func TestRunWithRunner_OAuthEnvVar(t *testing.T) {
 	r := &fakeRunner{}
 	opts := baseRunOpts()
@@ -68,6 +154,18 @@ func TestRunWithRunner_OAuthEnvVar(t *testing.T) {
 	assert.NotContains(t, call, "ANTHROPIC_API_KEY")
 }
 
+func TestRunWithRunner_AgentEnvReplacesClaudeAuth(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.AgentEnv = []string{"OPENAI_API_KEY"}
+	require.NoError(t, runWithRunner(r, opts))
+
+	call := r.calls[0]
+	assert.Contains(t, call, "OPENAI_API_KEY")
+	assert.NotContains(t, call, "ANTHROPIC_API_KEY")
+	assert.NotContains(t, call, "CLAUDE_CODE_OAUTH_TOKEN")
+}
+
 func TestRunWithRunner_AllowedDomainsEnvVar(t *testing.T) {
 	r := &fakeRunner{}
 	require.NoError(t, runWithRunner(r, baseRunOpts()))
@@ -114,6 +212,16 @@ func TestRunWithRunner_DepsVolume_Present(t *testing.T) {
 	assert.Contains(t, r.calls[0], "DEPS_DIR=node_modules")
 }
 
+func TestRunWithRunner_DepsVolume_PerSwarmBranch(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.DepsDir = "node_modules"
+	opts.DepsBranch = "feat-x"
+	require.NoError(t, runWithRunner(r, opts))
+
+	assert.Contains(t, r.calls[0], "ralph-deps-myproject-feat-x:/workspace/repo/node_modules")
+}
+
 func TestRunWithRunner_DepsVolume_Absent(t *testing.T) {
 	r := &fakeRunner{}
 	opts := baseRunOpts()
@@ -196,3 +304,227 @@ func TestRunWithRunner_WrapsError(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "docker run:")
 }
+
+func TestRunWithRunner_TagsAndNote(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.Tags = []string{"nightly", "prompt-v2"}
+	opts.Note = "attempt with new prompt"
+	require.NoError(t, runWithRunner(r, opts))
+
+	call := r.calls[0]
+	assert.Contains(t, call, "RALPH_TAGS=nightly,prompt-v2")
+	assert.Contains(t, call, "RALPH_NOTE=attempt with new prompt")
+}
+
+func TestRunWithRunner_Chaos(t *testing.T) {
+	r := &fakeRunner{}
+	require.NoError(t, runWithRunner(r, baseRunOpts()))
+	for _, arg := range r.calls[0] {
+		assert.NotContains(t, arg, "RALPH_CHAOS")
+	}
+
+	opts := baseRunOpts()
+	opts.Chaos = 0.3
+	opts.ChaosSeed = 42
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[1], "RALPH_CHAOS=0.3")
+	assert.Contains(t, r.calls[1], "RALPH_CHAOS_SEED=42")
+}
+
+func TestRunWithRunner_OnClaudeError(t *testing.T) {
+	r := &fakeRunner{}
+	require.NoError(t, runWithRunner(r, baseRunOpts()))
+	for _, arg := range r.calls[0] {
+		assert.NotContains(t, arg, "RALPH_ON_CLAUDE_ERROR")
+	}
+
+	opts := baseRunOpts()
+	opts.OnClaudeError = "skip-iteration"
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[1], "RALPH_ON_CLAUDE_ERROR=skip-iteration")
+}
+
+func TestRunWithRunner_NoTagsOrNote(t *testing.T) {
+	r := &fakeRunner{}
+	require.NoError(t, runWithRunner(r, baseRunOpts()))
+
+	for _, arg := range r.calls[0] {
+		assert.NotContains(t, arg, "RALPH_TAGS=")
+		assert.NotContains(t, arg, "RALPH_NOTE=")
+	}
+}
+
+func TestRunWithRunner_HostUserMapping(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.HostUID = 1001
+	opts.HostGID = 1002
+	require.NoError(t, runWithRunner(r, opts))
+
+	call := r.calls[0]
+	assert.Contains(t, call, "HOST_UID=1001")
+	assert.Contains(t, call, "HOST_GID=1002")
+	assert.NotContains(t, call, "--user", "entrypoint needs root for the firewall")
+}
+
+func TestRunWithRunner_NoHostUserMapping(t *testing.T) {
+	r := &fakeRunner{}
+	require.NoError(t, runWithRunner(r, baseRunOpts()))
+
+	for _, arg := range r.calls[0] {
+		assert.NotContains(t, arg, "HOST_UID=")
+	}
+}
+
+func TestRunWithRunner_StepMode(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.Step = true
+	require.NoError(t, runWithRunner(r, opts))
+
+	assert.Contains(t, r.calls[0], "RALPH_STEP=1")
+}
+
+func TestRunWithRunner_Profile(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.Profile = "work"
+	require.NoError(t, runWithRunner(r, opts))
+
+	assert.Contains(t, r.calls[0], "RALPH_PROFILE=work")
+}
+
+func TestRunWithRunner_Offline(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	require.NoError(t, runWithRunner(r, opts))
+	assert.NotContains(t, r.calls[0], "RALPH_OFFLINE=1")
+
+	opts.Offline = true
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[1], "RALPH_OFFLINE=1")
+}
+
+func TestRunWithRunner_Debug(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	require.NoError(t, runWithRunner(r, opts))
+	assert.NotContains(t, r.calls[0], "RALPH_DEBUG=1")
+
+	opts.Debug = true
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[1], "RALPH_DEBUG=1")
+}
+
+func TestRunWithRunner_Replay(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.ReplayOf = 4
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[0], "RALPH_REPLAY_OF=4")
+	assert.NotContains(t, r.calls[0], "RALPH_REPLAY_CURRENT_PROMPTS=1")
+
+	opts.CurrentPrompts = true
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[1], "RALPH_REPLAY_CURRENT_PROMPTS=1")
+}
+
+func TestRunWithRunner_Locale(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.Locale = "en"
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[0], "RALPH_LOCALE=en", "English too, so the loop's own environment can't override it")
+
+	opts.Locale = "es"
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[1], "RALPH_LOCALE=es")
+}
+
+func TestRunWithRunner_ResumeOf(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.ResumeOf = 3
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[0], "RALPH_RESUME_OF=3")
+}
+
+func TestRunWithRunner_PlanApproval(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.PlanApproval = &state.Approval{PR: 12, Approvers: []string{"ann", "bob"}}
+	require.NoError(t, runWithRunner(r, opts))
+
+	assert.Contains(t, r.calls[0], "RALPH_PLAN_PR=12")
+	assert.Contains(t, r.calls[0], "RALPH_PLAN_APPROVERS=ann,bob")
+}
+
+func TestRunWithRunner_ResolvesSymlinkedMounts(t *testing.T) {
+	base := t.TempDir()
+	repo := filepath.Join(base, "repo")
+	lib := filepath.Join(base, "lib-v2")
+	require.NoError(t, os.MkdirAll(repo, 0o750))
+	require.NoError(t, os.MkdirAll(lib, 0o750))
+	links := t.TempDir()
+	require.NoError(t, os.Symlink(repo, filepath.Join(links, "repo")))
+	require.NoError(t, os.Symlink(lib, filepath.Join(links, "lib")))
+
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.ProjectDir = filepath.Join(links, "repo")
+	opts.AdditionalDirs = []string{filepath.Join(links, "lib")}
+	require.NoError(t, runWithRunner(r, opts))
+
+	wantRepo, err := filepath.EvalSymlinks(repo)
+	require.NoError(t, err)
+	wantLib, err := filepath.EvalSymlinks(lib)
+	require.NoError(t, err)
+	call := r.calls[0]
+	assert.Contains(t, call, wantRepo+":/workspace/repo")
+	assert.Contains(t, call, wantLib+":/workspace/lib", "container path keeps the configured basename")
+	assert.Contains(t, call, "ADDITIONAL_DIRS=/workspace/lib")
+}
+
+func TestRunWithRunner_Experiment(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	require.NoError(t, runWithRunner(r, opts))
+	for _, arg := range r.calls[0] {
+		assert.NotContains(t, arg, "RALPH_EXPERIMENT")
+	}
+
+	opts.Experiment = "fast-sonnet"
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[1], "RALPH_EXPERIMENT=fast-sonnet")
+}
+
+func TestRunWithRunner_ScratchVolume(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	opts.ScratchVolume = ScratchVolume("myproject", "feature-x")
+	require.NoError(t, runWithRunner(r, opts))
+
+	call := r.calls[0]
+	assert.Contains(t, call, "ralph-scratch-myproject-feature-x:/workspace/repo/.ralph/scratch")
+	assert.Contains(t, call, "SCRATCH_DIR=.ralph/scratch")
+}
+
+func TestRemoveScratch(t *testing.T) {
+	r := &fakeRunner{}
+	require.NoError(t, removeScratchWithRunner(r, "myproject", "feature-x"))
+	assert.Equal(t, []string{"docker", "volume", "rm", "--force", "ralph-scratch-myproject-feature-x"}, r.calls[0])
+}
+
+func TestRunWithRunner_Origin(t *testing.T) {
+	r := &fakeRunner{}
+	opts := baseRunOpts()
+	require.NoError(t, runWithRunner(r, opts))
+	assert.NotContains(t, strings.Join(r.calls[0], " "), "RALPH_REPO")
+
+	opts.Origin = &state.Origin{Repo: "o/r", Remote: "https://github.com/o/r.git", Workspace: "/home/user/project"}
+	require.NoError(t, runWithRunner(r, opts))
+	assert.Contains(t, r.calls[1], "RALPH_REPO=o/r")
+	assert.Contains(t, r.calls[1], "RALPH_REMOTE=https://github.com/o/r.git")
+	assert.Contains(t, r.calls[1], "RALPH_WORKSPACE=/home/user/project")
+}
@@ -0,0 +1,27 @@
+package docker
+
+import (
+	"fmt"
+)
+
+// ScratchDir is where the scratchpad volume is mounted, relative to the repo
+// root. It is gitignored by ralph init, so agent notes never get committed.
+const ScratchDir = ".ralph/scratch"
+
+// ScratchVolume returns the named volume holding a branch's scratchpad.
+// sanitizedBranch must already be sanitized via git.SanitizeBranch.
+func ScratchVolume(projectName, sanitizedBranch string) string {
+	return "ralph-scratch-" + projectName + "-" + sanitizedBranch
+}
+
+// RemoveScratch deletes a branch's scratchpad volume.
+func RemoveScratch(projectName, sanitizedBranch string) error {
+	return removeScratchWithRunner(defaultRunner{}, projectName, sanitizedBranch)
+}
+
+func removeScratchWithRunner(runner CommandRunner, projectName, sanitizedBranch string) error {
+	if err := runner.Run("docker", "volume", "rm", "--force", ScratchVolume(projectName, sanitizedBranch)); err != nil {
+		return fmt.Errorf("docker volume rm: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// MaxCrashRetries is how many times a crashed container is relaunched to
+// retry the iteration it died in.
+const MaxCrashRetries = 1
+
+// loopLogsDir is where the in-container loop writes iteration logs, relative
+// to the project root.
+const loopLogsDir = "logs"
+
+// Exit codes that don't indicate a crash: the operator interrupted the run,
+// or docker couldn't start the container at all, so a retry would only fail
+// the same way.
+const (
+	exitInterrupted    = 130
+	exitDockerFailed   = 125
+	exitNotExecutable  = 126
+	exitCommandMissing = 127
+)
+
+// exitCoder is satisfied by *exec.ExitError.
+type exitCoder interface {
+	ExitCode() int
+}
+
+// runSupervised runs the container and relaunches it once if it exits
+// prematurely: non-zero, not interrupted, and without the loop having
+// recorded how the run ended in state.json. Each crash is recorded with
+// StatusContainerCrash so it shows up in `ralph status --history`, keeping
+// the checkpoints the loop saved. The retry resumes with the iterations
+// left, counting the one that crashed as not done.
+//
+//nolint:errcheck // display-only writes to terminal
+func runSupervised(runner CommandRunner, opts *RunOptions, w io.Writer, theme *ui.Theme) error {
+	statePath := filepath.Join(opts.ProjectDir, state.DefaultPath)
+	logsDir := filepath.Join(opts.ProjectDir, loopLogsDir)
+	attempt := *opts
+
+	for retries := 0; ; retries++ {
+		runsBefore := endedRuns(statePath)
+		logsBefore := logFiles(logsDir)
+		started := time.Now()
+
+		err := runWithRunner(runner, &attempt)
+		ended := endedRuns(statePath) > runsBefore
+		slog.Debug("supervise: container exited", "err", err, "crashed", err != nil && crashed(err), "run_ended", ended, "retries", retries)
+		if err == nil || !crashed(err) || ended {
+			return err
+		}
+
+		completed := recordCrash(statePath, &attempt, started, newLogs(logsDir, logsBefore))
+
+		if retries >= MaxCrashRetries {
+			return fmt.Errorf("container crashed again after retrying: %w", err)
+		}
+		fmt.Fprintf(w, "%s %s\n",
+			theme.Error.Render("Container exited unexpectedly:"),
+			theme.Muted.Render(fmt.Sprintf("%s after %d completed iteration(s); retrying once", err, completed)))
+
+		if attempt.MaxIter > 0 {
+			attempt.MaxIter -= completed
+		}
+	}
+}
+
+// crashed reports whether err is a container exit that warrants a retry.
+func crashed(err error) bool {
+	var ec exitCoder
+	if !errors.As(err, &ec) {
+		return false
+	}
+	switch ec.ExitCode() {
+	case 0, exitInterrupted, exitDockerFailed, exitNotExecutable, exitCommandMissing:
+		return false
+	}
+	return ec.ExitCode() > 0
+}
+
+// endedRuns returns how many runs state.json records as ended, or 0 if
+// unreadable. A run the loop checkpointed but didn't finish, or that
+// failed, hasn't ended.
+func endedRuns(path string) int {
+	st, err := state.Load(path)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for i := range st.Runs {
+		if !unfinished(&st.Runs[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+// unfinished reports whether the loop saved r without recording how the run
+// ended, or stopped on an error the container may have crashed on.
+func unfinished(r *state.RunRecord) bool {
+	return r.Status == state.StatusRunning || r.Status == state.StatusFailed
+}
+
+// logFiles returns the set of iteration log names in dir.
+func logFiles(dir string) map[string]bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".jsonl") {
+			names[e.Name()] = true
+		}
+	}
+	return names
+}
+
+// newLogs returns the container paths of logs in dir that weren't in
+// before, oldest first.
+func newLogs(dir string, before map[string]bool) []string {
+	var out []string
+	for name := range logFiles(dir) {
+		if !before[name] {
+			out = append(out, loopLogsDir+"/"+name)
+		}
+	}
+	sort.Strings(out) // names are timestamps
+	return out
+}
+
+// recordCrash records the attempt started at started as a
+// StatusContainerCrash run in state.json, returning how many iterations it
+// completed. The loop's own record of the attempt is kept, with its
+// checkpoints, if it saved one; otherwise a run is appended. Best-effort.
+func recordCrash(path string, opts *RunOptions, started time.Time, logs []string) int {
+	completed := max(len(logs)-1, 0) // the last log is the iteration that crashed
+	st, err := state.Load(path)
+	if err != nil {
+		return completed
+	}
+	for i := len(st.Runs) - 1; i >= 0; i-- {
+		r := &st.Runs[i]
+		if unfinished(r) && r.Mode == opts.Mode && !r.StartedAt.Before(started) {
+			r.Status = state.StatusContainerCrash
+			r.FinishedAt = time.Now()
+			r.LogFiles = logs
+			_ = state.Save(path, st) //nolint:errcheck // best-effort
+			return r.Iterations
+		}
+	}
+	st.Runs = append(st.Runs, state.RunRecord{
+		Mode:         opts.Mode,
+		StartedAt:    started,
+		FinishedAt:   time.Now(),
+		Iterations:   len(logs),
+		Status:       state.StatusContainerCrash,
+		LogFiles:     logs,
+		Tags:         opts.Tags,
+		Note:         opts.Note,
+		Profile:      opts.Profile,
+		Experiment:   opts.Experiment,
+		PlanApproval: opts.PlanApproval,
+		Branch:       opts.Branch,
+		PlanFile:     opts.PlanFile,
+		SpecsDir:     opts.SpecsDir,
+		ResumeOf:     opts.ResumeOf,
+		Origin:       opts.Origin,
+	})
+	_ = state.Save(path, st) //nolint:errcheck // best-effort
+	return completed
+}
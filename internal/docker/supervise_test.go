@@ -0,0 +1,167 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+type exitErr int
+
+func (e exitErr) Error() string { return "exit status " + strconv.Itoa(int(e)) }
+func (e exitErr) ExitCode() int { return int(e) }
+
+// crashingRunner writes iteration logs and optionally a state.json run on
+// each call, then exits with that call's code, the way a container killed
+// mid-iteration leaves the project.
+type crashingRunner struct {
+	fakeRunner
+	projectDir string
+	logs       []int  // logs to write on each call
+	codes      []int  // exit code per call; 0 = success
+	record     []bool // whether the loop recorded the run on each call
+	checkpoint []int  // iterations the loop checkpointed on each call, if set
+}
+
+func (c *crashingRunner) Run(name string, args ...string) error {
+	_ = c.fakeRunner.Run(name, args...) //nolint:errcheck // records the call only
+	i := len(c.calls) - 1
+	dir := filepath.Join(c.projectDir, loopLogsDir)
+	for n := range c.logs[i] {
+		name := fmt.Sprintf("20260101-%06d.jsonl", 10*i+n)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0o600); err != nil {
+			return err
+		}
+	}
+	if i < len(c.checkpoint) && c.checkpoint[i] > 0 {
+		path := filepath.Join(c.projectDir, state.DefaultPath)
+		st, _ := state.Load(path) //nolint:errcheck // test helper
+		r := state.RunRecord{Mode: "build", StartedAt: time.Now(), Status: state.StatusRunning, Iterations: c.checkpoint[i]}
+		for n := range c.checkpoint[i] {
+			r.Checkpoints = append(r.Checkpoints, state.Checkpoint{Iteration: n + 1})
+		}
+		st.Runs = append(st.Runs, r)
+		if err := state.Save(path, st); err != nil {
+			return err
+		}
+	}
+	if c.record[i] {
+		path := filepath.Join(c.projectDir, state.DefaultPath)
+		st, _ := state.Load(path) //nolint:errcheck // test helper
+		st.Runs = append(st.Runs, state.RunRecord{Status: state.StatusCompleted})
+		if err := state.Save(path, st); err != nil {
+			return err
+		}
+	}
+	if c.codes[i] != 0 {
+		return exitErr(c.codes[i])
+	}
+	return nil
+}
+
+func supervisedProject(t *testing.T) *RunOptions {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".ralph"), 0o750))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, loopLogsDir), 0o750))
+	opts := baseRunOpts()
+	opts.ProjectDir = dir
+	return opts
+}
+
+func maxIterArg(call []string) string {
+	return call[len(call)-1]
+}
+
+func TestRunSupervised_RetriesCrashOnce(t *testing.T) {
+	opts := supervisedProject(t)
+	r := &crashingRunner{projectDir: opts.ProjectDir,
+		logs: []int{3, 3}, codes: []int{137, 0}, record: []bool{false, true}}
+
+	var buf bytes.Buffer
+	require.NoError(t, runSupervised(r, opts, &buf, ui.DefaultTheme()))
+
+	require.Len(t, r.calls, 2)
+	assert.Equal(t, "5", maxIterArg(r.calls[0]))
+	assert.Equal(t, "3", maxIterArg(r.calls[1]), "two iterations completed before the crash")
+	assert.Contains(t, buf.String(), "Container exited unexpectedly")
+	assert.Contains(t, buf.String(), "2 completed iteration(s)")
+	assert.Contains(t, r.calls[0], "--restart")
+
+	st, err := state.Load(filepath.Join(opts.ProjectDir, state.DefaultPath))
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 2)
+	assert.Equal(t, state.StatusContainerCrash, st.Runs[0].Status)
+	assert.Equal(t, 3, st.Runs[0].Iterations)
+	assert.Len(t, st.Runs[0].LogFiles, 3)
+	assert.Equal(t, state.StatusCompleted, st.Runs[1].Status)
+}
+
+func TestRunSupervised_KeepsCheckpointedRun(t *testing.T) {
+	opts := supervisedProject(t)
+	r := &crashingRunner{projectDir: opts.ProjectDir,
+		logs: []int{3, 1}, codes: []int{137, 0}, record: []bool{false, true}, checkpoint: []int{2, 0}}
+
+	var buf bytes.Buffer
+	require.NoError(t, runSupervised(r, opts, &buf, ui.DefaultTheme()))
+	assert.Equal(t, "3", maxIterArg(r.calls[1]))
+
+	st, err := state.Load(filepath.Join(opts.ProjectDir, state.DefaultPath))
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 2, "the checkpointed run is marked, not recorded twice")
+	assert.Equal(t, state.StatusContainerCrash, st.Runs[0].Status)
+	assert.Equal(t, 2, st.Runs[0].Iterations)
+	assert.Len(t, st.Runs[0].Checkpoints, 2)
+	assert.Len(t, st.Runs[0].LogFiles, 3)
+}
+
+func TestRunSupervised_GivesUpAfterSecondCrash(t *testing.T) {
+	opts := supervisedProject(t)
+	r := &crashingRunner{projectDir: opts.ProjectDir,
+		logs: []int{1, 1}, codes: []int{1, 1}, record: []bool{false, false}}
+
+	var buf bytes.Buffer
+	err := runSupervised(r, opts, &buf, ui.DefaultTheme())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "crashed again")
+	assert.Len(t, r.calls, 2)
+
+	st, loadErr := state.Load(filepath.Join(opts.ProjectDir, state.DefaultPath))
+	require.NoError(t, loadErr)
+	require.Len(t, st.Runs, 2)
+	assert.Equal(t, state.StatusContainerCrash, st.Runs[1].Status)
+}
+
+func TestRunSupervised_NoRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   int
+		record bool
+	}{
+		{"interrupted", 130, false},
+		{"docker failed to start", 125, false},
+		{"loop recorded the run", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := supervisedProject(t)
+			r := &crashingRunner{projectDir: opts.ProjectDir,
+				logs: []int{1}, codes: []int{tt.code}, record: []bool{tt.record}}
+
+			var buf bytes.Buffer
+			require.Error(t, runSupervised(r, opts, &buf, ui.DefaultTheme()))
+			assert.Len(t, r.calls, 1)
+			assert.NotContains(t, buf.String(), "retrying")
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/agent"
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/docker"
+	"github.com/benwilkes9/ralph-cli/internal/git"
+)
+
+// Checks returns ralph's checks, in the order they build on each other.
+func Checks() []Check {
+	return []Check{
+		{Name: "git", Run: checkGit},
+		{Name: "repository", Run: checkRepo},
+		{Name: "config", Run: checkConfig},
+		{Name: "prompts", Run: checkPrompts},
+		{Name: "credentials", Run: checkCredentials},
+		{Name: "docker", Run: checkDocker},
+		{Name: "image", Run: checkImage},
+		{Name: "agent", Run: checkAgent},
+	}
+}
+
+func skip(needs string) Result {
+	return Result{Status: Skip, Detail: "skipped: needs " + needs}
+}
+
+// firstLine keeps command output to one line of detail.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func checkGit(ctx context.Context, e *Env) Result {
+	if _, err := e.LookPath("git"); err != nil {
+		return Result{Status: Fail, Detail: "git not found on PATH", Hint: "install git"}
+	}
+	out, err := e.Exec(ctx, e.Dir, "git", "--version")
+	if err != nil {
+		return Result{Status: Fail, Detail: "git --version failed: " + firstLine(out), Hint: "reinstall git"}
+	}
+	e.HasGit = true
+	return Result{Status: Pass, Detail: out}
+}
+
+func checkRepo(ctx context.Context, e *Env) Result {
+	if !e.HasGit {
+		return skip("git")
+	}
+	root, err := e.Exec(ctx, e.Dir, "git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return Result{Status: Fail, Detail: "not in a git repository", Hint: "run ralph in your project's repo (git init starts one)"}
+	}
+	e.RepoRoot = root
+	out, err := e.Exec(ctx, root, "git", "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return Result{Status: Fail, Detail: "git status failed: " + firstLine(out), Hint: "fix the repository so git status works"}
+	}
+	branch, detached, unmerged := git.ParseStatusV2(out)
+	switch {
+	case detached:
+		return Result{Status: Warn, Detail: root + ": HEAD is detached", Hint: "git switch to the branch ralph should work on"}
+	case len(unmerged) > 0:
+		return Result{Status: Warn, Detail: fmt.Sprintf("%s: %d unresolved conflict(s)", root, len(unmerged)), Hint: "resolve them and commit"}
+	}
+	if _, err := e.Exec(ctx, root, "git", "remote", "get-url", "origin"); err != nil {
+		return Result{Status: Warn, Detail: root + " on " + branch + ", with no origin remote",
+			Hint: "runs push to origin: git remote add origin <url>, or run with --offline"}
+	}
+	return Result{Status: Pass, Detail: root + " on " + branch}
+}
+
+func checkConfig(_ context.Context, e *Env) Result {
+	if e.RepoRoot == "" {
+		return skip("a repository")
+	}
+	cfg, err := config.Load(e.RepoRoot)
+	if errors.Is(err, os.ErrNotExist) {
+		return Result{Status: Fail, Detail: ".ralph/config.yaml not found", Hint: `run "ralph init"`}
+	}
+	if err != nil {
+		return Result{Status: Fail, Detail: err.Error(), Hint: "fix .ralph/config.yaml"}
+	}
+	e.Config = cfg
+	return Result{Status: Pass, Detail: fmt.Sprintf("project %s, agent %s", cfg.Project, cfg.Agent)}
+}
+
+func checkPrompts(_ context.Context, e *Env) Result {
+	if e.Config == nil {
+		return skip("config")
+	}
+	var missing []string
+	for _, p := range []struct{ mode, path string }{
+		{"plan", e.Config.Phases.Plan.Prompt},
+		{"build", e.Config.Phases.Build.Prompt},
+	} {
+		if _, err := os.Stat(filepath.Join(e.RepoRoot, p.path)); err != nil {
+			missing = append(missing, fmt.Sprintf("%s (phases.%s.prompt)", p.path, p.mode))
+		}
+	}
+	if len(missing) > 0 {
+		return Result{Status: Fail, Detail: "missing " + strings.Join(missing, ", "),
+			Hint: "restore the file, or point the phase's prompt at one that exists"}
+	}
+	return Result{Status: Pass, Detail: e.Config.Phases.Plan.Prompt + ", " + e.Config.Phases.Build.Prompt}
+}
+
+func checkCredentials(_ context.Context, e *Env) Result {
+	if e.RepoRoot == "" {
+		return skip("a repository")
+	}
+	env, err := docker.LoadEnvFile(filepath.Join(e.RepoRoot, ".env"))
+	if err != nil {
+		return Result{Status: Fail, Detail: err.Error(), Hint: "fix .env"}
+	}
+	name := agent.NameClaude
+	if e.Config != nil {
+		name = e.Config.Agent
+	}
+	if err := docker.CheckCredentials(env, name); err != nil {
+		return Result{Status: Fail, Detail: err.Error(), Hint: "set it in .env (see .env.example) or export it"}
+	}
+	return Result{Status: Pass, Detail: "found for " + name + " and GitHub"}
+}
+
+func checkDocker(ctx context.Context, e *Env) Result {
+	if _, err := e.LookPath("docker"); err != nil {
+		return Result{Status: Fail, Detail: "docker not found on PATH", Hint: "install Docker, or run with --no-docker"}
+	}
+	out, err := e.Exec(ctx, e.Dir, "docker", "info", "--format", "{{.ServerVersion}}")
+	if err != nil {
+		return Result{Status: Fail, Detail: "daemon not reachable: " + firstLine(out),
+			Hint: "start Docker (open Docker Desktop, or systemctl start docker)"}
+	}
+	e.HasDocker = true
+	return Result{Status: Pass, Detail: "server " + out}
+}
+
+func checkImage(ctx context.Context, e *Env) Result {
+	if !e.HasDocker {
+		return skip("docker")
+	}
+	out, err := e.Exec(ctx, e.Dir, "docker", "image", "inspect", "--format", "{{.Created}}", docker.DefaultTag)
+	if err != nil {
+		return Result{Status: Warn, Detail: docker.DefaultTag + " not built yet", Hint: "the first plan or build run builds it"}
+	}
+	e.HasImage = true
+	created, err := time.Parse(time.RFC3339Nano, out)
+	if err != nil {
+		return Result{Status: Pass, Detail: docker.DefaultTag + " built"}
+	}
+	detail := fmt.Sprintf("%s built %s", docker.DefaultTag, created.Local().Format("2006-01-02 15:04"))
+	if e.RepoRoot != "" {
+		if fi, err := os.Stat(filepath.Join(e.RepoRoot, docker.DefaultDockerfile)); err == nil && fi.ModTime().After(created) {
+			return Result{Status: Warn, Detail: detail + ", before " + docker.DefaultDockerfile + " last changed",
+				Hint: "the next online run rebuilds it; --offline runs use it as it is"}
+		}
+	}
+	return Result{Status: Pass, Detail: detail}
+}
+
+func checkAgent(ctx context.Context, e *Env) Result {
+	if !e.HasImage {
+		return skip("the image")
+	}
+	// Each agent's CLI is named after it.
+	name := agent.NameClaude
+	if e.Config != nil {
+		name = e.Config.Agent
+	}
+	out, err := e.Exec(ctx, e.Dir, "docker", "run", "--rm", "--entrypoint", name, docker.DefaultTag, "--version")
+	if err != nil {
+		return Result{Status: Fail, Detail: name + " CLI not runnable in the image: " + firstLine(out),
+			Hint: "rebuild the image: docker build -t " + docker.DefaultTag + " -f " + docker.DefaultDockerfile + " ."}
+	}
+	return Result{Status: Pass, Detail: name + " " + firstLine(out)}
+}
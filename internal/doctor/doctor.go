@@ -0,0 +1,129 @@
+// Package doctor diagnoses whether this machine and repo can run ralph.
+// Each Check looks at one thing and, when it finds a problem, says how to
+// fix it. Checks run in order and later ones build on what earlier ones
+// found, recorded on the Env: the image checks need a reachable daemon.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// Status is a check's outcome.
+type Status int
+
+const (
+	Pass Status = iota
+	Warn        // ralph can run, but something will likely get in the way
+	Fail        // ralph can't run until this is fixed
+	Skip        // a check this one needs didn't pass
+)
+
+// Result is what a check found.
+type Result struct {
+	Status Status
+	Detail string // what was found, e.g. a version
+	Hint   string // how to fix a warning or failure
+}
+
+// Check is one diagnostic.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context, e *Env) Result
+}
+
+// Env is what checks look at, and what they have found so far.
+type Env struct {
+	Dir string // where ralph doctor was run
+
+	// Exec runs a command in Dir and returns its trimmed combined output.
+	Exec func(ctx context.Context, dir, name string, args ...string) (string, error)
+	// LookPath finds a program on PATH.
+	LookPath func(file string) (string, error)
+
+	HasGit    bool           // git is installed
+	RepoRoot  string         // the repo's top level; "" outside one
+	Config    *config.Config // the repo's config, when it loads
+	HasDocker bool           // the docker daemon answered
+	HasImage  bool           // the ralph image is built
+}
+
+// NewEnv returns an Env for dir that runs real commands.
+func NewEnv(dir string) *Env {
+	return &Env{Dir: dir, Exec: execCommand, LookPath: exec.LookPath}
+}
+
+func execCommand(ctx context.Context, dir, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err //nolint:wrapcheck // checks describe the failure
+}
+
+// Report is a check's result under its name.
+type Report struct {
+	Name string
+	Result
+}
+
+// Run runs checks in order.
+func Run(ctx context.Context, e *Env, checks []Check) []Report {
+	reports := make([]Report, 0, len(checks))
+	for _, c := range checks {
+		reports = append(reports, Report{Name: c.Name, Result: c.Run(ctx, e)})
+	}
+	return reports
+}
+
+// Failed counts the reports that failed.
+func Failed(reports []Report) int {
+	n := 0
+	for i := range reports {
+		if reports[i].Status == Fail {
+			n++
+		}
+	}
+	return n
+}
+
+// Render writes one line per report, with the hint under any warning or
+// failure, then a summary line.
+//
+//nolint:errcheck // display output, best-effort writes
+func Render(w io.Writer, reports []Report, theme *ui.Theme) {
+	var warned int
+	for i := range reports {
+		r := &reports[i]
+		var mark string
+		switch r.Status {
+		case Pass:
+			mark = theme.Success.Render("✓")
+		case Warn:
+			mark = theme.Warning.Render("!")
+			warned++
+		case Fail:
+			mark = theme.Error.Render("✗")
+		case Skip:
+			mark = theme.Muted.Render("-")
+		}
+		fmt.Fprintf(w, "%s %-12s %s\n", mark, r.Name, r.Detail)
+		if r.Hint != "" && (r.Status == Warn || r.Status == Fail) {
+			fmt.Fprintf(w, "  %-12s %s\n", "", theme.Muted.Render("→ "+r.Hint))
+		}
+	}
+	failed := Failed(reports)
+	switch {
+	case failed > 0:
+		fmt.Fprintln(w, theme.Error.Render(fmt.Sprintf("%d of %d checks failed", failed, len(reports))))
+	case warned > 0:
+		fmt.Fprintln(w, theme.Warning.Render(fmt.Sprintf("Ready to run, with %d warning(s)", warned)))
+	default:
+		fmt.Fprintln(w, theme.Success.Render("Ready to run"))
+	}
+}
@@ -0,0 +1,128 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// fakeEnv answers commands from outputs, keyed by "name args..."; missing
+// commands fail.
+func fakeEnv(dir string, outputs map[string]string) *Env {
+	return &Env{
+		Dir: dir,
+		Exec: func(_ context.Context, _, name string, args ...string) (string, error) {
+			out, ok := outputs[strings.Join(append([]string{name}, args...), " ")]
+			if !ok {
+				return "command failed", errors.New("exit status 1")
+			}
+			return out, nil
+		},
+		LookPath: func(file string) (string, error) { return "/usr/bin/" + file, nil },
+	}
+}
+
+// readyRepo writes a config, prompts and .env that pass every check.
+func readyRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".ralph/config.yaml":      "project: shop\n",
+		".ralph/prompts/plan.md":  "plan",
+		".ralph/prompts/build.md": "build",
+		".env":                    "ANTHROPIC_API_KEY=sk-ant-test\nGITHUB_PAT=ghp_test\n",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestRun_AllPass(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("GITHUB_PAT", "")
+	dir := readyRepo(t)
+	e := fakeEnv(dir, map[string]string{
+		"git --version":                                            "git version 2.47.0",
+		"git rev-parse --show-toplevel":                            dir,
+		"git status --porcelain=v2 --branch":                       "# branch.oid abc\n# branch.head feat/cart\n",
+		"git remote get-url origin":                                "git@github.com:me/shop.git",
+		"docker info --format {{.ServerVersion}}":                  "27.3.1",
+		"docker image inspect --format {{.Created}} ralph-loop":    "2099-01-01T00:00:00.000000000Z",
+		"docker run --rm --entrypoint claude ralph-loop --version": "2.1.0 (Claude Code)",
+	})
+
+	reports := Run(context.Background(), e, Checks())
+	for _, r := range reports {
+		assert.Equal(t, Pass, r.Status, "%s: %s", r.Name, r.Detail)
+	}
+	assert.Zero(t, Failed(reports))
+	assert.Equal(t, "shop", e.Config.Project)
+
+	var out bytes.Buffer
+	Render(&out, reports, ui.DefaultTheme())
+	assert.Contains(t, out.String(), dir+" on feat/cart")
+	assert.Contains(t, out.String(), "claude 2.1.0 (Claude Code)")
+	assert.Contains(t, out.String(), "Ready to run")
+}
+
+func TestRun_Problems(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("CLAUDE_CODE_OAUTH_TOKEN", "")
+	t.Setenv("GITHUB_PAT", "")
+	dir := readyRepo(t)
+	require.NoError(t, os.Remove(filepath.Join(dir, ".ralph/prompts/build.md")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("GITHUB_PAT=ghp_test\n"), 0o600))
+	e := fakeEnv(dir, map[string]string{
+		"git --version":                      "git version 2.47.0",
+		"git rev-parse --show-toplevel":      dir,
+		"git status --porcelain=v2 --branch": "# branch.oid abc\n# branch.head (detached)\n",
+	})
+
+	byName := map[string]Report{}
+	for _, r := range Run(context.Background(), e, Checks()) {
+		byName[r.Name] = r
+	}
+	assert.Equal(t, Warn, byName["repository"].Status)
+	assert.Contains(t, byName["repository"].Detail, "detached")
+	assert.Equal(t, Fail, byName["prompts"].Status)
+	assert.Contains(t, byName["prompts"].Detail, "phases.build.prompt")
+	assert.Equal(t, Fail, byName["credentials"].Status)
+	assert.Contains(t, byName["credentials"].Detail, "ANTHROPIC_API_KEY")
+	assert.Equal(t, Fail, byName["docker"].Status)
+	assert.Contains(t, byName["docker"].Hint, "start Docker")
+	assert.Equal(t, Skip, byName["image"].Status)
+	assert.Equal(t, Skip, byName["agent"].Status)
+
+	var out bytes.Buffer
+	Render(&out, Run(context.Background(), fakeEnv(dir, nil), Checks()), ui.DefaultTheme())
+	assert.Contains(t, out.String(), "→ reinstall git")
+	assert.Contains(t, out.String(), "skipped: needs git")
+	assert.Contains(t, out.String(), "checks failed")
+}
+
+func TestCheckImage_Stale(t *testing.T) {
+	dir := t.TempDir()
+	dockerfile := filepath.Join(dir, ".ralph", "docker", "Dockerfile")
+	require.NoError(t, os.MkdirAll(filepath.Dir(dockerfile), 0o750))
+	require.NoError(t, os.WriteFile(dockerfile, []byte("FROM node\n"), 0o600))
+	e := fakeEnv(dir, map[string]string{
+		"docker image inspect --format {{.Created}} ralph-loop": "2020-01-01T00:00:00Z",
+	})
+	e.HasDocker, e.RepoRoot = true, dir
+
+	r := checkImage(context.Background(), e)
+	assert.Equal(t, Warn, r.Status)
+	assert.Contains(t, r.Detail, "before .ralph/docker/Dockerfile last changed")
+	assert.True(t, e.HasImage, "a stale image still runs")
+}
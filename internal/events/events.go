@@ -0,0 +1,282 @@
+// Package events is a per-user stream of run events shared by every ralph
+// process on the machine. Each plan or build run appends JSON lines to its
+// own file in one directory, so runs never contend for a lock, and ralph
+// overview folds the files into the live status of every run.
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/status"
+)
+
+// DirEnv overrides where events are kept.
+const DirEnv = "RALPH_EVENTS_DIR"
+
+// Retention is how long the file of a run that has ended is kept.
+const Retention = 24 * time.Hour
+
+// Type is what happened to a run.
+type Type string
+
+// Event types.
+const (
+	TypeStarted   Type = "started"
+	TypeIteration Type = "iteration" // a new iteration began, or the run's cost changed
+	TypeFinished  Type = "finished"
+)
+
+// Event is one line of a run's event file.
+type Event struct {
+	Time          time.Time `json:"time"`
+	Type          Type      `json:"type"`
+	Run           string    `json:"run"`     // unique per run: its file name without .jsonl
+	PID           int       `json:"pid"`     // the host ralph process
+	Project       string    `json:"project"` // repo root on the host
+	Branch        string    `json:"branch"`
+	Mode          string    `json:"mode"`
+	MaxIterations int       `json:"max_iterations,omitempty"` // 0 = unlimited
+	Iteration     int       `json:"iteration,omitempty"`      // iterations started so far
+	Cost          float64   `json:"cost,omitempty"`           // US dollars spent so far
+	Error         string    `json:"error,omitempty"`          // finished: why the run failed; empty on success
+}
+
+// Dir returns the events directory: $RALPH_EVENTS_DIR, or ~/.ralph/events.
+func Dir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding home directory: %w", err)
+	}
+	return filepath.Join(home, ".ralph", "events"), nil
+}
+
+// Publisher appends one run's events to its file. A nil Publisher ignores
+// every call, so publishing stays best-effort.
+type Publisher struct {
+	mu    sync.Mutex
+	f     *os.File
+	base  Event
+	clk   clock.Clock
+	stop  chan struct{}
+	done  chan struct{}
+	iters int
+	cost  float64
+}
+
+// Start creates the run's event file in dir and publishes TypeStarted.
+// base carries the run's fixed fields; Run and PID are filled in. Files of
+// runs that ended more than Retention ago are removed.
+func Start(dir string, base Event, clk clock.Clock) (*Publisher, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating events dir: %w", err)
+	}
+	c := clock.Or(clk)
+	now := c.Now()
+	Prune(dir, now.Add(-Retention))
+	base.PID = os.Getpid()
+	base.Run = fmt.Sprintf("%d-%d", now.UnixNano(), base.PID)
+	f, err := os.OpenFile(filepath.Join(dir, base.Run+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec // run id built from a timestamp and pid
+	if err != nil {
+		return nil, fmt.Errorf("creating event file: %w", err)
+	}
+	p := &Publisher{f: f, base: base, clk: c}
+	if err := p.publish(TypeStarted, ""); err != nil {
+		f.Close() //nolint:errcheck,gosec // already failing
+		return nil, err
+	}
+	return p, nil
+}
+
+// publish writes an event of type t with the run's current progress.
+func (p *Publisher) publish(t Type, errMsg string) error {
+	e := p.base
+	e.Time, e.Type, e.Iteration, e.Cost, e.Error = p.clk.Now(), t, p.iters, p.cost, errMsg
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	if _, err := p.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing event: %w", err)
+	}
+	return nil
+}
+
+// Watch publishes TypeIteration whenever the loop starts an iteration or
+// its cost changes, polling logsDir, where the run's iteration logs appear,
+// every poll until Finish. Logs already there belong to earlier runs. Logs
+// without a reported cost are priced from rates.
+func (p *Publisher) Watch(logsDir string, rates pricing.Table, poll time.Duration) {
+	if p == nil {
+		return
+	}
+	p.stop, p.done = make(chan struct{}), make(chan struct{})
+	earlier := map[string]bool{}
+	if runs, err := status.ParseLogs(logsDir, rates); err == nil {
+		for _, r := range runs {
+			earlier[r.Name] = true
+		}
+	}
+	go func() {
+		defer close(p.done)
+		t := time.NewTicker(poll)
+		defer t.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-t.C:
+				p.progress(logsDir, rates, earlier)
+			}
+		}
+	}()
+}
+
+// progress publishes the run's iterations and cost if they changed.
+func (p *Publisher) progress(logsDir string, rates pricing.Table, earlier map[string]bool) {
+	runs, err := status.ParseLogs(logsDir, rates)
+	if err != nil {
+		return
+	}
+	var iters int
+	var cost float64
+	for _, r := range runs {
+		if !earlier[r.Name] {
+			iters++
+			cost += r.Cost
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if iters == p.iters && cost == p.cost {
+		return
+	}
+	p.iters, p.cost = iters, cost
+	_ = p.publish(TypeIteration, "") //nolint:errcheck // best-effort
+}
+
+// Finish stops Watch and publishes TypeFinished, recording runErr when the
+// run failed, then closes the file.
+func (p *Publisher) Finish(runErr error) {
+	if p == nil {
+		return
+	}
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var msg string
+	if runErr != nil {
+		msg = runErr.Error()
+	}
+	_ = p.publish(TypeFinished, msg) //nolint:errcheck // best-effort
+	p.f.Close()                      //nolint:errcheck,gosec // best-effort
+}
+
+// Run is a run's state folded from its events.
+type Run struct {
+	Event              // the latest event
+	Started  time.Time // when TypeStarted was published
+	Finished bool      // TypeFinished was published
+	Alive    bool      // the publishing process is still running
+}
+
+// Active reports whether the run is still going: not finished, and its
+// process hasn't died without saying so.
+func (r *Run) Active() bool {
+	return !r.Finished && r.Alive
+}
+
+// Load folds every event file in dir into its run, oldest run first. A
+// missing directory has no runs.
+func Load(dir string) ([]Run, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading events dir: %w", err)
+	}
+	runs := make([]Run, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		if r, ok := loadRun(filepath.Join(dir, e.Name())); ok {
+			runs = append(runs, r)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Started.Before(runs[j].Started) })
+	return runs, nil
+}
+
+// loadRun folds one event file. Unparseable lines, such as one being
+// written, are skipped.
+func loadRun(path string) (Run, bool) {
+	f, err := os.Open(path) //nolint:gosec // files listed from the events dir
+	if err != nil {
+		return Run{}, false
+	}
+	defer f.Close() //nolint:errcheck // read-only
+	var r Run
+	seen := false
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e Event
+		if json.Unmarshal(sc.Bytes(), &e) != nil {
+			continue
+		}
+		if !seen {
+			r.Started = e.Time
+			seen = true
+		}
+		r.Event = e
+		r.Finished = r.Finished || e.Type == TypeFinished
+	}
+	if !seen {
+		return Run{}, false
+	}
+	r.Alive = processAlive(r.PID)
+	return r, true
+}
+
+// processAlive reports whether a process with pid exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// Prune removes the files of runs that ended before cutoff. Best-effort.
+func Prune(dir string, cutoff time.Time) {
+	runs, err := Load(dir)
+	if err != nil {
+		return
+	}
+	for i := range runs {
+		if !runs[i].Active() && runs[i].Time.Before(cutoff) {
+			_ = os.Remove(filepath.Join(dir, runs[i].Run+".jsonl")) //nolint:errcheck // best-effort
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func baseEvent() Event {
+	return Event{Project: "/home/me/shop", Branch: "feat/cart", Mode: "build", MaxIterations: 5}
+}
+
+func writeEvents(t *testing.T, dir, run string, evs ...Event) {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, e := range evs {
+		line, err := json.Marshal(e)
+		require.NoError(t, err)
+		buf.Write(append(line, '\n'))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, run+".jsonl"), buf.Bytes(), 0o600))
+}
+
+func TestPublisher_StartAndFinish(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "events")
+	p, err := Start(dir, baseEvent(), &clock.Fake{T: t0, Step: time.Minute})
+	require.NoError(t, err)
+
+	runs, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.True(t, runs[0].Active())
+	assert.Equal(t, TypeStarted, runs[0].Type)
+	assert.Equal(t, os.Getpid(), runs[0].PID)
+	assert.Equal(t, "feat/cart", runs[0].Branch)
+
+	p.Finish(errors.New("claude exited 1"))
+	runs, err = Load(dir)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.False(t, runs[0].Active())
+	assert.True(t, runs[0].Finished)
+	assert.Equal(t, "claude exited 1", runs[0].Error)
+	assert.Equal(t, t0.Add(time.Minute), runs[0].Started)
+	assert.Equal(t, t0.Add(2*time.Minute), runs[0].Time)
+}
+
+func TestPublisher_NilIsNoop(t *testing.T) {
+	var p *Publisher
+	p.Watch(t.TempDir(), nil, time.Millisecond)
+	p.Finish(nil)
+}
+
+func TestPublisher_WatchPublishesNewIterations(t *testing.T) {
+	dir, logsDir := t.TempDir(), t.TempDir()
+	result := `{"type":"result","total_cost_usd":0.5}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(logsDir, "20260301-110000.jsonl"), []byte(result), 0o600))
+
+	p, err := Start(dir, baseEvent(), nil)
+	require.NoError(t, err)
+	p.Watch(logsDir, nil, 5*time.Millisecond)
+	require.NoError(t, os.WriteFile(filepath.Join(logsDir, "20260301-120000.jsonl"), []byte(result), 0o600))
+
+	assert.Eventually(t, func() bool {
+		runs, err := Load(dir)
+		return err == nil && len(runs) == 1 && runs[0].Type == TypeIteration
+	}, time.Second, 5*time.Millisecond)
+	p.Finish(nil)
+
+	runs, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, 1, runs[0].Iteration, "the earlier log isn't counted")
+	assert.InDelta(t, 0.5, runs[0].Cost, 1e-9)
+	assert.Empty(t, runs[0].Error)
+}
+
+func TestLoad_DeadProcessIsInactive(t *testing.T) {
+	dir := t.TempDir()
+	e := baseEvent()
+	e.Time, e.Type, e.Run, e.PID = t0, TypeStarted, "gone", 1<<30
+	writeEvents(t, dir, "gone", e)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "torn.jsonl"), []byte(`{"type":"sta`), 0o600))
+
+	runs, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, runs, 1, "a file with no complete event is skipped")
+	assert.False(t, runs[0].Alive)
+	assert.False(t, runs[0].Active())
+}
+
+func TestLoad_MissingDir(t *testing.T) {
+	runs, err := Load(filepath.Join(t.TempDir(), "none"))
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	old := baseEvent()
+	old.Time, old.Type, old.Run = t0, TypeFinished, "old"
+	recent := old
+	recent.Time, recent.Run = t0.Add(2*time.Hour), "recent"
+	live := baseEvent()
+	live.Time, live.Type, live.Run, live.PID = t0, TypeStarted, "live", os.Getpid()
+	writeEvents(t, dir, "old", old)
+	writeEvents(t, dir, "recent", recent)
+	writeEvents(t, dir, "live", live)
+
+	Prune(dir, t0.Add(time.Hour))
+
+	runs, err := Load(dir)
+	require.NoError(t, err)
+	var names []string
+	for i := range runs {
+		names = append(names, runs[i].Run)
+	}
+	assert.ElementsMatch(t, []string{"recent", "live"}, names)
+}
+
+func TestRender(t *testing.T) {
+	running := Run{Event: baseEvent(), Started: t0, Alive: true}
+	running.Iteration, running.Cost = 2, 1.25
+	failed := Run{Event: baseEvent(), Started: t0, Finished: true}
+	failed.Project, failed.Time, failed.Error = "/home/me/api", t0.Add(90*time.Second), "push rejected"
+	exited := Run{Event: baseEvent(), Started: t0}
+	exited.Project, exited.Time = "/home/me/cli", t0
+
+	var buf bytes.Buffer
+	Render(&buf, []Run{running, failed, exited}, t0.Add(10*time.Minute), ui.PlainTheme())
+	out := buf.String()
+	assert.Contains(t, out, "shop")
+	assert.Contains(t, out, "2/5")
+	assert.Contains(t, out, "$1.2500")
+	assert.Contains(t, out, "10m0s  running")
+	assert.Contains(t, out, "1m30s  failed: push rejected")
+	assert.Contains(t, out, "exited without finishing")
+	assert.Contains(t, out, "Total: 1 running of 3")
+
+	buf.Reset()
+	Render(&buf, nil, t0, ui.PlainTheme())
+	assert.Contains(t, buf.String(), "No ralph runs to show")
+}
+
+func TestFollow_PrintsNewEvents(t *testing.T) {
+	dir := t.TempDir()
+	before := baseEvent()
+	before.Time, before.Type, before.PID = t0, TypeStarted, os.Getpid()
+	writeEvents(t, dir, "a", before)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf syncBuffer
+	done := make(chan error, 1)
+	offsets := endOffsets(dir)
+	go func() { done <- follow(ctx, dir, offsets, &buf, ui.PlainTheme(), 5*time.Millisecond) }()
+
+	p, err := Start(dir, baseEvent(), nil)
+	require.NoError(t, err)
+	p.Finish(nil)
+	assert.Eventually(t, func() bool { return bytes.Contains(buf.Bytes(), []byte("finished")) }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Contains(t, buf.String(), "started")
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("feat/cart")), "events already in the dir aren't printed")
+}
+
+// syncBuffer is a bytes.Buffer safe to read while Follow writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p) //nolint:wrapcheck // test buffer
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func (b *syncBuffer) String() string {
+	return string(b.Bytes())
+}
@@ -0,0 +1,163 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// Render writes one line per run: its project, branch, mode, progress,
+// cost, how long it has run and how it stands. Costs are in US dollars,
+// since each project may display another currency.
+//
+//nolint:errcheck // display output, best-effort writes
+func Render(w io.Writer, runs []Run, now time.Time, theme *ui.Theme) {
+	if len(runs) == 0 {
+		fmt.Fprintln(w, theme.Muted.Render("No ralph runs to show"))
+		return
+	}
+	var cost float64
+	active := 0
+	for i := range runs {
+		r := &runs[i]
+		cost += r.Cost
+		end := now
+		if !r.Active() {
+			end = r.Time
+		}
+		var label string
+		switch {
+		case r.Active():
+			label = theme.Info.Render("running")
+			active++
+		case r.Finished && r.Error != "":
+			label = theme.Error.Render("failed: " + r.Error)
+		case r.Finished:
+			label = theme.Success.Render("finished")
+		default:
+			label = theme.Warning.Render("exited without finishing")
+		}
+		fmt.Fprintf(w, "%-20s  %-24s  %-5s  %-7s  %s  %8s  %s\n",
+			filepath.Base(r.Project), r.Branch, r.Mode, progress(&r.Event),
+			theme.Cost.Render(pricing.Currency{}.Format(r.Cost, 4)),
+			end.Sub(r.Started).Round(time.Second), label)
+	}
+	fmt.Fprintf(w, "%s %d running of %d, %s\n", theme.Muted.Render("Total:"), active, len(runs),
+		theme.Cost.Render(pricing.Currency{}.Format(cost, 4)))
+}
+
+// progress formats an event's iteration count, with the run's limit when
+// it has one.
+func progress(e *Event) string {
+	if e.MaxIterations > 0 {
+		return fmt.Sprintf("%d/%d", e.Iteration, e.MaxIterations)
+	}
+	return fmt.Sprintf("%d", e.Iteration)
+}
+
+// Follow writes a line for each event published to dir after it is called,
+// from any run, checking every poll until ctx is done.
+func Follow(ctx context.Context, dir string, w io.Writer, theme *ui.Theme, poll time.Duration) error {
+	return follow(ctx, dir, endOffsets(dir), w, theme, poll)
+}
+
+// endOffsets maps each event file in dir to its current size.
+func endOffsets(dir string) map[string]int64 {
+	offsets := map[string]int64{}
+	for _, path := range eventFiles(dir) {
+		if fi, err := os.Stat(path); err == nil {
+			offsets[path] = fi.Size()
+		}
+	}
+	return offsets
+}
+
+// follow is Follow from offsets; files missing from it are read whole.
+func follow(ctx context.Context, dir string, offsets map[string]int64, w io.Writer, theme *ui.Theme, poll time.Duration) error {
+	t := time.NewTicker(poll)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		var batch []Event
+		for _, path := range eventFiles(dir) {
+			evs, next := readFrom(path, offsets[path])
+			offsets[path] = next
+			batch = append(batch, evs...)
+		}
+		sort.SliceStable(batch, func(i, j int) bool { return batch[i].Time.Before(batch[j].Time) })
+		for i := range batch {
+			renderEvent(w, &batch[i], theme)
+		}
+	}
+}
+
+// eventFiles lists the event files in dir.
+func eventFiles(dir string) []string {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil
+	}
+	return paths
+}
+
+// readFrom parses the complete lines of path after offset, returning them
+// and the offset to continue from. A partly written last line is left for
+// the next read.
+func readFrom(path string, offset int64) ([]Event, int64) {
+	f, err := os.Open(path) //nolint:gosec // files listed from the events dir
+	if err != nil {
+		return nil, offset
+	}
+	defer f.Close() //nolint:errcheck // read-only
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, offset
+	}
+	end := bytes.LastIndexByte(data, '\n') + 1
+	var evs []Event
+	for line := range strings.Lines(string(data[:end])) {
+		var e Event
+		if json.Unmarshal([]byte(line), &e) == nil {
+			evs = append(evs, e)
+		}
+	}
+	return evs, offset + int64(end)
+}
+
+// renderEvent writes one event as a line.
+//
+//nolint:errcheck // display output, best-effort writes
+func renderEvent(w io.Writer, e *Event, theme *ui.Theme) {
+	what := string(e.Type)
+	switch e.Type {
+	case TypeIteration:
+		what = "iteration " + progress(e)
+	case TypeFinished:
+		if e.Error != "" {
+			what = theme.Error.Render("failed: " + e.Error)
+		} else {
+			what = theme.Success.Render("finished")
+		}
+	case TypeStarted:
+		what = theme.Info.Render("started")
+	}
+	fmt.Fprintf(w, "%s  %-20s  %-24s  %-5s  %s  %s\n", theme.Muted.Render(e.Time.Local().Format("15:04:05")),
+		filepath.Base(e.Project), e.Branch, e.Mode, what, theme.Cost.Render(pricing.Currency{}.Format(e.Cost, 4)))
+}
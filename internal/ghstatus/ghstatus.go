@@ -0,0 +1,189 @@
+// Package ghstatus reports run progress on the branch head as a GitHub commit
+// status or check run, so teammates watching the PR can see that an
+// autonomous run is active and how it ended.
+package ghstatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Report kinds accepted by the github.report config key.
+const (
+	KindOff    = "off"    // don't report
+	KindStatus = "status" // commit status; works with a personal access token
+	KindCheck  = "check"  // check run with a markdown summary; needs CheckTokenEnv
+)
+
+// Tokens the report kinds are posted with. The Checks API only accepts a
+// GitHub App installation token, so checks can't use the run's GITHUB_PAT.
+const (
+	StatusTokenEnv = "GITHUB_PAT"
+	CheckTokenEnv  = "GITHUB_APP_TOKEN"
+)
+
+// TokenEnv names the environment variable holding the token kind reports
+// with.
+func TokenEnv(kind string) string {
+	if kind == KindCheck {
+		return CheckTokenEnv
+	}
+	return StatusTokenEnv
+}
+
+// ValidKind reports whether k is a recognised report kind. Empty means off.
+func ValidKind(k string) bool {
+	return k == "" || k == KindOff || k == KindStatus || k == KindCheck
+}
+
+// Reporter publishes run progress against a commit.
+type Reporter interface {
+	// Pending marks sha as having a run in progress.
+	Pending(ctx context.Context, sha, description string) error
+	// Done marks sha with the run's outcome. summary is markdown; commit
+	// statuses, which can't carry one, show only the title.
+	Done(ctx context.Context, sha string, success bool, title, summary string) error
+}
+
+// DefaultBaseURL is the GitHub REST API root.
+const DefaultBaseURL = "https://api.github.com"
+
+// maxDescription is GitHub's limit on a commit status description.
+const maxDescription = 140
+
+// Client reports through the GitHub REST API.
+type Client struct {
+	Kind    string // KindStatus or KindCheck
+	Repo    string // "owner/repo"
+	Token   string
+	Name    string       // status context / check run name, e.g. "ralph/build"
+	BaseURL string       // empty = DefaultBaseURL
+	HTTP    *http.Client // nil = a client with a short timeout
+
+	// Check runs belong to one commit, so the open run is recreated when
+	// the head moves.
+	checkSHA string
+	checkID  int64
+}
+
+// New returns the Reporter for kind, or nil when reporting is off or there
+// is no token to report with.
+func New(kind, repo, token, name string) Reporter {
+	if (kind != KindStatus && kind != KindCheck) || token == "" || repo == "" {
+		return nil
+	}
+	return &Client{Kind: kind, Repo: repo, Token: token, Name: name}
+}
+
+// Pending marks sha as running.
+func (c *Client) Pending(ctx context.Context, sha, description string) error {
+	if c.Kind == KindStatus {
+		return c.status(ctx, sha, "pending", description)
+	}
+	if c.checkSHA == sha {
+		return c.updateCheck(ctx, map[string]any{
+			"output": map[string]string{"title": description, "summary": description},
+		})
+	}
+	return c.createCheck(ctx, sha, map[string]any{
+		"status": "in_progress",
+		"output": map[string]string{"title": description, "summary": description},
+	})
+}
+
+// Done marks sha as succeeded or failed.
+func (c *Client) Done(ctx context.Context, sha string, success bool, title, summary string) error {
+	if c.Kind == KindStatus {
+		state := "failure"
+		if success {
+			state = "success"
+		}
+		return c.status(ctx, sha, state, title)
+	}
+	conclusion := "failure"
+	if success {
+		conclusion = "success"
+	}
+	fields := map[string]any{
+		"status":       "completed",
+		"conclusion":   conclusion,
+		"completed_at": time.Now().UTC().Format(time.RFC3339),
+		"output":       map[string]string{"title": title, "summary": summary},
+	}
+	if c.checkSHA == sha {
+		return c.updateCheck(ctx, fields)
+	}
+	return c.createCheck(ctx, sha, fields)
+}
+
+func (c *Client) status(ctx context.Context, sha, state, description string) error {
+	if r := []rune(description); len(r) > maxDescription {
+		description = string(r[:maxDescription-1]) + "…"
+	}
+	return c.do(ctx, http.MethodPost, "/repos/"+c.Repo+"/statuses/"+sha, map[string]any{
+		"state":       state,
+		"context":     c.Name,
+		"description": description,
+	}, nil)
+}
+
+func (c *Client) createCheck(ctx context.Context, sha string, fields map[string]any) error {
+	fields["name"] = c.Name
+	fields["head_sha"] = sha
+	var out struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/repos/"+c.Repo+"/check-runs", fields, &out); err != nil {
+		return err
+	}
+	c.checkSHA, c.checkID = sha, out.ID
+	return nil
+}
+
+func (c *Client) updateCheck(ctx context.Context, fields map[string]any) error {
+	return c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/check-runs/%d", c.Repo, c.checkID), fields, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body map[string]any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, base+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("reporting %s: %w", c.Kind, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("reporting %s: %s", c.Kind, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s response: %w", c.Kind, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package ghstatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	method, path string
+	body         map[string]any
+}
+
+// recorder is a fake GitHub API that records requests and answers check
+// run creation with id 7.
+func recorder(t *testing.T, reqs *[]request) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer ghp-test", r.Header.Get("Authorization"))
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*reqs = append(*reqs, request{r.Method, r.URL.Path, body})
+		_, _ = w.Write([]byte(`{"id": 7}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_Status(t *testing.T) {
+	var reqs []request
+	c := &Client{Kind: KindStatus, Repo: "o/r", Token: "ghp-test", Name: "ralph/build", BaseURL: recorder(t, &reqs).URL}
+	ctx := context.Background()
+
+	require.NoError(t, c.Pending(ctx, "abc", strings.Repeat("x", 200)))
+	require.NoError(t, c.Done(ctx, "def", true, "Ralph build completed", "| table |"))
+
+	require.Len(t, reqs, 2)
+	assert.Equal(t, "/repos/o/r/statuses/abc", reqs[0].path)
+	assert.Equal(t, "pending", reqs[0].body["state"])
+	assert.Equal(t, "ralph/build", reqs[0].body["context"])
+	assert.Len(t, []rune(reqs[0].body["description"].(string)), maxDescription)
+	assert.Equal(t, "/repos/o/r/statuses/def", reqs[1].path)
+	assert.Equal(t, "success", reqs[1].body["state"])
+	assert.Equal(t, "Ralph build completed", reqs[1].body["description"])
+}
+
+func TestClient_Check(t *testing.T) {
+	var reqs []request
+	c := &Client{Kind: KindCheck, Repo: "o/r", Token: "ghp-test", Name: "ralph/build", BaseURL: recorder(t, &reqs).URL}
+	ctx := context.Background()
+
+	require.NoError(t, c.Pending(ctx, "abc", "running"))
+	require.NoError(t, c.Pending(ctx, "abc", "iteration 1"))
+	require.NoError(t, c.Done(ctx, "abc", false, "Ralph build failed", "**summary**"))
+	require.NoError(t, c.Done(ctx, "def", true, "Ralph build completed", "ok"))
+
+	require.Len(t, reqs, 4)
+	assert.Equal(t, http.MethodPost, reqs[0].method)
+	assert.Equal(t, "/repos/o/r/check-runs", reqs[0].path)
+	assert.Equal(t, "abc", reqs[0].body["head_sha"])
+	assert.Equal(t, "in_progress", reqs[0].body["status"])
+
+	// Same head: the open run is updated.
+	assert.Equal(t, http.MethodPatch, reqs[1].method)
+	assert.Equal(t, "/repos/o/r/check-runs/7", reqs[1].path)
+	assert.Equal(t, http.MethodPatch, reqs[2].method)
+	assert.Equal(t, "failure", reqs[2].body["conclusion"])
+	assert.Equal(t, map[string]any{"title": "Ralph build failed", "summary": "**summary**"}, reqs[2].body["output"])
+
+	// New head: a completed run is created on it.
+	assert.Equal(t, http.MethodPost, reqs[3].method)
+	assert.Equal(t, "def", reqs[3].body["head_sha"])
+	assert.Equal(t, "success", reqs[3].body["conclusion"])
+}
+
+func TestClient_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c := &Client{Kind: KindStatus, Repo: "o/r", Token: "ghp-test", BaseURL: srv.URL}
+	require.ErrorContains(t, c.Pending(context.Background(), "abc", "running"), "403")
+}
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New("", "o/r", "ghp", "ralph/plan"))
+	assert.Nil(t, New(KindOff, "o/r", "ghp", "ralph/plan"))
+	assert.Nil(t, New(KindStatus, "o/r", "", "ralph/plan"))
+	assert.IsType(t, &Client{}, New(KindCheck, "o/r", "ghp", "ralph/plan"))
+	assert.True(t, ValidKind(KindStatus))
+	assert.False(t, ValidKind("comment"))
+	assert.Equal(t, "GITHUB_PAT", TokenEnv(KindStatus))
+	assert.Equal(t, "GITHUB_APP_TOKEN", TokenEnv(KindCheck))
+}
@@ -0,0 +1,226 @@
+package git
+
+import (
+	"context"
+	"time"
+)
+
+// The functions below run Repo methods in the process's working directory,
+// for callers that have already changed into the repository.
+
+// Head is Repo.Head in the working directory.
+func Head(ctx context.Context) (string, error) {
+	return cwd.Head(ctx)
+}
+
+// Branch is Repo.Branch in the working directory.
+func Branch(ctx context.Context) (string, error) {
+	return cwd.Branch(ctx)
+}
+
+// Add is Repo.Add in the working directory.
+func Add(ctx context.Context, paths ...string) error {
+	return cwd.Add(ctx, paths...)
+}
+
+// Commit is Repo.Commit in the working directory.
+func Commit(ctx context.Context, message string) error {
+	return cwd.Commit(ctx, message)
+}
+
+// CreateBranch is Repo.CreateBranch in the working directory.
+func CreateBranch(ctx context.Context, branch string) error {
+	return cwd.CreateBranch(ctx, branch)
+}
+
+// Push is Repo.Push in the working directory.
+func Push(ctx context.Context, branch string) error {
+	return cwd.Push(ctx, branch)
+}
+
+// PushSetUpstream is Repo.PushSetUpstream in the working directory.
+func PushSetUpstream(ctx context.Context, branch string) error {
+	return cwd.PushSetUpstream(ctx, branch)
+}
+
+// PullRebase is Repo.PullRebase in the working directory.
+func PullRebase(ctx context.Context, branch string) error {
+	return cwd.PullRebase(ctx, branch)
+}
+
+// RemoteURL is Repo.RemoteURL in the working directory.
+func RemoteURL(ctx context.Context, name string) (string, error) {
+	return cwd.RemoteURL(ctx, name)
+}
+
+// RepoRoot is Repo.RepoRoot in the working directory.
+func RepoRoot(ctx context.Context) (string, error) {
+	return cwd.RepoRoot(ctx)
+}
+
+// IsTracked is Repo.IsTracked in the working directory.
+func IsTracked(ctx context.Context, path string) (bool, error) {
+	return cwd.IsTracked(ctx, path)
+}
+
+// HasStagedChanges is Repo.HasStagedChanges in the working directory.
+func HasStagedChanges(ctx context.Context) (bool, error) {
+	return cwd.HasStagedChanges(ctx)
+}
+
+// DirtyFiles is Repo.DirtyFiles in the working directory.
+func DirtyFiles(ctx context.Context) ([]string, error) {
+	return cwd.DirtyFiles(ctx)
+}
+
+// CommitPaths is Repo.CommitPaths in the working directory.
+func CommitPaths(ctx context.Context, message string, paths ...string) error {
+	return cwd.CommitPaths(ctx, message, paths...)
+}
+
+// Stash is Repo.Stash in the working directory.
+func Stash(ctx context.Context, message string, paths ...string) error {
+	return cwd.Stash(ctx, message, paths...)
+}
+
+// StashPop is Repo.StashPop in the working directory.
+func StashPop(ctx context.Context) error {
+	return cwd.StashPop(ctx)
+}
+
+// BranchExistsOnRemote is Repo.BranchExistsOnRemote in the working directory.
+func BranchExistsOnRemote(ctx context.Context, branch string) (bool, error) {
+	return cwd.BranchExistsOnRemote(ctx, branch)
+}
+
+// DiffFromRemote is Repo.DiffFromRemote in the working directory.
+func DiffFromRemote(ctx context.Context, branch, path string) (string, error) {
+	return cwd.DiffFromRemote(ctx, branch, path)
+}
+
+// DiffStat is Repo.DiffStat in the working directory.
+func DiffStat(ctx context.Context, from, to string) (string, error) {
+	return cwd.DiffStat(ctx, from, to)
+}
+
+// Diff is Repo.Diff in the working directory.
+func Diff(ctx context.Context, from, to string) (string, error) {
+	return cwd.Diff(ctx, from, to)
+}
+
+// ChangedFiles is Repo.ChangedFiles in the working directory.
+func ChangedFiles(ctx context.Context, from, to string) ([]string, error) {
+	return cwd.ChangedFiles(ctx, from, to)
+}
+
+// ResetHard is Repo.ResetHard in the working directory.
+func ResetHard(ctx context.Context, rev string) error {
+	return cwd.ResetHard(ctx, rev)
+}
+
+// CommitSubjects is Repo.CommitSubjects in the working directory.
+func CommitSubjects(ctx context.Context, from, to string) ([]string, error) {
+	return cwd.CommitSubjects(ctx, from, to)
+}
+
+// LastChanged is Repo.LastChanged in the working directory.
+func LastChanged(ctx context.Context, path string) (time.Time, error) {
+	return cwd.LastChanged(ctx, path)
+}
+
+// CommitsBetween is Repo.CommitsBetween in the working directory.
+func CommitsBetween(ctx context.Context, since, until time.Time) ([]LoggedCommit, error) {
+	return cwd.CommitsBetween(ctx, since, until)
+}
+
+// LastCommitMessage is Repo.LastCommitMessage in the working directory.
+func LastCommitMessage(ctx context.Context) (string, error) {
+	return cwd.LastCommitMessage(ctx)
+}
+
+// AmendCommitMessage is Repo.AmendCommitMessage in the working directory.
+func AmendCommitMessage(ctx context.Context, message string) error {
+	return cwd.AmendCommitMessage(ctx, message)
+}
+
+// UnpushedCount is Repo.UnpushedCount in the working directory.
+func UnpushedCount(ctx context.Context, branch string) (int, error) {
+	return cwd.UnpushedCount(ctx, branch)
+}
+
+// LocalBranches is Repo.LocalBranches in the working directory.
+func LocalBranches(ctx context.Context) ([]string, error) {
+	return cwd.LocalBranches(ctx)
+}
+
+// ForkDistance is Repo.ForkDistance in the working directory.
+func ForkDistance(ctx context.Context, branch string) (int, error) {
+	return cwd.ForkDistance(ctx, branch)
+}
+
+// CheckHealth is Repo.CheckHealth in the working directory.
+func CheckHealth(ctx context.Context) (*Health, error) {
+	return cwd.CheckHealth(ctx)
+}
+
+// Repair is Repo.Repair in the working directory.
+func Repair(ctx context.Context, h *Health, branch string) ([]string, error) {
+	return cwd.Repair(ctx, h, branch)
+}
+
+// The functions below run Repo methods in the repository at dir.
+
+// IsGitRepo is Repo.IsRepo in dir.
+func IsGitRepo(ctx context.Context, dir string) bool {
+	return At(dir).IsRepo(ctx)
+}
+
+// HeadIn is Repo.Head in dir.
+func HeadIn(ctx context.Context, dir string) (string, error) {
+	return At(dir).Head(ctx)
+}
+
+// BranchIn is Repo.Branch in dir.
+func BranchIn(ctx context.Context, dir string) (string, error) {
+	return At(dir).Branch(ctx)
+}
+
+// PushIn is Repo.Push in dir.
+func PushIn(ctx context.Context, dir, branch string) error {
+	return At(dir).Push(ctx, branch)
+}
+
+// PushSetUpstreamIn is Repo.PushSetUpstream in dir.
+func PushSetUpstreamIn(ctx context.Context, dir, branch string) error {
+	return At(dir).PushSetUpstream(ctx, branch)
+}
+
+// BranchExistsOnRemoteIn is Repo.BranchExistsOnRemote in dir.
+func BranchExistsOnRemoteIn(ctx context.Context, dir, branch string) (bool, error) {
+	return At(dir).BranchExistsOnRemote(ctx, branch)
+}
+
+// BranchExistsIn is Repo.BranchExists in dir.
+func BranchExistsIn(ctx context.Context, dir, branch string) bool {
+	return At(dir).BranchExists(ctx, branch)
+}
+
+// AddWorktreeIn is Repo.AddWorktree in dir.
+func AddWorktreeIn(ctx context.Context, dir, path, branch string) error {
+	return At(dir).AddWorktree(ctx, path, branch)
+}
+
+// RemoveWorktreeIn is Repo.RemoveWorktree in dir.
+func RemoveWorktreeIn(ctx context.Context, dir, path string, force bool) error {
+	return At(dir).RemoveWorktree(ctx, path, force)
+}
+
+// TrackedFilesIn is Repo.TrackedFiles in dir.
+func TrackedFilesIn(ctx context.Context, dir string) ([]string, error) {
+	return At(dir).TrackedFiles(ctx)
+}
+
+// AddedFilesIn is Repo.AddedFiles in dir.
+func AddedFilesIn(ctx context.Context, dir string, n int) ([]string, error) {
+	return At(dir).AddedFiles(ctx, n)
+}
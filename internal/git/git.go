@@ -4,14 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/debuglog"
 )
 
 // Head returns the current HEAD commit hash.
-func Head() (string, error) {
-	out, err := run("rev-parse", "HEAD")
+func (r *Repo) Head(ctx context.Context) (string, error) {
+	out, err := r.run(ctx, "rev-parse", "HEAD")
 	if err != nil {
 		return "", err
 	}
@@ -19,8 +26,8 @@ func Head() (string, error) {
 }
 
 // Branch returns the current branch name.
-func Branch() (string, error) {
-	out, err := run("rev-parse", "--abbrev-ref", "HEAD")
+func (r *Repo) Branch(ctx context.Context) (string, error) {
+	out, err := r.run(ctx, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", err
 	}
@@ -28,57 +35,71 @@ func Branch() (string, error) {
 }
 
 // Add stages the given paths.
-func Add(paths ...string) error {
+func (r *Repo) Add(ctx context.Context, paths ...string) error {
 	args := append([]string{"add"}, paths...)
-	_, err := run(args...)
+	_, err := r.run(ctx, args...)
 	return err
 }
 
 // Commit creates a commit with the given message.
-func Commit(message string) error {
-	_, err := run("commit", "-m", message)
+func (r *Repo) Commit(ctx context.Context, message string) error {
+	_, err := r.run(ctx, "commit", "-m", message)
+	return err
+}
+
+// CreateBranch creates branch at HEAD and checks it out.
+func (r *Repo) CreateBranch(ctx context.Context, branch string) error {
+	_, err := r.run(ctx, "checkout", "-b", branch)
 	return err
 }
 
 // Push pushes the given branch to origin.
-func Push(branch string) error {
-	_, err := run("push", "origin", branch)
+func (r *Repo) Push(ctx context.Context, branch string) error {
+	_, err := r.run(ctx, "push", "origin", branch)
 	return err
 }
 
 // PushSetUpstream pushes and sets the upstream tracking branch.
-func PushSetUpstream(branch string) error {
-	_, err := run("push", "-u", "origin", branch)
+func (r *Repo) PushSetUpstream(ctx context.Context, branch string) error {
+	_, err := r.run(ctx, "push", "-u", "origin", branch)
 	return err
 }
 
 // PullRebase performs a pull --rebase on the given branch.
-func PullRebase(branch string) error {
-	_, err := run("pull", "--rebase", "origin", branch)
+func (r *Repo) PullRebase(ctx context.Context, branch string) error {
+	_, err := r.run(ctx, "pull", "--rebase", "origin", branch)
 	return err
 }
 
 // RemoteURL returns the URL configured for the given remote.
-func RemoteURL(name string) (string, error) {
-	out, err := run("remote", "get-url", name)
+func (r *Repo) RemoteURL(ctx context.Context, name string) (string, error) {
+	out, err := r.run(ctx, "remote", "get-url", name)
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(out), nil
 }
 
-// RepoRoot returns the top-level directory of the git repo.
-func RepoRoot() (string, error) {
-	out, err := run("rev-parse", "--show-toplevel")
+// RepoRoot returns the top-level directory of the git repo with symlinks
+// resolved.
+func (r *Repo) RepoRoot(ctx context.Context) (string, error) {
+	out, err := r.run(ctx, "rev-parse", "--show-toplevel")
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(out), nil
+	// Resolve symlinks so the root compares equal to other resolved paths
+	// (e.g. /var vs /private/var on macOS) and names the real directory
+	// when used as a docker bind mount source.
+	root, err := filepath.EvalSymlinks(strings.TrimSpace(out))
+	if err != nil {
+		return "", fmt.Errorf("resolving repo root: %w", err)
+	}
+	return root, nil
 }
 
 // IsTracked returns true if the given path is tracked by git (committed).
-func IsTracked(path string) (bool, error) {
-	_, err := run("ls-files", "--error-unmatch", path)
+func (r *Repo) IsTracked(ctx context.Context, path string) (bool, error) {
+	_, err := r.run(ctx, "ls-files", "--error-unmatch", path)
 	if err != nil {
 		// Exit code 1 means not tracked — not an error for our purposes.
 		var exitErr *exec.ExitError
@@ -91,22 +112,151 @@ func IsTracked(path string) (bool, error) {
 }
 
 // HasStagedChanges returns true if there are changes in the staging area.
-func HasStagedChanges() (bool, error) {
-	cmd := exec.CommandContext(context.Background(), "git", "diff", "--cached", "--quiet") //nolint:gosec // hardcoded args
-	err := cmd.Run()
+func (r *Repo) HasStagedChanges(ctx context.Context) (bool, error) {
+	_, err := r.run(ctx, "diff", "--cached", "--quiet")
 	if err != nil {
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
 			return true, nil // exit 1 = there are staged changes
 		}
-		return false, fmt.Errorf("git diff --cached: %w", err)
+		return false, err
 	}
 	return false, nil // exit 0 = clean
 }
 
+// DirtyFiles returns the paths, relative to the repo root, with uncommitted
+// changes: staged, unstaged, or untracked and not ignored. A rename lists
+// both its new and old path.
+func (r *Repo) DirtyFiles(ctx context.Context) ([]string, error) {
+	out, err := r.run(ctx, "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	return parsePorcelainZ(out), nil
+}
+
+// parsePorcelainZ extracts the paths from `git status --porcelain -z`
+// output, where each entry is "XY path" and a rename or copy is followed by
+// a separate entry holding its source path.
+func parsePorcelainZ(out string) []string {
+	var paths []string
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if len(f) < 4 {
+			continue
+		}
+		paths = append(paths, f[3:])
+		if (f[0] == 'R' || f[0] == 'C') && i+1 < len(fields) && fields[i+1] != "" {
+			i++
+			paths = append(paths, fields[i])
+		}
+	}
+	return paths
+}
+
+// CommitPaths stages every change to paths (relative to the repo root),
+// new and deleted files included, and commits what is staged with message.
+func (r *Repo) CommitPaths(ctx context.Context, message string, paths ...string) error {
+	if _, err := r.run(ctx, append([]string{"add", "-A", "--"}, topPathspecs(paths)...)...); err != nil {
+		return err
+	}
+	return r.Commit(ctx, message)
+}
+
+// Stash sets aside the uncommitted changes to paths (relative to the repo
+// root), untracked files included, leaving them as they were at HEAD.
+func (r *Repo) Stash(ctx context.Context, message string, paths ...string) error {
+	_, err := r.run(ctx, append([]string{"stash", "push", "--include-untracked", "-m", message, "--"}, topPathspecs(paths)...)...)
+	return err
+}
+
+// StashPop restores the most recent stash and drops it. On a conflict the
+// stash is kept.
+func (r *Repo) StashPop(ctx context.Context) error {
+	_, err := r.run(ctx, "stash", "pop")
+	return err
+}
+
+// StashCreate records the uncommitted changes to tracked files as a stash
+// commit, leaving the working tree and the stash list alone. It returns ""
+// when there are none.
+func (r *Repo) StashCreate(ctx context.Context) (string, error) {
+	out, err := r.run(ctx, "stash", "create")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// StashApply reapplies the stash commit rev, restaging what was staged.
+func (r *Repo) StashApply(ctx context.Context, rev string) error {
+	_, err := r.run(ctx, "stash", "apply", "--index", rev)
+	return err
+}
+
+// UntrackedFiles returns the untracked files that aren't ignored, relative
+// to the repo root.
+func (r *Repo) UntrackedFiles(ctx context.Context) ([]string, error) {
+	out, err := r.run(ctx, "ls-files", "--others", "--exclude-standard", "--full-name", "-z", "--", ":/")
+	if err != nil {
+		return nil, err
+	}
+	return splitNul(out), nil
+}
+
+// Clean deletes the untracked files and directories that aren't ignored.
+func (r *Repo) Clean(ctx context.Context) error {
+	_, err := r.run(ctx, "clean", "-fd", "--", ":/")
+	return err
+}
+
+// ForceCheckout points branch at rev and switches to it, discarding
+// uncommitted changes to tracked files. An empty branch detaches HEAD at rev.
+func (r *Repo) ForceCheckout(ctx context.Context, branch, rev string) error {
+	if branch == "" {
+		_, err := r.run(ctx, "checkout", "-f", "--detach", rev)
+		return err
+	}
+	_, err := r.run(ctx, "checkout", "-f", "-B", branch, rev)
+	return err
+}
+
+// UpdateRef points ref at rev, creating it if needed.
+func (r *Repo) UpdateRef(ctx context.Context, ref, rev string) error {
+	_, err := r.run(ctx, "update-ref", ref, rev)
+	return err
+}
+
+// DeleteRef deletes ref.
+func (r *Repo) DeleteRef(ctx context.Context, ref string) error {
+	_, err := r.run(ctx, "update-ref", "-d", ref)
+	return err
+}
+
+// CommonDir returns the absolute path of the repository's git directory
+// shared by all its worktrees.
+func (r *Repo) CommonDir(ctx context.Context) (string, error) {
+	out, err := r.run(ctx, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// topPathspecs turns repo-root-relative paths into pathspecs git matches
+// literally, whatever the working directory.
+func topPathspecs(paths []string) []string {
+	specs := make([]string, len(paths))
+	for i, p := range paths {
+		specs[i] = ":(top,literal)" + p
+	}
+	return specs
+}
+
 // BranchExistsOnRemote returns true if the branch exists on the origin remote.
-func BranchExistsOnRemote(branch string) (bool, error) {
-	out, err := run("ls-remote", "--heads", "origin", "refs/heads/"+branch)
+func (r *Repo) BranchExistsOnRemote(ctx context.Context, branch string) (bool, error) {
+	out, err := r.run(ctx, "ls-remote", "--heads", "origin", "refs/heads/"+branch)
 	if err != nil {
 		return false, err
 	}
@@ -115,14 +265,177 @@ func BranchExistsOnRemote(branch string) (bool, error) {
 
 // DiffFromRemote returns the diff output for the given path between HEAD and origin/branch.
 // A non-empty result means there are unpushed changes at that path.
-func DiffFromRemote(branch, path string) (string, error) {
-	out, err := run("diff", "origin/"+branch, "--", path)
+func (r *Repo) DiffFromRemote(ctx context.Context, branch, path string) (string, error) {
+	out, err := r.run(ctx, "diff", "origin/"+branch, "--", path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// DiffStat returns the `git diff --stat` summary between two revisions.
+func (r *Repo) DiffStat(ctx context.Context, from, to string) (string, error) {
+	return r.run(ctx, "diff", "--stat", from, to)
+}
+
+// Diff returns the patch between two revisions.
+func (r *Repo) Diff(ctx context.Context, from, to string) (string, error) {
+	return r.run(ctx, "diff", from, to)
+}
+
+// ChangedFiles returns the paths changed between two revisions, relative to
+// the repo root.
+func (r *Repo) ChangedFiles(ctx context.Context, from, to string) ([]string, error) {
+	out, err := r.run(ctx, "diff", "--name-only", "--no-renames", "-z", from, to)
+	if err != nil {
+		return nil, err
+	}
+	out = strings.TrimRight(out, "\x00")
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\x00"), nil
+}
+
+// ResetHard moves HEAD to rev and discards working tree changes.
+func (r *Repo) ResetHard(ctx context.Context, rev string) error {
+	_, err := r.run(ctx, "reset", "--hard", rev)
+	return err
+}
+
+// CommitSubjects returns the subject lines of commits in from..to, newest first.
+func (r *Repo) CommitSubjects(ctx context.Context, from, to string) ([]string, error) {
+	out, err := r.run(ctx, "log", "--format=%s", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// LastChanged returns when the last commit on HEAD touching path (relative
+// to the repo root) was made; the zero time when none has.
+func (r *Repo) LastChanged(ctx context.Context, path string) (time.Time, error) {
+	out, err := r.run(ctx, "log", "-1", "--format=%ct", "--", ":(top)"+path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return time.Time{}, nil
+	}
+	secs, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing commit time %q: %w", out, err)
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// LoggedCommit is a commit with its change totals, as listed by CommitsBetween.
+type LoggedCommit struct {
+	SHA        string   `json:"sha"` // abbreviated
+	Subject    string   `json:"subject"`
+	Files      []string `json:"files"`
+	Insertions int      `json:"insertions"`
+	Deletions  int      `json:"deletions"`
+}
+
+// CommitsBetween returns the commits on HEAD committed within [since, until],
+// newest first, with per-commit line counts. Binary files count as changed
+// with no lines.
+func (r *Repo) CommitsBetween(ctx context.Context, since, until time.Time) ([]LoggedCommit, error) {
+	out, err := r.run(ctx, "log", "--no-renames", "--numstat", "--format=%x1e%h%x00%s",
+		"--since="+since.Format(time.RFC3339), "--until="+until.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	return parseNumstatLog(out), nil
+}
+
+// parseNumstatLog parses `git log --numstat --format=%x1e%h%x00%s` output.
+func parseNumstatLog(out string) []LoggedCommit {
+	var commits []LoggedCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		sha, subject, ok := strings.Cut(lines[0], "\x00")
+		if !ok {
+			continue
+		}
+		c := LoggedCommit{SHA: sha, Subject: subject}
+		for _, line := range lines[1:] {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) != 3 {
+				continue
+			}
+			ins, _ := strconv.Atoi(fields[0]) //nolint:errcheck // "-" for binary files
+			del, _ := strconv.Atoi(fields[1]) //nolint:errcheck // "-" for binary files
+			c.Insertions += ins
+			c.Deletions += del
+			c.Files = append(c.Files, fields[2])
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// LastCommitMessage returns the full message of the HEAD commit.
+func (r *Repo) LastCommitMessage(ctx context.Context) (string, error) {
+	out, err := r.run(ctx, "log", "-1", "--format=%B")
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(out), nil
 }
 
+// AmendCommitMessage replaces the HEAD commit's message, keeping its tree.
+func (r *Repo) AmendCommitMessage(ctx context.Context, message string) error {
+	_, err := r.run(ctx, "commit", "--amend", "--allow-empty", "-m", message)
+	return err
+}
+
+// UnpushedCount returns how many commits HEAD has that origin/branch lacks.
+func (r *Repo) UnpushedCount(ctx context.Context, branch string) (int, error) {
+	out, err := r.run(ctx, "rev-list", "--count", "origin/"+branch+"..HEAD")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return 0, fmt.Errorf("parsing rev-list count %q: %w", out, err)
+	}
+	return n, nil
+}
+
+// LocalBranches returns the short names of all local branches.
+func (r *Repo) LocalBranches(ctx context.Context) ([]string, error) {
+	out, err := r.run(ctx, "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// ForkDistance returns how many commits HEAD has made since it diverged from
+// branch, i.e. the commits between their merge-base and HEAD.
+func (r *Repo) ForkDistance(ctx context.Context, branch string) (int, error) {
+	base, err := r.run(ctx, "merge-base", "HEAD", branch)
+	if err != nil {
+		return 0, err
+	}
+	out, err := r.run(ctx, "rev-list", "--count", strings.TrimSpace(base)+"..HEAD")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return 0, fmt.Errorf("parsing rev-list count %q: %w", out, err)
+	}
+	return n, nil
+}
+
 // unsafeChars matches characters that are not alphanumeric, hyphens, underscores, or dots.
 var unsafeChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
 
@@ -136,91 +449,129 @@ func SanitizeBranch(branch string) string {
 	return s
 }
 
-// IsProtectedBranch returns true if branch matches any entry in the protected
-// list (case-insensitive comparison).
+// protectedRegexPrefix marks a protected_branches entry as a regular
+// expression rather than a glob.
+const protectedRegexPrefix = "re:"
+
+// IsProtectedBranch returns true if branch matches any pattern in the
+// protected list, as MatchProtected decides. Invalid patterns match nothing;
+// config validation reports them.
 func IsProtectedBranch(branch string, protected []string) bool {
 	for _, p := range protected {
-		if strings.EqualFold(branch, p) {
+		if ok, err := MatchProtected(branch, p); err == nil && ok {
 			return true
 		}
 	}
 	return false
 }
 
-// IsGitRepo returns true if dir is a git repository.
-func IsGitRepo(dir string) bool {
-	_, err := runIn(dir, "rev-parse", "--git-dir")
-	return err == nil
-}
-
-// HeadIn returns the HEAD commit hash for the repo at dir.
-func HeadIn(dir string) (string, error) {
-	out, err := runIn(dir, "rev-parse", "HEAD")
+// MatchProtected reports whether branch matches pattern, a protected_branches
+// entry. A pattern starting with "re:" is a regular expression that must
+// match the whole branch name, e.g. "re:hotfix-[0-9]+". Any other pattern is
+// a glob as in path.Match, e.g. "release/*", where * doesn't cross a slash;
+// a plain name is an exact match. Both are case-insensitive.
+func MatchProtected(branch, pattern string) (bool, error) {
+	if expr, ok := strings.CutPrefix(pattern, protectedRegexPrefix); ok {
+		re, err := regexp.Compile(`(?i)^(?:` + expr + `)$`)
+		if err != nil {
+			return false, fmt.Errorf("protected branch pattern %q: %w", pattern, err)
+		}
+		return re.MatchString(branch), nil
+	}
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(branch))
 	if err != nil {
-		return "", err
+		return false, fmt.Errorf("protected branch pattern %q: %w", pattern, err)
 	}
-	return strings.TrimSpace(out), nil
+	return ok, nil
 }
 
-// BranchIn returns the current branch name for the repo at dir.
-func BranchIn(dir string) (string, error) {
-	out, err := runIn(dir, "rev-parse", "--abbrev-ref", "HEAD")
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(out), nil
+// IsRepo reports whether the Repo's directory is inside a git repository.
+func (r *Repo) IsRepo(ctx context.Context) bool {
+	_, err := r.run(ctx, "rev-parse", "--git-dir")
+	return err == nil
 }
 
-// PushIn pushes the given branch to origin for the repo at dir.
-func PushIn(dir, branch string) error {
-	_, err := runIn(dir, "push", "origin", branch)
+// BranchExists reports whether the local branch exists.
+func (r *Repo) BranchExists(ctx context.Context, branch string) bool {
+	_, err := r.run(ctx, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// AddWorktree checks branch out into a new worktree at path. A branch that
+// doesn't exist yet is created from HEAD.
+func (r *Repo) AddWorktree(ctx context.Context, path, branch string) error {
+	args := []string{"worktree", "add", path, branch}
+	if !r.BranchExists(ctx, branch) {
+		args = []string{"worktree", "add", "-b", branch, path}
+	}
+	_, err := r.run(ctx, args...)
 	return err
 }
 
-// PushSetUpstreamIn pushes and sets upstream for the repo at dir.
-func PushSetUpstreamIn(dir, branch string) error {
-	_, err := runIn(dir, "push", "-u", "origin", branch)
+// RemoveWorktree removes the worktree at path. With force, uncommitted
+// changes in it are discarded.
+func (r *Repo) RemoveWorktree(ctx context.Context, path string, force bool) error {
+	args := []string{"worktree", "remove", path}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err := r.run(ctx, args...)
 	return err
 }
 
-// BranchExistsOnRemoteIn returns true if the branch exists on origin for the repo at dir.
-func BranchExistsOnRemoteIn(dir, branch string) (bool, error) {
-	out, err := runIn(dir, "ls-remote", "--heads", "origin", "refs/heads/"+branch)
+// TrackedFiles returns the paths git tracks, relative to the repo root when
+// the Repo's directory is the root.
+func (r *Repo) TrackedFiles(ctx context.Context) ([]string, error) {
+	out, err := r.run(ctx, "ls-files", "-z")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return strings.TrimSpace(out) != "", nil
+	return splitNul(out), nil
 }
 
-func runIn(dir string, args ...string) (string, error) {
-	if len(args) == 0 {
-		return "", fmt.Errorf("git: no subcommand specified")
-	}
-	full := append([]string{"-C", dir}, args...)                     //nolint:gocritic // building new slice intentionally
-	cmd := exec.CommandContext(context.Background(), "git", full...) //nolint:gosec // args are hardcoded by callers in this package
-	out, err := cmd.Output()
+// AddedFiles returns the paths added by the last n commits on HEAD,
+// including any deleted since.
+func (r *Repo) AddedFiles(ctx context.Context, n int) ([]string, error) {
+	out, err := r.run(ctx, "log", "-n", strconv.Itoa(n), "--diff-filter=A", "--name-only", "--no-renames", "--format=", "-z")
 	if err != nil {
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
-			return "", fmt.Errorf("git %s: %w\n%s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
-		}
-		return "", fmt.Errorf("git %s: %w", args[0], err)
+		return nil, err
 	}
-	return string(out), nil
+	return splitNul(out), nil
 }
 
-func run(args ...string) (string, error) {
-	if len(args) == 0 {
-		return "", fmt.Errorf("git: no subcommand specified")
+// splitNul splits -z output into its paths, dropping empty entries.
+func splitNul(out string) []string {
+	var paths []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
 	}
-	cmd := exec.CommandContext(context.Background(), "git", args...) //nolint:gosec // args are hardcoded by callers in this package
+	return paths
+}
+
+// runGit runs git with argv under the per-command timeout for subcommand.
+// Terminal prompts are disabled so a missing credential fails fast instead
+// of blocking the loop.
+func runGit(ctx context.Context, subcommand string, argv []string) (string, error) {
+	timeout := timeoutFor(ctx, subcommand)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", argv...) //nolint:gosec // args are hardcoded by callers in this package
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	done := debuglog.Exec(ctx, "git", argv...)
 	out, err := cmd.Output()
+	done(err)
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("git %s: timed out after %s: %w", subcommand, timeout, ctx.Err())
+		}
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
-			return "", fmt.Errorf("git %s: %w\n%s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+			return "", fmt.Errorf("git %s: %w\n%s", subcommand, err, strings.TrimSpace(string(exitErr.Stderr)))
 		}
-		return "", fmt.Errorf("git %s: %w", args[0], err)
+		return "", fmt.Errorf("git %s: %w", subcommand, err)
 	}
 	return string(out), nil
 }
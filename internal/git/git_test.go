@@ -54,6 +54,18 @@ func TestIsProtectedBranch(t *testing.T) {
 		{"main", []string{"develop", "staging"}, false},
 		{"main", nil, false},
 		{"main", []string{}, false},
+		{"release/1.2", []string{"release/*"}, true},
+		{"Release/1.2", []string{"release/*"}, true},
+		{"release", []string{"release/*"}, false},
+		{"release/1.2/fix", []string{"release/*"}, false},
+		{"hotfix-42", []string{"main", "hotfix-*"}, true},
+		{"my-hotfix-42", []string{"hotfix-*"}, false},
+		{"hotfix-42", []string{"re:hotfix-[0-9]+"}, true},
+		{"HOTFIX-42", []string{"re:hotfix-[0-9]+"}, true},
+		{"hotfix-42-wip", []string{"re:hotfix-[0-9]+"}, false},
+		{"release/2.0", []string{"re:release/.*|main"}, true},
+		{"main", []string{"[", "main"}, true},
+		{"main", []string{"re:("}, false},
 	}
 
 	for _, tt := range tests {
@@ -64,6 +76,14 @@ func TestIsProtectedBranch(t *testing.T) {
 	}
 }
 
+func TestMatchProtected_InvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"release/[", "re:hotfix-(", "re:*"} {
+		_, err := MatchProtected("main", pattern)
+		require.Error(t, err, pattern)
+		assert.ErrorContains(t, err, pattern)
+	}
+}
+
 // initRepo creates a git repo with an initial commit in a temp dir.
 func initRepo(t *testing.T) string {
 	t.Helper()
@@ -83,22 +103,67 @@ func initRepo(t *testing.T) string {
 	return dir
 }
 
+func TestParseNumstatLog(t *testing.T) {
+	out := "\x1eabc1234\x00feat: add widget\n\n10\t2\tinternal/widget.go\n-\t-\tlogo.png\n" +
+		"\x1edef5678\x00docs: readme\n\n3\t0\tREADME.md\n" +
+		"\x1e0a1b2c3\x00chore: empty\n"
+	commits := parseNumstatLog(out)
+	require.Len(t, commits, 3)
+	assert.Equal(t, LoggedCommit{SHA: "abc1234", Subject: "feat: add widget", Files: []string{"internal/widget.go", "logo.png"}, Insertions: 10, Deletions: 2}, commits[0])
+	assert.Equal(t, 3, commits[1].Insertions)
+	assert.Empty(t, commits[2].Files)
+	assert.Empty(t, parseNumstatLog(""))
+}
+
+func TestParsePorcelainZ(t *testing.T) {
+	out := " M main.go\x00R  new.go\x00old.go\x00?? docs/notes.md\x00"
+	assert.Equal(t, []string{"main.go", "new.go", "old.go", "docs/notes.md"}, parsePorcelainZ(out))
+	assert.Empty(t, parsePorcelainZ(""))
+}
+
 func TestIsGitRepo(t *testing.T) {
 	dir := initRepo(t)
-	assert.True(t, IsGitRepo(dir))
-	assert.False(t, IsGitRepo(t.TempDir()))
+	assert.True(t, IsGitRepo(context.Background(), dir))
+	assert.False(t, IsGitRepo(context.Background(), t.TempDir()))
 }
 
 func TestHeadIn(t *testing.T) {
 	dir := initRepo(t)
-	head, err := HeadIn(dir)
+	head, err := HeadIn(context.Background(), dir)
 	require.NoError(t, err)
 	assert.Len(t, head, 40, "expected full SHA")
 }
 
 func TestBranchIn(t *testing.T) {
 	dir := initRepo(t)
-	branch, err := BranchIn(dir)
+	branch, err := BranchIn(context.Background(), dir)
 	require.NoError(t, err)
 	assert.Equal(t, "main", branch)
 }
+
+func TestParseStatusV2(t *testing.T) {
+	out := "# branch.oid 1234567890abcdef\n" +
+		"# branch.head feature/x\n" +
+		"# branch.upstream origin/feature/x\n" +
+		"1 .M N... 100644 100644 100644 aaa bbb main.go\n" +
+		"u UU N... 100644 100644 100644 100644 h1 h2 h3 dir/conflicted file.go\n" +
+		"? untracked.txt\n"
+	branch, detached, unmerged := ParseStatusV2(out)
+	assert.Equal(t, "feature/x", branch)
+	assert.False(t, detached)
+	assert.Equal(t, []string{"dir/conflicted file.go"}, unmerged)
+
+	branch, detached, unmerged = ParseStatusV2("# branch.oid 123\n# branch.head (detached)\n")
+	assert.Empty(t, branch)
+	assert.True(t, detached)
+	assert.Empty(t, unmerged)
+}
+
+func TestHealthOK(t *testing.T) {
+	assert.True(t, (&Health{Branch: "main"}).OK("main"))
+	assert.True(t, (&Health{Branch: "main"}).OK(""))
+	assert.False(t, (&Health{Branch: "other"}).OK("main"))
+	assert.False(t, (&Health{Detached: true}).OK(""))
+	assert.False(t, (&Health{Branch: "main", Rebasing: true}).OK("main"))
+	assert.False(t, (&Health{Branch: "main", Unmerged: []string{"a"}}).OK("main"))
+}
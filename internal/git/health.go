@@ -0,0 +1,169 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Health describes states a previous iteration can leave the repo in that
+// make the next one behave unpredictably.
+type Health struct {
+	Branch   string   // checked-out branch, "" when detached
+	Detached bool     // HEAD is not on a branch
+	Rebasing bool     // a rebase is in progress
+	Merging  bool     // a merge is in progress
+	Unmerged []string // paths with unresolved conflicts
+}
+
+// OK reports whether the repo is in a normal state on wantBranch. An empty
+// wantBranch accepts any branch.
+func (h *Health) OK(wantBranch string) bool {
+	return !h.Detached && !h.Rebasing && !h.Merging && len(h.Unmerged) == 0 && h.onBranch(wantBranch)
+}
+
+func (h *Health) onBranch(branch string) bool {
+	return !h.Detached && (branch == "" || h.Branch == branch)
+}
+
+// ParseStatusV2 extracts the branch and unmerged paths from
+// `git status --porcelain=v2 --branch` output.
+func ParseStatusV2(out string) (branch string, detached bool, unmerged []string) {
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.head "):
+			head := strings.TrimPrefix(line, "# branch.head ")
+			if head == "(detached)" {
+				detached = true
+			} else {
+				branch = head
+			}
+		case strings.HasPrefix(line, "u "):
+			// u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+			if fields := strings.SplitN(line, " ", 11); len(fields) == 11 {
+				unmerged = append(unmerged, fields[10])
+			}
+		}
+	}
+	return branch, detached, unmerged
+}
+
+// CheckHealth inspects the working tree for an interrupted rebase or merge,
+// a detached HEAD, or unresolved conflicts.
+func (r *Repo) CheckHealth(ctx context.Context) (*Health, error) {
+	out, err := r.run(ctx, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return nil, err
+	}
+	h := &Health{}
+	h.Branch, h.Detached, h.Unmerged = ParseStatusV2(out)
+
+	if h.Rebasing, err = r.gitPathExists(ctx, "rebase-merge", "rebase-apply"); err != nil {
+		return nil, err
+	}
+	if h.Merging, err = r.gitPathExists(ctx, "MERGE_HEAD"); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// gitPathExists reports whether any of the named files exists in the git dir.
+func (r *Repo) gitPathExists(ctx context.Context, names ...string) (bool, error) {
+	for _, name := range names {
+		out, err := r.run(ctx, "rev-parse", "--git-path", name)
+		if err != nil {
+			return false, err
+		}
+		path := strings.TrimSpace(out)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(r.Dir, path) // relative to where git ran
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Repair returns the repo to a clean state on branch, undoing whatever h
+// reports, and describes each step taken. It never discards commits: a
+// detached HEAD that is ahead of branch is kept by moving branch to it, and
+// any other detached commit is named in the returned actions.
+func (r *Repo) Repair(ctx context.Context, h *Health, branch string) ([]string, error) {
+	var actions []string
+	if h.Rebasing {
+		if _, err := r.run(ctx, "rebase", "--abort"); err != nil {
+			return actions, fmt.Errorf("aborting rebase: %w", err)
+		}
+		actions = append(actions, "aborted in-progress rebase")
+	}
+	if h.Merging {
+		if _, err := r.run(ctx, "merge", "--abort"); err != nil {
+			return actions, fmt.Errorf("aborting merge: %w", err)
+		}
+		actions = append(actions, "aborted in-progress merge")
+	}
+
+	// Aborting usually clears conflicts and restores the branch; re-check
+	// before touching anything else.
+	if len(actions) > 0 {
+		var err error
+		if h, err = r.CheckHealth(ctx); err != nil {
+			return actions, err
+		}
+	}
+
+	if len(h.Unmerged) > 0 {
+		if _, err := r.run(ctx, "reset", "--merge"); err != nil {
+			return actions, fmt.Errorf("resetting conflicted paths: %w", err)
+		}
+		actions = append(actions, fmt.Sprintf("reset %d conflicted path(s): %s", len(h.Unmerged), strings.Join(h.Unmerged, ", ")))
+	}
+
+	if !h.onBranch(branch) {
+		if branch == "" {
+			return actions, errors.New("HEAD is detached and no branch is configured to re-attach")
+		}
+		action, err := r.reattach(ctx, branch)
+		if err != nil {
+			return actions, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// reattach checks out branch, carrying HEAD along when it is a descendant.
+func (r *Repo) reattach(ctx context.Context, branch string) (string, error) {
+	head, err := r.Head(ctx)
+	if err != nil {
+		return "", err
+	}
+	_, err = r.run(ctx, "merge-base", "--is-ancestor", branch, "HEAD")
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		if _, err := r.run(ctx, "checkout", "-B", branch); err != nil {
+			return "", fmt.Errorf("re-attaching %s: %w", branch, err)
+		}
+		return fmt.Sprintf("re-attached %s at %s", branch, shortSHA(head)), nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		if _, err := r.run(ctx, "checkout", branch); err != nil {
+			return "", fmt.Errorf("checking out %s: %w", branch, err)
+		}
+		return fmt.Sprintf("checked out %s (left detached commit %s)", branch, shortSHA(head)), nil
+	default:
+		return "", fmt.Errorf("comparing HEAD with %s: %w", branch, err)
+	}
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
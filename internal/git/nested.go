@@ -0,0 +1,53 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// NestedRepos returns the directories under root/dir that contain their own
+// .git, such as vendored checkouts, as slash-separated paths relative to
+// root. It doesn't descend into them, and returns nil when dir is missing or
+// is itself a symlink.
+func NestedRepos(root, dir string) ([]string, error) {
+	start := filepath.Join(root, dir)
+	fi, err := os.Lstat(start)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !fi.IsDir()) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s for nested repos: %w", dir, err)
+	}
+
+	var nested []string
+	err = filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if path == start {
+			return nil
+		}
+		if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err //nolint:wrapcheck // wrapped below
+			}
+			nested = append(nested, filepath.ToSlash(rel))
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s for nested repos: %w", dir, err)
+	}
+	return nested, nil
+}
@@ -0,0 +1,38 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedRepos(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{
+		"specs/vendor/lib/.git",       // nested repo (directory)
+		"specs/vendor/lib/inner/.git", // inside a nested repo: not reported
+		"specs/docs",                  // plain directory
+		".git",                        // the root's own repo
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, d), 0o750))
+	}
+	// A worktree or submodule checkout has a .git file instead of a directory.
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "specs", "sub"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "specs", "sub", ".git"), []byte("gitdir: x\n"), 0o600))
+
+	nested, err := NestedRepos(root, "specs")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"specs/vendor/lib", "specs/sub"}, nested)
+
+	nested, err = NestedRepos(root, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, nested)
+
+	require.NoError(t, os.Symlink(filepath.Join(root, "specs"), filepath.Join(root, "linked")))
+	nested, err = NestedRepos(root, "linked")
+	require.NoError(t, err)
+	assert.Nil(t, nested, "symlinked dirs are staged as links, not scanned")
+}
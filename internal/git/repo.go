@@ -0,0 +1,33 @@
+package git
+
+import (
+	"context"
+	"fmt"
+)
+
+// Repo runs git commands in one repository. The package-level functions
+// run in the process's working directory; a Repo names its directory, so
+// callers juggling several repos, and tests running in parallel, don't
+// depend on os.Chdir.
+type Repo struct {
+	Dir string // where git runs: the repo root or any directory inside it; empty = the working directory
+}
+
+// At returns a Repo that runs git in dir.
+func At(dir string) *Repo {
+	return &Repo{Dir: dir}
+}
+
+// cwd is the Repo the package-level functions use.
+var cwd = &Repo{}
+
+// run runs a git subcommand in r.Dir and returns its stdout.
+func (r *Repo) run(ctx context.Context, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("git: no subcommand specified")
+	}
+	if r.Dir == "" {
+		return runGit(ctx, args[0], args)
+	}
+	return runGit(ctx, args[0], append([]string{"-C", r.Dir}, args...))
+}
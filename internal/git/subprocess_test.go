@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -14,44 +15,47 @@ import (
 	"github.com/benwilkes9/ralph-cli/internal/testutil"
 )
 
-// NOTE: Do NOT add t.Parallel() to any test in this file.
-// os.Chdir is process-global; parallel tests would race on the working directory.
+// Tests run git through At(dir) so they can run in parallel. Only TestHead
+// changes directory, to cover the package-level functions; it doesn't call
+// t.Parallel, so it finishes before the parallel tests start.
 
-// TestHead verifies that Head() returns a 40-character hex SHA and errors
+// TestHead verifies that Head(context.Background()) returns a 40-character hex SHA and errors
 // when called outside a git repo.
 func TestHead(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
 	testutil.Chdir(t, clone)
 
-	head, err := Head()
+	head, err := Head(context.Background())
 	require.NoError(t, err)
 	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{40}$`), head)
 }
 
 func TestHead_NotARepo(t *testing.T) {
-	testutil.Chdir(t, t.TempDir())
+	t.Parallel()
 
-	_, err := Head()
+	_, err := At(t.TempDir()).Head(context.Background())
 	assert.Error(t, err)
 }
 
-// TestBranch verifies Branch() returns the current branch name.
+// TestBranch verifies Branch returns the current branch name.
 func TestBranch(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
-	branch, err := Branch()
+	branch, err := r.Branch(context.Background())
 	require.NoError(t, err)
 	assert.Equal(t, "main", branch)
 }
 
 func TestBranch_FeatureBranch(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
 	testutil.RunGit(t, clone, "checkout", "-b", "feature-test")
 
-	branch, err := Branch()
+	branch, err := r.Branch(context.Background())
 	require.NoError(t, err)
 	assert.Equal(t, "feature-test", branch)
 }
@@ -59,13 +63,14 @@ func TestBranch_FeatureBranch(t *testing.T) {
 // TestAddAndCommit verifies that Add + Commit creates a commit visible in git log.
 func TestAddAndCommit(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
 	f := filepath.Join(clone, "hello.txt")
 	require.NoError(t, os.WriteFile(f, []byte("hello"), 0o600))
 
-	require.NoError(t, Add("hello.txt"))
-	require.NoError(t, Commit("test: add hello"))
+	require.NoError(t, r.Add(context.Background(), "hello.txt"))
+	require.NoError(t, r.Commit(context.Background(), "test: add hello"))
 
 	cmd := exec.CommandContext(context.Background(), "git", "log", "--oneline") //nolint:gosec // test helper
 	cmd.Dir = clone
@@ -77,23 +82,25 @@ func TestAddAndCommit(t *testing.T) {
 // TestAdd_NonExistentPath verifies that staging a missing file returns an error.
 func TestAdd_NonExistentPath(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
-	err := Add("nonexistent.txt")
+	err := r.Add(context.Background(), "nonexistent.txt")
 	assert.Error(t, err)
 }
 
 // TestPush verifies that Push sends a committed change to the bare remote.
 func TestPush(t *testing.T) {
 	bare, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
 	f := filepath.Join(clone, "pushed.txt")
 	require.NoError(t, os.WriteFile(f, []byte("pushed"), 0o600))
 	testutil.RunGit(t, clone, "add", "pushed.txt")
 	testutil.RunGit(t, clone, "commit", "-m", "push test")
 
-	require.NoError(t, Push("main"))
+	require.NoError(t, r.Push(context.Background(), "main"))
 
 	// The commit should now be visible in the bare remote's log.
 	cmd := exec.CommandContext(context.Background(), "git", "log", "--oneline") //nolint:gosec // test helper
@@ -106,14 +113,15 @@ func TestPush(t *testing.T) {
 // TestPushSetUpstream verifies that a local-only branch is created on the remote.
 func TestPushSetUpstream(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
 	testutil.RunGit(t, clone, "checkout", "-b", "upstream-test")
 
-	require.NoError(t, PushSetUpstream("upstream-test"))
+	require.NoError(t, r.PushSetUpstream(context.Background(), "upstream-test"))
 
 	// The branch should now exist on origin as seen from the clone.
-	exists, err := BranchExistsOnRemote("upstream-test")
+	exists, err := r.BranchExistsOnRemote(context.Background(), "upstream-test")
 	require.NoError(t, err)
 	assert.True(t, exists)
 }
@@ -121,10 +129,11 @@ func TestPushSetUpstream(t *testing.T) {
 // TestPullRebase verifies that a commit added to the bare remote is pulled into the clone.
 func TestPullRebase(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
 	// Discover the remote URL of the current clone.
-	remoteURL, err := RemoteURL("origin")
+	remoteURL, err := r.RemoteURL(context.Background(), "origin")
 	require.NoError(t, err)
 
 	// Push a new commit via a second clone of the same remote.
@@ -140,7 +149,7 @@ func TestPullRebase(t *testing.T) {
 	testutil.RunGit(t, clone2, "commit", "-m", "remote commit")
 	testutil.RunGit(t, clone2, "push", "origin", "main")
 
-	require.NoError(t, PullRebase("main"))
+	require.NoError(t, r.PullRebase(context.Background(), "main"))
 
 	// Verify the pulled commit is visible in the first clone.
 	cmd := exec.CommandContext(context.Background(), "git", "log", "--oneline") //nolint:gosec // test helper
@@ -153,18 +162,20 @@ func TestPullRebase(t *testing.T) {
 // TestRemoteURL verifies RemoteURL returns the origin URL and errors for unknown remotes.
 func TestRemoteURL(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
-	url, err := RemoteURL("origin")
+	url, err := r.RemoteURL(context.Background(), "origin")
 	require.NoError(t, err)
 	assert.NotEmpty(t, url)
 }
 
 func TestRemoteURL_Unknown(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
-	_, err := RemoteURL("nonexistent")
+	_, err := r.RemoteURL(context.Background(), "nonexistent")
 	assert.Error(t, err)
 }
 
@@ -173,9 +184,10 @@ func TestRepoRoot(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
 	sub := filepath.Join(clone, "a", "b")
 	require.NoError(t, os.MkdirAll(sub, 0o750))
-	testutil.Chdir(t, sub)
+	t.Parallel()
+	r := At(sub)
 
-	root, err := RepoRoot()
+	root, err := r.RepoRoot(context.Background())
 	require.NoError(t, err)
 
 	// Resolve symlinks so temp dir path comparisons are reliable.
@@ -186,16 +198,33 @@ func TestRepoRoot(t *testing.T) {
 	assert.Equal(t, wantEval, gotEval)
 }
 
+// TestRepoRoot_ThroughSymlink verifies the root is resolved when the repo is
+// entered via a symlink.
+func TestRepoRoot_ThroughSymlink(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(clone, link))
+	t.Parallel()
+	r := At(link)
+
+	root, err := r.RepoRoot(context.Background())
+	require.NoError(t, err)
+	want, err := filepath.EvalSymlinks(clone)
+	require.NoError(t, err)
+	assert.Equal(t, want, root)
+}
+
 // TestIsTracked verifies IsTracked behaviour for untracked, committed, and missing paths.
 func TestIsTracked(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
 	// Untracked file.
 	f := filepath.Join(clone, "untracked.txt")
 	require.NoError(t, os.WriteFile(f, []byte("x"), 0o600))
 
-	tracked, err := IsTracked("untracked.txt")
+	tracked, err := r.IsTracked(context.Background(), "untracked.txt")
 	require.NoError(t, err)
 	assert.False(t, tracked)
 
@@ -203,12 +232,12 @@ func TestIsTracked(t *testing.T) {
 	testutil.RunGit(t, clone, "add", "untracked.txt")
 	testutil.RunGit(t, clone, "commit", "-m", "track it")
 
-	tracked, err = IsTracked("untracked.txt")
+	tracked, err = r.IsTracked(context.Background(), "untracked.txt")
 	require.NoError(t, err)
 	assert.True(t, tracked)
 
 	// Non-existent path.
-	tracked, err = IsTracked("does-not-exist.txt")
+	tracked, err = r.IsTracked(context.Background(), "does-not-exist.txt")
 	require.NoError(t, err)
 	assert.False(t, tracked)
 }
@@ -216,15 +245,16 @@ func TestIsTracked(t *testing.T) {
 // TestBranchExistsOnRemote verifies BranchExistsOnRemote for existing and local-only branches.
 func TestBranchExistsOnRemote(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
-	exists, err := BranchExistsOnRemote("main")
+	exists, err := r.BranchExistsOnRemote(context.Background(), "main")
 	require.NoError(t, err)
 	assert.True(t, exists)
 
 	testutil.RunGit(t, clone, "checkout", "-b", "local-only")
 
-	exists, err = BranchExistsOnRemote("local-only")
+	exists, err = r.BranchExistsOnRemote(context.Background(), "local-only")
 	require.NoError(t, err)
 	assert.False(t, exists)
 }
@@ -232,9 +262,10 @@ func TestBranchExistsOnRemote(t *testing.T) {
 // TestDiffFromRemote verifies DiffFromRemote is empty when synced and non-empty after a local commit.
 func TestDiffFromRemote(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
+	t.Parallel()
+	r := At(clone)
 
-	diff, err := DiffFromRemote("main", ".")
+	diff, err := r.DiffFromRemote(context.Background(), "main", ".")
 	require.NoError(t, err)
 	assert.Empty(t, diff)
 
@@ -244,7 +275,239 @@ func TestDiffFromRemote(t *testing.T) {
 	testutil.RunGit(t, clone, "add", "local.txt")
 	testutil.RunGit(t, clone, "commit", "-m", "local change")
 
-	diff, err = DiffFromRemote("main", ".")
+	diff, err = r.DiffFromRemote(context.Background(), "main", ".")
 	require.NoError(t, err)
 	assert.NotEmpty(t, diff)
 }
+
+// TestWithTimeouts verifies that a context-supplied timeout bounds each git
+// subprocess and surfaces a descriptive error.
+func TestWithTimeouts(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	t.Parallel()
+	r := At(clone)
+
+	ctx := WithTimeouts(context.Background(), Timeouts{Local: time.Nanosecond})
+	_, err := r.Head(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+
+	// Zero values fall back to defaults.
+	ctx = WithTimeouts(context.Background(), Timeouts{})
+	_, err = r.Head(ctx)
+	require.NoError(t, err)
+}
+
+func TestTimeoutFor_HookCommands(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, DefaultTimeout, timeoutFor(ctx, "status"))
+	assert.Equal(t, DefaultNetworkTimeout, timeoutFor(ctx, "fetch"))
+	assert.Equal(t, DefaultHookTimeout, timeoutFor(ctx, "commit"))
+	assert.Equal(t, DefaultHookTimeout, timeoutFor(ctx, "push"))
+
+	ctx = WithTimeouts(ctx, Timeouts{Local: time.Second, Network: time.Hour, Hooks: 20 * time.Minute})
+	assert.Equal(t, 20*time.Minute, timeoutFor(ctx, "commit"))
+	assert.Equal(t, time.Hour, timeoutFor(ctx, "push"), "a longer network limit still applies")
+}
+
+// TestCancelledContext verifies that git commands honour cancellation.
+func TestCancelledContext(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	t.Parallel()
+	r := At(clone)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := r.Head(ctx)
+	assert.Error(t, err)
+}
+
+func TestLocalBranchesAndForkDistance(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	t.Parallel()
+	r := At(clone)
+	ctx := context.Background()
+
+	testutil.RunGit(t, clone, "checkout", "-b", "feature-a")
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "a1")
+	testutil.RunGit(t, clone, "checkout", "-b", "feature-b")
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "b1")
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "b2")
+
+	branches, err := r.LocalBranches(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, branches, "feature-a")
+	assert.Contains(t, branches, "feature-b")
+
+	dist, err := r.ForkDistance(ctx, "feature-a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, dist)
+
+	dist, err = r.ForkDistance(ctx, "main")
+	require.NoError(t, err)
+	assert.Equal(t, 3, dist)
+}
+
+func TestCommitSubjectsAndAmend(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	t.Parallel()
+	r := At(clone)
+	ctx := context.Background()
+
+	base, err := r.Head(ctx)
+	require.NoError(t, err)
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "first")
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "second\n\nbody")
+
+	subjects, err := r.CommitSubjects(ctx, base, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second", "first"}, subjects)
+
+	msg, err := r.LastCommitMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "second\n\nbody", msg)
+
+	require.NoError(t, r.AmendCommitMessage(ctx, "[T1] "+msg))
+	subjects, err = r.CommitSubjects(ctx, base, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, "[T1] second", subjects[0])
+
+	n, err := r.UnpushedCount(ctx, "main")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestChangedFiles(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	t.Parallel()
+	r := At(clone)
+	ctx := context.Background()
+
+	base, err := r.Head(ctx)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(clone, "infra"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "infra", "main.tf"), []byte("x\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "my notes.md"), []byte("x\n"), 0o600))
+	testutil.RunGit(t, clone, "add", ".")
+	testutil.RunGit(t, clone, "commit", "-m", "add files")
+
+	files, err := r.ChangedFiles(ctx, base, "HEAD")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"infra/main.tf", "my notes.md"}, files)
+
+	files, err = r.ChangedFiles(ctx, "HEAD", "HEAD")
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestDiff(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	t.Parallel()
+	r := At(clone)
+	ctx := context.Background()
+
+	base, err := r.Head(ctx)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "a.txt"), []byte("one\n"), 0o600))
+	testutil.RunGit(t, clone, "add", ".")
+	testutil.RunGit(t, clone, "commit", "-m", "add a")
+
+	patch, err := r.Diff(ctx, base, "HEAD")
+	require.NoError(t, err)
+	assert.Contains(t, patch, "diff --git a/a.txt b/a.txt")
+	assert.Contains(t, patch, "+one\n")
+}
+
+func TestCheckHealthAndRepair_InterruptedRebase(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	t.Parallel()
+	r := At(clone)
+	ctx := context.Background()
+
+	file := filepath.Join(clone, "f.txt")
+	require.NoError(t, os.WriteFile(file, []byte("base\n"), 0o600))
+	testutil.RunGit(t, clone, "add", "f.txt")
+	testutil.RunGit(t, clone, "commit", "-m", "base")
+	testutil.RunGit(t, clone, "checkout", "-b", "side")
+	require.NoError(t, os.WriteFile(file, []byte("side\n"), 0o600))
+	testutil.RunGit(t, clone, "commit", "-am", "side")
+	testutil.RunGit(t, clone, "checkout", "main")
+	require.NoError(t, os.WriteFile(file, []byte("main\n"), 0o600))
+	testutil.RunGit(t, clone, "commit", "-am", "main")
+	mainHead, err := r.Head(ctx)
+	require.NoError(t, err)
+
+	cmd := exec.Command("git", "rebase", "side")
+	cmd.Dir = clone
+	require.Error(t, cmd.Run(), "rebase should stop on the conflict")
+
+	h, err := r.CheckHealth(ctx)
+	require.NoError(t, err)
+	assert.True(t, h.Rebasing)
+	assert.True(t, h.Detached)
+	assert.Equal(t, []string{"f.txt"}, h.Unmerged)
+	assert.False(t, h.OK("main"))
+
+	actions, err := r.Repair(ctx, h, "main")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aborted in-progress rebase"}, actions)
+
+	h, err = r.CheckHealth(ctx)
+	require.NoError(t, err)
+	assert.True(t, h.OK("main"))
+	head, err := r.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, mainHead, head)
+}
+
+func TestRepair_DetachedAheadKeepsCommits(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	t.Parallel()
+	r := At(clone)
+	ctx := context.Background()
+
+	testutil.RunGit(t, clone, "checkout", "--detach")
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "made while detached")
+	detachedHead, err := r.Head(ctx)
+	require.NoError(t, err)
+
+	h, err := r.CheckHealth(ctx)
+	require.NoError(t, err)
+	require.True(t, h.Detached)
+
+	actions, err := r.Repair(ctx, h, "main")
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Contains(t, actions[0], "re-attached main")
+
+	h, err = r.CheckHealth(ctx)
+	require.NoError(t, err)
+	assert.True(t, h.OK("main"))
+	head, err := r.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, detachedHead, head, "commits made while detached must stay on the branch")
+}
+
+func TestRepair_DetachedBehindChecksOutBranch(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	t.Parallel()
+	r := At(clone)
+	ctx := context.Background()
+
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "second")
+	mainHead, err := r.Head(ctx)
+	require.NoError(t, err)
+	testutil.RunGit(t, clone, "checkout", "--detach", "HEAD~1")
+	testutil.RunGit(t, clone, "commit", "--allow-empty", "-m", "diverged")
+
+	h, err := r.CheckHealth(ctx)
+	require.NoError(t, err)
+	actions, err := r.Repair(ctx, h, "main")
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Contains(t, actions[0], "left detached commit")
+
+	head, err := r.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, mainHead, head)
+}
@@ -0,0 +1,87 @@
+package git
+
+import (
+	"context"
+	"time"
+)
+
+// Default per-command timeouts, used when the context carries no overrides.
+// Commands that run the repo's hooks (commit, merge, push, ...) may run a
+// whole test suite in a pre-commit or pre-push hook, so neither the local
+// nor the network limit applies to them: they get DefaultHookTimeout, or
+// git.hook_timeout when the config sets it.
+const (
+	DefaultTimeout        = 30 * time.Second
+	DefaultNetworkTimeout = 2 * time.Minute
+	DefaultHookTimeout    = 15 * time.Minute
+)
+
+// Timeouts bounds how long a single git subprocess may run. Network commands
+// (push, pull, fetch, ls-remote) get their own, usually longer, limit, and
+// commands that run hooks a longer one still.
+type Timeouts struct {
+	Local   time.Duration
+	Network time.Duration
+	Hooks   time.Duration
+}
+
+type timeoutsKey struct{}
+
+// WithTimeouts returns a context whose git commands use t instead of the
+// defaults. Zero fields fall back to the defaults.
+func WithTimeouts(ctx context.Context, t Timeouts) context.Context {
+	return context.WithValue(ctx, timeoutsKey{}, t)
+}
+
+// networkCommands are subcommands that talk to a remote.
+var networkCommands = map[string]bool{
+	"push":      true,
+	"pull":      true,
+	"fetch":     true,
+	"ls-remote": true,
+	"clone":     true,
+}
+
+// hookCommands are subcommands that run repo hooks, such as pre-commit,
+// commit-msg or pre-push.
+var hookCommands = map[string]bool{
+	"commit": true,
+	"merge":  true,
+	"am":     true,
+	"rebase": true,
+	"push":   true,
+}
+
+func timeoutFor(ctx context.Context, subcommand string) time.Duration {
+	if hookCommands[subcommand] {
+		return hookTimeout(ctx, CommandTimeout(ctx, networkCommands[subcommand]))
+	}
+	return CommandTimeout(ctx, networkCommands[subcommand])
+}
+
+// hookTimeout returns the hook limit under ctx, or limit if that is longer.
+func hookTimeout(ctx context.Context, limit time.Duration) time.Duration {
+	t, _ := ctx.Value(timeoutsKey{}).(Timeouts) //nolint:errcheck // zero value is a valid fallback
+	hooks := t.Hooks
+	if hooks <= 0 {
+		hooks = DefaultHookTimeout
+	}
+	return max(hooks, limit)
+}
+
+// CommandTimeout returns how long one version-control subprocess may run
+// under ctx: the network limit when it talks to a remote, else the local one.
+// Other VCS backends use it so the same config bounds their commands too.
+func CommandTimeout(ctx context.Context, network bool) time.Duration {
+	t, _ := ctx.Value(timeoutsKey{}).(Timeouts) //nolint:errcheck // zero value is a valid fallback
+	if network {
+		if t.Network > 0 {
+			return t.Network
+		}
+		return DefaultNetworkTimeout
+	}
+	if t.Local > 0 {
+		return t.Local
+	}
+	return DefaultTimeout
+}
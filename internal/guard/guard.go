@@ -0,0 +1,96 @@
+// Package guard implements Claude Code PreToolUse hooks that block
+// destructive shell commands before they run inside the container. There is
+// no PostToolUse hook: it runs once the command has already executed, so it
+// can't stop anything, and blocked attempts are already surfaced by the
+// stream formatter from the PreToolUse answer.
+package guard
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// BlockedPrefix starts every message emitted for a blocked tool call. The
+// stream formatter keys on it to surface blocked attempts.
+const BlockedPrefix = "Blocked by ralph guardrail"
+
+// HookCommand is the command Claude Code runs for each Bash tool call.
+const HookCommand = "ralph _guard"
+
+// DefaultBlockedCommands are the patterns applied when the config does not
+// set guardrails.blocked_commands.
+var DefaultBlockedCommands = []string{
+	// recursive rm aimed at /, ~, $HOME, ., .. or *
+	`\brm\s+(?:-\S+\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\s+(?:-\S+\s+)*(?:/|~|\$HOME|\.\.?|\*)(?:/\*?)?(?:\s|;|&|$)`,
+	// force pushes rewrite shared history
+	`\bgit\s+push\b.*(\s--force(-with-lease)?\b|\s-f\b)`,
+	// piping a download straight into a shell
+	`\b(curl|wget)\b[^|]*\|\s*(sudo\s+)?(ba|z|da)?sh\b`,
+}
+
+// Unavailable is the reason given when the guardrails can't be checked, e.g.
+// because the config doesn't load. The hook then blocks every command, so a
+// broken config never silently turns the guardrails off.
+func Unavailable(err error) string {
+	return fmt.Sprintf("%s: guardrails could not be checked, so the command was not run: %v", BlockedPrefix, err)
+}
+
+// Compile parses patterns into regexps, reporting the first invalid one.
+func Compile(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid guardrail pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// hookInput is the subset of the PreToolUse payload we inspect.
+type hookInput struct {
+	ToolName  string `json:"tool_name"`
+	ToolInput struct {
+		Command string `json:"command"`
+	} `json:"tool_input"`
+}
+
+// Check reads a PreToolUse payload from r and returns a non-empty reason if
+// the Bash command matches any pattern.
+func Check(r io.Reader, patterns []*regexp.Regexp) (string, error) {
+	var in hookInput
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return "", fmt.Errorf("decoding hook input: %w", err)
+	}
+	if in.ToolName != "Bash" || in.ToolInput.Command == "" {
+		return "", nil
+	}
+	for _, re := range patterns {
+		if re.MatchString(in.ToolInput.Command) {
+			return fmt.Sprintf("%s: command matches %q", BlockedPrefix, re.String()), nil
+		}
+	}
+	return "", nil
+}
+
+// Settings returns a Claude Code settings JSON document that routes every
+// Bash tool call through HookCommand. Pass it to claude via --settings.
+func Settings() string {
+	settings := map[string]any{
+		"hooks": map[string]any{
+			"PreToolUse": []any{
+				map[string]any{
+					"matcher": "Bash",
+					"hooks": []any{
+						map[string]any{"type": "command", "command": HookCommand},
+					},
+				},
+			},
+		},
+	}
+	data, _ := json.Marshal(settings) //nolint:errcheck,errchkjson // static map of strings cannot fail
+	return string(data)
+}
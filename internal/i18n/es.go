@@ -0,0 +1,105 @@
+package i18n
+
+// spanish is the Spanish catalog. Labels padded into columns (the loop
+// header, the job summary and ralph status) are kept short enough to fit.
+var spanish = map[string]string{
+	// Loop header.
+	"Mode":          "Modo",
+	"Prompt":        "Prompt",
+	"Branch":        "Rama",
+	"Variant":       "Variante",
+	"Max":           "Máx",
+	"Offline":       "Offline",
+	"Chaos":         "Caos",
+	"Hooks":         "Hooks",
+	"skipped: %s":   "omitidos: %s",
+	"Cost cap":      "Tope",
+	"%d iterations": "%d iteraciones",
+	"no pushes or GitHub reporting; ralph sync pushes later": "sin pushes ni informes a GitHub; ralph sync hace push después",
+	"%.0f%% failure rate (seed %d)":                          "%.0f%% de fallos (semilla %d)",
+
+	// Loop iterations.
+	"%s tokens":              "%s tokens",
+	"(%d%% of context)":      "(%d%% del contexto)",
+	"%s / %s context (%d%%)": "%s / %s de contexto (%d%%)",
+	"cache %.0f%%":           "caché %.0f%%",
+	"(%s task)":              "(tarea %s)",
+	"%d oversized event(s) skipped (over %d MB); see the raw log": "%d evento(s) demasiado grandes omitidos (más de %d MB); consulta el log sin procesar",
+	"raw log: %s":                   "log sin procesar: %s",
+	"tests:":                        "tests:",
+	"▲ failures up from %d":         "▲ los fallos suben desde %d",
+	"No new commits this iteration": "Ningún commit nuevo en esta iteración",
+	"(stale: %d/%d)":                "(estancado: %d/%d)",
+	"Stale loop detected:":          "Bucle estancado:",
+	"%d consecutive iterations with no commits. Stopping.":                 "%d iteraciones seguidas sin commits. Deteniendo.",
+	"Plan barely changed this iteration (%d line(s))":                      "El plan apenas cambió en esta iteración (%d línea(s))",
+	"(converging: %d/%d)":                                                  "(convergiendo: %d/%d)",
+	"Plan converged:":                                                      "Plan convergido:",
+	"%d consecutive iterations without meaningful plan changes. Stopping.": "%d iteraciones seguidas sin cambios relevantes en el plan. Deteniendo.",
+	"Reached max iterations: %d":                                           "Máximo de iteraciones alcanzado: %d",
+	"Reached cost cap: %s spent of %s":                                     "Tope de coste alcanzado: %s gastados de %s",
+	"Offline: commits kept local; run \"ralph sync\" to push them.":        "Offline: los commits se quedan en local; ejecuta \"ralph sync\" para hacer push.",
+	"Failed to push. Creating remote branch...":                            "Falló el push. Creando la rama remota...",
+	"Push failed: %s":                                                      "Falló el push: %s",
+	"pushed %d commit(s)":                                                  "push de %d commit(s) hecho",
+	"push queued: %d commit(s) waiting":                                    "push en cola: %d commit(s) en espera",
+	"(next push in %s or after %d more commit(s))":                         "(próximo push en %s o tras %d commit(s) más)",
+	"(next push in %s)":                                                    "(próximo push en %s)",
+	"(next push after %d more commit(s))":                                  "(próximo push tras %d commit(s) más)",
+	"Pushing %d queued commit(s)...":                                       "Haciendo push de %d commit(s) en cola...",
+	"%d queued commit(s) not pushed; run \"ralph sync\" to push them.":     "%d commit(s) en cola sin push; ejecuta \"ralph sync\" para hacer push.",
+
+	// Dashboard (--tui).
+	"Iteration":         "Iteración",
+	"Task":              "Tarea",
+	"Context":           "Contexto",
+	"Cost":              "Coste",
+	"Stale":             "Estancado",
+	"(last %s)":         "(última %s)",
+	"of %s":             "de %s",
+	"Recent tool calls": "Herramientas recientes",
+	"Run %s: %d task(s) completed, %d commit(s)": "Ejecución %s: %d tarea(s) completadas, %d commit(s)",
+
+	// Job summary.
+	"JOB SUMMARY":          "RESUMEN DEL TRABAJO",
+	"Outcome":              "Resultado",
+	"Iterations":           "Iteraciones",
+	"Wall time":            "Duración",
+	"Peak context":         "Contexto máximo",
+	"Subagent tokens":      "Tokens subagente",
+	"Input tokens":         "Tokens entrada",
+	"%s cached / %s fresh": "%s en caché / %s nuevos",
+	"Cache hit rate":       "Aciertos caché",
+	"Total cost":           "Coste total",
+	"Tasks completed":      "Tareas hechas",
+	"+%d (%d/%d done)":     "+%d (%d/%d hechas)",
+
+	// ralph status.
+	"Tasks  %d/%d complete (%d%%)":       "Tareas  %d/%d completadas (%d%%)",
+	"Last run":                           "Últ. ejec.",
+	"%s (%s, %d iterations)":             "%s (%s, %d iteraciones)",
+	"Tests":                              "Tests",
+	"▲ failures %d → %d":                 "▲ fallos %d → %d",
+	"Total cost %s across %d iterations": "Coste total %s en %d iteraciones",
+	"No runs recorded":                   "No hay ejecuciones registradas",
+	"hooks skipped: %s":                  "hooks omitidos: %s",
+	"By phase":                           "Por fase",
+	"By model":                           "Por modelo",
+	"Subagents":                          "Subagentes",
+	"%.0f%% of tokens":                   "%.0f%% de los tokens",
+	"all branches":                       "todas las ramas",
+	"No plans in %s":                     "No hay planes en %s",
+	"%d branch(es): %d/%d tasks complete (%d%%), %s across %d run(s)": "%d rama(s): %d/%d tareas completadas (%d%%), %s en %d ejecución(es)",
+	"%d run(s)":            "%d ejecución(es)",
+	"last %s %s (%s)":      "últ. %s %s (%s)",
+	"✓ all tasks complete": "✓ todas las tareas completadas",
+
+	// Commands.
+	"Previous answers from .ralph/config.yaml are preselected.":       "Las respuestas anteriores de .ralph/config.yaml están preseleccionadas.",
+	"Offline: \"ralph sync\" opens the plan pull request for review.": "Offline: \"ralph sync\" abre la pull request del plan para revisión.",
+	"Milestone:": "Hito:",
+	"✓ All %d tasks complete; nothing to build.": "✓ Las %d tareas están completas; no hay nada que construir.",
+	"Use --force to run anyway.":                 "Usa --force para ejecutar de todos modos.",
+	"Tests unchanged; nothing to commit.":        "Tests sin cambios; nada que commitear.",
+	"Specs unchanged; nothing to commit.":        "Specs sin cambios; nada que commitear.",
+}
@@ -0,0 +1,84 @@
+// Package i18n translates ralph's terminal output. Messages are looked up by
+// their English text, so anything missing from a catalog is shown in English.
+package i18n
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Locales with a message catalog.
+const (
+	English = "en"
+	Spanish = "es"
+)
+
+// Supported lists the locales ralph can show its output in.
+var Supported = []string{English, Spanish}
+
+// catalogs maps a locale to its translations, keyed by the English message.
+// English needs none.
+var catalogs = map[string]map[string]string{
+	Spanish: spanish,
+}
+
+var current atomic.Value // string: the locale set with Set
+
+// Set selects the locale output is translated to. Unsupported locales fall
+// back to English.
+func Set(locale string) {
+	if !slices.Contains(Supported, locale) {
+		locale = English
+	}
+	current.Store(locale)
+}
+
+// Current returns the locale set with Set, English by default.
+func Current() string {
+	if l, ok := current.Load().(string); ok {
+		return l
+	}
+	return English
+}
+
+// T returns msg in the current locale.
+func T(msg string) string {
+	if t, ok := catalogs[Current()][msg]; ok {
+		return t
+	}
+	return msg
+}
+
+// Tf formats the current locale's translation of format with args.
+func Tf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Valid reports whether locale is empty (unset) or supported.
+func Valid(locale string) bool {
+	return locale == "" || slices.Contains(Supported, locale)
+}
+
+// Resolve picks the locale to use: RALPH_LOCALE (set for the in-container
+// loop), then configured, then the first of LC_ALL, LC_MESSAGES and LANG
+// that is set. POSIX values such as "es_ES.UTF-8" are reduced to their
+// language; anything unsupported is English.
+func Resolve(configured string, getenv func(string) string) string {
+	candidates := []string{getenv("RALPH_LOCALE"), configured, getenv("LC_ALL"), getenv("LC_MESSAGES"), getenv("LANG")}
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		lang := strings.ToLower(c)
+		if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+			lang = lang[:i]
+		}
+		if slices.Contains(Supported, lang) {
+			return lang
+		}
+		return English
+	}
+	return English
+}
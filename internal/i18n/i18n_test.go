@@ -0,0 +1,46 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	assert.Equal(t, English, Resolve("", env(nil)))
+	assert.Equal(t, Spanish, Resolve("", env(map[string]string{"LANG": "es_ES.UTF-8"})))
+	assert.Equal(t, English, Resolve("", env(map[string]string{"LC_ALL": "C", "LANG": "es_ES.UTF-8"})), "LC_ALL overrides LANG")
+	assert.Equal(t, English, Resolve("", env(map[string]string{"LANG": "fr_FR.UTF-8"})), "unsupported languages are English")
+	assert.Equal(t, English, Resolve("en", env(map[string]string{"LANG": "es_MX"})), "the config overrides the environment")
+	assert.Equal(t, Spanish, Resolve("en", env(map[string]string{"RALPH_LOCALE": "es"})), "the forwarded locale wins")
+}
+
+func TestT(t *testing.T) {
+	t.Cleanup(func() { Set(English) })
+
+	assert.Equal(t, "Reached max iterations: 3", Tf("Reached max iterations: %d", 3))
+
+	Set(Spanish)
+	assert.Equal(t, Spanish, Current())
+	assert.Equal(t, "Máximo de iteraciones alcanzado: 3", Tf("Reached max iterations: %d", 3))
+	assert.Equal(t, "not in the catalog", T("not in the catalog"))
+
+	Set("fr")
+	assert.Equal(t, English, Current())
+}
+
+// verb matches a fmt verb, so translations can be checked to take the
+// same arguments as their English message.
+var verb = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestCatalogsKeepFormatVerbs(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for msg, translated := range catalog {
+			assert.Equal(t, verb.FindAllString(msg, -1), verb.FindAllString(translated, -1), "%s: %q", locale, msg)
+		}
+	}
+}
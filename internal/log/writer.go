@@ -1,33 +1,92 @@
 package logfile
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
 )
 
+// TimeLayout is the timestamp a log file is named by. Logs created in the
+// same second get a "-2", "-3", ... suffix after it.
+const TimeLayout = "20060102-150405"
+
+// maxSuffix bounds the search for a free name within one second.
+const maxSuffix = 1000
+
+// File is an open log file.
+type File interface {
+	io.WriteCloser
+	Name() string
+}
+
+// FS is the filesystem logs are created in.
+type FS interface {
+	MkdirAll(path string, perm os.FileMode) error
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+}
+
+// OSFS is the real filesystem.
+type OSFS struct{}
+
+// MkdirAll calls os.MkdirAll.
+func (OSFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm) //nolint:wrapcheck // thin os adapter
+}
+
+// OpenFile calls os.OpenFile.
+func (OSFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm) //nolint:gosec,wrapcheck // thin os adapter; name is built by New
+}
+
+// Options overrides where a Writer gets the time and creates its file.
+type Options struct {
+	Clock clock.Clock // nil = wall clock
+	FS    FS          // nil = OSFS
+}
+
 // Writer tees JSONL lines to a log file.
 type Writer struct {
-	file *os.File
+	file File
 }
 
 // New creates a new log writer under the given logs directory.
 // The filename is based on the current timestamp.
 func New(logsDir string) (*Writer, error) {
-	if err := os.MkdirAll(logsDir, 0o750); err != nil {
+	return NewWith(logsDir, Options{})
+}
+
+// NewWith is New with the clock and filesystem from opts. The file is
+// created exclusively, so a log started in the same second as another gets
+// a numbered name instead of overwriting it.
+func NewWith(logsDir string, opts Options) (*Writer, error) {
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = OSFS{}
+	}
+	if err := fsys.MkdirAll(logsDir, 0o750); err != nil {
 		return nil, fmt.Errorf("creating logs dir: %w", err)
 	}
-
-	name := time.Now().Format("20060102-150405") + ".jsonl"
-	path := filepath.Join(logsDir, name)
-
-	f, err := os.Create(path)
-	if err != nil {
-		return nil, fmt.Errorf("creating log file: %w", err)
+	stamp := clock.Or(opts.Clock).Now().Format(TimeLayout)
+	for n := 1; n <= maxSuffix; n++ {
+		name := stamp + ".jsonl"
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d.jsonl", stamp, n)
+		}
+		f, err := fsys.OpenFile(filepath.Join(logsDir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("creating log file: %w", err)
+		}
+		return &Writer{file: f}, nil
 	}
-
-	return &Writer{file: f}, nil
+	return nil, fmt.Errorf("creating log file: %d logs already named %s", maxSuffix, stamp)
 }
 
 // Path returns the path to the log file.
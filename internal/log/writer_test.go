@@ -1,13 +1,19 @@
 package logfile
 
 import (
+	"bytes"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
 )
 
 func TestNew_CreatesLogsDirectory(t *testing.T) {
@@ -67,3 +73,73 @@ func TestNew_UnwritableDirectory(t *testing.T) {
 	_, err := New(filepath.Join(locked, "logs"))
 	assert.Error(t, err)
 }
+
+func TestNewWith_SameSecondGetsSuffix(t *testing.T) {
+	dir := t.TempDir()
+	clk := &clock.Fake{T: time.Date(2026, 3, 1, 9, 30, 15, 0, time.Local)}
+
+	var names []string
+	for range 3 {
+		w, err := NewWith(dir, Options{Clock: clk})
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		names = append(names, filepath.Base(w.Path()))
+	}
+	assert.Equal(t, []string{"20260301-093015.jsonl", "20260301-093015-2.jsonl", "20260301-093015-3.jsonl"}, names)
+}
+
+// memFS is an in-memory FS for tests.
+type memFS struct {
+	dirs  []string
+	files map[string]*memFile
+}
+
+type memFile struct {
+	bytes.Buffer
+	name string
+}
+
+func (f *memFile) Name() string { return f.name }
+func (f *memFile) Close() error { return nil }
+
+func (m *memFS) MkdirAll(path string, _ os.FileMode) error {
+	m.dirs = append(m.dirs, path)
+	return nil
+}
+
+func (m *memFS) OpenFile(name string, flag int, _ os.FileMode) (File, error) {
+	if _, ok := m.files[name]; ok && flag&os.O_EXCL != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+	f := &memFile{name: name}
+	m.files[name] = f
+	return f, nil
+}
+
+func TestNewWith_InjectedFS(t *testing.T) {
+	m := &memFS{files: map[string]*memFile{}}
+	clk := &clock.Fake{T: time.Date(2026, 3, 1, 9, 30, 15, 0, time.Local)}
+
+	w, err := NewWith("logs", Options{Clock: clk, FS: m})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("line\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, []string{"logs"}, m.dirs)
+	path := filepath.Join("logs", "20260301-093015.jsonl")
+	require.Contains(t, m.files, path)
+	assert.Equal(t, "line\n", m.files[path].String())
+}
+
+type failFS struct{ memFS }
+
+func (failFS) OpenFile(string, int, os.FileMode) (File, error) {
+	return nil, errors.New("disk full")
+}
+
+func TestNewWith_OpenError(t *testing.T) {
+	_, err := NewWith("logs", Options{FS: &failFS{}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+}
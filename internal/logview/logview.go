@@ -0,0 +1,213 @@
+// Package logview lists, replays and follows the loop's iteration logs for
+// ralph logs.
+package logview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/status"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// Entry is one iteration log and the run that wrote it.
+type Entry struct {
+	Name      string
+	Path      string
+	Started   time.Time
+	Cost      float64
+	Run       int    // position in `ralph status --history`, 1 = oldest; 0 = not in state.json
+	Mode      string // the run's mode
+	Iteration int    // 1-based iteration within the run
+	Of        int    // iterations the run logged, retries counted once
+}
+
+// List returns the logs in logsDir, oldest first, linked to the runs in
+// state.json that recorded them. Costs come from status's log index, with
+// logs that reported none priced from rates.
+func List(logsDir string, runs []state.RunRecord, rates pricing.Table) ([]Entry, error) {
+	infos, err := status.ParseLogs(logsDir, rates)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped by status
+	}
+	type link struct{ run, iter int }
+	links := map[string]link{}
+	for i := range runs {
+		for j, f := range runs[i].LogFiles {
+			links[filepath.Base(f)] = link{run: i + 1, iter: runs[i].LogIteration(j)}
+		}
+	}
+	entries := make([]Entry, 0, len(infos))
+	for _, info := range infos {
+		e := Entry{Name: info.Name, Path: filepath.Join(logsDir, info.Name), Started: info.Time, Cost: info.Cost}
+		if l, ok := links[info.Name]; ok {
+			r := &runs[l.run-1]
+			e.Run, e.Mode, e.Iteration, e.Of = l.run, r.Mode, l.iter, r.LogIteration(len(r.LogFiles)-1)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Render writes one line per log: its name, start time, cost and the run
+// iteration it belongs to.
+//
+//nolint:errcheck // display output, best-effort writes
+func Render(w io.Writer, entries []Entry, cur pricing.Currency, theme *ui.Theme) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, theme.Muted.Render("No logs yet"))
+		return
+	}
+	for _, e := range entries {
+		run := theme.Muted.Render("not in state.json")
+		if e.Run > 0 {
+			run = fmt.Sprintf("run #%d %s · iteration %d/%d", e.Run, e.Mode, e.Iteration, e.Of)
+		}
+		fmt.Fprintf(w, "%-26s  %s  %s  %s\n", e.Name, e.Started.Format("2006-01-02 15:04:05"),
+			theme.Cost.Render(cur.Format(e.Cost, 4)), run)
+	}
+}
+
+// Find returns the path of the log arg names: a file name in entries, with
+// or without .jsonl, or a path to a log file. An empty arg is the newest log.
+func Find(entries []Entry, arg string) (string, error) {
+	if arg == "" {
+		if len(entries) == 0 {
+			return "", errors.New("no logs yet")
+		}
+		return entries[len(entries)-1].Path, nil
+	}
+	name := filepath.Base(arg)
+	if !strings.HasSuffix(name, ".jsonl") {
+		name += ".jsonl"
+	}
+	for _, e := range entries {
+		if e.Name == name {
+			return e.Path, nil
+		}
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return arg, nil
+	}
+	return "", fmt.Errorf("no log named %q; run ralph logs to list them", arg)
+}
+
+// Replay renders a finished log through the stream formatter, as it was
+// shown when the iteration ran.
+func Replay(path string, w io.Writer, theme *ui.Theme) error {
+	f, err := os.Open(path) //nolint:gosec // log file chosen by the user
+	if err != nil {
+		return fmt.Errorf("opening log: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // read-only
+	if _, err := stream.Process(f, w, theme); err != nil {
+		return fmt.Errorf("replaying %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// Follow renders the newest log in logsDir as the loop writes it, moving on
+// to each log a later iteration starts, until ctx is done. It waits for the
+// first log when there is none yet.
+func Follow(ctx context.Context, logsDir string, w io.Writer, theme *ui.Theme, poll time.Duration) error {
+	path := ""
+	for {
+		next := newest(logsDir)
+		if next == path {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(poll):
+				continue
+			}
+		}
+		path = next
+		if err := follow(ctx, logsDir, path, w, theme, poll); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func follow(ctx context.Context, logsDir, path string, w io.Writer, theme *ui.Theme, poll time.Duration) error {
+	f, err := os.Open(path) //nolint:gosec // newest log in the logs directory
+	if err != nil {
+		return fmt.Errorf("opening log: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // read-only
+
+	fmt.Fprintln(w, theme.Muted.Render("── "+filepath.Base(path))) //nolint:errcheck // display-only
+	r := &tailReader{stop: ctx.Done(), f: f, poll: poll, done: func() bool { return newest(logsDir) != path }}
+	if _, err := stream.Process(r, w, theme); err != nil {
+		return fmt.Errorf("following %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// tailReader reads a log that is still being written, waiting at its end
+// for more until done reports that the log is finished or stop is closed.
+type tailReader struct {
+	stop     <-chan struct{}
+	f        *os.File
+	poll     time.Duration
+	done     func() bool
+	finished bool
+}
+
+func (r *tailReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		if n > 0 || !errors.Is(err, io.EOF) {
+			return n, err //nolint:wrapcheck // thin io.Reader implementation
+		}
+		if r.finished {
+			return 0, io.EOF
+		}
+		if r.done() {
+			r.finished = true // read once more for what was written before the next log started
+			continue
+		}
+		select {
+		case <-r.stop:
+			return 0, io.EOF
+		case <-time.After(r.poll):
+		}
+	}
+}
+
+// newest returns the path of the most recently started log in logsDir, or
+// "" when there is none.
+func newest(logsDir string) string {
+	entries, err := os.ReadDir(logsDir)
+	if err != nil {
+		return ""
+	}
+	latest := ""
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		if _, err := status.LogStartTime(name); err != nil {
+			continue
+		}
+		if latest == "" || status.LogLess(latest, name) {
+			latest = name
+		}
+	}
+	if latest == "" {
+		return ""
+	}
+	return filepath.Join(logsDir, latest)
+}
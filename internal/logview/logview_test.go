@@ -0,0 +1,125 @@
+package logview
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+const (
+	toolLog   = `{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/workspace/main.go"}}]}}` + "\n"
+	resultLog = `{"type":"result","total_cost_usd":0.25}` + "\n"
+)
+
+func writeLog(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "20260210-140000.jsonl", resultLog)
+	writeLog(t, dir, "20260210-140000-2.jsonl", resultLog)
+	writeLog(t, dir, "20260211-090000.jsonl", resultLog)
+	runs := []state.RunRecord{{Mode: "build", LogFiles: []string{"logs/20260210-140000.jsonl", "logs/20260210-140000-2.jsonl"}}}
+
+	entries, err := List(dir, runs, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "20260210-140000.jsonl", entries[0].Name)
+	assert.Equal(t, Entry{
+		Name: "20260210-140000-2.jsonl", Path: filepath.Join(dir, "20260210-140000-2.jsonl"),
+		Started: time.Date(2026, 2, 10, 14, 0, 0, 0, time.UTC), Cost: 0.25,
+		Run: 1, Mode: "build", Iteration: 2, Of: 2,
+	}, entries[1])
+	assert.Zero(t, entries[2].Run)
+
+	var buf bytes.Buffer
+	Render(&buf, entries, pricing.Currency{}, ui.PlainTheme())
+	assert.Contains(t, buf.String(), "run #1 build · iteration 2/2")
+	assert.Contains(t, buf.String(), "$0.2500")
+	assert.Contains(t, buf.String(), "not in state.json")
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "20260210-140000.jsonl", resultLog)
+	writeLog(t, dir, "20260211-090000.jsonl", resultLog)
+	entries, err := List(dir, nil, nil)
+	require.NoError(t, err)
+
+	got, err := Find(entries, "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "20260211-090000.jsonl"), got)
+
+	got, err = Find(entries, "20260210-140000")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "20260210-140000.jsonl"), got)
+
+	_, err = Find(entries, "20990101-000000")
+	require.Error(t, err)
+	_, err = Find(nil, "")
+	require.Error(t, err)
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "20260210-140000.jsonl", toolLog+resultLog)
+
+	var buf bytes.Buffer
+	require.NoError(t, Replay(filepath.Join(dir, "20260210-140000.jsonl"), &buf, ui.PlainTheme()))
+	assert.Contains(t, buf.String(), "· Read /workspace/main.go")
+}
+
+func TestFollow_MovesToNewerLogs(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "20260210-140000.jsonl", toolLog)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- Follow(ctx, dir, &buf, ui.PlainTheme(), 5*time.Millisecond) }()
+
+	assert.Eventually(t, func() bool { return bytes.Contains(buf.Bytes(), []byte("main.go")) }, time.Second, 5*time.Millisecond)
+	writeLog(t, dir, "20260210-140500.jsonl", `{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test ./..."}}]}}`+"\n")
+	assert.Eventually(t, func() bool { return bytes.Contains(buf.Bytes(), []byte("go test ./...")) }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Contains(t, buf.String(), "── 20260210-140000.jsonl")
+	assert.Contains(t, buf.String(), "── 20260210-140500.jsonl")
+}
+
+// syncBuffer is a bytes.Buffer safe to read while Follow writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p) //nolint:wrapcheck // test buffer
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func (b *syncBuffer) String() string {
+	return string(b.Bytes())
+}
@@ -0,0 +1,117 @@
+package loop
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/tokens"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// budgetTopInputs is how many of the largest inputs a context warning lists.
+const budgetTopInputs = 5
+
+// contextInput is one thing an iteration is expected to load into the
+// agent's context before it starts work.
+type contextInput struct {
+	Name   string
+	Tokens int
+	Spec   bool
+}
+
+// contextInputs estimates the tokens of what an iteration starts from: the
+// prompt header, the prompt file, and the plan and specs the prompt tells
+// the agent to read. Files that can't be read are left out.
+func contextInputs(opts *Options) []contextInput {
+	inputs := []contextInput{{Name: "prompt header", Tokens: tokens.Estimate(string(promptHeader(opts)))}}
+	if prompt, err := os.ReadFile(opts.PromptFile); err == nil {
+		inputs = append(inputs, contextInput{Name: opts.PromptFile, Tokens: tokens.Estimate(string(prompt))})
+	}
+	if opts.PlanFile != "" {
+		if plan, err := os.ReadFile(opts.PlanFile); err == nil {
+			inputs = append(inputs, contextInput{Name: opts.PlanFile, Tokens: tokens.Estimate(string(plan))})
+		}
+	}
+	if opts.SpecsDir != "" {
+		_ = filepath.WalkDir(opts.SpecsDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(path) != ".md" {
+				return nil //nolint:nilerr // skip what can't be read
+			}
+			if spec, err := os.ReadFile(path); err == nil {
+				inputs = append(inputs, contextInput{Name: path, Tokens: tokens.Estimate(string(spec)), Spec: true})
+			}
+			return nil
+		})
+	}
+	return inputs
+}
+
+// contextBudget is the expected context use of an iteration before the
+// agent does any work of its own.
+type contextBudget struct {
+	Total   int
+	Largest []contextInput // biggest inputs first
+	Specs   int            // tokens across all specs
+}
+
+// budgetFor totals inputs and picks out the largest.
+func budgetFor(inputs []contextInput) contextBudget {
+	var b contextBudget
+	for _, in := range inputs {
+		b.Total += in.Tokens
+		if in.Spec {
+			b.Specs += in.Tokens
+		}
+	}
+	sorted := append([]contextInput(nil), inputs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Tokens > sorted[j].Tokens })
+	if len(sorted) > budgetTopInputs {
+		sorted = sorted[:budgetTopInputs]
+	}
+	b.Largest = sorted
+	return b
+}
+
+// warnContextBudget prints a warning before an iteration whose prompt, plan
+// and specs alone would fill more than opts.ContextWarn of the context
+// window, so an overflow doesn't surface only as a failed iteration.
+func warnContextBudget(opts *Options, w io.Writer, theme *ui.Theme) {
+	if opts.ContextWarn <= 0 {
+		return
+	}
+	b := budgetFor(contextInputs(opts))
+	if b.Total*100 <= opts.ContextWarn*contextLimit {
+		return
+	}
+	renderContextBudget(w, b, opts.ContextWarn, theme)
+}
+
+// renderContextBudget prints the expected context use, its largest inputs
+// and what to do about them.
+//
+//nolint:errcheck // display-only writes to terminal
+func renderContextBudget(w io.Writer, b contextBudget, warnPercent int, theme *ui.Theme) {
+	fmt.Fprintf(w, "  %s %s\n",
+		theme.Warning.Render(fmt.Sprintf("Prompt, plan and specs fill ~%s of %s context (%d%%)",
+			stream.FormatTokens(b.Total), stream.FormatTokens(contextLimit), b.Total*100/contextLimit)),
+		theme.Muted.Render(fmt.Sprintf("(warn at %d%%)", warnPercent)))
+	for _, in := range b.Largest {
+		fmt.Fprintf(w, "  %s %s\n", theme.Muted.Render(fmt.Sprintf("%8s", "~"+stream.FormatTokens(in.Tokens))), in.Name)
+	}
+	var tips []string
+	if b.Specs*2 > b.Total {
+		tips = append(tips, "split large specs into smaller files, one topic each")
+	}
+	if len(b.Largest) > 0 && !b.Largest[0].Spec && b.Largest[0].Name != "prompt header" {
+		tips = append(tips, fmt.Sprintf("trim %s", b.Largest[0].Name))
+	}
+	tips = append(tips, "enable auto-compaction in Claude's settings so long iterations are summarised instead of overflowing")
+	fmt.Fprintln(w, theme.Muted.Render("  Try: "+strings.Join(tips, "; ")))
+	fmt.Fprintln(w)
+}
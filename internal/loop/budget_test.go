@@ -0,0 +1,55 @@
+package loop
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetFor(t *testing.T) {
+	b := budgetFor([]contextInput{
+		{Name: "prompt header", Tokens: 10},
+		{Name: "plan.md", Tokens: 300},
+		{Name: "specs/a.md", Tokens: 500, Spec: true},
+		{Name: "specs/b.md", Tokens: 100, Spec: true},
+		{Name: "specs/c.md", Tokens: 50, Spec: true},
+		{Name: "specs/d.md", Tokens: 40, Spec: true},
+	})
+	assert.Equal(t, 1000, b.Total)
+	assert.Equal(t, 690, b.Specs)
+	require.Len(t, b.Largest, budgetTopInputs)
+	assert.Equal(t, "specs/a.md", b.Largest[0].Name)
+	assert.Equal(t, "plan.md", b.Largest[1].Name)
+}
+
+func TestWarnContextBudget(t *testing.T) {
+	dir := t.TempDir()
+	opts := &Options{
+		PromptFile:  filepath.Join(dir, "prompt.md"),
+		PlanFile:    filepath.Join(dir, "plan.md"),
+		SpecsDir:    filepath.Join(dir, "specs"),
+		ContextWarn: 50,
+	}
+	require.NoError(t, os.WriteFile(opts.PromptFile, []byte("Build the next task.\n"), 0o600))
+	require.NoError(t, os.WriteFile(opts.PlanFile, []byte("- [ ] T1\n"), 0o600))
+	require.NoError(t, os.MkdirAll(opts.SpecsDir, 0o750))
+	big := strings.Repeat("word ", contextLimit) // ~5 chars per word, well past half the window
+	require.NoError(t, os.WriteFile(filepath.Join(opts.SpecsDir, "huge.md"), []byte(big), 0o600))
+
+	var buf bytes.Buffer
+	warnContextBudget(opts, &buf, runTheme)
+	out := buf.String()
+	assert.Contains(t, out, "(warn at 50%)")
+	assert.Contains(t, out, filepath.Join(opts.SpecsDir, "huge.md"))
+	assert.Contains(t, out, "split large specs")
+
+	require.NoError(t, os.Remove(filepath.Join(opts.SpecsDir, "huge.md")))
+	buf.Reset()
+	warnContextBudget(opts, &buf, runTheme)
+	assert.Empty(t, buf.String())
+}
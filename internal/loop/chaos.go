@@ -0,0 +1,124 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// ErrChaos marks a failure injected by chaos mode.
+var ErrChaos = errors.New("injected by chaos mode")
+
+// Chaos configures failure injection for exercising the loop's recovery
+// paths against a real repo: push failures, claude exiting non-zero, and
+// output streams cut off mid-event.
+type Chaos struct {
+	Rate float64 // probability of each injection point failing, 0–1
+	Seed int64   // random seed, so a run can be reproduced
+}
+
+// truncatedStream is the claude output replayed for a truncated-stream
+// injection: a normal start, then an event cut off mid-line with no result.
+const truncatedStream = `{"type":"system","subtype":"init","session_id":"chaos"}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Picking up the next task."}],"usage":{"input_tokens":3,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"output_tokens":8}}}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_chaos","name":"Bash","input":{"command":"go te`
+
+// withChaos wraps the loop's clients so that they fail at random. A nil
+// chaos returns them unchanged. Both wrappers share one source so a seed
+// reproduces the whole sequence.
+func withChaos(c *Chaos, gitCl GitClient, claudeCl ClaudeRunner, w io.Writer, theme *ui.Theme) (GitClient, ClaudeRunner) {
+	if c == nil {
+		return gitCl, claudeCl
+	}
+	rng := rand.New(rand.NewSource(c.Seed)) //nolint:gosec // failure injection, not security
+	return &chaosGit{GitClient: gitCl, rate: c.Rate, rng: rng, w: w, theme: theme},
+		&chaosClaude{inner: claudeCl, rate: c.Rate, rng: rng, theme: theme}
+}
+
+// chaosGit fails pushes at random; every other operation is passed through.
+type chaosGit struct {
+	GitClient
+	rate  float64
+	rng   *rand.Rand
+	w     io.Writer
+	theme *ui.Theme
+}
+
+// fail returns an injected error for op at the configured rate, announcing
+// it, or nil to let the operation run.
+func (g *chaosGit) fail(op string) error {
+	if g.rng.Float64() >= g.rate {
+		return nil
+	}
+	renderChaos(g.w, op+" failure", g.theme)
+	return fmt.Errorf("%s: %w", op, ErrChaos)
+}
+
+func (g *chaosGit) Push(ctx context.Context, branch string) error {
+	if err := g.fail("push"); err != nil {
+		return err
+	}
+	return g.GitClient.Push(ctx, branch) //nolint:wrapcheck // decorator
+}
+
+func (g *chaosGit) PushSetUpstream(ctx context.Context, branch string) error {
+	if err := g.fail("push --set-upstream"); err != nil {
+		return err
+	}
+	return g.GitClient.PushSetUpstream(ctx, branch) //nolint:wrapcheck // decorator
+}
+
+func (g *chaosGit) PushIn(ctx context.Context, dir, branch string) error {
+	if err := g.fail("push " + dir); err != nil {
+		return err
+	}
+	return g.GitClient.PushIn(ctx, dir, branch) //nolint:wrapcheck // decorator
+}
+
+func (g *chaosGit) PushSetUpstreamIn(ctx context.Context, dir, branch string) error {
+	if err := g.fail("push --set-upstream " + dir); err != nil {
+		return err
+	}
+	return g.GitClient.PushSetUpstreamIn(ctx, dir, branch) //nolint:wrapcheck // decorator
+}
+
+// chaosClaude replaces an iteration, at random, with claude exiting non-zero
+// or with a truncated output stream. Half the rate goes to each.
+type chaosClaude struct {
+	inner ClaudeRunner
+	rate  float64
+	rng   *rand.Rand
+	theme *ui.Theme
+}
+
+func (c *chaosClaude) Run(ctx context.Context, opts *Options, logW, displayW io.Writer) (*stream.IterationStats, error) {
+	switch roll := c.rng.Float64(); {
+	case roll < c.rate/2:
+		renderChaos(displayW, "claude exit", c.theme)
+		return nil, fmt.Errorf("claude exited with status 1: %w", ErrChaos)
+	case roll < c.rate:
+		renderChaos(displayW, "truncated stream", c.theme)
+		tee := io.TeeReader(strings.NewReader(truncatedStream), logW)
+		sinks := append([]stream.Sink{stream.NewFormatter(displayW, c.theme).SetParamWidth(opts.ParamWidth)}, opts.Sinks...)
+		stats, err := stream.ProcessSinks(tee, sinks...)
+		if err != nil {
+			return stats, fmt.Errorf("processing stream: %w", err)
+		}
+		return stats, nil
+	default:
+		return c.inner.Run(ctx, opts, logW, displayW) //nolint:wrapcheck // decorator
+	}
+}
+
+// renderChaos announces an injected failure.
+//
+//nolint:errcheck // display-only writes to terminal
+func renderChaos(w io.Writer, what string, theme *ui.Theme) {
+	fmt.Fprintln(w, theme.Warning.Render("⚡ chaos: injecting "+what))
+}
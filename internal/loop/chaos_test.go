@@ -0,0 +1,102 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+func TestWithChaos_NilIsPassthrough(t *testing.T) {
+	g, c := &fakeGit{}, &fakeClaude{}
+	gotG, gotC := withChaos(nil, g, c, &bytes.Buffer{}, runTheme)
+	assert.Same(t, g, gotG)
+	assert.Same(t, c, gotC)
+}
+
+func TestChaosGit(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+
+	never, _ := withChaos(&Chaos{Rate: 0}, &fakeGit{}, &fakeClaude{}, &buf, runTheme)
+	require.NoError(t, never.Push(ctx, "feat"))
+	require.NoError(t, never.PushIn(ctx, "/repo", "feat"))
+	assert.Empty(t, buf.String())
+
+	always, _ := withChaos(&Chaos{Rate: 1}, &fakeGit{}, &fakeClaude{}, &buf, runTheme)
+	require.ErrorIs(t, always.Push(ctx, "feat"), ErrChaos)
+	require.ErrorIs(t, always.PushSetUpstream(ctx, "feat"), ErrChaos)
+	require.ErrorIs(t, always.PushIn(ctx, "/repo", "feat"), ErrChaos)
+	require.ErrorIs(t, always.PushSetUpstreamIn(ctx, "/repo", "feat"), ErrChaos)
+	assert.Contains(t, buf.String(), "chaos: injecting push failure")
+
+	// Non-push operations are never injected.
+	head, err := always.Head(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, head)
+}
+
+func TestChaosClaude(t *testing.T) {
+	inner := &fakeClaude{stats: iterStats()}
+	_, c := withChaos(&Chaos{Rate: 1, Seed: 1}, &fakeGit{}, inner, &bytes.Buffer{}, runTheme)
+
+	var exits, truncations int
+	for range 20 {
+		var logW, display bytes.Buffer
+		stats, err := c.Run(context.Background(), baseOpts(t), &logW, &display)
+		if err != nil {
+			require.ErrorIs(t, err, ErrChaos)
+			assert.Contains(t, display.String(), "injecting claude exit")
+			exits++
+			continue
+		}
+		assert.Contains(t, display.String(), "injecting truncated stream")
+		assert.Equal(t, truncatedStream, logW.String(), "the truncated stream is logged as received")
+		assert.Equal(t, 3, stats.PeakContext)
+		assert.Zero(t, stats.Cost, "no result event")
+		truncations++
+	}
+	assert.Zero(t, inner.called, "every iteration is injected at rate 1")
+	assert.Positive(t, exits)
+	assert.Positive(t, truncations)
+
+	_, c = withChaos(&Chaos{Rate: 0}, &fakeGit{}, inner, &bytes.Buffer{}, runTheme)
+	_, err := c.Run(context.Background(), baseOpts(t), &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.called)
+}
+
+func TestRun_ChaosRecovers(t *testing.T) {
+	var completed, crashed int
+	for seed := range int64(20) {
+		opts := baseOpts(t)
+		opts.MaxIterations = 2
+		opts.Chaos = &Chaos{Rate: 1, Seed: seed}
+		// Every iteration moves HEAD, so every iteration pushes, and every
+		// push fails.
+		g, c := withChaos(opts.Chaos, &fakeGit{heads: []string{"sha-a", "sha-b"}}, &fakeClaude{stats: iterStats()}, &bytes.Buffer{}, runTheme)
+
+		var buf bytes.Buffer
+		err := run(context.Background(), opts, &buf, runTheme, g, c)
+		if err != nil {
+			// A claude exit ends the run without recording it, leaving the
+			// container supervisor to retry and record container_crash.
+			require.ErrorIs(t, err, ErrChaos)
+			crashed++
+			continue
+		}
+		// Truncated streams and failed pushes don't stop the loop.
+		assert.Contains(t, buf.String(), "Push failed")
+		st, loadErr := state.Load(opts.StateFile)
+		require.NoError(t, loadErr)
+		require.Len(t, st.Runs, 1)
+		assert.Equal(t, state.StatusMaxIterations, st.Runs[0].Status)
+		completed++
+	}
+	assert.Positive(t, completed)
+	assert.Positive(t, crashed)
+}
@@ -0,0 +1,60 @@
+package loop
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// Claude error policies accepted by --continue-on-claude-error.
+const (
+	OnClaudeErrorAbort = "abort"          // stop the run (the default)
+	OnClaudeErrorSkip  = "skip-iteration" // count the iteration and move on
+	onClaudeErrorRetry = "retry-"         // prefix of retry-N
+)
+
+// ClaudeErrorPolicy says what the loop does when claude exits with an error.
+// The zero value aborts the run.
+type ClaudeErrorPolicy struct {
+	Skip    bool // move on to the next iteration
+	Retries int  // rerun the failed iteration up to this many times, then abort
+}
+
+// ParseClaudeErrorPolicy parses "abort", "skip-iteration" or "retry-N".
+// Empty means abort.
+func ParseClaudeErrorPolicy(s string) (ClaudeErrorPolicy, error) {
+	switch s {
+	case "", OnClaudeErrorAbort:
+		return ClaudeErrorPolicy{}, nil
+	case OnClaudeErrorSkip:
+		return ClaudeErrorPolicy{Skip: true}, nil
+	}
+	if n, ok := strings.CutPrefix(s, onClaudeErrorRetry); ok {
+		if retries, err := strconv.Atoi(n); err == nil && retries > 0 {
+			return ClaudeErrorPolicy{Retries: retries}, nil
+		}
+	}
+	return ClaudeErrorPolicy{}, fmt.Errorf("claude error policy must be abort, skip-iteration or retry-N, got %q", s)
+}
+
+// String returns the policy in the form ParseClaudeErrorPolicy accepts.
+func (p ClaudeErrorPolicy) String() string {
+	switch {
+	case p.Skip:
+		return OnClaudeErrorSkip
+	case p.Retries > 0:
+		return onClaudeErrorRetry + strconv.Itoa(p.Retries)
+	}
+	return OnClaudeErrorAbort
+}
+
+// RenderClaudeFailure reports a claude error the policy tolerates and what
+// the loop does next.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderClaudeFailure(w io.Writer, err error, next string, theme *ui.Theme) {
+	fmt.Fprintln(w, theme.Warning.Render(fmt.Sprintf("Claude failed: %s. %s", err, next)))
+}
@@ -0,0 +1,106 @@
+package loop
+
+import (
+	"os"
+	"strings"
+)
+
+// DefaultMaxPlanUnchanged is the number of consecutive plan iterations that
+// barely touch the plan before plan mode stops.
+const DefaultMaxPlanUnchanged = 2
+
+// DefaultPlanDiffThreshold is the most lines that may be added or removed in
+// an iteration for the plan to still count as unchanged. Rewording one line
+// counts as two.
+const DefaultPlanDiffThreshold = 2
+
+// PlanConvergence tracks consecutive plan-mode iterations that leave the plan
+// essentially as it was. Commits alone can't catch this: the agent may keep
+// committing cosmetic rewrites of the same plan.
+type PlanConvergence struct {
+	maxUnchanged int
+	threshold    int
+	count        int
+	last         []string
+}
+
+// NewPlanConvergence creates a detector that triggers after maxUnchanged
+// consecutive iterations changing at most threshold normalized lines.
+func NewPlanConvergence(maxUnchanged, threshold int) *PlanConvergence {
+	if maxUnchanged <= 0 {
+		maxUnchanged = DefaultMaxPlanUnchanged
+	}
+	if threshold < 0 {
+		threshold = DefaultPlanDiffThreshold
+	}
+	return &PlanConvergence{maxUnchanged: maxUnchanged, threshold: threshold}
+}
+
+// Seed records the plan as it stands before the first iteration.
+func (c *PlanConvergence) Seed(plan string) {
+	c.last = normalizePlan(plan)
+}
+
+// Check compares plan with the previous version and returns how many lines
+// changed. Returns converged=true once the threshold is reached. An empty
+// plan never counts as unchanged, since the agent hasn't written one yet.
+func (c *PlanConvergence) Check(plan string) (converged bool, count, changed int) {
+	lines := normalizePlan(plan)
+	changed = lineDiff(c.last, lines)
+	if len(lines) > 0 && len(c.last) > 0 && changed <= c.threshold {
+		c.count++
+	} else {
+		c.count = 0
+	}
+	c.last = lines
+	return c.count >= c.maxUnchanged, c.count, changed
+}
+
+// MaxUnchanged returns the configured threshold.
+func (c *PlanConvergence) MaxUnchanged() int {
+	return c.maxUnchanged
+}
+
+// readPlan returns the plan's contents, or "" when it can't be read.
+func readPlan(path string) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path comes from config
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// normalizePlan drops blank lines and collapses whitespace so reflowing or
+// re-indenting the plan doesn't count as a change.
+func normalizePlan(plan string) []string {
+	var out []string
+	for _, line := range strings.Split(plan, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// lineDiff counts lines present in one version but not the other, treating
+// each version as a multiset so moved lines don't count.
+func lineDiff(a, b []string) int {
+	counts := make(map[string]int, len(a))
+	for _, line := range a {
+		counts[line]++
+	}
+	for _, line := range b {
+		counts[line]--
+	}
+	n := 0
+	for _, c := range counts {
+		if c < 0 {
+			c = -c
+		}
+		n += c
+	}
+	return n
+}
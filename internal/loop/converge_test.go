@@ -0,0 +1,57 @@
+package loop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const convergePlan = `# Plan
+
+### Task 1 -- Schema
+- [ ] Create tables
+
+### Task 2 -- API
+- [ ] Add routes
+`
+
+func TestPlanConvergence(t *testing.T) {
+	c := NewPlanConvergence(2, 2)
+	c.Seed("")
+
+	// Writing the first plan is a change, however small the result.
+	done, count, _ := c.Check(convergePlan)
+	assert.False(t, done)
+	assert.Equal(t, 0, count)
+
+	// Reflowed whitespace and blank lines don't count.
+	done, count, changed := c.Check("\n" + convergePlan + "\n\n  ")
+	assert.False(t, done)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 0, changed)
+
+	// A real rewrite resets the counter.
+	done, count, changed = c.Check(convergePlan + "\n### Task 3 -- Auth\n- [ ] Add login\n- [ ] Add logout\n")
+	assert.False(t, done)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, 3, changed)
+
+	// Rewording one line is within the threshold; two in a row converge.
+	done, count, changed = c.Check(convergePlan + "\n### Task 3 -- Auth\n- [ ] Add login\n- [ ] Add sign-out\n")
+	assert.False(t, done)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 2, changed)
+
+	done, count, _ = c.Check(convergePlan + "\n### Task 3 -- Auth\n- [ ] Add login\n- [ ] Add sign-out\n")
+	assert.True(t, done)
+	assert.Equal(t, 2, count)
+}
+
+func TestPlanConvergenceMissingPlan(t *testing.T) {
+	c := NewPlanConvergence(1, 2)
+	c.Seed("")
+
+	done, count, _ := c.Check("")
+	assert.False(t, done, "no plan yet is not convergence")
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,256 @@
+package loop
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/status"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// Dashboard layout.
+const (
+	dashboardTools = 8  // recent tool calls listed
+	gaugeWidth     = 30 // cells in the context gauge
+)
+
+// dashboard is the OutputTUI view of a run: a bubbletea program, redrawn in
+// place as the loop's events arrive. It reads no input and leaves signals to
+// the loop, so Ctrl-C cancels the run as in text mode.
+type dashboard struct {
+	program *tea.Program
+	done    chan struct{}
+}
+
+// startDashboard starts drawing the dashboard to out.
+func startDashboard(out io.Writer, opts *Options, theme *ui.Theme) *dashboard {
+	d := &dashboard{
+		program: tea.NewProgram(newDashboardModel(opts, theme),
+			tea.WithOutput(out), tea.WithInput(nil), tea.WithoutSignalHandler()),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(d.done)
+		_, _ = d.program.Run() //nolint:errcheck // display-only; the loop's own errors are what matter
+	}()
+	return d
+}
+
+// send hands e to the dashboard, in order with the events before it.
+func (d *dashboard) send(e Event) {
+	d.program.Send(e)
+}
+
+// Close stops redrawing once every event sent has been shown, leaving the
+// last frame on screen.
+func (d *dashboard) Close() {
+	d.program.Quit()
+	<-d.done
+}
+
+// dashboardModel is the dashboard's state, built up from Events.
+type dashboardModel struct {
+	theme    *ui.Theme
+	currency pricing.Currency
+	maxStale int
+
+	mode, branch  string
+	maxIterations int
+	maxCost       float64
+	start, now    time.Time
+
+	iteration int
+	task      string
+	context   int // tokens in use in the current iteration
+	cost      float64
+	lastCost  float64
+	stale     int  // iterations in a row without a commit
+	staleSeen bool // the current iteration reported a stale count
+	tools     []toolCall
+	notice    string // the latest limit, warning or outcome
+	done      bool
+}
+
+// toolCall is a tool the agent called, with its main parameter.
+type toolCall struct {
+	name, param string
+}
+
+func newDashboardModel(opts *Options, theme *ui.Theme) dashboardModel {
+	return dashboardModel{theme: theme, currency: opts.Currency, maxStale: DefaultMaxStale}
+}
+
+// Init implements tea.Model.
+func (m dashboardModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model.
+func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	e, ok := msg.(Event)
+	if !ok {
+		return m, nil
+	}
+	m.now = e.Time
+	switch e.Type {
+	case EventRunStart:
+		m.mode, m.branch = e.Mode, e.Branch
+		m.maxIterations, m.maxCost = e.MaxIterations, e.MaxCost
+		m.start = e.Time
+	case EventIteration:
+		if !m.staleSeen {
+			m.stale = 0 // the previous iteration committed
+		}
+		m.iteration, m.context, m.staleSeen = e.Iteration, 0, false
+		m.tools = nil
+	case EventTask:
+		m.task = e.Task
+	case EventContext:
+		m.context = e.Tokens
+	case EventToolCall:
+		m.tools = append(m.tools, toolCall{name: e.Tool, param: e.Message})
+		if len(m.tools) > dashboardTools {
+			m.tools = m.tools[len(m.tools)-dashboardTools:]
+		}
+	case EventIterationEnd:
+		m.context = max(m.context, e.PeakContext)
+		m.cost += e.Cost
+		m.lastCost = e.Cost
+	case EventStale:
+		m.stale, m.maxStale, m.staleSeen = e.Count, e.Threshold, true
+		m.notice = e.Message
+	case EventSummary:
+		m.done = true
+		if e.Summary != nil {
+			m.notice = i18n.Tf("Run %s: %d task(s) completed, %d commit(s)",
+				e.Summary.Status, e.Summary.TasksCompleted, e.Summary.Commits)
+		}
+	case EventText, EventPromptTokens, EventTests:
+	default:
+		if e.Message != "" {
+			m.notice = e.Message
+		}
+	}
+	return m, nil
+}
+
+// View implements tea.Model.
+func (m dashboardModel) View() string {
+	t := m.theme
+	var b strings.Builder
+	row := func(label, value string) {
+		fmt.Fprintf(&b, "  %s %s\n", t.Muted.Render(fmt.Sprintf("%-10s", i18n.T(label))), value)
+	}
+
+	head := t.ModeStyle(m.mode).Render(strings.ToUpper(m.mode))
+	if m.branch != "" {
+		head += "  " + t.Info.Render(m.branch)
+	}
+	if !m.start.IsZero() {
+		head += "  " + t.Muted.Render(m.now.Sub(m.start).Round(time.Second).String())
+	}
+	fmt.Fprintf(&b, "\n  %s\n\n", head)
+
+	iter := fmt.Sprintf("%d", m.iteration)
+	if m.maxIterations > 0 {
+		iter += fmt.Sprintf(" / %d", m.maxIterations)
+	}
+	row("Iteration", iter)
+	if m.task != "" {
+		row("Task", m.task)
+	}
+	row("Context", m.gauge())
+	cost := t.Cost.Render(m.currency.Format(m.cost, 2))
+	if m.lastCost > 0 {
+		cost += "  " + t.Muted.Render(i18n.Tf("(last %s)", m.currency.Format(m.lastCost, 2)))
+	}
+	if m.maxCost > 0 {
+		cost += "  " + t.Muted.Render(i18n.Tf("of %s", m.currency.Format(m.maxCost, 2)))
+	}
+	row("Cost", cost)
+	staleStyle := t.Muted
+	if m.stale > 0 {
+		staleStyle = t.Warning
+	}
+	row("Stale", staleStyle.Render(fmt.Sprintf("%d / %d", m.stale, m.maxStale)))
+
+	if len(m.tools) > 0 {
+		fmt.Fprintf(&b, "\n  %s\n", t.Muted.Render(i18n.T("Recent tool calls")))
+		for _, tool := range m.tools {
+			fmt.Fprintf(&b, "    %s %s\n", t.Info.Render(fmt.Sprintf("%-10s", tool.name)), t.Muted.Render(tool.param))
+		}
+	}
+	if m.notice != "" {
+		style := t.Warning
+		if m.done {
+			style = t.Success
+		}
+		fmt.Fprintf(&b, "\n  %s\n", style.Render(m.notice))
+	}
+	return b.String()
+}
+
+// gauge draws the context in use against contextLimit.
+func (m dashboardModel) gauge() string {
+	used := min(m.context, contextLimit)
+	filled := used * gaugeWidth / contextLimit
+	style := m.theme.Success
+	switch pct := used * 100 / contextLimit; {
+	case pct >= 80:
+		style = m.theme.Error
+	case pct >= 50:
+		style = m.theme.Warning
+	}
+	return style.Render(strings.Repeat("█", filled)) +
+		m.theme.Muted.Render(strings.Repeat("░", gaugeWidth-filled)) +
+		fmt.Sprintf(" %d%%  %s / %s", used*100/contextLimit, stream.FormatTokens(m.context), stream.FormatTokens(contextLimit))
+}
+
+// dashboardSink feeds the dashboard what it shows of the agent's stream: the
+// tools it calls and the context it is using.
+type dashboardSink struct {
+	w io.Writer
+}
+
+// Format implements stream.Sink.
+func (s dashboardSink) Format(evt *stream.Event) error {
+	if evt.Type != "assistant" || evt.Message == nil {
+		return nil
+	}
+	if u := evt.Message.Usage; u != nil {
+		emitLive(s.w, Event{Type: EventContext, Tokens: u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens})
+	}
+	for _, block := range evt.Message.Content {
+		if block.Type == "tool_use" {
+			emitLive(s.w, Event{Type: EventToolCall, Tool: block.Name, Message: stream.ToolParam(block.Input)})
+		}
+	}
+	return nil
+}
+
+// renderTask tells the dashboard which plan task the iteration starts on.
+func renderTask(w io.Writer, planFile string) {
+	if !live(w) {
+		return
+	}
+	tasks, err := status.ParsePlan(planFile)
+	if err != nil {
+		return
+	}
+	t := status.ActiveTask(tasks)
+	if t == nil {
+		return
+	}
+	task := t.Title
+	if t.ID != "" {
+		task = "T" + t.ID + " " + task
+	}
+	emitLive(w, Event{Type: EventTask, Task: task})
+}
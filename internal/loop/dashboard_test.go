@@ -0,0 +1,96 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/summary"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+func TestDashboardModel(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	var m dashboardModel = newDashboardModel(&Options{}, ui.PlainTheme())
+	feed := func(e Event) {
+		next, _ := m.Update(e)
+		m = next.(dashboardModel) //nolint:errcheck // Update returns the model it was given
+	}
+
+	feed(Event{Type: EventRunStart, Time: start, Mode: "build", Branch: "feat", MaxIterations: 5, MaxCost: 2})
+	feed(Event{Type: EventIteration, Time: start, Iteration: 1})
+	feed(Event{Type: EventTask, Time: start, Task: "T1.2 Add login form"})
+	for i := range 10 {
+		feed(Event{Type: EventToolCall, Time: start, Tool: "Read", Message: string(rune('a'+i)) + ".go"})
+	}
+	feed(Event{Type: EventContext, Time: start, Tokens: 120_000})
+	feed(Event{Type: EventText, Time: start, Message: "agent chatter"})
+	feed(Event{Type: EventIterationEnd, Time: start.Add(90 * time.Second), PeakContext: 100_000, Cost: 0.5})
+	feed(Event{Type: EventStale, Time: start.Add(90 * time.Second), Count: 1, Threshold: 3, Message: "No new commits this iteration"})
+
+	view := m.View()
+	assert.Contains(t, view, "BUILD  feat  1m30s")
+	assert.Contains(t, view, "1 / 5")
+	assert.Contains(t, view, "T1.2 Add login form")
+	assert.Contains(t, view, "██████████████████░░░░░░░░░░░░ 60%  120.0k / 200.0k", "the peak never lowers what the stream reported")
+	assert.Contains(t, view, "$0.50  (last $0.50)  of $2.00")
+	assert.Contains(t, view, "1 / 3")
+	assert.NotContains(t, view, "a.go", "only the latest tool calls are kept")
+	assert.Contains(t, view, "c.go")
+	assert.Contains(t, view, "j.go")
+	assert.NotContains(t, view, "agent chatter")
+	assert.Contains(t, view, "No new commits this iteration")
+
+	feed(Event{Type: EventIteration, Time: start, Iteration: 2})
+	feed(Event{Type: EventIteration, Time: start, Iteration: 3})
+	feed(Event{Type: EventSummary, Time: start, Summary: &summary.JSON{Status: "complete", TasksCompleted: 2, Commits: 3}})
+	view = m.View()
+	assert.Contains(t, view, "0 / 3", "an iteration without a stale event resets the count")
+	assert.NotContains(t, view, "Recent tool calls", "tool calls are per iteration")
+	assert.Contains(t, view, "Run complete: 2 task(s) completed, 3 commit(s)")
+}
+
+// toolClaude feeds the run's extra sinks an agent turn that calls a tool.
+type toolClaude struct{ fakeClaude }
+
+func (c *toolClaude) Run(ctx context.Context, opts *Options, logW, w io.Writer) (*stream.IterationStats, error) {
+	evt := &stream.Event{Type: "assistant", Message: &stream.Message{
+		Content: []stream.ContentBlock{{Type: "tool_use", Name: "Bash", Input: json.RawMessage(`{"command":"go test ./..."}`)}},
+		Usage:   &stream.Usage{InputTokens: 10, CacheReadInputTokens: 40_000},
+	}}
+	for _, s := range opts.Sinks {
+		if err := s.Format(evt); err != nil {
+			return nil, err
+		}
+	}
+	return c.fakeClaude.Run(ctx, opts, logW, w)
+}
+
+func TestRun_OutputTUI(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 1
+	opts.Output = OutputTUI
+	opts.PlanFile = filepath.Join(t.TempDir(), "plan.md")
+	require.NoError(t, os.WriteFile(opts.PlanFile, []byte("# Plan\n\n### Task 1.1: Scaffold\n- [ ] **Status:** Incomplete\n"), 0o600))
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b"}}, &toolClaude{fakeClaude{stats: iterStats()}}))
+
+	out := buf.String()
+	assert.Contains(t, out, "BUILD")
+	assert.Contains(t, out, "T1.1 Scaffold")
+	assert.Contains(t, out, "go test ./...")
+	assert.Contains(t, out, "40.0k / 200.0k")
+	assert.Contains(t, out, "$0.01")
+	assert.NotContains(t, out, `"type"`, "no JSON events")
+	assert.Empty(t, opts.Sinks, "the dashboard sink is added to a copy of the options")
+}
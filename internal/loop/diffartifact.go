@@ -0,0 +1,71 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultDiffLimit is the most bytes of patch kept in an iteration's diff
+// artifact; the stat and totals above it are always complete.
+const DefaultDiffLimit = 256 << 10
+
+// DiffArtifactPath returns where iteration i of the run started at runID
+// (a logfile.TimeLayout stamp) records its changes.
+func DiffArtifactPath(logsDir, runID string, i int) string {
+	return filepath.Join(logsDir, runID, fmt.Sprintf("iter-%d.diff", i))
+}
+
+// writeDiffArtifact records what an iteration changed in the primary repo,
+// from before to after: the files and hunks changed, the diff stat and the
+// patch, capped at limit bytes. Additional repos are not included.
+func writeDiffArtifact(ctx context.Context, gitCl GitClient, path string, i int, before, after string, limit int) error {
+	files, err := gitCl.ChangedFiles(ctx, before, after)
+	if err != nil {
+		return fmt.Errorf("listing changed files: %w", err)
+	}
+	stat, err := gitCl.DiffStat(ctx, before, after)
+	if err != nil {
+		return fmt.Errorf("diff stat: %w", err)
+	}
+	patch, err := gitCl.Diff(ctx, before, after)
+	if err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Iteration %d: %s..%s\n", i, before, after)
+	fmt.Fprintf(&b, "Files changed: %d\n", len(files))
+	fmt.Fprintf(&b, "Hunks: %d\n\n", countHunks(patch))
+	if stat = strings.TrimRight(stat, "\n"); stat != "" {
+		b.WriteString(stat)
+		b.WriteString("\n\n")
+	}
+	if len(patch) > limit {
+		cut := strings.LastIndexByte(patch[:limit], '\n') + 1
+		fmt.Fprintf(&b, "%s[patch truncated at %d of %d bytes; run: git diff %s %s]\n", patch[:cut], cut, len(patch), before, after)
+	} else {
+		b.WriteString(patch)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("creating diff dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("writing diff: %w", err)
+	}
+	return nil
+}
+
+// countHunks returns the number of hunk headers in a unified diff.
+func countHunks(patch string) int {
+	n := 0
+	for line := range strings.Lines(patch) {
+		if strings.HasPrefix(line, "@@ ") {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,61 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+)
+
+const testPatch = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,2 +1,2 @@
+-old
++new
+@@ -10,1 +10,1 @@
+-x
++y
+`
+
+func TestRun_WritesDiffArtifactPerChangingIteration(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 2
+	opts.Clock = &clock.Fake{T: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Step: time.Second}
+
+	// Iteration 1 commits (sha-a → sha-b); iteration 2 changes nothing.
+	g := &fakeGit{heads: []string{"sha-a", "sha-a", "sha-b", "sha-b", "sha-b"}, changed: []string{"a.go"}, diffStat: " a.go | 4 ++--\n", patch: testPatch}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	data, err := os.ReadFile(DiffArtifactPath(opts.LogsDir, "20260301-120000", 1))
+	require.NoError(t, err)
+	out := string(data)
+	assert.Contains(t, out, "Iteration 1: sha-a..sha-b\nFiles changed: 1\nHunks: 2\n")
+	assert.Contains(t, out, " a.go | 4 ++--\n\ndiff --git a/a.go b/a.go")
+	assert.NotContains(t, out, "truncated")
+
+	assert.NoFileExists(t, DiffArtifactPath(opts.LogsDir, "20260301-120000", 2), "nothing to record for an iteration without commits")
+}
+
+func TestWriteDiffArtifact_TruncatesPatch(t *testing.T) {
+	path := DiffArtifactPath(t.TempDir(), "run", 3)
+	g := &fakeGit{patch: testPatch}
+	require.NoError(t, writeDiffArtifact(context.Background(), g, path, 3, "sha-a", "sha-b", 40))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	out := string(data)
+	assert.Contains(t, out, "Hunks: 2\n", "totals cover the whole patch")
+	assert.Contains(t, out, "--- a/a.go\n[patch truncated at 36 of 97 bytes; run: git diff sha-a sha-b]\n")
+	assert.NotContains(t, out, "+++ b/a.go")
+	assert.NotContains(t, out, "@@")
+}
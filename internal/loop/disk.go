@@ -0,0 +1,232 @@
+package loop
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// diskGroupDepth is how many leading path components growth is grouped by
+// when naming the largest offenders, e.g. "web/node_modules/".
+const diskGroupDepth = 2
+
+// diskTopPaths is how many offending paths a warning lists.
+const diskTopPaths = 5
+
+// diskUsage is a snapshot of the workspace's size.
+type diskUsage struct {
+	total int64
+	paths map[string]int64 // bytes per path, grouped to diskGroupDepth components
+}
+
+// measureDisk totals the regular files under root, skipping the exclude
+// paths (relative to root). Symlinks are not followed.
+func measureDisk(root string, exclude []string) (*diskUsage, error) {
+	skip := make(map[string]bool, len(exclude))
+	for _, e := range exclude {
+		skip[filepath.ToSlash(filepath.Clean(e))] = true
+	}
+	u := &diskUsage{paths: map[string]int64{}}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		rel = filepath.ToSlash(rel)
+		if skip[rel] {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err //nolint:wrapcheck // wrapped below
+		}
+		u.total += info.Size()
+		u.paths[diskGroup(rel)] += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("measuring workspace: %w", err)
+	}
+	return u, nil
+}
+
+// diskGroup truncates a slash-separated path to diskGroupDepth components,
+// marking truncated paths as directories with a trailing slash.
+func diskGroup(rel string) string {
+	parts := strings.SplitN(rel, "/", diskGroupDepth+1)
+	if len(parts) <= diskGroupDepth {
+		return rel
+	}
+	return strings.Join(parts[:diskGroupDepth], "/") + "/"
+}
+
+// pathGrowth is how much a path grew since the run started.
+type pathGrowth struct {
+	Path  string
+	Bytes int64
+}
+
+// diskCheck is the result of measuring the workspace after an iteration.
+type diskCheck struct {
+	Delta     int64        // change since the previous iteration
+	Growth    int64        // change since the run started
+	Largest   []pathGrowth // paths that grew most since the run started
+	OverWarn  bool
+	OverLimit bool
+}
+
+// diskWatch tracks how much the workspace grows over a run.
+type diskWatch struct {
+	root    string
+	exclude []string
+	warn    int64
+	limit   int64
+	start   *diskUsage
+	last    int64
+}
+
+// startDiskWatch measures the workspace before the first iteration. It
+// returns nil, disabling the watch, when opts.Workspace is unset or can't
+// be measured.
+func startDiskWatch(opts *Options, w io.Writer, theme *ui.Theme) *diskWatch {
+	if opts.Workspace == "" {
+		return nil
+	}
+	start, err := measureDisk(opts.Workspace, opts.WorkspaceSkip)
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Workspace size check disabled: %s", err))) //nolint:errcheck // display-only
+		return nil
+	}
+	return &diskWatch{
+		root:    opts.Workspace,
+		exclude: opts.WorkspaceSkip,
+		warn:    opts.DiskWarn,
+		limit:   opts.DiskLimit,
+		start:   start,
+		last:    start.total,
+	}
+}
+
+// check measures the workspace and compares it with the previous iteration
+// and the start of the run. A non-positive threshold is never crossed.
+func (d *diskWatch) check() (diskCheck, error) {
+	now, err := measureDisk(d.root, d.exclude)
+	if err != nil {
+		return diskCheck{}, err
+	}
+	c := diskCheck{
+		Delta:  now.total - d.last,
+		Growth: now.total - d.start.total,
+	}
+	d.last = now.total
+	c.OverWarn = d.warn > 0 && c.Growth > d.warn
+	c.OverLimit = d.limit > 0 && c.Growth > d.limit
+	if c.OverWarn || c.OverLimit {
+		c.Largest = largestGrowth(d.start, now, diskTopPaths)
+	}
+	return c, nil
+}
+
+// largestGrowth returns up to n paths that grew most between two snapshots.
+func largestGrowth(before, after *diskUsage, n int) []pathGrowth {
+	var grown []pathGrowth
+	for p, size := range after.paths {
+		if g := size - before.paths[p]; g > 0 {
+			grown = append(grown, pathGrowth{Path: p, Bytes: g})
+		}
+	}
+	sort.Slice(grown, func(i, j int) bool {
+		if grown[i].Bytes != grown[j].Bytes {
+			return grown[i].Bytes > grown[j].Bytes
+		}
+		return grown[i].Path < grown[j].Path
+	})
+	if len(grown) > n {
+		grown = grown[:n]
+	}
+	return grown
+}
+
+// watchDisk checks the workspace after an iteration and reports its growth,
+// returning true when the run should stop. A failed measurement only
+// produces a warning.
+func watchDisk(d *diskWatch, w io.Writer, theme *ui.Theme) bool {
+	if d == nil {
+		return false
+	}
+	c, err := d.check()
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Workspace size check failed: %s", err))) //nolint:errcheck // display-only
+		return false
+	}
+	renderDiskUsage(w, c, d.warn, d.limit, theme)
+	return c.OverLimit
+}
+
+// renderDiskUsage prints how much the workspace grew this iteration, and
+// the largest offending paths once it has grown past warn or limit.
+// Changes under a megabyte are not shown.
+//
+//nolint:errcheck // display-only writes to terminal
+func renderDiskUsage(w io.Writer, c diskCheck, warn, limit int64, theme *ui.Theme) {
+	switch {
+	case c.OverLimit:
+		fmt.Fprintf(w, "%s grew %s since the run started, over the %s limit. Stopping.\n",
+			theme.Error.Render("Workspace too large:"), formatSize(c.Growth), formatBytes(limit))
+	case c.OverWarn:
+		fmt.Fprintf(w, "%s %s\n",
+			theme.Warning.Render(fmt.Sprintf("Workspace grew %s since the run started", formatSize(c.Growth))),
+			theme.Muted.Render(fmt.Sprintf("(%s this iteration, warn at %s)", signedSize(c.Delta), formatBytes(warn))))
+	default:
+		if c.Delta > -1<<20 && c.Delta < 1<<20 {
+			return
+		}
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Workspace: %s this iteration (%s since start)", signedSize(c.Delta), signedSize(c.Growth))))
+		return
+	}
+	for _, p := range c.Largest {
+		fmt.Fprintf(w, "  %s %s\n", theme.Muted.Render(fmt.Sprintf("%8s", "+"+formatSize(p.Bytes))), p.Path)
+	}
+}
+
+// formatSize renders a byte count with a readable unit, e.g. "12MB".
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return formatBytes(n)
+	case n >= 1<<10:
+		return fmt.Sprintf("%dKB", n/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// signedSize renders a size change with its sign, e.g. "+12MB" or "-3MB".
+func signedSize(n int64) string {
+	if n < 0 {
+		return "-" + formatSize(-n)
+	}
+	return "+" + formatSize(n)
+}
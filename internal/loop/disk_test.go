@@ -0,0 +1,73 @@
+package loop
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSized(t *testing.T, dir, name string, size int) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0o600))
+}
+
+func TestMeasureDisk_GroupsAndSkips(t *testing.T) {
+	dir := t.TempDir()
+	writeSized(t, dir, "main.go", 10)
+	writeSized(t, dir, "web/node_modules/react/index.js", 100)
+	writeSized(t, dir, "web/node_modules/vue/index.js", 50)
+	writeSized(t, dir, "web/app.ts", 5)
+	writeSized(t, dir, ".git/objects/ab/cdef", 1000)
+
+	u, err := measureDisk(dir, []string{".git"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(165), u.total)
+	assert.Equal(t, map[string]int64{
+		"main.go":           10,
+		"web/node_modules/": 150,
+		"web/app.ts":        5,
+	}, u.paths)
+}
+
+func TestDiskWatch_WarnsAndStopsWithLargestPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeSized(t, dir, "README.md", 100)
+	opts := &Options{Workspace: dir, DiskWarn: 200, DiskLimit: 1000}
+	var buf bytes.Buffer
+	d := startDiskWatch(opts, &buf, runTheme)
+	require.NotNil(t, d)
+
+	writeSized(t, dir, "data/a.csv", 150)
+	assert.False(t, watchDisk(d, &buf, runTheme))
+	assert.Empty(t, buf.String(), "growth under a megabyte and below warn is quiet")
+
+	writeSized(t, dir, "data/b.csv", 100)
+	c, err := d.check()
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), c.Delta)
+	assert.Equal(t, int64(250), c.Growth)
+	assert.True(t, c.OverWarn)
+	assert.False(t, c.OverLimit)
+
+	writeSized(t, dir, "out/dump.bin", 900)
+	writeSized(t, dir, "data/c.csv", 10)
+	assert.True(t, watchDisk(d, &buf, runTheme))
+	out := buf.String()
+	assert.Contains(t, out, "Workspace too large:")
+	assert.Less(t, bytes.Index(buf.Bytes(), []byte("out/")), bytes.Index(buf.Bytes(), []byte("data/")), "largest first")
+	assert.NotContains(t, out, "README.md", "unchanged paths are not offenders")
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512B", formatSize(512))
+	assert.Equal(t, "3KB", formatSize(3<<10))
+	assert.Equal(t, "12MB", formatSize(12<<20))
+	assert.Equal(t, "1.5GB", formatSize(3<<29))
+	assert.Equal(t, "-2MB", signedSize(-2<<20))
+}
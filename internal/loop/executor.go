@@ -2,21 +2,33 @@ package loop
 
 import (
 	"context"
+	"fmt"
 	"io"
 
 	"github.com/benwilkes9/ralph-cli/internal/git"
 	"github.com/benwilkes9/ralph-cli/internal/stream"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
+	"github.com/benwilkes9/ralph-cli/internal/vcs"
 )
 
 // GitClient abstracts git operations used by the loop.
 type GitClient interface {
-	Head() (string, error)
-	Push(branch string) error
-	PushSetUpstream(branch string) error
-	HeadIn(dir string) (string, error)
-	PushIn(dir, branch string) error
-	PushSetUpstreamIn(dir, branch string) error
+	Head(ctx context.Context) (string, error)
+	Push(ctx context.Context, branch string) error
+	PushSetUpstream(ctx context.Context, branch string) error
+	HeadIn(ctx context.Context, dir string) (string, error)
+	PushIn(ctx context.Context, dir, branch string) error
+	PushSetUpstreamIn(ctx context.Context, dir, branch string) error
+	DiffStat(ctx context.Context, from, to string) (string, error)
+	Diff(ctx context.Context, from, to string) (string, error)
+	ResetHard(ctx context.Context, rev string) error
+	ChangedFiles(ctx context.Context, from, to string) ([]string, error)
+	CommitSubjects(ctx context.Context, from, to string) ([]string, error)
+	LastCommitMessage(ctx context.Context) (string, error)
+	AmendCommitMessage(ctx context.Context, message string) error
+	UnpushedCount(ctx context.Context, branch string) (int, error)
+	CheckHealth(ctx context.Context) (*git.Health, error)
+	Repair(ctx context.Context, h *git.Health, branch string) ([]string, error)
 }
 
 // ClaudeRunner abstracts the claude CLI subprocess.
@@ -26,28 +38,60 @@ type ClaudeRunner interface {
 
 type realGitClient struct{}
 
-func (r *realGitClient) Head() (string, error) {
-	return git.Head() //nolint:wrapcheck // thin adapter
+func (r *realGitClient) Head(ctx context.Context) (string, error) {
+	return git.Head(ctx) //nolint:wrapcheck // thin adapter
 }
 
-func (r *realGitClient) Push(branch string) error {
-	return git.Push(branch) //nolint:wrapcheck // thin adapter
+func (r *realGitClient) Push(ctx context.Context, branch string) error {
+	return git.Push(ctx, branch) //nolint:wrapcheck // thin adapter
 }
 
-func (r *realGitClient) PushSetUpstream(branch string) error {
-	return git.PushSetUpstream(branch) //nolint:wrapcheck // thin adapter
+func (r *realGitClient) PushSetUpstream(ctx context.Context, branch string) error {
+	return git.PushSetUpstream(ctx, branch) //nolint:wrapcheck // thin adapter
 }
 
-func (r *realGitClient) HeadIn(dir string) (string, error) {
-	return git.HeadIn(dir) //nolint:wrapcheck // thin adapter
+func (r *realGitClient) HeadIn(ctx context.Context, dir string) (string, error) {
+	return git.HeadIn(ctx, dir) //nolint:wrapcheck // thin adapter
 }
 
-func (r *realGitClient) PushIn(dir, branch string) error {
-	return git.PushIn(dir, branch) //nolint:wrapcheck // thin adapter
+func (r *realGitClient) PushIn(ctx context.Context, dir, branch string) error {
+	return git.PushIn(ctx, dir, branch) //nolint:wrapcheck // thin adapter
 }
 
-func (r *realGitClient) PushSetUpstreamIn(dir, branch string) error {
-	return git.PushSetUpstreamIn(dir, branch) //nolint:wrapcheck // thin adapter
+func (r *realGitClient) PushSetUpstreamIn(ctx context.Context, dir, branch string) error {
+	return git.PushSetUpstreamIn(ctx, dir, branch) //nolint:wrapcheck // thin adapter
+}
+
+func (r *realGitClient) DiffStat(ctx context.Context, from, to string) (string, error) {
+	return git.DiffStat(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+func (r *realGitClient) Diff(ctx context.Context, from, to string) (string, error) {
+	return git.Diff(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+func (r *realGitClient) ResetHard(ctx context.Context, rev string) error {
+	return git.ResetHard(ctx, rev) //nolint:wrapcheck // thin adapter
+}
+
+func (r *realGitClient) ChangedFiles(ctx context.Context, from, to string) ([]string, error) {
+	return git.ChangedFiles(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+func (r *realGitClient) CommitSubjects(ctx context.Context, from, to string) ([]string, error) {
+	return git.CommitSubjects(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+func (r *realGitClient) LastCommitMessage(ctx context.Context) (string, error) {
+	return git.LastCommitMessage(ctx) //nolint:wrapcheck // thin adapter
+}
+
+func (r *realGitClient) AmendCommitMessage(ctx context.Context, message string) error {
+	return git.AmendCommitMessage(ctx, message) //nolint:wrapcheck // thin adapter
+}
+
+func (r *realGitClient) UnpushedCount(ctx context.Context, branch string) (int, error) {
+	return git.UnpushedCount(ctx, branch) //nolint:wrapcheck // thin adapter
 }
 
 type realClaudeRunner struct {
@@ -57,3 +101,83 @@ type realClaudeRunner struct {
 func (r *realClaudeRunner) Run(ctx context.Context, opts *Options, logW, displayW io.Writer) (*stream.IterationStats, error) {
 	return runClaude(ctx, opts, logW, displayW, r.theme)
 }
+
+func (r *realGitClient) CheckHealth(ctx context.Context) (*git.Health, error) {
+	return git.CheckHealth(ctx) //nolint:wrapcheck // thin adapter
+}
+
+func (r *realGitClient) Repair(ctx context.Context, h *git.Health, branch string) ([]string, error) {
+	return git.Repair(ctx, h, branch) //nolint:wrapcheck // thin adapter
+}
+
+// vcsGitClient routes the primary repo's operations through its version
+// control backend. Additional directories are always git repos, so the *In
+// methods stay on git, as do health checks and repairs when the backend is git.
+type vcsGitClient struct {
+	realGitClient
+	v vcs.VCS
+}
+
+func (c *vcsGitClient) Head(ctx context.Context) (string, error) {
+	return c.v.Head(ctx) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) Push(ctx context.Context, branch string) error {
+	return c.v.Push(ctx, branch) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) PushSetUpstream(ctx context.Context, branch string) error {
+	return c.v.PushSetUpstream(ctx, branch) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) DiffStat(ctx context.Context, from, to string) (string, error) {
+	return c.v.DiffStat(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) Diff(ctx context.Context, from, to string) (string, error) {
+	return c.v.Diff(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) ResetHard(ctx context.Context, rev string) error {
+	return c.v.ResetHard(ctx, rev) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) ChangedFiles(ctx context.Context, from, to string) ([]string, error) {
+	return c.v.ChangedFiles(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) CommitSubjects(ctx context.Context, from, to string) ([]string, error) {
+	return c.v.CommitSubjects(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) LastCommitMessage(ctx context.Context) (string, error) {
+	return c.v.LastCommitMessage(ctx) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) AmendCommitMessage(ctx context.Context, message string) error {
+	return c.v.AmendCommitMessage(ctx, message) //nolint:wrapcheck // thin adapter
+}
+
+func (c *vcsGitClient) UnpushedCount(ctx context.Context, branch string) (int, error) {
+	return c.v.UnpushedCount(ctx, branch) //nolint:wrapcheck // thin adapter
+}
+
+// CheckHealth only inspects git repos; jj and hg have no half-finished
+// rebase or merge state to leave behind, so the only check is the branch.
+func (c *vcsGitClient) CheckHealth(ctx context.Context) (*git.Health, error) {
+	if c.v.Kind() == vcs.KindGit {
+		return c.realGitClient.CheckHealth(ctx)
+	}
+	branch, err := c.v.Branch(ctx)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // thin adapter
+	}
+	return &git.Health{Branch: branch}, nil
+}
+
+func (c *vcsGitClient) Repair(ctx context.Context, h *git.Health, branch string) ([]string, error) {
+	if c.v.Kind() == vcs.KindGit {
+		return c.realGitClient.Repair(ctx, h, branch)
+	}
+	return nil, fmt.Errorf("%s repo is not on %s; switch back to it", c.v.Kind(), branch)
+}
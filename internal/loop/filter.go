@@ -0,0 +1,40 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// filterEvents starts opts.Filters and passes src through them. A filter
+// that won't start or later fails is reported and left out, so a broken
+// filter doesn't stop the iteration. The returned func stops the filters.
+//
+//nolint:errcheck // display-only writes to terminal
+func filterEvents(ctx context.Context, opts *Options, src stream.Source, w io.Writer, theme *ui.Theme) (stream.Source, func()) {
+	var filters []*stream.Filter
+	var stderrs []*stream.RedactWriter // one each: filters write concurrently
+	for _, command := range opts.Filters {
+		stderr := opts.Redactor.Writer(os.Stderr)
+		f, err := stream.StartFilter(ctx, command, stderr)
+		if err != nil {
+			fmt.Fprintln(w, theme.Warning.Render(fmt.Sprintf("Skipping %s", err)))
+			continue
+		}
+		filters = append(filters, f)
+		stderrs = append(stderrs, stderr)
+	}
+	onError := func(f *stream.Filter, err error) {
+		fmt.Fprintln(w, theme.Warning.Render(fmt.Sprintf("Stream filter %q failed, skipping it for this iteration: %s", f.Command, err)))
+	}
+	return stream.Filtered(src, filters, onError), func() {
+		for i, f := range filters {
+			_ = f.Close()          //nolint:errcheck // failures were reported as they happened
+			_ = stderrs[i].Flush() //nolint:errcheck // best-effort terminal output
+		}
+	}
+}
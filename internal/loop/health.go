@@ -0,0 +1,39 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// ensureHealthy checks the primary repo before an iteration and undoes any
+// interrupted rebase or merge, conflicts, or detached HEAD a previous
+// iteration left behind. It returns the repair applied; its Actions are
+// empty when none was needed. A failed check is only reported; a failed
+// repair stops the loop, since the next iteration would build on a broken
+// tree.
+//
+//nolint:errcheck // display-only writes to terminal
+func ensureHealthy(ctx context.Context, gitCl GitClient, opts *Options, w io.Writer, theme *ui.Theme, iteration int) (state.Repair, error) {
+	h, err := gitCl.CheckHealth(ctx)
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Could not check repo health: %s", err)))
+		return state.Repair{}, nil //nolint:nilerr // a broken check shouldn't stop the loop
+	}
+	if h.OK(opts.Branch) {
+		return state.Repair{}, nil
+	}
+
+	actions, err := gitCl.Repair(ctx, h, opts.Branch)
+	for _, a := range actions {
+		fmt.Fprintf(w, "%s %s\n", theme.Warning.Render("Repaired repo:"), a)
+	}
+	if err != nil {
+		return state.Repair{}, fmt.Errorf("repo needs manual repair: %w", err)
+	}
+	return state.Repair{Iteration: iteration, At: clock.Or(opts.Clock).Now(), Actions: actions}, nil
+}
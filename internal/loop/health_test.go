@@ -0,0 +1,77 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+func TestRun_HealthyRepoNotRepaired(t *testing.T) {
+	opts := baseOpts(t)
+	g := &fakeGit{heads: []string{"sha-a", "sha-b"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, &fakeClaude{stats: iterStats()}))
+
+	assert.Empty(t, g.repairArgs)
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	assert.Empty(t, st.Runs[0].Repairs)
+}
+
+func TestRun_RepairsBrokenRepoAndRecordsEvent(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 2
+	broken := &git.Health{Detached: true, Rebasing: true}
+	// Iteration 1 starts healthy; iteration 2 finds a half-done rebase.
+	g := &fakeGit{
+		heads:  []string{"sha-a", "sha-b"},
+		health: []*git.Health{{Branch: "main"}, broken},
+	}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	assert.Equal(t, []*git.Health{broken}, g.repairArgs)
+	assert.Equal(t, 2, c.called)
+	assert.Contains(t, buf.String(), "Repaired repo: aborted in-progress rebase")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs[0].Repairs, 1)
+	assert.Equal(t, 2, st.Runs[0].Repairs[0].Iteration)
+	assert.Equal(t, []string{"aborted in-progress rebase", "re-attached main at abc"}, st.Runs[0].Repairs[0].Actions)
+}
+
+func TestRun_RepairOnWrongBranch(t *testing.T) {
+	opts := baseOpts(t)
+	g := &fakeGit{heads: []string{"sha-a", "sha-b"}, health: []*git.Health{{Branch: "other"}}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, &fakeClaude{stats: iterStats()}))
+	assert.Len(t, g.repairArgs, 1)
+}
+
+func TestRun_RepairFailureStopsLoop(t *testing.T) {
+	opts := baseOpts(t)
+	g := &fakeGit{
+		heads:     []string{"sha-a"},
+		health:    []*git.Health{{Merging: true, Branch: "main"}},
+		repairErr: errors.New("merge --abort failed"),
+	}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	err := run(context.Background(), opts, &buf, runTheme, g, c)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "repo needs manual repair")
+	assert.Equal(t, 0, c.called)
+}
@@ -1,20 +1,33 @@
 package loop
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/benwilkes9/ralph-cli/internal/agent"
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/ghstatus"
 	logfile "github.com/benwilkes9/ralph-cli/internal/log"
+	"github.com/benwilkes9/ralph-cli/internal/notify"
+	"github.com/benwilkes9/ralph-cli/internal/owners"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
 	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/status"
 	"github.com/benwilkes9/ralph-cli/internal/stream"
 	"github.com/benwilkes9/ralph-cli/internal/summary"
+	"github.com/benwilkes9/ralph-cli/internal/tokens"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
+	"github.com/benwilkes9/ralph-cli/internal/vcs"
 )
 
 // Mode represents the loop mode (plan or build).
@@ -37,36 +50,175 @@ type Options struct {
 	StateFile      string
 	PlanFile       string
 	SpecsDir       string
-	AdditionalDirs []string // container paths to additional repos
+	AdditionalDirs []string          // container paths to additional repos
+	Sinks          []stream.Sink     // extra event sinks fed alongside the terminal formatter
+	Redactor       *stream.Redactor  // masks secrets in the agent's output before it is shown or logged; nil = none
+	Filters        []string          // stream filter commands each event passes through before it is shown; see stream.Filter
+	Tags           []string          // run labels recorded in state.json
+	Note           string            // run description recorded in state.json
+	Profile        string            // credential profile recorded in state.json
+	Experiment     string            // experiment variant recorded in state.json
+	PlanApproval   *state.Approval   // plan pull request approval recorded in state.json; nil = none
+	ResumeOf       int               // run number (1-based) in state.json this run continues; 0 = a fresh run
+	ReplayOf       int               // run number (1-based) in state.json whose prompts Replay holds; 0 = not a replay
+	Replay         []PromptRecord    // recorded prompts to send in order, one per iteration, instead of the prompt file's
+	CurrentPrompts bool              // replay only the recorded tasks and feedback, with the current prompt file
+	PromptText     string            // the prompt to send instead of reading PromptFile (set by the loop for replays)
+	Origin         *state.Origin     // host clone recorded in state.json; nil = unknown
+	Clock          clock.Clock       // stamps run records and log names; nil = wall clock
+	Offline        bool              // keep commits local instead of pushing after each iteration (ralph sync pushes them later)
+	PushInterval   time.Duration     // push at most this often, queueing commits in between; 0 = no time limit
+	PushCommits    int               // push once this many commits are queued; 0 = no count limit (neither set = push every iteration)
+	Agent          agent.Agent       // coding agent CLI run each iteration; nil = claude
+	Model          string            // agent --model; empty = DefaultModel for claude, the agent's own default otherwise
+	ModelRoutes    map[string]string // build mode: active task label or category → model for the iteration
+	Timeout        time.Duration     // per iteration: kill the agent after this long and move on; 0 = no limit
+	MaxTimeouts    int               // iterations in a row that may time out before aborting; 0 = DefaultMaxTimeouts
+	Settings       string            // claude --settings JSON (guardrail hooks); empty to omit
+	Instructions   string            // repo instructions file (AGENTS.md) passed as the agent's cached system prompt; empty = none
+	NoPromptCache  bool              // put the dynamic header first and disable claude's prompt caching
+	DiffLimit      int               // patch bytes kept in each iteration's <LogsDir>/<run>/iter-<n>.diff; 0 = DefaultDiffLimit
+	ParamWidth     int               // runes of a tool call's parameter to show; 0 = stream.DefaultParamWidth, negative = all
+	StepIn         io.Reader         // non-nil enables step mode: pause after each iteration and read a command
+	Feedback       string            // operator guidance prepended to the prompt (set per iteration in step mode)
+	RequireTaskID  bool              // build mode: inject the active plan task id and check commits reference it
+	AmendTaskID    bool              // reword an unpushed last commit that lacks the task id
+	TaskID         string            // active task id for this iteration, e.g. "T2.1" (set by the loop)
+	ScratchDir     string            // agent scratchpad (not committed), advertised in the prompt; empty = none
+	ScratchLimit   int64             // max total bytes in ScratchDir; oldest files are pruned past it
+	Workspace      string            // directory whose growth is tracked per iteration; empty = off
+	WorkspaceSkip  []string          // paths under Workspace left out of its size, e.g. ".git"
+	DiskWarn       int64             // warn once Workspace has grown by more bytes than this; 0 = never
+	DiskLimit      int64             // stop the run once Workspace has grown by more bytes than this; 0 = never
+	Owners         *owners.Policy    // CODEOWNERS policy listed in the prompt and enforced on commits; nil = none
+	TokenCounter   tokens.Counter    // counts the assembled prompt before each iteration; nil = off
+	ContextWarn    int               // warn before an iteration whose prompt, plan and specs exceed this % of the context window; 0 = never
+	Chaos          *Chaos            // inject random push/claude/stream failures; nil = off
+	Reporter       ghstatus.Reporter // GitHub commit status / check run on the branch head; nil = off
+	Notifier       notify.Notifier   // webhook for run lifecycle events; nil = off
+	Pricing        pricing.Table     // rates used when the API reports no cost; nil = none
+	Currency       pricing.Currency  // currency costs are displayed in; zero = USD
+	OnClaudeError  ClaudeErrorPolicy // what to do when claude exits with an error; zero = abort
+	VCS            vcs.VCS           // backend for the primary repo; nil = git
+	SummaryRows    []string          // rows of the job summary box, in order; empty = summary.DefaultRows
+	Output         string            // OutputText, OutputJSON or OutputTUI; empty = OutputText
+	MaxCost        float64           // USD the run may spend; no iteration starts once it has; 0 = no cap
+	Package        string            // workspace package directory the run is scoped to, shown to the agent as PACKAGE; empty = whole repo
+	SkipHooks      string            // why the agent's commits skip the repo's commit hooks (git.hooks.reason); empty = hooks run
 }
 
+// DefaultModel is the claude model used unless an experiment overrides it.
+const DefaultModel = agent.ClaudeDefaultModel
+
 // Run executes the main iteration loop.
 func Run(ctx context.Context, opts *Options, w io.Writer, theme *ui.Theme) error {
-	return run(ctx, opts, w, theme, &realGitClient{}, &realClaudeRunner{theme: theme})
+	if opts.Output == OutputJSON || opts.Output == OutputTUI {
+		theme = ui.PlainTheme() // for the agent's stream, which run turns into text events
+	}
+	var gitCl GitClient = &realGitClient{}
+	if opts.VCS != nil {
+		gitCl = &vcsGitClient{v: opts.VCS}
+	}
+	gitCl, claudeCl := withChaos(opts.Chaos, gitCl, &realClaudeRunner{theme: theme}, w, theme)
+	return run(ctx, opts, w, theme, gitCl, claudeCl)
 }
 
-func run(ctx context.Context, opts *Options, w io.Writer, theme *ui.Theme, gitCl GitClient, claudeCl ClaudeRunner) error {
+func run(ctx context.Context, opts *Options, w io.Writer, theme *ui.Theme, gitCl GitClient, claudeCl ClaudeRunner) (err error) {
+	switch opts.Output {
+	case OutputJSON:
+		jw := newJSONWriter(w, opts.Clock)
+		defer jw.Flush() //nolint:errcheck // display-only
+		w, theme = jw, ui.PlainTheme()
+	case OutputTUI:
+		dash := startDashboard(w, opts, theme)
+		defer dash.Close()
+		jw := newEventWriter(dash.send, opts.Clock)
+		defer jw.Flush() //nolint:errcheck // display-only
+		w, theme = jw, ui.PlainTheme()
+		withSink := *opts
+		withSink.Sinks = append(slices.Clip(opts.Sinks), dashboardSink{w: jw})
+		opts = &withSink
+	}
 	RenderHeader(w, opts, theme)
 
+	cumStats := &stream.CumulativeStats{}
+	pushes := newPushQueue(opts)
+	clk := clock.Or(opts.Clock)
+	reported := false
+	var running *state.RunRecord // saved by the last checkpoint; nil before the first
+	defer func() {
+		if err != nil && !reported {
+			if running != nil {
+				running.Status = state.StatusFailed
+				running.FinishedAt = clk.Now()
+				putRecord(opts, running)
+			}
+			flushPushes(ctx, gitCl, opts, w, theme, pushes, clk.Now())
+			reportDone(ctx, gitCl, opts, w, theme, false, fmt.Sprintf("Ralph %s failed", opts.Mode), err.Error())
+			sendEvent(ctx, opts, w, theme, notify.Payload{
+				Event: notify.EventRunFinish, Status: "failed", Error: err.Error(),
+				Iteration: cumStats.Iterations, TotalCost: cumStats.TotalCost,
+			})
+		}
+	}()
+	reportPending(ctx, gitCl, opts, w, theme, fmt.Sprintf("Ralph %s running", opts.Mode))
+	sendEvent(ctx, opts, w, theme, notify.Payload{Event: notify.EventRunStart})
+
 	// Seed stale detector with initial composite HEAD.
-	initHead, err := compositeHead(gitCl, opts.AdditionalDirs)
+	initHead, err := compositeHead(ctx, gitCl, opts.AdditionalDirs)
 	if err != nil {
 		return fmt.Errorf("getting initial HEAD: %w", err)
 	}
 	stale := NewStaleDetector(DefaultMaxStale)
 	stale.Check(initHead) // seed
 
-	cumStats := &stream.CumulativeStats{}
-	startTime := time.Now()
+	var converge *PlanConvergence
+	if opts.Mode == ModePlan {
+		converge = NewPlanConvergence(DefaultMaxPlanUnchanged, DefaultPlanDiffThreshold)
+		converge.Seed(readPlan(opts.PlanFile))
+	}
+
+	disk := startDiskWatch(opts, w, theme)
+	startTime := clk.Now()
+	runID := startTime.Format(logfile.TimeLayout) // names the directory of the run's per-iteration artifacts
+	tasksBefore, _ := planTasks(opts.PlanFile)
 
 	var (
 		cancelled    bool
 		staleAborted bool
+		converged    bool
+		diskAborted  bool
+		timedOut     bool // aborted after opts.maxTimeouts() timeouts in a row
 		logPaths     []string
+		logIters     []int // the iteration each of logPaths was written for
+		step         *bufio.Reader
+		feedback     string
+		repairs      []state.Repair
+		tests        []state.TestResult
+		failures     int // claude errors tolerated by opts.OnClaudeError
+		retries      int // reruns of the current iteration so far
+		timeouts     int // iterations in a row killed at opts.Timeout
+		commits      int // made on the primary repo, for the summary
+		checkpoints  []state.Checkpoint
 	)
+	if opts.StepIn != nil {
+		step = bufio.NewReader(opts.StepIn)
+	}
 	for i := 1; ; i++ {
 		if opts.MaxIterations > 0 && i > opts.MaxIterations {
 			RenderMaxIterations(w, opts.MaxIterations, theme)
+			sendEvent(ctx, opts, w, theme, notify.Payload{
+				Event: notify.EventBudgetExceeded, Limit: notify.LimitIterations,
+				Iteration: cumStats.Iterations, TotalCost: cumStats.TotalCost,
+			})
+			break
+		}
+		if opts.MaxCost > 0 && cumStats.TotalCost >= opts.MaxCost {
+			RenderCostLimit(w, cumStats.TotalCost, opts.MaxCost, opts.Currency, theme)
+			sendEvent(ctx, opts, w, theme, notify.Payload{
+				Event: notify.EventBudgetExceeded, Limit: notify.LimitCost,
+				Iteration: cumStats.Iterations, TotalCost: cumStats.TotalCost,
+			})
 			break
 		}
 
@@ -75,118 +227,433 @@ func run(ctx context.Context, opts *Options, w io.Writer, theme *ui.Theme, gitCl
 			break
 		}
 
-		headBefore, err := compositeHead(gitCl, opts.AdditionalDirs)
+		repair, err := ensureHealthy(ctx, gitCl, opts, w, theme, i)
+		if err != nil {
+			return err
+		}
+		if len(repair.Actions) > 0 {
+			repairs = append(repairs, repair)
+		}
+
+		headBefore, err := compositeHead(ctx, gitCl, opts.AdditionalDirs)
 		if err != nil {
 			return fmt.Errorf("getting HEAD before iteration: %w", err)
 		}
 
 		RenderBanner(w, opts.Mode, i, theme)
+		renderTask(w, opts.PlanFile)
 
-		logW, err := logfile.New(opts.LogsDir)
+		logW, err := logfile.NewWith(opts.LogsDir, logfile.Options{Clock: clk})
 		if err != nil {
 			return fmt.Errorf("creating log writer: %w", err)
 		}
 
-		iterStats, runErr := claudeCl.Run(ctx, opts, logW, w)
+		var taskID string
+		if opts.RequireTaskID && opts.Mode == ModeBuild {
+			taskID = activeTaskID(opts.PlanFile)
+		}
+		replayed, replaying := replayRecord(opts, i)
+		if replaying {
+			taskID = replayed.TaskID
+			if replayed.Feedback != "" {
+				feedback = replayed.Feedback
+			}
+		}
+		routed, category := routeModel(opts)
+		slog.DebugContext(ctx, "loop: iteration", "i", i, "head", headBefore, "task", taskID, "routed_model", routed, "category", category, "feedback", feedback != "", "replay", replaying)
+		iterOpts := opts
+		if feedback != "" || taskID != "" || routed != "" || replaying {
+			perIter := *opts
+			perIter.Feedback = feedback
+			perIter.TaskID = taskID
+			if replaying && !opts.CurrentPrompts {
+				perIter.PromptText = replayed.Prompt
+			}
+			if routed != "" {
+				perIter.Model = routed
+				renderModelRoute(w, routed, category, theme)
+			}
+			iterOpts = &perIter
+			feedback = ""
+		}
+		if err := writePromptArtifact(PromptArtifactPath(opts.LogsDir, runID, i), i, iterOpts); err != nil {
+			fmt.Fprintf(w, "%s\n", theme.Muted.Render(fmt.Sprintf("Prompt not recorded: %s", err))) //nolint:errcheck // display-only
+		}
+		renderPromptTokens(ctx, iterOpts, w, theme)
+		warnContextBudget(iterOpts, w, theme)
+		iterCtx, cancelIter := iterationContext(ctx, opts)
+		iterStats, runErr := claudeCl.Run(iterCtx, iterOpts, logW, w)
+		hitTimeout := runErr != nil && ctx.Err() == nil && errors.Is(iterCtx.Err(), context.DeadlineExceeded)
+		cancelIter()
+		slog.DebugContext(ctx, "loop: agent exited", "i", i, "err", runErr, "timed_out", hitTimeout)
+		if iterStats != nil {
+			iterStats.PriceFallback(iterOpts.Pricing, iterOpts.model())
+		}
 		logW.Close() //nolint:errcheck // best-effort log close
+		enforceScratchLimit(opts, w, theme)
 		logPaths = append(logPaths, logW.Path())
+		logIters = append(logIters, i)
+		_ = status.RecordLog(opts.LogsDir, logW.Path(), iterStats, iterOpts.Pricing) //nolint:errcheck // best-effort cache for ralph status
+
+		if hitTimeout {
+			timeouts++
+			failures++
+			if iterStats == nil {
+				iterStats = &stream.IterationStats{}
+			}
+			iterStats.TimedOut = true
+			cumStats.Update(iterStats) // the killed attempt was still billed
+			if timeouts >= opts.maxTimeouts() {
+				RenderTimeoutAbort(w, timeouts, opts.Timeout, theme)
+				timedOut = true
+				break
+			}
+			RenderIterationTimeout(w, opts.Timeout, timeouts, opts.maxTimeouts(), theme)
+			continue
+		}
+		timeouts = 0
 
 		if runErr != nil {
-			return fmt.Errorf("running claude: %w", runErr)
+			if ctx.Err() != nil || (!opts.OnClaudeError.Skip && retries >= opts.OnClaudeError.Retries) {
+				return fmt.Errorf("running claude: %w", runErr)
+			}
+			failures++
+			if iterStats != nil {
+				cumStats.Update(iterStats) // the failed attempt was still billed
+			}
+			if opts.OnClaudeError.Skip {
+				RenderClaudeFailure(w, runErr, "Skipping to the next iteration.", theme)
+			} else {
+				retries++
+				RenderClaudeFailure(w, runErr, fmt.Sprintf("Retrying iteration %d (%d/%d).", i, retries, opts.OnClaudeError.Retries), theme)
+				i-- // rerun the same iteration
+			}
+			continue
 		}
+		retries = 0
 
 		if iterStats != nil {
 			cumStats.Update(iterStats)
-			RenderIterationSummary(w, iterStats, logW.Path(), theme)
+			RenderIterationSummary(w, iterStats, logW.Path(), opts.Currency, theme)
+			sendEvent(ctx, opts, w, theme, notify.Payload{
+				Event: notify.EventIteration, Iteration: i, Cost: iterStats.Cost, TotalCost: cumStats.TotalCost,
+			})
+			if iterStats.Tests != nil {
+				tests = append(tests, state.TestResult{Iteration: i, Counts: *iterStats.Tests})
+				RenderTestTrend(w, tests, theme)
+			}
 		}
 
 		// Check for stale iterations.
-		headAfter, err := compositeHead(gitCl, opts.AdditionalDirs)
+		headAfter, err := compositeHead(ctx, gitCl, opts.AdditionalDirs)
 		if err != nil {
 			return fmt.Errorf("getting HEAD after iteration: %w", err)
 		}
 
+		if taskID != "" && headBefore != headAfter {
+			if enforceTaskID(ctx, gitCl, opts, w, theme, taskID, primaryHead(headBefore)) {
+				if headAfter, err = compositeHead(ctx, gitCl, opts.AdditionalDirs); err != nil {
+					return fmt.Errorf("getting HEAD after amend: %w", err)
+				}
+			}
+		}
+
+		if opts.Owners != nil && headBefore != headAfter {
+			reverted, err := enforceOwners(ctx, gitCl, opts, w, theme, primaryHead(headBefore))
+			if err != nil {
+				return err
+			}
+			if reverted {
+				if headAfter, err = compositeHead(ctx, gitCl, opts.AdditionalDirs); err != nil {
+					return fmt.Errorf("getting HEAD after discarding iteration: %w", err)
+				}
+			}
+		}
+
+		var decision StepDecision
+		if step != nil {
+			if decision, err = stepPause(ctx, step, gitCl, w, headBefore, headAfter, iterStats, cumStats, opts.Currency, theme); err != nil {
+				return err
+			}
+		}
+		// Recorded once the operator has decided, so an iteration discarded
+		// at the pause leaves no diff artifact, and no checkpoint at a head
+		// that was thrown away for resume to start from.
+		if decision.Action != StepSkip || headBefore == headAfter {
+			if primaryHead(headBefore) != primaryHead(headAfter) {
+				limit := opts.DiffLimit
+				if limit == 0 {
+					limit = DefaultDiffLimit
+				}
+				path := DiffArtifactPath(opts.LogsDir, runID, i)
+				if err := writeDiffArtifact(ctx, gitCl, path, i, primaryHead(headBefore), primaryHead(headAfter), limit); err != nil {
+					fmt.Fprintf(w, "%s\n", theme.Muted.Render(fmt.Sprintf("Diff artifact skipped: %s", err))) //nolint:errcheck // display-only
+				}
+			}
+			checkpoints = append(checkpoints, checkpoint(i, clk.Now(), primaryHead(headAfter), iterStats, cumStats))
+			running = saveState(opts, cumStats, startTime, logPaths, logIters, repairs, tests, failures, checkpoints, state.StatusRunning)
+		}
+		if decision.Action == StepAbort {
+			cancelled = true
+			break
+		}
+		if decision.Action == StepSkip {
+			continue
+		}
+		feedback = decision.Feedback
+
+		if converge != nil {
+			done, count, changed := converge.Check(readPlan(opts.PlanFile))
+			if count > 0 {
+				RenderPlanUnchanged(w, changed, count, converge.MaxUnchanged(), theme)
+			}
+			if done {
+				RenderPlanConverged(w, converge.MaxUnchanged(), theme)
+				converged = true
+				break
+			}
+		}
+
+		slog.DebugContext(ctx, "loop: heads", "i", i, "before", headBefore, "after", headAfter, "offline", opts.Offline, "pending_pushes", pushes.pending)
 		if headBefore == headAfter {
 			abort, count := stale.Check(headAfter)
 			RenderStaleWarning(w, count, stale.MaxStale(), theme)
 			if abort {
 				RenderStaleAbort(w, stale.MaxStale(), theme)
+				sendEvent(ctx, opts, w, theme, notify.Payload{
+					Event: notify.EventStaleAbort, Iteration: cumStats.Iterations, TotalCost: cumStats.TotalCost,
+				})
 				staleAborted = true
 				break
 			}
+		} else if opts.Offline {
+			stale.Check(headAfter) // reset
+			commits += commitCount(ctx, gitCl, primaryHead(headBefore), primaryHead(headAfter))
+			RenderOfflineCommit(w, theme)
 		} else {
 			stale.Check(headAfter) // reset
 
-			// Push primary repo with fallback to --set-upstream.
-			if pushErr := gitCl.Push(opts.Branch); pushErr != nil {
-				RenderPushFallback(w, theme)
-				if upErr := gitCl.PushSetUpstream(opts.Branch); upErr != nil {
-					fmt.Fprintf(w, "%s\n", theme.Muted.Render(fmt.Sprintf("Push failed: %s", upErr))) //nolint:errcheck // display-only
+			n := commitCount(ctx, gitCl, primaryHead(headBefore), primaryHead(headAfter))
+			commits += n
+			pushes.pending += n
+			if now := clk.Now(); !pushes.due(now) {
+				RenderPushQueued(w, pushes, now, theme)
+			} else if n := pushes.pending; pushBranch(ctx, gitCl, opts, w, theme) {
+				pushes.pushed(now)
+				if pushes.debounced() {
+					RenderPushed(w, n, theme)
 				}
+				// The new head only exists on GitHub once it has been pushed.
+				reportPending(ctx, gitCl, opts, w, theme, fmt.Sprintf("Ralph %s running: iteration %d, %s so far", opts.Mode, i, opts.Currency.Format(cumStats.TotalCost, 2)))
 			}
+		}
 
-			// Push additional repos that changed.
-			pushAdditionalDirs(gitCl, opts, w, theme)
+		// Checked after pushing so the iteration's commits aren't lost.
+		if watchDisk(disk, w, theme) {
+			sendEvent(ctx, opts, w, theme, notify.Payload{
+				Event: notify.EventBudgetExceeded, Limit: notify.LimitDisk,
+				Iteration: cumStats.Iterations, TotalCost: cumStats.TotalCost,
+			})
+			diskAborted = true
+			break
 		}
 	}
 
-	summary.PrintBox(w, cumStats, time.Since(startTime), theme)
-	saveState(opts, cumStats, startTime, logPaths, cancelled, staleAborted)
-
-	if staleAborted {
+	flushPushes(ctx, gitCl, opts, w, theme, pushes, clk.Now())
+	wallTime := clk.Now().Sub(startTime)
+	runStatus := finalStatus(opts, cumStats, cancelled, staleAborted, converged, diskAborted, timedOut)
+	slog.DebugContext(ctx, "loop: finished", "status", runStatus, "iterations", cumStats.Iterations, "cost", cumStats.TotalCost, "commits", commits)
+	report := &summary.Report{
+		Mode: string(opts.Mode), Branch: opts.Branch, Status: string(runStatus),
+		StartedAt: startTime, WallTime: wallTime, Stats: cumStats, Commits: commits,
+	}
+	report.TasksDone, report.TasksTotal = planTasks(opts.PlanFile)
+	report.TasksCompleted = max(report.TasksDone-tasksBefore, 0)
+	renderSummary(w, report, opts, theme)
+	_, _ = summary.WriteJSON(opts.LogsDir, report) //nolint:errcheck // best-effort, like the state file
+	saveState(opts, cumStats, startTime, logPaths, logIters, repairs, tests, failures, checkpoints, runStatus)
+	reported = true
+	success := runStatus != state.StatusStaleAbort && runStatus != state.StatusCancelled && runStatus != state.StatusDiskLimit && runStatus != state.StatusTimeoutAbort
+	reportDone(ctx, gitCl, opts, w, theme, success,
+		fmt.Sprintf("Ralph %s %s after %d iteration(s), %s", opts.Mode, strings.ReplaceAll(string(runStatus), "_", " "), cumStats.Iterations, opts.Currency.Format(cumStats.TotalCost, 2)),
+		summary.Markdown(cumStats, wallTime, string(runStatus), opts.Currency))
+	sendEvent(ctx, opts, w, theme, notify.Payload{
+		Event: notify.EventRunFinish, Status: string(runStatus), Iteration: cumStats.Iterations, TotalCost: cumStats.TotalCost,
+	})
+
+	if staleAborted || converged {
 		return nil
 	}
+	if timedOut {
+		return fmt.Errorf("%d iterations in a row timed out after %s; raise phases.%s.iteration_timeout or check why the agent hangs", timeouts, opts.Timeout, opts.Mode)
+	}
+	if diskAborted {
+		return fmt.Errorf("workspace grew by more than %s; remove the large files or raise docker.disk_limit_mb", formatBytes(opts.DiskLimit))
+	}
 	if cancelled {
 		return ctx.Err() //nolint:wrapcheck // propagate context cancellation directly
 	}
 	return nil
 }
 
-// saveState persists a RunRecord to state.json. Best-effort — errors are silently ignored.
-func saveState(opts *Options, cumStats *stream.CumulativeStats, startTime time.Time, logPaths []string, cancelled, staleAborted bool) {
-	if opts.StateFile == "" {
-		return
+// stepPause shows the iteration's changes and waits for the operator. On skip
+// the primary repo is reset to where the iteration started; additional repos
+// are left as they are.
+func stepPause(ctx context.Context, r *bufio.Reader, gitCl GitClient, w io.Writer, headBefore, headAfter string,
+	iterStats *stream.IterationStats, cumStats *stream.CumulativeStats, cur pricing.Currency, theme *ui.Theme,
+) (StepDecision, error) {
+	primaryBefore := primaryHead(headBefore)
+
+	var diffStat string
+	if headBefore != headAfter {
+		stat, err := gitCl.DiffStat(ctx, primaryBefore, "HEAD")
+		if err != nil {
+			stat = fmt.Sprintf("(diff unavailable: %s)", err)
+		}
+		diffStat = stat
 	}
 
-	runStatus := state.StatusCompleted
+	d, err := promptStep(r, w, diffStat, iterStats, cumStats, cur, theme)
+	if err != nil {
+		return d, err
+	}
+	if d.Action == StepSkip && headBefore != headAfter {
+		if err := gitCl.ResetHard(ctx, primaryBefore); err != nil {
+			return d, fmt.Errorf("discarding iteration: %w", err)
+		}
+		fmt.Fprintln(w, theme.Warning.Render("  Iteration discarded.")) //nolint:errcheck // display-only
+	}
+	return d, nil
+}
+
+// finalStatus classifies how a run ended.
+func finalStatus(opts *Options, cumStats *stream.CumulativeStats, cancelled, staleAborted, converged, diskAborted, timedOut bool) state.RunStatus {
 	switch {
 	case staleAborted:
-		runStatus = state.StatusStaleAbort
+		return state.StatusStaleAbort
+	case timedOut:
+		return state.StatusTimeoutAbort
+	case diskAborted:
+		return state.StatusDiskLimit
+	case converged:
+		return state.StatusConverged
 	case cancelled:
-		runStatus = state.StatusCancelled
+		return state.StatusCancelled
 	case opts.MaxIterations > 0 && cumStats.Iterations >= opts.MaxIterations:
-		runStatus = state.StatusMaxIterations
+		return state.StatusMaxIterations
+	case opts.MaxCost > 0 && cumStats.TotalCost >= opts.MaxCost:
+		return state.StatusCostLimit
+	}
+	return state.StatusCompleted
+}
+
+// capabilitiesRecord converts the run's reported capabilities for state.json.
+func capabilitiesRecord(c *stream.Capabilities) *state.Capabilities {
+	if c == nil {
+		return nil
+	}
+	servers := make([]string, 0, len(c.MCPServers))
+	for _, s := range c.MCPServers {
+		servers = append(servers, s.Name)
 	}
+	return &state.Capabilities{
+		Model:        c.Model,
+		Version:      c.Version,
+		Tools:        c.Tools,
+		MCPServers:   servers,
+		MissingTools: c.Missing(),
+		FailedMCP:    c.FailedMCP(),
+	}
+}
 
-	record := state.RunRecord{
+// saveState records the run in state.json and returns the record. With
+// StatusRunning it is a checkpoint, which later calls replace.
+func saveState(opts *Options, cumStats *stream.CumulativeStats, startTime time.Time, logPaths []string, logIters []int, repairs []state.Repair, tests []state.TestResult, failures int, checkpoints []state.Checkpoint, runStatus state.RunStatus) *state.RunRecord {
+	record := &state.RunRecord{
 		Mode:           string(opts.Mode),
 		StartedAt:      startTime,
-		FinishedAt:     time.Now(),
+		FinishedAt:     clock.Or(opts.Clock).Now(),
 		Iterations:     cumStats.Iterations,
 		TotalCost:      cumStats.TotalCost,
 		PeakContext:    cumStats.PeakContext,
 		SubagentTokens: cumStats.SubagentTokens,
 		Status:         runStatus,
 		LogFiles:       logPaths,
+		LogIterations:  logIters,
+		Tags:           opts.Tags,
+		Note:           opts.Note,
+		Profile:        opts.Profile,
+		Experiment:     opts.Experiment,
+		Package:        opts.Package,
+		HooksSkipped:   opts.SkipHooks,
+		Repairs:        repairs,
+		Tests:          tests,
+		ClaudeFailures: failures,
+		PlanApproval:   opts.PlanApproval,
+		Branch:         opts.Branch,
+		PlanFile:       opts.PlanFile,
+		SpecsDir:       opts.SpecsDir,
+		MaxIterations:  opts.MaxIterations,
+		MaxCost:        opts.MaxCost,
+		ResumeOf:       opts.ResumeOf,
+		ReplayOf:       opts.ReplayOf,
+		Origin:         opts.Origin,
+		Capabilities:   capabilitiesRecord(cumStats.Capabilities),
+		Checkpoints:    checkpoints,
 	}
+	putRecord(opts, record)
+	return record
+}
 
+// putRecord writes record to state.json, replacing the run's last
+// checkpoint. Best-effort — errors are silently ignored.
+func putRecord(opts *Options, record *state.RunRecord) {
+	if opts.StateFile == "" {
+		return
+	}
 	st, _ := state.Load(opts.StateFile) //nolint:errcheck // best-effort
 	if st == nil {
 		st = &state.State{}
 	}
-	st.Runs = append(st.Runs, record)
+	st.Put(*record)
 	_ = state.Save(opts.StateFile, st) //nolint:errcheck // best-effort
 }
 
+// checkpoint records iteration i as completed at head.
+func checkpoint(i int, at time.Time, head string, iterStats *stream.IterationStats, cumStats *stream.CumulativeStats) state.Checkpoint {
+	c := state.Checkpoint{Iteration: i, At: at, Head: head, TotalCost: cumStats.TotalCost}
+	if iterStats != nil {
+		c.Cost = iterStats.Cost
+		c.PeakContext = iterStats.PeakContext
+		c.Tests = iterStats.Tests
+	}
+	return c
+}
+
+// planTasks counts the plan's done and total tasks; both are 0 when the
+// plan can't be read.
+func planTasks(planFile string) (done, total int) {
+	tasks, err := status.ParsePlan(planFile)
+	if err != nil {
+		return 0, 0
+	}
+	for _, t := range tasks {
+		if t.Done {
+			done++
+		}
+	}
+	return done, len(tasks)
+}
+
 // compositeHead concatenates the HEAD from the primary repo and all additional
 // dirs into a single string for stale detection. Any repo changing resets stale.
-func compositeHead(gitCl GitClient, additionalDirs []string) (string, error) {
-	head, err := gitCl.Head()
+func compositeHead(ctx context.Context, gitCl GitClient, additionalDirs []string) (string, error) {
+	head, err := gitCl.Head(ctx)
 	if err != nil {
 		return "", fmt.Errorf("primary HEAD: %w", err)
 	}
 	for _, dir := range additionalDirs {
-		h, err := gitCl.HeadIn(dir)
+		h, err := gitCl.HeadIn(ctx, dir)
 		if err != nil {
 			return "", fmt.Errorf("HeadIn(%s): %w", dir, err)
 		}
@@ -195,48 +662,81 @@ func compositeHead(gitCl GitClient, additionalDirs []string) (string, error) {
 	return head, nil
 }
 
+// primaryHead extracts the primary repo's HEAD from a compositeHead value.
+func primaryHead(composite string) string {
+	head, _, _ := strings.Cut(composite, ":")
+	return head
+}
+
 // pushAdditionalDirs pushes all additional repos after an iteration where changes were detected.
-func pushAdditionalDirs(gitCl GitClient, opts *Options, w io.Writer, theme *ui.Theme) {
+func pushAdditionalDirs(ctx context.Context, gitCl GitClient, opts *Options, w io.Writer, theme *ui.Theme) {
 	for _, dir := range opts.AdditionalDirs {
-		if pushErr := gitCl.PushIn(dir, opts.Branch); pushErr != nil {
+		if pushErr := gitCl.PushIn(ctx, dir, opts.Branch); pushErr != nil {
 			fmt.Fprintf(w, "%s\n", theme.Muted.Render(fmt.Sprintf("Push failed for %s, trying --set-upstream...", dir))) //nolint:errcheck // display-only
-			if upErr := gitCl.PushSetUpstreamIn(dir, opts.Branch); upErr != nil {
+			if upErr := gitCl.PushSetUpstreamIn(ctx, dir, opts.Branch); upErr != nil {
 				fmt.Fprintf(w, "%s\n", theme.Muted.Render(fmt.Sprintf("Push failed for %s: %s", dir, upErr))) //nolint:errcheck // display-only
 			}
 		}
 	}
 }
 
-// claudeArgs builds the argument list for the claude CLI invocation.
-func claudeArgs(additionalDirs []string) []string {
-	args := make([]string, 0, 7+2*len(additionalDirs))
-	args = append(args,
-		"-p",
-		"--dangerously-skip-permissions",
-		"--output-format=stream-json",
-		"--model", "opus",
-		"--verbose",
-	)
-	for _, dir := range additionalDirs {
-		args = append(args, "--add-dir", dir)
+// agentOf returns the agent opts runs, defaulting to claude.
+func agentOf(opts *Options) agent.Agent {
+	if opts.Agent == nil {
+		return agent.Claude{}
 	}
-	return args
+	return opts.Agent
 }
 
-// runClaude invokes the claude CLI, tees output to the log writer, and returns iteration stats.
-func runClaude(ctx context.Context, opts *Options, logW, displayW io.Writer, theme *ui.Theme) (*stream.IterationStats, error) {
-	args := claudeArgs(opts.AdditionalDirs)
-
-	cmd := exec.CommandContext(ctx, "claude", args...) //nolint:gosec // args are static
+// model returns the model the iteration runs on: opts.Model, else claude's
+// default when the agent is claude. Other agents' defaults are their own
+// business, so it is empty for them.
+func (o *Options) model() string {
+	if o.Model == "" && agentOf(o).Name() == agent.NameClaude {
+		return DefaultModel
+	}
+	return o.Model
+}
 
-	cmd.Stderr = os.Stderr
+// maxInstructions caps the instructions file passed on the command line,
+// well under the kernel's per-argument limit.
+const maxInstructions = 64 << 10
+
+// invocation is what opts asks of the agent this iteration. The
+// instructions file is re-read each time so edits the agent makes to it
+// reach the next iteration; a missing or oversized file is left for the
+// agent to read itself.
+func invocation(opts *Options) agent.Invocation {
+	inv := agent.Invocation{Model: opts.Model, AdditionalDirs: opts.AdditionalDirs, Settings: opts.Settings, NoPromptCache: opts.NoPromptCache}
+	if opts.Instructions != "" && !opts.NoPromptCache {
+		if data, err := os.ReadFile(opts.Instructions); err == nil && len(data) <= maxInstructions {
+			inv.Instructions = string(data)
+		}
+	}
+	return inv
+}
 
-	promptContent, err := os.ReadFile(opts.PromptFile)
+// assemblePrompt returns the prompt file and the dynamic header (plan,
+// specs, branch and per-iteration context) that claude reads on stdin. The
+// prompt file comes first so the unchanging prefix is served from the
+// prompt cache on every iteration; with NoPromptCache the header leads.
+func assemblePrompt(opts *Options) ([]byte, error) {
+	promptContent, err := promptBody(opts)
 	if err != nil {
-		return nil, fmt.Errorf("reading prompt file: %w", err)
+		return nil, err
+	}
+	if opts.NoPromptCache {
+		return bytes.Join([][]byte{promptHeader(opts), promptContent}, nil), nil
 	}
+	if len(promptContent) > 0 && !bytes.HasSuffix(promptContent, []byte("\n")) {
+		promptContent = append(promptContent, '\n')
+	}
+	return bytes.Join([][]byte{promptContent, []byte("---\n"), bytes.TrimSuffix(promptHeader(opts), []byte("---\n"))}, nil), nil
+}
 
-	// Prepend dynamic context so Claude knows the branch-specific paths.
+// promptHeader is the dynamic context prepended to the prompt file so Claude
+// knows the branch-specific paths.
+func promptHeader(opts *Options) []byte {
 	var header bytes.Buffer
 	fmt.Fprintf(&header, "PLAN_FILE: %s\n", opts.PlanFile)
 	fmt.Fprintf(&header, "SPECS_DIR: %s\n", opts.SpecsDir)
@@ -244,10 +744,72 @@ func runClaude(ctx context.Context, opts *Options, logW, displayW io.Writer, the
 	if len(opts.AdditionalDirs) > 0 {
 		fmt.Fprintf(&header, "ADDITIONAL_REPOS: %s\n", strings.Join(opts.AdditionalDirs, ", "))
 	}
+	if opts.ScratchDir != "" {
+		fmt.Fprintf(&header, "SCRATCH_DIR: %s (private notes, never committed, max %s)\n", opts.ScratchDir, formatBytes(opts.ScratchLimit))
+	}
+	if opts.Package != "" {
+		fmt.Fprintf(&header, "PACKAGE: %s (scope this run to this package's directory)\n", opts.Package)
+	}
+	header.WriteString(ownersHeader(opts.Owners))
+	if opts.TaskID != "" {
+		fmt.Fprintf(&header, "TASK_ID: %s\n", opts.TaskID)
+	}
+	if opts.Feedback != "" {
+		fmt.Fprintf(&header, "OPERATOR_FEEDBACK: %s\n", opts.Feedback)
+	}
 	header.WriteString("---\n")
+	return header.Bytes()
+}
 
-	combined := bytes.Join([][]byte{header.Bytes(), promptContent}, nil)
-	cmd.Stdin = bytes.NewReader(combined)
+// renderPromptTokens shows the token count of the prompt the iteration is
+// about to send, when a counter is configured.
+func renderPromptTokens(ctx context.Context, opts *Options, w io.Writer, theme *ui.Theme) {
+	if opts.TokenCounter == nil {
+		return
+	}
+	prompt, err := assemblePrompt(opts)
+	if err != nil {
+		return // runClaude reports the unreadable prompt
+	}
+	n, exact := opts.TokenCounter.Count(ctx, string(prompt), opts.model())
+	RenderPromptTokens(w, n, exact, theme)
+}
+
+// skipHooksEnv points the agent's git at an empty hooks directory, for
+// git.hooks.skip. Config from the environment outranks the repo's own, where
+// husky sets core.hooksPath.
+var skipHooksEnv = []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=core.hooksPath", "GIT_CONFIG_VALUE_0=/dev/null"}
+
+// runClaude invokes the agent CLI, logs its output, and returns iteration
+// stats. Claude's stream-json is logged as it arrives; other agents' output
+// is logged as the stream events it translates to, so reports and replays
+// read every agent's logs alike.
+func runClaude(ctx context.Context, opts *Options, logW, displayW io.Writer, theme *ui.Theme) (*stream.IterationStats, error) {
+	a := agentOf(opts)
+	argv := a.Command(invocation(opts))
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...) //nolint:gosec // argv comes from the fixed agent definitions
+	cmd.WaitDelay = killGrace
+
+	stderr := opts.Redactor.Writer(os.Stderr)
+	cmd.Stderr = stderr
+	var env []string
+	if opts.NoPromptCache && a.Name() == agent.NameClaude {
+		env = append(env, "DISABLE_PROMPT_CACHING=1")
+	}
+	if opts.SkipHooks != "" {
+		env = append(env, skipHooksEnv...)
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	prompt, err := assemblePrompt(opts)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = bytes.NewReader(prompt)
+	slog.DebugContext(ctx, "loop: agent", "argv", argv, "prompt_bytes", len(prompt), "prompt_cache", !opts.NoPromptCache)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -255,13 +817,22 @@ func runClaude(ctx context.Context, opts *Options, logW, displayW io.Writer, the
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("starting claude: %w", err)
+		return nil, fmt.Errorf("starting %s: %w", a.Name(), err)
 	}
 
-	tee := io.TeeReader(stdout, logW)
-	stats, processErr := stream.Process(tee, displayW, theme)
+	sinks := append([]stream.Sink{stream.NewFormatter(displayW, theme).SetParamWidth(opts.ParamWidth)}, opts.Sinks...)
+	out := opts.Redactor.Reader(stdout)
+	if a.Name() == agent.NameClaude {
+		out = io.TeeReader(out, logW)
+	} else {
+		sinks = append(sinks, stream.NewJSONSink(logW))
+	}
+	src, closeFilters := filterEvents(ctx, opts, a.Events(out), displayW, theme)
+	stats, processErr := stream.ProcessSource(src, sinks...)
+	closeFilters()
 
 	waitErr := cmd.Wait()
+	_ = stderr.Flush() //nolint:errcheck // best-effort terminal output
 
 	// On context cancellation, return whatever stats we collected.
 	if ctx.Err() != nil {
@@ -272,7 +843,7 @@ func runClaude(ctx context.Context, opts *Options, logW, displayW io.Writer, the
 		return stats, fmt.Errorf("processing stream: %w", processErr)
 	}
 	if waitErr != nil {
-		return stats, fmt.Errorf("claude exited: %w", waitErr)
+		return stats, fmt.Errorf("%s exited: %w", a.Name(), waitErr)
 	}
 
 	return stats, nil
@@ -6,15 +6,22 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/notify"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
 	"github.com/benwilkes9/ralph-cli/internal/state"
 	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/summary"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
 
@@ -24,14 +31,32 @@ type fakeGit struct {
 	heads          []string
 	headIdx        int
 	pushErr        error
+	pushes         int
 	upstreamCalled bool
 	// Additional dirs support.
 	additionalHeads map[string][]string // dir → sequence of HEADs
 	additionalIdx   map[string]int
 	pushedDirs      []string
+	// Step mode support.
+	diffStat string
+	resetTo  []string
+	// Diff artifact support.
+	patch string
+	// Task id enforcement support.
+	subjects   []string
+	lastMsg    string
+	amendedMsg string
+	unpushed   int
+	// Repo health support: each CheckHealth call consumes one entry; once
+	// exhausted the repo reports healthy on main.
+	health     []*git.Health
+	repairArgs []*git.Health
+	repairErr  error
+	// CODEOWNERS enforcement support.
+	changed []string
 }
 
-func (f *fakeGit) Head() (string, error) {
+func (f *fakeGit) Head(_ context.Context) (string, error) {
 	if len(f.heads) == 0 {
 		return "0000000000000000000000000000000000000000", nil
 	}
@@ -40,14 +65,17 @@ func (f *fakeGit) Head() (string, error) {
 	return sha, nil
 }
 
-func (f *fakeGit) Push(_ string) error { return f.pushErr }
+func (f *fakeGit) Push(_ context.Context, _ string) error {
+	f.pushes++
+	return f.pushErr
+}
 
-func (f *fakeGit) PushSetUpstream(_ string) error {
+func (f *fakeGit) PushSetUpstream(_ context.Context, _ string) error {
 	f.upstreamCalled = true
 	return nil
 }
 
-func (f *fakeGit) HeadIn(dir string) (string, error) {
+func (f *fakeGit) HeadIn(_ context.Context, dir string) (string, error) {
 	heads, ok := f.additionalHeads[dir]
 	if !ok || len(heads) == 0 {
 		return "0000000000000000000000000000000000000000", nil
@@ -60,27 +88,91 @@ func (f *fakeGit) HeadIn(dir string) (string, error) {
 	return heads[idx], nil
 }
 
-func (f *fakeGit) PushIn(dir, _ string) error {
+func (f *fakeGit) PushIn(_ context.Context, dir, _ string) error {
 	f.pushedDirs = append(f.pushedDirs, dir)
 	return f.pushErr
 }
 
-func (f *fakeGit) PushSetUpstreamIn(dir, _ string) error {
+func (f *fakeGit) PushSetUpstreamIn(_ context.Context, dir, _ string) error {
 	f.pushedDirs = append(f.pushedDirs, dir)
 	f.upstreamCalled = true
 	return nil
 }
 
+func (f *fakeGit) DiffStat(_ context.Context, _, _ string) (string, error) {
+	return f.diffStat, nil
+}
+
+func (f *fakeGit) Diff(_ context.Context, _, _ string) (string, error) {
+	return f.patch, nil
+}
+
+func (f *fakeGit) ResetHard(_ context.Context, rev string) error {
+	f.resetTo = append(f.resetTo, rev)
+	return nil
+}
+
+func (f *fakeGit) CommitSubjects(_ context.Context, _, _ string) ([]string, error) {
+	return f.subjects, nil
+}
+
+func (f *fakeGit) LastCommitMessage(_ context.Context) (string, error) { return f.lastMsg, nil }
+
+func (f *fakeGit) AmendCommitMessage(_ context.Context, message string) error {
+	f.amendedMsg = message
+	return nil
+}
+
+func (f *fakeGit) UnpushedCount(_ context.Context, _ string) (int, error) { return f.unpushed, nil }
+
+func (f *fakeGit) ChangedFiles(_ context.Context, _, _ string) ([]string, error) {
+	return f.changed, nil
+}
+
+func (f *fakeGit) CheckHealth(_ context.Context) (*git.Health, error) {
+	if len(f.health) == 0 {
+		return &git.Health{Branch: "main"}, nil
+	}
+	h := f.health[0]
+	f.health = f.health[1:]
+	return h, nil
+}
+
+func (f *fakeGit) Repair(_ context.Context, h *git.Health, branch string) ([]string, error) {
+	f.repairArgs = append(f.repairArgs, h)
+	if f.repairErr != nil {
+		return nil, f.repairErr
+	}
+	return []string{"aborted in-progress rebase", "re-attached " + branch + " at abc"}, nil
+}
+
 type fakeClaude struct {
-	stats  *stream.IterationStats
-	err    error
-	called int
+	stats     *stream.IterationStats
+	err       error
+	called    int
+	feedbacks []string
+	taskIDs   []string
+	perIter   []*stream.IterationStats      // when set, iteration i returns perIter[i-1] instead of stats
+	onRun     func(call int, opts *Options) // when set, called on each run, e.g. to edit the plan
+	errs      []error                       // when set, call i returns errs[i-1] instead of err
 }
 
-func (f *fakeClaude) Run(_ context.Context, _ *Options, logW, _ io.Writer) (*stream.IterationStats, error) {
+func (f *fakeClaude) Run(_ context.Context, opts *Options, logW, _ io.Writer) (*stream.IterationStats, error) {
 	f.called++
+	f.feedbacks = append(f.feedbacks, opts.Feedback)
+	f.taskIDs = append(f.taskIDs, opts.TaskID)
 	fmt.Fprintln(logW, `{}`) //nolint:errcheck // test helper write
-	return f.stats, f.err
+	if f.onRun != nil {
+		f.onRun(f.called, opts)
+	}
+	err := f.err
+	if f.called <= len(f.errs) {
+		err = f.errs[f.called-1]
+	}
+	if f.called <= len(f.perIter) {
+		return f.perIter[f.called-1], err
+	}
+	return f.stats, err
 }
 
 // --- helpers ---
@@ -125,6 +217,21 @@ func TestRun_MaxIterationsExits(t *testing.T) {
 	assert.Equal(t, state.StatusMaxIterations, st.Runs[0].Status)
 }
 
+func TestRun_RecordsSkippedHooks(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 1
+	opts.SkipHooks = "hooks need a database"
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"sha-a", "sha-b"}}, &fakeClaude{stats: iterStats()}))
+	assert.Contains(t, buf.String(), "skipped: hooks need a database")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	assert.Equal(t, "hooks need a database", st.Runs[0].HooksSkipped)
+}
+
 func TestRun_CancellationBeforeLoop(t *testing.T) {
 	opts := baseOpts(t)
 	opts.MaxIterations = 10
@@ -166,6 +273,234 @@ func TestRun_StaleAbort(t *testing.T) {
 	assert.Equal(t, state.StatusStaleAbort, st.Runs[0].Status)
 }
 
+func TestRun_PlanConverged(t *testing.T) {
+	opts := baseOpts(t)
+	opts.Mode = ModePlan
+	opts.MaxIterations = 10
+	opts.PlanFile = filepath.Join(t.TempDir(), "IMPLEMENTATION_PLAN.md")
+
+	// Every iteration commits, so stale detection never fires; only the
+	// first actually changes the plan.
+	g := &fakeGit{heads: []string{"sha-a", "sha-b"}}
+	c := &fakeClaude{stats: iterStats(), onRun: func(call int, opts *Options) {
+		plan := "### Task 1 -- Schema\n- [ ] Create tables\n"
+		if call > 1 {
+			plan += strings.Repeat("\n", call) // cosmetic churn
+		}
+		require.NoError(t, os.WriteFile(opts.PlanFile, []byte(plan), 0o600))
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	assert.Equal(t, 1+DefaultMaxPlanUnchanged, c.called)
+	assert.Contains(t, buf.String(), "Plan converged")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	assert.Equal(t, state.StatusConverged, st.Runs[0].Status)
+}
+
+func TestRun_BuildIgnoresPlanConvergence(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 4
+	opts.PlanFile = filepath.Join(t.TempDir(), "IMPLEMENTATION_PLAN.md")
+	require.NoError(t, os.WriteFile(opts.PlanFile, []byte("### Task 1 -- Schema\n"), 0o600))
+
+	c := &fakeClaude{stats: iterStats()}
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"sha-a", "sha-b"}}, c))
+	assert.Equal(t, 4, c.called)
+}
+
+type fakeCounter struct {
+	prompt, model string
+}
+
+func (f *fakeCounter) Count(_ context.Context, prompt, model string) (int, bool) {
+	f.prompt, f.model = prompt, model
+	return 12_345, false
+}
+
+func TestRun_PromptTokens(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 1
+	opts.PlanFile = "plan.md"
+	opts.PromptFile = filepath.Join(t.TempDir(), "PROMPT_build.md")
+	require.NoError(t, os.WriteFile(opts.PromptFile, []byte("Build the next task.\n"), 0o600))
+	counter := &fakeCounter{}
+	opts.TokenCounter = counter
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"sha-a", "sha-b"}}, &fakeClaude{stats: iterStats()}))
+
+	assert.Contains(t, counter.prompt, "PLAN_FILE: plan.md\n")
+	assert.True(t, strings.HasPrefix(counter.prompt, "Build the next task.\n---\nPLAN_FILE:"))
+	assert.Equal(t, DefaultModel, counter.model)
+	assert.Contains(t, buf.String(), "~12.3k tokens")
+}
+
+func TestAssemblePrompt_Order(t *testing.T) {
+	opts := baseOpts(t)
+	opts.PlanFile = "plan.md"
+	opts.Branch = "feat/x"
+	opts.TaskID = "T1"
+	opts.PromptFile = filepath.Join(t.TempDir(), "PROMPT_build.md")
+	require.NoError(t, os.WriteFile(opts.PromptFile, []byte("Build the next task."), 0o600))
+
+	cached, err := assemblePrompt(opts)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(cached), "Build the next task.\n---\n"))
+	assert.True(t, strings.HasSuffix(string(cached), "TASK_ID: T1\n"))
+	assert.NotContains(t, string(cached), "PACKAGE:")
+
+	opts.Package = "apps/web"
+	scoped, err := assemblePrompt(opts)
+	require.NoError(t, err)
+	assert.Contains(t, string(scoped), "BRANCH: feat/x\nPACKAGE: apps/web (scope this run to this package's directory)\n")
+	opts.Package = ""
+
+	opts.NoPromptCache = true
+	legacy, err := assemblePrompt(opts)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(legacy), "PLAN_FILE: plan.md\n"))
+	assert.True(t, strings.HasSuffix(string(legacy), "---\nBuild the next task."))
+}
+
+func TestInvocation_Instructions(t *testing.T) {
+	opts := baseOpts(t)
+	opts.Instructions = filepath.Join(t.TempDir(), "AGENTS.md")
+	assert.Empty(t, invocation(opts).Instructions, "missing file is skipped")
+
+	require.NoError(t, os.WriteFile(opts.Instructions, []byte("# Conventions\n"), 0o600))
+	assert.Equal(t, "# Conventions\n", invocation(opts).Instructions)
+
+	opts.NoPromptCache = true
+	inv := invocation(opts)
+	assert.Empty(t, inv.Instructions)
+	assert.True(t, inv.NoPromptCache)
+}
+
+type fakeReporter struct {
+	events  []string
+	summary string
+	err     error
+}
+
+func (f *fakeReporter) Pending(_ context.Context, _, description string) error {
+	f.events = append(f.events, "pending: "+description)
+	return f.err
+}
+
+func (f *fakeReporter) Done(_ context.Context, _ string, success bool, title, summary string) error {
+	f.events = append(f.events, fmt.Sprintf("done %t: %s", success, title))
+	f.summary = summary
+	return f.err
+}
+
+func TestRun_ReportsGitHubStatus(t *testing.T) {
+	opts := baseOpts(t)
+	rep := &fakeReporter{}
+	opts.Reporter = rep
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"sha-a", "sha-b"}}, &fakeClaude{stats: iterStats()}))
+
+	require.Len(t, rep.events, 3)
+	assert.Equal(t, "pending: Ralph build running", rep.events[0])
+	assert.Contains(t, rep.events[1], "pending: Ralph build running: iteration 1")
+	assert.Contains(t, rep.events[2], "done true: Ralph build max iterations after 1 iteration(s)")
+	assert.Contains(t, rep.summary, "| Outcome | max_iterations |")
+}
+
+func TestRun_ReportsGitHubFailure(t *testing.T) {
+	opts := baseOpts(t)
+	rep := &fakeReporter{err: errors.New("403 Forbidden")}
+	opts.Reporter = rep
+
+	var buf bytes.Buffer
+	err := run(context.Background(), opts, &buf, runTheme, &fakeGit{}, &fakeClaude{err: errors.New("exit 1")})
+	require.Error(t, err)
+
+	require.Len(t, rep.events, 2)
+	assert.Equal(t, "done false: Ralph build failed", rep.events[1])
+	assert.Contains(t, rep.summary, "exit 1")
+	assert.Contains(t, buf.String(), "GitHub status not updated: 403 Forbidden")
+}
+
+type fakeNotifier struct {
+	events []notify.Payload
+}
+
+func (f *fakeNotifier) Notify(_ context.Context, p *notify.Payload) error {
+	f.events = append(f.events, *p)
+	return nil
+}
+
+func TestRun_SendsLifecycleEvents(t *testing.T) {
+	opts := baseOpts(t)
+	n := &fakeNotifier{}
+	opts.Notifier = n
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"sha-a", "sha-b"}}, &fakeClaude{stats: iterStats()}))
+
+	var names []string
+	for _, e := range n.events {
+		names = append(names, e.Event)
+	}
+	assert.Equal(t, []string{notify.EventRunStart, notify.EventIteration, notify.EventBudgetExceeded, notify.EventRunFinish}, names)
+	assert.Equal(t, opts.Branch, n.events[0].Branch)
+	assert.Equal(t, "build", n.events[0].Mode)
+	assert.Equal(t, 1, n.events[1].Iteration)
+	assert.Equal(t, notify.LimitIterations, n.events[2].Limit)
+	assert.Equal(t, string(state.StatusMaxIterations), n.events[3].Status)
+	assert.InDelta(t, iterStats().Cost, n.events[3].TotalCost, 1e-9)
+}
+
+func TestRun_SendsFailedRunFinish(t *testing.T) {
+	opts := baseOpts(t)
+	n := &fakeNotifier{}
+	opts.Notifier = n
+
+	var buf bytes.Buffer
+	err := run(context.Background(), opts, &buf, runTheme, &fakeGit{}, &fakeClaude{err: errors.New("exit 1")})
+	require.Error(t, err)
+
+	require.Len(t, n.events, 2)
+	assert.Equal(t, notify.EventRunFinish, n.events[1].Event)
+	assert.Equal(t, "failed", n.events[1].Status)
+	assert.Contains(t, n.events[1].Error, "exit 1")
+}
+
+func TestRun_PricesIterationsWithoutCost(t *testing.T) {
+	opts := baseOpts(t)
+	opts.Model = "claude-sonnet-4-5-20250929"
+	opts.Pricing = pricing.Table{"sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30}}
+	opts.Currency = pricing.Currency{Code: "EUR", PerUSD: 0.5}
+	// A gateway that reports usage but no total_cost_usd.
+	stats := &stream.IterationStats{InputTokens: 1_000_000, OutputTokens: 100_000, CacheReadTokens: 1_000_000}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"sha-a", "sha-b"}}, &fakeClaude{stats: stats}))
+
+	assert.InDelta(t, 4.8, stats.Cost, 1e-9)
+	assert.Contains(t, buf.String(), "€2.4000")
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	assert.InDelta(t, 4.8, st.Runs[0].TotalCost, 1e-9) // recorded in dollars
+}
+
+func TestRun_KeepsReportedCost(t *testing.T) {
+	opts := baseOpts(t)
+	opts.Pricing = pricing.Default
+	stats := &stream.IterationStats{Cost: 0.25, InputTokens: 1_000_000}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"sha-a", "sha-b"}}, &fakeClaude{stats: stats}))
+	assert.InDelta(t, 0.25, stats.Cost, 1e-9)
+}
+
 func TestRun_AlternatingHeadsNoStale(t *testing.T) {
 	opts := baseOpts(t)
 	opts.MaxIterations = 3
@@ -206,27 +541,222 @@ func TestRun_PushFallback(t *testing.T) {
 	assert.True(t, g.upstreamCalled, "PushSetUpstream should be called on push failure")
 }
 
-func TestClaudeArgs(t *testing.T) {
-	args := claudeArgs(nil)
-	assert.Contains(t, args, "-p")
-	assert.Contains(t, args, "--dangerously-skip-permissions")
-	assert.Contains(t, args, "--output-format=stream-json")
-	assert.Contains(t, args, "--model")
+func TestRun_OfflineKeepsCommitsLocal(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 1
+	opts.Offline = true
+	opts.AdditionalDirs = []string{"/workspace/repo-b"}
+
+	g := &fakeGit{
+		heads: []string{"sha-a", "sha-b"},
+		additionalHeads: map[string][]string{
+			"/workspace/repo-b": {"sha-x", "sha-x", "sha-y"},
+		},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, &fakeClaude{stats: iterStats()}))
+
+	assert.Zero(t, g.pushes)
+	assert.False(t, g.upstreamCalled)
+	assert.Empty(t, g.pushedDirs)
+	assert.Contains(t, buf.String(), "ralph sync")
+}
+
+func TestRun_ClockStampsStateAndLogs(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 2
+	start := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	opts.Clock = &clock.Fake{T: start} // frozen: both iterations start in the same second
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b", "c", "d"}}, &fakeClaude{stats: iterStats()}))
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	r := st.Runs[0]
+	assert.True(t, r.StartedAt.Equal(start))
+	assert.True(t, r.FinishedAt.Equal(start))
+	require.Len(t, r.LogFiles, 2)
+	assert.Equal(t, "20260301-093000.jsonl", filepath.Base(r.LogFiles[0]))
+	assert.Equal(t, "20260301-093000-2.jsonl", filepath.Base(r.LogFiles[1]), "same-second logs must not overwrite each other")
+}
+
+func TestRun_SummaryCountsTasksAndCommits(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 1
+	opts.SummaryRows = []string{summary.RowTasks, summary.RowCommits}
+	opts.PlanFile = filepath.Join(t.TempDir(), "plan.md")
+	start := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	opts.Clock = &clock.Fake{T: start}
+	require.NoError(t, os.WriteFile(opts.PlanFile, []byte(routedPlan), 0o600))
+	c := &fakeClaude{stats: iterStats(), onRun: func(int, *Options) {
+		done := strings.Replace(routedPlan, "- [ ] **Status:** Incomplete", "- [x] **Status:** Complete", 1)
+		require.NoError(t, os.WriteFile(opts.PlanFile, []byte(done), 0o600))
+	}}
+	g := &fakeGit{heads: []string{"a", "a", "b"}, subjects: []string{"feat: one", "test: two"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+	out := buf.String()
+	assert.Contains(t, out, "Tasks completed  +1 (2/3 done)")
+	assert.Contains(t, out, "Commits          2")
+	assert.NotContains(t, out, "Peak context")
+
+	data, err := os.ReadFile(filepath.Join(opts.LogsDir, summary.JSONName(start)))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"commits": 2`)
+	assert.Contains(t, string(data), `"tasks_completed": 1`)
+}
+
+func TestRun_SkipsFailedIteration(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 3
+	opts.OnClaudeError = ClaudeErrorPolicy{Skip: true}
+	c := &fakeClaude{stats: iterStats(), errs: []error{nil, errors.New("exit 1")}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b", "c", "d"}}, c))
+	assert.Equal(t, 3, c.called)
+	assert.Contains(t, buf.String(), "Claude failed: exit 1. Skipping to the next iteration.")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Runs[0].ClaudeFailures)
+	assert.Equal(t, 3, st.Runs[0].Iterations)
+}
+
+func TestRun_RetriesFailedIteration(t *testing.T) {
+	opts := baseOpts(t)
+	opts.OnClaudeError = ClaudeErrorPolicy{Retries: 2}
+	c := &fakeClaude{stats: iterStats(), errs: []error{errors.New("exit 1"), nil}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b"}}, c))
+	assert.Equal(t, 2, c.called, "the retry doesn't use up an iteration")
+	assert.Contains(t, buf.String(), "Retrying iteration 1 (1/2).")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Runs[0].ClaudeFailures)
+	assert.Equal(t, state.StatusMaxIterations, st.Runs[0].Status)
+	assert.Equal(t, []int{1, 1}, st.Runs[0].LogIterations, "both attempts logged for iteration 1")
+}
+
+func TestRun_AbortsWhenRetriesRunOut(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 3
+	opts.OnClaudeError = ClaudeErrorPolicy{Retries: 1}
+	c := &fakeClaude{err: errors.New("exit 1")}
+
+	var buf bytes.Buffer
+	err := run(context.Background(), opts, &buf, runTheme, &fakeGit{}, c)
+	require.ErrorContains(t, err, "running claude: exit 1")
+	assert.Equal(t, 2, c.called)
 }
 
-func TestClaudeArgs_WithAdditionalDirs(t *testing.T) {
-	args := claudeArgs([]string{"/workspace/repo-a", "/workspace/repo-b"})
-	assert.Contains(t, args, "--add-dir")
+func TestRun_CostLimitStops(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 10
+	opts.MaxCost = 0.02
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b"}}, c))
+	assert.Equal(t, 2, c.called, "no iteration starts once the cap is spent")
+	assert.Contains(t, buf.String(), "Cost cap")
+	assert.Contains(t, buf.String(), "Reached cost cap: $0.02 spent of $0.02")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	assert.Equal(t, state.StatusCostLimit, st.Runs[0].Status)
+	assert.True(t, st.Runs[0].Resumable())
+	assert.InDelta(t, 0.02, st.Runs[0].MaxCost, 1e-9, "the cap is kept for ralph resume")
+}
 
-	// Verify both dirs appear after --add-dir flags.
-	count := 0
-	for i, a := range args {
-		if a == "--add-dir" {
-			count++
-			require.Less(t, i+1, len(args))
+func TestRun_CheckpointsEachIteration(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 3
+	g := &fakeGit{heads: []string{"a", "b", "c", "d"}}
+	c := &fakeClaude{stats: iterStats(), onRun: func(call int, opts *Options) {
+		st, err := state.Load(opts.StateFile)
+		require.NoError(t, err)
+		if call == 1 {
+			assert.Empty(t, st.Runs, "nothing is saved before an iteration completes")
+			return
 		}
+		require.Len(t, st.Runs, 1, "checkpoints replace each other")
+		r := st.Runs[0]
+		assert.Equal(t, state.StatusRunning, r.Status)
+		assert.Equal(t, call-1, r.Iterations)
+		require.Len(t, r.Checkpoints, call-1)
+		assert.Len(t, r.LogFiles, call-1)
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	r := st.Runs[0]
+	assert.Equal(t, state.StatusMaxIterations, r.Status)
+	require.Len(t, r.Checkpoints, 3)
+	assert.Equal(t, 2, r.Checkpoints[1].Iteration)
+	assert.NotEmpty(t, r.Checkpoints[1].Head)
+	assert.InDelta(t, 0.01, r.Checkpoints[1].Cost, 1e-9)
+	assert.InDelta(t, 0.02, r.Checkpoints[1].TotalCost, 1e-9)
+	assert.Equal(t, 1000, r.Checkpoints[1].PeakContext)
+}
+
+func TestRun_FailedRunKeepsCheckpoints(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 5
+	c := &fakeClaude{stats: iterStats(), errs: []error{nil, errors.New("exit 1")}}
+
+	var buf bytes.Buffer
+	err := run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b"}}, c)
+	require.ErrorContains(t, err, "running claude: exit 1")
+
+	st, loadErr := state.Load(opts.StateFile)
+	require.NoError(t, loadErr)
+	require.Len(t, st.Runs, 1)
+	assert.Equal(t, state.StatusFailed, st.Runs[0].Status)
+	assert.Equal(t, 1, st.Runs[0].Iterations)
+	assert.Len(t, st.Runs[0].Checkpoints, 1)
+	assert.True(t, st.Runs[0].Resumable())
+}
+
+func TestParseClaudeErrorPolicy(t *testing.T) {
+	for in, want := range map[string]ClaudeErrorPolicy{
+		"":               {},
+		"abort":          {},
+		"skip-iteration": {Skip: true},
+		"retry-3":        {Retries: 3},
+	} {
+		got, err := ParseClaudeErrorPolicy(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	assert.Equal(t, "retry-3", ClaudeErrorPolicy{Retries: 3}.String())
+
+	for _, in := range []string{"retry", "retry-0", "retry-x", "skip"} {
+		_, err := ParseClaudeErrorPolicy(in)
+		assert.Error(t, err, in)
 	}
-	assert.Equal(t, 2, count)
+}
+
+func TestRun_RecordsExperiment(t *testing.T) {
+	opts := baseOpts(t)
+	opts.Experiment = "fast-sonnet"
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"sha-a", "sha-b"}}, &fakeClaude{stats: iterStats()}))
+	assert.Contains(t, buf.String(), "fast-sonnet")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	assert.Equal(t, "fast-sonnet", st.Runs[0].Experiment)
 }
 
 func TestRun_AdditionalDir_ChangeResetsStale(t *testing.T) {
@@ -306,7 +836,7 @@ func TestSaveState_PersistsRecord(t *testing.T) {
 	}
 	logPaths := []string{"logs/a.jsonl", "logs/b.jsonl"}
 
-	saveState(opts, cumStats, time.Now(), logPaths, false, false)
+	saveState(opts, cumStats, time.Now(), logPaths, nil, nil, nil, 0, nil, finalStatus(opts, cumStats, false, false, false, false, false))
 
 	st, err := state.Load(stateFile)
 	require.NoError(t, err)
@@ -319,3 +849,80 @@ func TestSaveState_PersistsRecord(t *testing.T) {
 	assert.Equal(t, state.StatusCompleted, r.Status)
 	assert.Equal(t, logPaths, r.LogFiles)
 }
+
+func TestSaveState_RecordsResumeDetails(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	opts := &Options{
+		Mode:          ModeBuild,
+		StateFile:     stateFile,
+		Branch:        "feat-x",
+		PlanFile:      ".ralph/plans/IMPLEMENTATION_PLAN_feat-x.md",
+		SpecsDir:      "specs/feat-x",
+		MaxIterations: 10,
+		ResumeOf:      2,
+		Origin:        &state.Origin{Repo: "o/r", Remote: "https://github.com/o/r.git", Workspace: "/home/me/r"},
+	}
+
+	caps := &stream.Capabilities{Model: "claude-opus-4-6", Version: "2.1.37", Tools: []string{"Read", "Edit"},
+		MCPServers: []stream.MCPServer{{Name: "db", Status: "failed"}}}
+	saveState(opts, &stream.CumulativeStats{Iterations: 4, Capabilities: caps}, time.Now(), nil, nil, nil, nil, 0, nil, state.StatusCancelled)
+
+	st, err := state.Load(stateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	r := st.Runs[0]
+	assert.Equal(t, "feat-x", r.Branch)
+	assert.Equal(t, opts.PlanFile, r.PlanFile)
+	assert.Equal(t, "specs/feat-x", r.SpecsDir)
+	assert.Equal(t, 10, r.MaxIterations)
+	assert.Equal(t, 2, r.ResumeOf)
+	assert.Equal(t, opts.Origin, r.Origin)
+	assert.Equal(t, &state.Capabilities{
+		Model: "claude-opus-4-6", Version: "2.1.37", Tools: []string{"Read", "Edit"},
+		MCPServers: []string{"db"}, MissingTools: []string{"Bash"}, FailedMCP: []string{"db"},
+	}, r.Capabilities)
+}
+
+func TestSaveState_RecordsTagsAndNote(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	opts := &Options{
+		Mode:      ModeBuild,
+		StateFile: stateFile,
+		Tags:      []string{"nightly"},
+		Note:      "attempt with new prompt",
+	}
+
+	saveState(opts, &stream.CumulativeStats{Iterations: 1}, time.Now(), nil, nil, nil, nil, 0, nil, state.StatusCompleted)
+
+	st, err := state.Load(stateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	assert.Equal(t, []string{"nightly"}, st.Runs[0].Tags)
+	assert.Equal(t, "attempt with new prompt", st.Runs[0].Note)
+}
+
+func TestRun_DiskLimitStops(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 10
+	opts.Workspace = t.TempDir()
+	opts.DiskLimit = 1 << 10
+
+	g := &fakeGit{heads: []string{"sha-a", "sha-b"}}
+	c := &fakeClaude{stats: iterStats(), onRun: func(call int, opts *Options) {
+		if call == 2 {
+			writeSized(t, opts.Workspace, "dataset/rows.csv", 2<<10)
+		}
+	}}
+
+	var buf bytes.Buffer
+	err := run(context.Background(), opts, &buf, runTheme, g, c)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "docker.disk_limit_mb")
+	assert.Equal(t, 2, c.called)
+	assert.Contains(t, buf.String(), "dataset/")
+
+	st, loadErr := state.Load(opts.StateFile)
+	require.NoError(t, loadErr)
+	require.Len(t, st.Runs, 1)
+	assert.Equal(t, state.StatusDiskLimit, st.Runs[0].Status)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
 	"github.com/benwilkes9/ralph-cli/internal/stream"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
@@ -78,7 +79,7 @@ func TestRenderIterationSummary(t *testing.T) {
 		PeakContext: 85_200,
 		Cost:        0.0234,
 	}
-	RenderIterationSummary(&buf, stats, "logs/20250101-120000.jsonl", testTheme)
+	RenderIterationSummary(&buf, stats, "logs/20250101-120000.jsonl", pricing.Currency{}, testTheme)
 	out := buf.String()
 
 	for _, want := range []string{"85.2k", "200.0k", "42%", "$0.0234", "logs/20250101-120000.jsonl"} {
@@ -86,16 +87,37 @@ func TestRenderIterationSummary(t *testing.T) {
 	}
 }
 
+func TestRenderIterationSummaryCacheRate(t *testing.T) {
+	var buf bytes.Buffer
+	RenderIterationSummary(&buf, &stream.IterationStats{}, "logs/test.jsonl", pricing.Currency{}, testTheme)
+	assert.NotContains(t, buf.String(), "cache")
+
+	buf.Reset()
+	stats := &stream.IterationStats{InputTokens: 100, CacheWriteTokens: 900, CacheReadTokens: 9_000}
+	RenderIterationSummary(&buf, stats, "logs/test.jsonl", pricing.Currency{}, testTheme)
+	assert.Contains(t, buf.String(), "cache 90%")
+}
+
 func TestRenderIterationSummaryZeroCost(t *testing.T) {
 	var buf bytes.Buffer
 	stats := &stream.IterationStats{
 		PeakContext: 10_000,
 		Cost:        0,
 	}
-	RenderIterationSummary(&buf, stats, "logs/test.jsonl", testTheme)
+	RenderIterationSummary(&buf, stats, "logs/test.jsonl", pricing.Currency{}, testTheme)
 	assert.NotContains(t, buf.String(), "$")
 }
 
+func TestRenderIterationSummaryOversized(t *testing.T) {
+	var buf bytes.Buffer
+	RenderIterationSummary(&buf, &stream.IterationStats{}, "logs/test.jsonl", pricing.Currency{}, testTheme)
+	assert.NotContains(t, buf.String(), "oversized")
+
+	buf.Reset()
+	RenderIterationSummary(&buf, &stream.IterationStats{Oversized: 2}, "logs/test.jsonl", pricing.Currency{}, testTheme)
+	assert.Contains(t, buf.String(), "2 oversized event(s) skipped")
+}
+
 func TestRenderStaleWarning(t *testing.T) {
 	var buf bytes.Buffer
 	RenderStaleWarning(&buf, 1, 2, testTheme)
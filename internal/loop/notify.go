@@ -0,0 +1,32 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/notify"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// sendEvent posts a lifecycle event to opts.Notifier, filling in the run's
+// repo, branch, mode and budget. Like status reporting it is best-effort,
+// and it runs after cancellation too.
+//
+//nolint:errcheck // display-only writes to terminal
+func sendEvent(ctx context.Context, opts *Options, w io.Writer, theme *ui.Theme, p notify.Payload) {
+	if opts.Notifier == nil {
+		return
+	}
+	if opts.Origin != nil {
+		p.Repo = opts.Origin.Repo
+	}
+	p.Branch = opts.Branch
+	p.Mode = string(opts.Mode)
+	p.MaxIterations = opts.MaxIterations
+	p.Time = clock.Or(opts.Clock).Now()
+	if err := opts.Notifier.Notify(context.WithoutCancel(ctx), &p); err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Webhook not sent: %s", err)))
+	}
+}
@@ -0,0 +1,225 @@
+package loop
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/summary"
+	"github.com/benwilkes9/ralph-cli/internal/testresult"
+)
+
+// Output formats for the loop's progress, chosen with --output.
+const (
+	OutputText = "text" // styled text for a terminal (the default)
+	OutputJSON = "json" // one Event per line, for CI systems
+	OutputTUI  = "tui"  // a live dashboard redrawn in place, chosen with --tui
+)
+
+// ValidOutput reports whether format is a supported --output value.
+func ValidOutput(format string) bool {
+	return format == OutputText || format == OutputJSON
+}
+
+// Event types written in OutputJSON mode, and shown by the OutputTUI
+// dashboard.
+const (
+	EventRunStart      = "run_start"
+	EventIteration     = "iteration_start"
+	EventPromptTokens  = "prompt_tokens"
+	EventModelRoute    = "model_route"
+	EventIterationEnd  = "iteration_end"
+	EventTests         = "tests"
+	EventStale         = "stale"
+	EventStaleAbort    = "stale_abort"
+	EventPlanUnchanged = "plan_unchanged"
+	EventPlanConverged = "plan_converged"
+	EventMaxIterations = "max_iterations"
+	EventCostLimit     = "cost_limit"
+	EventOfflineCommit = "offline_commit"
+	EventPushed        = "pushed"
+	EventPushQueued    = "push_queued"
+	EventPushFlush     = "push_flush"
+	EventPushesLeft    = "pushes_left"
+	EventPushFallback  = "push_fallback"
+	EventSummary       = "summary"
+	EventText          = "text" // any other output, e.g. the agent's stream, one line each
+)
+
+// Event types only the OutputTUI dashboard receives, too chatty for
+// OutputJSON.
+const (
+	EventTask     = "task"      // the plan task the iteration is working on
+	EventToolCall = "tool_call" // a tool the agent called
+	EventContext  = "context"   // the context in use after an agent turn, in Tokens
+)
+
+// Event is one line of OutputJSON output. Type says which fields are set;
+// Message is the line text mode would have shown, without styling.
+type Event struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	Iteration int       `json:"iteration,omitempty"` // the iteration under way; 0 before the first
+	Message   string    `json:"message,omitempty"`
+
+	Mode          string  `json:"mode,omitempty"`
+	Branch        string  `json:"branch,omitempty"`
+	Prompt        string  `json:"prompt,omitempty"`
+	Variant       string  `json:"variant,omitempty"`
+	MaxIterations int     `json:"max_iterations,omitempty"`
+	MaxCost       float64 `json:"max_cost_usd,omitempty"`
+	Offline       bool    `json:"offline,omitempty"`
+	HooksSkipped  string  `json:"hooks_skipped,omitempty"`
+
+	Tokens   int    `json:"tokens,omitempty"`
+	Exact    bool   `json:"exact,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Category string `json:"category,omitempty"`
+	Task     string `json:"task,omitempty"`
+	Tool     string `json:"tool,omitempty"`
+
+	PeakContext  int                `json:"peak_context,omitempty"`
+	CacheHitRate *float64           `json:"cache_hit_rate,omitempty"` // percent; absent without cache usage
+	Cost         float64            `json:"cost_usd,omitempty"`
+	Log          string             `json:"log,omitempty"`
+	Oversized    int                `json:"oversized,omitempty"`
+	Tests        *testresult.Counts `json:"tests,omitempty"`
+
+	Count     int `json:"count,omitempty"`     // stale or unchanged iterations in a row
+	Threshold int `json:"threshold,omitempty"` // the limit Count is measured against
+	Changed   int `json:"changed,omitempty"`   // plan lines changed
+	Commits   int `json:"commits,omitempty"`
+
+	Summary *summary.JSON `json:"summary,omitempty"`
+}
+
+// jsonWriter turns the loop's output into Events. Renderers emit their own
+// event through emit; anything else written to it, such as the agent's
+// stream, becomes an EventText per line. Its output is written with
+// ui.PlainTheme, so the text carries no styling. Events go to out as JSON
+// lines, or to deliver when it is set, as the dashboard takes them.
+type jsonWriter struct {
+	mu        sync.Mutex
+	out       io.Writer
+	deliver   func(Event)
+	clk       clock.Clock
+	iteration int
+	partial   []byte // text written since the last newline
+}
+
+func newJSONWriter(out io.Writer, clk clock.Clock) *jsonWriter {
+	return &jsonWriter{out: out, clk: clock.Or(clk)}
+}
+
+// newEventWriter returns a jsonWriter that hands each Event to deliver.
+func newEventWriter(deliver func(Event), clk clock.Clock) *jsonWriter {
+	return &jsonWriter{deliver: deliver, clk: clock.Or(clk)}
+}
+
+// Write implements io.Writer for unstructured output.
+func (j *jsonWriter) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.partial = append(j.partial, p...)
+	for {
+		i := bytes.IndexByte(j.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(j.partial[:i])
+		j.partial = j.partial[i+1:]
+		if err := j.text(line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes any unterminated line left by Write.
+func (j *jsonWriter) Flush() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.flush()
+}
+
+// flush writes the unterminated line, if any. Callers hold mu.
+func (j *jsonWriter) flush() error {
+	line := string(j.partial)
+	j.partial = nil
+	return j.text(line)
+}
+
+// text writes line as an EventText; blank lines are dropped.
+func (j *jsonWriter) text(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	return j.write(Event{Type: EventText, Message: line})
+}
+
+// write stamps e and writes it as one line. Callers hold mu.
+func (j *jsonWriter) write(e Event) error {
+	if e.Type == EventIteration {
+		j.iteration = e.Iteration
+	}
+	e.Time = j.clk.Now()
+	if e.Iteration == 0 {
+		e.Iteration = j.iteration
+	}
+	if j.deliver != nil {
+		j.deliver(e)
+		return nil
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	if _, err := j.out.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing event: %w", err)
+	}
+	return nil
+}
+
+// emit writes e when w is in OutputJSON mode, reporting whether it did, so
+// a renderer can skip its text output. Text written before it without a
+// final newline is written first, to keep the events in order.
+func emit(w io.Writer, e Event) bool {
+	j, ok := w.(*jsonWriter)
+	if !ok {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_ = j.flush()  //nolint:errcheck // display-only, like the text renderers
+	_ = j.write(e) //nolint:errcheck // display-only, like the text renderers
+	return true
+}
+
+// emitLive writes e when w feeds the OutputTUI dashboard, for the events
+// only it shows.
+func emitLive(w io.Writer, e Event) {
+	if live(w) {
+		emit(w, e)
+	}
+}
+
+// live reports whether w feeds the OutputTUI dashboard.
+func live(w io.Writer) bool {
+	j, ok := w.(*jsonWriter)
+	return ok && j.deliver != nil
+}
+
+// cacheRate is the hit rate for an Event: nil when rate is negative, as
+// reported without cache usage.
+func cacheRate(rate float64) *float64 {
+	if rate < 0 {
+		return nil
+	}
+	return &rate
+}
@@ -0,0 +1,107 @@
+package loop
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+// decodeEvents parses NDJSON output, failing on any line that isn't an Event.
+func decodeEvents(t *testing.T, out string) []Event {
+	t.Helper()
+	var events []Event
+	sc := bufio.NewScanner(bytes.NewBufferString(out))
+	for sc.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &e), "line %q", sc.Text())
+		events = append(events, e)
+	}
+	return events
+}
+
+// chattyClaude writes agent output to the terminal writer like the real
+// runner does.
+type chattyClaude struct{ fakeClaude }
+
+func (c *chattyClaude) Run(ctx context.Context, opts *Options, logW, w io.Writer) (*stream.IterationStats, error) {
+	fmt.Fprint(w, "Read main.go\n\n  partial") //nolint:errcheck // test helper write
+	return c.fakeClaude.Run(ctx, opts, logW, w)
+}
+
+func TestRun_OutputJSON(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 2
+	opts.Output = OutputJSON
+	opts.Clock = &clock.Fake{T: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)}
+
+	g := &fakeGit{heads: []string{"a", "b", "c", "c", "c"}}
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, &chattyClaude{fakeClaude{stats: iterStats()}}))
+
+	events := decodeEvents(t, buf.String())
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, EventRunStart, types[0])
+	assert.Equal(t, EventSummary, types[len(types)-1])
+	assert.Contains(t, types, EventIteration)
+	assert.Contains(t, types, EventIterationEnd)
+	assert.Contains(t, types, EventMaxIterations)
+
+	start := events[0]
+	assert.Equal(t, "build", start.Mode)
+	assert.Equal(t, "main", start.Branch)
+	assert.Equal(t, 2, start.MaxIterations)
+	assert.Zero(t, start.Iteration)
+
+	var texts []string
+	for _, e := range events {
+		if e.Type == EventText {
+			texts = append(texts, e.Message)
+		}
+		if e.Type == EventIterationEnd {
+			assert.Equal(t, 1000, e.PeakContext)
+			assert.InDelta(t, 0.01, e.Cost, 1e-9)
+			assert.NotEmpty(t, e.Log)
+		}
+	}
+	assert.Contains(t, texts, "Read main.go", "agent output is passed through as text events")
+	assert.Contains(t, texts, "partial", "an unterminated line is flushed")
+
+	last := events[len(events)-1]
+	require.NotNil(t, last.Summary)
+	assert.Equal(t, 2, last.Summary.Iterations)
+	assert.Equal(t, 2, last.Iteration, "events carry the iteration under way")
+	assert.NotContains(t, buf.String(), "JOB SUMMARY")
+}
+
+func TestRun_OutputJSONStale(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 1
+	opts.Output = OutputJSON
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a"}}, &fakeClaude{stats: iterStats()}))
+
+	for _, e := range decodeEvents(t, buf.String()) {
+		if e.Type == EventStale {
+			assert.Equal(t, 1, e.Count)
+			assert.Equal(t, DefaultMaxStale, e.Threshold)
+			assert.Equal(t, "No new commits this iteration", e.Message)
+			return
+		}
+	}
+	t.Fatal("no stale event")
+}
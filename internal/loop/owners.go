@@ -0,0 +1,52 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/owners"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// ownersHeader returns the prompt lines listing protected and careful
+// CODEOWNERS patterns, or "" when the policy lists neither.
+func ownersHeader(p *owners.Policy) string {
+	if p == nil {
+		return ""
+	}
+	var b strings.Builder
+	if forbidden := p.Patterns(owners.Forbidden); len(forbidden) > 0 {
+		fmt.Fprintf(&b, "DO_NOT_TOUCH: %s (CODEOWNERS-protected; commits changing these are discarded)\n", strings.Join(forbidden, ", "))
+	}
+	if careful := p.Patterns(owners.Careful); len(careful) > 0 {
+		fmt.Fprintf(&b, "EXTRA_CARE: %s (CODEOWNERS-owned; keep changes minimal and explain them in the commit message)\n", strings.Join(careful, ", "))
+	}
+	return b.String()
+}
+
+// enforceOwners discards the iteration's commits in the primary repo when
+// they change a protected path. Returns whether HEAD was reset. An error
+// listing the changes is only reported, since it can't be enforced.
+//
+//nolint:errcheck // display-only writes to terminal
+func enforceOwners(ctx context.Context, gitCl GitClient, opts *Options, w io.Writer, theme *ui.Theme, primaryBefore string) (bool, error) {
+	files, err := gitCl.ChangedFiles(ctx, primaryBefore, "HEAD")
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Could not check CODEOWNERS policy: %s", err)))
+		return false, nil //nolint:nilerr // a broken check shouldn't stop the loop
+	}
+	violations := opts.Owners.Violations(files)
+	if len(violations) == 0 {
+		return false, nil
+	}
+
+	fmt.Fprintf(w, "%s %s\n",
+		theme.Error.Render("Protected paths changed:"), strings.Join(violations, ", "))
+	if err := gitCl.ResetHard(ctx, primaryBefore); err != nil {
+		return false, fmt.Errorf("discarding iteration that changed protected paths: %w", err)
+	}
+	fmt.Fprintln(w, theme.Warning.Render("  Iteration discarded."))
+	return true, nil
+}
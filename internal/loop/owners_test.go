@@ -0,0 +1,55 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/owners"
+)
+
+func testPolicy(t *testing.T) *owners.Policy {
+	t.Helper()
+	rules, err := owners.Parse(strings.NewReader("/infra/ @acme/security\n/api/ @acme/platform\n"))
+	require.NoError(t, err)
+	return owners.NewPolicy(rules, []string{"@acme/security"}, []string{"@acme/platform"})
+}
+
+func TestOwnersHeader(t *testing.T) {
+	assert.Empty(t, ownersHeader(nil))
+
+	header := ownersHeader(testPolicy(t))
+	assert.Contains(t, header, "DO_NOT_TOUCH: /infra/")
+	assert.Contains(t, header, "EXTRA_CARE: /api/")
+}
+
+func TestRun_DiscardsProtectedChanges(t *testing.T) {
+	opts := baseOpts(t)
+	opts.Owners = testPolicy(t)
+
+	// Head calls: seed, before, after.
+	g := &fakeGit{heads: []string{"sha-0", "sha-a", "sha-b"}, changed: []string{"api/routes.go", "infra/main.tf"}}
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, &fakeClaude{stats: iterStats()}))
+
+	assert.Equal(t, []string{"sha-a"}, g.resetTo)
+	assert.Contains(t, buf.String(), "Protected paths changed:")
+	assert.Contains(t, buf.String(), "infra/main.tf")
+	assert.NotContains(t, buf.String(), "api/routes.go")
+}
+
+func TestRun_AllowsCarefulChanges(t *testing.T) {
+	opts := baseOpts(t)
+	opts.Owners = testPolicy(t)
+
+	g := &fakeGit{heads: []string{"sha-a", "sha-b"}, changed: []string{"api/routes.go"}}
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, &fakeClaude{stats: iterStats()}))
+
+	assert.Empty(t, g.resetTo)
+	assert.NotContains(t, buf.String(), "Protected paths")
+}
@@ -0,0 +1,94 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// pushQueue holds back pushes under git.push_debounce, so remote CI isn't
+// triggered by every iteration. With neither limit set every iteration
+// pushes, as before.
+type pushQueue struct {
+	interval time.Duration // push at most this often; 0 = no time limit
+	commits  int           // push once this many commits are waiting; 0 = no count limit
+	pending  int           // commits made since the last successful push
+	lastPush time.Time     // zero until the run's first push
+}
+
+func newPushQueue(opts *Options) *pushQueue {
+	return &pushQueue{interval: opts.PushInterval, commits: opts.PushCommits}
+}
+
+// debounced reports whether pushes are held back at all.
+func (q *pushQueue) debounced() bool {
+	return q.interval > 0 || q.commits > 0
+}
+
+// due reports whether the pending commits should be pushed at now: when
+// either limit is reached. The run's first commits push straight away.
+func (q *pushQueue) due(now time.Time) bool {
+	switch {
+	case q.pending == 0:
+		return false
+	case !q.debounced():
+		return true
+	case q.commits > 0 && q.pending >= q.commits:
+		return true
+	}
+	return q.interval > 0 && now.Sub(q.lastPush) >= q.interval
+}
+
+// pushed records a successful push at now.
+func (q *pushQueue) pushed(now time.Time) {
+	q.pending = 0
+	q.lastPush = now
+}
+
+// commitCount is how many commits an iteration made on the primary repo,
+// at least one since its heads (or an additional repo's) moved.
+func commitCount(ctx context.Context, gitCl GitClient, from, to string) int {
+	if from == to {
+		return 1
+	}
+	subjects, err := gitCl.CommitSubjects(ctx, from, to)
+	if err != nil || len(subjects) == 0 {
+		return 1
+	}
+	return len(subjects)
+}
+
+// pushBranch pushes the primary repo, falling back to --set-upstream, and
+// then the additional repos. It reports whether the primary push succeeded.
+func pushBranch(ctx context.Context, gitCl GitClient, opts *Options, w io.Writer, theme *ui.Theme) bool {
+	pushed := true
+	if pushErr := gitCl.Push(ctx, opts.Branch); pushErr != nil {
+		RenderPushFallback(w, theme)
+		if upErr := gitCl.PushSetUpstream(ctx, opts.Branch); upErr != nil {
+			fmt.Fprintf(w, "%s\n", theme.Muted.Render(i18n.Tf("Push failed: %s", upErr))) //nolint:errcheck // display-only
+			pushed = false
+		}
+	}
+	pushAdditionalDirs(ctx, gitCl, opts, w, theme)
+	return pushed
+}
+
+// flushPushes pushes commits still queued when the run ends. After
+// cancellation it only says how many were left, for ralph sync to push.
+func flushPushes(ctx context.Context, gitCl GitClient, opts *Options, w io.Writer, theme *ui.Theme, q *pushQueue, now time.Time) {
+	if opts.Offline || q.pending == 0 {
+		return
+	}
+	if ctx.Err() != nil {
+		RenderPushesLeft(w, q.pending, theme)
+		return
+	}
+	RenderPushFlush(w, q.pending, theme)
+	if pushBranch(ctx, gitCl, opts, w, theme) {
+		q.pushed(now)
+	}
+}
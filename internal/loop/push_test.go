@@ -0,0 +1,71 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushQueue_Due(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	q := &pushQueue{}
+	assert.False(t, q.due(start), "nothing to push")
+	q.pending = 1
+	assert.True(t, q.due(start), "no debounce pushes every iteration")
+
+	q = &pushQueue{interval: 10 * time.Minute, pending: 1}
+	assert.True(t, q.due(start), "the first commits push straight away")
+	q.pushed(start)
+	q.pending = 3
+	assert.False(t, q.due(start.Add(9*time.Minute)))
+	assert.True(t, q.due(start.Add(10*time.Minute)))
+
+	q = &pushQueue{commits: 3, pending: 2}
+	assert.False(t, q.due(start))
+	q.pending = 3
+	assert.True(t, q.due(start))
+
+	q = &pushQueue{interval: time.Hour, commits: 2, lastPush: start, pending: 2}
+	assert.True(t, q.due(start.Add(time.Minute)), "either limit triggers a push")
+}
+
+func TestRun_PushDebounceQueuesAndFlushes(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 3
+	opts.PushCommits = 2
+
+	g := &fakeGit{heads: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}}
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, &fakeClaude{stats: iterStats()}))
+
+	assert.Equal(t, 2, g.pushes, "one push after two commits, one for the last commit at the end")
+	out := buf.String()
+	assert.Contains(t, out, "push queued: 1 commit(s) waiting")
+	assert.Contains(t, out, "(next push after 1 more commit(s))")
+	assert.Contains(t, out, "pushed 2 commit(s)")
+	assert.Contains(t, out, "Pushing 1 queued commit(s)...")
+}
+
+func TestRun_PushDebounceLeavesQueueWhenCancelled(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 2
+	opts.PushInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &fakeClaude{stats: iterStats(), onRun: func(call int, _ *Options) {
+		if call == 2 {
+			cancel()
+		}
+	}}
+	g := &fakeGit{heads: []string{"a", "b", "c", "d", "e", "f"}}
+	var buf bytes.Buffer
+	_ = run(ctx, opts, &buf, runTheme, g, c) //nolint:errcheck // cancellation is the point
+
+	assert.Equal(t, 1, g.pushes, "only the first iteration's push")
+	assert.Contains(t, buf.String(), `queued commit(s) not pushed; run "ralph sync"`)
+}
@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
 	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/summary"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
 
@@ -15,6 +20,13 @@ const contextLimit = 200_000
 //
 //nolint:errcheck // display-only writes to terminal
 func RenderHeader(w io.Writer, opts *Options, theme *ui.Theme) {
+	if emit(w, Event{
+		Type: EventRunStart, Mode: string(opts.Mode), Branch: opts.Branch, Prompt: opts.PromptFile,
+		Variant: opts.Experiment, MaxIterations: opts.MaxIterations, MaxCost: opts.MaxCost, Offline: opts.Offline,
+		HooksSkipped: opts.SkipHooks,
+	}) {
+		return
+	}
 	fmt.Fprintln(w, theme.Banner())
 	fmt.Fprintln(w)
 
@@ -23,11 +35,29 @@ func RenderHeader(w io.Writer, opts *Options, theme *ui.Theme) {
 	modeStyle := theme.ModeStyle(string(opts.Mode))
 
 	fmt.Fprintln(w, bar)
-	fmt.Fprintf(w, "  %s     %s\n", theme.Muted.Render("Mode"), modeStyle.Render(string(opts.Mode)))
-	fmt.Fprintf(w, "  %s   %s\n", theme.Muted.Render("Prompt"), opts.PromptFile)
-	fmt.Fprintf(w, "  %s   %s\n", theme.Muted.Render("Branch"), theme.Info.Render(opts.Branch))
+	field := func(label, value string) {
+		fmt.Fprintf(w, "  %s %s\n", theme.Muted.Render(fmt.Sprintf("%-8s", i18n.T(label))), value)
+	}
+	field("Mode", modeStyle.Render(string(opts.Mode)))
+	field("Prompt", opts.PromptFile)
+	field("Branch", theme.Info.Render(opts.Branch))
+	if opts.Experiment != "" {
+		field("Variant", theme.Info.Render(opts.Experiment))
+	}
 	if opts.MaxIterations > 0 {
-		fmt.Fprintf(w, "  %s      %s\n", theme.Muted.Render("Max"), fmt.Sprintf("%d iterations", opts.MaxIterations))
+		field("Max", i18n.Tf("%d iterations", opts.MaxIterations))
+	}
+	if opts.MaxCost > 0 {
+		field("Cost cap", opts.Currency.Format(opts.MaxCost, 2))
+	}
+	if opts.Offline {
+		field("Offline", theme.Warning.Render(i18n.T("no pushes or GitHub reporting; ralph sync pushes later")))
+	}
+	if opts.SkipHooks != "" {
+		field("Hooks", theme.Warning.Render(i18n.Tf("skipped: %s", opts.SkipHooks)))
+	}
+	if opts.Chaos != nil {
+		field("Chaos", theme.Warning.Render(i18n.Tf("%.0f%% failure rate (seed %d)", opts.Chaos.Rate*100, opts.Chaos.Seed)))
 	}
 	fmt.Fprintln(w, bar)
 }
@@ -36,6 +66,9 @@ func RenderHeader(w io.Writer, opts *Options, theme *ui.Theme) {
 //
 //nolint:errcheck // display-only writes to terminal
 func RenderBanner(w io.Writer, mode Mode, iteration int, theme *ui.Theme) {
+	if emit(w, Event{Type: EventIteration, Mode: string(mode), Iteration: iteration}) {
+		return
+	}
 	style := theme.IterationStyle(string(mode))
 	label := "BUILD"
 	if mode == ModePlan {
@@ -57,51 +90,240 @@ func RenderBanner(w io.Writer, mode Mode, iteration int, theme *ui.Theme) {
 	fmt.Fprintln(w)
 }
 
+// RenderPromptTokens prints the assembled prompt's token count under the
+// iteration banner. Estimates are prefixed with "~".
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderPromptTokens(w io.Writer, n int, exact bool, theme *ui.Theme) {
+	if emit(w, Event{Type: EventPromptTokens, Tokens: n, Exact: exact}) {
+		return
+	}
+	count := stream.FormatTokens(n)
+	if !exact {
+		count = "~" + count
+	}
+	fmt.Fprintf(w, "  %s %s %s\n\n",
+		theme.Muted.Render("prompt"),
+		i18n.Tf("%s tokens", count),
+		theme.Muted.Render(i18n.Tf("(%d%% of context)", n*100/contextLimit)))
+}
+
+// renderModelRoute prints the model a build iteration was routed to under
+// the iteration banner.
+//
+//nolint:errcheck // display-only writes to terminal
+func renderModelRoute(w io.Writer, model, category string, theme *ui.Theme) {
+	if emit(w, Event{Type: EventModelRoute, Model: model, Category: category}) {
+		return
+	}
+	fmt.Fprintf(w, "  %s %s %s\n",
+		theme.Muted.Render("model"), model,
+		theme.Muted.Render(i18n.Tf("(%s task)", category)))
+}
+
 // RenderIterationSummary prints the per-iteration context/cost line and log path.
 //
 //nolint:errcheck // display-only writes to terminal
-func RenderIterationSummary(w io.Writer, stats *stream.IterationStats, logPath string, theme *ui.Theme) {
+func RenderIterationSummary(w io.Writer, stats *stream.IterationStats, logPath string, cur pricing.Currency, theme *ui.Theme) {
+	if emit(w, Event{
+		Type: EventIterationEnd, PeakContext: stats.PeakContext, CacheHitRate: cacheRate(stats.CacheHitRate()),
+		Cost: stats.Cost, Log: logPath, Oversized: stats.Oversized,
+	}) {
+		return
+	}
 	pct := stats.PeakContext * 100 / contextLimit
 
-	fmt.Fprintf(w, "\n  %s %s / %s context (%d%%)",
+	fmt.Fprintf(w, "\n  %s %s",
 		theme.Muted.Render("────"),
-		stream.FormatTokens(stats.PeakContext),
-		stream.FormatTokens(contextLimit),
-		pct)
+		i18n.Tf("%s / %s context (%d%%)", stream.FormatTokens(stats.PeakContext), stream.FormatTokens(contextLimit), pct))
+	if rate := stats.CacheHitRate(); rate >= 0 {
+		fmt.Fprintf(w, "  %s", theme.Muted.Render(i18n.Tf("cache %.0f%%", rate)))
+	}
 	if stats.Cost > 0 {
-		fmt.Fprintf(w, "  %s", theme.Cost.Render(fmt.Sprintf("$%.4f", stats.Cost)))
+		fmt.Fprintf(w, "  %s", theme.Cost.Render(cur.Format(stats.Cost, 4)))
 	}
 	fmt.Fprintln(w)
-	fmt.Fprintf(w, "  %s\n", theme.Muted.Render("raw log: "+logPath))
+	if stats.Oversized > 0 {
+		fmt.Fprintf(w, "  %s\n", theme.Warning.Render(i18n.Tf(
+			"%d oversized event(s) skipped (over %d MB); see the raw log", stats.Oversized, stream.DefaultMaxLineSize>>20)))
+	}
+	fmt.Fprintf(w, "  %s\n", theme.Muted.Render(i18n.Tf("raw log: %s", logPath)))
+}
+
+// RenderTestTrend prints the latest test counts and flags a rise in failures
+// since the previous iteration that ran tests.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderTestTrend(w io.Writer, tests []state.TestResult, theme *ui.Theme) {
+	if len(tests) == 0 {
+		return
+	}
+	last := tests[len(tests)-1]
+	if emit(w, Event{Type: EventTests, Tests: &last.Counts}) {
+		return
+	}
+	line := fmt.Sprintf("  %s %s", theme.Muted.Render(i18n.T("tests:")), last.Counts)
+	if len(tests) > 1 && last.Failed > tests[len(tests)-2].Failed {
+		line += "  " + theme.Warning.Render(i18n.Tf("▲ failures up from %d", tests[len(tests)-2].Failed))
+	}
+	fmt.Fprintln(w, line)
 }
 
 // RenderStaleWarning prints a warning when no new commits were detected.
 //
 //nolint:errcheck // display-only writes to terminal
 func RenderStaleWarning(w io.Writer, count, threshold int, theme *ui.Theme) {
+	if emit(w, Event{Type: EventStale, Count: count, Threshold: threshold, Message: i18n.T("No new commits this iteration")}) {
+		return
+	}
 	fmt.Fprintf(w, "%s %s\n",
-		theme.Warning.Render("No new commits this iteration"),
-		theme.Muted.Render(fmt.Sprintf("(stale: %d/%d)", count, threshold)))
+		theme.Warning.Render(i18n.T("No new commits this iteration")),
+		theme.Muted.Render(i18n.Tf("(stale: %d/%d)", count, threshold)))
 }
 
 // RenderStaleAbort prints the abort message when the stale threshold is reached.
 //
 //nolint:errcheck // display-only writes to terminal
 func RenderStaleAbort(w io.Writer, threshold int, theme *ui.Theme) {
-	fmt.Fprintf(w, "%s %d consecutive iterations with no commits. Stopping.\n",
-		theme.Error.Render("Stale loop detected:"), threshold)
+	if emit(w, Event{Type: EventStaleAbort, Threshold: threshold,
+		Message: i18n.Tf("%d consecutive iterations with no commits. Stopping.", threshold)}) {
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n",
+		theme.Error.Render(i18n.T("Stale loop detected:")), i18n.Tf("%d consecutive iterations with no commits. Stopping.", threshold))
+}
+
+// RenderPlanUnchanged prints a warning when an iteration barely changed the plan.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderPlanUnchanged(w io.Writer, changed, count, threshold int, theme *ui.Theme) {
+	if emit(w, Event{Type: EventPlanUnchanged, Changed: changed, Count: count, Threshold: threshold,
+		Message: i18n.Tf("Plan barely changed this iteration (%d line(s))", changed)}) {
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n",
+		theme.Warning.Render(i18n.Tf("Plan barely changed this iteration (%d line(s))", changed)),
+		theme.Muted.Render(i18n.Tf("(converging: %d/%d)", count, threshold)))
+}
+
+// RenderPlanConverged prints the stop message when the plan has converged.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderPlanConverged(w io.Writer, threshold int, theme *ui.Theme) {
+	if emit(w, Event{Type: EventPlanConverged, Threshold: threshold,
+		Message: i18n.Tf("%d consecutive iterations without meaningful plan changes. Stopping.", threshold)}) {
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n",
+		theme.Success.Render(i18n.T("Plan converged:")), i18n.Tf("%d consecutive iterations without meaningful plan changes. Stopping.", threshold))
 }
 
 // RenderMaxIterations prints the max iterations reached message.
 //
 //nolint:errcheck // display-only writes to terminal
 func RenderMaxIterations(w io.Writer, threshold int, theme *ui.Theme) {
-	fmt.Fprintln(w, theme.Warning.Render(fmt.Sprintf("Reached max iterations: %d", threshold)))
+	if emit(w, Event{Type: EventMaxIterations, Threshold: threshold, Message: i18n.Tf("Reached max iterations: %d", threshold)}) {
+		return
+	}
+	fmt.Fprintln(w, theme.Warning.Render(i18n.Tf("Reached max iterations: %d", threshold)))
+}
+
+// RenderCostLimit prints the notice that the run spent its cost cap.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderCostLimit(w io.Writer, spent, limit float64, cur pricing.Currency, theme *ui.Theme) {
+	msg := i18n.Tf("Reached cost cap: %s spent of %s", cur.Format(spent, 2), cur.Format(limit, 2))
+	if emit(w, Event{Type: EventCostLimit, Cost: spent, MaxCost: limit, Message: msg}) {
+		return
+	}
+	fmt.Fprintln(w, theme.Warning.Render(msg))
+}
+
+// RenderOfflineCommit notes that an offline iteration's commits stay local.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderOfflineCommit(w io.Writer, theme *ui.Theme) {
+	if emit(w, Event{Type: EventOfflineCommit, Message: i18n.T("Offline: commits kept local; run \"ralph sync\" to push them.")}) {
+		return
+	}
+	fmt.Fprintln(w, theme.Muted.Render(i18n.T("Offline: commits kept local; run \"ralph sync\" to push them.")))
+}
+
+// RenderPushed notes that a debounced push sent the queued commits.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderPushed(w io.Writer, commits int, theme *ui.Theme) {
+	if emit(w, Event{Type: EventPushed, Commits: commits}) {
+		return
+	}
+	fmt.Fprintf(w, "  %s\n", theme.Muted.Render(i18n.Tf("pushed %d commit(s)", commits)))
+}
+
+// RenderPushQueued notes that the iteration's commits are waiting for the
+// next debounced push, and when it is due.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderPushQueued(w io.Writer, q *pushQueue, now time.Time, theme *ui.Theme) {
+	var next string
+	wait := q.interval - now.Sub(q.lastPush)
+	more := q.commits - q.pending
+	switch {
+	case q.interval > 0 && q.commits > 0:
+		next = i18n.Tf("(next push in %s or after %d more commit(s))", formatWait(wait), more)
+	case q.interval > 0:
+		next = i18n.Tf("(next push in %s)", formatWait(wait))
+	default:
+		next = i18n.Tf("(next push after %d more commit(s))", more)
+	}
+	queued := i18n.Tf("push queued: %d commit(s) waiting", q.pending)
+	if emit(w, Event{Type: EventPushQueued, Commits: q.pending, Message: queued + " " + next}) {
+		return
+	}
+	fmt.Fprintf(w, "  %s %s\n", theme.Info.Render(queued), theme.Muted.Render(next))
+}
+
+// formatWait rounds a wait up to the second for display.
+func formatWait(d time.Duration) string {
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
+
+// RenderPushFlush prints a message when queued commits are pushed at the
+// end of a run.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderPushFlush(w io.Writer, commits int, theme *ui.Theme) {
+	if emit(w, Event{Type: EventPushFlush, Commits: commits}) {
+		return
+	}
+	fmt.Fprintln(w, theme.Muted.Render(i18n.Tf("Pushing %d queued commit(s)...", commits)))
+}
+
+// RenderPushesLeft notes queued commits a cancelled run didn't push.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderPushesLeft(w io.Writer, commits int, theme *ui.Theme) {
+	if emit(w, Event{Type: EventPushesLeft, Commits: commits,
+		Message: i18n.Tf("%d queued commit(s) not pushed; run \"ralph sync\" to push them.", commits)}) {
+		return
+	}
+	fmt.Fprintln(w, theme.Warning.Render(i18n.Tf("%d queued commit(s) not pushed; run \"ralph sync\" to push them.", commits)))
 }
 
 // RenderPushFallback prints a message when falling back to push -u.
 //
 //nolint:errcheck // display-only writes to terminal
 func RenderPushFallback(w io.Writer, theme *ui.Theme) {
-	fmt.Fprintln(w, theme.Warning.Render("Failed to push. Creating remote branch..."))
+	if emit(w, Event{Type: EventPushFallback, Message: i18n.T("Failed to push. Creating remote branch...")}) {
+		return
+	}
+	fmt.Fprintln(w, theme.Warning.Render(i18n.T("Failed to push. Creating remote branch...")))
+}
+
+// renderSummary prints the job summary box, or the summary event in
+// OutputJSON mode.
+func renderSummary(w io.Writer, r *summary.Report, opts *Options, theme *ui.Theme) {
+	if emit(w, Event{Type: EventSummary, Summary: r.JSON()}) {
+		return
+	}
+	summary.PrintBox(w, r, opts.SummaryRows, opts.Currency, theme)
 }
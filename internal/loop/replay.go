@@ -0,0 +1,91 @@
+package loop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// PromptRecord is what an iteration asked the agent: the prompt file as it
+// read then, and the per-iteration context added to its header. ralph
+// replay feeds a run's records back to a new run on another branch.
+type PromptRecord struct {
+	Iteration int    `json:"iteration"`
+	Model     string `json:"model"`
+	Prompt    string `json:"prompt"` // the prompt file's content, without the header
+	TaskID    string `json:"task_id,omitempty"`
+	Feedback  string `json:"feedback,omitempty"`
+}
+
+// PromptArtifactPath returns where iteration i of the run started at runID
+// (a logfile.TimeLayout stamp) records its prompt, next to its diff.
+func PromptArtifactPath(logsDir, runID string, i int) string {
+	return filepath.Join(logsDir, runID, fmt.Sprintf("iter-%d.prompt.json", i))
+}
+
+// writePromptArtifact records the prompt iteration i is about to send.
+func writePromptArtifact(path string, i int, opts *Options) error {
+	prompt, err := promptBody(opts)
+	if err != nil {
+		return nil //nolint:nilerr // runClaude reports the unreadable prompt
+	}
+	data, err := json.MarshalIndent(PromptRecord{
+		Iteration: i, Model: opts.model(), Prompt: string(prompt), TaskID: opts.TaskID, Feedback: opts.Feedback,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding prompt: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("creating prompt dir: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing prompt: %w", err)
+	}
+	return nil
+}
+
+// LoadPromptRecords reads the prompts the run started at runID recorded,
+// in iteration order, stopping at the first iteration without one. A run
+// from before prompts were recorded has none.
+func LoadPromptRecords(logsDir, runID string) ([]PromptRecord, error) {
+	var records []PromptRecord
+	for i := 1; ; i++ {
+		path := PromptArtifactPath(logsDir, runID, i)
+		data, err := os.ReadFile(path) //nolint:gosec // ralph's own log directory
+		if errors.Is(err, fs.ErrNotExist) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading recorded prompt: %w", err)
+		}
+		var r PromptRecord
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
+		}
+		records = append(records, r)
+	}
+}
+
+// replayRecord returns the recorded prompt iteration i replays, if any.
+func replayRecord(opts *Options, i int) (PromptRecord, bool) {
+	if i > len(opts.Replay) {
+		return PromptRecord{}, false
+	}
+	return opts.Replay[i-1], true
+}
+
+// promptBody returns the prompt the iteration sends before its header is
+// added: a replayed prompt, or the prompt file.
+func promptBody(opts *Options) ([]byte, error) {
+	if opts.PromptText != "" {
+		return []byte(opts.PromptText), nil
+	}
+	content, err := os.ReadFile(opts.PromptFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading prompt file: %w", err)
+	}
+	return content, nil
+}
@@ -0,0 +1,102 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logfile "github.com/benwilkes9/ralph-cli/internal/log"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+func TestRun_RecordsPrompts(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 2
+	require.NoError(t, os.WriteFile(opts.PromptFile, []byte("Build the next task.\n"), 0o600))
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b", "c", "d"}}, &fakeClaude{stats: iterStats()}))
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	records, err := LoadPromptRecords(opts.LogsDir, st.Runs[0].StartedAt.Format(logfile.TimeLayout))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, PromptRecord{Iteration: 2, Model: DefaultModel, Prompt: "Build the next task.\n"}, records[1])
+}
+
+func TestRun_ReplaysPrompts(t *testing.T) {
+	opts := baseOpts(t)
+	require.NoError(t, os.WriteFile(opts.PromptFile, []byte("Today's prompt.\n"), 0o600))
+	opts.ReplayOf = 3
+	opts.Replay = []PromptRecord{
+		{Iteration: 1, Prompt: "Recorded prompt one.\n", TaskID: "T1"},
+		{Iteration: 2, Prompt: "Recorded prompt two.\n", Feedback: "use the helper"},
+	}
+	opts.MaxIterations = len(opts.Replay)
+
+	var prompts []string
+	c := &fakeClaude{stats: iterStats(), onRun: func(_ int, o *Options) {
+		p, err := assemblePrompt(o)
+		require.NoError(t, err)
+		prompts = append(prompts, string(p))
+	}}
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b", "c", "d"}}, c))
+
+	require.Len(t, prompts, 2)
+	assert.Contains(t, prompts[0], "Recorded prompt one.\n---\n")
+	assert.Contains(t, prompts[0], "TASK_ID: T1\n")
+	assert.Contains(t, prompts[1], "Recorded prompt two.\n---\n")
+	assert.Contains(t, prompts[1], "OPERATOR_FEEDBACK: use the helper\n")
+	assert.Equal(t, []string{"T1", ""}, c.taskIDs)
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	assert.Equal(t, 3, st.Runs[0].ReplayOf)
+}
+
+func TestRun_ReplayCurrentPrompts(t *testing.T) {
+	opts := baseOpts(t)
+	require.NoError(t, os.WriteFile(opts.PromptFile, []byte("Today's prompt.\n"), 0o600))
+	opts.Replay = []PromptRecord{{Iteration: 1, Prompt: "Recorded prompt.\n", Feedback: "smaller steps"}}
+	opts.CurrentPrompts = true
+
+	var prompt string
+	c := &fakeClaude{stats: iterStats(), onRun: func(_ int, o *Options) {
+		p, err := assemblePrompt(o)
+		require.NoError(t, err)
+		prompt = string(p)
+	}}
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b"}}, c))
+
+	assert.Contains(t, prompt, "Today's prompt.\n---\n")
+	assert.Contains(t, prompt, "OPERATOR_FEEDBACK: smaller steps\n")
+	assert.NotContains(t, prompt, "Recorded prompt.")
+}
+
+func TestLoadPromptRecords(t *testing.T) {
+	dir := t.TempDir()
+	records, err := LoadPromptRecords(dir, "20250101-120000")
+	require.NoError(t, err)
+	assert.Empty(t, records, "a run from before prompts were recorded")
+
+	opts := &Options{PromptText: "Plan.\n", Model: "opus"}
+	runID := "20260101-120000"
+	require.NoError(t, writePromptArtifact(PromptArtifactPath(dir, runID, 1), 1, opts))
+	require.NoError(t, writePromptArtifact(PromptArtifactPath(dir, runID, 3), 3, opts))
+	records, err = LoadPromptRecords(dir, runID)
+	require.NoError(t, err)
+	assert.Equal(t, []PromptRecord{{Iteration: 1, Model: "opus", Prompt: "Plan.\n"}}, records, "stops at the first gap")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, runID, "iter-2.prompt.json"), []byte("{"), 0o600))
+	_, err = LoadPromptRecords(dir, runID)
+	require.ErrorContains(t, err, "parsing iter-2.prompt.json")
+}
@@ -0,0 +1,43 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// reportPending marks the primary repo's HEAD as having a run in progress.
+// Reporting is best-effort: failures are shown and the run carries on.
+func reportPending(ctx context.Context, gitCl GitClient, opts *Options, w io.Writer, theme *ui.Theme, description string) {
+	if opts.Reporter == nil {
+		return
+	}
+	head, err := gitCl.Head(ctx)
+	if err == nil {
+		err = opts.Reporter.Pending(ctx, head, description)
+	}
+	renderReportError(w, err, theme)
+}
+
+// reportDone marks the primary repo's HEAD with the run's outcome. It runs
+// after cancellation too, so it ignores ctx's cancellation.
+func reportDone(ctx context.Context, gitCl GitClient, opts *Options, w io.Writer, theme *ui.Theme, success bool, title, summary string) {
+	if opts.Reporter == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	head, err := gitCl.Head(ctx)
+	if err == nil {
+		err = opts.Reporter.Done(ctx, head, success, title, summary)
+	}
+	renderReportError(w, err, theme)
+}
+
+//nolint:errcheck // display-only writes to terminal
+func renderReportError(w io.Writer, err error, theme *ui.Theme) {
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("GitHub status not updated: %s", err)))
+	}
+}
@@ -0,0 +1,34 @@
+package loop
+
+import (
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/status"
+)
+
+// routeModel returns the model opts.ModelRoutes picks for the plan's active
+// task, with the task's label or category that picked it. model is "" when
+// no route matches, leaving the iteration on opts.Model.
+func routeModel(opts *Options) (model, category string) {
+	if len(opts.ModelRoutes) == 0 || opts.Mode != ModeBuild {
+		return "", ""
+	}
+	tasks, err := status.ParsePlan(opts.PlanFile)
+	if err != nil {
+		return "", ""
+	}
+	t := status.ActiveTask(tasks)
+	if t == nil {
+		return "", ""
+	}
+	category = t.Category()
+	if category == "" {
+		return "", ""
+	}
+	for label, m := range opts.ModelRoutes {
+		if strings.EqualFold(label, category) {
+			return m, category
+		}
+	}
+	return "", ""
+}
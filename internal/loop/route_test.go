@@ -0,0 +1,74 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const routedPlan = `# Plan
+
+### Task 1.1: Scaffold
+- **Label:** feature
+- [x] **Status:** Complete
+
+### Task 1.2: Refactor the session store
+- [ ] **Status:** Incomplete
+
+### Task 2.1: Design the sync protocol
+- **Label:** Design
+- [ ] **Status:** Incomplete
+`
+
+func routeOpts(t *testing.T, plan string) *Options {
+	t.Helper()
+	opts := baseOpts(t)
+	opts.PlanFile = filepath.Join(t.TempDir(), "PLAN.md")
+	require.NoError(t, os.WriteFile(opts.PlanFile, []byte(plan), 0o600))
+	opts.Model = "claude-sonnet-4-5"
+	opts.ModelRoutes = map[string]string{"refactor": "claude-haiku-4-5", "design": "claude-opus-4-1"}
+	return opts
+}
+
+func TestRouteModel(t *testing.T) {
+	opts := routeOpts(t, routedPlan)
+	model, category := routeModel(opts)
+	assert.Equal(t, "claude-haiku-4-5", model, "the title implies refactor")
+	assert.Equal(t, "refactor", category)
+
+	opts = routeOpts(t, taskPlan)
+	model, _ = routeModel(opts)
+	assert.Empty(t, model, "no route for an uncategorised task")
+
+	opts = routeOpts(t, routedPlan)
+	opts.Mode = ModePlan
+	model, _ = routeModel(opts)
+	assert.Empty(t, model, "plan iterations aren't routed")
+}
+
+func TestRun_RoutesModelPerIteration(t *testing.T) {
+	opts := routeOpts(t, routedPlan)
+	opts.MaxIterations = 2
+	var models []string
+	c := &fakeClaude{stats: iterStats(), onRun: func(call int, o *Options) {
+		models = append(models, o.Model)
+		if call == 1 { // finish the refactor; the design task is next
+			plan, err := os.ReadFile(o.PlanFile)
+			require.NoError(t, err)
+			done := bytes.Replace(plan, []byte("- [ ] **Status:** Incomplete"), []byte("- [x] **Status:** Complete"), 1)
+			require.NoError(t, os.WriteFile(o.PlanFile, done, 0o600))
+		}
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b", "c"}}, c))
+	assert.Equal(t, []string{"claude-haiku-4-5", "claude-opus-4-1"}, models)
+	assert.Contains(t, buf.String(), "(refactor task)")
+	assert.Contains(t, buf.String(), "(design task)")
+	assert.Equal(t, "claude-sonnet-4-5", opts.Model, "routing doesn't change the run's model")
+}
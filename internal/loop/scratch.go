@@ -0,0 +1,92 @@
+package loop
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+type scratchFile struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+// pruneScratch deletes the oldest files under dir until its total size is at
+// most limit bytes, returning the removed paths relative to dir. A missing
+// dir or a non-positive limit is a no-op.
+func pruneScratch(dir string, limit int64) ([]string, error) {
+	if dir == "" || limit <= 0 {
+		return nil, nil
+	}
+
+	var (
+		files []scratchFile
+		total int64
+	)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		files = append(files, scratchFile{path: path, size: info.Size(), modTime: info.ModTime().UnixNano()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("measuring scratch dir: %w", err)
+	}
+	if total <= limit {
+		return nil, nil
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime < files[j].modTime })
+	var removed []string
+	for _, f := range files {
+		if total <= limit {
+			break
+		}
+		if err := os.Remove(f.path); err != nil {
+			return removed, fmt.Errorf("pruning scratch: %w", err)
+		}
+		total -= f.size
+		rel, _ := filepath.Rel(dir, f.path) //nolint:errcheck // path is under dir
+		removed = append(removed, rel)
+	}
+	return removed, nil
+}
+
+// enforceScratchLimit prunes the scratchpad after an iteration and reports
+// what was removed. Failures only produce a warning.
+//
+//nolint:errcheck // display-only writes to terminal
+func enforceScratchLimit(opts *Options, w io.Writer, theme *ui.Theme) {
+	removed, err := pruneScratch(opts.ScratchDir, opts.ScratchLimit)
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Scratch limit check failed: %s", err)))
+	}
+	if len(removed) > 0 {
+		fmt.Fprintf(w, "%s %s\n",
+			theme.Warning.Render(fmt.Sprintf("Scratch dir over %s limit:", formatBytes(opts.ScratchLimit))),
+			theme.Muted.Render(fmt.Sprintf("pruned %d oldest file(s)", len(removed))))
+	}
+}
+
+// formatBytes renders a byte count in whole megabytes, e.g. "100MB".
+func formatBytes(n int64) string {
+	return fmt.Sprintf("%dMB", n/(1<<20))
+}
@@ -0,0 +1,59 @@
+package loop
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScratch(t *testing.T, dir, name string, size int, age time.Duration) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0o600))
+	mod := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, mod, mod))
+}
+
+func TestPruneScratch_RemovesOldestFirst(t *testing.T) {
+	dir := t.TempDir()
+	writeScratch(t, dir, "old.md", 60, 3*time.Hour)
+	writeScratch(t, dir, "notes/mid.md", 30, 2*time.Hour)
+	writeScratch(t, dir, "new.md", 30, time.Hour)
+
+	removed, err := pruneScratch(dir, 70)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old.md"}, removed)
+	assert.NoFileExists(t, filepath.Join(dir, "old.md"))
+	assert.FileExists(t, filepath.Join(dir, "new.md"))
+}
+
+func TestPruneScratch_UnderLimitOrMissing(t *testing.T) {
+	dir := t.TempDir()
+	writeScratch(t, dir, "a.md", 10, time.Hour)
+
+	removed, err := pruneScratch(dir, 100)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+
+	removed, err = pruneScratch(filepath.Join(dir, "missing"), 1)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestEnforceScratchLimit(t *testing.T) {
+	opts := baseOpts(t)
+	opts.ScratchDir = filepath.Join(t.TempDir(), "scratch")
+	opts.ScratchLimit = 1 << 20
+	writeScratch(t, opts.ScratchDir, "big.md", 2<<20, time.Hour)
+
+	var buf bytes.Buffer
+	enforceScratchLimit(opts, &buf, runTheme)
+	assert.Contains(t, buf.String(), "Scratch dir over 1MB limit")
+	assert.NoFileExists(t, filepath.Join(opts.ScratchDir, "big.md"))
+}
@@ -0,0 +1,132 @@
+package loop
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// StepAction is the operator's decision at a step-mode pause.
+type StepAction int
+
+const (
+	StepContinue StepAction = iota // push and run the next iteration
+	StepSkip                       // discard this iteration's commits, then continue
+	StepAbort                      // stop the loop
+	StepExpand                     // show the last tool call's full input, then prompt again
+)
+
+// StepDecision is a parsed step-mode response.
+type StepDecision struct {
+	Action   StepAction
+	Feedback string // guidance prepended to the next iteration's prompt
+}
+
+// stepHelp lists the commands accepted at a step-mode pause.
+const stepHelp = "enter = continue · skip = discard this iteration · abort = stop · feedback <text> = guide the next iteration · expand = show the last tool call in full"
+
+// ParseStep interprets one line typed at a step-mode pause. ok is false for
+// unrecognised input.
+func ParseStep(line string) (d StepDecision, ok bool) {
+	line = strings.TrimSpace(line)
+	cmd, rest, _ := strings.Cut(line, " ")
+	switch strings.ToLower(cmd) {
+	case "", "c", "continue":
+		return StepDecision{Action: StepContinue}, true
+	case "s", "skip":
+		return StepDecision{Action: StepSkip}, true
+	case "a", "abort", "q", "quit":
+		return StepDecision{Action: StepAbort}, true
+	case "f", "feedback":
+		return StepDecision{Action: StepContinue, Feedback: strings.TrimSpace(rest)}, true
+	case "x", "expand":
+		return StepDecision{Action: StepExpand}, true
+	}
+	return StepDecision{}, false
+}
+
+// promptStep shows the iteration's diff and cost, then reads commands from r
+// until one is recognised. A bare "feedback" reads the guidance from the
+// following line. EOF aborts, since nobody is left to supervise the run.
+//
+//nolint:errcheck // display-only writes to terminal
+func promptStep(r *bufio.Reader, w io.Writer, diffStat string, iter *stream.IterationStats, cum *stream.CumulativeStats, cur pricing.Currency, theme *ui.Theme) (StepDecision, error) {
+	fmt.Fprintln(w)
+	if diffStat != "" {
+		fmt.Fprintln(w, theme.Muted.Render("  Changes this iteration:"))
+		for _, line := range strings.Split(strings.TrimRight(diffStat, "\n"), "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	} else {
+		fmt.Fprintln(w, theme.Muted.Render("  No commits this iteration."))
+	}
+	if iter != nil {
+		fmt.Fprintf(w, "  %s %s  %s %s\n",
+			theme.Muted.Render("Cost"), theme.Cost.Render(cur.Format(iter.Cost, 4)),
+			theme.Muted.Render("Total"), theme.Cost.Render(cur.Format(cum.TotalCost, 4)))
+	}
+	fmt.Fprintln(w, theme.Muted.Render("  "+stepHelp))
+
+	for {
+		fmt.Fprint(w, theme.Info.Render("  step> "))
+		line, err := r.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			if errors.Is(err, io.EOF) {
+				return StepDecision{Action: StepAbort}, nil
+			}
+			return StepDecision{}, fmt.Errorf("reading step input: %w", err)
+		}
+
+		d, ok := ParseStep(line)
+		if !ok {
+			fmt.Fprintln(w, theme.Warning.Render("  Unknown command. "+stepHelp))
+			continue
+		}
+		if d.Action == StepExpand {
+			showLastTool(w, iter, theme)
+			continue
+		}
+		if d.Action == StepContinue && d.Feedback == "" && isBareFeedback(line) {
+			fmt.Fprint(w, theme.Info.Render("  feedback> "))
+			fb, err := r.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return StepDecision{}, fmt.Errorf("reading step feedback: %w", err)
+			}
+			d.Feedback = strings.TrimSpace(fb)
+		}
+		return d, nil
+	}
+}
+
+// showLastTool prints the last tool call of the iteration with its input in
+// full, for when the one-line parameter in the stream was cut short.
+//
+//nolint:errcheck // display-only writes to terminal
+func showLastTool(w io.Writer, iter *stream.IterationStats, theme *ui.Theme) {
+	if iter == nil || iter.LastTool == nil {
+		fmt.Fprintln(w, theme.Muted.Render("  No tool calls this iteration."))
+		return
+	}
+	input := []byte(iter.LastTool.Input)
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, input, "    ", "  ") == nil {
+		input = pretty.Bytes()
+	}
+	fmt.Fprintf(w, "  %s\n    %s\n", theme.Muted.Render("Last tool call: "+iter.LastTool.Name), input)
+}
+
+func isBareFeedback(line string) bool {
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "f", "feedback":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package loop
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+func TestParseStep(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   StepDecision
+		wantOK bool
+	}{
+		{"\n", StepDecision{Action: StepContinue}, true},
+		{"continue\n", StepDecision{Action: StepContinue}, true},
+		{"SKIP\n", StepDecision{Action: StepSkip}, true},
+		{"abort", StepDecision{Action: StepAbort}, true},
+		{"q\n", StepDecision{Action: StepAbort}, true},
+		{"feedback  add more tests \n", StepDecision{Action: StepContinue, Feedback: "add more tests"}, true},
+		{"f use the helper\n", StepDecision{Action: StepContinue, Feedback: "use the helper"}, true},
+		{"x\n", StepDecision{Action: StepExpand}, true},
+		{"expand", StepDecision{Action: StepExpand}, true},
+		{"maybe\n", StepDecision{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.line), func(t *testing.T) {
+			got, ok := ParseStep(tt.line)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPromptStep_Expand(t *testing.T) {
+	iter := iterStats()
+	iter.LastTool = &stream.ContentBlock{Type: "tool_use", Name: "Bash", Input: json.RawMessage(`{"command":"go test ./...","timeout":600000}`)}
+	var buf bytes.Buffer
+	r := bufio.NewReader(strings.NewReader("expand\n\n"))
+
+	d, err := promptStep(r, &buf, "", iter, &stream.CumulativeStats{}, pricing.Currency{}, runTheme)
+	require.NoError(t, err)
+	assert.Equal(t, StepContinue, d.Action)
+	assert.Contains(t, buf.String(), "Last tool call: Bash")
+	assert.Contains(t, buf.String(), `"command": "go test ./...",`)
+	assert.Contains(t, buf.String(), `"timeout": 600000`)
+
+	buf.Reset()
+	_, err = promptStep(bufio.NewReader(strings.NewReader("x\n\n")), &buf, "", nil, &stream.CumulativeStats{}, pricing.Currency{}, runTheme)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "No tool calls this iteration.")
+}
+
+// stepHeads yields a new HEAD on every call so each iteration has commits.
+func stepHeads() []string {
+	return []string{"sha-0", "sha-1", "sha-2", "sha-3", "sha-4", "sha-5", "sha-6"}
+}
+
+func TestRun_StepFeedbackReachesNextIteration(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 2
+	opts.StepIn = strings.NewReader("feedback\nadd more tests\n\n")
+
+	g := &fakeGit{heads: stepHeads(), diffStat: " main.go | 3 ++-\n"}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	assert.Equal(t, []string{"", "add more tests"}, c.feedbacks)
+	assert.Contains(t, buf.String(), "main.go | 3 ++-")
+	assert.Contains(t, buf.String(), "$0.0100")
+	assert.Empty(t, opts.Feedback, "caller's options must not be mutated")
+}
+
+func TestRun_StepAbortStopsLoop(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 3
+	opts.StepIn = strings.NewReader("nonsense\nabort\n")
+
+	g := &fakeGit{heads: stepHeads()}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	assert.Equal(t, 1, c.called)
+	assert.Contains(t, buf.String(), "Unknown command")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	assert.Equal(t, state.StatusCancelled, st.Runs[0].Status)
+}
+
+func TestRun_StepSkipResetsToIterationStart(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 1
+	opts.StepIn = strings.NewReader("skip\n")
+	opts.Clock = &clock.Fake{T: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Step: time.Second}
+
+	g := &fakeGit{heads: stepHeads()}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	assert.Equal(t, []string{"sha-1"}, g.resetTo)
+	assert.Contains(t, buf.String(), "Iteration discarded")
+
+	// Nothing of the discarded iteration is kept for resume to start from.
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	assert.Empty(t, st.Runs[0].Checkpoints)
+	assert.NoFileExists(t, DiffArtifactPath(opts.LogsDir, "20260301-120000", 1))
+}
+
+func TestRun_StepEOFAborts(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 3
+	opts.StepIn = strings.NewReader("")
+
+	g := &fakeGit{heads: stepHeads()}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+	assert.Equal(t, 1, c.called)
+}
@@ -0,0 +1,92 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/status"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// activeTaskID returns the id of the first incomplete task in the plan
+// (e.g. "T2.1"), or "" when there is none or the plan can't be read.
+func activeTaskID(planFile string) string {
+	tasks, err := status.ParsePlan(planFile)
+	if err != nil {
+		return ""
+	}
+	t := status.ActiveTask(tasks)
+	if t == nil || t.ID == "" {
+		return ""
+	}
+	return "T" + t.ID
+}
+
+// taskTag is the marker commit messages must contain, e.g. "[T2.1]".
+func taskTag(taskID string) string {
+	return "[" + taskID + "]"
+}
+
+// enforceTaskID checks that the iteration's commits (primaryBefore..HEAD)
+// reference taskID. With opts.AmendTaskID, a last commit that lacks the tag
+// and hasn't been pushed is reworded to start with it; older commits would
+// need a rebase and are only reported. Returns whether HEAD was rewritten.
+//
+//nolint:errcheck // display-only writes to terminal
+func enforceTaskID(ctx context.Context, gitCl GitClient, opts *Options, w io.Writer, theme *ui.Theme, taskID, primaryBefore string) bool {
+	tag := taskTag(taskID)
+	subjects, err := gitCl.CommitSubjects(ctx, primaryBefore, "HEAD")
+	if err != nil {
+		fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Could not check commit messages: %s", err)))
+		return false
+	}
+
+	missing := 0
+	for _, s := range subjects {
+		if !strings.Contains(s, tag) {
+			missing++
+		}
+	}
+	if missing == 0 {
+		return false
+	}
+
+	amended := false
+	if opts.AmendTaskID && !strings.Contains(subjects[0], tag) && headUnpushed(ctx, gitCl, opts.Branch) {
+		if err := amendWithTag(ctx, gitCl, tag); err != nil {
+			fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("Could not amend commit: %s", err)))
+		} else {
+			fmt.Fprintln(w, theme.Muted.Render("Amended last commit to reference "+tag))
+			amended = true
+			missing--
+		}
+	}
+
+	if missing > 0 {
+		fmt.Fprintf(w, "%s %s\n",
+			theme.Warning.Render(fmt.Sprintf("%d commit(s) this iteration don't reference %s", missing, tag)),
+			theme.Muted.Render(fmt.Sprintf("(task %s)", taskID)))
+	}
+	return amended
+}
+
+// headUnpushed reports whether HEAD is not yet on origin. A branch with no
+// remote counterpart counts as unpushed.
+func headUnpushed(ctx context.Context, gitCl GitClient, branch string) bool {
+	n, err := gitCl.UnpushedCount(ctx, branch)
+	return err != nil || n > 0
+}
+
+// amendWithTag prefixes the HEAD commit's message with tag.
+func amendWithTag(ctx context.Context, gitCl GitClient, tag string) error {
+	msg, err := gitCl.LastCommitMessage(ctx)
+	if err != nil {
+		return fmt.Errorf("reading last commit message: %w", err)
+	}
+	if err := gitCl.AmendCommitMessage(ctx, tag+" "+msg); err != nil {
+		return fmt.Errorf("amending commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const taskPlan = `# Plan
+
+### Task 1.1: Scaffold
+- [x] **Status:** Complete
+
+### Task 2.1: Login form
+- [ ] **Status:** Incomplete
+`
+
+func taskOpts(t *testing.T) *Options {
+	t.Helper()
+	opts := baseOpts(t)
+	opts.PlanFile = filepath.Join(t.TempDir(), "PLAN.md")
+	require.NoError(t, os.WriteFile(opts.PlanFile, []byte(taskPlan), 0o600))
+	opts.RequireTaskID = true
+	return opts
+}
+
+func TestActiveTaskID(t *testing.T) {
+	opts := taskOpts(t)
+	assert.Equal(t, "T2.1", activeTaskID(opts.PlanFile))
+	assert.Empty(t, activeTaskID(filepath.Join(t.TempDir(), "missing.md")))
+}
+
+func TestRun_TaskIDPassedToClaude(t *testing.T) {
+	opts := taskOpts(t)
+	g := &fakeGit{heads: []string{"sha-a", "sha-b"}, subjects: []string{"[T2.1] feat: login form"}}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	assert.Equal(t, []string{"T2.1"}, c.taskIDs)
+	assert.NotContains(t, buf.String(), "don't reference")
+	assert.Empty(t, g.amendedMsg)
+}
+
+func TestRun_TaskIDSkippedInPlanMode(t *testing.T) {
+	opts := taskOpts(t)
+	opts.Mode = ModePlan
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"sha-a", "sha-b"}}, c))
+	assert.Equal(t, []string{""}, c.taskIDs)
+}
+
+func TestRun_TaskIDMissingWarns(t *testing.T) {
+	opts := taskOpts(t)
+	g := &fakeGit{heads: []string{"sha-a", "sha-b"}, subjects: []string{"feat: login form"}, unpushed: 1}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	assert.Contains(t, buf.String(), "1 commit(s) this iteration don't reference [T2.1]")
+	assert.Empty(t, g.amendedMsg, "amend is opt-in")
+}
+
+func TestRun_TaskIDAmendsUnpushedHead(t *testing.T) {
+	opts := taskOpts(t)
+	opts.AmendTaskID = true
+	g := &fakeGit{
+		heads:    []string{"sha-a", "sha-b"},
+		subjects: []string{"feat: login form", "[T2.1] test: login form"},
+		lastMsg:  "feat: login form\n\nAdds the form.",
+		unpushed: 1,
+	}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	assert.Equal(t, "[T2.1] feat: login form\n\nAdds the form.", g.amendedMsg)
+	assert.NotContains(t, buf.String(), "don't reference")
+}
+
+func TestRun_TaskIDDoesNotAmendPushedHead(t *testing.T) {
+	opts := taskOpts(t)
+	opts.AmendTaskID = true
+	g := &fakeGit{heads: []string{"sha-a", "sha-b"}, subjects: []string{"feat: login form"}, unpushed: 0}
+	c := &fakeClaude{stats: iterStats()}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	assert.Empty(t, g.amendedMsg)
+	assert.Contains(t, buf.String(), "don't reference [T2.1]")
+}
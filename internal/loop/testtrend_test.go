@@ -0,0 +1,55 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/testresult"
+)
+
+func TestRun_RecordsTestTrend(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 3
+	withTests := func(c testresult.Counts) *stream.IterationStats {
+		s := iterStats()
+		s.Tests = &c
+		return s
+	}
+	c := &fakeClaude{perIter: []*stream.IterationStats{
+		withTests(testresult.Counts{Passed: 5}),
+		iterStats(), // no test run this iteration
+		withTests(testresult.Counts{Passed: 4, Failed: 2}),
+	}}
+	g := &fakeGit{heads: []string{"sha-a", "sha-b", "sha-c", "sha-d", "sha-e", "sha-f"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, g, c))
+
+	out := buf.String()
+	assert.Contains(t, out, "tests: 5 passed, 0 failed")
+	assert.Contains(t, out, "tests: 4 passed, 2 failed")
+	assert.Contains(t, out, "▲ failures up from 0")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs[0].Tests, 2)
+	assert.Equal(t, 1, st.Runs[0].Tests[0].Iteration)
+	assert.Equal(t, 3, st.Runs[0].Tests[1].Iteration)
+	assert.Equal(t, 2, st.Runs[0].Tests[1].Failed)
+}
+
+func TestRenderTestTrend_NoRegression(t *testing.T) {
+	var buf bytes.Buffer
+	RenderTestTrend(&buf, []state.TestResult{
+		{Iteration: 1, Counts: testresult.Counts{Passed: 3, Failed: 2}},
+		{Iteration: 2, Counts: testresult.Counts{Passed: 5}},
+	}, runTheme)
+	assert.Contains(t, buf.String(), "5 passed, 0 failed")
+	assert.NotContains(t, buf.String(), "▲")
+}
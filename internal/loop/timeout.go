@@ -0,0 +1,54 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// DefaultMaxTimeouts is how many iterations in a row may time out before the
+// run aborts, when Options.MaxTimeouts is unset.
+const DefaultMaxTimeouts = 3
+
+// killGrace is how long a killed agent's output pipes may stay open, e.g.
+// held by its own subprocesses, before the loop stops waiting for them.
+const killGrace = 10 * time.Second
+
+// iterationContext bounds one iteration by opts.Timeout.
+func iterationContext(ctx context.Context, opts *Options) (context.Context, context.CancelFunc) {
+	if opts.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, opts.Timeout)
+}
+
+// maxTimeouts returns opts.MaxTimeouts, defaulting to DefaultMaxTimeouts.
+func (o *Options) maxTimeouts() int {
+	if o.MaxTimeouts > 0 {
+		return o.MaxTimeouts
+	}
+	return DefaultMaxTimeouts
+}
+
+// RenderIterationTimeout prints the warning for an iteration killed at the
+// timeout, with how many in a row have timed out.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderIterationTimeout(w io.Writer, limit time.Duration, count, maxCount int, theme *ui.Theme) {
+	fmt.Fprintf(w, "%s %s\n",
+		theme.Warning.Render(fmt.Sprintf("Iteration timed out after %s; moving on.", limit)),
+		theme.Muted.Render(fmt.Sprintf("(timeouts: %d/%d)", count, maxCount)))
+}
+
+// RenderTimeoutAbort prints the abort message when too many iterations in a
+// row timed out.
+//
+//nolint:errcheck // display-only writes to terminal
+func RenderTimeoutAbort(w io.Writer, count int, limit time.Duration, theme *ui.Theme) {
+	fmt.Fprintf(w, "\n%s %s\n",
+		theme.Error.Render("Timed out:"),
+		fmt.Sprintf("%d iterations in a row ran past %s. Stopping.", count, limit))
+}
@@ -0,0 +1,88 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+// hangingClaude hangs until its context ends on the calls listed in hang,
+// and finishes at once on the others.
+type hangingClaude struct {
+	hang   map[int]bool
+	called int
+}
+
+func (h *hangingClaude) Run(ctx context.Context, _ *Options, logW, _ io.Writer) (*stream.IterationStats, error) {
+	h.called++
+	fmt.Fprintln(logW, `{}`) //nolint:errcheck // test helper write
+	if h.hang[h.called] {
+		<-ctx.Done()
+		return &stream.IterationStats{Cost: 0.5}, ctx.Err()
+	}
+	return iterStats(), nil
+}
+
+func TestRun_TimedOutIterationMovesOn(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 3
+	opts.Timeout = 10 * time.Millisecond
+	c := &hangingClaude{hang: map[int]bool{1: true, 3: true}}
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a", "b", "c", "d"}}, c))
+	assert.Equal(t, 3, c.called)
+	assert.Contains(t, buf.String(), "Iteration timed out after 10ms; moving on.")
+	assert.Contains(t, buf.String(), "(timeouts: 1/3)")
+	assert.NotContains(t, buf.String(), "(timeouts: 2/3)", "a finished iteration resets the count")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	assert.Equal(t, 2, st.Runs[0].ClaudeFailures)
+	assert.InDelta(t, 1.01, st.Runs[0].TotalCost, 1e-9, "timed-out attempts are still billed")
+}
+
+func TestRun_AbortsAfterConsecutiveTimeouts(t *testing.T) {
+	opts := baseOpts(t)
+	opts.MaxIterations = 10
+	opts.Timeout = 5 * time.Millisecond
+	opts.MaxTimeouts = 2
+	c := &hangingClaude{hang: map[int]bool{1: true, 2: true, 3: true}}
+
+	var buf bytes.Buffer
+	err := run(context.Background(), opts, &buf, runTheme, &fakeGit{heads: []string{"a"}}, c)
+	require.ErrorContains(t, err, "2 iterations in a row timed out after 5ms")
+	assert.Equal(t, 2, c.called)
+	assert.Contains(t, buf.String(), "Stopping.")
+
+	st, err := state.Load(opts.StateFile)
+	require.NoError(t, err)
+	require.Len(t, st.Runs, 1)
+	assert.Equal(t, state.StatusTimeoutAbort, st.Runs[0].Status)
+}
+
+func TestRun_CancelIsNotATimeout(t *testing.T) {
+	opts := baseOpts(t)
+	opts.Timeout = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &hangingClaude{hang: map[int]bool{1: true}}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	var buf bytes.Buffer
+	err := run(ctx, opts, &buf, runTheme, &fakeGit{heads: []string{"a"}}, c)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NotContains(t, buf.String(), "timed out")
+}
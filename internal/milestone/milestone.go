@@ -0,0 +1,212 @@
+// Package milestone splits an oversized plan into milestone plan files that
+// are built one at a time, leaving an index in the original plan file.
+package milestone
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/status"
+)
+
+// ErrAlreadySplit is returned when a plan already has milestone files.
+var ErrAlreadySplit = errors.New("plan is already split")
+
+// Milestone is one milestone plan file.
+type Milestone struct {
+	Path  string
+	Text  string
+	Tasks []status.Task
+}
+
+// Result is a split plan: the index that replaces the plan, and its
+// milestones in build order.
+type Result struct {
+	Index      string
+	Milestones []Milestone
+}
+
+// Path returns the file for milestone n (1-based) of planPath, e.g.
+// "plans/PLAN-m2.md" for "plans/PLAN.md".
+func Path(planPath string, n int) string {
+	ext := filepath.Ext(planPath)
+	return fmt.Sprintf("%s-m%d%s", strings.TrimSuffix(planPath, ext), n, ext)
+}
+
+// List returns the milestone files of planPath that exist, in order.
+func List(planPath string) []string {
+	var paths []string
+	for n := 1; ; n++ {
+		p := Path(planPath, n)
+		if _, err := os.Stat(p); err != nil {
+			return paths
+		}
+		paths = append(paths, p)
+	}
+}
+
+// Active returns the number (1-based) of the first milestone in paths with
+// a task left, or 0 when every task is done.
+func Active(paths []string) (int, error) {
+	for i, p := range paths {
+		tasks, err := status.ParsePlan(p)
+		if err != nil {
+			return 0, err //nolint:wrapcheck // ParsePlan errors already have context
+		}
+		if status.ActiveTask(tasks) != nil {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// Write splits the plan at planPath into milestones of at most maxTasks
+// tasks, writes them next to it and replaces the plan with their index.
+func Write(planPath string, maxTasks int) (*Result, error) {
+	if existing := List(planPath); len(existing) > 0 {
+		return nil, fmt.Errorf("%w into %d milestone(s); remove %s to split it again", ErrAlreadySplit, len(existing), filepath.Base(Path(planPath, 1)))
+	}
+	data, err := os.ReadFile(planPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+	res, err := Split(string(data), planPath, maxTasks)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range res.Milestones {
+		if err := os.WriteFile(m.Path, []byte(m.Text), 0o644); err != nil { //nolint:gosec // plan files are committed
+			return nil, fmt.Errorf("writing milestone: %w", err)
+		}
+	}
+	if err := os.WriteFile(planPath, []byte(res.Index), 0o644); err != nil { //nolint:gosec // plan files are committed
+		return nil, fmt.Errorf("writing plan index: %w", err)
+	}
+	return res, nil
+}
+
+// group is a "## " section of the plan: its heading and intro, then its
+// tasks. Tasks before any such heading form a group with no heading.
+type group struct {
+	head  string
+	tasks []task
+}
+
+type task struct {
+	status.Task
+	text string
+}
+
+// Split divides plan into milestones of at most maxTasks tasks. Sections
+// ("## " headings) are kept together where they fit, and a section too big
+// for one milestone repeats its heading in each. Text before the first
+// section and sections without tasks stay in the index.
+func Split(plan, planPath string, maxTasks int) (*Result, error) {
+	if maxTasks < 1 {
+		return nil, fmt.Errorf("milestones need at least 1 task each, got %d", maxTasks)
+	}
+	preamble, groups, notes := parse(plan)
+	total := 0
+	for _, g := range groups {
+		total += len(g.tasks)
+	}
+	if total <= maxTasks {
+		return nil, fmt.Errorf("plan has %d task(s), no more than %d; nothing to split", total, maxTasks)
+	}
+
+	var chunks []*Milestone
+	var text strings.Builder
+	cur := &Milestone{}
+	flush := func() {
+		cur.Text = text.String()
+		chunks = append(chunks, cur)
+		cur = &Milestone{}
+		text.Reset()
+	}
+	for _, g := range groups {
+		if len(cur.Tasks) > 0 && len(cur.Tasks)+len(g.tasks) > maxTasks {
+			flush()
+		}
+		for i, t := range g.tasks {
+			if len(cur.Tasks) == maxTasks {
+				flush()
+			}
+			if i == 0 || len(cur.Tasks) == 0 {
+				text.WriteString(g.head)
+			}
+			text.WriteString(t.text)
+			cur.Tasks = append(cur.Tasks, t.Task)
+		}
+	}
+	flush()
+
+	name := filepath.Base(planPath)
+	res := &Result{}
+	var index strings.Builder
+	index.WriteString(withNewline(preamble))
+	index.WriteString("\n## Milestones\n\n")
+	index.WriteString("This plan was split by `ralph plan split`. `ralph build` works through the milestones in order; `ralph build --milestone N` targets one.\n\n")
+	for i, c := range chunks {
+		n := i + 1
+		path := Path(planPath, n)
+		first, last := c.Tasks[0].ID, c.Tasks[len(c.Tasks)-1].ID
+		fmt.Fprintf(&index, "- Milestone %d: [%s](%s), tasks %s–%s (%d tasks)\n", n, filepath.Base(path), filepath.Base(path), first, last, len(c.Tasks))
+		header := fmt.Sprintf("# Milestone %d of %d\n\nPart of [%s](%s). Only the tasks below are in scope for this milestone.\n\n", n, len(chunks), name, name)
+		res.Milestones = append(res.Milestones, Milestone{Path: path, Text: header + c.Text, Tasks: c.Tasks})
+	}
+	if notes != "" {
+		index.WriteString("\n" + notes)
+	}
+	res.Index = index.String()
+	return res, nil
+}
+
+// parse breaks plan into the text before its first section or task, the
+// groups that hold tasks, and the sections that hold none.
+func parse(plan string) (preamble string, groups []*group, notes string) {
+	var pre, rest strings.Builder
+	var cur *group
+	done := func() {
+		if cur == nil {
+			return
+		}
+		if len(cur.tasks) > 0 {
+			groups = append(groups, cur)
+		} else {
+			rest.WriteString(cur.head)
+		}
+		cur = nil
+	}
+	for _, line := range strings.SplitAfter(plan, "\n") {
+		if t, ok := status.ParseTaskHeading(strings.TrimRight(line, "\r\n")); ok {
+			if cur == nil {
+				cur = &group{}
+			}
+			cur.tasks = append(cur.tasks, task{Task: t, text: line})
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "## "):
+			done()
+			cur = &group{head: line}
+		case cur == nil:
+			pre.WriteString(line)
+		case len(cur.tasks) == 0:
+			cur.head += line
+		default:
+			cur.tasks[len(cur.tasks)-1].text += line
+		}
+	}
+	done()
+	return pre.String(), groups, rest.String()
+}
+
+func withNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
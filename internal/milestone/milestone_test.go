@@ -0,0 +1,115 @@
+package milestone
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const plan = `# Implementation Plan
+
+Overview of the work.
+
+## Phase 1: Foundations
+
+### Task 1.1: Scaffold
+- [x] **Status:** Complete
+
+### Task 1.2: Database
+- [ ] **Status:** Incomplete
+
+## Phase 2: Features
+
+### Task 2.1: Login
+- [ ] **Status:** Incomplete
+
+### Task 2.2: Logout
+- [ ] **Status:** Incomplete
+
+### Task 2.3: Profile
+- [ ] **Status:** Incomplete
+
+## Notes
+
+Remember the docs.
+`
+
+func ids(m Milestone) []string {
+	var out []string
+	for _, t := range m.Tasks {
+		out = append(out, t.ID)
+	}
+	return out
+}
+
+func TestSplit_KeepsSectionsTogether(t *testing.T) {
+	res, err := Split(plan, "plans/PLAN.md", 3)
+	require.NoError(t, err)
+	require.Len(t, res.Milestones, 2)
+
+	m1, m2 := res.Milestones[0], res.Milestones[1]
+	assert.Equal(t, "plans/PLAN-m1.md", m1.Path)
+	assert.Equal(t, []string{"1.1", "1.2"}, ids(m1))
+	assert.Equal(t, []string{"2.1", "2.2", "2.3"}, ids(m2))
+	assert.True(t, strings.HasPrefix(m1.Text, "# Milestone 1 of 2\n"))
+	assert.Contains(t, m1.Text, "## Phase 1: Foundations\n")
+	assert.Contains(t, m2.Text, "## Phase 2: Features\n")
+	assert.NotContains(t, m2.Text, "Task 1.2")
+
+	assert.True(t, strings.HasPrefix(res.Index, "# Implementation Plan\n\nOverview of the work.\n"))
+	assert.Contains(t, res.Index, "- Milestone 1: [PLAN-m1.md](PLAN-m1.md), tasks 1.1–1.2 (2 tasks)\n")
+	assert.Contains(t, res.Index, "- Milestone 2: [PLAN-m2.md](PLAN-m2.md), tasks 2.1–2.3 (3 tasks)\n")
+	assert.Contains(t, res.Index, "## Notes\n\nRemember the docs.\n")
+	assert.NotContains(t, res.Index, "### Task")
+}
+
+func TestSplit_BreaksLargeSections(t *testing.T) {
+	res, err := Split(plan, "PLAN.md", 2)
+	require.NoError(t, err)
+	require.Len(t, res.Milestones, 3)
+	assert.Equal(t, []string{"2.1", "2.2"}, ids(res.Milestones[1]))
+	assert.Equal(t, []string{"2.3"}, ids(res.Milestones[2]))
+	assert.Contains(t, res.Milestones[2].Text, "## Phase 2: Features\n", "continuation repeats the heading")
+}
+
+func TestSplit_NothingToSplit(t *testing.T) {
+	_, err := Split(plan, "PLAN.md", 5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nothing to split")
+
+	_, err = Split(plan, "PLAN.md", 0)
+	require.Error(t, err)
+}
+
+func TestWriteListActive(t *testing.T) {
+	dir := t.TempDir()
+	planPath := filepath.Join(dir, "PLAN.md")
+	require.NoError(t, os.WriteFile(planPath, []byte(plan), 0o600))
+	assert.Empty(t, List(planPath))
+
+	res, err := Write(planPath, 3)
+	require.NoError(t, err)
+	paths := List(planPath)
+	assert.Equal(t, []string{filepath.Join(dir, "PLAN-m1.md"), filepath.Join(dir, "PLAN-m2.md")}, paths)
+
+	index, err := os.ReadFile(planPath)
+	require.NoError(t, err)
+	assert.Equal(t, res.Index, string(index))
+
+	n, err := Active(paths)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NoError(t, os.WriteFile(paths[0], []byte("### Task 1.2: Database\n- [x] done\n"), 0o600))
+	n, err = Active(paths)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	_, err = Write(planPath, 3)
+	assert.True(t, errors.Is(err, ErrAlreadySplit))
+}
@@ -0,0 +1,129 @@
+// Package notify posts run lifecycle events to a webhook as JSON, so teams
+// can route them to Slack, Discord or an incident tool without wrapping the
+// CLI.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Event names sent in Payload.Event.
+const (
+	EventRunStart       = "run_start"
+	EventIteration      = "iteration_complete"
+	EventStaleAbort     = "stale_abort"
+	EventBudgetExceeded = "budget_exceeded"
+	EventRunFinish      = "run_finish"
+)
+
+// Limits named in Payload.Limit when a run exceeds its budget.
+const (
+	LimitIterations = "iterations" // max iterations reached
+	LimitDisk       = "disk"       // the workspace grew past docker.disk_limit_mb
+	LimitCost       = "cost"       // the run spent cost.max_cost or --max-cost
+)
+
+// Payload is the JSON body posted for each event. Text repeats the event as
+// a sentence under the keys Slack ("text") and Discord ("content") show.
+type Payload struct {
+	Event         string    `json:"event"`
+	Text          string    `json:"text"`
+	Content       string    `json:"content"`
+	Repo          string    `json:"repo,omitempty"` // "owner/repo"
+	Branch        string    `json:"branch"`
+	Mode          string    `json:"mode"` // plan or build
+	Iteration     int       `json:"iteration,omitempty"`
+	MaxIterations int       `json:"max_iterations,omitempty"` // 0 = unlimited
+	Cost          float64   `json:"cost_usd,omitempty"`       // the iteration's, for iteration_complete
+	TotalCost     float64   `json:"total_cost_usd"`
+	Status        string    `json:"status,omitempty"` // run_finish: the status recorded in state.json, or "failed"
+	Limit         string    `json:"limit,omitempty"`  // budget_exceeded: which limit was hit
+	Error         string    `json:"error,omitempty"`  // run_finish with status failed
+	Time          time.Time `json:"time"`
+}
+
+// Summary describes the event in one line.
+func (p *Payload) Summary() string {
+	run := "Ralph " + p.Mode
+	if p.Branch != "" {
+		run += " on " + p.Branch
+	}
+	switch p.Event {
+	case EventRunStart:
+		return run + " started"
+	case EventIteration:
+		return fmt.Sprintf("%s finished iteration %d ($%.2f so far)", run, p.Iteration, p.TotalCost)
+	case EventStaleAbort:
+		return fmt.Sprintf("%s stopped after %d iterations without commits", run, p.Iteration)
+	case EventBudgetExceeded:
+		return fmt.Sprintf("%s hit its %s limit after %d iterations", run, p.Limit, p.Iteration)
+	case EventRunFinish:
+		s := fmt.Sprintf("%s finished: %s after %d iterations, $%.2f", run, strings.ReplaceAll(p.Status, "_", " "), p.Iteration, p.TotalCost)
+		if p.Error != "" {
+			s += " (" + p.Error + ")"
+		}
+		return s
+	}
+	return run + ": " + p.Event
+}
+
+// Notifier delivers lifecycle events.
+type Notifier interface {
+	Notify(ctx context.Context, p *Payload) error
+}
+
+// Webhook posts each event to URL.
+type Webhook struct {
+	URL  string
+	HTTP *http.Client // nil = a client with a short timeout
+}
+
+// New returns a Webhook for webhookURL, or nil when none is configured.
+func New(webhookURL string) Notifier {
+	if webhookURL == "" {
+		return nil
+	}
+	return &Webhook{URL: webhookURL}
+}
+
+// Notify posts p, filling in its text fields.
+func (h *Webhook) Notify(ctx context.Context, p *Payload) error {
+	p.Text = p.Summary()
+	p.Content = p.Text
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encoding %s event: %w", p.Event, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("building webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ralph")
+
+	client := h.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err // webhook URLs often embed a secret token
+		}
+		return fmt.Errorf("posting %s event: %w", p.Event, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("posting %s event: %s", p.Event, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	t.Cleanup(srv.Close)
+
+	n := New(srv.URL)
+	require.NoError(t, n.Notify(context.Background(), &Payload{
+		Event: EventRunFinish, Repo: "o/r", Branch: "feature/auth", Mode: "build",
+		Iteration: 3, TotalCost: 1.5, Status: "max_iterations", Time: time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+	}))
+
+	assert.Equal(t, "run_finish", got["event"])
+	assert.Equal(t, "Ralph build on feature/auth finished: max iterations after 3 iterations, $1.50", got["text"])
+	assert.Equal(t, got["text"], got["content"])
+	assert.Equal(t, "o/r", got["repo"])
+	assert.InDelta(t, 1.5, got["total_cost_usd"], 1e-9)
+	assert.Equal(t, "2026-03-01T09:30:00Z", got["time"])
+}
+
+func TestWebhook_NotifyError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(srv.Close)
+
+	err := New(srv.URL).Notify(context.Background(), &Payload{Event: EventRunStart})
+	require.ErrorContains(t, err, "posting run_start event: 403 Forbidden")
+
+	err = New("http://127.0.0.1:1/services/secret-token").Notify(context.Background(), &Payload{Event: EventRunStart})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-token", "the URL is left out of errors")
+}
+
+func TestNew_Off(t *testing.T) {
+	assert.Nil(t, New(""))
+}
+
+func TestPayload_Summary(t *testing.T) {
+	for _, tc := range []struct {
+		p    Payload
+		want string
+	}{
+		{Payload{Event: EventRunStart, Mode: "plan", Branch: "main"}, "Ralph plan on main started"},
+		{Payload{Event: EventIteration, Mode: "build", Iteration: 2, TotalCost: 0.75}, "Ralph build finished iteration 2 ($0.75 so far)"},
+		{Payload{Event: EventStaleAbort, Mode: "build", Iteration: 4}, "Ralph build stopped after 4 iterations without commits"},
+		{Payload{Event: EventBudgetExceeded, Mode: "build", Iteration: 5, Limit: LimitDisk}, "Ralph build hit its disk limit after 5 iterations"},
+		{Payload{Event: EventRunFinish, Mode: "build", Status: "failed", Error: "exit 1"}, "Ralph build finished: failed after 0 iterations, $0.00 (exit 1)"},
+	} {
+		assert.Equal(t, tc.want, tc.p.Summary())
+	}
+}
@@ -0,0 +1,192 @@
+// Package owners parses CODEOWNERS files and turns configured owners into a
+// path policy: paths the agent must not touch, and paths that need extra care.
+package owners
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Locations are the paths GitHub reads CODEOWNERS from, in priority order.
+var Locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Rule is one CODEOWNERS entry.
+type Rule struct {
+	Pattern string
+	Owners  []string
+	re      *regexp.Regexp
+}
+
+// Matches reports whether path (relative to the repo root, slash-separated)
+// falls under the rule's pattern.
+func (r *Rule) Matches(path string) bool {
+	return r.re.MatchString(strings.TrimPrefix(path, "/"))
+}
+
+// Parse reads CODEOWNERS entries from r. Comments and blank lines are
+// skipped; a pattern with no owners is kept, since it un-owns the paths an
+// earlier rule matched.
+func Parse(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		re, err := compile(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %w", n, fields[0], err)
+		}
+		rules = append(rules, Rule{Pattern: fields[0], Owners: fields[1:], re: re})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading CODEOWNERS: %w", err)
+	}
+	return rules, nil
+}
+
+// Load parses the first CODEOWNERS file found under repoRoot and returns its
+// rules and relative path. Returns nil, "", nil when there is none.
+func Load(repoRoot string) ([]Rule, string, error) {
+	for _, loc := range Locations {
+		f, err := os.Open(filepath.Join(repoRoot, loc)) //nolint:gosec // fixed locations under the repo root
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("opening %s: %w", loc, err)
+		}
+		rules, err := Parse(f)
+		f.Close() //nolint:errcheck,gosec // read-only
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing %s: %w", loc, err)
+		}
+		return rules, loc, nil
+	}
+	return nil, "", nil
+}
+
+// compile converts a CODEOWNERS (gitignore-style) pattern to a regexp. A
+// pattern without a slash before its end matches at any depth; one with a
+// leading or inner slash is anchored to the repo root. A match on a
+// directory covers everything beneath it.
+func compile(pattern string) (*regexp.Regexp, error) {
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	b.WriteString("(?:/.*)?$")
+	return regexp.Compile(b.String()) //nolint:wrapcheck // wrapped by Parse
+}
+
+// Class is how the policy treats a path.
+type Class int
+
+// Path classes, from least to most restricted.
+const (
+	Unrestricted Class = iota
+	Careful            // owned by a careful owner: flagged in the prompt
+	Forbidden          // owned by a protected owner: the agent must not touch it
+)
+
+// Policy classifies paths by their CODEOWNERS owners.
+type Policy struct {
+	rules   []Rule
+	protect map[string]bool
+	careful map[string]bool
+}
+
+// NewPolicy builds a policy where paths owned by any of protect are
+// Forbidden and paths owned by any of careful are Careful. Owners are
+// compared case-insensitively, as GitHub does.
+func NewPolicy(rules []Rule, protect, careful []string) *Policy {
+	return &Policy{rules: rules, protect: ownerSet(protect), careful: ownerSet(careful)}
+}
+
+func ownerSet(owners []string) map[string]bool {
+	set := make(map[string]bool, len(owners))
+	for _, o := range owners {
+		set[strings.ToLower(o)] = true
+	}
+	return set
+}
+
+// Classify returns the class of path. As in CODEOWNERS, the last matching
+// rule decides.
+func (p *Policy) Classify(path string) Class {
+	for i := len(p.rules) - 1; i >= 0; i-- {
+		if p.rules[i].Matches(path) {
+			return p.ruleClass(&p.rules[i])
+		}
+	}
+	return Unrestricted
+}
+
+func (p *Policy) ruleClass(r *Rule) Class {
+	class := Unrestricted
+	for _, o := range r.Owners {
+		switch o = strings.ToLower(o); {
+		case p.protect[o]:
+			return Forbidden
+		case p.careful[o]:
+			class = Careful
+		}
+	}
+	return class
+}
+
+// Patterns returns the patterns of rules in class, in file order, for
+// listing in the prompt. A pattern can still be partly overridden by a later
+// rule; Classify has the final say.
+func (p *Policy) Patterns(class Class) []string {
+	var out []string
+	for i := range p.rules {
+		if p.ruleClass(&p.rules[i]) == class {
+			out = append(out, p.rules[i].Pattern)
+		}
+	}
+	return out
+}
+
+// Violations returns the paths that are Forbidden.
+func (p *Policy) Violations(paths []string) []string {
+	var out []string
+	for _, path := range paths {
+		if p.Classify(path) == Forbidden {
+			out = append(out, path)
+		}
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package owners
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `# Default owners
+*                 @acme/devs
+
+# Infrastructure
+/infra/           @acme/security
+*.tf              @acme/security  # anywhere
+docs/**/*.md      @acme/docs
+/api/v?/          @acme/platform dev@acme.io
+/infra/README.md
+`
+
+func TestParse(t *testing.T) {
+	rules, err := Parse(strings.NewReader(sample))
+	require.NoError(t, err)
+	require.Len(t, rules, 6)
+	assert.Equal(t, "*.tf", rules[2].Pattern)
+	assert.Equal(t, []string{"@acme/security"}, rules[2].Owners, "trailing comment stripped")
+	assert.Equal(t, []string{"@acme/platform", "dev@acme.io"}, rules[4].Owners)
+	assert.Empty(t, rules[5].Owners)
+}
+
+func TestRuleMatches(t *testing.T) {
+	rules, err := Parse(strings.NewReader(sample))
+	require.NoError(t, err)
+
+	tests := []struct {
+		rule  int
+		path  string
+		match bool
+	}{
+		{0, "anything/at/all.go", true},
+		{1, "infra/main.tf", true},
+		{1, "infra/modules/vpc/main.tf", true},
+		{1, "src/infra/main.tf", false},
+		{2, "modules/vpc/main.tf", true},
+		{2, "main.tfvars", false},
+		{3, "docs/guide.md", true},
+		{3, "docs/a/b/guide.md", true},
+		{3, "src/docs/guide.md", false},
+		{4, "api/v1/handler.go", true},
+		{4, "api/v10/handler.go", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, rules[tt.rule].Matches(tt.path), "%s ~ %s", rules[tt.rule].Pattern, tt.path)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	rules, err := Parse(strings.NewReader(sample))
+	require.NoError(t, err)
+	p := NewPolicy(rules, []string{"@ACME/Security"}, []string{"@acme/platform"})
+
+	assert.Equal(t, Forbidden, p.Classify("infra/main.tf"))
+	assert.Equal(t, Forbidden, p.Classify("modules/vpc/main.tf"))
+	assert.Equal(t, Unrestricted, p.Classify("infra/README.md"), "later ownerless rule wins")
+	assert.Equal(t, Careful, p.Classify("api/v2/routes.go"))
+	assert.Equal(t, Unrestricted, p.Classify("cmd/main.go"))
+
+	assert.Equal(t, []string{"/infra/", "*.tf"}, p.Patterns(Forbidden))
+	assert.Equal(t, []string{"/api/v?/"}, p.Patterns(Careful))
+	assert.Equal(t, []string{"infra/x.tf"}, p.Violations([]string{"cmd/main.go", "infra/x.tf", "infra/README.md"}))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	rules, path, err := Load(dir)
+	require.NoError(t, err)
+	assert.Nil(t, rules)
+	assert.Empty(t, path)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CODEOWNERS"), []byte("* @root\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".github"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte("/infra/ @sec\n"), 0o600))
+
+	rules, path, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, ".github/CODEOWNERS", path)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "/infra/", rules[0].Pattern)
+}
@@ -0,0 +1,91 @@
+package planreview
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/status"
+)
+
+// IssueSync is what SyncIssue changed on an issue.
+type IssueSync struct {
+	Done, Total int
+	Completed   []string // tasks done since the last sync, as listed on the issue
+	Updated     bool     // the issue's checklist changed
+}
+
+// SyncIssue mirrors the plan's tasks on branch into a checklist in the
+// issue's body, kept between markers so the rest of the body and other
+// branches' checklists are left alone, and comments with the tasks that
+// have been done since the checklist was last synced. An unchanged plan
+// changes nothing.
+func (c *Client) SyncIssue(ctx context.Context, number int, branch string, tasks []status.Task) (*IssueSync, error) {
+	path := fmt.Sprintf("/repos/%s/issues/%d", c.Repo, number)
+	var issue struct {
+		Body string `json:"body"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &issue); err != nil {
+		return nil, err
+	}
+
+	open, closing := "<!-- ralph:plan "+branch+" -->", "<!-- /ralph:plan "+branch+" -->"
+	var old string
+	start := strings.Index(issue.Body, open)
+	end := strings.Index(issue.Body, closing)
+	if start >= 0 && end > start {
+		old = issue.Body[start : end+len(closing)]
+	}
+	wasDone := map[string]bool{}
+	for _, line := range strings.Split(old, "\n") {
+		if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- [x] "); ok {
+			wasDone[item] = true
+		}
+	}
+
+	res := &IssueSync{Total: len(tasks)}
+	var items strings.Builder
+	for i := range tasks {
+		item := checklistItem(&tasks[i])
+		box := "[ ]"
+		if tasks[i].Done {
+			box = "[x]"
+			res.Done++
+			if !wasDone[item] {
+				res.Completed = append(res.Completed, item)
+			}
+		}
+		fmt.Fprintf(&items, "- %s %s\n", box, item)
+	}
+	block := fmt.Sprintf("%s\n**Ralph plan for `%s`**: %d of %d tasks done\n\n%s%s", open, branch, res.Done, res.Total, items.String(), closing)
+	if block == old {
+		return res, nil
+	}
+
+	body := strings.TrimRight(issue.Body, "\n") + "\n\n" + block
+	if old != "" {
+		body = issue.Body[:start] + block + issue.Body[end+len(closing):]
+	}
+	if err := c.do(ctx, http.MethodPatch, path, map[string]any{"body": body}, nil); err != nil {
+		return nil, err
+	}
+	res.Updated = true
+
+	if len(res.Completed) > 0 {
+		comment := fmt.Sprintf("Ralph finished on `%s`:\n\n- %s\n\n%d of %d plan tasks done.",
+			branch, strings.Join(res.Completed, "\n- "), res.Done, res.Total)
+		if err := c.do(ctx, http.MethodPost, path+"/comments", map[string]any{"body": comment}, nil); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// checklistItem names a task as the issue lists it, e.g. "T1.2 Add login".
+func checklistItem(t *status.Task) string {
+	if t.ID == "" {
+		return t.Title
+	}
+	return "T" + t.ID + " " + t.Title
+}
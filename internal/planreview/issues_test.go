@@ -0,0 +1,84 @@
+package planreview
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/status"
+)
+
+func issueBody(t *testing.T, body string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{"body": body})
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestSyncIssue_AddsChecklist(t *testing.T) {
+	var reqs []request
+	c := fakeGitHub(t, map[string]string{
+		"GET /repos/o/r/issues/7":           issueBody(t, "Users need to log in.\n"),
+		"PATCH /repos/o/r/issues/7":         `{}`,
+		"POST /repos/o/r/issues/7/comments": `{}`,
+	}, &reqs)
+
+	res, err := c.SyncIssue(context.Background(), 7, "feat/login", []status.Task{
+		{ID: "1.1", Title: "Scaffold", Done: true},
+		{ID: "1.2", Title: "Login form"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &IssueSync{Done: 1, Total: 2, Completed: []string{"T1.1 Scaffold"}, Updated: true}, res)
+
+	require.Len(t, reqs, 3)
+	assert.Equal(t, "Users need to log in.\n\n"+
+		"<!-- ralph:plan feat/login -->\n"+
+		"**Ralph plan for `feat/login`**: 1 of 2 tasks done\n\n"+
+		"- [x] T1.1 Scaffold\n"+
+		"- [ ] T1.2 Login form\n"+
+		"<!-- /ralph:plan feat/login -->", reqs[1].body["body"])
+	assert.Equal(t, "Ralph finished on `feat/login`:\n\n- T1.1 Scaffold\n\n1 of 2 plan tasks done.", reqs[2].body["body"])
+}
+
+func TestSyncIssue_UpdatesChecklistInPlace(t *testing.T) {
+	existing := "Intro\n\n<!-- ralph:plan feat/login -->\n**Ralph plan for `feat/login`**: 1 of 2 tasks done\n\n" +
+		"- [x] T1.1 Scaffold\n- [ ] T1.2 Login form\n<!-- /ralph:plan feat/login -->\n\nFooter"
+	var reqs []request
+	c := fakeGitHub(t, map[string]string{
+		"GET /repos/o/r/issues/7":           issueBody(t, existing),
+		"PATCH /repos/o/r/issues/7":         `{}`,
+		"POST /repos/o/r/issues/7/comments": `{}`,
+	}, &reqs)
+
+	res, err := c.SyncIssue(context.Background(), 7, "feat/login", []status.Task{
+		{ID: "1.1", Title: "Scaffold", Done: true},
+		{ID: "1.2", Title: "Login form", Done: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"T1.2 Login form"}, res.Completed, "only tasks not already ticked are announced")
+
+	require.Len(t, reqs, 3)
+	body, ok := reqs[1].body["body"].(string)
+	require.True(t, ok)
+	assert.Contains(t, body, "- [x] T1.2 Login form")
+	assert.Contains(t, body, "2 of 2 tasks done")
+	assert.True(t, strings.HasPrefix(body, "Intro\n\n<!-- ralph:plan"), "the rest of the body is kept")
+	assert.True(t, strings.HasSuffix(body, "feat/login -->\n\nFooter"), "the rest of the body is kept")
+}
+
+func TestSyncIssue_Unchanged(t *testing.T) {
+	existing := "<!-- ralph:plan b -->\n**Ralph plan for `b`**: 0 of 1 tasks done\n\n- [ ] Task\n<!-- /ralph:plan b -->"
+	var reqs []request
+	c := fakeGitHub(t, map[string]string{"GET /repos/o/r/issues/3": issueBody(t, existing)}, &reqs)
+
+	res, err := c.SyncIssue(context.Background(), 3, "b", []status.Task{{Title: "Task"}})
+	require.NoError(t, err)
+	assert.False(t, res.Updated)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, http.MethodGet, reqs[0].method)
+}
@@ -0,0 +1,207 @@
+// Package planreview puts a generated plan up for review as a GitHub pull
+// request and checks whether a reviewer has approved it, so a build only
+// starts from a plan a human has signed off.
+package planreview
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the GitHub REST API root.
+const DefaultBaseURL = "https://api.github.com"
+
+// maxBody keeps a plan posted to GitHub under its 65536-character limit on
+// pull request bodies and comments.
+const maxBody = 60_000
+
+// ErrNoPR is returned when the branch has no open pull request to review.
+var ErrNoPR = errors.New("no open pull request")
+
+// Client talks to the GitHub REST API.
+type Client struct {
+	Repo    string // "owner/repo"
+	Token   string
+	BaseURL string       // empty = DefaultBaseURL
+	HTTP    *http.Client // nil = a client with a short timeout
+}
+
+// New returns a client for repo, or an error when there is no token.
+func New(repo, token string) (*Client, error) {
+	if token == "" {
+		return nil, fmt.Errorf("GITHUB_PAT is not set")
+	}
+	if !strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("invalid GitHub repo %q (want owner/repo)", repo)
+	}
+	return &Client{Repo: repo, Token: token}, nil
+}
+
+// PR is a pull request.
+type PR struct {
+	Number int    `json:"number"`
+	URL    string `json:"html_url"`
+	Draft  bool   `json:"draft"`
+}
+
+// Request puts plan up for review on branch's pull request: a new draft pull
+// request against the repo's default branch, or a comment on the one already
+// open. created reports which.
+func (c *Client) Request(ctx context.Context, branch, plan string) (pr *PR, created bool, err error) {
+	existing, err := c.openPR(ctx, branch)
+	if err != nil && !errors.Is(err, ErrNoPR) {
+		return nil, false, err
+	}
+	if existing != nil {
+		body := "Ralph updated the plan. Approve this pull request to let `ralph build` start.\n\n" + clip(plan)
+		if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", c.Repo, existing.Number), map[string]any{"body": body}, nil); err != nil {
+			return nil, false, err
+		}
+		return existing, false, nil
+	}
+
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/repos/"+c.Repo, nil, &repo); err != nil {
+		return nil, false, err
+	}
+	body := "Ralph generated this plan for `" + branch + "`. Approve this pull request to let `ralph build` start.\n\n" + clip(plan)
+	pr = &PR{}
+	if err := c.do(ctx, http.MethodPost, "/repos/"+c.Repo+"/pulls", map[string]any{
+		"title": "Plan: " + branch,
+		"head":  branch,
+		"base":  repo.DefaultBranch,
+		"body":  body,
+		"draft": true,
+	}, pr); err != nil {
+		return nil, false, err
+	}
+	return pr, true, nil
+}
+
+// Status is the review state of branch's pull request.
+type Status struct {
+	PR               PR
+	Approvers        []string // reviewers whose latest review approves
+	ChangesRequested []string // reviewers whose latest review requests changes
+}
+
+// Approved reports whether someone approved and nobody requests changes.
+func (s *Status) Approved() bool {
+	return len(s.Approvers) > 0 && len(s.ChangesRequested) == 0
+}
+
+// Check returns the review state of branch's open pull request, or ErrNoPR.
+// Only each reviewer's latest approving or change-requesting review counts;
+// comments and dismissed reviews are ignored.
+func (c *Client) Check(ctx context.Context, branch string) (*Status, error) {
+	pr, err := c.openPR(ctx, branch)
+	if err != nil {
+		return nil, err
+	}
+	var reviews []struct {
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		State string `json:"state"`
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/pulls/%d/reviews?per_page=100", c.Repo, pr.Number), nil, &reviews); err != nil {
+		return nil, err
+	}
+	latest := map[string]string{}
+	for _, r := range reviews { // oldest first
+		switch r.State {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			latest[r.User.Login] = r.State
+		}
+	}
+	st := &Status{PR: *pr}
+	for login, state := range latest {
+		switch state {
+		case "APPROVED":
+			st.Approvers = append(st.Approvers, login)
+		case "CHANGES_REQUESTED":
+			st.ChangesRequested = append(st.ChangesRequested, login)
+		}
+	}
+	sort.Strings(st.Approvers)
+	sort.Strings(st.ChangesRequested)
+	return st, nil
+}
+
+// openPR returns branch's open pull request, or ErrNoPR.
+func (c *Client) openPR(ctx context.Context, branch string) (*PR, error) {
+	owner, _, _ := strings.Cut(c.Repo, "/")
+	var prs []PR
+	path := "/repos/" + c.Repo + "/pulls?state=open&head=" + url.QueryEscape(owner+":"+branch)
+	if err := c.do(ctx, http.MethodGet, path, nil, &prs); err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoPR, branch)
+	}
+	return &prs[0], nil
+}
+
+// clip shortens plan to fit a pull request body.
+func clip(plan string) string {
+	if len(plan) <= maxBody {
+		return plan
+	}
+	return plan[:maxBody] + "\n\n… (truncated; see the plan file on the branch)"
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body map[string]any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, base+path, reader)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling GitHub: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GitHub %s %s: %s", method, strings.SplitN(path, "?", 2)[0], resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(out); err != nil {
+		return fmt.Errorf("decoding GitHub response: %w", err)
+	}
+	return nil
+}
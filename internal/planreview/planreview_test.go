@@ -0,0 +1,148 @@
+package planreview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	method, path, query string
+	body                map[string]any
+}
+
+// fakeGitHub answers each "METHOD /path" from replies and records requests.
+func fakeGitHub(t *testing.T, replies map[string]string, reqs *[]request) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer ghp-test", r.Header.Get("Authorization"))
+		var body map[string]any
+		if r.Body != http.NoBody {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+		}
+		*reqs = append(*reqs, request{r.Method, r.URL.Path, r.URL.RawQuery, body})
+		reply, ok := replies[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(reply))
+	}))
+	t.Cleanup(srv.Close)
+	return &Client{Repo: "o/r", Token: "ghp-test", BaseURL: srv.URL}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("o/r", "")
+	require.Error(t, err)
+	_, err = New("nope", "tok")
+	require.Error(t, err)
+	c, err := New("o/r", "tok")
+	require.NoError(t, err)
+	assert.Equal(t, "o/r", c.Repo)
+}
+
+func TestRequest_CreatesDraftPR(t *testing.T) {
+	var reqs []request
+	c := fakeGitHub(t, map[string]string{
+		"GET /repos/o/r/pulls":  `[]`,
+		"GET /repos/o/r":        `{"default_branch": "main"}`,
+		"POST /repos/o/r/pulls": `{"number": 12, "html_url": "https://github.com/o/r/pull/12", "draft": true}`,
+	}, &reqs)
+
+	pr, created, err := c.Request(context.Background(), "feat/x", "# Plan\n- [ ] task")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 12, pr.Number)
+	assert.Equal(t, "https://github.com/o/r/pull/12", pr.URL)
+
+	require.Len(t, reqs, 3)
+	assert.Equal(t, "head=o%3Afeat%2Fx&state=open", sortedQuery(reqs[0].query))
+	create := reqs[2].body
+	assert.Equal(t, "feat/x", create["head"])
+	assert.Equal(t, "main", create["base"])
+	assert.Equal(t, true, create["draft"])
+	assert.Contains(t, create["body"], "- [ ] task")
+}
+
+func TestRequest_CommentsOnOpenPR(t *testing.T) {
+	var reqs []request
+	c := fakeGitHub(t, map[string]string{
+		"GET /repos/o/r/pulls":              `[{"number": 5, "html_url": "https://github.com/o/r/pull/5"}]`,
+		"POST /repos/o/r/issues/5/comments": `{}`,
+	}, &reqs)
+
+	pr, created, err := c.Request(context.Background(), "feat/x", "new plan")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 5, pr.Number)
+	require.Len(t, reqs, 2)
+	assert.Contains(t, reqs[1].body["body"], "new plan")
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		reviews  string
+		approved bool
+		want     []string
+	}{
+		{"no reviews", `[]`, false, nil},
+		{"approved", `[{"user":{"login":"ann"},"state":"APPROVED"}]`, true, []string{"ann"}},
+		{"comment after approval still counts", `[{"user":{"login":"ann"},"state":"APPROVED"},{"user":{"login":"ann"},"state":"COMMENTED"}]`, true, []string{"ann"}},
+		{"changes requested blocks", `[{"user":{"login":"ann"},"state":"APPROVED"},{"user":{"login":"bob"},"state":"CHANGES_REQUESTED"}]`, false, []string{"ann"}},
+		{"later approval supersedes", `[{"user":{"login":"bob"},"state":"CHANGES_REQUESTED"},{"user":{"login":"bob"},"state":"APPROVED"}]`, true, []string{"bob"}},
+		{"dismissed approval", `[{"user":{"login":"ann"},"state":"APPROVED"},{"user":{"login":"ann"},"state":"DISMISSED"}]`, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reqs []request
+			c := fakeGitHub(t, map[string]string{
+				"GET /repos/o/r/pulls":           `[{"number": 5, "html_url": "u"}]`,
+				"GET /repos/o/r/pulls/5/reviews": tt.reviews,
+			}, &reqs)
+			st, err := c.Check(context.Background(), "feat/x")
+			require.NoError(t, err)
+			assert.Equal(t, 5, st.PR.Number)
+			assert.Equal(t, tt.approved, st.Approved())
+			assert.Equal(t, tt.want, st.Approvers)
+		})
+	}
+}
+
+func TestCheck_NoPR(t *testing.T) {
+	var reqs []request
+	c := fakeGitHub(t, map[string]string{"GET /repos/o/r/pulls": `[]`}, &reqs)
+	_, err := c.Check(context.Background(), "feat/x")
+	assert.True(t, errors.Is(err, ErrNoPR))
+}
+
+func TestCheck_APIError(t *testing.T) {
+	var reqs []request
+	c := fakeGitHub(t, map[string]string{}, &reqs)
+	_, err := c.Check(context.Background(), "feat/x")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrNoPR))
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestClip(t *testing.T) {
+	assert.Equal(t, "short", clip("short"))
+	got := clip(strings.Repeat("x", maxBody+10))
+	assert.Less(t, len(got), maxBody+100)
+	assert.Contains(t, got, "truncated")
+}
+
+// sortedQuery normalises a raw query so assertions don't depend on order.
+func sortedQuery(raw string) string {
+	q, _ := url.ParseQuery(raw)
+	return q.Encode()
+}
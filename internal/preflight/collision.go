@@ -0,0 +1,139 @@
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/status"
+)
+
+// Collision is existing content at a path ralph is about to commit and let
+// the agent write to.
+type Collision struct {
+	Path   string // repo-relative
+	Reason string
+}
+
+func (c Collision) String() string {
+	return c.Path + ": " + c.Reason
+}
+
+// CheckCollisions looks for content ralph didn't create at the specs
+// directory and plan file it will use: files other than markdown specs in
+// specsDir, or a planFile that isn't a ralph plan. Paths under .ralph/, and
+// paths an earlier run recorded in state.json, are ralph's own and never
+// collide. Both paths are relative to repoRoot.
+func CheckCollisions(repoRoot, specsDir, planFile string) ([]Collision, error) {
+	st, err := state.Load(filepath.Join(repoRoot, state.DefaultPath))
+	if err != nil {
+		return nil, fmt.Errorf("preflight: %w", err)
+	}
+	usedSpecs, usedPlan := false, false
+	for _, r := range st.Runs {
+		usedSpecs = usedSpecs || filepath.Clean(r.SpecsDir) == filepath.Clean(specsDir)
+		usedPlan = usedPlan || filepath.Clean(r.PlanFile) == filepath.Clean(planFile)
+	}
+
+	slog.Debug("preflight: collisions", "specs", specsDir, "specs_used_before", usedSpecs, "plan", planFile, "plan_used_before", usedPlan)
+	var out []Collision
+	if !usedSpecs && !isRalphPath(specsDir) {
+		c, err := specsCollision(repoRoot, specsDir)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c...)
+	}
+	if !usedPlan && !isRalphPath(planFile) {
+		c, err := planCollision(repoRoot, planFile)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c...)
+	}
+	return out, nil
+}
+
+// specsCollision reports files in specsDir that aren't markdown specs. Nested
+// repos are skipped, as Prepare skips them.
+func specsCollision(repoRoot, specsDir string) ([]Collision, error) {
+	root, err := filepath.EvalSymlinks(filepath.Join(repoRoot, specsDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("preflight: checking %s: %w", specsDir, err)
+	}
+	var other []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == ".gitkeep" || strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path) //nolint:errcheck // path is under root
+		other = append(other, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("preflight: checking %s: %w", specsDir, err)
+	}
+	if len(other) == 0 {
+		return nil, nil
+	}
+	return []Collision{{
+		Path:   specsDir,
+		Reason: fmt.Sprintf("holds %s that %s not specs (%s)", countFiles(len(other)), areOrIs(len(other)), listFiles(other)),
+	}}, nil
+}
+
+// planCollision reports a planFile that is a directory, or a non-empty file
+// without a single "### Task" heading, which ralph's plans always have.
+func planCollision(repoRoot, planFile string) ([]Collision, error) {
+	path := filepath.Join(repoRoot, planFile)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("preflight: checking %s: %w", planFile, err)
+	}
+	if info.IsDir() {
+		return []Collision{{Path: planFile, Reason: "is a directory"}}, nil
+	}
+	if info.Size() == 0 {
+		return nil, nil // an empty file is safe to write
+	}
+	tasks, err := status.ParsePlan(path)
+	if err != nil {
+		return nil, fmt.Errorf("preflight: %w", err)
+	}
+	if len(tasks) > 0 {
+		return nil, nil // a ralph plan
+	}
+	return []Collision{{Path: planFile, Reason: "already exists and isn't a ralph plan (no \"### Task\" headings)"}}, nil
+}
+
+// isRalphPath reports whether the repo-relative path is under .ralph/.
+func isRalphPath(path string) bool {
+	return strings.HasPrefix(filepath.ToSlash(filepath.Clean(path)), ".ralph/")
+}
+
+func areOrIs(n int) string {
+	if n == 1 {
+		return "is"
+	}
+	return "are"
+}
@@ -0,0 +1,95 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestCheckCollisions_NothingThere(t *testing.T) {
+	t.Parallel()
+	got, err := CheckCollisions(t.TempDir(), "specs/feat", "docs/plans/feat.md")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestCheckCollisions_SpecsOnly(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "specs/feat/auth.md"), "# Auth\n")
+	writeFile(t, filepath.Join(root, "specs/feat/.gitkeep"), "")
+	writeFile(t, filepath.Join(root, "specs/feat/vendor/.git/HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(root, "specs/feat/vendor/main.go"), "package vendor\n")
+
+	got, err := CheckCollisions(root, "specs/feat", "docs/plans/feat.md")
+	require.NoError(t, err)
+	assert.Empty(t, got, "markdown, .gitkeep and nested repos are fine")
+}
+
+func TestCheckCollisions_UnrelatedSpecsContent(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "specs/feat/auth.md"), "# Auth\n")
+	writeFile(t, filepath.Join(root, "specs/feat/fixtures/users.json"), "[]\n")
+
+	got, err := CheckCollisions(root, "specs/feat", "docs/plans/feat.md")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "specs/feat: holds 1 file that is not specs (fixtures/users.json)", got[0].String())
+}
+
+func TestCheckCollisions_Plan(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "docs/notes.md"), "# Meeting notes\n\n- [ ] book a room\n")
+	writeFile(t, filepath.Join(root, "docs/plan.md"), "# Plan\n\n### Task 1.1: Scaffold\n- [ ] **Status:** Incomplete\n")
+	writeFile(t, filepath.Join(root, "docs/empty.md"), "")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs/dir.md"), 0o750))
+
+	for plan, want := range map[string]string{
+		"docs/notes.md": "isn't a ralph plan",
+		"docs/dir.md":   "is a directory",
+		"docs/plan.md":  "",
+		"docs/empty.md": "",
+	} {
+		got, err := CheckCollisions(root, "specs", plan)
+		require.NoError(t, err)
+		if want == "" {
+			assert.Empty(t, got, plan)
+			continue
+		}
+		require.Len(t, got, 1, plan)
+		assert.Equal(t, plan, got[0].Path)
+		assert.Contains(t, got[0].Reason, want)
+	}
+}
+
+func TestCheckCollisions_KnownPaths(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "specs/feat/schema.sql"), "create table t;\n")
+	writeFile(t, filepath.Join(root, "docs/plan.md"), "# Plan\n")
+	writeFile(t, filepath.Join(root, ".ralph/plans/feat.md"), "# Plan\n")
+
+	got, err := CheckCollisions(root, "specs/other", ".ralph/plans/feat.md")
+	require.NoError(t, err)
+	assert.Empty(t, got, ".ralph/ is ralph's own")
+
+	require.NoError(t, state.Save(filepath.Join(root, state.DefaultPath), &state.State{
+		Runs: []state.RunRecord{{SpecsDir: "specs/feat", PlanFile: "docs/plan.md"}},
+	}))
+	got, err = CheckCollisions(root, "specs/feat", "docs/plan.md")
+	require.NoError(t, err)
+	assert.Empty(t, got, "paths an earlier run used are ralph's")
+}
@@ -0,0 +1,130 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/scaffold"
+	"github.com/benwilkes9/ralph-cli/internal/vcs"
+)
+
+// wipMessage is the commit message for uncommitted changes committed under
+// git.dirty_tree: commit.
+const wipMessage = "wip: uncommitted changes before ralph run"
+
+// stashMessage labels the changes set aside under git.dirty_tree: stash,
+// so they can be found in `git stash list` if restoring them fails.
+const stashMessage = "ralph: uncommitted changes set aside for the run"
+
+// dirtyListMax is how many dirty files messages name before "and N more".
+const dirtyListMax = 5
+
+// Stash is uncommitted work CheckDirtyTree set aside for the run.
+type Stash struct {
+	v     vcs.VCS
+	Files []string
+}
+
+// Restore puts the stashed changes back in the working tree. It does
+// nothing when nothing was stashed.
+func (s Stash) Restore(ctx context.Context) error {
+	if len(s.Files) == 0 {
+		return nil
+	}
+	if err := s.v.Unstash(ctx); err != nil {
+		return fmt.Errorf("restoring %s set aside before the run (still stashed as %q): %w",
+			countFiles(len(s.Files)), stashMessage, err)
+	}
+	return nil
+}
+
+// CheckDirtyTree applies policy, one of the config.DirtyTree settings, to
+// uncommitted changes outside the scaffold paths Prepare commits. The
+// workspace is mounted into the container, so without it those changes
+// would silently be part of the agent's starting point. It runs before
+// Prepare, reports what it does on w, and the returned Stash must be
+// restored once the run ends.
+func CheckDirtyTree(ctx context.Context, w io.Writer, v vcs.VCS, policy, specsDir, planFile string) (Stash, error) {
+	files, err := v.DirtyFiles(ctx)
+	if err != nil {
+		return Stash{}, fmt.Errorf("preflight: checking for uncommitted changes: %w", err)
+	}
+	files = slices.DeleteFunc(files, func(f string) bool {
+		return isScaffoldPath(f, specsDir, planFile)
+	})
+	slog.DebugContext(ctx, "preflight: dirty tree", "policy", policy, "files", files)
+	if len(files) == 0 {
+		return Stash{}, nil
+	}
+
+	switch policy {
+	case config.DirtyTreeFail:
+		return Stash{}, fmt.Errorf("preflight: %s uncommitted (%s); commit or stash them, or set git.dirty_tree to stash or commit",
+			countFiles(len(files)), listFiles(files))
+	case config.DirtyTreeStash:
+		fmt.Fprintf(w, "Stashing %s until the run ends...\n", countFiles(len(files))) //nolint:errcheck // display-only
+		if err := v.Stash(ctx, stashMessage, files...); err != nil {
+			return Stash{}, fmt.Errorf("preflight: stashing uncommitted changes: %w", err)
+		}
+		return Stash{v: v, Files: files}, nil
+	case config.DirtyTreeCommit:
+		secrets := slices.DeleteFunc(slices.Clone(files), func(f string) bool { return !IsSecretFile(f) })
+		files = slices.DeleteFunc(files, IsSecretFile)
+		if len(secrets) > 0 {
+			fmt.Fprintf(w, "Not committing %s that may hold credentials: %s\n", countFiles(len(secrets)), listFiles(secrets)) //nolint:errcheck // display-only
+		}
+		if len(files) == 0 {
+			return Stash{}, nil
+		}
+		fmt.Fprintf(w, "Committing %s as work in progress...\n", countFiles(len(files))) //nolint:errcheck // display-only
+		if err := v.CommitPaths(ctx, wipMessage, files...); err != nil {
+			return Stash{}, fmt.Errorf("preflight: committing uncommitted changes: %w", err)
+		}
+	default:
+		fmt.Fprintf(w, "Warning: %s uncommitted, and visible to the agent: %s\n", countFiles(len(files)), listFiles(files)) //nolint:errcheck // display-only
+	}
+	return Stash{}, nil
+}
+
+// isScaffoldPath reports whether the repo-relative path f is one Prepare
+// commits itself: under .ralph/, the specs or plans directory, or a root
+// file written by ralph init.
+func isScaffoldPath(f, specsDir, planFile string) bool {
+	f = filepath.ToSlash(f)
+	if slices.Contains(scaffold.RootFiles(), f) {
+		return true
+	}
+	planDir := filepath.Dir(planFile)
+	if planDir == "." {
+		planDir = planFile
+	}
+	for _, dir := range []string{".ralph", specsDir, planDir} {
+		dir = strings.TrimSuffix(filepath.ToSlash(dir), "/")
+		if dir != "" && (f == dir || strings.HasPrefix(f, dir+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// countFiles is "1 file" or "n files".
+func countFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// listFiles joins the first dirtyListMax files, noting how many more there are.
+func listFiles(files []string) string {
+	if len(files) <= dirtyListMax {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:dirtyListMax], ", "), len(files)-dirtyListMax)
+}
@@ -0,0 +1,114 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/testutil"
+)
+
+const testPlanFile = ".ralph/plans/IMPLEMENTATION_PLAN_main.md"
+
+// dirtyClone returns a clone with scaffold files, a modified tracked file
+// and an untracked one.
+func dirtyClone(t *testing.T) string {
+	t.Helper()
+	_, clone := testutil.InitBareAndClone(t)
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "main.go"), []byte("package main\n"), 0o600))
+	testutil.RunGit(t, clone, "add", "main.go")
+	testutil.RunGit(t, clone, "commit", "-m", "add main")
+
+	writeScaffold(t, clone)
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "main.go"), []byte("package main // edited\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(clone, "notes.txt"), []byte("todo"), 0o600))
+	return clone
+}
+
+func TestCheckDirtyTree_CleanIgnoresScaffold(t *testing.T) {
+	t.Parallel()
+	_, clone := testutil.InitBareAndClone(t)
+	writeScaffold(t, clone)
+
+	stash, err := CheckDirtyTree(context.Background(), io.Discard, openVCS(t, clone), config.DirtyTreeFail, "specs", testPlanFile)
+	require.NoError(t, err)
+	assert.Empty(t, stash.Files)
+}
+
+func TestCheckDirtyTree_Fail(t *testing.T) {
+	t.Parallel()
+	clone := dirtyClone(t)
+
+	_, err := CheckDirtyTree(context.Background(), io.Discard, openVCS(t, clone), config.DirtyTreeFail, "specs", testPlanFile)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "2 files uncommitted (main.go, notes.txt)")
+	assert.ErrorContains(t, err, "git.dirty_tree")
+}
+
+func TestCheckDirtyTree_Warn(t *testing.T) {
+	t.Parallel()
+	clone := dirtyClone(t)
+
+	var out bytes.Buffer
+	stash, err := CheckDirtyTree(context.Background(), &out, openVCS(t, clone), config.DirtyTreeWarn, "specs", testPlanFile)
+	require.NoError(t, err)
+	assert.Empty(t, stash.Files)
+	assert.Contains(t, out.String(), "Warning: 2 files uncommitted")
+	assert.Equal(t, "main.go", gitDiff(t, clone, "--name-only"))
+}
+
+func TestCheckDirtyTree_StashAndRestore(t *testing.T) {
+	t.Parallel()
+	clone := dirtyClone(t)
+	v := openVCS(t, clone)
+
+	stash, err := CheckDirtyTree(context.Background(), io.Discard, v, config.DirtyTreeStash, "specs", testPlanFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go", "notes.txt"}, stash.Files)
+	assert.Empty(t, gitDiff(t, clone, "--name-only"))
+	assert.NoFileExists(t, filepath.Join(clone, "notes.txt"))
+	assert.FileExists(t, filepath.Join(clone, "AGENTS.md"), "scaffold files stay for Prepare to commit")
+
+	require.NoError(t, Prepare(context.Background(), io.Discard, v, "specs", testPlanFile))
+	require.NoError(t, stash.Restore(context.Background()))
+	assert.Equal(t, "main.go", gitDiff(t, clone, "--name-only"))
+	assert.FileExists(t, filepath.Join(clone, "notes.txt"))
+}
+
+func TestCheckDirtyTree_Commit(t *testing.T) {
+	t.Parallel()
+	clone := dirtyClone(t)
+	require.NoError(t, os.WriteFile(filepath.Join(clone, ".env"), []byte("KEY=x"), 0o600))
+
+	_, err := CheckDirtyTree(context.Background(), io.Discard, openVCS(t, clone), config.DirtyTreeCommit, "specs", testPlanFile)
+	require.NoError(t, err)
+	assert.Contains(t, gitLog(t, clone), wipMessage)
+
+	show := gitShow(t, clone, "HEAD", "--name-only", "--format=")
+	assert.Contains(t, show, "main.go")
+	assert.Contains(t, show, "notes.txt")
+	assert.NotContains(t, show, ".env")
+	assert.NotContains(t, show, "AGENTS.md")
+}
+
+func TestIsScaffoldPath(t *testing.T) {
+	for path, want := range map[string]bool{
+		".ralph/config.yaml":     true,
+		"AGENTS.md":              true,
+		"specs/auth.md":          true,
+		"plans/PLAN.md":          true,
+		"specs-old/auth.md":      false,
+		"src/main.go":            false,
+		"IMPLEMENTATION_PLAN.md": false,
+	} {
+		assert.Equal(t, want, isScaffoldPath(path, "specs", "plans/PLAN.md"), path)
+	}
+	assert.True(t, isScaffoldPath("IMPLEMENTATION_PLAN.md", "specs", "IMPLEMENTATION_PLAN.md"))
+}
@@ -1,18 +1,32 @@
 package preflight
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 
 	"github.com/benwilkes9/ralph-cli/internal/git"
 	"github.com/benwilkes9/ralph-cli/internal/scaffold"
+	"github.com/benwilkes9/ralph-cli/internal/vcs"
 )
 
 // CheckAdditionalDirs validates that each additional directory exists, is a git
 // repo, and is on the expected branch. If a repo's branch is not pushed to the
-// remote, it will be pushed automatically.
-func CheckAdditionalDirs(branch string, dirs []string) error {
+// remote, it will be pushed automatically, reporting the push on w.
+func CheckAdditionalDirs(ctx context.Context, w io.Writer, branch string, dirs []string) error {
+	return checkAdditionalDirs(ctx, w, branch, dirs, true)
+}
+
+// PrepareAdditionalDirs is CheckAdditionalDirs without touching the remote,
+// for offline runs.
+func PrepareAdditionalDirs(ctx context.Context, w io.Writer, branch string, dirs []string) error {
+	return checkAdditionalDirs(ctx, w, branch, dirs, false)
+}
+
+func checkAdditionalDirs(ctx context.Context, w io.Writer, branch string, dirs []string, publish bool) error {
 	for _, dir := range dirs {
 		base := filepath.Base(dir)
 
@@ -22,25 +36,28 @@ func CheckAdditionalDirs(branch string, dirs []string) error {
 			return fmt.Errorf("preflight: checking additional directory %q: %w", dir, err)
 		}
 
-		if !git.IsGitRepo(dir) {
+		if !git.IsGitRepo(ctx, dir) {
 			return fmt.Errorf("preflight: %q is not a git repository", dir)
 		}
 
-		dirBranch, err := git.BranchIn(dir)
+		dirBranch, err := git.BranchIn(ctx, dir)
 		if err != nil {
 			return fmt.Errorf("preflight: getting branch for %q: %w", base, err)
 		}
 		if dirBranch != branch {
 			return fmt.Errorf("preflight: repo %q is on branch %q, expected %q", base, dirBranch, branch)
 		}
+		if !publish {
+			continue
+		}
 
-		exists, err := git.BranchExistsOnRemoteIn(dir, branch)
+		exists, err := git.BranchExistsOnRemoteIn(ctx, dir, branch)
 		if err != nil {
 			return fmt.Errorf("preflight: checking remote branch for %q: %w", base, err)
 		}
 		if !exists {
-			fmt.Printf("Pushing branch %q to origin for %s...\n", branch, base)
-			if err := git.PushSetUpstreamIn(dir, branch); err != nil {
+			fmt.Fprintf(w, "Pushing branch %q to origin for %s...\n", branch, base) //nolint:errcheck // display-only
+			if err := git.PushSetUpstreamIn(ctx, dir, branch); err != nil {
 				return fmt.Errorf("preflight: git push -u origin %s in %q: %w", branch, base, err)
 			}
 		}
@@ -51,11 +68,19 @@ func CheckAdditionalDirs(branch string, dirs []string) error {
 // Check runs pre-flight validation before launching Docker. It verifies that
 // .ralph/ scaffold files exist on disk, auto-commits them if needed, ensures
 // the specs and plans directories are tracked, and pushes the branch to the remote.
-func Check(branch, specsDir, planFile string) error {
-	repoRoot, err := git.RepoRoot()
-	if err != nil {
-		return fmt.Errorf("preflight: finding repo root: %w", err)
+// v is the repository's version control backend; what it does is reported
+// on w.
+func Check(ctx context.Context, w io.Writer, v vcs.VCS, branch, specsDir, planFile string) error {
+	if err := Prepare(ctx, w, v, specsDir, planFile); err != nil {
+		return err
 	}
+	return Publish(ctx, w, v, branch)
+}
+
+// Prepare is the local part of Check: everything but pushing the branch,
+// for offline runs.
+func Prepare(ctx context.Context, w io.Writer, v vcs.VCS, specsDir, planFile string) error {
+	repoRoot := v.Root()
 
 	configPath := filepath.Join(repoRoot, ".ralph", "config.yaml")
 
@@ -71,13 +96,14 @@ func Check(branch, specsDir, planFile string) error {
 	//    append), specs dir, and plans dir.
 
 	// .ralph/ directory — only add if not yet tracked.
-	ralphTracked, err := git.IsTracked(".ralph/config.yaml")
+	ralphTracked, err := v.IsTracked(ctx, ".ralph/config.yaml")
 	if err != nil {
-		return fmt.Errorf("preflight: checking git tracking: %w", err)
+		return fmt.Errorf("preflight: checking tracking: %w", err)
 	}
+	slog.DebugContext(ctx, "preflight: scaffold", "ralph_tracked", ralphTracked, "specs", specsDir, "plan", planFile)
 	if !ralphTracked {
-		if err := git.Add(".ralph/"); err != nil {
-			return fmt.Errorf("preflight: git add .ralph/: %w", err)
+		if err := addDir(ctx, w, v, ".ralph"); err != nil {
+			return fmt.Errorf("preflight: adding .ralph/: %w", err)
 		}
 	}
 
@@ -88,8 +114,8 @@ func Check(branch, specsDir, planFile string) error {
 		if _, statErr := os.Stat(filepath.Join(repoRoot, f)); statErr != nil {
 			continue
 		}
-		if err := git.Add(f); err != nil {
-			return fmt.Errorf("preflight: git add %s: %w", f, err)
+		if err := v.Add(ctx, f); err != nil {
+			return fmt.Errorf("preflight: adding %s: %w", f, err)
 		}
 	}
 
@@ -99,40 +125,77 @@ func Check(branch, specsDir, planFile string) error {
 		if _, statErr := os.Stat(dirPath); os.IsNotExist(statErr) {
 			continue
 		}
-		dirTracked, trackErr := git.IsTracked(filepath.Join(dir, ".gitkeep"))
+		marker := filepath.Join(dir, ".gitkeep")
+		if isSymlink(dirPath) {
+			marker = dir // a linked dir (ralph plan --inherit-specs link) is tracked as the link
+		}
+		dirTracked, trackErr := v.IsTracked(ctx, marker)
 		if trackErr != nil {
-			return fmt.Errorf("preflight: checking git tracking for %s: %w", dir, trackErr)
+			return fmt.Errorf("preflight: checking tracking for %s: %w", dir, trackErr)
 		}
+		slog.DebugContext(ctx, "preflight: directory", "dir", dir, "marker", marker, "tracked", dirTracked)
 		if !dirTracked {
-			if err := git.Add(dir + "/"); err != nil {
-				return fmt.Errorf("preflight: git add %s/: %w", dir, err)
+			if err := addDir(ctx, w, v, dir); err != nil {
+				return fmt.Errorf("preflight: adding %s/: %w", dir, err)
 			}
 		}
 	}
 
 	// Commit only if there are actually staged changes.
-	hasChanges, err := git.HasStagedChanges()
+	hasChanges, err := v.HasStagedChanges(ctx)
 	if err != nil {
 		return fmt.Errorf("preflight: checking staged changes: %w", err)
 	}
+	slog.DebugContext(ctx, "preflight: scaffold commit", "staged", hasChanges)
 	if hasChanges {
-		fmt.Println("Committing scaffold files...")
-		if err := git.Commit("chore: scaffold ralph"); err != nil {
+		fmt.Fprintln(w, "Committing scaffold files...") //nolint:errcheck // display-only
+		if err := v.Commit(ctx, "chore: scaffold ralph"); err != nil {
 			return fmt.Errorf("preflight: %w", err)
 		}
 	}
 
-	// 3. Push branch to remote if it doesn't exist there yet.
-	exists, err := git.BranchExistsOnRemote(branch)
+	return nil
+}
+
+// Publish pushes branch to the remote if it doesn't exist there yet.
+func Publish(ctx context.Context, w io.Writer, v vcs.VCS, branch string) error {
+	exists, err := v.BranchExistsOnRemote(ctx, branch)
 	if err != nil {
 		return fmt.Errorf("preflight: checking remote branch: %w", err)
 	}
+	slog.DebugContext(ctx, "preflight: publish", "branch", branch, "on_remote", exists)
 	if !exists {
-		fmt.Printf("Pushing branch %q to origin...\n", branch)
-		if err := git.PushSetUpstream(branch); err != nil {
-			return fmt.Errorf("preflight: git push -u origin %s: %w", branch, err)
+		fmt.Fprintf(w, "Pushing branch %q to origin...\n", branch) //nolint:errcheck // display-only
+		if err := v.PushSetUpstream(ctx, branch); err != nil {
+			return fmt.Errorf("preflight: pushing %s to origin: %w", branch, err)
 		}
 	}
 
 	return nil
 }
+
+// addDir stages dir. A symlinked dir is staged as the link itself, since git
+// refuses paths beyond a symlink. Nested git repos inside dir are left out:
+// git would otherwise record them as embedded gitlinks with no submodule
+// entry, which breaks clones of the branch. Other backends add dir as is.
+func addDir(ctx context.Context, w io.Writer, v vcs.VCS, dir string) error {
+	repoRoot := v.Root()
+	if isSymlink(filepath.Join(repoRoot, dir)) || v.Kind() != vcs.KindGit {
+		return v.Add(ctx, dir) //nolint:wrapcheck // caller adds context
+	}
+	nested, err := git.NestedRepos(repoRoot, dir)
+	if err != nil {
+		return err //nolint:wrapcheck // caller adds context
+	}
+	paths := []string{dir + "/"}
+	for _, n := range nested {
+		fmt.Fprintf(w, "Skipping nested git repository %s (add it to .gitignore to silence this)\n", n) //nolint:errcheck // display-only
+		paths = append(paths, ":(exclude)"+n)
+	}
+	return v.Add(ctx, paths...) //nolint:wrapcheck // caller adds context
+}
+
+func isSymlink(path string) bool {
+	fi, err := os.Lstat(path)
+	return err == nil && fi.Mode()&os.ModeSymlink != 0
+}
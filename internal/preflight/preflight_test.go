@@ -2,6 +2,7 @@ package preflight
 
 import (
 	"context"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/benwilkes9/ralph-cli/internal/git"
 	"github.com/benwilkes9/ralph-cli/internal/testutil"
+	"github.com/benwilkes9/ralph-cli/internal/vcs"
 )
 
 func gitLog(t *testing.T, dir string) string {
@@ -50,6 +52,14 @@ func gitDiff(t *testing.T, dir string, args ...string) string {
 	return strings.TrimSpace(string(out))
 }
 
+// openVCS opens the git repo at dir.
+func openVCS(t *testing.T, dir string) vcs.VCS {
+	t.Helper()
+	v, err := vcs.OpenIn(context.Background(), dir, vcs.KindGit)
+	require.NoError(t, err)
+	return v
+}
+
 func writeScaffold(t *testing.T, dir string) {
 	t.Helper()
 	ralphDir := filepath.Join(dir, ".ralph")
@@ -61,20 +71,20 @@ func writeScaffold(t *testing.T, dir string) {
 }
 
 func TestCheck_ConfigMissing(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 
-	err := Check("main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
 	require.Error(t, err)
 	assert.ErrorContains(t, err, `"ralph init"`)
 }
 
 func TestCheck_AutoCommitsUntracked(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 	writeScaffold(t, clone)
 
-	err := Check("main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
 	require.NoError(t, err)
 
 	// Verify all scaffold files were committed in a single commit.
@@ -89,8 +99,8 @@ func TestCheck_AutoCommitsUntracked(t *testing.T) {
 }
 
 func TestCheck_AutoCommitsRootFilesWhenRalphAlreadyTracked(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 	writeScaffold(t, clone)
 
 	// Simulate a previous run that only committed .ralph/ but missed root files.
@@ -98,7 +108,7 @@ func TestCheck_AutoCommitsRootFilesWhenRalphAlreadyTracked(t *testing.T) {
 	testutil.RunGit(t, clone, "commit", "-m", "add scaffold (partial)")
 	testutil.RunGit(t, clone, "push", "origin", "main")
 
-	err := Check("main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
 	require.NoError(t, err)
 
 	// Verify root-level files were committed in a follow-up scaffold commit.
@@ -111,8 +121,8 @@ func TestCheck_AutoCommitsRootFilesWhenRalphAlreadyTracked(t *testing.T) {
 }
 
 func TestCheck_AutoPushesBranch(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 	writeScaffold(t, clone)
 
 	// Create a local-only branch with .ralph/ committed.
@@ -120,13 +130,13 @@ func TestCheck_AutoPushesBranch(t *testing.T) {
 	testutil.RunGit(t, clone, "commit", "-m", "add scaffold")
 	testutil.RunGit(t, clone, "checkout", "-b", "feature-xyz")
 
-	err := Check("feature-xyz", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_feature-xyz.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "feature-xyz", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_feature-xyz.md")
 	require.NoError(t, err)
 }
 
 func TestCheck_UnpushedChanges_NoAutoPush(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 	writeScaffold(t, clone)
 
 	// Commit and push scaffold.
@@ -140,7 +150,7 @@ func TestCheck_UnpushedChanges_NoAutoPush(t *testing.T) {
 	testutil.RunGit(t, clone, "commit", "-m", "update scaffold")
 
 	// Should succeed without pushing (bind mount reads host files directly).
-	err := Check("main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
 	require.NoError(t, err)
 
 	// Verify the unpushed changes are NOT auto-pushed.
@@ -149,8 +159,8 @@ func TestCheck_UnpushedChanges_NoAutoPush(t *testing.T) {
 }
 
 func TestCheck_AutoCommitsSpecsDir(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 	writeScaffold(t, clone)
 
 	// Commit and push scaffold so it's tracked.
@@ -163,7 +173,7 @@ func TestCheck_AutoCommitsSpecsDir(t *testing.T) {
 	require.NoError(t, os.MkdirAll(specsDir, 0o750))
 	require.NoError(t, os.WriteFile(filepath.Join(specsDir, ".gitkeep"), []byte(""), 0o600))
 
-	err := Check("main", "requirements/v2", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "main", "requirements/v2", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
 	require.NoError(t, err)
 
 	log := gitLog(t, clone)
@@ -173,9 +183,53 @@ func TestCheck_AutoCommitsSpecsDir(t *testing.T) {
 	assert.Contains(t, show, "requirements/v2/.gitkeep")
 }
 
+func TestCheck_SkipsNestedRepoInSpecsDir(t *testing.T) {
+	t.Parallel()
+	_, clone := testutil.InitBareAndClone(t)
+	writeScaffold(t, clone)
+	testutil.RunGit(t, clone, "add", ".ralph/")
+	testutil.RunGit(t, clone, "commit", "-m", "add scaffold")
+	testutil.RunGit(t, clone, "push", "origin", "main")
+
+	specsDir := filepath.Join(clone, "specs", "main")
+	require.NoError(t, os.MkdirAll(specsDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(specsDir, ".gitkeep"), []byte(""), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(specsDir, "api.md"), []byte("# API"), 0o600))
+	vendored := filepath.Join(specsDir, "vendor", "upstream")
+	require.NoError(t, os.MkdirAll(vendored, 0o750))
+	testutil.RunGit(t, vendored, "init", "--initial-branch=main")
+	testutil.RunGit(t, vendored, "-c", "user.name=t", "-c", "user.email=t@t", "commit", "--allow-empty", "-m", "vendored")
+
+	require.NoError(t, Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs/main", ".ralph/plans/IMPLEMENTATION_PLAN_main.md"))
+
+	show := gitShow(t, clone, "HEAD", "--name-only")
+	assert.Contains(t, show, "specs/main/api.md")
+	assert.NotContains(t, show, "vendor/upstream", "nested repo must not be committed as a gitlink")
+}
+
+func TestCheck_CommitsSymlinkedSpecsDir(t *testing.T) {
+	t.Parallel()
+	_, clone := testutil.InitBareAndClone(t)
+	writeScaffold(t, clone)
+
+	parent := filepath.Join(clone, "specs", "parent")
+	require.NoError(t, os.MkdirAll(parent, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(parent, "api.md"), []byte("# API"), 0o600))
+	require.NoError(t, os.Symlink("parent", filepath.Join(clone, "specs", "child")))
+
+	require.NoError(t, Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs/child", ".ralph/plans/IMPLEMENTATION_PLAN_main.md"))
+
+	show := gitShow(t, clone, "HEAD", "--name-only")
+	assert.Contains(t, show, "specs/child")
+
+	// A second run sees the link as tracked and has nothing to commit.
+	require.NoError(t, Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs/child", ".ralph/plans/IMPLEMENTATION_PLAN_main.md"))
+	assert.Equal(t, 1, strings.Count(gitLog(t, clone), "chore: scaffold ralph"))
+}
+
 func TestCheck_AutoCommitsPlansDir(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 	writeScaffold(t, clone)
 
 	// Commit and push scaffold so it's tracked.
@@ -188,7 +242,7 @@ func TestCheck_AutoCommitsPlansDir(t *testing.T) {
 	require.NoError(t, os.MkdirAll(plansDir, 0o750))
 	require.NoError(t, os.WriteFile(filepath.Join(plansDir, ".gitkeep"), []byte(""), 0o600))
 
-	err := Check("main", "specs", "custom/plans/PLAN.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs", "custom/plans/PLAN.md")
 	require.NoError(t, err)
 
 	log := gitLog(t, clone)
@@ -199,8 +253,8 @@ func TestCheck_AutoCommitsPlansDir(t *testing.T) {
 }
 
 func TestCheck_CustomPlanDir_NoAutoPush(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 	writeScaffold(t, clone)
 
 	// Commit and push scaffold.
@@ -218,7 +272,7 @@ func TestCheck_CustomPlanDir_NoAutoPush(t *testing.T) {
 	testutil.RunGit(t, clone, "commit", "-m", "add plan")
 
 	// Should succeed without pushing (bind mount reads host files directly).
-	err := Check("main", "specs", "plans/IMPLEMENTATION_PLAN_main.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs", "plans/IMPLEMENTATION_PLAN_main.md")
 	require.NoError(t, err)
 
 	// Verify the plan was NOT auto-pushed — diff should still exist.
@@ -227,8 +281,8 @@ func TestCheck_CustomPlanDir_NoAutoPush(t *testing.T) {
 }
 
 func TestCheck_AutoCommitsModifiedGitignore(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 
 	// Create and commit a .gitignore, then scaffold.
 	require.NoError(t, os.WriteFile(filepath.Join(clone, ".gitignore"), []byte("*.pyc\n"), 0o600))
@@ -240,7 +294,7 @@ func TestCheck_AutoCommitsModifiedGitignore(t *testing.T) {
 	// Simulate ralph init appending to .gitignore (file is already tracked).
 	require.NoError(t, os.WriteFile(filepath.Join(clone, ".gitignore"), []byte("*.pyc\n.ralph/logs/\n.env\n"), 0o600))
 
-	err := Check("main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
 	require.NoError(t, err)
 
 	// Verify .gitignore was included in the scaffold commit.
@@ -249,19 +303,19 @@ func TestCheck_AutoCommitsModifiedGitignore(t *testing.T) {
 }
 
 func TestCheckAdditionalDirs_EmptyList(t *testing.T) {
-	err := CheckAdditionalDirs("main", nil)
+	err := CheckAdditionalDirs(context.Background(), io.Discard, "main", nil)
 	require.NoError(t, err)
 }
 
 func TestCheckAdditionalDirs_DirMissing(t *testing.T) {
-	err := CheckAdditionalDirs("main", []string{"/nonexistent/path"})
+	err := CheckAdditionalDirs(context.Background(), io.Discard, "main", []string{"/nonexistent/path"})
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "does not exist")
 }
 
 func TestCheckAdditionalDirs_NotARepo(t *testing.T) {
 	dir := t.TempDir()
-	err := CheckAdditionalDirs("main", []string{dir})
+	err := CheckAdditionalDirs(context.Background(), io.Discard, "main", []string{dir})
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "not a git repository")
 }
@@ -269,7 +323,7 @@ func TestCheckAdditionalDirs_NotARepo(t *testing.T) {
 func TestCheckAdditionalDirs_WrongBranch(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
 	// clone is on "main", but we expect "feature"
-	err := CheckAdditionalDirs("feature", []string{clone})
+	err := CheckAdditionalDirs(context.Background(), io.Discard, "feature", []string{clone})
 	require.Error(t, err)
 	assert.ErrorContains(t, err, `on branch "main"`)
 	assert.ErrorContains(t, err, `expected "feature"`)
@@ -281,24 +335,51 @@ func TestCheckAdditionalDirs_AutoPush(t *testing.T) {
 	// Create a new branch that doesn't exist on the remote.
 	testutil.RunGit(t, clone, "checkout", "-b", "feature-new")
 
-	err := CheckAdditionalDirs("feature-new", []string{clone})
+	err := CheckAdditionalDirs(context.Background(), io.Discard, "feature-new", []string{clone})
 	require.NoError(t, err)
 
 	// Verify the branch now exists on the remote.
-	exists, err := git.BranchExistsOnRemoteIn(clone, "feature-new")
+	exists, err := git.BranchExistsOnRemoteIn(context.Background(), clone, "feature-new")
 	require.NoError(t, err)
 	assert.True(t, exists, "expected branch to be auto-pushed to remote")
 }
 
+func TestPrepare_DoesNotPush(t *testing.T) {
+	t.Parallel()
+	_, clone := testutil.InitBareAndClone(t)
+	writeScaffold(t, clone)
+	testutil.RunGit(t, clone, "checkout", "-b", "feature-offline")
+
+	require.NoError(t, Prepare(context.Background(), io.Discard, openVCS(t, clone), "specs", ".ralph/plans/IMPLEMENTATION_PLAN_feature-offline.md"))
+
+	assert.Contains(t, gitLog(t, clone), "chore: scaffold ralph")
+	exists, err := git.BranchExistsOnRemoteIn(context.Background(), clone, "feature-offline")
+	require.NoError(t, err)
+	assert.False(t, exists, "an offline run must not push")
+}
+
+func TestPrepareAdditionalDirs_DoesNotPush(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	testutil.RunGit(t, clone, "checkout", "-b", "feature-new")
+
+	require.NoError(t, PrepareAdditionalDirs(context.Background(), io.Discard, "feature-new", []string{clone}))
+	exists, err := git.BranchExistsOnRemoteIn(context.Background(), clone, "feature-new")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = PrepareAdditionalDirs(context.Background(), io.Discard, "other", []string{clone})
+	assert.ErrorContains(t, err, `expected "other"`)
+}
+
 func TestCheckAdditionalDirs_HappyPath(t *testing.T) {
 	_, clone := testutil.InitBareAndClone(t)
-	err := CheckAdditionalDirs("main", []string{clone})
+	err := CheckAdditionalDirs(context.Background(), io.Discard, "main", []string{clone})
 	require.NoError(t, err)
 }
 
 func TestCheck_AllClean(t *testing.T) {
+	t.Parallel()
 	_, clone := testutil.InitBareAndClone(t)
-	testutil.Chdir(t, clone)
 	writeScaffold(t, clone)
 
 	// Everything committed and pushed — should be a no-op.
@@ -306,6 +387,6 @@ func TestCheck_AllClean(t *testing.T) {
 	testutil.RunGit(t, clone, "commit", "-m", "add scaffold")
 	testutil.RunGit(t, clone, "push", "origin", "main")
 
-	err := Check("main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
+	err := Check(context.Background(), io.Discard, openVCS(t, clone), "main", "specs", ".ralph/plans/IMPLEMENTATION_PLAN_main.md")
 	require.NoError(t, err)
 }
@@ -0,0 +1,105 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/vcs"
+)
+
+// SecretHistoryDepth is how many commits back CheckSecrets looks for a
+// secret file that was committed and later removed.
+const SecretHistoryDepth = 100
+
+// secretPatterns match the base names of files that hold credentials and
+// must never be committed. Templates such as .env.example are allowed.
+var secretPatterns = []string{".env", ".env.*", ".netrc", "id_rsa", "id_ecdsa", "id_ed25519"}
+
+// secretTemplates are the .env.* suffixes of files meant to be committed.
+var secretTemplates = []string{".example", ".sample", ".template", ".dist"}
+
+// secretPaths are repo-relative files that hold credentials.
+var secretPaths = []string{".ralph/profiles.yaml"}
+
+// IsSecretFile reports whether the repo-relative path p names a file that
+// holds credentials, such as .env or .ralph/profiles.yaml.
+func IsSecretFile(p string) bool {
+	if slices.Contains(secretPaths, p) {
+		return true
+	}
+	base := path.Base(p)
+	for _, suffix := range secretTemplates {
+		if strings.HasSuffix(base, suffix) {
+			return false
+		}
+	}
+	for _, pattern := range secretPatterns {
+		if ok, _ := path.Match(pattern, base); ok { //nolint:errcheck // the patterns are valid
+			return true
+		}
+	}
+	return false
+}
+
+// CheckSecrets refuses to run when a secret file is tracked, or was added in
+// the last SecretHistoryDepth commits, since the loop pushes the branch and
+// would publish it. rotated lists files from history whose keys have been
+// rotated; a tracked file is refused regardless. Only git repos are checked.
+func CheckSecrets(ctx context.Context, v vcs.VCS, rotated []string) error {
+	if v.Kind() != vcs.KindGit {
+		return nil
+	}
+	root := v.Root()
+	tracked, err := git.TrackedFilesIn(ctx, root)
+	if err != nil {
+		return fmt.Errorf("preflight: listing tracked files: %w", err)
+	}
+	var inTree, inHistory []string
+	for _, p := range tracked {
+		if IsSecretFile(p) {
+			inTree = append(inTree, p)
+		}
+	}
+	if _, err := git.HeadIn(ctx, root); err == nil {
+		added, err := git.AddedFilesIn(ctx, root, SecretHistoryDepth)
+		if err != nil {
+			return fmt.Errorf("preflight: reading recent history: %w", err)
+		}
+		for _, p := range added {
+			if IsSecretFile(p) && !slices.Contains(inTree, p) && !slices.Contains(inHistory, p) && !slices.Contains(rotated, p) {
+				inHistory = append(inHistory, p)
+			}
+		}
+	}
+	if len(inTree) == 0 && len(inHistory) == 0 {
+		return nil
+	}
+	return secretsError(inTree, inHistory)
+}
+
+// secretsError explains what was found and how to clean it up.
+func secretsError(inTree, inHistory []string) error {
+	var b strings.Builder
+	b.WriteString("preflight: refusing to run with secrets committed to git; the loop pushes this branch and would publish them\n")
+	if len(inTree) > 0 {
+		fmt.Fprintf(&b, "  tracked: %s\n", strings.Join(inTree, ", "))
+	}
+	if len(inHistory) > 0 {
+		fmt.Fprintf(&b, "  in the last %d commits: %s\n", SecretHistoryDepth, strings.Join(inHistory, ", "))
+	}
+	all := slices.Concat(inTree, inHistory)
+	b.WriteString("To fix:\n")
+	if len(inTree) > 0 {
+		fmt.Fprintf(&b, "  - Stop tracking them: git rm --cached %s && git commit -m \"Stop tracking secrets\"\n", strings.Join(inTree, " "))
+		b.WriteString("  - List them in .gitignore (ralph init adds .env and .ralph/profiles.yaml)\n")
+	}
+	b.WriteString("  - Rotate every key they hold: treat anything committed as leaked, even if never pushed\n")
+	fmt.Fprintf(&b, "  - Drop them from history before pushing: git filter-repo --invert-paths --path %s\n", strings.Join(all, " --path "))
+	b.WriteString("  - Once the keys are rotated, a file left in history can be listed under guardrails.secrets_rotated in .ralph/config.yaml")
+	return errors.New(b.String())
+}
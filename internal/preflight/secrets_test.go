@@ -0,0 +1,51 @@
+package preflight
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/testutil"
+)
+
+func TestIsSecretFile(t *testing.T) {
+	for _, p := range []string{".env", ".env.local", "services/api/.env.production", ".ralph/profiles.yaml", ".netrc", "deploy/id_ed25519"} {
+		assert.True(t, IsSecretFile(p), p)
+	}
+	for _, p := range []string{".env.example", ".env.sample", "config/.env.template", "profiles.yaml", "env.go", ".envrc.md", "id_ed25519.pub"} {
+		assert.False(t, IsSecretFile(p), p)
+	}
+}
+
+func TestCheckSecrets(t *testing.T) {
+	t.Parallel()
+	_, clone := testutil.InitBareAndClone(t)
+	writeScaffold(t, clone)
+	ctx := context.Background()
+
+	require.NoError(t, CheckSecrets(ctx, openVCS(t, clone), nil), ".env.example is a template")
+
+	require.NoError(t, os.WriteFile(filepath.Join(clone, ".env"), []byte("ANTHROPIC_API_KEY=sk-ant-test"), 0o600))
+	require.NoError(t, CheckSecrets(ctx, openVCS(t, clone), nil), "an untracked .env is fine")
+
+	testutil.RunGit(t, clone, "add", "-f", ".env")
+	testutil.RunGit(t, clone, "commit", "-m", "add env")
+	err := CheckSecrets(ctx, openVCS(t, clone), []string{".env"})
+	require.Error(t, err, "a tracked file is refused even when listed as rotated")
+	assert.ErrorContains(t, err, "tracked: .env")
+	assert.ErrorContains(t, err, "git rm --cached .env")
+
+	testutil.RunGit(t, clone, "rm", "--cached", ".env")
+	testutil.RunGit(t, clone, "commit", "-m", "untrack env")
+	err = CheckSecrets(ctx, openVCS(t, clone), nil)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "in the last 100 commits: .env")
+	assert.ErrorContains(t, err, "git filter-repo --invert-paths --path .env")
+	assert.NotContains(t, err.Error(), "git rm --cached")
+
+	require.NoError(t, CheckSecrets(ctx, openVCS(t, clone), []string{".env"}))
+}
@@ -0,0 +1,181 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultGitHubAPI is the GitHub REST API root CheckToken asks.
+const DefaultGitHubAPI = "https://api.github.com"
+
+// ErrTokenUnchecked is returned, wrapped, when GitHub couldn't be reached to
+// check the token; the run may still work, so callers usually warn.
+var ErrTokenUnchecked = errors.New("couldn't reach GitHub to check GITHUB_PAT")
+
+// TokenNeeds is what a run does with GITHUB_PAT besides pushing the agent's
+// commits, which every online run does.
+type TokenNeeds struct {
+	PullRequests bool // github.plan_review opens and comments on the plan's pull request
+	Statuses     bool // github.report: status sets commit statuses on the branch head
+	Checks       bool // github.report: check posts check runs, with TokenProbe.AppToken
+}
+
+// patPrefixes start the personal access tokens GitHub issues: classic and
+// fine-grained. The Checks API rejects both.
+var patPrefixes = []string{"ghp_", "github_pat_"}
+
+// TokenProbe checks GITHUB_PAT against the GitHub API before anything is
+// pushed, so a token that can't do what the run needs fails here, naming
+// what it lacks, instead of as a bare 403 from git mid-run. When check runs
+// are needed, AppToken must not be a personal access token either.
+type TokenProbe struct {
+	Repo     string // "owner/repo"
+	Token    string
+	AppToken string // GITHUB_APP_TOKEN, checked when Needs.Checks
+	Needs    TokenNeeds
+	BaseURL  string       // empty = DefaultGitHubAPI
+	HTTP     *http.Client // nil = a client with a short timeout
+}
+
+// Check asks GitHub for the repo as the token sees it. A classic token
+// lists its scopes in the response, which are checked against the run's
+// needs; a fine-grained token's permissions aren't listed, so only its
+// push access, which the repo's permissions reflect, is checked.
+func (p *TokenProbe) Check(ctx context.Context) error {
+	if p.Needs.Checks {
+		if err := p.checkAppToken(); err != nil {
+			return err
+		}
+	}
+	base := p.BaseURL
+	if base == "" {
+		base = DefaultGitHubAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/repos/"+p.Repo, nil)
+	if err != nil {
+		return fmt.Errorf("checking GITHUB_PAT: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	client := p.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTokenUnchecked, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("GITHUB_PAT was rejected by GitHub: it is invalid, expired or revoked")
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("GITHUB_PAT can't see %s (%s); %s", p.Repo, resp.Status, p.guidance(true))
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("%w: GitHub answered %s", ErrTokenUnchecked, resp.Status)
+	}
+
+	var repo struct {
+		Private     bool `json:"private"`
+		Permissions *struct {
+			Push bool `json:"push"`
+		} `json:"permissions"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&repo); err != nil {
+		return fmt.Errorf("%w: decoding response: %w", ErrTokenUnchecked, err)
+	}
+
+	if header, classic := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; classic {
+		scopes := parseScopes(strings.Join(header, ","))
+		slog.DebugContext(ctx, "preflight: token", "kind", "classic", "scopes", scopes, "private", repo.Private)
+		if missing := p.missingScopes(scopes, repo.Private); len(missing) > 0 {
+			return fmt.Errorf("GITHUB_PAT is missing scopes on %s: %s", p.Repo, strings.Join(missing, "; "))
+		}
+	}
+	slog.DebugContext(ctx, "preflight: token", "push", repo.Permissions != nil && repo.Permissions.Push)
+	if repo.Permissions != nil && !repo.Permissions.Push {
+		return fmt.Errorf("GITHUB_PAT can't push to %s; %s", p.Repo, p.guidance(false))
+	}
+	return nil
+}
+
+// checkAppToken makes sure check runs have a token GitHub accepts for them.
+// It can only be told apart from a personal access token by its shape, so
+// that is all that is checked; GitHub itself isn't asked.
+func (p *TokenProbe) checkAppToken() error {
+	const fix = "set it to a GitHub App installation token, or use github.report: status"
+	switch {
+	case p.AppToken == "":
+		return fmt.Errorf("github.report: check needs a GitHub App installation token in GITHUB_APP_TOKEN, since the Checks API rejects GITHUB_PAT; %s", fix)
+	case p.AppToken == p.Token:
+		return fmt.Errorf("GITHUB_APP_TOKEN is the same token as GITHUB_PAT, and the Checks API rejects personal access tokens; %s", fix)
+	}
+	for _, prefix := range patPrefixes {
+		if strings.HasPrefix(p.AppToken, prefix) {
+			return fmt.Errorf("GITHUB_APP_TOKEN is a personal access token (%s...), which the Checks API rejects; %s", prefix, fix)
+		}
+	}
+	return nil
+}
+
+// parseScopes splits an X-OAuth-Scopes header, e.g. "repo, workflow".
+func parseScopes(header string) []string {
+	var scopes []string
+	for _, s := range strings.Split(header, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// missingScopes lists each classic scope the run needs and the token
+// lacks, with what needs it. The repo scope covers everything; on a public
+// repo public_repo does too.
+func (p *TokenProbe) missingScopes(scopes []string, private bool) []string {
+	if slices.Contains(scopes, "repo") || (!private && slices.Contains(scopes, "public_repo")) {
+		return nil
+	}
+	code := "repo"
+	if !private {
+		code = "public_repo (or repo)"
+	}
+	uses := []string{"push the agent's commits"}
+	if p.Needs.PullRequests {
+		uses = append(uses, "open the plan's pull request (github.plan_review)")
+	}
+	missing := []string{code + " to " + strings.Join(uses, " and ")}
+	if p.Needs.Statuses && !slices.Contains(scopes, "repo:status") {
+		missing = append(missing, "repo:status to set commit statuses (github.report: status)")
+	}
+	return missing
+}
+
+// guidance names the smallest token that does what the run needs, for
+// either kind of token; seeRepo adds that a fine-grained token must have
+// the repository selected.
+func (p *TokenProbe) guidance(seeRepo bool) string {
+	perms := []string{"Contents"}
+	if p.Needs.PullRequests {
+		perms = append(perms, "Pull requests")
+	}
+	if p.Needs.Statuses {
+		perms = append(perms, "Commit statuses")
+	}
+	fine := "a fine-grained token needs " + strings.Join(perms, ", ") + ": Read and write"
+	if seeRepo {
+		fine = "a fine-grained token needs this repository selected, with " + strings.Join(perms, ", ") + ": Read and write"
+	}
+	return "a classic token needs the repo scope (public_repo for a public repo); " + fine
+}
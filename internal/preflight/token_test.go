@@ -0,0 +1,103 @@
+package preflight
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub answers GET /repos/o/r with status, body and, unless scopes is
+// nil, an X-OAuth-Scopes header as a classic token gets.
+func fakeGitHub(t *testing.T, status int, body string, scopes *string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/o/r", r.URL.Path)
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		if scopes != nil {
+			w.Header().Set("X-OAuth-Scopes", *scopes)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body)) //nolint:errcheck // test server
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestTokenProbe_Check(t *testing.T) {
+	scopes := func(s string) *string { return &s }
+	const (
+		privatePush = `{"private":true,"permissions":{"push":true}}`
+		publicPush  = `{"private":false,"permissions":{"push":true}}`
+		readOnly    = `{"private":true,"permissions":{"push":false}}`
+	)
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		scopes   *string
+		needs    TokenNeeds
+		appToken string
+		wantErr  []string
+	}{
+		{name: "classic with repo", status: 200, body: privatePush, scopes: scopes("repo, workflow"),
+			needs: TokenNeeds{PullRequests: true, Statuses: true}},
+		{name: "classic public_repo on a public repo", status: 200, body: publicPush, scopes: scopes("public_repo"),
+			needs: TokenNeeds{PullRequests: true, Statuses: true}},
+		{name: "classic public_repo on a private repo", status: 200, body: privatePush, scopes: scopes("public_repo"),
+			needs:   TokenNeeds{PullRequests: true},
+			wantErr: []string{"missing scopes on o/r", "repo to push the agent's commits and open the plan's pull request"}},
+		{name: "classic repo:status only", status: 200, body: privatePush, scopes: scopes("repo:status"),
+			needs:   TokenNeeds{Statuses: true},
+			wantErr: []string{"repo to push the agent's commits"}},
+		{name: "classic without scopes", status: 200, body: publicPush, scopes: scopes(""),
+			needs:   TokenNeeds{Statuses: true},
+			wantErr: []string{"public_repo (or repo)", "repo:status to set commit statuses"}},
+		{name: "fine-grained with push", status: 200, body: privatePush},
+		{name: "fine-grained read-only", status: 200, body: readOnly, needs: TokenNeeds{PullRequests: true},
+			wantErr: []string{"can't push to o/r", "Contents, Pull requests: Read and write"}},
+		{name: "repo not selected", status: 404, body: `{}`,
+			wantErr: []string{"can't see o/r (404 Not Found)", "this repository selected, with Contents: Read and write"}},
+		{name: "revoked", status: 401, body: `{}`, wantErr: []string{"invalid, expired or revoked"}},
+		{name: "checks with an installation token", status: 200, body: privatePush,
+			needs: TokenNeeds{Checks: true}, appToken: "ghs_app"},
+		{name: "checks without an app token", status: 200, body: privatePush, needs: TokenNeeds{Checks: true},
+			wantErr: []string{"needs a GitHub App installation token in GITHUB_APP_TOKEN"}},
+		{name: "checks with a classic PAT", status: 200, body: privatePush,
+			needs: TokenNeeds{Checks: true}, appToken: "ghp_abc",
+			wantErr: []string{"GITHUB_APP_TOKEN is a personal access token (ghp_...)"}},
+		{name: "checks with a fine-grained PAT", status: 200, body: privatePush,
+			needs: TokenNeeds{Checks: true}, appToken: "github_pat_abc",
+			wantErr: []string{"GITHUB_APP_TOKEN is a personal access token (github_pat_...)"}},
+		{name: "checks with GITHUB_PAT again", status: 200, body: privatePush,
+			needs: TokenNeeds{Checks: true}, appToken: "tok",
+			wantErr: []string{"GITHUB_APP_TOKEN is the same token as GITHUB_PAT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := TokenProbe{Repo: "o/r", Token: "tok", AppToken: tt.appToken, Needs: tt.needs,
+				BaseURL: fakeGitHub(t, tt.status, tt.body, tt.scopes)}
+			err := p.Check(context.Background())
+			if len(tt.wantErr) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.NotErrorIs(t, err, ErrTokenUnchecked)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestTokenProbe_Unreachable(t *testing.T) {
+	p := TokenProbe{Repo: "o/r", Token: "tok", BaseURL: fakeGitHub(t, 502, "", nil)}
+	require.ErrorIs(t, p.Check(context.Background()), ErrTokenUnchecked)
+
+	p.BaseURL = "http://127.0.0.1:1"
+	require.ErrorIs(t, p.Check(context.Background()), ErrTokenUnchecked)
+}
@@ -0,0 +1,124 @@
+// Package pricing computes iteration cost from token usage when the API
+// doesn't report one, and formats costs in the configured currency.
+package pricing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rates are US dollars per million tokens.
+type Rates struct {
+	Input      float64 `yaml:"input"`
+	Output     float64 `yaml:"output"`
+	CacheWrite float64 `yaml:"cache_write"`
+	CacheRead  float64 `yaml:"cache_read"`
+}
+
+// Usage is the token count an iteration was billed for.
+type Usage struct {
+	Input, Output, CacheWrite, CacheRead int
+}
+
+// Cost returns the dollar cost of u at these rates.
+func (r Rates) Cost(u Usage) float64 {
+	return (float64(u.Input)*r.Input +
+		float64(u.Output)*r.Output +
+		float64(u.CacheWrite)*r.CacheWrite +
+		float64(u.CacheRead)*r.CacheRead) / 1_000_000
+}
+
+// Table maps a model name, or a fragment of one such as "sonnet", to its
+// rates.
+type Table map[string]Rates
+
+// Default is Anthropic's list pricing for the models claude runs. Keys match
+// CLI aliases and full model ids alike.
+var Default = Table{
+	"opus":      {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+	"opus-4-5":  {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50},
+	"sonnet":    {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"haiku":     {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10},
+	"haiku-3-5": {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+}
+
+// With returns a copy of t with overrides added or replacing its entries.
+func (t Table) With(overrides Table) Table {
+	out := make(Table, len(t)+len(overrides))
+	for k, v := range t {
+		out[k] = v
+	}
+	for k, v := range overrides {
+		out[k] = v
+	}
+	return out
+}
+
+// Lookup returns the rates for model: an exact entry, else the longest key
+// the model name contains, so "claude-sonnet-4-5-20250929" uses "sonnet".
+func (t Table) Lookup(model string) (Rates, bool) {
+	if r, ok := t[model]; ok {
+		return r, true
+	}
+	best := ""
+	for k := range t {
+		if strings.Contains(model, k) && len(k) > len(best) {
+			best = k
+		}
+	}
+	if best == "" {
+		return Rates{}, false
+	}
+	return t[best], true
+}
+
+// Fingerprint identifies t's rates, so costs priced from it can be told
+// apart from ones priced from other rates. Equal tables share one; nil and
+// empty tables give "".
+func (t Table) Fingerprint() string {
+	if len(t) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		r := t[k]
+		fmt.Fprintf(h, "%q %g %g %g %g\n", k, r.Input, r.Output, r.CacheWrite, r.CacheRead)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// USD is the currency costs are reported in by the API.
+const USD = "USD"
+
+// symbols are the currencies shown with a sign rather than their code.
+var symbols = map[string]string{USD: "$", "EUR": "€", "GBP": "£", "JPY": "¥"}
+
+// Currency converts dollar costs for display at a fixed rate. The zero
+// value shows US dollars.
+type Currency struct {
+	Code   string  // ISO 4217 code, e.g. "EUR"; empty = USD
+	PerUSD float64 // units of Code per US dollar
+}
+
+// Format renders a dollar amount in c with the given number of decimals,
+// e.g. "$0.1234" or "€0.11".
+func (c Currency) Format(usd float64, decimals int) string {
+	code, amount := c.Code, usd
+	if code == "" || code == USD {
+		code = USD
+	} else {
+		amount *= c.PerUSD
+	}
+	if sym, ok := symbols[code]; ok {
+		return fmt.Sprintf("%s%.*f", sym, decimals, amount)
+	}
+	return fmt.Sprintf("%s %.*f", code, decimals, amount)
+}
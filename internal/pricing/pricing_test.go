@@ -0,0 +1,51 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRatesCost(t *testing.T) {
+	r := Rates{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30}
+	u := Usage{Input: 1_000, Output: 2_000, CacheWrite: 10_000, CacheRead: 100_000}
+	assert.InDelta(t, 0.003+0.03+0.0375+0.03, r.Cost(u), 1e-12)
+}
+
+func TestLookup(t *testing.T) {
+	r, ok := Default.Lookup("sonnet")
+	assert.True(t, ok)
+	assert.InDelta(t, 3.0, r.Input, 0)
+
+	r, ok = Default.Lookup("claude-opus-4-5-20251101")
+	assert.True(t, ok)
+	assert.InDelta(t, 5.0, r.Input, 0) // longest matching key wins over "opus"
+
+	r, ok = Default.Lookup("claude-opus-4-1-20250805")
+	assert.True(t, ok)
+	assert.InDelta(t, 15.0, r.Input, 0)
+
+	_, ok = Default.Lookup("gpt-4o")
+	assert.False(t, ok)
+}
+
+func TestWith(t *testing.T) {
+	custom := Default.With(Table{"sonnet": {Input: 1}, "gateway-model": {Input: 2}})
+	assert.InDelta(t, 1.0, custom["sonnet"].Input, 0)
+	assert.InDelta(t, 2.0, custom["gateway-model"].Input, 0)
+	assert.InDelta(t, 3.0, Default["sonnet"].Input, 0) // Default is untouched
+}
+
+func TestFingerprint(t *testing.T) {
+	assert.Empty(t, Table(nil).Fingerprint())
+	assert.Equal(t, Default.Fingerprint(), Default.With(nil).Fingerprint(), "equal tables match")
+	assert.NotEqual(t, Default.Fingerprint(), Default.With(Table{"sonnet": {Input: 1}}).Fingerprint())
+	assert.NotEqual(t, Default.Fingerprint(), Default.With(Table{"gateway-model": {}}).Fingerprint())
+}
+
+func TestCurrencyFormat(t *testing.T) {
+	assert.Equal(t, "$1.2346", Currency{}.Format(1.23456, 4))
+	assert.Equal(t, "$1.23", Currency{Code: USD, PerUSD: 2}.Format(1.23456, 2)) // USD ignores the rate
+	assert.Equal(t, "€0.92", Currency{Code: "EUR", PerUSD: 0.92}.Format(1, 2))
+	assert.Equal(t, "CHF 0.8800", Currency{Code: "CHF", PerUSD: 0.88}.Format(1, 4))
+}
@@ -0,0 +1,132 @@
+// Package progress emits run progress as JSON-RPC style notifications over
+// stdio so editor extensions can display task and cost updates live.
+package progress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/status"
+)
+
+// MethodProgress is the notification method name sent for every snapshot.
+const MethodProgress = "ralph/progress"
+
+// DefaultInterval is how often Watch re-reads the plan, logs, and state.
+const DefaultInterval = 2 * time.Second
+
+// Task is a plan task as reported to editors.
+type Task struct {
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+// Snapshot is the progress payload sent with each notification.
+type Snapshot struct {
+	Branch     string           `json:"branch"`
+	Tasks      []Task           `json:"tasks"`
+	TasksDone  int              `json:"tasks_done"`
+	TasksTotal int              `json:"tasks_total"`
+	Iterations int              `json:"iterations"`
+	TotalCost  float64          `json:"total_cost"`
+	LastRun    *state.RunRecord `json:"last_run,omitempty"`
+	Error      string           `json:"error,omitempty"` // why the latest check failed; the rest is from the last that succeeded
+}
+
+// Notification is a JSON-RPC 2.0 notification (no id, no response expected).
+type Notification struct {
+	JSONRPC string    `json:"jsonrpc"`
+	Method  string    `json:"method"`
+	Params  *Snapshot `json:"params"`
+}
+
+// Sources locates the files a Snapshot is built from.
+type Sources struct {
+	Branch    string
+	PlanFile  string        // absolute path to the branch plan
+	LogsDir   string        // absolute path to .ralph/logs
+	StateFile string        // absolute path to .ralph/state.json
+	Pricing   pricing.Table // cost.pricing, for logs without a reported cost
+}
+
+// Collect builds a Snapshot from the plan, iteration logs, and state file.
+func Collect(src *Sources) (*Snapshot, error) {
+	tasks, err := status.ParsePlan(src.PlanFile)
+	if err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+	runs, err := status.ParseLogs(src.LogsDir, src.Pricing)
+	if err != nil {
+		return nil, fmt.Errorf("parsing logs: %w", err)
+	}
+	st, err := state.Load(src.StateFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading state: %w", err)
+	}
+
+	snap := &Snapshot{
+		Branch:     src.Branch,
+		Tasks:      make([]Task, 0, len(tasks)),
+		TasksTotal: len(tasks),
+		Iterations: len(runs),
+		LastRun:    st.LastRun(),
+	}
+	for _, t := range tasks {
+		snap.Tasks = append(snap.Tasks, Task{Title: t.Title, Done: t.Done})
+		if t.Done {
+			snap.TasksDone++
+		}
+	}
+	for _, r := range runs {
+		snap.TotalCost += r.Cost
+	}
+	return snap, nil
+}
+
+// Watch polls src every interval and writes a notification line to w each
+// time the snapshot changes. The first snapshot is always sent. A check that
+// fails, e.g. on a state file caught mid-write, is sent as the last snapshot
+// with its Error set, and watching goes on. Watch returns nil when ctx is
+// cancelled.
+func Watch(ctx context.Context, src *Sources, interval time.Duration, w io.Writer) error {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last []byte
+	good := &Snapshot{Branch: src.Branch, Tasks: []Task{}}
+	for {
+		snap, err := Collect(src)
+		if err != nil {
+			failed := *good
+			failed.Error = err.Error()
+			snap = &failed
+		} else {
+			good = snap
+		}
+		line, err := json.Marshal(&Notification{JSONRPC: "2.0", Method: MethodProgress, Params: snap})
+		if err != nil {
+			return fmt.Errorf("encoding notification: %w", err)
+		}
+		if !bytes.Equal(line, last) {
+			if _, err := fmt.Fprintf(w, "%s\n", line); err != nil {
+				return fmt.Errorf("writing notification: %w", err)
+			}
+			last = line
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package progress
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+const plan = `### Task 1 -- Setup
+- [x] init
+
+### Task 2 -- Feature
+- [ ] build it
+`
+
+func testSources(t *testing.T) *Sources {
+	t.Helper()
+	dir := t.TempDir()
+	src := &Sources{
+		Branch:    "feature",
+		PlanFile:  filepath.Join(dir, "plan.md"),
+		LogsDir:   filepath.Join(dir, "logs"),
+		StateFile: filepath.Join(dir, "state.json"),
+	}
+	require.NoError(t, os.WriteFile(src.PlanFile, []byte(plan), 0o600))
+	require.NoError(t, os.MkdirAll(src.LogsDir, 0o750))
+	log := `{"type":"result","total_cost_usd":0.25}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(src.LogsDir, "20260101-120000.jsonl"), []byte(log), 0o600))
+	return src
+}
+
+func TestCollect(t *testing.T) {
+	src := testSources(t)
+
+	snap, err := Collect(src)
+	require.NoError(t, err)
+
+	assert.Equal(t, "feature", snap.Branch)
+	assert.Equal(t, 2, snap.TasksTotal)
+	assert.Equal(t, 1, snap.TasksDone)
+	assert.Equal(t, []Task{{"Setup", true}, {"Feature", false}}, snap.Tasks)
+	assert.Equal(t, 1, snap.Iterations)
+	assert.InDelta(t, 0.25, snap.TotalCost, 1e-9)
+	assert.Nil(t, snap.LastRun)
+}
+
+func TestCollect_NothingYet(t *testing.T) {
+	dir := t.TempDir()
+	snap, err := Collect(&Sources{
+		PlanFile:  filepath.Join(dir, "missing.md"),
+		LogsDir:   filepath.Join(dir, "logs"),
+		StateFile: filepath.Join(dir, "state.json"),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, snap.Tasks)
+	assert.Equal(t, 0, snap.Iterations)
+}
+
+func TestWatch_EmitsOnChange(t *testing.T) {
+	src := testSources(t)
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, src, 10*time.Millisecond, pw)
+		pw.Close() //nolint:errcheck // test pipe
+	}()
+
+	sc := bufio.NewScanner(pr)
+	require.True(t, sc.Scan())
+	var first Notification
+	require.NoError(t, json.Unmarshal(sc.Bytes(), &first))
+	assert.Equal(t, "2.0", first.JSONRPC)
+	assert.Equal(t, MethodProgress, first.Method)
+	assert.Equal(t, 1, first.Params.TasksDone)
+
+	// Completing a task produces a second notification.
+	updated := `### Task 1 -- Setup
+- [x] init
+
+### Task 2 -- Feature
+- [x] build it
+`
+	require.NoError(t, os.WriteFile(src.PlanFile, []byte(updated), 0o600))
+	require.True(t, sc.Scan())
+	var second Notification
+	require.NoError(t, json.Unmarshal(sc.Bytes(), &second))
+	assert.Equal(t, 2, second.Params.TasksDone)
+
+	cancel()
+	go io.Copy(io.Discard, pr) //nolint:errcheck // drain until writer closes
+	require.NoError(t, <-done)
+}
+
+func TestWatch_ReportsFailedChecks(t *testing.T) {
+	src := testSources(t)
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, src, 10*time.Millisecond, pw)
+		pw.Close() //nolint:errcheck // test pipe
+	}()
+
+	sc := bufio.NewScanner(pr)
+	next := func() *Notification {
+		t.Helper()
+		require.True(t, sc.Scan())
+		var n Notification
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &n))
+		return &n
+	}
+	assert.Empty(t, next().Params.Error)
+
+	require.NoError(t, os.WriteFile(src.StateFile, []byte("{"), 0o600))
+	failed := next()
+	assert.Contains(t, failed.Params.Error, "loading state")
+	assert.Equal(t, 1, failed.Params.TasksDone, "the last good snapshot is kept")
+
+	require.NoError(t, os.Remove(src.StateFile))
+	assert.Empty(t, next().Params.Error, "still watching once the state is readable again")
+
+	cancel()
+	go io.Copy(io.Discard, pr) //nolint:errcheck // drain until writer closes
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+//go:embed report.html.tmpl
+var pageTemplate string
+
+// HTML writes r as a self-contained HTML page, with costs shown in cur. The
+// page has no scripts or external assets, so it can be attached or mailed
+// as a single file.
+func HTML(w io.Writer, r *Report, cur pricing.Currency) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"money":       func(usd float64) string { return cur.Format(usd, 2) },
+		"tokens":      stream.FormatTokens,
+		"duration":    wallTime,
+		"elapsed":     func(d time.Duration) string { return d.Round(time.Second).String() },
+		"statusText":  func(s state.RunStatus) string { return strings.ReplaceAll(string(s), "_", " ") },
+		"statusClass": statusClass,
+		"chart":       func(its []Iteration) *costChart { return newCostChart(its, cur) },
+	}).Parse(pageTemplate)
+	if err != nil {
+		return fmt.Errorf("parsing report template: %w", err)
+	}
+	if err := tmpl.Execute(w, r); err != nil {
+		return fmt.Errorf("rendering report: %w", err)
+	}
+	return nil
+}
+
+// wallTime formats the time between start and end, e.g. "1h 4m" or "3m 20s".
+func wallTime(start, end time.Time) string {
+	if start.IsZero() || end.Before(start) {
+		return "–"
+	}
+	d := end.Sub(start).Round(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// statusClass colours the status badge: runs that stopped early are bad,
+// runs that finished their work good, anything else neutral.
+func statusClass(s state.RunStatus) string {
+	switch s { //nolint:exhaustive // remaining statuses are neutral
+	case state.StatusStaleAbort, state.StatusCancelled, state.StatusContainerCrash, state.StatusDiskLimit, state.StatusTimeoutAbort, state.StatusFailed:
+		return "bad"
+	case state.StatusCompleted, state.StatusConverged:
+		return "good"
+	}
+	return ""
+}
+
+// costChart is an SVG bar chart of cost per iteration.
+type costChart struct {
+	Width, Height int
+	Max           string // cost of the tallest bar, labelled on the axis
+	Bars          []costBar
+}
+
+type costBar struct {
+	X, Y, W, H float64
+	LabelX     float64
+	Label      string
+	Value      string
+}
+
+const (
+	chartWidth  = 720
+	chartHeight = 180
+	chartLabel  = 16 // room below the bars for iteration numbers
+	chartTop    = 14 // room above the tallest bar for the axis label
+)
+
+// newCostChart lays out one bar per iteration, or returns nil when no
+// iteration has a cost.
+func newCostChart(its []Iteration, cur pricing.Currency) *costChart {
+	peak := 0.0
+	for i := range its {
+		peak = max(peak, its[i].Cost)
+	}
+	if peak == 0 {
+		return nil
+	}
+	c := &costChart{Width: chartWidth, Height: chartHeight, Max: cur.Format(peak, 2)}
+	slot := float64(chartWidth) / float64(len(its))
+	plot := float64(chartHeight - chartLabel - chartTop)
+	for i := range its {
+		h := its[i].Cost / peak * plot
+		c.Bars = append(c.Bars, costBar{
+			X:      float64(i)*slot + slot*0.15,
+			Y:      chartTop + plot - h,
+			W:      slot * 0.7,
+			H:      h,
+			LabelX: float64(i)*slot + slot/2,
+			Label:  strconv.Itoa(its[i].Number),
+			Value:  cur.Format(its[i].Cost, 4),
+		})
+	}
+	return c
+}
@@ -0,0 +1,212 @@
+// Package report renders a recorded run as a standalone HTML page, for
+// sharing with people who don't follow runs in the terminal.
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/status"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+	"github.com/benwilkes9/ralph-cli/internal/testresult"
+)
+
+// Report is everything shown on a run's page.
+type Report struct {
+	Project    string             `json:"project,omitempty"`
+	Number     int                `json:"number"` // position in `ralph status --history`, 1 = oldest
+	Run        state.RunRecord    `json:"run"`
+	Iterations []Iteration        `json:"iterations"`
+	Commits    []git.LoggedCommit `json:"commits"` // commits made during the run, newest first
+	Generated  time.Time          `json:"generated"`
+}
+
+// Iteration is one log file of the run.
+type Iteration struct {
+	Number      int                `json:"number"`
+	Log         string             `json:"log"` // log file name
+	Started     time.Time          `json:"started"`
+	Duration    time.Duration      `json:"duration_ns"`
+	Cost        float64            `json:"cost"`
+	PeakContext int                `json:"peak_context"`
+	Tools       []ToolCall         `json:"tools"`
+	Failed      int                `json:"failed"`            // tool calls that returned an error
+	Tests       *testresult.Counts `json:"tests,omitempty"`   // last test summary seen; nil if none
+	Missing     bool               `json:"missing,omitempty"` // the log file no longer exists
+}
+
+// ToolCall is one tool invocation by the agent.
+type ToolCall struct {
+	Name   string `json:"name"`
+	Detail string `json:"detail"` // file path, command or other key input, as in the terminal
+	Failed bool   `json:"failed,omitempty"`
+}
+
+// Iterations reads the run's log files, which are recorded relative to the
+// repo root; a log not found there is looked for in .ralph/logs. Logs since
+// deleted are returned marked Missing so the timeline keeps its shape.
+func Iterations(repoRoot string, run *state.RunRecord) ([]Iteration, error) {
+	out := make([]Iteration, 0, len(run.LogFiles))
+	for i, rel := range run.LogFiles {
+		it := Iteration{Number: run.LogIteration(i), Log: filepath.Base(rel)}
+		path := filepath.Join(repoRoot, rel)
+		fi, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			path = filepath.Join(repoRoot, ".ralph", "logs", it.Log)
+			fi, err = os.Stat(path)
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			it.Missing = true
+			out = append(out, it)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading log %s: %w", it.Log, err)
+		}
+		if err := readLog(path, &it); err != nil {
+			return nil, err
+		}
+		it.Started = fi.ModTime()
+		if t, err := status.LogStartTime(it.Log); err == nil {
+			it.Started = t
+		}
+		it.Duration = fi.ModTime().Sub(it.Started)
+		if it.Cost == 0 {
+			// The loop caches a priced cost when the API reported none.
+			if e, ok := status.LoadIndex(filepath.Dir(path)).Logs[it.Log]; ok {
+				it.Cost = e.Cost
+			}
+		}
+		out = append(out, it)
+	}
+	return out, nil
+}
+
+// readLog fills it from a stream-json log. A malformed line ends the log
+// early rather than failing the report.
+func readLog(path string, it *Iteration) error {
+	f, err := os.Open(path) //nolint:gosec // log file recorded in state.json
+	if err != nil {
+		return fmt.Errorf("opening log %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close() //nolint:errcheck // read-only
+
+	var stats stream.IterationStats
+	byID := map[string]int{}
+	p := stream.NewParser(f)
+	for {
+		evt, err := p.Next()
+		if err != nil {
+			break // EOF, or a truncated line at the end of a crashed iteration
+		}
+		switch evt.Type {
+		case "assistant":
+			if evt.Message == nil {
+				continue
+			}
+			stats.ObserveAssistant(evt.Message.Usage)
+			for _, b := range evt.Message.Content {
+				if b.Type == "tool_use" {
+					byID[b.ID] = len(it.Tools)
+					it.Tools = append(it.Tools, ToolCall{Name: b.Name, Detail: stream.ToolParam(b.Input)})
+				}
+			}
+		case "user":
+			if evt.ToolUseResult != nil {
+				stats.ObserveCommandOutput(evt.ToolUseResult.Stdout, evt.ToolUseResult.Stderr)
+			}
+			if evt.Message == nil {
+				continue
+			}
+			for _, b := range evt.Message.Content {
+				if i, ok := byID[b.ToolUseID]; ok && b.Type == "tool_result" && b.IsError && !it.Tools[i].Failed {
+					it.Tools[i].Failed = true
+					it.Failed++
+				}
+			}
+		case "result":
+			stats.ObserveResult(evt.TotalCostUSD)
+		}
+	}
+	it.Cost = stats.Cost
+	it.PeakContext = stats.PeakContext
+	it.Tests = stats.Tests
+	return nil
+}
+
+// ToolCount totals the calls to one tool across a run.
+type ToolCount struct {
+	Name   string
+	Calls  int
+	Failed int
+}
+
+// ToolCounts returns per-tool totals, most used first.
+func (r *Report) ToolCounts() []ToolCount {
+	byName := map[string]*ToolCount{}
+	for i := range r.Iterations {
+		for _, t := range r.Iterations[i].Tools {
+			c, ok := byName[t.Name]
+			if !ok {
+				c = &ToolCount{Name: t.Name}
+				byName[t.Name] = c
+			}
+			c.Calls++
+			if t.Failed {
+				c.Failed++
+			}
+		}
+	}
+	out := make([]ToolCount, 0, len(byName))
+	for _, c := range byName {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Calls != out[j].Calls {
+			return out[i].Calls > out[j].Calls
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// DiffTotals sums a run's commits.
+type DiffTotals struct {
+	Files      int // distinct files changed
+	Insertions int
+	Deletions  int
+}
+
+// Diff totals the changes made by the run's commits.
+func (r *Report) Diff() DiffTotals {
+	var d DiffTotals
+	seen := map[string]bool{}
+	for _, c := range r.Commits {
+		for _, f := range c.Files {
+			seen[f] = true
+		}
+		d.Insertions += c.Insertions
+		d.Deletions += c.Deletions
+	}
+	d.Files = len(seen)
+	return d
+}
+
+// JSON writes r as indented JSON, the machine-readable companion of the
+// HTML page.
+func JSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	return nil
+}
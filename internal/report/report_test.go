@@ -0,0 +1,137 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+const iterationLog = `{"type":"system","subtype":"init"}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/workspace/repo/main.go"}}],"usage":{"input_tokens":10,"cache_creation_input_tokens":2000,"cache_read_input_tokens":0,"output_tokens":5}}}
+{"type":"user","message":{"role":"user","content":[{"tool_use_id":"t1","type":"tool_result","content":"package main"}]}}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test ./..."}}]}}
+{"type":"user","message":{"role":"user","content":[{"tool_use_id":"t2","type":"tool_result","content":"exit 1","is_error":true}]},"tool_use_result":{"stdout":"--- FAIL: TestX\nFAIL\tpkg\t0.1s\nok  \tother\t0.2s\n"}}
+{"type":"result","total_cost_usd":0.42}
+`
+
+func writeLog(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	logs := filepath.Join(dir, "logs")
+	require.NoError(t, os.MkdirAll(logs, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(logs, name), []byte(content), 0o600))
+	return "logs/" + name
+}
+
+func TestIterations(t *testing.T) {
+	dir := t.TempDir()
+	run := &state.RunRecord{LogFiles: []string{
+		writeLog(t, dir, "20260301-101500.jsonl", iterationLog),
+		"logs/20260301-102000.jsonl", // deleted since
+	}}
+
+	its, err := Iterations(dir, run)
+	require.NoError(t, err)
+	require.Len(t, its, 2)
+
+	it := its[0]
+	assert.Equal(t, 1, it.Number)
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC), it.Started)
+	assert.InDelta(t, 0.42, it.Cost, 1e-9)
+	assert.Equal(t, 2010, it.PeakContext)
+	assert.Equal(t, []ToolCall{
+		{Name: "Read", Detail: "/workspace/repo/main.go"},
+		{Name: "Bash", Detail: "go test ./...", Failed: true},
+	}, it.Tools)
+	assert.Equal(t, 1, it.Failed)
+	require.NotNil(t, it.Tests)
+
+	assert.True(t, its[1].Missing)
+	assert.Equal(t, 2, its[1].Number)
+}
+
+func TestIterations_FallsBackToRalphLogs(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, filepath.Join(dir, ".ralph"), "20260301-101500.jsonl", iterationLog)
+
+	its, err := Iterations(dir, &state.RunRecord{LogFiles: []string{"logs/20260301-101500.jsonl"}})
+	require.NoError(t, err)
+	require.Len(t, its, 1)
+	assert.False(t, its[0].Missing)
+	assert.Len(t, its[0].Tools, 2)
+}
+
+func TestToolCountsAndDiff(t *testing.T) {
+	r := &Report{
+		Iterations: []Iteration{
+			{Tools: []ToolCall{{Name: "Bash"}, {Name: "Read"}, {Name: "Bash", Failed: true}}},
+			{Tools: []ToolCall{{Name: "Edit"}, {Name: "Read"}}},
+		},
+		Commits: []git.LoggedCommit{
+			{Files: []string{"a.go", "b.go"}, Insertions: 10, Deletions: 1},
+			{Files: []string{"a.go"}, Insertions: 2, Deletions: 3},
+		},
+	}
+	assert.Equal(t, []ToolCount{
+		{Name: "Bash", Calls: 2, Failed: 1},
+		{Name: "Read", Calls: 2},
+		{Name: "Edit", Calls: 1},
+	}, r.ToolCounts())
+	assert.Equal(t, DiffTotals{Files: 2, Insertions: 12, Deletions: 4}, r.Diff())
+}
+
+func TestHTML(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+	r := &Report{
+		Project: "demo",
+		Number:  3,
+		Run: state.RunRecord{
+			Mode: "build", StartedAt: start, FinishedAt: start.Add(12 * time.Minute),
+			Iterations: 2, TotalCost: 1.5, Status: state.StatusStaleAbort,
+			Note: "try <b>new</b> prompt", ClaudeFailures: 1,
+			Origin: &state.Origin{Repo: "acme/demo", Workspace: "/src/demo"},
+		},
+		Iterations: []Iteration{
+			{Number: 1, Started: start, Duration: 5 * time.Minute, Cost: 1, Tools: []ToolCall{{Name: "Bash", Detail: "make test", Failed: true}}, Failed: 1},
+			{Number: 2, Missing: true, Log: "20260301-102000.jsonl"},
+		},
+		Commits:   []git.LoggedCommit{{SHA: "abc1234", Subject: "feat: add widget", Files: []string{"w.go"}, Insertions: 40, Deletions: 2}},
+		Generated: start.Add(time.Hour),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, HTML(&buf, r, pricing.Currency{Code: "EUR", PerUSD: 0.5}))
+	page := buf.String()
+
+	assert.Contains(t, page, "<title>Ralph build run #3 · demo</title>")
+	assert.Contains(t, page, "<code>acme/demo</code>")
+	assert.Contains(t, page, "stale abort")
+	assert.Contains(t, page, "€0.75", "costs are shown in the display currency")
+	assert.Contains(t, page, "12m 0s")
+	assert.Contains(t, page, "claude failures")
+	assert.Contains(t, page, "try &lt;b&gt;new&lt;/b&gt; prompt", "run labels are escaped")
+	assert.Contains(t, page, "20260301-102000.jsonl</code> no longer exists")
+	assert.Contains(t, page, "<rect ")
+	assert.Contains(t, page, "make test")
+	assert.Contains(t, page, "feat: add widget")
+	assert.Contains(t, page, "+40")
+	assert.NotContains(t, page, "<script")
+}
+
+func TestHTML_EmptyRun(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, HTML(&buf, &Report{Number: 1, Run: state.RunRecord{Mode: "plan", Status: state.StatusContainerCrash}}, pricing.Currency{}))
+	page := buf.String()
+	assert.Contains(t, page, "No iteration logs were recorded")
+	assert.Contains(t, page, "No cost was recorded")
+	assert.Contains(t, page, "No commits were made")
+	assert.Contains(t, page, `class="status bad"`)
+}
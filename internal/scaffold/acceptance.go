@@ -0,0 +1,179 @@
+package scaffold
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrNoTestFramework is returned by AcceptanceTests for projects whose test
+// framework wasn't detected.
+var ErrNoTestFramework = errors.New("no test framework detected")
+
+// TestSkeleton is a generated acceptance test file.
+type TestSkeleton struct {
+	Path    string // relative to the repo root
+	Content string
+	Tests   int
+}
+
+// AcceptanceTests renders a test file with one failing test per criterion
+// of the spec at specPath, in the project's test framework. The tests fail
+// until the behaviour they name is implemented, so the build loop's test
+// backpressure keeps them in view.
+func AcceptanceTests(repoRoot string, info *ProjectInfo, specPath string, criteria []string) (*TestSkeleton, error) {
+	if len(criteria) == 0 {
+		return nil, errors.New("no acceptance criteria to generate tests from")
+	}
+	base := strings.TrimSuffix(filepath.Base(specPath), filepath.Ext(specPath))
+	slug := strings.Join(words(base), "_")
+	if slug == "" {
+		slug = "spec"
+	}
+	header := fmt.Sprintf("Acceptance tests for %s, generated by `ralph spec tests`.", filepath.ToSlash(specPath))
+
+	var b strings.Builder
+	var path string
+	names := map[string]bool{}
+	switch info.Language { //nolint:exhaustive // unknown is an error
+	case LangPython:
+		path = filepath.Join(testDir(info, "tests"), "test_"+slug+"_acceptance.py")
+		fmt.Fprintf(&b, "\"\"\"%s\n\nEach test fails until its criterion is met.\n\"\"\"\n\nimport pytest\n", header)
+		for _, c := range criteria {
+			fmt.Fprintf(&b, "\n\n# %s\ndef test_%s():\n    pytest.fail(%s)\n", c, unique(names, strings.Join(words(c), "_")), strconv.Quote("not implemented: "+c))
+		}
+	case LangGo:
+		path = filepath.Join("acceptance", slug+"_test.go")
+		fmt.Fprintf(&b, "// %s\n// Each test fails until its criterion is met.\n\npackage acceptance\n\nimport \"testing\"\n", header)
+		// Every spec's tests share the package, so names carry the spec.
+		prefix := camel(words(base))
+		for _, c := range criteria {
+			fmt.Fprintf(&b, "\n// %s\nfunc Test%s_%s(t *testing.T) {\n\tt.Fatal(%s)\n}\n", c, prefix, unique(names, camel(words(c))), strconv.Quote("not implemented: "+c))
+		}
+	case LangRust:
+		path = filepath.Join("tests", slug+"_acceptance.rs")
+		fmt.Fprintf(&b, "//! %s\n//! Each test fails until its criterion is met.\n", header)
+		for _, c := range criteria {
+			// todo! takes a format string, so braces are doubled.
+			msg := strings.NewReplacer("{", "{{", "}", "}}").Replace("not implemented: " + c)
+			fmt.Fprintf(&b, "\n/// %s\n#[test]\nfn %s() {\n    todo!(%s);\n}\n", c, unique(names, rustIdent(words(c))), strconv.Quote(msg))
+		}
+	case LangNode:
+		framework := nodeTestFramework(repoRoot)
+		ext, imports := ".test.js", ""
+		switch framework {
+		case "vitest":
+			imports = "import { test } from \"vitest\";\n\n"
+		case "jest":
+			// test is a global.
+		default:
+			ext, imports = ".test.mjs", "import { test } from \"node:test\";\n\n"
+		}
+		path = filepath.Join(testDir(info, "test"), slug+".acceptance"+ext)
+		fmt.Fprintf(&b, "// %s\n// Each test fails until its criterion is met.\n\n%s", header, imports)
+		for i, c := range criteria {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "test(%s, () => {\n  throw new Error(%s);\n});\n", strconv.Quote(c), strconv.Quote("not implemented: "+c))
+		}
+	default:
+		return nil, fmt.Errorf("%w for this project; supported: python, go, rust and node", ErrNoTestFramework)
+	}
+	return &TestSkeleton{Path: path, Content: b.String(), Tests: len(criteria)}, nil
+}
+
+// testDir returns the project's first detected test directory, or fallback.
+func testDir(info *ProjectInfo, fallback string) string {
+	if len(info.TestDirs) > 0 {
+		return info.TestDirs[0]
+	}
+	return fallback
+}
+
+// nodeTestFramework returns "vitest" or "jest" when package.json depends on
+// one, or "" for the built-in node:test runner.
+func nodeTestFramework(repoRoot string) string {
+	data, err := os.ReadFile(filepath.Join(repoRoot, "package.json")) //nolint:gosec // fixed path under the repo root
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	for _, fw := range []string{"vitest", "jest"} {
+		if _, ok := pkg.DevDependencies[fw]; ok {
+			return fw
+		}
+		if _, ok := pkg.Dependencies[fw]; ok {
+			return fw
+		}
+	}
+	return ""
+}
+
+var nonWord = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// maxNameWords keeps generated test names readable.
+const maxNameWords = 8
+
+// words splits text into lowercase identifier words, dropping a leading
+// digit so the result is a valid identifier in every supported language.
+func words(text string) []string {
+	ws := strings.Fields(strings.ToLower(nonWord.ReplaceAllString(text, " ")))
+	if len(ws) > maxNameWords {
+		ws = ws[:maxNameWords]
+	}
+	if len(ws) > 0 && ws[0][0] >= '0' && ws[0][0] <= '9' {
+		ws[0] = "n" + ws[0]
+	}
+	return ws
+}
+
+// rustKeywords are the words that can't name a function on their own.
+var rustKeywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true, "continue": true, "crate": true,
+	"dyn": true, "else": true, "enum": true, "extern": true, "false": true, "fn": true, "for": true, "if": true,
+	"impl": true, "in": true, "let": true, "loop": true, "match": true, "mod": true, "move": true, "mut": true,
+	"pub": true, "ref": true, "return": true, "self": true, "static": true, "struct": true, "super": true,
+	"trait": true, "true": true, "type": true, "unsafe": true, "use": true, "where": true, "while": true,
+}
+
+func rustIdent(ws []string) string {
+	name := strings.Join(ws, "_")
+	if rustKeywords[name] {
+		name += "_works"
+	}
+	return name
+}
+
+func camel(ws []string) string {
+	var b strings.Builder
+	for _, w := range ws {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+// unique returns name, or name with a numeric suffix if it was already
+// used in this file, and records it. An empty name becomes "criterion".
+func unique(used map[string]bool, name string) string {
+	if name == "" {
+		name = "criterion"
+	}
+	candidate := name
+	for n := 2; used[candidate]; n++ {
+		candidate = name + strconv.Itoa(n)
+	}
+	used[candidate] = true
+	return candidate
+}
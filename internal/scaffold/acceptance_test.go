@@ -0,0 +1,77 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptanceTests_Go(t *testing.T) {
+	info := &ProjectInfo{Language: LangGo}
+	sk, err := AcceptanceTests(t.TempDir(), info, "specs/feat/user-login.md", []string{
+		"Users can log in",
+		"Users can log in!",
+		"2FA codes expire",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("acceptance", "user_login_test.go"), sk.Path)
+	assert.Equal(t, 3, sk.Tests)
+	assert.Contains(t, sk.Content, "package acceptance")
+	assert.Contains(t, sk.Content, "specs/feat/user-login.md")
+	assert.Contains(t, sk.Content, "func TestUserLogin_UsersCanLogIn(t *testing.T) {")
+	assert.Contains(t, sk.Content, "func TestUserLogin_UsersCanLogIn2(t *testing.T) {")
+	assert.Contains(t, sk.Content, "func TestUserLogin_N2faCodesExpire(t *testing.T) {")
+	assert.Contains(t, sk.Content, `t.Fatal("not implemented: Users can log in")`)
+}
+
+func TestAcceptanceTests_Python(t *testing.T) {
+	info := &ProjectInfo{Language: LangPython, TestDirs: []string{"tests/unit"}}
+	sk, err := AcceptanceTests(t.TempDir(), info, "specs/auth.md", []string{`Rejects "bad" tokens`})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("tests/unit", "test_auth_acceptance.py"), sk.Path)
+	assert.Contains(t, sk.Content, "import pytest")
+	assert.Contains(t, sk.Content, "def test_rejects_bad_tokens():")
+	assert.Contains(t, sk.Content, `pytest.fail("not implemented: Rejects \"bad\" tokens")`)
+}
+
+func TestAcceptanceTests_Rust(t *testing.T) {
+	info := &ProjectInfo{Language: LangRust}
+	sk, err := AcceptanceTests(t.TempDir(), info, "specs/cli.md", []string{"Match {braces}", "Loop"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("tests", "cli_acceptance.rs"), sk.Path)
+	assert.Contains(t, sk.Content, "fn match_braces() {")
+	assert.Contains(t, sk.Content, `todo!("not implemented: Match {{braces}}");`)
+	assert.Contains(t, sk.Content, "fn loop_works() {")
+}
+
+func TestAcceptanceTests_NodeFrameworks(t *testing.T) {
+	tests := []struct {
+		name, pkg, path, want string
+	}{
+		{"vitest", `{"devDependencies":{"vitest":"^1"}}`, "test/api.acceptance.test.js", `import { test } from "vitest";`},
+		{"jest", `{"dependencies":{"jest":"^29"}}`, "test/api.acceptance.test.js", `test("Lists users", () => {`},
+		{"node:test", `{}`, "test/api.acceptance.test.mjs", `import { test } from "node:test";`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(tt.pkg), 0o600))
+			sk, err := AcceptanceTests(dir, &ProjectInfo{Language: LangNode}, "specs/api.md", []string{"Lists users"})
+			require.NoError(t, err)
+			assert.Equal(t, filepath.FromSlash(tt.path), sk.Path)
+			assert.Contains(t, sk.Content, tt.want)
+			assert.Contains(t, sk.Content, `throw new Error("not implemented: Lists users");`)
+		})
+	}
+}
+
+func TestAcceptanceTests_Errors(t *testing.T) {
+	_, err := AcceptanceTests(t.TempDir(), &ProjectInfo{Language: LangUnknown}, "specs/x.md", []string{"a"})
+	require.ErrorIs(t, err, ErrNoTestFramework)
+
+	_, err = AcceptanceTests(t.TempDir(), &ProjectInfo{Language: LangGo}, "specs/x.md", nil)
+	require.Error(t, err)
+}
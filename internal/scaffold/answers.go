@@ -0,0 +1,49 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// previousAnswers is the subset of .ralph/config.yaml written from the init
+// prompts.
+type previousAnswers struct {
+	Agent         string `yaml:"agent"`
+	SpecsDir      string `yaml:"specs_dir"`
+	SpecsDirExact bool   `yaml:"specs_dir_exact"`
+	Init          struct {
+		RunCmd string `yaml:"run_cmd"`
+		Goal   string `yaml:"goal"`
+	} `yaml:"init"`
+}
+
+// LoadPreviousAnswers copies the agent, run command, goal and specs
+// directory from an existing .ralph/config.yaml into info, so RunPrompts
+// offers them as the defaults. It reports whether a config was found. An unreadable or invalid
+// config is ignored: init then behaves as on a fresh repo.
+func LoadPreviousAnswers(repoRoot string, info *ProjectInfo) bool {
+	data, err := os.ReadFile(filepath.Join(repoRoot, ".ralph", "config.yaml")) //nolint:gosec // fixed path under the repo root
+	if err != nil {
+		return false
+	}
+	var prev previousAnswers
+	if err := yaml.Unmarshal(data, &prev); err != nil {
+		return false
+	}
+	if prev.Agent != "" {
+		info.Agent = prev.Agent
+	}
+	if prev.Init.RunCmd != "" {
+		info.RunCmd = prev.Init.RunCmd
+	}
+	if prev.Init.Goal != "" {
+		info.Goal = prev.Init.Goal
+	}
+	if prev.SpecsDir != "" {
+		info.SpecsDir = prev.SpecsDir
+		info.SpecsDirExact = prev.SpecsDirExact
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPreviousAnswers_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	info := &ProjectInfo{
+		ProjectName:    "myapp",
+		Language:       LangPython,
+		PackageManager: PmUV,
+		SpecsDir:       "my/specs",
+		SpecsDirExact:  true,
+		TestCmd:        "uv run pytest",
+		RunCmd:         `uv run python -m myapp --name "demo"`,
+		Goal:           "A CLI: fast, tested #1",
+		BaseImage:      "node:22-bookworm",
+	}
+	_, err := Generate(dir, "feat", info, false)
+	require.NoError(t, err)
+
+	got := &ProjectInfo{SpecsDir: "specs"}
+	require.True(t, LoadPreviousAnswers(dir, got))
+	assert.Equal(t, info.RunCmd, got.RunCmd)
+	assert.Equal(t, info.Goal, got.Goal)
+	assert.Equal(t, "my/specs", got.SpecsDir)
+	assert.True(t, got.SpecsDirExact)
+}
+
+func TestLoadPreviousAnswers_NoConfig(t *testing.T) {
+	info := &ProjectInfo{SpecsDir: "specs"}
+	assert.False(t, LoadPreviousAnswers(t.TempDir(), info))
+	assert.Equal(t, "specs", info.SpecsDir)
+}
+
+func TestLoadPreviousAnswers_InvalidConfigIgnored(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".ralph"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".ralph", "config.yaml"), []byte("init: [unclosed\n"), 0o600))
+
+	info := &ProjectInfo{SpecsDir: "specs"}
+	assert.False(t, LoadPreviousAnswers(dir, info))
+	assert.Equal(t, "specs", info.SpecsDir)
+}
@@ -0,0 +1,107 @@
+package scaffold
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/charmbracelet/huh"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// CIGitHub is the only CI provider init can generate a workflow for.
+const CIGitHub = "github"
+
+// ciWorkflows maps a CI provider to its template and output path.
+var ciWorkflows = map[string]struct{ tmpl, output string }{
+	CIGitHub: {"templates/ci/github.yml.tmpl", ".github/workflows/ralph.yml"},
+}
+
+// DefaultCIMaxIterations caps a CI run unless the workflow is run by hand
+// with another value.
+const DefaultCIMaxIterations = 20
+
+// ValidCI reports whether provider is a CI provider GenerateCI supports.
+func ValidCI(provider string) bool {
+	_, ok := ciWorkflows[provider]
+	return ok
+}
+
+// GenerateCI writes the workflow that runs ralph build in the given CI
+// provider, recording it on result. An existing workflow is skipped unless
+// force is true. The template uses [[ ]] delimiters so the workflow's own
+// ${{ }} expressions pass through.
+func GenerateCI(repoRoot, provider string, info *ProjectInfo, force bool, result *GenerateResult) error {
+	wf, ok := ciWorkflows[provider]
+	if !ok {
+		return fmt.Errorf("unknown CI provider %q (want %s)", provider, CIGitHub)
+	}
+	outputPath := filepath.Join(repoRoot, wf.output)
+	exists := fileExists(outputPath)
+	if exists && !force {
+		result.Skipped = append(result.Skipped, wf.output)
+		return nil
+	}
+
+	tmplContent, err := templateFS.ReadFile(wf.tmpl)
+	if err != nil {
+		return fmt.Errorf("reading template %s: %w", wf.tmpl, err)
+	}
+	tmpl, err := template.New(filepath.Base(wf.tmpl)).Delims("[[", "]]").Parse(string(tmplContent))
+	if err != nil {
+		return fmt.Errorf("parsing template %s: %w", wf.tmpl, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o750); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", wf.output, err)
+	}
+	data := struct {
+		ProjectName   string
+		MaxIterations int
+	}{info.ProjectName, DefaultCIMaxIterations}
+	if err := renderToFile(outputPath, tmpl, data); err != nil {
+		return fmt.Errorf("rendering %s: %w", wf.output, err)
+	}
+
+	if exists {
+		result.Overwritten = append(result.Overwritten, wf.output)
+	} else {
+		result.Created = append(result.Created, wf.output)
+	}
+	return nil
+}
+
+// AskCI offers to generate a GitHub Actions workflow.
+func AskCI(opts *PromptOptions) (bool, error) {
+	var want bool
+	form := huh.NewForm(huh.NewGroup(
+		huh.NewConfirm().
+			Title("Generate a GitHub Actions workflow that runs ralph build in CI?").
+			Value(&want),
+	)).WithAccessible(opts.Accessible).
+		WithTheme(ui.HuhTheme())
+	if opts.In != nil {
+		form = form.WithInput(opts.In)
+	}
+	if opts.Out != nil {
+		form = form.WithOutput(opts.Out)
+	}
+	if err := form.Run(); err != nil {
+		return false, err //nolint:wrapcheck // propagate huh errors directly
+	}
+	return want, nil
+}
+
+// PrintCISecrets lists the secrets the generated workflow expects.
+//
+//nolint:errcheck // display-only writes
+func PrintCISecrets(w io.Writer, theme *ui.Theme) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, theme.NextSteps.Render("GitHub Actions\n\n"+
+		"  1. Add repository secrets: ANTHROPIC_API_KEY (or CLAUDE_CODE_OAUTH_TOKEN)\n"+
+		"     and RALPH_GITHUB_PAT, a token with contents:write\n"+
+		"  2. Commit .github/workflows/ralph.yml to the default branch\n"+
+		"  3. Label a pull request \"ralph\" or run the workflow by hand"))
+}
@@ -0,0 +1,51 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCI_GitHub(t *testing.T) {
+	dir := t.TempDir()
+	result := &GenerateResult{}
+
+	require.NoError(t, GenerateCI(dir, CIGitHub, &ProjectInfo{ProjectName: "todo-api"}, false, result))
+	assert.Equal(t, []string{".github/workflows/ralph.yml"}, result.Created)
+
+	data, err := os.ReadFile(filepath.Join(dir, ".github", "workflows", "ralph.yml"))
+	require.NoError(t, err)
+	wf := string(data)
+	assert.Contains(t, wf, "ralph build for todo-api")
+	assert.Contains(t, wf, `ralph build --max "${{ inputs.max || '20' }}"`)
+	assert.Contains(t, wf, "GITHUB_PAT: ${{ secrets.RALPH_GITHUB_PAT }}")
+	assert.NotContains(t, wf, "[[")
+}
+
+func TestGenerateCI_SkipsExisting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".github", "workflows", "ralph.yml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, []byte("custom"), 0o600))
+
+	result := &GenerateResult{}
+	require.NoError(t, GenerateCI(dir, CIGitHub, &ProjectInfo{}, false, result))
+	assert.Equal(t, []string{".github/workflows/ralph.yml"}, result.Skipped)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "custom", string(data))
+
+	result = &GenerateResult{}
+	require.NoError(t, GenerateCI(dir, CIGitHub, &ProjectInfo{}, true, result))
+	assert.Equal(t, []string{".github/workflows/ralph.yml"}, result.Overwritten)
+}
+
+func TestGenerateCI_UnknownProvider(t *testing.T) {
+	err := GenerateCI(t.TempDir(), "gitlab", &ProjectInfo{}, false, &GenerateResult{})
+	require.Error(t, err)
+	assert.False(t, ValidCI("gitlab"))
+	assert.True(t, ValidCI(CIGitHub))
+}
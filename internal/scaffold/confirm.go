@@ -0,0 +1,148 @@
+package scaffold
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// DetectionSummary lists what Detect found, one "label  value" line each,
+// with "-" for anything it couldn't work out.
+func DetectionSummary(info *ProjectInfo) string {
+	rows := []struct{ label, value string }{
+		{"Language", string(info.Language)},
+		{"Package manager", string(info.PackageManager)},
+		{"Version", info.LanguageVersion},
+		{"Install", info.InstallCmd},
+		{"Test", info.TestCmd},
+		{"Typecheck", info.TypecheckCmd},
+		{"Lint", info.LintCmd},
+		{"Source dirs", strings.Join(info.SourceDirs, ", ")},
+		{"Test dirs", strings.Join(info.TestDirs, ", ")},
+	}
+	var b strings.Builder
+	for _, r := range rows {
+		value := r.value
+		if value == "" || value == string(LangUnknown) {
+			value = "-"
+		}
+		fmt.Fprintf(&b, "%-16s %s\n", r.label, value)
+	}
+	return b.String()
+}
+
+// PrintDetection shows what Detect found, for ConfirmDetection to ask about.
+//
+//nolint:errcheck // display-only writes
+func PrintDetection(w io.Writer, info *ProjectInfo, theme *ui.Theme) {
+	fmt.Fprintln(w, theme.Info.Render("Detected"))
+	fmt.Fprintln(w, DetectionSummary(info))
+}
+
+// ConfirmDetection asks whether the detected settings are right. If not,
+// the user picks the language and package manager, which recomputes the
+// ecosystem defaults as Override does, and then edits the version, commands
+// and directories, prefilled with the detected values.
+func ConfirmDetection(repoRoot string, info *ProjectInfo, opts *PromptOptions) error {
+	ok := true
+	if err := runForm(opts, huh.NewGroup(
+		huh.NewConfirm().
+			Title("Use these detected settings?").
+			Affirmative("Yes").
+			Negative("Edit").
+			Value(&ok),
+	)); err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	pm := info.PackageManager
+	if err := runForm(opts, huh.NewGroup(
+		huh.NewSelect[PackageManager]().
+			Title("Language and package manager").
+			Options(toolchainOptions()...).
+			Value(&pm),
+	)); err != nil {
+		return err
+	}
+	if pm != info.PackageManager {
+		if err := Override(repoRoot, info, "", pm); err != nil {
+			return err
+		}
+	}
+
+	sourceDirs := strings.Join(info.SourceDirs, ", ")
+	testDirs := strings.Join(info.TestDirs, ", ")
+	if err := runForm(opts, huh.NewGroup(
+		huh.NewInput().Title("Language version").Value(&info.LanguageVersion),
+		huh.NewInput().Title("Install command").Value(&info.InstallCmd),
+		huh.NewInput().Title("Test command").Value(&info.TestCmd),
+		huh.NewInput().Title("Typecheck command").Value(&info.TypecheckCmd),
+		huh.NewInput().Title("Lint command").Value(&info.LintCmd),
+		huh.NewInput().Title("Source dirs (comma-separated)").Value(&sourceDirs),
+		huh.NewInput().Title("Test dirs (comma-separated)").Value(&testDirs),
+	)); err != nil {
+		return err
+	}
+	info.LanguageVersion = strings.TrimSpace(info.LanguageVersion)
+	if info.Language == LangGo && info.LanguageVersion != "" {
+		info.GoVersion = info.LanguageVersion
+	}
+	info.SourceDirs = splitList(sourceDirs)
+	info.TestDirs = splitList(testDirs)
+	return nil
+}
+
+// toolchainOptions offers every supported package manager, labelled with
+// its language, e.g. "python · uv".
+func toolchainOptions() []huh.Option[PackageManager] {
+	pms := make([]PackageManager, 0, len(packageManagerLanguages))
+	for pm := range packageManagerLanguages {
+		pms = append(pms, pm)
+	}
+	sort.Slice(pms, func(i, j int) bool {
+		li, lj := packageManagerLanguages[pms[i]], packageManagerLanguages[pms[j]]
+		if li != lj {
+			return li < lj
+		}
+		return pms[i] < pms[j]
+	})
+	opts := make([]huh.Option[PackageManager], len(pms))
+	for i, pm := range pms {
+		opts[i] = huh.NewOption(fmt.Sprintf("%s · %s", packageManagerLanguages[pm], pm), pm)
+	}
+	return opts
+}
+
+// splitList splits a comma-separated list, dropping blank entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// runForm runs a single-group form with the prompt options' input, output
+// and accessibility.
+func runForm(opts *PromptOptions, group *huh.Group) error {
+	form := huh.NewForm(group).
+		WithAccessible(opts.Accessible).
+		WithTheme(ui.HuhTheme())
+	if opts.In != nil {
+		form = form.WithInput(opts.In)
+	}
+	if opts.Out != nil {
+		form = form.WithOutput(opts.Out)
+	}
+	return form.Run() //nolint:wrapcheck // propagate huh errors directly
+}
@@ -0,0 +1,78 @@
+package scaffold
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+func goRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module tools\n\ngo 1.24\n"), 0o600))
+	return dir
+}
+
+func TestPrintDetection(t *testing.T) {
+	info := Detect(goRepo(t))
+
+	var buf bytes.Buffer
+	PrintDetection(&buf, info, ui.DefaultTheme())
+	out := buf.String()
+	assert.Contains(t, out, "Language         go")
+	assert.Contains(t, out, "Version          1.24")
+	assert.Contains(t, out, "Test             go test ./...")
+	assert.Contains(t, out, "Test dirs        -")
+}
+
+func TestConfirmDetection_Accept(t *testing.T) {
+	info := Detect(goRepo(t))
+	before := *info
+
+	err := ConfirmDetection("", info, &PromptOptions{
+		In: &byteReader{strings.NewReader("y\n")}, Out: &bytes.Buffer{}, Accessible: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, before, *info)
+}
+
+func TestConfirmDetection_Edit(t *testing.T) {
+	dir := goRepo(t)
+	info := Detect(dir)
+
+	// Edit, pick node · pnpm (option 5), keep the version and install
+	// command, replace the test command, keep typecheck and lint, and list
+	// the source dirs.
+	input := "n\n5\n\n\npnpm vitest run\n\n\nsrc, lib ,\n\n"
+	err := ConfirmDetection(dir, info, &PromptOptions{
+		In: &byteReader{strings.NewReader(input)}, Out: &bytes.Buffer{}, Accessible: true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, LangNode, info.Language)
+	assert.Equal(t, PmPNPM, info.PackageManager)
+	assert.Equal(t, "pnpm vitest run", info.TestCmd)
+	assert.Equal(t, "pnpm install", info.InstallCmd, "defaults come from the chosen package manager")
+	assert.Equal(t, []string{"src", "lib"}, info.SourceDirs)
+	assert.Empty(t, info.TestDirs)
+}
+
+func TestConfirmDetection_EditGoVersion(t *testing.T) {
+	dir := goRepo(t)
+	info := Detect(dir)
+
+	err := ConfirmDetection(dir, info, &PromptOptions{
+		In: &byteReader{strings.NewReader("n\n1\n1.26\n\n\n\n\n\n\n")}, Out: &bytes.Buffer{}, Accessible: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1.26", info.LanguageVersion)
+	assert.Equal(t, "1.26", info.GoVersion)
+	assert.Equal(t, "go test ./...", info.TestCmd)
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/agent"
 )
 
 // safeVersion matches version strings that are safe to interpolate into shell
@@ -23,6 +25,7 @@ const (
 	LangNode    Language = "node"
 	LangGo      Language = "go"
 	LangRust    Language = "rust"
+	LangJava    Language = "java"
 	LangUnknown Language = "unknown"
 )
 
@@ -38,6 +41,8 @@ const (
 	PmPNPM    PackageManager = "pnpm"
 	PmGo      PackageManager = "go"
 	PmCargo   PackageManager = "cargo"
+	PmMaven   PackageManager = "maven"
+	PmGradle  PackageManager = "gradle"
 	PmUnknown PackageManager = "unknown"
 )
 
@@ -64,6 +69,7 @@ var (
 // ProjectInfo holds detected and user-provided project metadata used to render templates.
 type ProjectInfo struct {
 	ProjectName     string
+	Agent           string // coding agent CLI installed in the image; empty = claude
 	Language        Language
 	LanguageVersion string
 	GoVersion       string // Go version for Dockerfile (detected for Go projects, DefaultGoVersion otherwise)
@@ -85,6 +91,11 @@ type ProjectInfo struct {
 	TestDirs    []string
 	BaseImage   string
 	HasMakefile bool
+
+	Workspace string        // workspace file listing the packages, e.g. "go.work"; empty = one package
+	Packages  []PackageInfo // the workspace's packages, by directory
+
+	Hooks string // commit hook framework, HooksPreCommit or HooksHusky; empty = none
 }
 
 // lockFileSignals maps lock/config files to their language and package manager.
@@ -97,11 +108,15 @@ var lockFileSignals = []struct {
 	{"poetry.lock", LangPython, PmPoetry},
 	{"go.sum", LangGo, PmGo},
 	{"go.mod", LangGo, PmGo},
+	{"go.work", LangGo, PmGo},
 	{"package-lock.json", LangNode, PmNPM},
 	{"yarn.lock", LangNode, PmYarn},
 	{"pnpm-lock.yaml", LangNode, PmPNPM},
 	{"Cargo.lock", LangRust, PmCargo},
 	{"Cargo.toml", LangRust, PmCargo},
+	{"pom.xml", LangJava, PmMaven},
+	{"build.gradle.kts", LangJava, PmGradle},
+	{"build.gradle", LangJava, PmGradle},
 }
 
 // Detect inspects the repo at repoRoot and returns a ProjectInfo with sensible defaults.
@@ -122,6 +137,19 @@ func Detect(repoRoot string) *ProjectInfo {
 		}
 	}
 
+	applyLanguage(repoRoot, info)
+	info.SourceDirs = detectSourceDirs(repoRoot, info.Language)
+	info.TestDirs = detectDirs(repoRoot, testDirNames)
+	info.HasMakefile = fileExists(filepath.Join(repoRoot, "Makefile"))
+	info.Workspace, info.Packages = detectWorkspace(repoRoot)
+	info.Hooks = detectHooks(repoRoot)
+
+	return info
+}
+
+// applyLanguage fills in the version and ecosystem defaults for the
+// language and package manager set on info.
+func applyLanguage(repoRoot string, info *ProjectInfo) {
 	info.LanguageVersion = detectLanguageVersion(repoRoot, info.Language)
 	if info.Language == LangGo && info.LanguageVersion != "" {
 		info.GoVersion = info.LanguageVersion
@@ -129,18 +157,71 @@ func Detect(repoRoot string) *ProjectInfo {
 		info.GoVersion = DefaultGoVersion
 	}
 	applyEcosystemDefaults(info)
-	info.SourceDirs = detectDirs(repoRoot, []string{"src", "lib", "app", "cmd", "internal"})
-	info.TestDirs = detectDirs(repoRoot, []string{"tests", "test", "__tests__"})
-	info.HasMakefile = fileExists(filepath.Join(repoRoot, "Makefile"))
+	useGradleWithoutWrapper(repoRoot, info)
+}
 
-	return info
+// packageManagerLanguages maps each package manager to its language.
+var packageManagerLanguages = map[PackageManager]Language{
+	PmUV: LangPython, PmPoetry: LangPython,
+	PmNPM: LangNode, PmYarn: LangNode, PmPNPM: LangNode,
+	PmGo:    LangGo,
+	PmCargo: LangRust,
+	PmMaven: LangJava, PmGradle: LangJava,
+}
+
+// defaultPackageManagers is the package manager assumed for a language
+// given without one.
+var defaultPackageManagers = map[Language]PackageManager{
+	LangPython: PmUV,
+	LangNode:   PmNPM,
+	LangGo:     PmGo,
+	LangRust:   PmCargo,
+	LangJava:   PmMaven,
+}
+
+// Override replaces the detected language and package manager, for repos
+// where detection guesses wrong (e.g. a Go repo with a stray
+// package-lock.json), and recomputes the version and ecosystem defaults.
+// Either may be empty: a language given alone keeps the detected package
+// manager if it belongs to that language and otherwise takes the
+// language's default, and a package manager given alone implies its
+// language. Source directories are detected again for the new language.
+func Override(repoRoot string, info *ProjectInfo, lang Language, pm PackageManager) error {
+	if lang == "" && pm == "" {
+		return nil
+	}
+	if _, ok := defaultPackageManagers[lang]; lang != "" && !ok {
+		return fmt.Errorf("unknown language %q (want python, node, go, rust or java)", lang)
+	}
+	if _, ok := packageManagerLanguages[pm]; pm != "" && !ok {
+		return fmt.Errorf("unknown package manager %q (want uv, poetry, npm, yarn, pnpm, go, cargo, maven or gradle)", pm)
+	}
+	switch {
+	case pm == "" && packageManagerLanguages[info.PackageManager] == lang:
+		pm = info.PackageManager
+	case pm == "":
+		pm = defaultPackageManagers[lang]
+	case lang == "":
+		lang = packageManagerLanguages[pm]
+	case packageManagerLanguages[pm] != lang:
+		return fmt.Errorf("package manager %s is for %s, not %s", pm, packageManagerLanguages[pm], lang)
+	}
+
+	info.Language, info.PackageManager = lang, pm
+	info.InstallCmd, info.TestCmd, info.TypecheckCmd, info.LintCmd = "", "", "", ""
+	info.DepsDir, info.ExtraAllowedDomains = "", nil
+	applyLanguage(repoRoot, info)
+	info.SourceDirs = detectSourceDirs(repoRoot, lang)
+	return nil
 }
 
 func detectLanguageVersion(repoRoot string, lang Language) string {
 	var v string
 	switch lang { //nolint:exhaustive // unknown has no version
 	case LangGo:
-		v = readGoVersion(filepath.Join(repoRoot, "go.mod"))
+		if v = readGoVersion(filepath.Join(repoRoot, "go.mod")); v == "" {
+			v = readGoVersion(filepath.Join(repoRoot, "go.work"))
+		}
 	case LangPython:
 		if fv := readFirstLine(filepath.Join(repoRoot, ".python-version")); fv != "" {
 			v = fv
@@ -159,6 +240,10 @@ func detectLanguageVersion(repoRoot string, lang Language) string {
 		} else {
 			v = "stable"
 		}
+	case LangJava:
+		if v = readJavaVersion(repoRoot); v == "" {
+			v = DefaultJavaVersion
+		}
 	default:
 		return ""
 	}
@@ -227,6 +312,20 @@ func applyEcosystemDefaults(info *ProjectInfo) {
 		info.LintCmd = "cargo clippy"
 		info.DepsDir = depsTarget
 		info.ExtraAllowedDomains = domainsRust
+	case PmMaven:
+		info.InstallCmd = "mvn -B dependency:go-offline"
+		info.TestCmd = "mvn -B test"
+		info.TypecheckCmd = "mvn -B test-compile"
+		info.LintCmd = "mvn -B verify -DskipTests"
+		info.DepsDir = depsMaven
+		info.ExtraAllowedDomains = domainsMaven
+	case PmGradle:
+		info.InstallCmd = "./gradlew --no-daemon dependencies"
+		info.TestCmd = "./gradlew --no-daemon test"
+		info.TypecheckCmd = "./gradlew --no-daemon testClasses"
+		info.LintCmd = "./gradlew --no-daemon check -x test"
+		info.DepsDir = depsGradle
+		info.ExtraAllowedDomains = domainsGradle
 	}
 }
 
@@ -272,6 +371,14 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
+// AgentName returns the coding agent the scaffold is generated for.
+func (p *ProjectInfo) AgentName() string {
+	if p.Agent == "" {
+		return agent.NameClaude
+	}
+	return p.Agent
+}
+
 // SourceDirsList returns source dirs as a comma-separated string for display.
 func (p *ProjectInfo) SourceDirsList() string {
 	return joinOrDefault(p.SourceDirs, "src/")
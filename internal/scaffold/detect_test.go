@@ -297,3 +297,166 @@ func assertEqual(t *testing.T, field, got, want string) {
 	t.Helper()
 	require.Equal(t, want, got, field)
 }
+
+func TestOverride_LanguageReplacesStrayLockFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "go.mod", "module tools\n\ngo 1.24\n")
+	writeFile(t, dir, "package-lock.json", "{}")
+	info := Detect(dir)
+	require.Equal(t, LangGo, info.Language)
+
+	require.NoError(t, Override(dir, info, LangNode, ""))
+
+	assert.Equal(t, LangNode, info.Language)
+	assert.Equal(t, PmNPM, info.PackageManager)
+	assert.Equal(t, "22", info.LanguageVersion)
+	assert.Equal(t, DefaultGoVersion, info.GoVersion)
+	assert.Equal(t, "npm test", info.TestCmd)
+	assert.Equal(t, depsNodeModules, info.DepsDir)
+	assert.Empty(t, info.ExtraAllowedDomains)
+}
+
+func TestOverride_PackageManagerImpliesLanguage(t *testing.T) {
+	dir := t.TempDir()
+	info := Detect(dir)
+
+	require.NoError(t, Override(dir, info, "", PmPoetry))
+
+	assert.Equal(t, LangPython, info.Language)
+	assert.Equal(t, "poetry run pytest", info.TestCmd)
+	assert.Equal(t, "3.12", info.LanguageVersion)
+}
+
+func TestOverride_KeepsMatchingPackageManager(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "yarn.lock", "")
+	info := Detect(dir)
+
+	require.NoError(t, Override(dir, info, LangNode, ""))
+	assert.Equal(t, PmYarn, info.PackageManager)
+}
+
+func TestOverride_Errors(t *testing.T) {
+	dir := t.TempDir()
+	info := Detect(dir)
+
+	require.ErrorContains(t, Override(dir, info, "ruby", ""), "unknown language")
+	require.ErrorContains(t, Override(dir, info, "", "bundler"), "unknown package manager")
+	require.ErrorContains(t, Override(dir, info, LangGo, PmNPM), "npm is for node, not go")
+	assert.Equal(t, LangUnknown, info.Language)
+}
+
+func TestDetect_SourceDirs_LanguageAware(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "go.mod", "module example.com/test\n\ngo 1.25\n")
+	mkdirAll(t, filepath.Join(dir, "pkg", "auth"))
+	writeFile(t, dir, "pkg/auth/auth.go", "package auth\n")
+	mkdirAll(t, filepath.Join(dir, "services", "billing"))
+	writeFile(t, dir, "services/billing/main.go", "package main\n")
+	mkdirAll(t, filepath.Join(dir, "services", "docs-only"))
+	writeFile(t, dir, "services/docs-only/README.md", "")
+	mkdirAll(t, filepath.Join(dir, "tools", "gen"))
+	writeFile(t, dir, "tools/gen/gen.go", "package main\n")
+	mkdirAll(t, filepath.Join(dir, "vendor", "x"))
+	writeFile(t, dir, "vendor/x/x.go", "package x\n")
+	mkdirAll(t, filepath.Join(dir, "web"))
+	writeFile(t, dir, "web/index.ts", "")
+
+	info := Detect(dir)
+
+	assert.Equal(t, []string{"pkg", "services/billing", "tools"}, info.SourceDirs)
+}
+
+func TestDetect_SourceDirs_NestedConventional(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "package-lock.json", "{}")
+	mkdirAll(t, filepath.Join(dir, "frontend", "src"))
+	writeFile(t, dir, "frontend/src/app.tsx", "")
+	mkdirAll(t, filepath.Join(dir, "frontend", "node_modules", "react"))
+
+	info := Detect(dir)
+
+	assert.Equal(t, []string{"frontend/src"}, info.SourceDirs)
+}
+
+func TestDetect_SourceDirs_Capped(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "uv.lock", "")
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		mkdirAll(t, filepath.Join(dir, name))
+		writeFile(t, dir, name+"/__init__.py", "")
+	}
+
+	info := Detect(dir)
+
+	assert.Len(t, info.SourceDirs, maxSourceDirs)
+	assert.Equal(t, "a", info.SourceDirs[0])
+}
+
+func TestOverride_RedetectsSourceDirs(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "go.mod", "module example.com/test\n")
+	mkdirAll(t, filepath.Join(dir, "web"))
+	writeFile(t, dir, "web/index.ts", "")
+
+	info := Detect(dir)
+	assert.Empty(t, info.SourceDirs)
+
+	require.NoError(t, Override(dir, info, LangNode, ""))
+	assert.Equal(t, []string{"web"}, info.SourceDirs)
+}
+
+func TestDetect_Java_Maven(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "pom.xml", "<project>\n  <properties>\n    <maven.compiler.release>17</maven.compiler.release>\n  </properties>\n</project>\n")
+	mkdirAll(t, filepath.Join(dir, "src", "main", "java", "com", "example"))
+	writeFile(t, filepath.Join(dir, "src", "main", "java", "com", "example"), "App.java", "class App {}")
+
+	info := Detect(dir)
+
+	assert.Equal(t, LangJava, info.Language)
+	assert.Equal(t, PmMaven, info.PackageManager)
+	assert.Equal(t, "17", info.LanguageVersion)
+	assert.Equal(t, "mvn -B test", info.TestCmd)
+	assert.Equal(t, ".m2", info.DepsDir)
+	assert.Contains(t, info.ExtraAllowedDomains, "repo.maven.apache.org")
+	assert.Contains(t, info.SourceDirs, "src")
+}
+
+func TestDetect_Java_Gradle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "build.gradle.kts", "java {\n    toolchain {\n        languageVersion = JavaLanguageVersion.of(21)\n    }\n}\n")
+	writeFile(t, dir, "gradlew", "#!/bin/sh\n")
+
+	info := Detect(dir)
+
+	assert.Equal(t, PmGradle, info.PackageManager)
+	assert.Equal(t, "21", info.LanguageVersion)
+	assert.Equal(t, "./gradlew --no-daemon test", info.TestCmd)
+	assert.Equal(t, ".gradle", info.DepsDir)
+	assert.Contains(t, info.ExtraAllowedDomains, "plugins.gradle.org")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "gradlew")))
+	info = Detect(dir)
+	assert.Equal(t, "gradle --no-daemon test", info.TestCmd, "without a wrapper the image's gradle is used")
+}
+
+func TestDetect_JavaVersion(t *testing.T) {
+	cases := map[string][2]string{
+		".java-version":  {".java-version", "1.8\n"},
+		".sdkmanrc":      {".sdkmanrc", "# tools\njava=21.0.2-tem\n"},
+		".tool-versions": {".tool-versions", "nodejs 20.1.0\njava temurin-17.0.9+9\n"},
+		"gradle":         {"build.gradle", "sourceCompatibility = JavaVersion.VERSION_11\n"},
+	}
+	want := map[string]string{".java-version": "8", ".sdkmanrc": "21", ".tool-versions": "17", "gradle": "11"}
+	for name, c := range cases {
+		dir := t.TempDir()
+		writeFile(t, dir, "build.gradle", "")
+		writeFile(t, dir, c[0], c[1])
+		assert.Equal(t, want[name], Detect(dir).LanguageVersion, name)
+	}
+
+	dir := t.TempDir()
+	writeFile(t, dir, "pom.xml", "<project/>")
+	assert.Equal(t, DefaultJavaVersion, Detect(dir).LanguageVersion)
+}
@@ -0,0 +1,172 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxSourceDirs caps how many source directories AGENTS.md lists; past a
+// handful the list stops being a useful map of the repo.
+const maxSourceDirs = 6
+
+// sourceScanDepth is how many directory levels below a candidate are
+// searched for source files.
+const sourceScanDepth = 3
+
+// commonSourceDirs are source directory names worth listing in any repo.
+var commonSourceDirs = []string{"src", "lib", "app", "cmd", "internal"}
+
+// languageSourceDirs are further conventional source directories per language.
+var languageSourceDirs = map[Language][]string{
+	LangGo:     {"pkg", "api", "server"},
+	LangNode:   {"server", "client", "frontend/src", "backend/src", "web/src", "pages", "components"},
+	LangPython: {"server", "backend"},
+	LangRust:   {"crates"},
+	LangJava:   {"src/main/java", "src/main/kotlin"},
+}
+
+// sourceContainerDirs hold one project per child, e.g. "services/billing".
+var sourceContainerDirs = []string{"services", "packages", "apps", "crates", "modules"}
+
+// sourceExtensions are the file extensions that mark a directory as holding
+// a language's source.
+var sourceExtensions = map[Language][]string{
+	LangGo:     {".go"},
+	LangNode:   {".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs"},
+	LangPython: {".py"},
+	LangRust:   {".rs"},
+	LangJava:   {".java", ".kt"},
+}
+
+// testDirNames are the test directories detected separately.
+var testDirNames = []string{"tests", "test", "__tests__"}
+
+// nonSourceDirs are never listed as source, even when they hold source files.
+var nonSourceDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "target": true, "dist": true, "build": true,
+	"out": true, "coverage": true, "docs": true, "examples": true, "scripts": true,
+	"specs": true, "testdata": true, "fixtures": true,
+}
+
+// detectSourceDirs lists where lang's source lives under repoRoot, in order:
+// conventional directory names that exist, then the projects inside
+// container directories such as services/, then any other top-level
+// directory holding lang's source files. The list is capped at
+// maxSourceDirs.
+func detectSourceDirs(repoRoot string, lang Language) []string {
+	candidates := append(append([]string(nil), commonSourceDirs...), languageSourceDirs[lang]...)
+	found := detectDirs(repoRoot, candidates)
+	seen := make(map[string]bool, len(found))
+	for _, d := range found {
+		seen[d] = true
+	}
+	add := func(dir string) {
+		if !seen[dir] && !coveredBy(dir, found) {
+			seen[dir] = true
+			found = append(found, dir)
+		}
+	}
+
+	exts := sourceExtensions[lang]
+	if len(exts) > 0 {
+		for _, container := range sourceContainerDirs {
+			for _, child := range subdirs(filepath.Join(repoRoot, container)) {
+				if dir := container + "/" + child; hasSourceFiles(filepath.Join(repoRoot, dir), exts, sourceScanDepth) {
+					add(dir)
+				}
+			}
+		}
+		for _, top := range subdirs(repoRoot) {
+			if !isContainer(top) && !isTestDir(top) && hasSourceFiles(filepath.Join(repoRoot, top), exts, sourceScanDepth) {
+				add(top)
+			}
+		}
+	}
+
+	if len(found) > maxSourceDirs {
+		found = found[:maxSourceDirs]
+	}
+	return found
+}
+
+// coveredBy reports whether dir is inside or contains one of dirs, e.g.
+// "frontend" once "frontend/src" is listed.
+func coveredBy(dir string, dirs []string) bool {
+	for _, d := range dirs {
+		if strings.HasPrefix(d, dir+"/") || strings.HasPrefix(dir, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// subdirs returns the visible child directories of dir, sorted, leaving out
+// dependency, build and documentation directories.
+func subdirs(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() && !strings.HasPrefix(name, ".") && !strings.HasPrefix(name, "_") && !nonSourceDirs[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasSourceFiles reports whether dir holds a file with one of exts, looking
+// at most depth levels down.
+func hasSourceFiles(dir string, exts []string, depth int) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if e.Type().IsRegular() && hasExtension(e.Name(), exts) {
+			return true
+		}
+	}
+	if depth <= 1 {
+		return false
+	}
+	for _, sub := range subdirs(dir) {
+		if hasSourceFiles(filepath.Join(dir, sub), exts, depth-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasExtension(name string, exts []string) bool {
+	ext := filepath.Ext(name)
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func isContainer(name string) bool {
+	for _, c := range sourceContainerDirs {
+		if name == c {
+			return true
+		}
+	}
+	return false
+}
+
+func isTestDir(name string) bool {
+	for _, t := range testDirNames {
+		if name == t {
+			return true
+		}
+	}
+	return false
+}
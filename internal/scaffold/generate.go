@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -18,7 +19,8 @@ var templateFS embed.FS
 
 // funcMap provides helper functions available in all templates.
 var funcMap = template.FuncMap{
-	"str": func(v any) string { return fmt.Sprintf("%v", v) },
+	"str":   func(v any) string { return fmt.Sprintf("%v", v) },
+	"quote": strconv.Quote, // a Go-quoted string is a valid YAML double-quoted scalar
 }
 
 // templateMapping maps template paths (under templates/) to output paths (relative to repoRoot).
@@ -32,7 +34,8 @@ var templateMapping = []struct {
 	{"templates/prompts/build.md.tmpl", ".ralph/prompts/build.md"},
 	{"templates/docker/Dockerfile.tmpl", ".ralph/docker/Dockerfile"},
 	{"templates/docker/entrypoint.sh.tmpl", ".ralph/docker/entrypoint.sh"},
-	{"templates/docker/dockerignore.tmpl", ".ralph/docker/.dockerignore"},
+	// Named for the Dockerfile so BuildKit uses it; the build context is the repo root.
+	{"templates/docker/dockerignore.tmpl", ".ralph/docker/Dockerfile.dockerignore"},
 	{"templates/env.example.tmpl", ".env.example"},
 }
 
@@ -55,6 +58,10 @@ func RootFiles() []string {
 var gitignoreEntries = []string{
 	".ralph/logs/",
 	".ralph/state.json",
+	".ralph/state.json.tmp",
+	".ralph/profiles.yaml",
+	".ralph/scratch/",
+	".ralph/swarm.json",
 	".env",
 }
 
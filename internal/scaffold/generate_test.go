@@ -42,7 +42,7 @@ func TestGenerate_CreatesAllFiles(t *testing.T) {
 		".ralph/prompts/build.md",
 		".ralph/docker/Dockerfile",
 		".ralph/docker/entrypoint.sh",
-		".ralph/docker/.dockerignore",
+		".ralph/docker/Dockerfile.dockerignore",
 		".env.example",
 		"specs/my-feature/.gitkeep",
 		".ralph/plans/.gitkeep",
@@ -103,6 +103,32 @@ func TestGenerate_ConfigContent(t *testing.T) {
 	assert.Contains(t, s, `lint: "golangci-lint run ./..."`)
 }
 
+func TestGenerate_AgentsListsPackages(t *testing.T) {
+	dir := t.TempDir()
+	info := Detect(t.TempDir())
+	info.Workspace = WorkspaceGo
+	info.Packages = []PackageInfo{{Name: "example.com/tool", Dir: "cmd/tool"}, {Name: "example.com/lib", Dir: "lib"}}
+
+	_, err := Generate(dir, "", info, false)
+	require.NoError(t, err)
+
+	agents, err := os.ReadFile(filepath.Join(dir, "AGENTS.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(agents), "## Packages\n\nThis is a monorepo; `go.work` lists its packages.")
+	assert.Contains(t, string(agents), "- `example.com/tool`: `cmd/tool/`\n- `example.com/lib`: `lib/`\n\n## Validation")
+
+	build, err := os.ReadFile(filepath.Join(dir, ".ralph", "prompts", "build.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(build), "If PACKAGE is present")
+
+	plain := t.TempDir()
+	_, err = Generate(plain, "", Detect(t.TempDir()), false)
+	require.NoError(t, err)
+	agents, err = os.ReadFile(filepath.Join(plain, "AGENTS.md"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(agents), "## Packages")
+}
+
 func TestGenerate_SkipsExistingFiles(t *testing.T) {
 	dir := t.TempDir()
 	info := &ProjectInfo{
@@ -198,6 +224,14 @@ func TestGenerate_EntrypointIsExecutable(t *testing.T) {
 	fi, err := os.Stat(filepath.Join(dir, ".ralph", "docker", "entrypoint.sh"))
 	require.NoError(t, err)
 	assert.NotZero(t, fi.Mode()&0o111, "entrypoint.sh should be executable")
+
+	content, err := os.ReadFile(filepath.Join(dir, ".ralph", "docker", "entrypoint.sh"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `usermod -o -u "$HOST_UID"`, "entrypoint should remap claude to the host user")
+	assert.Contains(t, string(content), "chown -R claude:claude /home/claude\n")
+	assert.NotContains(t, string(content), "chown -R claude:claude /home/claude /workspace", "bind mounts must not be re-owned")
+	assert.Contains(t, string(content), `own_volume "/workspace/repo/$DEPS_DIR"`)
+	assert.Contains(t, string(content), `if [ "$(stat -c %u:%g "$1")" != "$(id -u claude):$(id -g claude)" ]; then`, "volumes are walked only when their owner changed")
 }
 
 func TestGenerate_DockerfileContent(t *testing.T) {
@@ -227,6 +261,23 @@ func TestGenerate_DockerfileContent(t *testing.T) {
 	assert.Contains(t, s, "ralph")
 }
 
+func TestGenerate_DockerfileJava(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "pom.xml", "<project/>")
+	info := Detect(dir)
+
+	_, err := Generate(dir, "", info, false)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, ".ralph", "docker", "Dockerfile"))
+	require.NoError(t, err)
+	s := string(content)
+	assert.Contains(t, s, "COPY --from=eclipse-temurin:21-jdk /opt/java/openjdk /opt/java/openjdk")
+	assert.Contains(t, s, "COPY --from=maven:3-eclipse-temurin-21 /usr/share/maven /usr/share/maven")
+	assert.Contains(t, s, `ENV MAVEN_OPTS="-Dmaven.repo.local=/workspace/repo/.m2/repository"`)
+	assert.NotContains(t, s, "gradle")
+}
+
 func TestPrintSummary_WithBranch(t *testing.T) {
 	result := &GenerateResult{
 		Created:  []string{".ralph/config.yaml", "AGENTS.md"},
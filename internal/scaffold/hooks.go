@@ -0,0 +1,71 @@
+package scaffold
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Commit hook frameworks Detect recognises.
+const (
+	HooksPreCommit = "pre-commit" // .pre-commit-config.yaml
+	HooksHusky     = "husky"      // .husky/, or husky in package.json
+)
+
+// detectHooks returns the commit hook framework the repo at repoRoot uses,
+// or "" for none.
+func detectHooks(repoRoot string) string {
+	if fileExists(filepath.Join(repoRoot, ".pre-commit-config.yaml")) {
+		return HooksPreCommit
+	}
+	if fi, err := os.Stat(filepath.Join(repoRoot, ".husky")); err == nil && fi.IsDir() {
+		return HooksHusky
+	}
+	data, err := os.ReadFile(filepath.Join(repoRoot, "package.json")) //nolint:gosec // fixed name in the repo
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		DevDependencies map[string]string `json:"devDependencies"`
+		Dependencies    map[string]string `json:"dependencies"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	if _, ok := pkg.DevDependencies["husky"]; ok {
+		return HooksHusky
+	}
+	if _, ok := pkg.Dependencies["husky"]; ok {
+		return HooksHusky
+	}
+	return ""
+}
+
+// HooksInstallCmd returns the command the container runs after installing
+// dependencies so the repo's commit hooks run on the agent's commits, or ""
+// when there are none to install.
+func (p *ProjectInfo) HooksInstallCmd() string {
+	switch p.Hooks {
+	case HooksPreCommit:
+		return "pre-commit install --install-hooks"
+	case HooksHusky:
+		return "npx --no-install husky"
+	}
+	return ""
+}
+
+// NetworkDomains returns the domains the generated config lets through the
+// firewall: the ecosystem's registries, plus PyPI for the environments
+// pre-commit builds its hooks in.
+func (p *ProjectInfo) NetworkDomains() []string {
+	domains := slices.Clone(p.ExtraAllowedDomains)
+	if p.Hooks == HooksPreCommit {
+		for _, d := range domainsPython {
+			if !slices.Contains(domains, d) {
+				domains = append(domains, d)
+			}
+		}
+	}
+	return domains
+}
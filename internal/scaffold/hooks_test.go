@@ -0,0 +1,64 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect_Hooks(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		want string
+	}{
+		{"pre-commit", ".pre-commit-config.yaml", "repos: []\n", HooksPreCommit},
+		{"husky directory", ".husky/pre-commit", "npx lint-staged\n", HooksHusky},
+		{"husky in package.json", "package.json", `{"devDependencies": {"husky": "^9.0.0"}}`, HooksHusky},
+		{"none", "package.json", `{"devDependencies": {"eslint": "^9.0.0"}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, tt.path)), 0o750))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, tt.path), []byte(tt.body), 0o600))
+			assert.Equal(t, tt.want, Detect(dir).Hooks)
+		})
+	}
+}
+
+func TestGenerate_Hooks(t *testing.T) {
+	dir := t.TempDir()
+	info := Detect(t.TempDir())
+	info.Hooks = HooksPreCommit
+
+	_, err := Generate(dir, "", info, false)
+	require.NoError(t, err)
+
+	dockerfile, err := os.ReadFile(filepath.Join(dir, ".ralph", "docker", "Dockerfile"))
+	require.NoError(t, err)
+	assert.Contains(t, string(dockerfile), "pipx install pre-commit")
+
+	entrypoint, err := os.ReadFile(filepath.Join(dir, ".ralph", "docker", "entrypoint.sh"))
+	require.NoError(t, err)
+	assert.Contains(t, string(entrypoint), `&& pre-commit install --install-hooks; } >&2 && exec ralph _loop "$@"`)
+
+	cfg, err := os.ReadFile(filepath.Join(dir, ".ralph", "config.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(cfg), "extra_allowed_domains:\n    - pypi.org\n    - files.pythonhosted.org")
+	assert.Contains(t, string(cfg), "# The repo's pre-commit hooks run")
+
+	plain := t.TempDir()
+	_, err = Generate(plain, "", Detect(t.TempDir()), false)
+	require.NoError(t, err)
+	dockerfile, err = os.ReadFile(filepath.Join(plain, ".ralph", "docker", "Dockerfile"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(dockerfile), "pre-commit")
+	cfg, err = os.ReadFile(filepath.Join(plain, ".ralph", "config.yaml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(cfg), "hooks")
+}
@@ -0,0 +1,114 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultJavaVersion is the JDK used when a Java project doesn't pin one.
+const DefaultJavaVersion = "21"
+
+// Dependency caches for Java builds. Maven's local repository and Gradle's
+// user home live outside the project, so the Dockerfile points them here,
+// into the deps volume.
+const (
+	depsMaven  = ".m2"
+	depsGradle = ".gradle"
+)
+
+// Maven Central, and for Gradle also the plugin portal and wrapper
+// distributions.
+var (
+	domainsMaven  = []string{"repo.maven.apache.org", "repo1.maven.org"}
+	domainsGradle = []string{
+		"repo.maven.apache.org", "repo1.maven.org",
+		"plugins.gradle.org", "plugins-artifacts.gradle.org",
+		"services.gradle.org", "downloads.gradle.org",
+	}
+)
+
+// javaBuildVersion finds the Java release in a Maven or Gradle build file:
+// maven.compiler.release/source, java.version or the compiler plugin's
+// <release> in pom.xml; a toolchain's JavaLanguageVersion.of(N) or
+// sourceCompatibility in build.gradle(.kts).
+var javaBuildVersion = []*regexp.Regexp{
+	regexp.MustCompile(`<(?:maven\.compiler\.release|maven\.compiler\.source|java\.version|release)>\s*([^<\s]+)\s*<`),
+	regexp.MustCompile(`JavaLanguageVersion\.of\(\s*"?(\d+)`),
+	regexp.MustCompile(`(?:source|target)Compatibility\s*=\s*([\w.'"]+)`),
+}
+
+// javaMajor matches the version number in values such as "21.0.2-tem",
+// "temurin-17", "1.8" and "VERSION_1_8" (after underscores become dots).
+var javaMajor = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// readJavaVersion returns the major Java version a project pins, from the
+// first of .java-version, .sdkmanrc, .tool-versions and the build file that
+// names one, or "" when none does.
+func readJavaVersion(repoRoot string) string {
+	if v := readFirstLine(filepath.Join(repoRoot, ".java-version")); v != "" {
+		return majorJavaVersion(v)
+	}
+	if v := readKeyedLine(filepath.Join(repoRoot, ".sdkmanrc"), "java="); v != "" {
+		return majorJavaVersion(v)
+	}
+	if v := readKeyedLine(filepath.Join(repoRoot, ".tool-versions"), "java "); v != "" {
+		return majorJavaVersion(v)
+	}
+	for _, file := range []string{"pom.xml", "build.gradle.kts", "build.gradle"} {
+		data, err := os.ReadFile(filepath.Join(repoRoot, file)) //nolint:gosec // fixed build file names
+		if err != nil {
+			continue
+		}
+		for _, re := range javaBuildVersion {
+			if m := re.FindSubmatch(data); m != nil {
+				if v := majorJavaVersion(string(m[1])); v != "" {
+					return v
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// majorJavaVersion reduces a Java version to its major release, which the
+// JDK, Maven and Gradle image tags are named by: "21.0.2-tem" → "21",
+// "1.8" → "8". It returns "" when v holds no version.
+func majorJavaVersion(v string) string {
+	m := javaMajor.FindString(strings.ReplaceAll(v, "_", "."))
+	if m == "" {
+		return ""
+	}
+	major, minor, ok := strings.Cut(m, ".")
+	if major == "1" && ok {
+		return minor
+	}
+	return major
+}
+
+// readKeyedLine returns the rest of the first line in path that starts with
+// prefix, trimmed, or "".
+func readKeyedLine(path, prefix string) string {
+	data, err := os.ReadFile(path) //nolint:gosec // fixed toolchain file names
+	if err != nil {
+		return ""
+	}
+	for line := range strings.Lines(string(data)) {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), prefix); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	return ""
+}
+
+// useGradleWithoutWrapper switches Gradle commands from ./gradlew to the
+// gradle installed in the image, for projects without a wrapper.
+func useGradleWithoutWrapper(repoRoot string, info *ProjectInfo) {
+	if info.PackageManager != PmGradle || fileExists(filepath.Join(repoRoot, "gradlew")) {
+		return
+	}
+	for _, cmd := range []*string{&info.InstallCmd, &info.TestCmd, &info.TypecheckCmd, &info.LintCmd} {
+		*cmd = strings.Replace(*cmd, "./gradlew ", "gradle ", 1)
+	}
+}
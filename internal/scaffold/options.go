@@ -61,6 +61,16 @@ func runCmdOptions(info *ProjectInfo) []huh.Option[string] {
 		opts = append(opts,
 			option("cargo run", "Build and run the default binary"),
 		)
+	case PmMaven:
+		opts = append(opts,
+			option("mvn spring-boot:run", "Run a Spring Boot application"),
+			option("mvn exec:java", "Run the main class configured for exec-maven-plugin"),
+		)
+	case PmGradle:
+		opts = append(opts,
+			option("./gradlew bootRun", "Run a Spring Boot application"),
+			option("./gradlew run", "Run the main class of the application plugin"),
+		)
 	}
 
 	opts = append(opts, customOption())
@@ -88,6 +98,10 @@ func goalOptions(info *ProjectInfo) []huh.Option[string] {
 		opts = append(opts,
 			option("Production-ready system tool", "A performant system utility with comprehensive tests"),
 		)
+	case LangJava:
+		opts = append(opts,
+			option("Production-ready backend service", "A Spring Boot service with JUnit tests and clear module boundaries"),
+		)
 	}
 
 	opts = append(opts,
@@ -129,6 +143,10 @@ func runCmdTitle(info *ProjectInfo) string {
 		example = fmt.Sprintf("go run ./cmd/%s", info.ProjectName)
 	case PmCargo:
 		example = "cargo run"
+	case PmMaven:
+		example = "mvn spring-boot:run"
+	case PmGradle:
+		example = "./gradlew bootRun"
 	}
 
 	if example != "" {
@@ -19,9 +19,20 @@ type PromptOptions struct {
 	Branch     string // current git branch; used to show concrete examples in prompts
 }
 
-// RunPrompts asks the user to confirm or override detected values.
+// RunPrompts asks the user to confirm or override detected values. Values
+// already set on info (e.g. by LoadPreviousAnswers) are preselected: a
+// matching option is highlighted, anything else prefills the custom input.
 func RunPrompts(info *ProjectInfo, opts *PromptOptions) error {
-	var runChoice, goalChoice, specsChoice string
+	runChoice := preselect(info.RunCmd, runCmdOptions(info))
+	goalChoice := preselect(info.Goal, goalOptions(info))
+	// Only an exact (typed) specs dir is custom; a branch-appended one is
+	// always one of the listed options.
+	var specsChoice string
+	if info.SpecsDirExact {
+		specsChoice = preselect(info.SpecsDir, nil)
+	} else if preselect(info.SpecsDir, specsDirOptions(opts.Branch)) != customSentinel {
+		specsChoice = info.SpecsDir
+	}
 
 	form := huh.NewForm(
 		// Group 1: Select run command
@@ -98,6 +109,58 @@ func RunPrompts(info *ProjectInfo, opts *PromptOptions) error {
 	return nil
 }
 
+// Answer records answers given up front, such as ralph init's flags, on
+// info; empty values are left alone. Answered prompts are preselected like
+// previous answers. A specs dir that isn't one of the listed options is used
+// as-is, as if it had been typed.
+func Answer(info *ProjectInfo, runCmd, goal, specsDir, branch string) {
+	if runCmd != "" {
+		info.RunCmd = runCmd
+	}
+	if goal != "" {
+		info.Goal = goal
+	}
+	if specsDir != "" {
+		info.SpecsDir = specsDir
+		info.SpecsDirExact = preselect(specsDir, specsDirOptions(branch)) == customSentinel
+	}
+}
+
+// AcceptDefaults answers the prompts without asking: each value not already
+// set takes the option the prompt would preselect. It fails when there is no
+// run command to default to, as for an undetected package manager.
+func AcceptDefaults(info *ProjectInfo) error {
+	if info.RunCmd == "" {
+		info.RunCmd = runCmdOptions(info)[0].Value
+		if info.RunCmd == customSentinel {
+			info.RunCmd = ""
+			return fmt.Errorf("no run command detected; pass --run-cmd")
+		}
+	}
+	if info.Goal == "" {
+		info.Goal = goalOptions(info)[0].Value
+	}
+	if info.SpecsDir == "" {
+		info.SpecsDir = specsDirOptions("")[0].Value
+	}
+	return validateSpecsDir(info.SpecsDir)
+}
+
+// preselect returns the option to select by default for a current value:
+// the matching option, the custom sentinel for an unlisted value, or "" (the
+// first option) when there is no value yet.
+func preselect(value string, options []huh.Option[string]) string {
+	if value == "" {
+		return ""
+	}
+	for _, o := range options {
+		if o.Value == value {
+			return value
+		}
+	}
+	return customSentinel
+}
+
 // validateSpecsDir rejects absolute paths and paths that escape the repo root.
 func validateSpecsDir(dir string) error {
 	if filepath.IsAbs(dir) {
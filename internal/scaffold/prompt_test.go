@@ -76,6 +76,32 @@ func TestRunPrompts_CustomValues(t *testing.T) {
 	assert.True(t, info.SpecsDirExact)
 }
 
+func TestRunPrompts_PreselectsPreviousAnswers(t *testing.T) {
+	info := &ProjectInfo{
+		ProjectName:    "myapp",
+		Language:       LangPython,
+		PackageManager: PmUV,
+		RunCmd:         "uv run python -m myapp", // option 2
+		Goal:           "my previous goal",       // not an option: prefills the custom input
+		SpecsDir:       "my/specs",
+		SpecsDirExact:  true,
+	}
+	// Accept every default: three selects and their custom inputs.
+	input := strings.Repeat("\n", 6)
+
+	err := RunPrompts(info, &PromptOptions{
+		In:         &byteReader{strings.NewReader(input)},
+		Out:        &bytes.Buffer{},
+		Accessible: true,
+	})
+	require.NoError(t, err)
+
+	assertEqual(t, "RunCmd", info.RunCmd, "uv run python -m myapp")
+	assertEqual(t, "Goal", info.Goal, "my previous goal")
+	assertEqual(t, "SpecsDir", info.SpecsDir, "my/specs")
+	assert.True(t, info.SpecsDirExact)
+}
+
 func TestRunPrompts_OutputContainsPrompts(t *testing.T) {
 	info := &ProjectInfo{
 		ProjectName:    "myapp",
@@ -214,3 +240,29 @@ func TestRunPrompts_ValidatesSpecsDir(t *testing.T) {
 	require.NoError(t, err)
 	assertEqual(t, "SpecsDir", info.SpecsDir, "specs")
 }
+
+func TestAnswer(t *testing.T) {
+	info := &ProjectInfo{RunCmd: "npm start", Goal: "old"}
+	Answer(info, "", "Ship it", "docs/specs", "feat")
+	assert.Equal(t, "npm start", info.RunCmd, "empty answers are left alone")
+	assert.Equal(t, "Ship it", info.Goal)
+	assert.Equal(t, "docs/specs", info.SpecsDir)
+	assert.False(t, info.SpecsDirExact, "a listed specs dir gets the branch appended")
+
+	Answer(info, "", "", "design", "feat")
+	assert.True(t, info.SpecsDirExact)
+}
+
+func TestAcceptDefaults(t *testing.T) {
+	info := &ProjectInfo{ProjectName: "tool", Language: LangGo, PackageManager: PmGo}
+	require.NoError(t, AcceptDefaults(info))
+	assert.Equal(t, "go run ./cmd/tool", info.RunCmd)
+	assert.Equal(t, "Production-ready CLI tool", info.Goal)
+	assert.Equal(t, "specs", info.SpecsDir)
+
+	unknown := &ProjectInfo{Goal: "kept"}
+	require.ErrorContains(t, AcceptDefaults(unknown), "pass --run-cmd")
+
+	unknown = &ProjectInfo{RunCmd: "make run", SpecsDir: "../specs"}
+	require.ErrorContains(t, AcceptDefaults(unknown), "within the repository")
+}
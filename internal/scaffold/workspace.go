@@ -0,0 +1,274 @@
+package scaffold
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workspace files whose packages Detect lists.
+const (
+	WorkspacePNPM  = "pnpm-workspace.yaml"
+	WorkspaceNPM   = "package.json" // its "workspaces" field, used by npm and yarn
+	WorkspaceGo    = "go.work"
+	WorkspaceCargo = "Cargo.toml" // its [workspace] members
+)
+
+// PackageInfo is one package of a monorepo workspace.
+type PackageInfo struct {
+	Name string // from the package's manifest, or its directory when it has none
+	Dir  string // relative to the repo root, slash-separated
+}
+
+// detectWorkspace returns the workspace file at repoRoot and its packages,
+// sorted by directory, or "" and nil for a single-package repo.
+func detectWorkspace(repoRoot string) (string, []PackageInfo) {
+	for _, ws := range []struct {
+		file     string
+		manifest string
+		patterns func(data []byte) []string
+	}{
+		{WorkspacePNPM, "package.json", pnpmPatterns},
+		{WorkspaceNPM, "package.json", npmPatterns},
+		{WorkspaceGo, "go.mod", goWorkPatterns},
+		{WorkspaceCargo, "Cargo.toml", cargoPatterns},
+	} {
+		data, err := os.ReadFile(filepath.Join(repoRoot, ws.file)) //nolint:gosec // fixed names in the repo
+		if err != nil {
+			continue
+		}
+		patterns := ws.patterns(data)
+		if len(patterns) == 0 {
+			continue
+		}
+		if pkgs := expandPackages(repoRoot, patterns, ws.manifest); len(pkgs) > 0 {
+			return ws.file, pkgs
+		}
+	}
+	return "", nil
+}
+
+// expandPackages resolves workspace patterns to the directories under
+// repoRoot that hold manifest. Patterns starting with "!" exclude, and a
+// trailing "/**" matches at any depth, as pnpm allows.
+func expandPackages(repoRoot string, patterns []string, manifest string) []PackageInfo {
+	dirs := map[string]bool{}
+	for _, p := range patterns {
+		exclude := strings.HasPrefix(p, "!")
+		for _, dir := range matchDirs(repoRoot, strings.TrimPrefix(p, "!"), manifest) {
+			if exclude {
+				delete(dirs, dir)
+			} else {
+				dirs[dir] = true
+			}
+		}
+	}
+	pkgs := make([]PackageInfo, 0, len(dirs))
+	for dir := range dirs {
+		pkgs = append(pkgs, PackageInfo{Name: packageName(filepath.Join(repoRoot, dir), manifest, dir), Dir: dir})
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Dir < pkgs[j].Dir })
+	return pkgs
+}
+
+// matchDirs returns the slash-separated directories pattern matches that
+// hold manifest, skipping the repo root itself.
+func matchDirs(repoRoot, pattern, manifest string) []string {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+	var candidates []string
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		root := filepath.Join(repoRoot, filepath.FromSlash(prefix))
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error { //nolint:errcheck // unreadable dirs have no packages
+			if err != nil || !d.IsDir() {
+				return nil //nolint:nilerr // skip what can't be read
+			}
+			if d.Name() == depsNodeModules || d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			candidates = append(candidates, path)
+			return nil
+		})
+	} else {
+		candidates, _ = filepath.Glob(filepath.Join(repoRoot, filepath.FromSlash(pattern))) //nolint:errcheck // a bad pattern matches nothing
+	}
+	var dirs []string
+	for _, path := range candidates {
+		rel, err := filepath.Rel(repoRoot, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if fileExists(filepath.Join(path, manifest)) {
+			dirs = append(dirs, filepath.ToSlash(rel))
+		}
+	}
+	return dirs
+}
+
+// pnpmPatterns reads the packages list of pnpm-workspace.yaml.
+func pnpmPatterns(data []byte) []string {
+	var ws struct {
+		Packages []string `yaml:"packages"`
+	}
+	if yaml.Unmarshal(data, &ws) != nil {
+		return nil
+	}
+	return ws.Packages
+}
+
+// npmPatterns reads package.json workspaces, either a list or yarn's
+// {"packages": [...]} form.
+func npmPatterns(data []byte) []string {
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal(data, &pkg) != nil || len(pkg.Workspaces) == 0 {
+		return nil
+	}
+	var list []string
+	if json.Unmarshal(pkg.Workspaces, &list) == nil {
+		return list
+	}
+	var obj struct {
+		Packages []string `json:"packages"`
+	}
+	if json.Unmarshal(pkg.Workspaces, &obj) == nil {
+		return obj.Packages
+	}
+	return nil
+}
+
+// goWorkPatterns reads the use directives of go.work, single or in a block.
+func goWorkPatterns(data []byte) []string {
+	var dirs []string
+	inBlock := false
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "//")
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			dirs = append(dirs, strings.Trim(line, `"`))
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			dirs = append(dirs, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "use ")), `"`))
+		}
+	}
+	return dirs
+}
+
+// tomlString matches a double-quoted TOML string.
+var tomlString = regexp.MustCompile(`"([^"]*)"`)
+
+// cargoPatterns reads members from the [workspace] table of Cargo.toml; the
+// array may span lines.
+func cargoPatterns(data []byte) []string {
+	section := ""
+	var members strings.Builder
+	collecting := false
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		line = strings.TrimSpace(line)
+		if collecting {
+			members.WriteString(line)
+			if strings.Contains(line, "]") {
+				break
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "[") && !strings.Contains(line, "=") {
+			section = strings.Trim(line, "[] ")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if section != "workspace" || !ok || strings.TrimSpace(key) != "members" {
+			continue
+		}
+		members.WriteString(value)
+		if collecting = !strings.Contains(value, "]"); !collecting {
+			break
+		}
+	}
+	var patterns []string
+	for _, m := range tomlString.FindAllStringSubmatch(members.String(), -1) {
+		patterns = append(patterns, m[1])
+	}
+	return patterns
+}
+
+// packageName reads a package's name from its manifest, falling back to dir.
+func packageName(pkgDir, manifest, dir string) string {
+	data, err := os.ReadFile(filepath.Join(pkgDir, manifest)) //nolint:gosec // a manifest in the repo
+	if err != nil {
+		return dir
+	}
+	var name string
+	switch manifest {
+	case "package.json":
+		var pkg struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(data, &pkg) == nil {
+			name = pkg.Name
+		}
+	case "go.mod":
+		for _, line := range strings.Split(string(data), "\n") {
+			if mod, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+				name = strings.Trim(strings.TrimSpace(mod), `"`)
+				break
+			}
+		}
+	case "Cargo.toml":
+		name = cargoPackageName(data)
+	}
+	if name == "" {
+		return dir
+	}
+	return name
+}
+
+// cargoPackageName reads name from the [package] table of a Cargo.toml.
+func cargoPackageName(data []byte) string {
+	section := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[] ")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if section == "package" && ok && strings.TrimSpace(key) == "name" {
+			if m := tomlString.FindStringSubmatch(value); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+// FindPackage returns the package named name, or whose directory is name.
+func (p *ProjectInfo) FindPackage(name string) (PackageInfo, error) {
+	if len(p.Packages) == 0 {
+		return PackageInfo{}, fmt.Errorf("no workspace packages found (pnpm-workspace.yaml, package.json workspaces, go.work or a Cargo [workspace])")
+	}
+	dir := strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(name), "./"), "/")
+	names := make([]string, 0, len(p.Packages))
+	for _, pkg := range p.Packages {
+		if pkg.Name == name || pkg.Dir == dir {
+			return pkg, nil
+		}
+		names = append(names, pkg.Name)
+	}
+	return PackageInfo{}, fmt.Errorf("no package %q in %s; packages: %s", name, p.Workspace, strings.Join(names, ", "))
+}
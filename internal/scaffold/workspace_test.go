@@ -0,0 +1,111 @@
+package scaffold
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePackage writes a manifest into dir under root, creating dir.
+func writePackage(t *testing.T, root, dir, manifest, content string) {
+	t.Helper()
+	mkdirAll(t, filepath.Join(root, dir))
+	writeFile(t, root, filepath.Join(dir, manifest), content)
+}
+
+func TestDetect_PNPMWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "pnpm-lock.yaml", "")
+	writeFile(t, dir, "package.json", `{"name": "root"}`)
+	writeFile(t, dir, "pnpm-workspace.yaml", "packages:\n  - apps/*\n  - packages/**\n  - '!packages/legacy'\n")
+	writePackage(t, dir, "apps/web", "package.json", `{"name": "@acme/web"}`)
+	writePackage(t, dir, "apps/docs", "README.md", "not a package")
+	writePackage(t, dir, "packages/ui/button", "package.json", `{"name": "@acme/button"}`)
+	writePackage(t, dir, "packages/legacy", "package.json", `{"name": "@acme/legacy"}`)
+	writePackage(t, dir, "packages/ui/node_modules/dep", "package.json", `{"name": "dep"}`)
+
+	info := Detect(dir)
+	assert.Equal(t, PmPNPM, info.PackageManager)
+	assert.Equal(t, WorkspacePNPM, info.Workspace)
+	assert.Equal(t, []PackageInfo{
+		{Name: "@acme/web", Dir: "apps/web"},
+		{Name: "@acme/button", Dir: "packages/ui/button"},
+	}, info.Packages)
+}
+
+func TestDetect_NPMWorkspaces(t *testing.T) {
+	for name, workspaces := range map[string]string{
+		"list":       `["services/*"]`,
+		"yarn style": `{"packages": ["services/*"]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "package.json", `{"name": "root", "workspaces": `+workspaces+`}`)
+			writePackage(t, dir, "services/api", "package.json", `{}`)
+
+			info := Detect(dir)
+			assert.Equal(t, WorkspaceNPM, info.Workspace)
+			assert.Equal(t, []PackageInfo{{Name: "services/api", Dir: "services/api"}}, info.Packages, "unnamed packages go by their directory")
+		})
+	}
+}
+
+func TestDetect_GoWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "go.work", "go 1.25.1\n\nuse (\n\t./cmd/tool // the CLI\n\t./lib\n)\nuse ./extra\n")
+	writePackage(t, dir, "cmd/tool", "go.mod", "module example.com/tool\n")
+	writePackage(t, dir, "lib", "go.mod", "module example.com/lib\n")
+
+	info := Detect(dir)
+	assert.Equal(t, LangGo, info.Language, "go.work alone marks a Go repo")
+	assert.Equal(t, "1.25.1", info.LanguageVersion)
+	assert.Equal(t, WorkspaceGo, info.Workspace)
+	assert.Equal(t, []PackageInfo{
+		{Name: "example.com/tool", Dir: "cmd/tool"},
+		{Name: "example.com/lib", Dir: "lib"},
+	}, info.Packages, "a use without a go.mod is skipped")
+}
+
+func TestDetect_CargoWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "Cargo.toml", "[workspace]\nresolver = \"2\"\nmembers = [\n  \"crates/*\", # all crates\n  \"xtask\",\n]\n\n[workspace.dependencies]\nserde = \"1\"\n")
+	writePackage(t, dir, "crates/core", "Cargo.toml", "[package]\nname = \"acme-core\"\nversion = \"0.1.0\"\n")
+	writePackage(t, dir, "xtask", "Cargo.toml", "[package]\nname = \"xtask\"\n")
+
+	info := Detect(dir)
+	assert.Equal(t, WorkspaceCargo, info.Workspace)
+	assert.Equal(t, []PackageInfo{
+		{Name: "acme-core", Dir: "crates/core"},
+		{Name: "xtask", Dir: "xtask"},
+	}, info.Packages)
+}
+
+func TestDetect_SinglePackage(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "Cargo.toml", "[package]\nname = \"solo\"\n")
+
+	info := Detect(dir)
+	assert.Empty(t, info.Workspace)
+	assert.Empty(t, info.Packages)
+}
+
+func TestFindPackage(t *testing.T) {
+	info := &ProjectInfo{Workspace: WorkspacePNPM, Packages: []PackageInfo{
+		{Name: "@acme/web", Dir: "apps/web"},
+		{Name: "@acme/api", Dir: "apps/api"},
+	}}
+
+	for _, name := range []string{"@acme/web", "apps/web", "./apps/web/"} {
+		pkg, err := info.FindPackage(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, "apps/web", pkg.Dir)
+	}
+
+	_, err := info.FindPackage("mobile")
+	require.EqualError(t, err, `no package "mobile" in pnpm-workspace.yaml; packages: @acme/web, @acme/api`)
+
+	_, err = (&ProjectInfo{}).FindPackage("web")
+	require.ErrorContains(t, err, "no workspace packages found")
+}
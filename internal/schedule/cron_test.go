@@ -0,0 +1,67 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	for expr, msg := range map[string]string{
+		"0 22 * *":      "want 5 fields",
+		"60 * * * *":    `minute: "60" is not in 0-59`,
+		"* 24 * * *":    `hour: "24" is not in 0-23`,
+		"* * 0 * *":     `day of month: "0" is not in 1-31`,
+		"* * * foo * ":  `month: "foo"`,
+		"* * * * 5-1":   `range "5-1" runs backwards`,
+		"*/0 * * * *":   `bad step "0"`,
+		"@fortnightly":  "want 5 fields",
+		"1,,2 * * * * ": `minute: ""`,
+	} {
+		_, err := ParseCron(expr)
+		require.Error(t, err, expr)
+		assert.Contains(t, err.Error(), msg, expr)
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	tests := []struct {
+		expr, from, want string
+	}{
+		// Friday evening: tonight, then Monday.
+		{"0 22 * * 1-5", "2026-10-16 09:30", "2026-10-16 22:00"},
+		{"0 22 * * 1-5", "2026-10-16 22:00", "2026-10-19 22:00"},
+		{"0 22 * * mon-fri", "2026-10-17 12:00", "2026-10-19 22:00"},
+		{"*/15 * * * *", "2026-10-16 09:31", "2026-10-16 09:45"},
+		{"5/20 9 * * *", "2026-10-16 09:26", "2026-10-16 09:45"},
+		{"0 0 1 jan *", "2026-10-16 09:30", "2027-01-01 00:00"},
+		{"@daily", "2026-10-16 23:59", "2026-10-17 00:00"},
+		{"@hourly", "2026-10-16 09:00", "2026-10-16 10:00"},
+		{"0 12 * * 7", "2026-10-16 09:30", "2026-10-18 12:00"}, // 7 is Sunday
+		// Both day fields restricted: the 20th or any Monday.
+		{"0 0 20 * 1", "2026-10-16 09:30", "2026-10-19 00:00"},
+		{"0 0 20 * 1", "2026-10-19 00:00", "2026-10-20 00:00"},
+		{"0 0 29 2 *", "2026-10-16 09:30", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, at(tt.want), c.Next(at(tt.from)), "%s from %s", tt.expr, tt.from)
+	}
+}
+
+func TestCron_NextNever(t *testing.T) {
+	c, err := ParseCron("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, c.Next(at("2026-10-16 09:30")).IsZero())
+}
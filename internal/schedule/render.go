@@ -0,0 +1,42 @@
+package schedule
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// TimeLayout shows run times to the minute, which is as fine as cron goes.
+const TimeLayout = "Mon 2006-01-02 15:04"
+
+// Render writes one line per schedule: its id, cron, command, next run
+// after now and how its last run went.
+//
+//nolint:errcheck // display output, best-effort writes
+func Render(w io.Writer, f *File, now time.Time, theme *ui.Theme) {
+	if len(f.Schedules) == 0 {
+		fmt.Fprintln(w, theme.Muted.Render("No schedules yet; add one with ralph schedule add"))
+		return
+	}
+	for i := range f.Schedules {
+		s := &f.Schedules[i]
+		next := theme.Muted.Render("never")
+		if t := s.Next(now); !t.IsZero() {
+			next = t.Format(TimeLayout)
+		}
+		var last string
+		switch {
+		case s.LastRun.IsZero():
+			last = theme.Muted.Render("not run yet")
+		case s.LastStatus == "ok":
+			last = theme.Success.Render("ok at " + s.LastRun.Format(TimeLayout))
+		default:
+			last = theme.Error.Render("failed at " + s.LastRun.Format(TimeLayout) + ": " + s.LastStatus)
+		}
+		fmt.Fprintf(w, "%3d  %-16s  %-28s  next %s  %s\n",
+			s.ID, s.Cron, "ralph "+strings.Join(s.Args, " "), next, last)
+	}
+}
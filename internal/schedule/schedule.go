@@ -0,0 +1,259 @@
+// Package schedule runs ralph commands at times given by cron expressions,
+// for teams that want unattended nightly progress. Schedules are kept in
+// Path; ralph schedule run is the daemon that starts them when they are due
+// and reports how each run went.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+)
+
+// Path is where schedules are kept, relative to the repo root.
+const Path = ".ralph/schedules.yaml"
+
+// LogDir holds the output of each scheduled run, relative to the repo root.
+const LogDir = ".ralph/logs/schedule"
+
+// Commands are the ralph commands a schedule may run.
+var Commands = []string{"plan", "build", "resume", "sync"}
+
+// Schedule is one recurring ralph command.
+type Schedule struct {
+	ID         int       `yaml:"id"`
+	Cron       string    `yaml:"cron"`
+	Args       []string  `yaml:"args"` // the ralph command and its flags, e.g. [build, --max, "10"]
+	Added      time.Time `yaml:"added"`
+	LastRun    time.Time `yaml:"last_run,omitempty"`
+	LastStatus string    `yaml:"last_status,omitempty"` // "ok", or why the last run failed
+}
+
+// File is the contents of Path.
+type File struct {
+	Schedules []Schedule `yaml:"schedules"`
+}
+
+// Load reads the schedules at path. A missing file has none.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is Path under the repo root
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading schedules: %w", err)
+	}
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing schedules: %w", err)
+	}
+	return &f, nil
+}
+
+// Save writes the schedules to path.
+func (f *File) Save(path string) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("encoding schedules: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("creating schedules dir: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing schedules: %w", err)
+	}
+	return nil
+}
+
+// Add validates and appends a schedule running args at times matching
+// cron, numbered after the highest existing id.
+func (f *File) Add(cron string, args []string, now time.Time) (*Schedule, error) {
+	if _, err := ParseCron(cron); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || !slices.Contains(Commands, args[0]) {
+		return nil, fmt.Errorf("a schedule runs one of: %s", strings.Join(Commands, ", "))
+	}
+	id := 1
+	for i := range f.Schedules {
+		id = max(id, f.Schedules[i].ID+1)
+	}
+	f.Schedules = append(f.Schedules, Schedule{ID: id, Cron: cron, Args: args, Added: now})
+	return &f.Schedules[len(f.Schedules)-1], nil
+}
+
+// Remove deletes the schedule with id, reporting whether there was one.
+func (f *File) Remove(id int) bool {
+	n := len(f.Schedules)
+	f.Schedules = slices.DeleteFunc(f.Schedules, func(s Schedule) bool { return s.ID == id })
+	return len(f.Schedules) < n
+}
+
+// Next returns when s next runs after t; zero if its cron never matches or
+// doesn't parse.
+func (s *Schedule) Next(t time.Time) time.Time {
+	c, err := ParseCron(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return c.Next(t)
+}
+
+// Result is how one scheduled run went.
+type Result struct {
+	Schedule Schedule
+	Started  time.Time
+	Finished time.Time
+	Log      string // the run's output
+	Err      error  // nil when the run succeeded
+}
+
+// Launcher runs a schedule's command, writing its output to out.
+type Launcher func(ctx context.Context, s *Schedule, out io.Writer) error
+
+// Daemon starts schedules when they fall due.
+type Daemon struct {
+	Path   string      // the schedules file; re-read on each check, so edits apply while running
+	LogDir string      // where each run's output is written
+	Clock  clock.Clock // nil = wall clock
+	Launch Launcher
+	Done   func(*Result) // called after each run; nil = ignore
+	Warn   func(error)   // called when the schedules file can't be read or written; nil = ignore
+}
+
+// Run checks for due schedules every poll until ctx is done. Times missed
+// before it started aren't caught up; a schedule that falls due while
+// another run is going starts once that run ends. A schedules file that
+// can't be read, e.g. while it is being edited, is reported to Warn and
+// read again on the next check, which still covers the times missed.
+func (d *Daemon) Run(ctx context.Context, poll time.Duration) {
+	c := clock.Or(d.Clock)
+	since := c.Now()
+	t := time.NewTicker(poll)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		now := c.Now()
+		if err := d.tick(ctx, since, now); err != nil {
+			d.warn(err)
+			continue
+		}
+		since = now
+	}
+}
+
+// tick runs, one at a time, each schedule due in (since, now]. Only failing
+// to read the schedules is returned; a run whose outcome can't be saved is
+// reported to Warn and the rest still run.
+func (d *Daemon) tick(ctx context.Context, since, now time.Time) error {
+	f, err := Load(d.Path)
+	if err != nil {
+		return err
+	}
+	for i := range f.Schedules {
+		s := f.Schedules[i]
+		next := s.Next(since)
+		if next.IsZero() || next.After(now) || ctx.Err() != nil {
+			continue
+		}
+		r := d.run(ctx, &s)
+		if err := d.record(r); err != nil {
+			d.warn(fmt.Errorf("recording schedule %d: %w", s.ID, err))
+		}
+		if d.Done != nil {
+			d.Done(r)
+		}
+	}
+	return nil
+}
+
+func (d *Daemon) warn(err error) {
+	if d.Warn != nil {
+		d.Warn(err)
+	}
+}
+
+// run launches s with its output in a new log file.
+func (d *Daemon) run(ctx context.Context, s *Schedule) *Result {
+	c := clock.Or(d.Clock)
+	r := &Result{Schedule: *s, Started: c.Now()}
+	r.Log = filepath.Join(d.LogDir, fmt.Sprintf("%d-%s.log", s.ID, r.Started.Format("20060102-150405")))
+	r.Err = func() error {
+		if err := os.MkdirAll(d.LogDir, 0o750); err != nil {
+			return fmt.Errorf("creating log dir: %w", err)
+		}
+		out, err := os.Create(r.Log) //nolint:gosec // log path built from the schedule id and time
+		if err != nil {
+			return fmt.Errorf("creating log: %w", err)
+		}
+		defer out.Close() //nolint:errcheck // best-effort log
+		return d.Launch(ctx, s, out)
+	}()
+	r.Finished = c.Now()
+	return r
+}
+
+// record stores r's outcome on its schedule, re-reading the file so changes
+// made during the run are kept. A schedule removed meanwhile is skipped.
+func (d *Daemon) record(r *Result) error {
+	f, err := Load(d.Path)
+	if err != nil {
+		return err
+	}
+	for i := range f.Schedules {
+		if f.Schedules[i].ID == r.Schedule.ID {
+			f.Schedules[i].LastRun = r.Started
+			f.Schedules[i].LastStatus = r.Status()
+			return f.Save(d.Path)
+		}
+	}
+	return nil
+}
+
+// Status is "ok", or why the run failed.
+func (r *Result) Status() string {
+	if r.Err == nil {
+		return "ok"
+	}
+	return r.Err.Error()
+}
+
+// Notify runs command with sh, describing r in its environment:
+// RALPH_SCHEDULE_ID, RALPH_SCHEDULE_COMMAND, RALPH_SCHEDULE_STATUS ("ok" or
+// "failed"), RALPH_SCHEDULE_ERROR and RALPH_SCHEDULE_LOG. Its output goes to
+// out.
+func Notify(ctx context.Context, command string, r *Result, out io.Writer) error {
+	status, errMsg := "ok", ""
+	if r.Err != nil {
+		status, errMsg = "failed", r.Err.Error()
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"RALPH_SCHEDULE_ID="+strconv.Itoa(r.Schedule.ID),
+		"RALPH_SCHEDULE_COMMAND=ralph "+strings.Join(r.Schedule.Args, " "),
+		"RALPH_SCHEDULE_STATUS="+status,
+		"RALPH_SCHEDULE_ERROR="+errMsg,
+		"RALPH_SCHEDULE_LOG="+r.Log,
+	)
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running notify command: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+)
+
+func TestFile_AddRemoveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ralph", "schedules.yaml")
+	f, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, f.Schedules, "a missing file has no schedules")
+
+	now := at("2026-10-16 09:30")
+	s, err := f.Add("0 22 * * 1-5", []string{"build", "--max", "10"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.ID)
+	s, err = f.Add("@daily", []string{"sync"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.ID)
+
+	_, err = f.Add("0 22 * *", []string{"build"}, now)
+	require.ErrorContains(t, err, "want 5 fields")
+	_, err = f.Add("@daily", []string{"apply"}, now)
+	require.ErrorContains(t, err, "plan, build, resume, sync")
+
+	require.True(t, f.Remove(1))
+	assert.False(t, f.Remove(1))
+	s, err = f.Add("@hourly", []string{"plan"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.ID, "ids aren't reused")
+	require.NoError(t, f.Save(path))
+
+	got, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, got.Schedules, 2)
+	assert.Equal(t, []string{"sync"}, got.Schedules[0].Args)
+	assert.Equal(t, "@hourly", got.Schedules[1].Cron)
+	assert.True(t, now.Equal(got.Schedules[1].Added))
+}
+
+func TestDaemon_Tick(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schedules.yaml")
+	f := &File{}
+	now := at("2026-10-16 09:30")
+	_, err := f.Add("0 22 * * 1-5", []string{"build", "--max", "10"}, now)
+	require.NoError(t, err)
+	_, err = f.Add("0 23 * * *", []string{"sync"}, now)
+	require.NoError(t, err)
+	require.NoError(t, f.Save(path))
+
+	var launched []int
+	var results []*Result
+	d := &Daemon{
+		Path:   path,
+		LogDir: filepath.Join(dir, "logs"),
+		Clock:  &clock.Fake{T: at("2026-10-16 22:00")},
+		Launch: func(_ context.Context, s *Schedule, out io.Writer) error {
+			launched = append(launched, s.ID)
+			fmt.Fprintln(out, "ran", s.Args[0]) //nolint:errcheck // test output
+			if s.ID == 2 {
+				return errors.New("exit status 1")
+			}
+			return nil
+		},
+		Done: func(r *Result) { results = append(results, r) },
+	}
+
+	require.NoError(t, d.tick(context.Background(), at("2026-10-16 21:59"), at("2026-10-16 22:00")))
+	assert.Equal(t, []int{1}, launched, "only the 22:00 schedule is due")
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	log, err := os.ReadFile(results[0].Log)
+	require.NoError(t, err)
+	assert.Equal(t, "ran build\n", string(log))
+
+	require.NoError(t, d.tick(context.Background(), at("2026-10-16 22:00"), at("2026-10-16 22:30")))
+	assert.Equal(t, []int{1}, launched, "nothing falls due between checks")
+
+	// A check that spans both times runs each once.
+	launched = nil
+	require.NoError(t, d.tick(context.Background(), at("2026-10-19 21:00"), at("2026-10-19 23:30")))
+	assert.Equal(t, []int{1, 2}, launched)
+
+	got, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", got.Schedules[0].LastStatus)
+	assert.Equal(t, "exit status 1", got.Schedules[1].LastStatus)
+	assert.False(t, got.Schedules[1].LastRun.IsZero())
+}
+
+func TestDaemon_RunKeepsGoingPastErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schedules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("schedules: [\n"), 0o600))
+	valid := &File{}
+	_, err := valid.Add("0 22 * * *", []string{"build"}, at("2026-10-16 09:30"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	warnings := make(chan error)
+	done := make(chan *Result)
+	d := &Daemon{
+		Path:   path,
+		LogDir: filepath.Join(dir, "logs"),
+		Clock:  &clock.Fake{T: at("2026-10-16 21:59"), Step: time.Minute}, // each check is a minute on
+		Launch: func(context.Context, *Schedule, io.Writer) error {
+			// Leaves the file unreadable, so the run's outcome can't be saved.
+			return os.WriteFile(path, []byte("schedules: [\n"), 0o600)
+		},
+		Done: func(r *Result) { done <- r },
+		Warn: func(err error) {
+			select {
+			case warnings <- err:
+			case <-ctx.Done():
+			}
+		},
+	}
+	stopped := make(chan struct{})
+	go func() {
+		d.Run(ctx, time.Millisecond)
+		close(stopped)
+	}()
+
+	// 22:00 passes while the file can't be read; the run still starts
+	// once it can.
+	require.ErrorContains(t, <-warnings, "parsing schedules")
+	require.ErrorContains(t, <-warnings, "parsing schedules")
+	require.NoError(t, valid.Save(path))
+
+	var r *Result
+	var recorded error
+	for r == nil {
+		select {
+		case err := <-warnings:
+			if strings.HasPrefix(err.Error(), "recording schedule 1") {
+				recorded = err
+			}
+		case r = <-done:
+		}
+	}
+	assert.Equal(t, 1, r.Schedule.ID)
+	require.ErrorContains(t, recorded, "parsing schedules", "the unsaved outcome is reported, not fatal")
+
+	cancel()
+	<-stopped
+}
+
+func TestNotify(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "notified")
+	r := &Result{Schedule: Schedule{ID: 4, Args: []string{"build", "--max", "10"}}, Log: "/tmp/4.log", Err: errors.New("boom")}
+	cmd := `printf '%s|%s|%s|%s|%s' "$RALPH_SCHEDULE_ID" "$RALPH_SCHEDULE_COMMAND" "$RALPH_SCHEDULE_STATUS" "$RALPH_SCHEDULE_ERROR" "$RALPH_SCHEDULE_LOG" > ` + out
+	require.NoError(t, Notify(context.Background(), cmd, r, io.Discard))
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "4|ralph build --max 10|failed|boom|/tmp/4.log", string(got))
+
+	require.ErrorContains(t, Notify(context.Background(), "exit 3", r, io.Discard), "running notify command")
+}
@@ -0,0 +1,186 @@
+// Package share uploads a run's report to object storage and links to it,
+// so teammates without access to the repo's .ralph/logs can review a run.
+// S3 and Google Cloud Storage are both reached through the S3 API, which GCS
+// serves for HMAC keys.
+package share
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Providers accepted by the share.provider config key.
+const (
+	ProviderS3  = "s3"
+	ProviderGCS = "gcs"
+)
+
+// ValidProvider reports whether p is a recognised provider. Empty means
+// sharing is off.
+func ValidProvider(p string) bool {
+	return p == "" || p == ProviderS3 || p == ProviderGCS
+}
+
+// DefaultCredentialEnv returns the environment variables holding the access
+// key id and secret for provider.
+func DefaultCredentialEnv(provider string) (accessKey, secretKey string) {
+	if provider == ProviderGCS {
+		return "GCS_ACCESS_KEY_ID", "GCS_SECRET_ACCESS_KEY"
+	}
+	return "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"
+}
+
+// MaxLinkExpiry is the longest a presigned link can stay valid.
+const MaxLinkExpiry = 7 * 24 * time.Hour
+
+// Client uploads objects over the S3 API with SigV4-signed requests.
+type Client struct {
+	Provider     string
+	Bucket       string
+	Region       string // signing region; empty = us-east-1 (S3) or auto (GCS)
+	Endpoint     string // API root; empty = the provider's public endpoint
+	AccessKey    string
+	SecretKey    string
+	SessionToken string        // temporary S3 credentials; empty = none
+	LinkExpiry   time.Duration // presign links for this long; 0 = plain object URLs
+	HTTP         *http.Client  // nil = a client with a short timeout
+	Now          func() time.Time
+}
+
+// New returns a client for provider and bucket, or an error naming what is
+// missing.
+func New(provider, bucket, accessKey, secretKey string) (*Client, error) {
+	if provider != ProviderS3 && provider != ProviderGCS {
+		return nil, fmt.Errorf("unknown share provider %q (want s3 or gcs)", provider)
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("share.bucket is not set")
+	}
+	if accessKey == "" || secretKey == "" {
+		keyEnv, secretEnv := DefaultCredentialEnv(provider)
+		return nil, fmt.Errorf("no %s credentials: set %s and %s", provider, keyEnv, secretEnv)
+	}
+	return &Client{Provider: provider, Bucket: bucket, AccessKey: accessKey, SecretKey: secretKey}, nil
+}
+
+func (c *Client) region() string {
+	switch {
+	case c.Region != "":
+		return c.Region
+	case c.Provider == ProviderGCS:
+		return "auto"
+	}
+	return "us-east-1"
+}
+
+// endpoint returns the API root. Objects are addressed path-style, which
+// every S3-compatible store accepts.
+func (c *Client) endpoint() (*url.URL, error) {
+	raw := c.Endpoint
+	switch {
+	case raw != "":
+	case c.Provider == ProviderGCS:
+		raw = "https://storage.googleapis.com"
+	default:
+		raw = "https://s3." + c.region() + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimSuffix(raw, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid share endpoint %q", raw)
+	}
+	return u, nil
+}
+
+// objectURL returns the URL of key in the bucket.
+func (c *Client) objectURL(key string) (*url.URL, error) {
+	u, err := c.endpoint()
+	if err != nil {
+		return nil, err
+	}
+	u.Path += "/" + c.Bucket + "/" + key
+	u.RawPath = uriEncode(u.Path, false) // the escaping SigV4 signs
+	return u, nil
+}
+
+func (c *Client) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Put uploads body as key.
+func (c *Client) Put(ctx context.Context, key, contentType string, body []byte) error {
+	u, err := c.objectURL(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	c.signer().sign(req, body, c.now())
+
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("uploading %s: %w", key, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512)) //nolint:errcheck // best-effort detail
+		return fmt.Errorf("uploading %s: %s %s", key, resp.Status, errorCode(msg))
+	}
+	return nil
+}
+
+// Link returns a URL for key: presigned when LinkExpiry is set, so the
+// bucket can stay private, else the plain object URL.
+func (c *Client) Link(key string) (string, error) {
+	u, err := c.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	if c.LinkExpiry <= 0 {
+		return u.String(), nil
+	}
+	return c.signer().presign(u, c.now(), c.LinkExpiry), nil
+}
+
+func (c *Client) signer() *signer {
+	return &signer{accessKey: c.AccessKey, secretKey: c.SecretKey, sessionToken: c.SessionToken, region: c.region()}
+}
+
+// errorCode extracts <Code> from an S3 XML error body.
+func errorCode(body []byte) string {
+	s := string(body)
+	start := strings.Index(s, "<Code>")
+	end := strings.Index(s, "</Code>")
+	if start < 0 || end < start {
+		return ""
+	}
+	return "(" + s[start+len("<Code>"):end] + ")"
+}
+
+// Key joins the object key for a run's file: prefix/project/branch/run/name.
+// Empty parts are left out.
+func Key(prefix, project, branch, run, name string) string {
+	parts := make([]string, 0, 5)
+	for _, p := range []string{strings.Trim(prefix, "/"), project, branch, run, name} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return path.Join(parts...)
+}
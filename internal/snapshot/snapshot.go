@@ -0,0 +1,380 @@
+// Package snapshot saves the whole workspace before a risky run so it can be
+// put back in one step: the branch and its head, uncommitted changes to
+// tracked files as a stash commit kept alive by a ref under
+// refs/ralph/snapshots/, and untracked files as an archive. Everything is
+// kept in the git directory, where neither the working tree nor the agent's
+// commits can reach it. Ignored files (.env, dependencies) aren't saved.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/git"
+)
+
+// RefPrefix is where each snapshot's commit is referenced.
+const RefPrefix = "refs/ralph/snapshots/"
+
+// DefaultKeep is how many snapshots Create keeps; older ones are pruned.
+const DefaultKeep = 10
+
+// ErrNotFound is returned when there is no snapshot to restore.
+var ErrNotFound = errors.New("no snapshot")
+
+// Snapshot describes one saved workspace.
+type Snapshot struct {
+	ID        string    `json:"id"`
+	Created   time.Time `json:"created"`
+	Reason    string    `json:"reason,omitempty"` // e.g. "before ralph build"
+	Branch    string    `json:"branch,omitempty"` // empty = detached HEAD
+	Head      string    `json:"head"`
+	Stash     string    `json:"stash,omitempty"`     // stash commit of uncommitted changes to tracked files; empty = none
+	Untracked int       `json:"untracked,omitempty"` // untracked files archived
+}
+
+// Create saves the workspace at repoRoot, then prunes all but the newest
+// keep snapshots.
+func Create(ctx context.Context, repoRoot, reason string, now time.Time, keep int) (*Snapshot, error) {
+	repo := git.At(repoRoot)
+	dir, err := storeDir(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	branch, err := repo.Branch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	if branch == "HEAD" {
+		branch = ""
+	}
+	stash, err := repo.StashCreate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: saving uncommitted changes: %w", err)
+	}
+	untracked, err := repo.UntrackedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+
+	s := &Snapshot{ID: newID(dir, now), Created: now, Reason: reason, Branch: branch, Head: head, Stash: stash, Untracked: len(untracked)}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	if err := writeArchive(filepath.Join(dir, s.ID+".tar.gz"), repoRoot, untracked); err != nil {
+		return nil, fmt.Errorf("snapshot: archiving untracked files: %w", err)
+	}
+	ref := head
+	if stash != "" {
+		ref = stash // its first parent is head
+	}
+	if err := repo.UpdateRef(ctx, RefPrefix+s.ID, ref); err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, s.ID+".json"), append(data, '\n'), 0o600); err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	if err := prune(ctx, repo, dir, keep); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// List returns the snapshots of the repo at repoRoot, newest first.
+func List(ctx context.Context, repoRoot string) ([]Snapshot, error) {
+	dir, err := storeDir(ctx, git.At(repoRoot))
+	if err != nil {
+		return nil, err
+	}
+	return list(dir)
+}
+
+// Find returns the snapshot with id, or the newest when id is empty.
+func Find(ctx context.Context, repoRoot, id string) (*Snapshot, error) {
+	all, err := List(ctx, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	for i := range all {
+		if id == "" || all[i].ID == id {
+			return &all[i], nil
+		}
+	}
+	if id == "" {
+		return nil, fmt.Errorf("%w taken yet; run \"ralph snapshot create\" or set safety.snapshot: true", ErrNotFound)
+	}
+	return nil, fmt.Errorf("%w %q; \"ralph snapshot list\" shows them", ErrNotFound, id)
+}
+
+// Restore puts the workspace at repoRoot back as s saved it: the branch is
+// reset to its head, uncommitted changes are reapplied, and untracked files
+// are replaced by the archived ones. Commits made since, on the branch or
+// pushed, are only reachable from the reflog and the remote afterwards.
+func Restore(ctx context.Context, repoRoot string, s *Snapshot) error {
+	repo := git.At(repoRoot)
+	dir, err := storeDir(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if err := repo.ForceCheckout(ctx, s.Branch, s.Head); err != nil {
+		return fmt.Errorf("restoring %s: %w", s.ID, err)
+	}
+	if err := repo.Clean(ctx); err != nil {
+		return fmt.Errorf("restoring %s: %w", s.ID, err)
+	}
+	if s.Stash != "" {
+		if err := repo.StashApply(ctx, s.Stash); err != nil {
+			return fmt.Errorf("restoring %s: reapplying uncommitted changes: %w", s.ID, err)
+		}
+	}
+	if err := readArchive(filepath.Join(dir, s.ID+".tar.gz"), repoRoot); err != nil {
+		return fmt.Errorf("restoring %s: untracked files: %w", s.ID, err)
+	}
+	return nil
+}
+
+// storeDir is where snapshots are kept, shared by the repo's worktrees.
+func storeDir(ctx context.Context, repo *git.Repo) (string, error) {
+	common, err := repo.CommonDir(ctx)
+	if err != nil {
+		return "", fmt.Errorf("snapshot: %w", err)
+	}
+	return filepath.Join(common, "ralph", "snapshots"), nil
+}
+
+// newID names a snapshot by its UTC time, suffixed when one taken in the
+// same second exists.
+func newID(dir string, now time.Time) string {
+	base := now.UTC().Format("20060102-150405")
+	id := base
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(dir, id+".json")); errors.Is(err, fs.ErrNotExist) {
+			return id
+		}
+		id = base + "-" + strconv.Itoa(n)
+	}
+}
+
+func list(dir string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	var out []Snapshot
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name())) //nolint:gosec // under the git directory
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: %w", err)
+		}
+		var s Snapshot
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("snapshot: reading %s: %w", e.Name(), err)
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Created.Equal(out[j].Created) {
+			return out[i].Created.After(out[j].Created)
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out, nil
+}
+
+// prune deletes all but the newest keep snapshots; keep <= 0 keeps all.
+func prune(ctx context.Context, repo *git.Repo, dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	all, err := list(dir)
+	if err != nil || len(all) <= keep {
+		return err
+	}
+	for _, s := range all[keep:] {
+		if err := repo.DeleteRef(ctx, RefPrefix+s.ID); err != nil {
+			return fmt.Errorf("snapshot: pruning %s: %w", s.ID, err)
+		}
+		for _, ext := range []string{".json", ".tar.gz"} {
+			if err := os.Remove(filepath.Join(dir, s.ID+ext)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("snapshot: pruning %s: %w", s.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeArchive stores files, relative to root, in a tar.gz at path.
+// Symlinks are stored as links.
+func writeArchive(path, root string, files []string) (err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) //nolint:gosec // under the git directory
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by Create
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, name := range files {
+		if err := addFile(tw, root, name); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err //nolint:wrapcheck // wrapped by Create
+	}
+	return gz.Close() //nolint:wrapcheck // wrapped by Create
+}
+
+func addFile(tw *tar.Writer, root, name string) error {
+	full := filepath.Join(root, filepath.FromSlash(name))
+	info, err := os.Lstat(full)
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by Create
+	}
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(full); err != nil {
+			return err //nolint:wrapcheck // wrapped by Create
+		}
+	} else if !info.Mode().IsRegular() {
+		return nil
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by Create
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err //nolint:wrapcheck // wrapped by Create
+	}
+	if link != "" {
+		return nil
+	}
+	src, err := os.Open(full) //nolint:gosec // an untracked file in the repo
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by Create
+	}
+	defer src.Close() //nolint:errcheck // read-only
+	_, err = io.Copy(tw, src)
+	return err //nolint:wrapcheck // wrapped by Create
+}
+
+// readArchive extracts the tar.gz at path under root. A snapshot without
+// untracked files may have no archive.
+func readArchive(path, root string) error {
+	f, err := os.Open(path) //nolint:gosec // under the git directory
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by Restore
+	}
+	defer f.Close() //nolint:errcheck // read-only
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by Restore
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped by Restore
+		}
+		if !filepath.IsLocal(filepath.FromSlash(hdr.Name)) {
+			return fmt.Errorf("unsafe path %q in archive", hdr.Name)
+		}
+		dest := filepath.Join(root, filepath.FromSlash(hdr.Name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0o750); err != nil {
+			return err //nolint:wrapcheck // wrapped by Restore
+		}
+		if err := os.RemoveAll(dest); err != nil {
+			return err //nolint:wrapcheck // wrapped by Restore
+		}
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, dest); err != nil {
+				return err //nolint:wrapcheck // wrapped by Restore
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, dest, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected entry %q in archive", hdr.Name)
+		}
+	}
+}
+
+func extractFile(r io.Reader, dest string, perm fs.FileMode) (err error) {
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm) //nolint:gosec // checked to be under the repo root
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by Restore
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	if _, err := io.Copy(out, r); err != nil { //nolint:gosec // our own archive, its size bounded by what was archived
+		return err //nolint:wrapcheck // wrapped by Restore
+	}
+	return nil
+}
+
+// String is a one-line description, e.g. for listing.
+func (s *Snapshot) String() string {
+	var parts []string
+	if s.Branch != "" {
+		parts = append(parts, s.Branch)
+	}
+	parts = append(parts, shortSHA(s.Head))
+	if s.Stash != "" {
+		parts = append(parts, "uncommitted changes")
+	}
+	if s.Untracked > 0 {
+		parts = append(parts, fmt.Sprintf("%d untracked file(s)", s.Untracked))
+	}
+	if s.Reason != "" {
+		parts = append(parts, s.Reason)
+	}
+	return s.ID + "  " + strings.Join(parts, ", ")
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
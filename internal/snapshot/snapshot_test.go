@@ -0,0 +1,114 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/testutil"
+)
+
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+}
+
+func read(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name)) //nolint:gosec // test file
+	require.NoError(t, err)
+	return string(data)
+}
+
+func gitOut(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.CommandContext(context.Background(), "git", append([]string{"-C", dir}, args...)...).Output() //nolint:gosec // test
+	require.NoError(t, err)
+	return strings.TrimSpace(string(out))
+}
+
+func TestCreateRestore(t *testing.T) {
+	ctx := context.Background()
+	_, dir := testutil.InitBareAndClone(t)
+	testutil.RunGit(t, dir, "checkout", "-b", "feat")
+	write(t, dir, "app.go", "package app\n")
+	write(t, dir, ".gitignore", ".env\n")
+	testutil.RunGit(t, dir, "add", "app.go", ".gitignore")
+	testutil.RunGit(t, dir, "commit", "-m", "app")
+	head := gitOut(t, dir, "rev-parse", "HEAD")
+
+	// The workspace as the user leaves it: a staged edit, an unstaged one,
+	// an untracked file, and an ignored one.
+	write(t, dir, "app.go", "package app\n\n// staged\n")
+	testutil.RunGit(t, dir, "add", "app.go")
+	write(t, dir, ".gitignore", ".env\nbin/\n")
+	write(t, dir, "notes/todo.txt", "my notes\n")
+	write(t, dir, ".env", "KEY=1\n")
+
+	const wantStatus = "M .gitignore\nM  app.go\n?? notes/" // " M .gitignore" with its leading space trimmed
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	s, err := Create(ctx, dir, "before ralph build", now, DefaultKeep)
+	require.NoError(t, err)
+	assert.Equal(t, "20261016-090000", s.ID)
+	assert.Equal(t, "feat", s.Branch)
+	assert.Equal(t, head, s.Head)
+	assert.NotEmpty(t, s.Stash)
+	assert.Equal(t, 1, s.Untracked)
+	assert.Equal(t, wantStatus, gitOut(t, dir, "status", "--porcelain"), "taking a snapshot changes nothing")
+
+	// A bad run: commits, edits and deletes tracked and untracked files.
+	testutil.RunGit(t, dir, "add", "-A")
+	testutil.RunGit(t, dir, "commit", "-m", "agent work")
+	write(t, dir, "app.go", "broken\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "notes/todo.txt")))
+	write(t, dir, "scratch.txt", "agent junk\n")
+	write(t, dir, ".env", "KEY=2\n")
+
+	found, err := Find(ctx, dir, "")
+	require.NoError(t, err)
+	require.Equal(t, s.ID, found.ID)
+	require.NoError(t, Restore(ctx, dir, found))
+
+	assert.Equal(t, head, gitOut(t, dir, "rev-parse", "HEAD"))
+	assert.Equal(t, "feat", gitOut(t, dir, "branch", "--show-current"))
+	assert.Equal(t, wantStatus, gitOut(t, dir, "status", "--porcelain"))
+	assert.Equal(t, "package app\n\n// staged\n", read(t, dir, "app.go"))
+	assert.Equal(t, "my notes\n", read(t, dir, "notes/todo.txt"))
+	assert.NoFileExists(t, filepath.Join(dir, "scratch.txt"))
+	assert.Equal(t, "KEY=2\n", read(t, dir, ".env"), "ignored files aren't part of a snapshot")
+}
+
+func TestCreate_CleanTreeAndPrune(t *testing.T) {
+	ctx := context.Background()
+	_, dir := testutil.InitBareAndClone(t)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for i := range 4 {
+		s, err := Create(ctx, dir, "", now.Add(time.Duration(i/2)*time.Minute), 3)
+		require.NoError(t, err)
+		assert.Empty(t, s.Stash)
+		assert.Zero(t, s.Untracked)
+		ids = append(ids, s.ID)
+	}
+	assert.Equal(t, []string{"20261016-090000", "20261016-090000-2", "20261016-090100", "20261016-090100-2"}, ids)
+
+	all, err := List(ctx, dir)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, "20261016-090100-2", all[0].ID, "newest first")
+	assert.Equal(t, "20261016-090000-2", all[2].ID, "the oldest is pruned")
+	assert.Empty(t, gitOut(t, dir, "for-each-ref", RefPrefix+"20261016-090000"))
+
+	_, err = Find(ctx, dir, "nope")
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,68 @@
+package specs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// SuggestBranch names a feature branch after the first spec, alphabetically,
+// in the first of dirs that has any, e.g. "specs/user-auth.md" → "user-auth".
+// It returns "" when none of dirs has a spec.
+func SuggestBranch(dirs ...string) string {
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if !e.IsDir() && filepath.Ext(e.Name()) == ".md" {
+				names = append(names, e.Name())
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			return strings.TrimSuffix(NormalizeName(names[0]), ".md")
+		}
+	}
+	return ""
+}
+
+// PromptBranch asks for the name of a feature branch to create instead of
+// running on protected, prefilled with suggested.
+func PromptBranch(in io.Reader, out io.Writer, accessible bool, protected, suggested string) (string, error) {
+	name := suggested
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(fmt.Sprintf("Ralph doesn't run on %s. Create a feature branch:", protected)).
+				Value(&name).
+				Validate(func(s string) error {
+					if strings.TrimSpace(s) == "" {
+						return errors.New("enter a branch name")
+					}
+					if strings.ContainsAny(strings.TrimSpace(s), " \t") {
+						return errors.New("branch names can't contain spaces")
+					}
+					return nil
+				}),
+		),
+	).WithAccessible(accessible).
+		WithTheme(ui.HuhTheme()).
+		WithInput(in).
+		WithOutput(out)
+
+	if err := form.Run(); err != nil {
+		return "", err //nolint:wrapcheck // propagate huh errors directly
+	}
+	return strings.TrimSpace(name), nil
+}
@@ -0,0 +1,25 @@
+package specs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestBranch(t *testing.T) {
+	root := t.TempDir()
+	branchDir := filepath.Join(root, "specs", "main")
+	require.NoError(t, os.MkdirAll(branchDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "specs", "Billing Export.md"), []byte("# B\n"), 0o600))
+
+	assert.Equal(t, "billing-export", SuggestBranch(branchDir, filepath.Join(root, "specs")))
+
+	require.NoError(t, os.WriteFile(filepath.Join(branchDir, "zeta.md"), []byte("# Z\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(branchDir, "alpha.md"), []byte("# A\n"), 0o600))
+	assert.Equal(t, "alpha", SuggestBranch(branchDir, filepath.Join(root, "specs")))
+
+	assert.Empty(t, SuggestBranch(filepath.Join(root, "missing")))
+}
@@ -0,0 +1,240 @@
+package specs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrNoConverter is returned for document types that can't be converted to
+// markdown on this machine.
+var ErrNoConverter = errors.New("no markdown converter")
+
+// maxDocumentSize caps a single imported document (and each part read from
+// inside a .docx archive).
+const maxDocumentSize = 32 << 20
+
+// ToMarkdown converts a document to markdown based on its extension:
+// markdown and plain text pass through, .docx is converted in-process, and
+// .pdf goes through pdftotext when it is installed.
+func ToMarkdown(ctx context.Context, name string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown", ".txt":
+		return string(data), nil
+	case ".docx":
+		return docxToMarkdown(data)
+	case ".pdf":
+		return pdfToMarkdown(ctx, data)
+	default:
+		return "", fmt.Errorf("%w for %s files", ErrNoConverter, filepath.Ext(name))
+	}
+}
+
+// pdfToMarkdown extracts a PDF's text with poppler's pdftotext. The result is
+// plain text, which is valid markdown.
+func pdfToMarkdown(ctx context.Context, data []byte) (string, error) {
+	bin, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", fmt.Errorf("%w for .pdf files: install pdftotext (poppler) or convert it first", ErrNoConverter)
+	}
+	cmd := exec.CommandContext(ctx, bin, "-layout", "-enc", "UTF-8", "-", "-") //nolint:gosec // fixed arguments
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pdftotext: %w", err)
+	}
+	return string(out), nil
+}
+
+// docxToMarkdown converts the paragraphs of a Word document: heading styles
+// become #-headings, numbered or bulleted paragraphs become list items, and
+// bold/italic runs are kept. Tables are flattened to one paragraph per cell.
+func docxToMarkdown(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("reading docx: %w", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("reading docx: %w", err)
+		}
+		defer rc.Close() //nolint:errcheck // read-only
+		return renderDocx(io.LimitReader(rc, maxDocumentSize))
+	}
+	return "", errors.New("reading docx: word/document.xml not found")
+}
+
+// docxParagraph accumulates one <w:p> while decoding.
+type docxParagraph struct {
+	style   string
+	list    bool
+	text    strings.Builder
+	bold    bool
+	italic  bool
+	inRun   bool
+	inText  bool
+	runText strings.Builder
+}
+
+func renderDocx(r io.Reader) (string, error) {
+	dec := xml.NewDecoder(r)
+	var (
+		out    strings.Builder
+		p      *docxParagraph
+		inList bool
+	)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parsing docx: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				p = &docxParagraph{}
+			case "pStyle":
+				if p != nil {
+					p.style = attr(t, "val")
+				}
+			case "numPr":
+				if p != nil {
+					p.list = true
+				}
+			case "r":
+				if p != nil {
+					p.inRun, p.bold, p.italic = true, false, false
+					p.runText.Reset()
+				}
+			case "b":
+				if p != nil && p.inRun && attr(t, "val") != "0" && attr(t, "val") != "false" {
+					p.bold = true
+				}
+			case "i":
+				if p != nil && p.inRun && attr(t, "val") != "0" && attr(t, "val") != "false" {
+					p.italic = true
+				}
+			case "t":
+				if p != nil {
+					p.inText = true
+				}
+			case "tab":
+				if p != nil && p.inRun {
+					p.runText.WriteString("\t")
+				}
+			case "br":
+				if p != nil && p.inRun {
+					p.runText.WriteString("\n")
+				}
+			}
+		case xml.CharData:
+			if p != nil && p.inText {
+				p.runText.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				if p != nil {
+					p.inText = false
+				}
+			case "r":
+				if p != nil && p.inRun {
+					p.text.WriteString(emphasize(p.runText.String(), p.bold, p.italic))
+					p.inRun = false
+				}
+			case "p":
+				if p != nil {
+					inList = writeParagraph(&out, p, inList)
+					p = nil
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(out.String()) + "\n", nil
+}
+
+func attr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// emphasize wraps text in markdown bold/italic markers, keeping surrounding
+// whitespace outside them.
+func emphasize(text string, bold, italic bool) string {
+	core := strings.TrimSpace(text)
+	if core == "" || (!bold && !italic) {
+		return text
+	}
+	marker := ""
+	if bold {
+		marker += "**"
+	}
+	if italic {
+		marker += "_"
+	}
+	lead := text[:strings.Index(text, core)]
+	trail := text[len(lead)+len(core):]
+	return lead + marker + core + reverse(marker) + trail
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// writeParagraph appends p and reports whether it was a list item, so the
+// paragraph after a list can be separated from it.
+func writeParagraph(out *strings.Builder, p *docxParagraph, inList bool) bool {
+	text := strings.TrimSpace(p.text.String())
+	if text == "" {
+		return inList
+	}
+	if p.list || strings.HasPrefix(strings.ToLower(p.style), "list") {
+		out.WriteString("- " + text + "\n")
+		return true
+	}
+	if inList {
+		out.WriteString("\n")
+	}
+	if level := headingLevel(p.style); level > 0 {
+		out.WriteString(strings.Repeat("#", level) + " " + text + "\n\n")
+	} else {
+		out.WriteString(text + "\n\n")
+	}
+	return false
+}
+
+// headingLevel maps Word heading styles ("Title", "Heading1".."Heading6") to
+// a markdown heading level, or 0 for body text.
+func headingLevel(style string) int {
+	s := strings.ToLower(style)
+	if s == "title" {
+		return 1
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(s, "heading")); err == nil && strings.HasPrefix(s, "heading") {
+		return min(max(n, 1), 6)
+	}
+	return 0
+}
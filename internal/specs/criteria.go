@@ -0,0 +1,124 @@
+package specs
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	headingLine   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletLine    = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?(.*)$`)
+	checkboxLine  = regexp.MustCompile(`^\s*[-*+]\s+\[[ xX]\]\s+(.*)$`)
+	inlineMarkup  = regexp.MustCompile("\\*\\*|__|`")
+	acceptanceHdr = regexp.MustCompile(`(?i)\bacceptance\b`)
+)
+
+// AcceptanceCriteria returns the acceptance criteria listed in a markdown
+// spec: the top-level bullets under every heading that mentions
+// "acceptance" (e.g. "## Acceptance Criteria"), up to the next heading of
+// the same or a higher level. A spec without such a heading falls back to
+// its checklist items ("- [ ] ..."). Wrapped bullet lines are joined and
+// bold/code markers are dropped; nested bullets are treated as detail of
+// their parent and skipped.
+func AcceptanceCriteria(md string) []string {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	if criteria := criteriaUnderHeadings(lines); len(criteria) > 0 {
+		return criteria
+	}
+	var out []string
+	inFence := false
+	for _, line := range lines {
+		if isFence(line) {
+			inFence = !inFence
+			continue
+		}
+		if m := checkboxLine.FindStringSubmatch(line); m != nil && !inFence {
+			out = appendCriterion(out, m[1])
+		}
+	}
+	return out
+}
+
+func criteriaUnderHeadings(lines []string) []string {
+	var (
+		out      []string
+		level    int    // heading level of the current acceptance section; 0 = outside one
+		indent   = -1   // indent of the section's top-level bullets
+		current  string // bullet being accumulated
+		inFence  bool
+		inBullet bool
+	)
+	flush := func() {
+		if inBullet {
+			out = appendCriterion(out, current)
+		}
+		current, inBullet = "", false
+	}
+
+	for _, line := range lines {
+		if isFence(line) {
+			inFence = !inFence
+			flush()
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if m := headingLine.FindStringSubmatch(line); m != nil {
+			flush()
+			switch l := len(m[1]); {
+			case acceptanceHdr.MatchString(m[2]):
+				level, indent = l, -1
+			case level > 0 && l <= level:
+				level = 0
+			}
+			continue
+		}
+		if level == 0 {
+			continue
+		}
+		if m := bulletLine.FindStringSubmatch(line); m != nil {
+			depth := len(m[1])
+			if indent < 0 {
+				indent = depth
+			}
+			if depth <= indent {
+				flush()
+				current, inBullet = m[2], true
+			}
+			// Deeper bullets are detail of the current criterion.
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case inBullet && strings.HasPrefix(line, " "):
+			current += " " + trimmed // wrapped bullet text
+		default:
+			flush()
+		}
+	}
+	flush()
+	return out
+}
+
+func isFence(line string) bool {
+	t := strings.TrimSpace(line)
+	return strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~")
+}
+
+func appendCriterion(out []string, text string) []string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, inlineMarkup.ReplaceAllString(text, ""))
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return out
+	}
+	return append(out, text)
+}
@@ -0,0 +1,52 @@
+package specs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptanceCriteria_Heading(t *testing.T) {
+	md := `# Login
+
+Some intro.
+
+- not a criterion
+
+## Acceptance Criteria
+
+- Users can log in with **email** and password
+- Failed logins show an error
+  that wraps onto a second line
+  - nested detail is skipped
+1. Sessions expire after ` + "`30m`" + `
+
+### Notes under acceptance
+
+- still part of the section
+
+## Out of scope
+
+- SSO
+`
+	assert.Equal(t, []string{
+		"Users can log in with email and password",
+		"Failed logins show an error that wraps onto a second line",
+		"Sessions expire after 30m",
+		"still part of the section",
+	}, AcceptanceCriteria(md))
+}
+
+func TestAcceptanceCriteria_IgnoresFencedCode(t *testing.T) {
+	md := "## Acceptance\n\n```\n- not a bullet\n```\n\n- real one\n"
+	assert.Equal(t, []string{"real one"}, AcceptanceCriteria(md))
+}
+
+func TestAcceptanceCriteria_ChecklistFallback(t *testing.T) {
+	md := "# Spec\n\n- plain bullet\n- [ ] first check\n- [x] second check\n\n```\n- [ ] in code\n```\n"
+	assert.Equal(t, []string{"first check", "second check"}, AcceptanceCriteria(md))
+}
+
+func TestAcceptanceCriteria_None(t *testing.T) {
+	assert.Empty(t, AcceptanceCriteria("# Spec\n\n- just a bullet\n"))
+}
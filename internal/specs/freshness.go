@@ -0,0 +1,25 @@
+package specs
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stale explains why a plan may no longer match its specs, judged by when
+// each was last committed. It returns "" when the plan looks current, or
+// when either has never been committed. A plan is stale when the specs
+// changed after it, or when it is older than maxAge though the specs
+// haven't changed since; maxAge 0 skips the age check.
+func Stale(planChanged, specsChanged, now time.Time, maxAge time.Duration) string {
+	if planChanged.IsZero() || specsChanged.IsZero() {
+		return ""
+	}
+	if specsChanged.After(planChanged) {
+		return fmt.Sprintf("the specs changed on %s, after the plan was last updated on %s",
+			specsChanged.Format(time.DateOnly), planChanged.Format(time.DateOnly))
+	}
+	if age := now.Sub(planChanged); maxAge > 0 && age > maxAge {
+		return fmt.Sprintf("the plan was last updated %d days ago, on %s", int(age.Hours()/24), planChanged.Format(time.DateOnly))
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package specs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStale(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	days := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	maxAge := 14 * 24 * time.Hour
+
+	assert.Empty(t, Stale(days(3), days(5), now, maxAge), "recent plan, specs older")
+	assert.Equal(t, "the specs changed on 2026-10-14, after the plan was last updated on 2026-10-11",
+		Stale(days(5), days(2), now, maxAge))
+	assert.Equal(t, "the plan was last updated 20 days ago, on 2026-09-26",
+		Stale(days(20), days(30), now, maxAge))
+	assert.Empty(t, Stale(days(20), days(30), now, 0), "age check off")
+	assert.Empty(t, Stale(time.Time{}, days(2), now, maxAge), "plan never committed")
+	assert.Empty(t, Stale(days(20), time.Time{}, now, maxAge), "specs never committed")
+}
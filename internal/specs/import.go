@@ -0,0 +1,207 @@
+package specs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Imported records one document written into the specs directory.
+type Imported struct {
+	Source string // file path or URL it came from
+	Dest   string // written file, relative to the specs directory
+}
+
+// Skipped records a document that wasn't imported and why.
+type Skipped struct {
+	Source string
+	Reason string
+}
+
+// ImportResult summarizes an Import.
+type ImportResult struct {
+	Imported []Imported
+	Skipped  []Skipped
+}
+
+// source is a document to import, read lazily.
+type source struct {
+	name string // original file name, used for the extension and the new name
+	ref  string // path or URL, for reporting
+	read func(ctx context.Context) ([]byte, error)
+}
+
+// Import copies the documents at src (a file, a directory walked
+// recursively, or an http(s) URL to a single document) into destDir as
+// markdown. A GitHub issue URL imports the issue, linked by an issue field.
+// File names are normalized to lowercase-kebab-case .md; a name that
+// already exists in destDir is skipped unless force is set. Documents that
+// can't be converted are reported in Skipped rather than failing the whole
+// import.
+func Import(ctx context.Context, src, destDir string, force bool) (*ImportResult, error) {
+	sources, err := collect(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no documents found in %s", src)
+	}
+	if err := os.MkdirAll(destDir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating specs dir: %w", err)
+	}
+
+	res := &ImportResult{}
+	taken := map[string]bool{}
+	for _, s := range sources {
+		dest := uniqueName(NormalizeName(s.name), taken)
+		target := filepath.Join(destDir, dest)
+		if _, err := os.Stat(target); err == nil && !force {
+			res.Skipped = append(res.Skipped, Skipped{Source: s.ref, Reason: dest + " already exists (use --force to overwrite)"})
+			continue
+		}
+
+		data, err := s.read(ctx)
+		if err != nil {
+			res.Skipped = append(res.Skipped, Skipped{Source: s.ref, Reason: err.Error()})
+			continue
+		}
+		md, err := ToMarkdown(ctx, s.name, data)
+		if err != nil {
+			res.Skipped = append(res.Skipped, Skipped{Source: s.ref, Reason: err.Error()})
+			continue
+		}
+		if err := os.WriteFile(target, []byte(md), 0o600); err != nil {
+			return res, fmt.Errorf("writing %s: %w", dest, err)
+		}
+		res.Imported = append(res.Imported, Imported{Source: s.ref, Dest: dest})
+	}
+	return res, nil
+}
+
+// collect lists the documents src refers to.
+func collect(src string) ([]source, error) {
+	if issueURL.MatchString(src) {
+		ref, err := ParseIssueRef(src, "")
+		if err != nil {
+			return nil, err
+		}
+		return []source{issueSource(ref, src)}, nil
+	}
+	if u, err := url.Parse(src); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		name := path.Base(u.Path)
+		if name == "." || name == "/" {
+			name = u.Host + ".md"
+		}
+		return []source{{name: name, ref: src, read: func(ctx context.Context) ([]byte, error) { return fetch(ctx, src) }}}, nil
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", src, err)
+	}
+	if !info.IsDir() {
+		return []source{fileSource(src)}, nil
+	}
+
+	var sources []source
+	err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != src && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(d.Name(), ".") {
+			sources = append(sources, fileSource(p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", src, err)
+	}
+	return sources, nil
+}
+
+func fileSource(p string) source {
+	return source{name: filepath.Base(p), ref: p, read: func(context.Context) ([]byte, error) {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // reported as a skip reason
+		}
+		if info.Size() > maxDocumentSize {
+			return nil, fmt.Errorf("larger than %d MB", maxDocumentSize>>20)
+		}
+		return os.ReadFile(p) //nolint:gosec,wrapcheck // user-chosen import source; reported as a skip reason
+	}}
+}
+
+// fetch downloads a document, refusing anything over maxDocumentSize.
+func fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("downloading: %w", err)
+	}
+	if len(data) > maxDocumentSize {
+		return nil, fmt.Errorf("larger than %d MB", maxDocumentSize>>20)
+	}
+	return data, nil
+}
+
+var nameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// NormalizeName turns a document file name into a lowercase-kebab-case
+// markdown name, e.g. "API Design (v2).docx" → "api-design-v2.md".
+func NormalizeName(name string) string {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	slug := strings.Trim(nameUnsafe.ReplaceAllString(strings.ToLower(base), "-"), "-")
+	if slug == "" {
+		slug = "spec"
+	}
+	return slug + ".md"
+}
+
+// uniqueName suffixes name with -2, -3, … until it hasn't been taken in
+// this import, then marks it taken.
+func uniqueName(name string, taken map[string]bool) string {
+	base := strings.TrimSuffix(name, ".md")
+	for n := 2; taken[name]; n++ {
+		name = base + "-" + strconv.Itoa(n) + ".md"
+	}
+	taken[name] = true
+	return name
+}
+
+// SortedDests returns the imported file names, sorted.
+func (r *ImportResult) SortedDests() []string {
+	out := make([]string, 0, len(r.Imported))
+	for _, im := range r.Imported {
+		out = append(out, im.Dest)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,126 @@
+package specs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeDocx builds a minimal .docx whose word/document.xml has body as its
+// <w:body> contents.
+func makeDocx(t *testing.T, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("word/document.xml")
+	require.NoError(t, err)
+	_, err = f.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` + body + `</w:body></w:document>`))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestToMarkdown_Docx(t *testing.T) {
+	data := makeDocx(t, `
+<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Todo API</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Endpoints</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Every route </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>must</w:t></w:r><w:r><w:t xml:space="preserve"> validate input.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>GET /todos</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>DELETE /todos/{id}</w:t></w:r></w:p>
+<w:p><w:r><w:rPr><w:i/></w:rPr><w:t>Draft</w:t></w:r></w:p>
+<w:p/>`)
+
+	md, err := ToMarkdown(context.Background(), "spec.docx", data)
+	require.NoError(t, err)
+	assert.Equal(t, "# Todo API\n\n## Endpoints\n\nEvery route **must** validate input.\n\n- GET /todos\n- DELETE /todos/{id}\n\n_Draft_\n", md)
+}
+
+func TestToMarkdown_Unsupported(t *testing.T) {
+	_, err := ToMarkdown(context.Background(), "diagram.png", []byte("x"))
+	assert.True(t, errors.Is(err, ErrNoConverter))
+
+	_, err = ToMarkdown(context.Background(), "broken.docx", []byte("not a zip"))
+	assert.Error(t, err)
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := map[string]string{
+		"API Design (v2).docx": "api-design-v2.md",
+		"auth_flow.MD":         "auth-flow.md",
+		"notes.txt":            "notes.md",
+		"---.pdf":              "spec.md",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, NormalizeName(in), in)
+	}
+}
+
+func TestImport_Directory(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0o750))
+	require.NoError(t, os.MkdirAll(filepath.Join(src, ".git"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "Auth Flow.md"), []byte("# Auth\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "auth_flow.txt"), []byte("dup name\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "Data Model.docx"), makeDocx(t, `<w:p><w:r><w:t>Tables</w:t></w:r></w:p>`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "logo.png"), []byte("png"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, ".git", "config"), []byte("x"), 0o600))
+
+	dest := filepath.Join(t.TempDir(), "specs", "feature")
+	res, err := Import(context.Background(), src, dest, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"auth-flow-2.md", "auth-flow.md", "data-model.md"}, res.SortedDests())
+	require.Len(t, res.Skipped, 1)
+	assert.Contains(t, res.Skipped[0].Source, "logo.png")
+
+	got, err := os.ReadFile(filepath.Join(dest, "data-model.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "Tables\n", string(got))
+
+	// Re-importing skips existing files unless forced.
+	res, err = Import(context.Background(), filepath.Join(src, "Auth Flow.md"), dest, false)
+	require.NoError(t, err)
+	assert.Empty(t, res.Imported)
+	assert.Contains(t, res.Skipped[0].Reason, "already exists")
+
+	res, err = Import(context.Background(), filepath.Join(src, "Auth Flow.md"), dest, true)
+	require.NoError(t, err)
+	assert.Len(t, res.Imported, 1)
+}
+
+func TestImport_URL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/docs/Payments%20Spec.md" && r.URL.Path != "/docs/Payments Spec.md" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("# Payments\n")) //nolint:errcheck,gosec // test server
+	}))
+	defer srv.Close()
+
+	dest := t.TempDir()
+	res, err := Import(context.Background(), srv.URL+"/docs/Payments%20Spec.md", dest, false)
+	require.NoError(t, err)
+	require.Len(t, res.Imported, 1)
+	assert.Equal(t, "payments-spec.md", res.Imported[0].Dest)
+
+	res, err = Import(context.Background(), srv.URL+"/missing.md", dest, false)
+	require.NoError(t, err)
+	require.Len(t, res.Skipped, 1)
+	assert.Contains(t, res.Skipped[0].Reason, "404")
+}
+
+func TestImport_MissingSource(t *testing.T) {
+	_, err := Import(context.Background(), filepath.Join(t.TempDir(), "nope"), t.TempDir(), false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,176 @@
+// Package specs lets a fresh branch reuse the specs of the branch it was
+// forked from, so follow-up work doesn't start by duplicating specs by hand.
+package specs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/charmbracelet/huh"
+
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// Inherit methods accepted by --inherit-specs.
+const (
+	MethodCopy = "copy" // copy parent specs into the new branch's directory
+	MethodLink = "link" // symlink the new branch's directory to the parent's
+	MethodNone = "none" // leave the new branch's directory empty
+)
+
+// ValidMethod reports whether m is a recognised inherit method.
+func ValidMethod(m string) bool {
+	return m == MethodCopy || m == MethodLink || m == MethodNone
+}
+
+// Parent is a branch whose specs directory can seed the current branch.
+type Parent struct {
+	Branch string
+	Dir    string // specs directory relative to the repo root
+}
+
+// HasSpecs reports whether dir contains at least one .md file.
+func HasSpecs(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".md" {
+			return true
+		}
+	}
+	return false
+}
+
+// FindParent returns the local branch with existing specs that the current
+// branch forked from most recently (fewest commits since their merge-base).
+// dirFor maps a sanitized branch name to its specs directory. Branches with
+// no history in common with the current one are skipped. It returns nil when
+// no other branch has specs.
+func FindParent(ctx context.Context, repoRoot, branch string, dirFor func(string) string) (*Parent, error) {
+	branches, err := git.LocalBranches(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing branches: %w", err)
+	}
+	sort.Strings(branches)
+
+	ownDir := dirFor(git.SanitizeBranch(branch))
+	var best *Parent
+	bestDist := -1
+	for _, b := range branches {
+		if b == branch {
+			continue
+		}
+		dir := dirFor(git.SanitizeBranch(b))
+		if dir == ownDir || !HasSpecs(filepath.Join(repoRoot, dir)) {
+			continue
+		}
+		dist, err := git.ForkDistance(ctx, b)
+		if err != nil {
+			// No merge-base, e.g. gh-pages or another orphan branch: not a
+			// branch this one was forked from.
+			slog.DebugContext(ctx, "specs: skipping unrelated branch", "branch", b, "err", err)
+			continue
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = &Parent{Branch: b, Dir: dir}, dist
+		}
+	}
+	return best, nil
+}
+
+// Copy copies every file under the parent specs directory into dst,
+// preserving the directory layout. Existing files in dst are overwritten.
+func Copy(repoRoot string, parent *Parent, dst string) error {
+	src := filepath.Join(repoRoot, parent.Dir)
+	dstAbs := filepath.Join(repoRoot, dst)
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		target := filepath.Join(dstAbs, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o750) //nolint:wrapcheck // wrapped below
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path) //nolint:gosec // path is within the repo's specs directory
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		return os.WriteFile(target, data, 0o600) //nolint:wrapcheck // wrapped below
+	})
+	if err != nil {
+		return fmt.Errorf("copying specs from %s: %w", parent.Dir, err)
+	}
+	return nil
+}
+
+// Link replaces dst with a relative symlink to the parent specs directory,
+// so edits on either branch are shared. dst must be absent or contain
+// nothing but a .gitkeep.
+func Link(repoRoot string, parent *Parent, dst string) error {
+	dstAbs := filepath.Join(repoRoot, dst)
+	entries, err := os.ReadDir(dstAbs)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("reading %s: %w", dst, err)
+	case len(entries) > 1 || (len(entries) == 1 && entries[0].Name() != ".gitkeep"):
+		return fmt.Errorf("cannot link %s: directory is not empty", dst)
+	default:
+		if err := os.RemoveAll(dstAbs); err != nil {
+			return fmt.Errorf("removing %s: %w", dst, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstAbs), 0o750); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(dst), err)
+	}
+	rel, err := filepath.Rel(filepath.Dir(dstAbs), filepath.Join(repoRoot, parent.Dir))
+	if err != nil {
+		return fmt.Errorf("resolving link target: %w", err)
+	}
+	if err := os.Symlink(rel, dstAbs); err != nil {
+		return fmt.Errorf("linking %s to %s: %w", dst, parent.Dir, err)
+	}
+	return nil
+}
+
+// Prompt asks whether to copy, link, or skip the parent's specs.
+func Prompt(in io.Reader, out io.Writer, accessible bool, parent *Parent, dst string) (string, error) {
+	method := MethodCopy
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title(fmt.Sprintf("%s/ has no specs. Reuse specs from %s (%s/)?", dst, parent.Branch, parent.Dir)).
+				Options(
+					huh.NewOption("Copy them (diverge independently)", MethodCopy),
+					huh.NewOption("Symlink them (share edits with "+parent.Branch+")", MethodLink),
+					huh.NewOption("No, start empty", MethodNone),
+				).
+				Value(&method),
+		),
+	).WithAccessible(accessible).
+		WithTheme(ui.HuhTheme()).
+		WithInput(in).
+		WithOutput(out)
+
+	if err := form.Run(); err != nil {
+		return "", err //nolint:wrapcheck // propagate huh errors directly
+	}
+	return method, nil
+}
@@ -0,0 +1,72 @@
+package specs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeParent(t *testing.T, root string) *Parent {
+	t.Helper()
+	dir := filepath.Join(root, "specs", "parent")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "b.md"), []byte("# B"), 0o600))
+	return &Parent{Branch: "parent", Dir: "specs/parent"}
+}
+
+func TestHasSpecs(t *testing.T) {
+	root := t.TempDir()
+	writeParent(t, root)
+	assert.True(t, HasSpecs(filepath.Join(root, "specs", "parent")))
+	assert.False(t, HasSpecs(filepath.Join(root, "specs", "parent", "missing")))
+	assert.False(t, HasSpecs(filepath.Join(root, "specs")), "only direct .md files count")
+}
+
+func TestCopy(t *testing.T) {
+	root := t.TempDir()
+	parent := writeParent(t, root)
+
+	require.NoError(t, Copy(root, parent, "specs/child"))
+
+	data, err := os.ReadFile(filepath.Join(root, "specs", "child", "nested", "b.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# B", string(data))
+}
+
+func TestLink(t *testing.T) {
+	root := t.TempDir()
+	parent := writeParent(t, root)
+	child := filepath.Join(root, "specs", "child")
+	require.NoError(t, os.MkdirAll(child, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(child, ".gitkeep"), nil, 0o600))
+
+	require.NoError(t, Link(root, parent, "specs/child"))
+
+	target, err := os.Readlink(child)
+	require.NoError(t, err)
+	assert.Equal(t, "parent", target, "link should be relative")
+	assert.FileExists(t, filepath.Join(child, "a.md"))
+}
+
+func TestLink_RefusesNonEmptyDir(t *testing.T) {
+	root := t.TempDir()
+	parent := writeParent(t, root)
+	child := filepath.Join(root, "specs", "child")
+	require.NoError(t, os.MkdirAll(child, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(child, "notes.txt"), nil, 0o600))
+
+	err := Link(root, parent, "specs/child")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not empty")
+}
+
+func TestValidMethod(t *testing.T) {
+	for _, m := range []string{MethodCopy, MethodLink, MethodNone} {
+		assert.True(t, ValidMethod(m), m)
+	}
+	assert.False(t, ValidMethod("move"))
+}
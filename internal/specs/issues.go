@@ -0,0 +1,158 @@
+package specs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// githubAPI is where imported GitHub issues are read from.
+var githubAPI = "https://api.github.com"
+
+// issueClient reads imported issues, bounded like ralph's other GitHub calls
+// so an unresponsive API can't hang an import.
+var issueClient = &http.Client{Timeout: 10 * time.Second}
+
+// IssueRef is the GitHub issue a spec was imported from, named by the spec's
+// issue frontmatter field.
+type IssueRef struct {
+	Repo   string // "owner/repo"
+	Number int
+}
+
+func (r IssueRef) String() string {
+	return r.Repo + "#" + strconv.Itoa(r.Number)
+}
+
+// URL is the issue's page on github.com.
+func (r IssueRef) URL() string {
+	return "https://github.com/" + r.Repo + "/issues/" + strconv.Itoa(r.Number)
+}
+
+// Forms of an issue reference.
+var (
+	issueURL   = regexp.MustCompile(`^https://github\.com/([\w.-]+/[\w.-]+)/issues/(\d+)/?$`)
+	issueShort = regexp.MustCompile(`^([\w.-]+/[\w.-]+)?#(\d+)$`)
+)
+
+// ParseIssueRef reads an issue URL, "owner/repo#12", or "#12" for an issue
+// in defaultRepo.
+func ParseIssueRef(s, defaultRepo string) (IssueRef, error) {
+	s = strings.TrimSpace(s)
+	m := issueURL.FindStringSubmatch(s)
+	if m == nil {
+		m = issueShort.FindStringSubmatch(s)
+	}
+	if m == nil {
+		return IssueRef{}, fmt.Errorf("issue %q must be a GitHub issue URL, owner/repo#N or #N", s)
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil || n == 0 {
+		return IssueRef{}, fmt.Errorf("issue %q: invalid number", s)
+	}
+	repo := m[1]
+	if repo == "" {
+		repo = defaultRepo
+	}
+	return IssueRef{Repo: repo, Number: n}, nil
+}
+
+// LinkedIssues returns the issues the markdown specs in dir were imported
+// from, sorted and without duplicates. Specs without an issue field, or
+// whose frontmatter can't be read, link none; Lint reports the latter.
+func LinkedIssues(dir, defaultRepo string) ([]IssueRef, error) {
+	seen := map[IssueRef]bool{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		data, err := os.ReadFile(path) //nolint:gosec // a spec in the repo
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		fm, _, _, ok := splitFrontmatter(strings.ReplaceAll(string(data), "\r\n", "\n"))
+		if !ok {
+			return nil
+		}
+		var fields struct {
+			Issue string `yaml:"issue"`
+		}
+		if yaml.Unmarshal([]byte(fm), &fields) != nil || fields.Issue == "" {
+			return nil
+		}
+		if ref, err := ParseIssueRef(fields.Issue, defaultRepo); err == nil {
+			seen[ref] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading specs in %s: %w", dir, err)
+	}
+	refs := make([]IssueRef, 0, len(seen))
+	for ref := range seen {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Repo != refs[j].Repo {
+			return refs[i].Repo < refs[j].Repo
+		}
+		return refs[i].Number < refs[j].Number
+	})
+	return refs, nil
+}
+
+// issueSource imports a GitHub issue through the API rather than as its web
+// page: its title and body become the spec, and the issue field links the
+// spec back to it. GITHUB_PAT, when set, lets private issues be read.
+func issueSource(ref IssueRef, rawURL string) source {
+	return source{name: "issue-" + strconv.Itoa(ref.Number) + ".md", ref: rawURL, read: func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/repos/%s/issues/%d", githubAPI, ref.Repo, ref.Number), nil)
+		if err != nil {
+			return nil, fmt.Errorf("building request: %w", err)
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		if token := os.Getenv("GITHUB_PAT"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := issueClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("downloading: %w", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck // read-only
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("downloading: %s", resp.Status)
+		}
+		var issue struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&issue); err != nil {
+			return nil, fmt.Errorf("decoding issue: %w", err)
+		}
+		fm, err := yaml.Marshal(struct {
+			Title string `yaml:"title"`
+			Issue string `yaml:"issue"`
+		}{issue.Title, ref.URL()})
+		if err != nil {
+			return nil, fmt.Errorf("encoding frontmatter: %w", err)
+		}
+		body := strings.TrimSpace(strings.ReplaceAll(issue.Body, "\r\n", "\n"))
+		return []byte("---\n" + string(fm) + "---\n\n# " + issue.Title + "\n\n" + body + "\n"), nil
+	}}
+}
@@ -0,0 +1,90 @@
+package specs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIssueRef(t *testing.T) {
+	for in, want := range map[string]IssueRef{
+		"https://github.com/o/r/issues/12":    {Repo: "o/r", Number: 12},
+		"https://github.com/o/r.js/issues/3/": {Repo: "o/r.js", Number: 3},
+		"other/repo#4":                        {Repo: "other/repo", Number: 4},
+		"#9":                                  {Repo: "me/here", Number: 9},
+	} {
+		got, err := ParseIssueRef(in, "me/here")
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"12", "https://github.com/o/r/pull/12", "#0", "o/r#x"} {
+		_, err := ParseIssueRef(in, "me/here")
+		assert.Error(t, err, in)
+	}
+}
+
+func TestLinkedIssues(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	write("a.md", "---\ntitle: A\nissue: \"#7\"\n---\n")
+	write("nested/b.md", "---\ntitle: B\nissue: https://github.com/o/r/issues/7\n---\n")
+	write("c.md", "---\ntitle: C\nissue: other/repo#2\n---\n")
+	write("d.md", "---\ntitle: D\n---\n")
+	write("e.md", "no frontmatter\n")
+	write("notes.txt", "---\nissue: \"#99\"\n---\n")
+
+	refs, err := LinkedIssues(dir, "o/r")
+	require.NoError(t, err)
+	assert.Equal(t, []IssueRef{{Repo: "o/r", Number: 7}, {Repo: "other/repo", Number: 2}}, refs)
+}
+
+func TestImport_GitHubIssue(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/o/r/issues/12", r.URL.Path)
+		_, _ = w.Write([]byte(`{"title": "Add login: email + password", "body": "Users sign in.\r\n\r\n- [ ] Remember me"}`)) //nolint:errcheck // test server
+	}))
+	t.Cleanup(srv.Close)
+	old := githubAPI
+	githubAPI = srv.URL
+	t.Cleanup(func() { githubAPI = old })
+
+	dest := t.TempDir()
+	res, err := Import(context.Background(), "https://github.com/o/r/issues/12", dest, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"issue-12.md"}, res.SortedDests())
+
+	data, err := os.ReadFile(filepath.Join(dest, "issue-12.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "---\ntitle: 'Add login: email + password'\nissue: https://github.com/o/r/issues/12\n---\n\n"+
+		"# Add login: email + password\n\nUsers sign in.\n\n- [ ] Remember me\n", string(data))
+
+	refs, err := LinkedIssues(dest, "")
+	require.NoError(t, err)
+	assert.Equal(t, []IssueRef{{Repo: "o/r", Number: 12}}, refs)
+}
+
+func TestImport_GitHubIssueTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	oldAPI, oldClient := githubAPI, issueClient
+	githubAPI, issueClient = srv.URL, &http.Client{Timeout: 50 * time.Millisecond}
+	t.Cleanup(func() { githubAPI, issueClient = oldAPI, oldClient })
+
+	res, err := Import(context.Background(), "https://github.com/o/r/issues/12", t.TempDir(), false)
+	require.NoError(t, err)
+	assert.Empty(t, res.Imported)
+	require.Len(t, res.Skipped, 1)
+	assert.Contains(t, res.Skipped[0].Reason, "Client.Timeout")
+}
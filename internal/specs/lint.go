@@ -0,0 +1,431 @@
+package specs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lint rules, reported on each Issue.
+const (
+	RuleFilename    = "filename"
+	RuleFormat      = "format"
+	RuleFrontmatter = "frontmatter"
+	RuleLink        = "link"
+	RuleSize        = "size"
+)
+
+// DefaultMaxSpecSize is the largest spec Lint accepts unless told otherwise.
+// Every spec is read into the plan prompt, so a huge one crowds out the rest.
+const DefaultMaxSpecSize = 64 << 10
+
+// Spec statuses accepted in the status frontmatter field.
+var specStatuses = []string{"draft", "ready", "done"}
+
+// frontmatterFields are the keys a spec's frontmatter may use.
+var frontmatterFields = map[string]bool{
+	"title": true, "status": true, "depends_on": true, "owner": true, "tags": true,
+	"issue": true, // the GitHub issue the spec was imported from
+}
+
+// Issue is one problem found in a spec.
+type Issue struct {
+	File    string // relative to the specs directory
+	Line    int    // 1-based; 0 when it applies to the whole file
+	Rule    string
+	Message string
+	Fixable bool // Lint with fix set can repair it
+}
+
+func (i *Issue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.Rule, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.File, i.Rule, i.Message)
+}
+
+// LintResult is the outcome of linting a specs directory.
+type LintResult struct {
+	Specs  int     // spec files checked
+	Issues []Issue // problems remaining
+	Fixed  []Issue // problems repaired when fix was set
+}
+
+// Fixable counts the remaining issues a fix run could repair.
+func (r *LintResult) Fixable() int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Fixable {
+			n++
+		}
+	}
+	return n
+}
+
+// Lint checks every .md file under dir: names are lowercase-kebab-case,
+// files use LF line endings and end in a newline, frontmatter has a title
+// and only known fields, relative links resolve inside repoRoot, and no
+// spec is larger than maxSize bytes. When fix is set, fixable issues are
+// repaired first and reported in Fixed.
+func Lint(repoRoot, dir string, maxSize int64, fix bool) (*LintResult, error) {
+	files, err := specFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	res := &LintResult{Specs: len(files)}
+	if fix {
+		if files, err = fixAll(dir, files, res); err != nil {
+			return nil, err
+		}
+	}
+	for _, rel := range files {
+		issues, err := lintFile(repoRoot, dir, rel, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		res.Issues = append(res.Issues, issues...)
+	}
+	return res, nil
+}
+
+// specFiles returns the .md files under dir, relative to it and sorted.
+func specFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(p), ".md") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing specs in %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func lintFile(repoRoot, dir, rel string, maxSize int64) ([]Issue, error) {
+	path := filepath.Join(dir, rel)
+	data, err := os.ReadFile(path) //nolint:gosec // spec inside the specs dir
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", rel, err)
+	}
+	var issues []Issue
+	add := func(line int, rule, msg string, fixable bool) {
+		issues = append(issues, Issue{File: rel, Line: line, Rule: rule, Message: msg, Fixable: fixable})
+	}
+
+	if name := filepath.Base(rel); NormalizeName(name) != name {
+		add(0, RuleFilename, fmt.Sprintf("name should be %s", NormalizeName(name)), true)
+	}
+	if int64(len(data)) > maxSize {
+		add(0, RuleSize, fmt.Sprintf("%d KB is over the %d KB limit; split it into smaller specs", len(data)>>10, maxSize>>10), false)
+	}
+	text := string(data)
+	if strings.Contains(text, "\r\n") {
+		add(0, RuleFormat, "uses CRLF line endings", true)
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		add(0, RuleFormat, "no newline at end of file", true)
+	}
+
+	fm, body, bodyLine, ok := splitFrontmatter(text)
+	if !ok {
+		add(1, RuleFrontmatter, "missing frontmatter with a title", true)
+	} else {
+		for _, i := range lintFrontmatter(dir, rel, fm) {
+			add(i.Line, RuleFrontmatter, i.Message, i.Fixable)
+		}
+	}
+	for _, l := range brokenLinks(repoRoot, filepath.Dir(path), body) {
+		add(bodyLine+l.line, RuleLink, fmt.Sprintf("%s does not exist", l.target), false)
+	}
+	return issues, nil
+}
+
+// splitFrontmatter separates a leading "---" YAML block from the body. It
+// returns the frontmatter, the body, the number of lines before the body,
+// and whether there was frontmatter at all.
+func splitFrontmatter(text string) (fm, body string, bodyLine int, ok bool) {
+	if !strings.HasPrefix(text, "---\n") {
+		return "", text, 0, false
+	}
+	rest := text[len("---\n"):]
+	end := strings.Index(rest, "\n---\n")
+	switch {
+	case strings.HasPrefix(rest, "---\n"):
+		end = -1 // empty frontmatter
+	case end < 0 && strings.HasSuffix(rest, "\n---"):
+		end = len(rest) - len("\n---")
+	case end < 0:
+		return "", text, 0, false
+	}
+	fm = rest[:end+1]
+	body = strings.TrimPrefix(strings.TrimPrefix(rest[end+1:], "---"), "\n")
+	return fm, body, strings.Count(text[:len(text)-len(body)], "\n"), true
+}
+
+// lintFrontmatter checks the fields of one spec's frontmatter. Lines are
+// counted from the opening "---".
+func lintFrontmatter(dir, rel, fm string) []Issue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(fm), &doc); err != nil {
+		return []Issue{{Line: 1, Message: fmt.Sprintf("invalid YAML: %s", err)}}
+	}
+	var fields map[string]yaml.Node
+	if len(doc.Content) > 0 {
+		if err := doc.Content[0].Decode(&fields); err != nil {
+			return []Issue{{Line: 1, Message: "must be a map of fields"}}
+		}
+	}
+
+	var issues []Issue
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !frontmatterFields[k] {
+			n := fields[k]
+			issues = append(issues, Issue{Line: n.Line + 1, Message: fmt.Sprintf("unknown field %q", k)})
+		}
+	}
+
+	title, ok := fields["title"]
+	if !ok || strings.TrimSpace(title.Value) == "" || title.Kind != yaml.ScalarNode {
+		issues = append(issues, Issue{Line: 1, Message: "title is required", Fixable: !ok})
+	}
+	if status, ok := fields["status"]; ok && !validStatus(status.Value) {
+		issues = append(issues, Issue{
+			Line:    status.Line + 1,
+			Message: fmt.Sprintf("status %q must be one of %s", status.Value, strings.Join(specStatuses, ", ")),
+			Fixable: validStatus(strings.ToLower(strings.TrimSpace(status.Value))),
+		})
+	}
+	if issue, ok := fields["issue"]; ok {
+		if _, err := ParseIssueRef(issue.Value, "owner/repo"); err != nil {
+			issues = append(issues, Issue{Line: issue.Line + 1, Message: err.Error()})
+		}
+	}
+	if deps, ok := fields["depends_on"]; ok {
+		var names []string
+		if err := deps.Decode(&names); err != nil {
+			issues = append(issues, Issue{Line: deps.Line + 1, Message: "depends_on must be a list of spec files"})
+		}
+		for _, name := range names {
+			if !dependencyExists(dir, rel, name) {
+				issues = append(issues, Issue{Line: deps.Line + 1, Message: fmt.Sprintf("depends_on %s: no such spec", name)})
+			}
+		}
+	}
+	return issues
+}
+
+func validStatus(s string) bool {
+	for _, v := range specStatuses {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyExists resolves a depends_on entry next to the spec, then from
+// the specs directory root.
+func dependencyExists(dir, rel, name string) bool {
+	for _, p := range []string{filepath.Join(dir, filepath.Dir(rel), name), filepath.Join(dir, name)} {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// mdLink matches inline markdown links and images, capturing the target.
+var mdLink = regexp.MustCompile(`!?\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+
+// brokenLink is a relative link whose target is missing.
+type brokenLink struct {
+	line   int // 1-based within the body
+	target string
+}
+
+// brokenLinks returns the relative links in body that don't resolve.
+// Links starting with "/" are taken from repoRoot; links leaving the repo,
+// URLs, and same-page anchors are not checked. Fenced code is skipped.
+func brokenLinks(repoRoot, fileDir, body string) []brokenLink {
+	var out []brokenLink
+	inFence := false
+	for n, line := range strings.Split(body, "\n") {
+		if isFence(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, m := range mdLink.FindAllStringSubmatch(line, -1) {
+			target, _, _ := strings.Cut(m[1], "#")
+			if target == "" || strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+				continue
+			}
+			var p string
+			if strings.HasPrefix(target, "/") {
+				p = filepath.Join(repoRoot, filepath.FromSlash(target))
+			} else {
+				p = filepath.Join(fileDir, filepath.FromSlash(target))
+			}
+			if r, err := filepath.Rel(repoRoot, p); err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+				continue
+			}
+			if _, err := os.Stat(p); err != nil {
+				out = append(out, brokenLink{line: n + 1, target: m[1]})
+			}
+		}
+	}
+	return out
+}
+
+// fixAll repairs every fixable issue it can and returns the file list with
+// renamed files updated.
+func fixAll(dir string, files []string, res *LintResult) ([]string, error) {
+	taken := make(map[string]bool, len(files))
+	for _, f := range files {
+		taken[f] = true
+	}
+	out := make([]string, 0, len(files))
+	for _, rel := range files {
+		fixed, err := fixContent(dir, rel)
+		if err != nil {
+			return nil, err
+		}
+		res.Fixed = append(res.Fixed, fixed...)
+
+		if name := filepath.Base(rel); NormalizeName(name) != name {
+			want := filepath.Join(filepath.Dir(rel), NormalizeName(name))
+			if !taken[want] {
+				if err := os.Rename(filepath.Join(dir, rel), filepath.Join(dir, want)); err != nil {
+					return nil, fmt.Errorf("renaming %s: %w", rel, err)
+				}
+				res.Fixed = append(res.Fixed, Issue{File: rel, Rule: RuleFilename, Message: "renamed to " + want})
+				delete(taken, rel)
+				taken[want] = true
+				rel = want
+			}
+		}
+		out = append(out, rel)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// statusLine matches the status field in frontmatter.
+var statusLine = regexp.MustCompile(`(?m)^status:[ \t]*(.*?)[ \t]*$`)
+
+// fixContent normalizes line endings and the trailing newline, adds
+// frontmatter titled from the first heading (or the file name) when it is
+// missing, and lowercases a status that is only wrongly cased.
+func fixContent(dir, rel string) ([]Issue, error) {
+	path := filepath.Join(dir, rel)
+	data, err := os.ReadFile(path) //nolint:gosec // spec inside the specs dir
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", rel, err)
+	}
+	var fixed []Issue
+	note := func(msg string) {
+		fixed = append(fixed, Issue{File: rel, Rule: RuleFormat, Message: msg})
+	}
+
+	text := string(data)
+	if strings.Contains(text, "\r\n") {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+		note("converted CRLF line endings to LF")
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+		note("added newline at end of file")
+	}
+
+	fm, body, _, ok := splitFrontmatter(text)
+	switch {
+	case !ok:
+		text = "---\ntitle: " + yamlString(specTitle(text, rel)) + "\n---\n\n" + text
+		fixed = append(fixed, Issue{File: rel, Rule: RuleFrontmatter, Message: "added frontmatter with a title"})
+	default:
+		newFM := fm
+		if !hasField(fm, "title") {
+			newFM = "title: " + yamlString(specTitle(body, rel)) + "\n" + newFM
+			fixed = append(fixed, Issue{File: rel, Rule: RuleFrontmatter, Message: "added a title"})
+		}
+		if m := statusLine.FindStringSubmatchIndex(newFM); m != nil {
+			v := newFM[m[2]:m[3]]
+			if lower := strings.ToLower(strings.Trim(v, `"'`)); lower != v && validStatus(lower) {
+				newFM = newFM[:m[2]] + lower + newFM[m[3]:]
+				fixed = append(fixed, Issue{File: rel, Rule: RuleFrontmatter, Message: fmt.Sprintf("status %q → %s", v, lower)})
+			}
+		}
+		if newFM != fm {
+			text = "---\n" + newFM + "---\n" + body
+		}
+	}
+
+	if text == string(data) {
+		return nil, nil
+	}
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		return nil, fmt.Errorf("writing %s: %w", rel, err)
+	}
+	return fixed, nil
+}
+
+// hasField reports whether the frontmatter sets key at the top level.
+func hasField(fm, key string) bool {
+	var fields map[string]any
+	if err := yaml.Unmarshal([]byte(fm), &fields); err != nil {
+		return true // leave broken YAML for the user to repair
+	}
+	_, ok := fields[key]
+	return ok
+}
+
+// specTitle is the text of the first heading, else a title made from the
+// file name.
+func specTitle(md, rel string) string {
+	for _, line := range strings.Split(md, "\n") {
+		if m := headingLine.FindStringSubmatch(line); m != nil && m[2] != "" {
+			return m[2]
+		}
+	}
+	name := strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	r := []rune(strings.NewReplacer("-", " ", "_", " ").Replace(name))
+	if len(r) == 0 {
+		return "Spec"
+	}
+	return string(unicode.ToUpper(r[0])) + string(r[1:])
+}
+
+// yamlString renders s as a YAML scalar, quoting it only when needed.
+func yamlString(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
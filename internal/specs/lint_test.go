@@ -0,0 +1,105 @@
+package specs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSpecs creates files (name → content) under a specs dir inside a
+// fresh repo root and returns both.
+func writeSpecs(t *testing.T, files map[string]string) (root, dir string) {
+	t.Helper()
+	root = t.TempDir()
+	dir = filepath.Join(root, "specs")
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o750))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	}
+	return root, dir
+}
+
+func rules(issues []Issue) []string {
+	out := make([]string, 0, len(issues))
+	for i := range issues {
+		out = append(out, issues[i].String())
+	}
+	return out
+}
+
+func TestLint_Clean(t *testing.T) {
+	root, dir := writeSpecs(t, map[string]string{
+		"auth.md":  "---\ntitle: Auth\nstatus: ready\ndepends_on: [users.md]\n---\n\n# Auth\n\nSee [users](users.md#model) and [docs](https://example.com).\n",
+		"users.md": "---\ntitle: Users\n---\n# Users\n",
+	})
+
+	res, err := Lint(root, dir, DefaultMaxSpecSize, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Specs)
+	assert.Empty(t, rules(res.Issues))
+}
+
+func TestLint_ReportsIssues(t *testing.T) {
+	root, dir := writeSpecs(t, map[string]string{
+		"API Design.md": "# API\r\n",
+		"billing.md":    "---\ntitle: Billing\nstatus: Draft\npriority: high\ndepends_on: [ledger.md]\n---\n# Billing\n\n![diagram](img/flow.png)\n\n```\n[not a link](missing.md)\n```",
+		"big.md":        "---\ntitle: Big\n---\n" + string(make([]byte, 2048)) + "\n",
+		"login.md":      "---\ntitle: Login\nissue: 12\n---\n",
+	})
+
+	res, err := Lint(root, dir, 1024, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"API Design.md: filename: name should be api-design.md",
+		"API Design.md: format: uses CRLF line endings",
+		"API Design.md:1: frontmatter: missing frontmatter with a title",
+		"big.md: size: 2 KB is over the 1 KB limit; split it into smaller specs",
+		"billing.md: format: no newline at end of file",
+		`billing.md:4: frontmatter: unknown field "priority"`,
+		`billing.md:3: frontmatter: status "Draft" must be one of draft, ready, done`,
+		"billing.md:5: frontmatter: depends_on ledger.md: no such spec",
+		"billing.md:9: link: img/flow.png does not exist",
+		`login.md:3: frontmatter: issue "12" must be a GitHub issue URL, owner/repo#N or #N`,
+	}, rules(res.Issues))
+	assert.Equal(t, 5, res.Fixable())
+}
+
+func TestLint_Fix(t *testing.T) {
+	root, dir := writeSpecs(t, map[string]string{
+		"API Design.md": "Intro\r\n\r\n# API Design\r\n",
+		"billing.md":    "---\nstatus: Draft\n---\n# Billing",
+	})
+
+	res, err := Lint(root, dir, DefaultMaxSpecSize, true)
+	require.NoError(t, err)
+	assert.Empty(t, rules(res.Issues))
+	assert.Len(t, res.Fixed, 6)
+
+	data, err := os.ReadFile(filepath.Join(dir, "api-design.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "---\ntitle: API Design\n---\n\nIntro\n\n# API Design\n", string(data))
+	assert.NoFileExists(t, filepath.Join(dir, "API Design.md"))
+
+	data, err = os.ReadFile(filepath.Join(dir, "billing.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "---\ntitle: Billing\nstatus: draft\n---\n# Billing\n", string(data))
+}
+
+func TestSplitFrontmatter(t *testing.T) {
+	fm, body, line, ok := splitFrontmatter("---\ntitle: A\n---\nbody\n")
+	assert.True(t, ok)
+	assert.Equal(t, "title: A\n", fm)
+	assert.Equal(t, "body\n", body)
+	assert.Equal(t, 3, line)
+
+	_, body, _, ok = splitFrontmatter("---\ntitle: A\n---")
+	assert.True(t, ok)
+	assert.Empty(t, body)
+
+	_, _, _, ok = splitFrontmatter("# no frontmatter\n---\n")
+	assert.False(t, ok)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/testresult"
 )
 
 // DefaultPath is the default location for state.json relative to repo root.
@@ -16,23 +18,150 @@ type RunStatus string
 
 // Run statuses.
 const (
-	StatusCompleted     RunStatus = "completed"
-	StatusStaleAbort    RunStatus = "stale_abort"
-	StatusCancelled     RunStatus = "cancelled"
-	StatusMaxIterations RunStatus = "max_iterations"
+	StatusCompleted      RunStatus = "completed"
+	StatusStaleAbort     RunStatus = "stale_abort"
+	StatusCancelled      RunStatus = "cancelled"
+	StatusMaxIterations  RunStatus = "max_iterations"
+	StatusConverged      RunStatus = "converged"       // plan mode stopped changing the plan
+	StatusContainerCrash RunStatus = "container_crash" // container exited without the loop recording the run
+	StatusDiskLimit      RunStatus = "disk_limit"      // the workspace grew past docker.disk_limit_mb
+	StatusTimeoutAbort   RunStatus = "timeout_abort"   // too many iterations in a row ran past phases.<phase>.iteration_timeout
+	StatusCostLimit      RunStatus = "cost_limit"      // spent cost.max_cost or --max-cost
+	StatusFailed         RunStatus = "failed"          // the loop stopped on an error, e.g. claude kept failing
+	StatusRunning        RunStatus = "running"         // still going, or killed before it could record how it ended
 )
 
 // RunRecord captures metadata from a single loop run.
 type RunRecord struct {
-	Mode           string    `json:"mode"`
-	StartedAt      time.Time `json:"started_at"`
-	FinishedAt     time.Time `json:"finished_at"`
-	Iterations     int       `json:"iterations"`
-	TotalCost      float64   `json:"total_cost"`
-	PeakContext    int       `json:"peak_context"`
-	SubagentTokens int       `json:"subagent_tokens"`
-	Status         RunStatus `json:"status"`
-	LogFiles       []string  `json:"log_files"`
+	Mode           string        `json:"mode"`
+	StartedAt      time.Time     `json:"started_at"`
+	FinishedAt     time.Time     `json:"finished_at"`
+	Iterations     int           `json:"iterations"`
+	TotalCost      float64       `json:"total_cost"`
+	PeakContext    int           `json:"peak_context"`
+	SubagentTokens int           `json:"subagent_tokens"`
+	Status         RunStatus     `json:"status"`
+	LogFiles       []string      `json:"log_files"`
+	LogIterations  []int         `json:"log_iterations,omitempty"` // the iteration each of LogFiles was written for; retries and timeouts log more than once
+	Tags           []string      `json:"tags,omitempty"`
+	Note           string        `json:"note,omitempty"`
+	Profile        string        `json:"profile,omitempty"`       // credential profile, for cost attribution across accounts
+	Experiment     string        `json:"experiment,omitempty"`    // prompt/model variant from config experiments
+	Package        string        `json:"package,omitempty"`       // workspace package directory the run was scoped to
+	HooksSkipped   string        `json:"hooks_skipped,omitempty"` // git.hooks.reason, when the agent's commits skipped the repo's hooks
+	Repairs        []Repair      `json:"repairs,omitempty"`
+	Tests          []TestResult  `json:"tests,omitempty"`           // per-iteration test counts, oldest first
+	ClaudeFailures int           `json:"claude_failures,omitempty"` // claude errors the run continued past
+	PlanApproval   *Approval     `json:"plan_approval,omitempty"`   // who approved the plan's pull request before a build
+	Branch         string        `json:"branch,omitempty"`
+	PlanFile       string        `json:"plan_file,omitempty"`
+	SpecsDir       string        `json:"specs_dir,omitempty"`
+	MaxIterations  int           `json:"max_iterations,omitempty"` // iteration budget the run started with; 0 = unlimited
+	MaxCost        float64       `json:"max_cost,omitempty"`       // cost cap in USD the run started with; 0 = none
+	ResumeOf       int           `json:"resume_of,omitempty"`      // run number (1-based) this run continued with ralph resume
+	ReplayOf       int           `json:"replay_of,omitempty"`      // run number (1-based) whose prompts this run replayed with ralph replay
+	Origin         *Origin       `json:"origin,omitempty"`         // the clone the run worked in; nil for runs recorded before it was kept
+	Capabilities   *Capabilities `json:"capabilities,omitempty"`   // what the agent reported at session start; nil if it reports nothing
+	Checkpoints    []Checkpoint  `json:"checkpoints,omitempty"`    // one per completed iteration, written as the run goes
+}
+
+// Resumable reports whether ralph resume can continue the run: it was
+// interrupted, crashed or ran out of iterations, rather than finishing its
+// work.
+func (r *RunRecord) Resumable() bool {
+	switch r.Status { //nolint:exhaustive // the rest ended for a reason resuming won't fix
+	case StatusCancelled, StatusMaxIterations, StatusCostLimit, StatusContainerCrash, StatusFailed, StatusRunning:
+		return true
+	}
+	return false
+}
+
+// Checkpoint is what a run had done when an iteration completed. The loop
+// saves the run with StatusRunning after each one, so a run that is killed
+// keeps its telemetry and can be resumed from its last completed iteration.
+type Checkpoint struct {
+	Iteration   int                `json:"iteration"`
+	At          time.Time          `json:"at"`
+	Head        string             `json:"head"` // HEAD after the iteration
+	Cost        float64            `json:"cost"` // the iteration's cost
+	TotalCost   float64            `json:"total_cost"`
+	PeakContext int                `json:"peak_context"`
+	Tests       *testresult.Counts `json:"tests,omitempty"` // backpressure test counts; nil if none ran
+}
+
+// Origin identifies the clone a run worked in, so state and reports copied
+// out of the repo still say where they came from.
+type Origin struct {
+	Repo      string `json:"repo,omitempty"`      // "owner/repo"
+	Remote    string `json:"remote,omitempty"`    // origin URL, without credentials
+	Workspace string `json:"workspace,omitempty"` // host path of the clone
+}
+
+// Capabilities is what the agent's first session of the run reported it
+// could use.
+type Capabilities struct {
+	Model        string   `json:"model,omitempty"`
+	Version      string   `json:"version,omitempty"` // agent CLI version
+	Tools        []string `json:"tools,omitempty"`
+	MCPServers   []string `json:"mcp_servers,omitempty"`
+	MissingTools []string `json:"missing_tools,omitempty"` // required tools it lacked, e.g. Bash
+	FailedMCP    []string `json:"failed_mcp,omitempty"`    // MCP servers that didn't connect
+}
+
+// Approval records who approved a plan on its GitHub pull request.
+type Approval struct {
+	PR        int      `json:"pr"`
+	Approvers []string `json:"approvers"`
+}
+
+// TestResult is the last test summary seen in an iteration's command output.
+type TestResult struct {
+	Iteration int `json:"iteration"`
+	testresult.Counts
+}
+
+// TestRegression reports whether failures rose between the run's last two
+// recorded test results, returning the failure counts before and after.
+func (r *RunRecord) TestRegression() (before, after int, regressed bool) {
+	n := len(r.Tests)
+	if n < 2 {
+		return 0, 0, false
+	}
+	before, after = r.Tests[n-2].Failed, r.Tests[n-1].Failed
+	return before, after, after > before
+}
+
+// Repair records an automatic fix applied to the repo before an iteration,
+// e.g. aborting a rebase a previous iteration left half-done.
+type Repair struct {
+	Iteration int       `json:"iteration"`
+	At        time.Time `json:"at"`
+	Actions   []string  `json:"actions"`
+}
+
+// LogIteration returns the iteration LogFiles[j] was written for. Logs past
+// the end of LogIterations, such as a crashed container's last, are taken
+// to be the iterations after it; runs recorded before it was kept logged
+// once per iteration.
+func (r *RunRecord) LogIteration(j int) int {
+	n := len(r.LogIterations)
+	if j < n {
+		return r.LogIterations[j]
+	}
+	if n == 0 {
+		return j + 1
+	}
+	return r.LogIterations[n-1] + j - n + 1
+}
+
+// HasTag reports whether the run was labelled with tag.
+func (r *RunRecord) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // State holds all recorded loop runs.
@@ -64,12 +193,72 @@ func Save(path string, s *State) error {
 		return fmt.Errorf("marshaling state: %w", err)
 	}
 	data = append(data, '\n')
-	if err := os.WriteFile(path, data, 0o600); err != nil {
+	// Written aside and renamed, so a run killed mid-checkpoint can't leave
+	// a truncated file behind.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
 		return fmt.Errorf("writing state: %w", err)
 	}
 	return nil
 }
 
+// Put records r: it replaces the StatusRunning run with the same mode and
+// start time, which an earlier checkpoint of r saved, or is appended.
+func (s *State) Put(r RunRecord) {
+	for i := len(s.Runs) - 1; i >= 0; i-- {
+		run := &s.Runs[i]
+		if run.Status == StatusRunning && run.Mode == r.Mode && run.StartedAt.Equal(r.StartedAt) {
+			*run = r
+			return
+		}
+	}
+	s.Runs = append(s.Runs, r)
+}
+
+// RunsWithTag returns the runs labelled with tag, oldest first. An empty tag
+// matches every run.
+func (s *State) RunsWithTag(tag string) []RunRecord {
+	if tag == "" {
+		return s.Runs
+	}
+	var out []RunRecord
+	for i := range s.Runs {
+		if s.Runs[i].HasTag(tag) {
+			out = append(out, s.Runs[i])
+		}
+	}
+	return out
+}
+
+// LatestOnBranch returns the 1-based number of the most recent run on
+// branch, or 0 when none is recorded.
+func (s *State) LatestOnBranch(branch string) int {
+	for i := len(s.Runs) - 1; i >= 0; i-- {
+		if s.Runs[i].Branch == branch {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Lineage returns the run numbers from the run that n was resumed from,
+// through each resume, to n itself, oldest first.
+func (s *State) Lineage(n int) []int {
+	var chain []int
+	for n > 0 && n <= len(s.Runs) && len(chain) <= len(s.Runs) {
+		chain = append([]int{n}, chain...)
+		parent := s.Runs[n-1].ResumeOf
+		if parent >= n {
+			break // only earlier runs can be resumed; guards a hand-edited file
+		}
+		n = parent
+	}
+	return chain
+}
+
 // LastRun returns the most recent run record, or nil if there are no runs.
 func (s *State) LastRun() *RunRecord {
 	if len(s.Runs) == 0 {
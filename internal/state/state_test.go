@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/testresult"
 )
 
 func TestLoadMissingFile(t *testing.T) {
@@ -61,3 +63,107 @@ func TestLastRun(t *testing.T) {
 	assert.Equal(t, "build", last.Mode)
 	assert.Equal(t, 5, last.Iterations)
 }
+
+func TestRunsWithTag(t *testing.T) {
+	s := &State{Runs: []RunRecord{
+		{Mode: "plan", Tags: []string{"nightly"}},
+		{Mode: "build"},
+		{Mode: "build", Tags: []string{"experiment", "nightly"}, Note: "new prompt"},
+	}}
+
+	tagged := s.RunsWithTag("nightly")
+	require.Len(t, tagged, 2)
+	assert.Equal(t, "plan", tagged[0].Mode)
+	assert.Equal(t, "new prompt", tagged[1].Note)
+
+	assert.Empty(t, s.RunsWithTag("missing"))
+	assert.Len(t, s.RunsWithTag(""), 3, "empty tag matches all runs")
+}
+
+func TestTagsAndNoteRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	original := &State{Runs: []RunRecord{{Mode: "build", Tags: []string{"nightly"}, Note: "attempt 2"}}}
+	require.NoError(t, Save(path, original))
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nightly"}, loaded.Runs[0].Tags)
+	assert.Equal(t, "attempt 2", loaded.Runs[0].Note)
+}
+
+func TestTestsRoundTripAndRegression(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	run := RunRecord{Mode: "build", Tests: []TestResult{
+		{Iteration: 1, Counts: testresult.Counts{Passed: 10}},
+		{Iteration: 2, Counts: testresult.Counts{Passed: 9, Failed: 2, Skipped: 1}},
+	}}
+	require.NoError(t, Save(path, &State{Runs: []RunRecord{run}}))
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, run.Tests, loaded.Runs[0].Tests)
+
+	before, after, regressed := loaded.Runs[0].TestRegression()
+	assert.True(t, regressed)
+	assert.Equal(t, 0, before)
+	assert.Equal(t, 2, after)
+
+	run.Tests = run.Tests[:1]
+	_, _, regressed = run.TestRegression()
+	assert.False(t, regressed, "one result has no trend")
+}
+
+func TestLatestOnBranchAndLineage(t *testing.T) {
+	s := &State{Runs: []RunRecord{
+		{Branch: "feat-a", Status: StatusCancelled},
+		{Branch: "feat-b", Status: StatusCompleted},
+		{Branch: "feat-a", Status: StatusMaxIterations, ResumeOf: 1},
+		{Branch: "feat-a", Status: StatusCancelled, ResumeOf: 3},
+	}}
+	assert.Equal(t, 4, s.LatestOnBranch("feat-a"))
+	assert.Equal(t, 2, s.LatestOnBranch("feat-b"))
+	assert.Zero(t, s.LatestOnBranch("feat-c"))
+
+	assert.Equal(t, []int{1, 3, 4}, s.Lineage(4))
+	assert.Equal(t, []int{2}, s.Lineage(2))
+	assert.Empty(t, s.Lineage(9))
+
+	assert.True(t, s.Runs[0].Resumable())
+	assert.True(t, s.Runs[2].Resumable())
+	assert.False(t, s.Runs[1].Resumable())
+	for _, st := range []RunStatus{StatusContainerCrash, StatusFailed, StatusRunning} {
+		assert.True(t, (&RunRecord{Status: st}).Resumable(), st)
+	}
+
+	s.Runs[0].ResumeOf = 4 // hand-edited cycle
+	assert.Equal(t, []int{1, 3, 4}, s.Lineage(4))
+}
+
+func TestPut(t *testing.T) {
+	started := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	s := &State{Runs: []RunRecord{
+		{Mode: "build", StartedAt: started, Status: StatusCompleted},
+	}}
+
+	s.Put(RunRecord{Mode: "build", StartedAt: started, Status: StatusRunning, Iterations: 1})
+	require.Len(t, s.Runs, 2, "a finished run with the same start isn't replaced")
+
+	s.Put(RunRecord{Mode: "build", StartedAt: started.In(time.Local), Status: StatusRunning, Iterations: 2})
+	s.Put(RunRecord{Mode: "build", StartedAt: started, Status: StatusMaxIterations, Iterations: 3})
+	require.Len(t, s.Runs, 2, "later checkpoints and the final record replace the running one")
+	assert.Equal(t, StatusMaxIterations, s.Runs[1].Status)
+	assert.Equal(t, 3, s.Runs[1].Iterations)
+
+	s.Put(RunRecord{Mode: "build", StartedAt: started, Status: StatusCompleted})
+	assert.Len(t, s.Runs, 3)
+}
+
+func TestLogIteration(t *testing.T) {
+	old := RunRecord{LogFiles: []string{"a", "b"}}
+	assert.Equal(t, 2, old.LogIteration(1), "runs without LogIterations logged once per iteration")
+
+	r := RunRecord{LogFiles: []string{"a", "b", "c", "d"}, LogIterations: []int{1, 1, 2}}
+	assert.Equal(t, 1, r.LogIteration(1), "a retry logs the same iteration again")
+	assert.Equal(t, 2, r.LogIteration(2))
+	assert.Equal(t, 3, r.LogIteration(3), "a log past the recorded ones follows the last")
+}
@@ -0,0 +1,223 @@
+// Package statedb exports the run history in state.json, with the per-log
+// figures from the log index, into a SQLite database so it can be queried
+// with SQL. It drives the sqlite3 shell rather than linking a driver.
+package statedb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/status"
+)
+
+// Schema is the database layout, recreated on every export. Times are
+// RFC 3339 text in UTC; costs are USD.
+const Schema = `CREATE TABLE runs (
+  id              INTEGER PRIMARY KEY, -- run number, as in ralph status --history (1 = oldest)
+  mode            TEXT NOT NULL,       -- plan or build
+  status          TEXT NOT NULL,       -- completed, cancelled, max_iterations, ...
+  branch          TEXT,
+  started_at      TEXT,
+  finished_at     TEXT,
+  iterations      INTEGER NOT NULL,
+  max_iterations  INTEGER,             -- NULL = unlimited
+  total_cost      REAL NOT NULL,
+  peak_context    INTEGER NOT NULL,
+  subagent_tokens INTEGER NOT NULL,
+  claude_failures INTEGER NOT NULL,
+  model           TEXT,                -- as reported by the agent at session start
+  profile         TEXT,
+  experiment      TEXT,
+  note            TEXT,
+  plan_file       TEXT,
+  specs_dir       TEXT,
+  resume_of       INTEGER REFERENCES runs(id)
+);
+CREATE TABLE run_tags (
+  run_id INTEGER NOT NULL REFERENCES runs(id),
+  tag    TEXT NOT NULL,
+  PRIMARY KEY (run_id, tag)
+);
+CREATE TABLE iterations (
+  run_id          INTEGER NOT NULL REFERENCES runs(id),
+  iteration       INTEGER NOT NULL,    -- 1-based within the run
+  attempt         INTEGER NOT NULL,    -- 1, or higher for each retry after claude failed
+  log_file        TEXT NOT NULL,
+  started_at      TEXT,                -- NULL when the log is no longer on disk
+  finished_at     TEXT,
+  cost            REAL,
+  peak_context    INTEGER,
+  subagent_tokens INTEGER,
+  tests_passed    INTEGER,             -- NULL when no test summary was seen; only on an iteration's last attempt
+  tests_failed    INTEGER,
+  tests_skipped   INTEGER,
+  PRIMARY KEY (run_id, iteration, attempt)
+);
+CREATE TABLE iteration_models (
+  run_id    INTEGER NOT NULL,
+  iteration INTEGER NOT NULL,
+  attempt   INTEGER NOT NULL,
+  model     TEXT NOT NULL,
+  tokens    INTEGER NOT NULL,          -- input, cache writes and output billed to the model
+  PRIMARY KEY (run_id, iteration, attempt, model),
+  FOREIGN KEY (run_id, iteration, attempt) REFERENCES iterations(run_id, iteration, attempt)
+);
+`
+
+// tables lists the tables in Schema, dropped before it is recreated.
+var tables = []string{"iteration_models", "iterations", "run_tags", "runs"}
+
+// Write writes a SQL script that replaces the tables in Schema with the
+// runs in st. logs holds the index entries by log file name; iterations
+// whose log isn't in it get NULL figures.
+//
+//nolint:errcheck // scripts go to a buffer or stdout, like other rendered output
+func Write(w io.Writer, st *state.State, logs map[string]status.IndexEntry) {
+	fmt.Fprintln(w, "BEGIN;")
+	for _, t := range tables {
+		fmt.Fprintf(w, "DROP TABLE IF EXISTS %s;\n", t)
+	}
+	fmt.Fprint(w, Schema)
+
+	for i := range st.Runs {
+		r := &st.Runs[i]
+		id := i + 1
+		model := ""
+		if r.Capabilities != nil {
+			model = r.Capabilities.Model
+		}
+		resumeOf := "NULL"
+		if r.ResumeOf > 0 {
+			resumeOf = strconv.Itoa(r.ResumeOf)
+		}
+		maxIter := "NULL"
+		if r.MaxIterations > 0 {
+			maxIter = strconv.Itoa(r.MaxIterations)
+		}
+		fmt.Fprintf(w, "INSERT INTO runs VALUES (%d, %s, %s, %s, %s, %s, %d, %s, %s, %d, %d, %d, %s, %s, %s, %s, %s, %s, %s);\n",
+			id, text(r.Mode), text(string(r.Status)), optText(r.Branch),
+			timeText(r.StartedAt), timeText(r.FinishedAt), r.Iterations, maxIter,
+			number(r.TotalCost), r.PeakContext, r.SubagentTokens, r.ClaudeFailures,
+			optText(model), optText(r.Profile), optText(r.Experiment), optText(r.Note),
+			optText(r.PlanFile), optText(r.SpecsDir), resumeOf)
+
+		for _, tag := range dedupe(r.Tags) {
+			fmt.Fprintf(w, "INSERT INTO run_tags VALUES (%d, %s);\n", id, text(tag))
+		}
+
+		tests := make(map[int]state.TestResult, len(r.Tests))
+		for _, t := range r.Tests {
+			tests[t.Iteration] = t
+		}
+		// An iteration claude failed and was retried has a log per attempt;
+		// its test counts come from the last.
+		last := make(map[int]int, len(r.LogFiles))
+		for j := range r.LogFiles {
+			last[r.LogIteration(j)] = j
+		}
+		attempts := make(map[int]int, len(r.LogFiles))
+		for j, logFile := range r.LogFiles {
+			iter := r.LogIteration(j)
+			attempts[iter]++
+			t, ok := tests[iter]
+			writeIteration(w, id, iter, attempts[iter], logFile, logs, t, ok && last[iter] == j)
+		}
+	}
+	fmt.Fprintln(w, "COMMIT;")
+}
+
+//nolint:errcheck // see Write
+func writeIteration(w io.Writer, run, iter, attempt int, logFile string, logs map[string]status.IndexEntry, t state.TestResult, tested bool) {
+	started, finished, cost, peak, sub := "NULL", "NULL", "NULL", "NULL", "NULL"
+	entry, ok := logs[filepath.Base(logFile)]
+	if ok {
+		started, finished = timeText(entry.StartedAt), timeText(entry.FinishedAt)
+		cost, peak, sub = number(entry.Cost), strconv.Itoa(entry.PeakContext), strconv.Itoa(entry.SubagentTokens)
+	}
+	passed, failed, skipped := "NULL", "NULL", "NULL"
+	if tested {
+		passed, failed, skipped = strconv.Itoa(t.Passed), strconv.Itoa(t.Failed), strconv.Itoa(t.Skipped)
+	}
+	fmt.Fprintf(w, "INSERT INTO iterations VALUES (%d, %d, %d, %s, %s, %s, %s, %s, %s, %s, %s, %s);\n",
+		run, iter, attempt, text(logFile), started, finished, cost, peak, sub, passed, failed, skipped)
+
+	models := make([]string, 0, len(entry.ModelTokens))
+	for m := range entry.ModelTokens {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	for _, m := range models {
+		fmt.Fprintf(w, "INSERT INTO iteration_models VALUES (%d, %d, %d, %s, %d);\n", run, iter, attempt, text(m), entry.ModelTokens[m])
+	}
+}
+
+// Export replaces the tables in the SQLite database at dbPath with the runs
+// in st, creating the file if needed. The sqlite3 shell must be on PATH.
+func Export(ctx context.Context, dbPath string, st *state.State, logs map[string]status.IndexEntry) error {
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return errors.New("sqlite3 not found on PATH; install it, or use --sql to write the SQL script instead")
+	}
+	var script bytes.Buffer
+	Write(&script, st, logs)
+
+	cmd := exec.CommandContext(ctx, bin, "-bail", dbPath) //nolint:gosec // the database path is chosen by the user
+	cmd.Stdin = &script
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("writing %s: %s: %w", dbPath, msg, err)
+		}
+		return fmt.Errorf("writing %s: %w", dbPath, err)
+	}
+	return nil
+}
+
+// text quotes s as a SQL string literal.
+func text(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// optText is text, or NULL for an empty string.
+func optText(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return text(s)
+}
+
+// timeText formats t as RFC 3339 in UTC, or NULL for the zero time.
+func timeText(t time.Time) string {
+	if t.IsZero() {
+		return "NULL"
+	}
+	return text(t.UTC().Format(time.RFC3339))
+}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// dedupe drops repeated tags, which would violate run_tags' primary key.
+func dedupe(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var out []string
+	for _, t := range tags {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package statedb
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/status"
+	"github.com/benwilkes9/ralph-cli/internal/testresult"
+)
+
+var started = time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+
+func sampleState() *state.State {
+	return &state.State{Runs: []state.RunRecord{
+		{
+			Mode: "plan", Status: state.StatusConverged, Branch: "feature/auth",
+			StartedAt: started, FinishedAt: started.Add(time.Minute), Iterations: 1, TotalCost: 0.5,
+			LogFiles: []string{"logs/20260301-093000.jsonl"},
+		},
+		{
+			Mode: "build", Status: state.StatusCompleted, Branch: "feature/auth",
+			StartedAt: started.Add(time.Hour), FinishedAt: started.Add(2 * time.Hour), Iterations: 2, TotalCost: 3.25,
+			Tags: []string{"nightly", "nightly"}, Note: "Bob's run", ResumeOf: 1,
+			Capabilities: &state.Capabilities{Model: "claude-opus-4-6"},
+			Tests:        []state.TestResult{{Iteration: 2, Counts: testresult.Counts{Passed: 8, Failed: 1}}},
+			LogFiles:     []string{"logs/20260301-103000.jsonl", "logs/20260301-110000.jsonl"},
+		},
+	}}
+}
+
+func sampleLogs() map[string]status.IndexEntry {
+	return map[string]status.IndexEntry{
+		"20260301-093000.jsonl": {Cost: 0.5, PeakContext: 1000, StartedAt: started, FinishedAt: started.Add(time.Minute)},
+		"20260301-103000.jsonl": {
+			Cost: 2, PeakContext: 5000, SubagentTokens: 300, StartedAt: started.Add(time.Hour),
+			ModelTokens: map[string]int{"claude-opus-4-6": 900, "claude-haiku-4-5": 100},
+		},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	Write(&buf, sampleState(), sampleLogs())
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN;\nDROP TABLE IF EXISTS iteration_models;"))
+	assert.Contains(t, out, Schema)
+	assert.Contains(t, out, "INSERT INTO runs VALUES (1, 'plan', 'converged', 'feature/auth', '2026-03-01T09:30:00Z', '2026-03-01T09:31:00Z', 1, NULL, 0.5,")
+	assert.Contains(t, out, "'Bob''s run'", "quotes are escaped")
+	assert.Equal(t, 1, strings.Count(out, "INSERT INTO run_tags"), "duplicate tags are dropped")
+	assert.Contains(t, out, "INSERT INTO iterations VALUES (2, 1, 1, 'logs/20260301-103000.jsonl', '2026-03-01T10:30:00Z', NULL, 2, 5000, 300, NULL, NULL, NULL);")
+	assert.Contains(t, out, "INSERT INTO iterations VALUES (2, 2, 1, 'logs/20260301-110000.jsonl', NULL, NULL, NULL, NULL, NULL, 8, 1, 0);")
+	assert.Contains(t, out, "INSERT INTO iteration_models VALUES (2, 1, 1, 'claude-haiku-4-5', 100);\nINSERT INTO iteration_models VALUES (2, 1, 1, 'claude-opus-4-6', 900);")
+	assert.True(t, strings.HasSuffix(out, "COMMIT;\n"))
+}
+
+func TestWrite_RetriedIteration(t *testing.T) {
+	// Iteration 1 failed and was retried, and iteration 2 timed out before
+	// iteration 3 ran: the logs are matched to the iterations that wrote them.
+	st := &state.State{Runs: []state.RunRecord{{
+		Mode: "build", Status: state.StatusCompleted, Iterations: 3,
+		Tests:         []state.TestResult{{Iteration: 1, Counts: testresult.Counts{Passed: 4}}, {Iteration: 3, Counts: testresult.Counts{Passed: 5}}},
+		LogFiles:      []string{"logs/a.jsonl", "logs/b.jsonl", "logs/c.jsonl", "logs/d.jsonl"},
+		LogIterations: []int{1, 1, 2, 3},
+	}}}
+	var buf bytes.Buffer
+	Write(&buf, st, nil)
+	out := buf.String()
+
+	assert.Contains(t, out, "INSERT INTO iterations VALUES (1, 1, 1, 'logs/a.jsonl', NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL);")
+	assert.Contains(t, out, "INSERT INTO iterations VALUES (1, 1, 2, 'logs/b.jsonl', NULL, NULL, NULL, NULL, NULL, 4, 0, 0);")
+	assert.Contains(t, out, "INSERT INTO iterations VALUES (1, 2, 1, 'logs/c.jsonl', NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL);")
+	assert.Contains(t, out, "INSERT INTO iterations VALUES (1, 3, 1, 'logs/d.jsonl', NULL, NULL, NULL, NULL, NULL, 5, 0, 0);")
+}
+
+func TestExport(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	db := filepath.Join(t.TempDir(), "ralph.db")
+	ctx := context.Background()
+
+	require.NoError(t, Export(ctx, db, sampleState(), sampleLogs()))
+	// Exporting again replaces the tables rather than failing or duplicating rows.
+	require.NoError(t, Export(ctx, db, sampleState(), sampleLogs()))
+
+	out, err := exec.Command("sqlite3", db,
+		"SELECT r.mode, count(*), sum(i.cost) FROM runs r JOIN iterations i ON i.run_id = r.id GROUP BY r.mode ORDER BY r.mode;").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "build|2|2.0\nplan|1|0.5\n", string(out))
+}
@@ -0,0 +1,72 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+)
+
+// BadgeLabel is the left-hand text of the status badge.
+const BadgeLabel = "ralph"
+
+// Badge is a shields.io endpoint response
+// (https://shields.io/badges/endpoint-badge). Commit or publish it and point
+// https://img.shields.io/endpoint?url=... at the raw file.
+type Badge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// NewBadge summarises plan progress and total cost, e.g. "7/10 tasks (70%) · $3.21".
+func NewBadge(tasks []Task, runs []RunInfo, cur pricing.Currency) *Badge {
+	var cost float64
+	for _, r := range runs {
+		cost += r.Cost
+	}
+	costText := cur.Format(cost, 2)
+
+	if len(tasks) == 0 {
+		return &Badge{SchemaVersion: 1, Label: BadgeLabel, Message: "no plan · " + costText, Color: "lightgrey"}
+	}
+	done := 0
+	for _, t := range tasks {
+		if t.Done {
+			done++
+		}
+	}
+	pct := done * 100 / len(tasks)
+	return &Badge{
+		SchemaVersion: 1,
+		Label:         BadgeLabel,
+		Message:       fmt.Sprintf("%d/%d tasks (%d%%) · %s", done, len(tasks), pct, costText),
+		Color:         badgeColor(pct),
+	}
+}
+
+// badgeColor shades the badge from orange towards green as tasks complete.
+func badgeColor(pct int) string {
+	switch {
+	case pct >= 100:
+		return "brightgreen"
+	case pct >= 75:
+		return "green"
+	case pct >= 50:
+		return "yellowgreen"
+	case pct >= 25:
+		return "yellow"
+	default:
+		return "orange"
+	}
+}
+
+// JSON renders the badge as indented JSON with a trailing newline.
+func (b *Badge) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling badge: %w", err)
+	}
+	return append(data, '\n'), nil
+}
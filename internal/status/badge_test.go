@@ -0,0 +1,43 @@
+package status
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+)
+
+func TestNewBadge(t *testing.T) {
+	tasks := []Task{{Done: true}, {Done: true}, {Done: true}, {Done: false}}
+	runs := []RunInfo{{Cost: 1.234}, {Cost: 2}}
+
+	b := NewBadge(tasks, runs, pricing.Currency{})
+	assert.Equal(t, 1, b.SchemaVersion)
+	assert.Equal(t, "ralph", b.Label)
+	assert.Equal(t, "3/4 tasks (75%) · $3.23", b.Message)
+	assert.Equal(t, "green", b.Color)
+
+	data, err := b.JSON()
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.InDelta(t, 1, decoded["schemaVersion"], 0)
+	assert.Equal(t, "3/4 tasks (75%) · $3.23", decoded["message"])
+}
+
+func TestNewBadge_NoPlan(t *testing.T) {
+	b := NewBadge(nil, nil, pricing.Currency{})
+	assert.Equal(t, "no plan · $0.00", b.Message)
+	assert.Equal(t, "lightgrey", b.Color)
+}
+
+func TestBadgeColor(t *testing.T) {
+	assert.Equal(t, "orange", badgeColor(0))
+	assert.Equal(t, "yellow", badgeColor(25))
+	assert.Equal(t, "yellowgreen", badgeColor(50))
+	assert.Equal(t, "green", badgeColor(99))
+	assert.Equal(t, "brightgreen", badgeColor(100))
+}
@@ -0,0 +1,154 @@
+package status
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// unattributed labels cost whose phase or model isn't known, such as logs
+// written before either was recorded.
+const unattributed = "other"
+
+// AttachModes sets each run's Mode from the recorded run whose log files
+// include it. Logs no record mentions keep an empty Mode.
+func AttachModes(runs []RunInfo, records []state.RunRecord) {
+	modes := make(map[string]string)
+	for i := range records {
+		for _, f := range records[i].LogFiles {
+			modes[filepath.Base(f)] = records[i].Mode
+		}
+	}
+	for i := range runs {
+		runs[i].Mode = modes[runs[i].Name]
+	}
+}
+
+// CostShare is the cost attributed to one phase or model.
+type CostShare struct {
+	Name string
+	Cost float64
+}
+
+// CostBreakdown splits the total cost of a set of logs by phase and by model.
+type CostBreakdown struct {
+	Total   float64
+	ByMode  []CostShare // most expensive first
+	ByModel []CostShare // most expensive first
+
+	// SubagentShare is the percentage of billed tokens spent by subagents,
+	// or -1 when no tokens were recorded.
+	SubagentShare float64
+}
+
+// BreakdownCosts attributes each log's cost to its run's mode and, in
+// proportion to billed tokens, to the models that answered in it. Cost
+// without a known mode or model counts as "other".
+func BreakdownCosts(runs []RunInfo) CostBreakdown {
+	var b CostBreakdown
+	byMode := map[string]float64{}
+	byModel := map[string]float64{}
+	mainTokens, subTokens := 0, 0
+	for i := range runs {
+		r := &runs[i]
+		b.Total += r.Cost
+
+		mode := r.Mode
+		if mode == "" {
+			mode = unattributed
+		}
+		byMode[mode] += r.Cost
+
+		total := 0
+		for _, n := range r.ModelTokens {
+			total += n
+		}
+		mainTokens += total
+		subTokens += r.SubagentTokens
+		if total == 0 {
+			byModel[unattributed] += r.Cost
+			continue
+		}
+		for model, n := range r.ModelTokens {
+			byModel[modelName(model)] += r.Cost * float64(n) / float64(total)
+		}
+	}
+
+	b.ByMode = sortShares(byMode)
+	b.ByModel = sortShares(byModel)
+	b.SubagentShare = -1
+	if all := mainTokens + subTokens; all > 0 {
+		b.SubagentShare = float64(subTokens) / float64(all) * 100
+	}
+	return b
+}
+
+// modelName shortens a Claude model ID to its family, e.g.
+// "claude-opus-4-6" to "opus". Other agents' models are kept as is.
+func modelName(model string) string {
+	rest, ok := strings.CutPrefix(model, "claude-")
+	if !ok {
+		return model
+	}
+	family, _, _ := strings.Cut(rest, "-")
+	return family
+}
+
+func sortShares(m map[string]float64) []CostShare {
+	shares := make([]CostShare, 0, len(m))
+	for name, cost := range m {
+		shares = append(shares, CostShare{Name: name, Cost: cost})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Cost != shares[j].Cost {
+			return shares[i].Cost > shares[j].Cost
+		}
+		return shares[i].Name < shares[j].Name
+	})
+	return shares
+}
+
+// lines renders the breakdown for the status info box. A split is omitted
+// when nothing in it is attributed, since it would only repeat the total.
+func (b *CostBreakdown) lines(cur pricing.Currency, theme *ui.Theme) []string {
+	var lines []string
+	if attributed(b.ByMode) {
+		lines = append(lines, fmt.Sprintf("  %-11s%s", i18n.T("By phase"), b.shares(b.ByMode, cur, theme)))
+	}
+	if attributed(b.ByModel) {
+		lines = append(lines, fmt.Sprintf("  %-11s%s", i18n.T("By model"), b.shares(b.ByModel, cur, theme)))
+	}
+	if b.SubagentShare > 0 {
+		lines = append(lines, fmt.Sprintf("  %-11s%s", i18n.T("Subagents"), i18n.Tf("%.0f%% of tokens", b.SubagentShare)))
+	}
+	return lines
+}
+
+func (b *CostBreakdown) shares(shares []CostShare, cur pricing.Currency, theme *ui.Theme) string {
+	parts := make([]string, 0, len(shares))
+	for _, s := range shares {
+		pct := 0.0
+		if b.Total > 0 {
+			pct = s.Cost / b.Total * 100
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", s.Name,
+			theme.Cost.Render(cur.Format(s.Cost, 4)), theme.Muted.Render(fmt.Sprintf("(%.0f%%)", pct))))
+	}
+	return strings.Join(parts, "  ·  ")
+}
+
+// attributed reports whether any share names a known phase or model.
+func attributed(shares []CostShare) bool {
+	for _, s := range shares {
+		if s.Name != unattributed {
+			return true
+		}
+	}
+	return false
+}
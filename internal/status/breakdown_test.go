@@ -0,0 +1,80 @@
+package status
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+func TestAttachModes(t *testing.T) {
+	runs := []RunInfo{{Name: "20260210-140000.jsonl"}, {Name: "20260211-143000.jsonl"}, {Name: "20260212-090000.jsonl"}}
+	AttachModes(runs, []state.RunRecord{
+		{Mode: "plan", LogFiles: []string{"logs/20260210-140000.jsonl"}},
+		{Mode: "build", LogFiles: []string{"logs/20260211-143000.jsonl"}},
+	})
+
+	assert.Equal(t, "plan", runs[0].Mode)
+	assert.Equal(t, "build", runs[1].Mode)
+	assert.Empty(t, runs[2].Mode)
+}
+
+func TestBreakdownCosts(t *testing.T) {
+	runs := []RunInfo{
+		{Mode: "plan", Cost: 1, ModelTokens: map[string]int{"claude-opus-4-6": 1000}},
+		{Mode: "build", Cost: 4, ModelTokens: map[string]int{"claude-opus-4-6": 3000, "claude-sonnet-4-5-20250929": 1000}, SubagentTokens: 1000},
+		{Cost: 1}, // an old log: no mode or models recorded
+	}
+
+	b := BreakdownCosts(runs)
+	assert.InDelta(t, 6, b.Total, 1e-9)
+
+	require.Len(t, b.ByMode, 3)
+	assert.Equal(t, CostShare{Name: "build", Cost: 4}, b.ByMode[0])
+	assert.Equal(t, "other", b.ByMode[1].Name)
+	assert.Equal(t, "plan", b.ByMode[2].Name)
+
+	require.Len(t, b.ByModel, 3)
+	assert.Equal(t, "opus", b.ByModel[0].Name)
+	assert.InDelta(t, 4, b.ByModel[0].Cost, 1e-9)
+	assert.Equal(t, "other", b.ByModel[1].Name)
+	assert.Equal(t, "sonnet", b.ByModel[2].Name)
+	assert.InDelta(t, 1, b.ByModel[2].Cost, 1e-9)
+
+	assert.InDelta(t, 16.7, b.SubagentShare, 0.1)
+}
+
+func TestBreakdownCosts_NoTokens(t *testing.T) {
+	b := BreakdownCosts([]RunInfo{{Cost: 2}})
+	assert.InDelta(t, -1, b.SubagentShare, 0)
+	assert.Equal(t, []CostShare{{Name: "other", Cost: 2}}, b.ByModel)
+}
+
+func TestRenderCostBreakdown(t *testing.T) {
+	runs := []RunInfo{
+		{Mode: "plan", Cost: 1, ModelTokens: map[string]int{"claude-opus-4-6": 1000}},
+		{Mode: "build", Cost: 3, ModelTokens: map[string]int{"claude-sonnet-4-5": 1000}, SubagentTokens: 1000},
+	}
+
+	var buf bytes.Buffer
+	Render(&buf, "my-api", "main", nil, runs, nil, pricing.Currency{}, testTheme)
+	out := buf.String()
+
+	assert.Contains(t, out, "By phase")
+	assert.Contains(t, out, "build $3.0000 (75%)")
+	assert.Contains(t, out, "plan $1.0000 (25%)")
+	assert.Contains(t, out, "By model")
+	assert.Contains(t, out, "sonnet $3.0000 (75%)")
+	assert.Contains(t, out, "33% of tokens")
+
+	// Logs with nothing attributed only show the total.
+	buf.Reset()
+	Render(&buf, "my-api", "main", nil, []RunInfo{{Cost: 1}}, nil, pricing.Currency{}, testTheme)
+	assert.NotContains(t, buf.String(), "By phase")
+	assert.NotContains(t, buf.String(), "By model")
+	assert.NotContains(t, buf.String(), "Subagents")
+}
@@ -0,0 +1,117 @@
+package status
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// Baseline labels runs launched without --experiment in a comparison.
+const Baseline = "(baseline)"
+
+// VariantStats aggregates the recorded runs of one experiment variant in one
+// mode. Plan and build runs are kept apart since their costs aren't
+// comparable.
+type VariantStats struct {
+	Experiment  string
+	Mode        string
+	Runs        int
+	Iterations  int
+	TotalCost   float64
+	StaleAborts int
+}
+
+// AvgCost is the mean cost per run.
+func (v *VariantStats) AvgCost() float64 {
+	return v.TotalCost / float64(v.Runs)
+}
+
+// AvgIterations is the mean iteration count per run.
+func (v *VariantStats) AvgIterations() float64 {
+	return float64(v.Iterations) / float64(v.Runs)
+}
+
+// CostPerIteration is the mean cost of one iteration, or 0 with none recorded.
+func (v *VariantStats) CostPerIteration() float64 {
+	if v.Iterations == 0 {
+		return 0
+	}
+	return v.TotalCost / float64(v.Iterations)
+}
+
+// StaleRate is the fraction of runs that ended in a stale abort.
+func (v *VariantStats) StaleRate() float64 {
+	return float64(v.StaleAborts) / float64(v.Runs)
+}
+
+// CompareExperiments groups runs by mode and experiment variant, with runs
+// that used no variant reported as Baseline. Results are sorted by mode, then
+// baseline first, then variant name.
+func CompareExperiments(runs []state.RunRecord) []VariantStats {
+	type key struct{ mode, experiment string }
+	byKey := map[key]*VariantStats{}
+	for i := range runs {
+		r := &runs[i]
+		k := key{r.Mode, r.Experiment}
+		if k.experiment == "" {
+			k.experiment = Baseline
+		}
+		v, ok := byKey[k]
+		if !ok {
+			v = &VariantStats{Experiment: k.experiment, Mode: k.mode}
+			byKey[k] = v
+		}
+		v.Runs++
+		v.Iterations += r.Iterations
+		v.TotalCost += r.TotalCost
+		if r.Status == state.StatusStaleAbort {
+			v.StaleAborts++
+		}
+	}
+
+	out := make([]VariantStats, 0, len(byKey))
+	for _, v := range byKey {
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Mode != out[j].Mode {
+			return out[i].Mode < out[j].Mode
+		}
+		if (out[i].Experiment == Baseline) != (out[j].Experiment == Baseline) {
+			return out[i].Experiment == Baseline
+		}
+		return out[i].Experiment < out[j].Experiment
+	})
+	return out
+}
+
+// RenderComparison writes one row per variant with its run count, average
+// iterations and cost, cost per iteration, and stale-abort rate.
+//
+//nolint:errcheck // display output, best-effort writes
+func RenderComparison(w io.Writer, stats []VariantStats, cur pricing.Currency, theme *ui.Theme) {
+	if len(stats) == 0 {
+		fmt.Fprintln(w, theme.Muted.Render("No runs recorded"))
+		return
+	}
+	fmt.Fprintln(w, theme.Muted.Render(fmt.Sprintf("%-20s  %-5s  %4s  %8s  %10s  %10s  %6s",
+		"VARIANT", "MODE", "RUNS", "AVG ITER", "AVG COST", "COST/ITER", "STALE")))
+	for i := range stats {
+		v := &stats[i]
+		name := fmt.Sprintf("%-20s", v.Experiment)
+		if v.Experiment == Baseline {
+			name = theme.Muted.Render(name)
+		} else {
+			name = theme.Info.Render(name)
+		}
+		fmt.Fprintf(w, "%s  %-5s  %4d  %8.1f  %s  %s  %5.0f%%\n",
+			name, v.Mode, v.Runs, v.AvgIterations(),
+			theme.Cost.Render(fmt.Sprintf("%10s", cur.Format(v.AvgCost(), 4))),
+			theme.Cost.Render(fmt.Sprintf("%10s", cur.Format(v.CostPerIteration(), 4))),
+			100*v.StaleRate())
+	}
+}
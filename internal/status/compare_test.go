@@ -0,0 +1,63 @@
+package status
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+func TestCompareExperiments(t *testing.T) {
+	runs := []state.RunRecord{
+		{Mode: "build", Experiment: "fast-sonnet", Iterations: 4, TotalCost: 1.0, Status: state.StatusCompleted},
+		{Mode: "build", Iterations: 6, TotalCost: 3.0, Status: state.StatusCompleted},
+		{Mode: "build", Experiment: "fast-sonnet", Iterations: 6, TotalCost: 2.0, Status: state.StatusStaleAbort},
+		{Mode: "plan", Experiment: "fast-sonnet", Iterations: 1, TotalCost: 0.2, Status: state.StatusCompleted},
+		{Mode: "build", Experiment: "alpha", Iterations: 0, TotalCost: 0, Status: state.StatusCancelled},
+	}
+
+	stats := CompareExperiments(runs)
+	require.Len(t, stats, 4)
+
+	// build: baseline first, then variants by name; then plan.
+	assert.Equal(t, Baseline, stats[0].Experiment)
+	assert.Equal(t, "alpha", stats[1].Experiment)
+	assert.Equal(t, "fast-sonnet", stats[2].Experiment)
+	assert.Equal(t, "plan", stats[3].Mode)
+
+	fast := stats[2]
+	assert.Equal(t, 2, fast.Runs)
+	assert.InDelta(t, 5.0, fast.AvgIterations(), 1e-9)
+	assert.InDelta(t, 1.5, fast.AvgCost(), 1e-9)
+	assert.InDelta(t, 0.3, fast.CostPerIteration(), 1e-9)
+	assert.InDelta(t, 0.5, fast.StaleRate(), 1e-9)
+
+	assert.Zero(t, stats[1].CostPerIteration(), "no iterations must not divide by zero")
+}
+
+func TestRenderComparison(t *testing.T) {
+	stats := CompareExperiments([]state.RunRecord{
+		{Mode: "build", Experiment: "fast-sonnet", Iterations: 4, TotalCost: 1.0, Status: state.StatusStaleAbort},
+		{Mode: "build", Iterations: 2, TotalCost: 0.5, Status: state.StatusCompleted},
+	})
+
+	var buf bytes.Buffer
+	RenderComparison(&buf, stats, pricing.Currency{}, testTheme)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "VARIANT")
+	assert.Contains(t, lines[1], Baseline)
+	assert.Contains(t, lines[1], "$0.2500")
+	assert.Contains(t, lines[2], "fast-sonnet")
+	assert.Contains(t, lines[2], "$1.0000")
+	assert.Contains(t, lines[2], "100%")
+
+	buf.Reset()
+	RenderComparison(&buf, nil, pricing.Currency{}, testTheme)
+	assert.Contains(t, buf.String(), "No runs recorded")
+}
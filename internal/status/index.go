@@ -0,0 +1,258 @@
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+// IndexFile is the name of the log index kept alongside the .jsonl logs.
+const IndexFile = "index.json"
+
+// indexVersion is bumped whenever IndexEntry changes meaning, forcing a rebuild.
+const indexVersion = 4
+
+// IndexEntry caches what status extracts from one log file. ModTime and Size
+// identify the file contents the entry was computed from, and Pricing the
+// rates when its cost was priced from them.
+type IndexEntry struct {
+	Cost        float64   `json:"cost"`
+	PeakContext int       `json:"peak_context"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	ModTime     time.Time `json:"mod_time"`
+	Size        int64     `json:"size"`
+	Pricing     string    `json:"pricing,omitempty"` // pricing.Table.Fingerprint of cost.pricing for a log without a reported cost
+
+	ModelTokens    map[string]int `json:"model_tokens,omitempty"`
+	SubagentTokens int            `json:"subagent_tokens,omitempty"`
+}
+
+// Index maps log file names (not paths) to their cached entries.
+type Index struct {
+	Version int                   `json:"version"`
+	Logs    map[string]IndexEntry `json:"logs"`
+}
+
+// fresh reports whether e was computed from a file with the given info and,
+// if its cost was priced from rates, from the rates with fingerprint pricing.
+func (e *IndexEntry) fresh(fi os.FileInfo, pricing string) bool {
+	return e.Size == fi.Size() && e.ModTime.Equal(fi.ModTime()) && (e.Pricing == "" || e.Pricing == pricing)
+}
+
+// LoadIndex reads the log index from logsDir. A missing, unreadable, or
+// outdated index yields an empty one, since it can always be rebuilt.
+func LoadIndex(logsDir string) *Index {
+	empty := &Index{Version: indexVersion, Logs: map[string]IndexEntry{}}
+	data, err := os.ReadFile(filepath.Join(logsDir, IndexFile))
+	if err != nil {
+		return empty
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil || idx.Version != indexVersion || idx.Logs == nil {
+		return empty
+	}
+	return &idx
+}
+
+// Save writes the index atomically so a concurrent reader never sees a
+// partial file.
+func (idx *Index) Save(logsDir string) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling log index: %w", err)
+	}
+	tmp, err := os.CreateTemp(logsDir, IndexFile+".*")
+	if err != nil {
+		return fmt.Errorf("creating log index: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after a successful rename
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close() //nolint:errcheck,gosec // already failing
+		return fmt.Errorf("writing log index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing log index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(logsDir, IndexFile)); err != nil {
+		return fmt.Errorf("replacing log index: %w", err)
+	}
+	return nil
+}
+
+// RecordLog indexes a finished log file. The loop calls it after each
+// iteration with the stats it already has, priced from rates, so status
+// never re-parses it while cost.pricing stays the same.
+func RecordLog(logsDir, logPath string, stats *stream.IterationStats, rates pricing.Table) error {
+	fi, err := os.Stat(logPath)
+	if err != nil {
+		return fmt.Errorf("stat log: %w", err)
+	}
+	started, err := LogStartTime(filepath.Base(logPath))
+	if err != nil {
+		return err
+	}
+
+	entry := IndexEntry{
+		StartedAt:  started,
+		FinishedAt: fi.ModTime(),
+		ModTime:    fi.ModTime(),
+		Size:       fi.Size(),
+	}
+	if stats != nil {
+		entry.Cost = stats.Cost
+		entry.PeakContext = stats.PeakContext
+		entry.ModelTokens = stats.ModelTokens
+		entry.SubagentTokens = stats.SubagentTokens
+		if stats.FallbackPriced {
+			entry.Pricing = rates.Fingerprint()
+		}
+	}
+
+	idx := LoadIndex(logsDir)
+	idx.Logs[filepath.Base(logPath)] = entry
+	return idx.Save(logsDir)
+}
+
+// LogStartTime parses the timestamp encoded in a log file name, ignoring
+// the numbered suffix of a log started in the same second as another.
+func LogStartTime(name string) (time.Time, error) {
+	stamp := name[:len(name)-len(filepath.Ext(name))]
+	if n := len(logTimeLayout); len(stamp) > n+1 && stamp[n] == '-' && allDigits(stamp[n+1:]) {
+		stamp = stamp[:n] // a log started in the same second as another
+	}
+	t, err := time.Parse(logTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("log name %q is not a timestamp: %w", name, err)
+	}
+	return t, nil
+}
+
+// LogLess reports whether log a was started before log b: by the timestamp
+// in their names, then by the numbered suffix of logs from the same second.
+// Names that aren't timestamps sort by name.
+func LogLess(a, b string) bool {
+	ta, errA := LogStartTime(a)
+	tb, errB := LogStartTime(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	if !ta.Equal(tb) {
+		return ta.Before(tb)
+	}
+	return logSeq(a) < logSeq(b)
+}
+
+// logSeq returns the numbered suffix of a log name, or 1 for none.
+func logSeq(name string) int {
+	stamp := name[:len(name)-len(filepath.Ext(name))]
+	if n := len(logTimeLayout); len(stamp) > n+1 {
+		if seq, err := strconv.Atoi(stamp[n+1:]); err == nil {
+			return seq
+		}
+	}
+	return 1
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// extractEntry parses a log file for its cost, peak context and token split
+// by model and subagent. A log whose result event has no cost is priced from
+// rates, as the loop priced the iteration. Parsing stops at the first
+// malformed line; what was read before it is kept as long as the result
+// event was seen.
+func extractEntry(path string, fi os.FileInfo, started time.Time, rates pricing.Table) (IndexEntry, error) {
+	f, err := os.Open(path) //nolint:gosec // path is a log file inside the logs directory
+	if err != nil {
+		return IndexEntry{}, fmt.Errorf("opening log %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close() //nolint:errcheck // read-only
+
+	var stats stream.IterationStats
+	var sessionModel string
+	sawResult := false
+	p := stream.NewParser(f)
+	for {
+		evt, err := p.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if sawResult {
+				break
+			}
+			return IndexEntry{}, fmt.Errorf("parsing log %s: %w", filepath.Base(path), err)
+		}
+		switch evt.Type {
+		case "system":
+			if evt.Subtype == "init" && sessionModel == "" {
+				sessionModel = evt.Model
+			}
+		case "assistant":
+			if evt.Message != nil {
+				stats.ObserveAssistant(evt.Message.Usage)
+				stats.ObserveModel(evt.Message.Model, evt.Message.Usage)
+			}
+		case "user":
+			if r := evt.ToolUseResult; r != nil && r.TotalTokens > 0 {
+				stats.ObserveSubagent(r.TotalTokens)
+			}
+		case "result":
+			if sawResult {
+				break
+			}
+			stats.ObserveResult(evt.TotalCostUSD)
+			stats.ObserveUsage(evt.Usage)
+			sawResult = true
+		}
+	}
+	stats.PriceFallback(rates, logModel(sessionModel, stats.ModelTokens))
+	var fingerprint string
+	if stats.FallbackPriced {
+		fingerprint = rates.Fingerprint()
+	}
+
+	return IndexEntry{
+		Cost:        stats.Cost,
+		PeakContext: stats.PeakContext,
+		StartedAt:   started,
+		FinishedAt:  fi.ModTime(),
+		ModTime:     fi.ModTime(),
+		Size:        fi.Size(),
+		Pricing:     fingerprint,
+
+		ModelTokens:    stats.ModelTokens,
+		SubagentTokens: stats.SubagentTokens,
+	}, nil
+}
+
+// logModel is the model a log is priced at: the one its session started
+// with, else the one that billed the most tokens.
+func logModel(session string, tokens map[string]int) string {
+	if session != "" {
+		return session
+	}
+	best := ""
+	for m, n := range tokens {
+		if n > tokens[best] || (n == tokens[best] && (best == "" || m < best)) {
+			best = m
+		}
+	}
+	return best
+}
@@ -0,0 +1,175 @@
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/stream"
+)
+
+func writeLog(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestParseLogs_WritesIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "20260210-140000.jsonl",
+		`{"type":"assistant","message":{"role":"assistant","content":[],"usage":{"input_tokens":100,"cache_read_input_tokens":900}}}
+{"type":"result","total_cost_usd":1.5}
+`)
+
+	_, err := ParseLogs(dir, nil)
+	require.NoError(t, err)
+
+	idx := LoadIndex(dir)
+	require.Contains(t, idx.Logs, "20260210-140000.jsonl")
+	entry := idx.Logs["20260210-140000.jsonl"]
+	assert.InDelta(t, 1.5, entry.Cost, 1e-9)
+	assert.Equal(t, 1000, entry.PeakContext)
+	assert.Equal(t, time.Date(2026, 2, 10, 14, 0, 0, 0, time.UTC), entry.StartedAt)
+}
+
+func TestParseLogs_IndexesModelAndSubagentTokens(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "20260210-140000.jsonl",
+		`{"type":"assistant","message":{"model":"claude-opus-4-6","role":"assistant","content":[],"usage":{"input_tokens":100,"cache_creation_input_tokens":50,"cache_read_input_tokens":900,"output_tokens":10}}}
+{"type":"assistant","message":{"model":"claude-sonnet-4-5","role":"assistant","content":[],"usage":{"input_tokens":20,"output_tokens":5}}}
+{"type":"user","message":{"role":"user","content":[]},"tool_use_result":{"totalTokens":400}}
+{"type":"result","total_cost_usd":1.5}
+`)
+
+	runs, err := ParseLogs(dir, nil)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, map[string]int{"claude-opus-4-6": 160, "claude-sonnet-4-5": 25}, runs[0].ModelTokens)
+	assert.Equal(t, 400, runs[0].SubagentTokens)
+
+	entry := LoadIndex(dir).Logs["20260210-140000.jsonl"]
+	assert.Equal(t, runs[0].ModelTokens, entry.ModelTokens)
+	assert.Equal(t, 400, entry.SubagentTokens)
+}
+
+func TestParseLogs_PricesLogsWithoutCost(t *testing.T) {
+	dir := t.TempDir()
+	// A gateway that reports usage but no total_cost_usd.
+	writeLog(t, dir, "20260210-140000.jsonl",
+		`{"type":"system","subtype":"init","model":"claude-sonnet-4-5"}
+{"type":"assistant","message":{"model":"claude-sonnet-4-5","role":"assistant","content":[],"usage":{"input_tokens":100}}}
+{"type":"result","usage":{"input_tokens":1000000,"output_tokens":100000,"cache_read_input_tokens":1000000}}
+`)
+	rates := pricing.Table{"sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30}}
+
+	runs, err := ParseLogs(dir, rates)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.InDelta(t, 4.8, runs[0].Cost, 1e-9, "priced as the loop prices it")
+	assert.InDelta(t, 4.8, LoadIndex(dir).Logs["20260210-140000.jsonl"].Cost, 1e-9)
+}
+
+func TestParseLogs_RepricesWhenPricingChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLog(t, dir, "20260210-140000.jsonl",
+		`{"type":"system","subtype":"init","model":"claude-sonnet-4-5"}
+{"type":"result","usage":{"input_tokens":1000000}}
+`)
+	old := pricing.Table{"sonnet": {Input: 3}}
+	stats := &stream.IterationStats{InputTokens: 1000000}
+	stats.PriceFallback(old, "claude-sonnet-4-5")
+	require.NoError(t, RecordLog(dir, path, stats, old))
+
+	runs, err := ParseLogs(dir, old)
+	require.NoError(t, err)
+	assert.InDelta(t, 3.0, runs[0].Cost, 1e-9, "the loop's entry is kept while the rates are the same")
+
+	runs, err = ParseLogs(dir, pricing.Table{"sonnet": {Input: 2}})
+	require.NoError(t, err)
+	assert.InDelta(t, 2.0, runs[0].Cost, 1e-9, "re-priced at the new rates")
+
+	// A reported cost doesn't depend on the rates.
+	writeLog(t, dir, "20260210-150000.jsonl", `{"type":"result","total_cost_usd":1.5}`+"\n")
+	_, err = ParseLogs(dir, old)
+	require.NoError(t, err)
+	assert.Empty(t, LoadIndex(dir).Logs["20260210-150000.jsonl"].Pricing)
+}
+
+func TestParseLogs_UsesFreshIndexEntries(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLog(t, dir, "20260210-140000.jsonl", `{"type":"result","total_cost_usd":1.5}`+"\n")
+
+	// Record different stats than the log contains; a fresh entry must win.
+	require.NoError(t, RecordLog(dir, path, &stream.IterationStats{Cost: 9.99, PeakContext: 42}, nil))
+
+	runs, err := ParseLogs(dir, nil)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.InDelta(t, 9.99, runs[0].Cost, 1e-9)
+	assert.Equal(t, 42, runs[0].PeakContext)
+}
+
+func TestParseLogs_ReparsesModifiedLogs(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLog(t, dir, "20260210-140000.jsonl", `{"type":"result","total_cost_usd":1.5}`+"\n")
+	require.NoError(t, RecordLog(dir, path, &stream.IterationStats{Cost: 9.99}, nil))
+
+	writeLog(t, dir, "20260210-140000.jsonl", `{"type":"result","total_cost_usd":2.25}`+"\n")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	runs, err := ParseLogs(dir, nil)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.InDelta(t, 2.25, runs[0].Cost, 1e-9)
+}
+
+func TestParseLogs_PrunesDeletedLogs(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLog(t, dir, "20260210-140000.jsonl", `{"type":"result","total_cost_usd":1.5}`+"\n")
+	require.NoError(t, RecordLog(dir, path, nil, nil))
+	require.NoError(t, os.Remove(path))
+
+	runs, err := ParseLogs(dir, nil)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+	assert.Empty(t, LoadIndex(dir).Logs)
+}
+
+func TestLoadIndex_CorruptOrOutdated(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, LoadIndex(dir).Logs, "missing index")
+
+	writeLog(t, dir, IndexFile, "{not json")
+	assert.Empty(t, LoadIndex(dir).Logs, "corrupt index")
+
+	writeLog(t, dir, IndexFile, `{"version":0,"logs":{"x.jsonl":{"cost":1}}}`)
+	assert.Empty(t, LoadIndex(dir).Logs, "outdated index version")
+}
+
+func TestLogStartTime(t *testing.T) {
+	want := time.Date(2026, 2, 10, 14, 0, 0, 0, time.UTC)
+	for _, name := range []string{"20260210-140000.jsonl", "20260210-140000-2.jsonl", "20260210-140000-17.jsonl"} {
+		got, err := LogStartTime(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+	for _, name := range []string{"notes.jsonl", "20260210-140000-.jsonl", "20260210-140000-x.jsonl"} {
+		_, err := LogStartTime(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLogLess(t *testing.T) {
+	assert.True(t, LogLess("20260210-140000.jsonl", "20260210-140000-2.jsonl"))
+	assert.True(t, LogLess("20260210-140000-2.jsonl", "20260210-140000-10.jsonl"))
+	assert.True(t, LogLess("20260210-140000-10.jsonl", "20260210-140001.jsonl"))
+	assert.False(t, LogLess("20260210-140000-2.jsonl", "20260210-140000.jsonl"))
+	assert.True(t, LogLess("a.jsonl", "b.jsonl"))
+}
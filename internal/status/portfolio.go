@@ -0,0 +1,171 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// defaultPlanPrefix starts the name of a plan file written without a
+// filename template, followed by the sanitized branch.
+const defaultPlanPrefix = "IMPLEMENTATION_PLAN_"
+
+// BranchPlan is one branch's plan, with what its runs have spent, for a
+// view across every branch whose plan is in the tree.
+type BranchPlan struct {
+	Branch   string
+	PlanFile string // relative to the repo root, slash-separated
+	Tasks    []Task
+	Runs     int
+	Cost     float64
+	LastRun  *state.RunRecord // nil = no run recorded in this tree
+}
+
+// Done counts the plan's completed tasks.
+func (p *BranchPlan) Done() int {
+	n := 0
+	for _, t := range p.Tasks {
+		if t.Done {
+			n++
+		}
+	}
+	return n
+}
+
+// CollectPlans reads every ralph plan under plansDir (relative to repoRoot),
+// such as the plans a release branch merged in from its feature branches.
+// A plan belongs to the branch whose runs recorded it, else to the branch
+// its default name carries, else is named by its path. Markdown files
+// without tasks aren't plans. With plansDir "." only the repo root's own
+// files are read. Plans are sorted by branch.
+func CollectPlans(repoRoot, plansDir string, runs []state.RunRecord) ([]BranchPlan, error) {
+	root := filepath.Join(repoRoot, plansDir)
+	var plans []BranchPlan
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (filepath.Clean(plansDir) == "." || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		tasks, err := ParsePlan(path)
+		if err != nil || len(tasks) == 0 {
+			return err
+		}
+		rel, err := filepath.Rel(repoRoot, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		plans = append(plans, BranchPlan{PlanFile: filepath.ToSlash(rel), Tasks: tasks})
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading plans in %s: %w", plansDir, err)
+	}
+
+	for i := range plans {
+		p := &plans[i]
+		for j := range runs {
+			r := &runs[j]
+			if r.PlanFile == "" || filepath.ToSlash(filepath.Clean(r.PlanFile)) != p.PlanFile {
+				continue
+			}
+			if r.Branch != "" {
+				p.Branch = r.Branch
+			}
+			p.Runs++
+			p.Cost += r.TotalCost
+			p.LastRun = r
+		}
+		if p.Branch == "" {
+			p.Branch = planBranch(p.PlanFile)
+		}
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].Branch < plans[j].Branch })
+	return plans, nil
+}
+
+// planBranch names a plan no run recorded: the branch in a default plan
+// name, else the plan's path without its extension.
+func planBranch(planFile string) string {
+	name := strings.TrimSuffix(filepath.Base(planFile), filepath.Ext(planFile))
+	if branch, ok := strings.CutPrefix(name, defaultPlanPrefix); ok && branch != "" {
+		return branch
+	}
+	return strings.TrimSuffix(planFile, filepath.Ext(planFile))
+}
+
+// RenderPortfolio writes one block per branch plan: its progress, its next
+// task, and what its runs have spent, under a total across all of them.
+//
+//nolint:errcheck // display output, best-effort writes
+func RenderPortfolio(w io.Writer, project, plansDir string, plans []BranchPlan, current string, cur pricing.Currency, theme *ui.Theme) {
+	fmt.Fprintln(w, theme.StatusBox.Render(fmt.Sprintf("%s  ·  %s", project, i18n.T("all branches"))))
+	if len(plans) == 0 {
+		fmt.Fprintln(w, theme.Muted.Render(i18n.Tf("No plans in %s", plansDir)))
+		return
+	}
+
+	done, total, runs := 0, 0, 0
+	var cost float64
+	width := 0
+	for i := range plans {
+		done += plans[i].Done()
+		total += len(plans[i].Tasks)
+		runs += plans[i].Runs
+		cost += plans[i].Cost
+		width = max(width, len([]rune(plans[i].Branch)))
+	}
+	fmt.Fprintf(w, "\n %s\n", i18n.Tf("%d branch(es): %d/%d tasks complete (%d%%), %s across %d run(s)",
+		len(plans), done, total, done*100/total, theme.Cost.Render(cur.Format(cost, 2)), runs))
+
+	const barWidth = 20
+	for i := range plans {
+		p := &plans[i]
+		pct := p.Done() * 100 / len(p.Tasks)
+		filled := barWidth * pct / 100
+		bar := theme.Success.Render(strings.Repeat("█", filled)) + theme.Muted.Render(strings.Repeat("░", barWidth-filled))
+		marker := " "
+		// A plan named from its file carries the sanitized branch, so
+		// compare both sides sanitized.
+		if git.SanitizeBranch(p.Branch) == git.SanitizeBranch(current) {
+			marker = theme.Info.Render("*")
+		}
+		name := p.Branch + strings.Repeat(" ", width-len([]rune(p.Branch)))
+		line := fmt.Sprintf("%s %s  %s %3d%%  %d/%d", marker, theme.Info.Render(name), bar, pct, p.Done(), len(p.Tasks))
+		if p.Runs > 0 {
+			line += "  " + theme.Cost.Render(cur.Format(p.Cost, 2)) + "  " + theme.Muted.Render(i18n.Tf("%d run(s)", p.Runs))
+		}
+		if p.LastRun != nil {
+			line += "  " + theme.Muted.Render(i18n.Tf("last %s %s (%s)", p.LastRun.Mode, p.LastRun.StartedAt.Format("2006-01-02"), p.LastRun.Status))
+		}
+		fmt.Fprintf(w, "\n%s\n", line)
+
+		indent := strings.Repeat(" ", width+4)
+		if next := ActiveTask(p.Tasks); next != nil {
+			fmt.Fprintf(w, "%s%s %s\n", indent, theme.Muted.Render("→"), next.Title)
+		} else {
+			fmt.Fprintf(w, "%s%s\n", indent, theme.Success.Render(i18n.T("✓ all tasks complete")))
+		}
+		fmt.Fprintf(w, "%s%s\n", indent, theme.Muted.Render(p.PlanFile))
+	}
+}
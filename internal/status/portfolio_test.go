@@ -0,0 +1,87 @@
+package status
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+)
+
+func TestCollectPlans(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	write(".ralph/plans/IMPLEMENTATION_PLAN_feat-login.md", "### Task 1: Scaffold\n- [x] done\n\n### Task 2: Form\n- [ ] todo\n")
+	write(".ralph/plans/IMPLEMENTATION_PLAN_feat-search.md", "### Task 1: Index\n- [ ] todo\n")
+	write(".ralph/plans/billing/plan.md", "### Task 1: Invoices\n- [x] done\n")
+	write(".ralph/plans/README.md", "No tasks here.\n")
+	write(".ralph/plans/.archive/old.md", "### Task 1: Old\n- [ ] todo\n")
+
+	started := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	runs := []state.RunRecord{
+		{Mode: "build", Branch: "feat/login", PlanFile: ".ralph/plans/IMPLEMENTATION_PLAN_feat-login.md", TotalCost: 1.5, StartedAt: started, Status: state.StatusCompleted},
+		{Mode: "build", Branch: "feat/login", PlanFile: ".ralph/plans/IMPLEMENTATION_PLAN_feat-login.md", TotalCost: 0.5, StartedAt: started.Add(time.Hour), Status: state.StatusStaleAbort},
+		{Mode: "build", Branch: "feat/other", PlanFile: ".ralph/plans/gone.md", TotalCost: 9},
+	}
+
+	plans, err := CollectPlans(dir, ".ralph/plans", runs)
+	require.NoError(t, err)
+	require.Len(t, plans, 3)
+
+	assert.Equal(t, ".ralph/plans/billing/plan", plans[0].Branch, "a plan no run recorded is named by its path")
+	assert.Equal(t, 1, plans[0].Done())
+	assert.Zero(t, plans[0].Runs)
+
+	assert.Equal(t, "feat-search", plans[1].Branch, "the branch in a default plan name")
+	assert.Nil(t, plans[1].LastRun)
+
+	login := plans[2]
+	assert.Equal(t, "feat/login", login.Branch, "the branch its runs recorded")
+	assert.Equal(t, ".ralph/plans/IMPLEMENTATION_PLAN_feat-login.md", login.PlanFile)
+	assert.Equal(t, 2, login.Runs)
+	assert.InDelta(t, 2.0, login.Cost, 1e-9)
+	require.NotNil(t, login.LastRun)
+	assert.Equal(t, state.StatusStaleAbort, login.LastRun.Status)
+
+	plans, err = CollectPlans(dir, "missing", runs)
+	require.NoError(t, err)
+	assert.Empty(t, plans)
+}
+
+func TestRenderPortfolio(t *testing.T) {
+	plans := []BranchPlan{
+		{Branch: "feat/login", PlanFile: "plans/login.md", Runs: 2, Cost: 2,
+			Tasks:   []Task{{Title: "Scaffold", Done: true}, {Title: "Form"}},
+			LastRun: &state.RunRecord{Mode: "build", StartedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC), Status: state.StatusCompleted}},
+		{Branch: "feat/search", PlanFile: "plans/search.md", Tasks: []Task{{Title: "Index", Done: true}}},
+	}
+
+	var buf bytes.Buffer
+	RenderPortfolio(&buf, "my-api", "plans", plans, "feat/search", pricing.Currency{}, testTheme)
+	out := buf.String()
+	assert.Contains(t, out, "my-api")
+	assert.Contains(t, out, "2 branch(es): 2/3 tasks complete (66%), $2.00 across 2 run(s)")
+	assert.Contains(t, out, "→ Form")
+	assert.Contains(t, out, "last build 2026-10-01 (completed)")
+	assert.Contains(t, out, "✓ all tasks complete")
+	assert.Contains(t, out, "plans/search.md")
+
+	// A plan no run recorded is named by its sanitized branch.
+	buf.Reset()
+	plans[1].Branch = "feat-search"
+	RenderPortfolio(&buf, "my-api", "plans", plans, "feat/search", pricing.Currency{}, testTheme)
+	assert.Contains(t, buf.String(), "* feat-search", "the current branch is marked")
+
+	buf.Reset()
+	RenderPortfolio(&buf, "my-api", "plans", nil, "", pricing.Currency{}, testTheme)
+	assert.Contains(t, buf.String(), "No plans in plans")
+}
@@ -12,24 +12,78 @@ import (
 	"strings"
 	"time"
 
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	logfile "github.com/benwilkes9/ralph-cli/internal/log"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
 	"github.com/benwilkes9/ralph-cli/internal/state"
-	"github.com/benwilkes9/ralph-cli/internal/stream"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
 
 // Task represents a single task parsed from the implementation plan.
 type Task struct {
+	ID    string // task number from the heading, e.g. "2.1"
 	Title string
 	Done  bool
+	Label string // from a "- **Label:** tests" line, lowercased; empty = unlabelled
 }
 
+// Task categories inferred from a title when a task has no label.
+const (
+	CategoryRefactor = "refactor"
+	CategoryTests    = "tests"
+	CategoryDocs     = "docs"
+)
+
+// categoryWords maps words in task titles to the category they imply, in
+// priority order.
+var categoryWords = []struct {
+	re       *regexp.Regexp
+	category string
+}{
+	{regexp.MustCompile(`(?i)\brefactor`), CategoryRefactor},
+	{regexp.MustCompile(`(?i)\b(?:docs?|documentation|readme)\b`), CategoryDocs},
+	{regexp.MustCompile(`(?i)\btests?\b`), CategoryTests},
+}
+
+// Category is the kind of work the task is: its label, else refactor, docs
+// or tests when its title says so, else "".
+func (t *Task) Category() string {
+	if t.Label != "" {
+		return t.Label
+	}
+	for _, w := range categoryWords {
+		if w.re.MatchString(t.Title) {
+			return w.category
+		}
+	}
+	return ""
+}
+
+var taskLabelRe = regexp.MustCompile(`^(?:[-*]\s+)?\*{0,2}Labels?:\*{0,2}\s*` + "`?" + `([\w-]+)`)
+
 // RunInfo holds metadata from a single log file.
 type RunInfo struct {
-	Time time.Time
-	Cost float64
+	Name        string // log file name
+	Time        time.Time
+	Cost        float64
+	PeakContext int
+
+	ModelTokens    map[string]int // billed tokens by model, for splitting Cost
+	SubagentTokens int
+	Mode           string // plan or build, from the run that wrote the log; "" if unknown
 }
 
-var taskHeadingRe = regexp.MustCompile(`^###\s+Task\s+[\d.]+\s*[-–—:]+\s*(.+)`)
+var taskHeadingRe = regexp.MustCompile(`^###\s+Task\s+([\d.]+)\s*[-–—:]+\s*(.+)`)
+
+// ParseTaskHeading returns the task a plan line starts, e.g.
+// "### Task 2.1: Add login", or false when the line isn't a task heading.
+func ParseTaskHeading(line string) (Task, bool) {
+	m := taskHeadingRe.FindStringSubmatch(line)
+	if m == nil {
+		return Task{}, false
+	}
+	return Task{ID: strings.TrimRight(m[1], "."), Title: strings.TrimSpace(m[2])}, true
+}
 
 // ParsePlan reads an IMPLEMENTATION_PLAN.md and extracts tasks from it.
 // Returns nil, nil if the file does not exist (plan not yet generated).
@@ -48,12 +102,16 @@ func ParsePlan(path string) ([]Task, error) {
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		if m := taskHeadingRe.FindStringSubmatch(line); m != nil {
-			tasks = append(tasks, Task{Title: strings.TrimSpace(m[1])})
+		if task, ok := ParseTaskHeading(line); ok {
+			tasks = append(tasks, task)
 			continue
 		}
 
 		trimmed := strings.TrimSpace(line)
+		if m := taskLabelRe.FindStringSubmatch(trimmed); m != nil && len(tasks) > 0 {
+			tasks[len(tasks)-1].Label = strings.ToLower(m[1])
+			continue
+		}
 		if strings.HasPrefix(trimmed, "- [x]") || strings.HasPrefix(trimmed, "- [X]") {
 			if len(tasks) > 0 {
 				tasks[len(tasks)-1].Done = true
@@ -70,9 +128,26 @@ func ParsePlan(path string) ([]Task, error) {
 	return tasks, nil
 }
 
+// logTimeLayout is the timestamp format of log file names.
+const logTimeLayout = logfile.TimeLayout
+
+// ActiveTask returns the first incomplete task, or nil when all are done.
+func ActiveTask(tasks []Task) *Task {
+	for i := range tasks {
+		if !tasks[i].Done {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
 // ParseLogs scans a logs directory for .jsonl files and extracts run info.
+// Results are cached in IndexFile and reused while a log's size and mtime are
+// unchanged, so only new or modified logs are parsed, and logs without a
+// reported cost are priced from rates (cost.pricing), again when those
+// change. The index is rewritten (best-effort) when anything changed.
 // Returns nil, nil if the directory does not exist.
-func ParseLogs(logsDir string) ([]RunInfo, error) {
+func ParseLogs(logsDir string, rates pricing.Table) ([]RunInfo, error) {
 	entries, err := os.ReadDir(logsDir)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -81,59 +156,63 @@ func ParseLogs(logsDir string) ([]RunInfo, error) {
 		return nil, fmt.Errorf("reading logs dir: %w", err)
 	}
 
+	idx := LoadIndex(logsDir)
+	fingerprint := rates.Fingerprint()
+	seen := make(map[string]bool, len(entries))
+	dirty := false
+
 	var runs []RunInfo
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
 			continue
 		}
 
-		name := strings.TrimSuffix(entry.Name(), ".jsonl")
-		t, err := time.Parse("20060102-150405", name)
+		t, err := LogStartTime(entry.Name())
 		if err != nil {
 			continue // skip files that don't match the timestamp format
 		}
-
-		cost, err := extractCost(filepath.Join(logsDir, entry.Name()))
+		fi, err := entry.Info()
 		if err != nil {
 			continue
 		}
 
-		runs = append(runs, RunInfo{Time: t, Cost: cost})
-	}
-
-	sort.Slice(runs, func(i, j int) bool {
-		return runs[i].Time.Before(runs[j].Time)
-	})
-	return runs, nil
-}
+		seen[entry.Name()] = true
+		cached, ok := idx.Logs[entry.Name()]
+		if !ok || !cached.fresh(fi, fingerprint) {
+			cached, err = extractEntry(filepath.Join(logsDir, entry.Name()), fi, t, rates)
+			if err != nil {
+				continue
+			}
+			idx.Logs[entry.Name()] = cached
+			dirty = true
+		}
 
-func extractCost(path string) (float64, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return 0, fmt.Errorf("opening log %s: %w", filepath.Base(path), err)
+		runs = append(runs, RunInfo{
+			Name: entry.Name(), Time: t, Cost: cached.Cost, PeakContext: cached.PeakContext,
+			ModelTokens: cached.ModelTokens, SubagentTokens: cached.SubagentTokens,
+		})
 	}
-	defer f.Close() //nolint:errcheck // read-only
 
-	p := stream.NewParser(f)
-	for {
-		evt, err := p.Next()
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		if err != nil {
-			return 0, fmt.Errorf("parsing log %s: %w", filepath.Base(path), err)
-		}
-		if evt.Type == "result" && evt.TotalCostUSD > 0 {
-			return evt.TotalCostUSD, nil
+	for name := range idx.Logs {
+		if !seen[name] {
+			delete(idx.Logs, name)
+			dirty = true
 		}
 	}
-	return 0, nil
+	if dirty {
+		_ = idx.Save(logsDir) //nolint:errcheck // best-effort cache; status still works read-only
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return LogLess(runs[i].Name, runs[j].Name)
+	})
+	return runs, nil
 }
 
 // Render writes a formatted status summary to w with themed styling.
 //
 //nolint:errcheck // display output, best-effort writes
-func Render(w io.Writer, project, branch string, tasks []Task, runs []RunInfo, lastRun *state.RunRecord, theme *ui.Theme) {
+func Render(w io.Writer, project, branch string, tasks []Task, runs []RunInfo, lastRun *state.RunRecord, cur pricing.Currency, theme *ui.Theme) {
 	fmt.Fprintln(w, theme.Banner())
 	fmt.Fprintln(w)
 
@@ -149,7 +228,7 @@ func Render(w io.Writer, project, branch string, tasks []Task, runs []RunInfo, l
 			}
 		}
 		pct := done * 100 / len(tasks)
-		fmt.Fprintf(w, "\n Tasks  %d/%d complete (%d%%)\n", done, len(tasks), pct)
+		fmt.Fprintf(w, "\n %s\n", i18n.Tf("Tasks  %d/%d complete (%d%%)", done, len(tasks), pct))
 
 		// Progress bar
 		barWidth := 40
@@ -170,12 +249,19 @@ func Render(w io.Writer, project, branch string, tasks []Task, runs []RunInfo, l
 	var infoLines []string
 	if lastRun != nil {
 		infoLines = append(infoLines,
-			fmt.Sprintf("Last run   %s (%s, %d iterations)",
-				lastRun.StartedAt.Format("2006-01-02 15:04"), lastRun.Mode, lastRun.Iterations))
+			fmt.Sprintf("%-11s%s", i18n.T("Last run"), i18n.Tf("%s (%s, %d iterations)",
+				lastRun.StartedAt.Format("2006-01-02 15:04"), lastRun.Mode, lastRun.Iterations)))
+		if n := len(lastRun.Tests); n > 0 {
+			line := fmt.Sprintf("%-11s%s", i18n.T("Tests"), lastRun.Tests[n-1].Counts)
+			if before, after, regressed := lastRun.TestRegression(); regressed {
+				line += "  " + theme.Warning.Render(i18n.Tf("▲ failures %d → %d", before, after))
+			}
+			infoLines = append(infoLines, line)
+		}
 	} else if len(runs) > 0 {
 		last := runs[len(runs)-1]
 		infoLines = append(infoLines,
-			fmt.Sprintf("Last run   %s", last.Time.Format("2006-01-02 15:04")))
+			fmt.Sprintf("%-11s%s", i18n.T("Last run"), last.Time.Format("2006-01-02 15:04")))
 	}
 
 	if len(runs) > 0 {
@@ -184,8 +270,10 @@ func Render(w io.Writer, project, branch string, tasks []Task, runs []RunInfo, l
 			totalCost += r.Cost
 		}
 		infoLines = append(infoLines,
-			fmt.Sprintf("Total cost %s across %d iterations",
-				theme.Cost.Render(fmt.Sprintf("$%.4f", totalCost)), len(runs)))
+			i18n.Tf("Total cost %s across %d iterations",
+				theme.Cost.Render(cur.Format(totalCost, 4)), len(runs)))
+		breakdown := BreakdownCosts(runs)
+		infoLines = append(infoLines, breakdown.lines(cur, theme)...)
 	}
 
 	if len(infoLines) > 0 {
@@ -194,3 +282,40 @@ func Render(w io.Writer, project, branch string, tasks []Task, runs []RunInfo, l
 		fmt.Fprintln(w, theme.SummaryBox.Render(content))
 	}
 }
+
+// RenderHistory writes one line per recorded run, oldest first, including any
+// profile, experiment, tags and note attached at launch, the run a resumed
+// run continues, and why commit hooks were skipped.
+//
+//nolint:errcheck // display output, best-effort writes
+func RenderHistory(w io.Writer, runs []state.RunRecord, cur pricing.Currency, theme *ui.Theme) {
+	if len(runs) == 0 {
+		fmt.Fprintln(w, theme.Muted.Render(i18n.T("No runs recorded")))
+		return
+	}
+	for i := range runs {
+		r := &runs[i]
+		line := fmt.Sprintf("%s  %-5s  %3d iter  %-14s  %s",
+			r.StartedAt.Format("2006-01-02 15:04"), r.Mode, r.Iterations, r.Status,
+			theme.Cost.Render(cur.Format(r.TotalCost, 4)))
+		if r.ResumeOf > 0 {
+			line += "  " + theme.Muted.Render(fmt.Sprintf("↻#%d", r.ResumeOf))
+		}
+		if r.Profile != "" {
+			line += "  " + theme.Muted.Render("@"+r.Profile)
+		}
+		if r.Experiment != "" {
+			line += "  " + theme.Info.Render("exp:"+r.Experiment)
+		}
+		if len(r.Tags) > 0 {
+			line += "  " + theme.Info.Render("["+strings.Join(r.Tags, ", ")+"]")
+		}
+		if r.Note != "" {
+			line += "  " + theme.Muted.Render(r.Note)
+		}
+		if r.HooksSkipped != "" {
+			line += "  " + theme.Warning.Render(i18n.Tf("hooks skipped: %s", r.HooksSkipped))
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
 	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/testresult"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
 
@@ -93,18 +96,53 @@ func TestParsePlan_NewFormat(t *testing.T) {
 	require.Len(t, tasks, 4)
 
 	want := []struct {
+		id    string
 		title string
 		done  bool
 	}{
-		{"Dependencies and project config", true},
-		{"Database layer", true},
-		{"Delete todo endpoint", false},
-		{"List filtering and search", false},
+		{"1.1", "Dependencies and project config", true},
+		{"1.2", "Database layer", true},
+		{"2.1", "Delete todo endpoint", false},
+		{"2.2", "List filtering and search", false},
 	}
 	for i, w := range want {
+		assert.Equal(t, w.id, tasks[i].ID, "task %d id", i)
 		assert.Equal(t, w.title, tasks[i].Title, "task %d title", i)
 		assert.Equal(t, w.done, tasks[i].Done, "task %d done", i)
 	}
+
+	active := ActiveTask(tasks)
+	require.NotNil(t, active)
+	assert.Equal(t, "2.1", active.ID)
+	assert.Nil(t, ActiveTask(tasks[:2]), "all done")
+}
+
+func TestParsePlan_Labels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "PLAN.md")
+	require.NoError(t, os.WriteFile(path, []byte(`### Task 1.1: Add login
+- **Label:** Design
+- [ ] **Status:** Incomplete
+
+### Task 1.2: Write README section
+Label: `+"`docs`"+`
+
+### Task 1.3: Add tests for the parser
+
+### Task 1.4: Refactoring the store, with tests
+
+### Task 1.5: Add billing
+`), 0o600))
+
+	tasks, err := ParsePlan(path)
+	require.NoError(t, err)
+	require.Len(t, tasks, 5)
+	assert.Equal(t, "design", tasks[0].Label)
+	assert.Equal(t, "docs", tasks[1].Label)
+	var categories []string
+	for i := range tasks {
+		categories = append(categories, tasks[i].Category())
+	}
+	assert.Equal(t, []string{"design", "docs", CategoryTests, CategoryRefactor, ""}, categories)
 }
 
 func TestParsePlanMissingFile(t *testing.T) {
@@ -126,7 +164,7 @@ func TestParseLogs(t *testing.T) {
 	// Non-JSONL file should be ignored.
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignore me"), 0o600))
 
-	runs, err := ParseLogs(dir)
+	runs, err := ParseLogs(dir, nil)
 	require.NoError(t, err)
 	require.Len(t, runs, 2)
 
@@ -136,7 +174,7 @@ func TestParseLogs(t *testing.T) {
 }
 
 func TestParseLogsMissingDir(t *testing.T) {
-	runs, err := ParseLogs("/nonexistent/logs")
+	runs, err := ParseLogs("/nonexistent/logs", nil)
 	require.NoError(t, err)
 	assert.Nil(t, runs)
 }
@@ -159,7 +197,7 @@ func TestRender(t *testing.T) {
 	}
 
 	var buf bytes.Buffer
-	Render(&buf, "my-api", "feature/auth", tasks, runs, lastRun, testTheme)
+	Render(&buf, "my-api", "feature/auth", tasks, runs, lastRun, pricing.Currency{}, testTheme)
 	out := buf.String()
 
 	for _, want := range []string{
@@ -180,12 +218,85 @@ func TestRender(t *testing.T) {
 	}
 }
 
+func TestRenderTestRegression(t *testing.T) {
+	lastRun := &state.RunRecord{
+		Mode:       "build",
+		Iterations: 3,
+		Tests: []state.TestResult{
+			{Iteration: 1, Counts: testresult.Counts{Passed: 8, Failed: 1}},
+			{Iteration: 3, Counts: testresult.Counts{Passed: 6, Failed: 3}},
+		},
+	}
+
+	var buf bytes.Buffer
+	Render(&buf, "my-api", "main", nil, nil, lastRun, pricing.Currency{}, testTheme)
+	assert.Contains(t, buf.String(), "6 passed, 3 failed")
+	assert.Contains(t, buf.String(), "▲ failures 1 → 3")
+
+	lastRun.Tests[1].Failed = 0
+	buf.Reset()
+	Render(&buf, "my-api", "main", nil, nil, lastRun, pricing.Currency{}, testTheme)
+	assert.Contains(t, buf.String(), "6 passed, 0 failed")
+	assert.NotContains(t, buf.String(), "▲")
+}
+
 func TestRenderEmpty(t *testing.T) {
 	var buf bytes.Buffer
-	Render(&buf, "my-api", "main", nil, nil, nil, testTheme)
+	Render(&buf, "my-api", "main", nil, nil, nil, pricing.Currency{}, testTheme)
 	out := buf.String()
 
 	assert.Contains(t, out, "my-api")
 	assert.NotContains(t, out, "Tasks")
 	assert.NotContains(t, out, "Last run")
 }
+
+func TestRenderHistory(t *testing.T) {
+	runs := []state.RunRecord{
+		{
+			Mode:       "plan",
+			StartedAt:  time.Date(2026, 2, 10, 9, 0, 0, 0, time.UTC),
+			Iterations: 2,
+			Status:     state.StatusCompleted,
+			TotalCost:  0.5,
+		},
+		{
+			Mode:         "build",
+			StartedAt:    time.Date(2026, 2, 11, 14, 30, 0, 0, time.UTC),
+			Iterations:   7,
+			Status:       state.StatusStaleAbort,
+			TotalCost:    3.25,
+			Tags:         []string{"nightly", "v2"},
+			Note:         "attempt with new prompt",
+			Profile:      "work",
+			Experiment:   "fast-sonnet",
+			ResumeOf:     1,
+			HooksSkipped: "hooks need a database",
+		},
+	}
+
+	var buf bytes.Buffer
+	RenderHistory(&buf, runs, pricing.Currency{}, testTheme)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	assert.Contains(t, lines[0], "2026-02-10 09:00")
+	assert.NotContains(t, lines[0], "@")
+	assert.Contains(t, lines[1], "@work")
+	assert.Contains(t, lines[1], "exp:fast-sonnet")
+	assert.NotContains(t, lines[0], "exp:")
+	assert.Contains(t, lines[0], "$0.5000")
+	assert.NotContains(t, lines[0], "[")
+	assert.Contains(t, lines[1], "stale_abort")
+	assert.Contains(t, lines[1], "[nightly, v2]")
+	assert.Contains(t, lines[1], "attempt with new prompt")
+	assert.Contains(t, lines[1], "↻#1")
+	assert.NotContains(t, lines[0], "↻")
+	assert.Contains(t, lines[1], "hooks skipped: hooks need a database")
+	assert.NotContains(t, lines[0], "hooks")
+}
+
+func TestRenderHistoryEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	RenderHistory(&buf, nil, pricing.Currency{}, testTheme)
+	assert.Contains(t, buf.String(), "No runs recorded")
+}
@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RequiredTools are the tools the plan and build prompts can't work without.
+var RequiredTools = []string{"Bash", "Edit"}
+
+// Capabilities is what an agent session reported it can use as it started:
+// its model, CLI version, tools and MCP servers.
+type Capabilities struct {
+	Model      string
+	Version    string
+	Tools      []string
+	MCPServers []MCPServer
+}
+
+// CapabilitiesOf reads the capabilities from a system init event.
+func CapabilitiesOf(evt *Event) *Capabilities {
+	return &Capabilities{Model: evt.Model, Version: evt.Version, Tools: evt.Tools, MCPServers: evt.MCPServers}
+}
+
+// Missing returns the RequiredTools the session doesn't have.
+func (c *Capabilities) Missing() []string {
+	var missing []string
+	for _, tool := range RequiredTools {
+		if !slices.Contains(c.Tools, tool) {
+			missing = append(missing, tool)
+		}
+	}
+	return missing
+}
+
+// FailedMCP returns the MCP servers that didn't connect.
+func (c *Capabilities) FailedMCP() []string {
+	var failed []string
+	for _, s := range c.MCPServers {
+		if s.Status != "connected" {
+			failed = append(failed, s.Name)
+		}
+	}
+	return failed
+}
+
+// formatInit shows the session's capabilities in one line, and warns when a
+// required tool is missing or an MCP server failed to connect, which
+// usually means a misconfigured settings file or MCP config.
+func (f *Formatter) formatInit(c *Capabilities) error {
+	parts := make([]string, 0, 4)
+	if c.Version != "" {
+		parts = append(parts, "claude "+c.Version)
+	}
+	if c.Model != "" {
+		parts = append(parts, c.Model)
+	}
+	parts = append(parts, fmt.Sprintf("%d tools", len(c.Tools)))
+	if len(c.MCPServers) > 0 {
+		names := make([]string, 0, len(c.MCPServers))
+		for _, s := range c.MCPServers {
+			names = append(names, s.Name)
+		}
+		parts = append(parts, "MCP: "+strings.Join(names, ", "))
+	}
+	if _, err := fmt.Fprintln(f.w, f.theme.Muted.Render("  ◆ "+strings.Join(parts, " · "))); err != nil {
+		return fmt.Errorf("writing capabilities: %w", err)
+	}
+	if missing := c.Missing(); len(missing) > 0 {
+		msg := fmt.Sprintf("  ⚠ The agent has no %s tool; check --settings, allowed tools and permissions", strings.Join(missing, " or "))
+		if _, err := fmt.Fprintln(f.w, f.theme.Warning.Render(msg)); err != nil {
+			return fmt.Errorf("writing capabilities: %w", err)
+		}
+	}
+	if failed := c.FailedMCP(); len(failed) > 0 {
+		msg := fmt.Sprintf("  ⚠ MCP server %s didn't connect", strings.Join(failed, ", "))
+		if _, err := fmt.Fprintln(f.w, f.theme.Warning.Render(msg)); err != nil {
+			return fmt.Errorf("writing capabilities: %w", err)
+		}
+	}
+	return nil
+}
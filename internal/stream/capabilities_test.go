@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+func TestProcess_Capabilities(t *testing.T) {
+	f := openFixture(t, "testdata/full_iteration.jsonl")
+
+	var buf bytes.Buffer
+	stats, err := Process(f, &buf, ui.PlainTheme())
+	require.NoError(t, err)
+
+	c := stats.Capabilities
+	require.NotNil(t, c)
+	assert.Equal(t, "claude-opus-4-6", c.Model)
+	assert.Equal(t, "2.1.37", c.Version)
+	assert.Contains(t, c.Tools, "Bash")
+	assert.Empty(t, c.Missing())
+	assert.Contains(t, buf.String(), "◆ claude 2.1.37 · claude-opus-4-6 · 17 tools\n")
+	assert.NotContains(t, buf.String(), "⚠")
+}
+
+func TestFormatInit_Warnings(t *testing.T) {
+	var buf bytes.Buffer
+	evt := `{"type":"system","subtype":"init","model":"m","tools":["Read","Edit"],"mcp_servers":[{"name":"github","status":"connected"},{"name":"db","status":"failed"}]}`
+	stats, err := ProcessSinks(strings.NewReader(evt), NewFormatter(&buf, ui.PlainTheme()))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Bash"}, stats.Capabilities.Missing())
+	assert.Equal(t, []string{"db"}, stats.Capabilities.FailedMCP())
+	out := buf.String()
+	assert.Contains(t, out, "MCP: github, db")
+	assert.Contains(t, out, "no Bash tool")
+	assert.Contains(t, out, "MCP server db didn't connect")
+}
+
+func TestCumulativeStats_KeepsFirstCapabilities(t *testing.T) {
+	var c CumulativeStats
+	c.Update(&IterationStats{})
+	assert.Nil(t, c.Capabilities)
+
+	first := &Capabilities{Model: "a"}
+	c.Update(&IterationStats{Capabilities: first})
+	c.Update(&IterationStats{Capabilities: &Capabilities{Model: "b"}})
+	assert.Same(t, first, c.Capabilities)
+}
@@ -0,0 +1,208 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// FilterTimeout is how long a filter gets to answer one event before it is
+// stopped and the stream carries on without it.
+const FilterTimeout = 5 * time.Second
+
+// Annotation is a note a filter attaches to an event, shown below it.
+type Annotation struct {
+	Level string `json:"level,omitempty"` // info (default), warning or error
+	Text  string `json:"text"`
+}
+
+// Annotation levels.
+const (
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// Filter is a program that sees each event before it is shown. Every event
+// is written to its stdin as one JSON line, and it answers each with one
+// line on stdout:
+//
+//   - the event, changed or not, to pass it on;
+//   - null, to suppress it;
+//   - an array of events, to pass on all of them in its place.
+//
+// Events may carry ralph_annotations, e.g. [{"level":"warning","text":"..."}],
+// which are shown below the event.
+type Filter struct {
+	Command string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	replies chan []byte // lines from stdout; closed when it ends
+	timeout time.Duration
+	failed  bool
+}
+
+// StartFilter starts command with sh. Its stderr goes to stderr.
+func StartFilter(ctx context.Context, command string, stderr io.Writer) (*Filter, error) {
+	f := &Filter{Command: command, replies: make(chan []byte), timeout: FilterTimeout}
+	f.cmd = exec.CommandContext(ctx, "sh", "-c", command) //nolint:gosec // the command is the user's configured filter
+	f.cmd.Stderr = stderr
+	f.cmd.WaitDelay = time.Second
+	var err error
+	if f.stdin, err = f.cmd.StdinPipe(); err != nil {
+		return nil, fmt.Errorf("stream filter %q: %w", command, err)
+	}
+	stdout, err := f.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stream filter %q: %w", command, err)
+	}
+	if err := f.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("stream filter %q: %w", command, err)
+	}
+	go func() {
+		defer close(f.replies)
+		r := bufio.NewReader(stdout)
+		for {
+			line, err := r.ReadBytes('\n')
+			if len(line) > 0 {
+				f.replies <- line
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return f, nil
+}
+
+// Apply sends evt to the filter and returns the events it answers with.
+func (f *Filter) Apply(evt *Event) ([]*Event, error) {
+	line, err := json.Marshal(evt)
+	if err != nil {
+		return nil, fmt.Errorf("encoding event: %w", err)
+	}
+	// A filter that stops reading blocks the write once the pipe is full,
+	// so the write shares the answer's deadline. On expiry the caller
+	// kills the filter, which ends the write.
+	timer := time.NewTimer(f.timeout)
+	defer timer.Stop()
+	written := make(chan error, 1)
+	go func() {
+		_, err := f.stdin.Write(append(line, '\n'))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			return nil, fmt.Errorf("writing event: %w", err)
+		}
+	case <-timer.C:
+		return nil, fmt.Errorf("not reading its input within %s", f.timeout)
+	}
+	var reply []byte
+	select {
+	case r, ok := <-f.replies:
+		if !ok {
+			return nil, errors.New("exited")
+		}
+		reply = bytes.TrimSpace(r)
+	case <-timer.C:
+		return nil, fmt.Errorf("no answer within %s", f.timeout)
+	}
+
+	switch {
+	case bytes.Equal(reply, []byte("null")):
+		return []*Event{}, nil
+	case bytes.HasPrefix(reply, []byte("[")):
+		var events []*Event
+		if err := json.Unmarshal(reply, &events); err != nil {
+			return nil, fmt.Errorf("parsing answer: %w", err)
+		}
+		return events, nil
+	}
+	var out Event
+	if err := json.Unmarshal(reply, &out); err != nil {
+		return nil, fmt.Errorf("parsing answer: %w", err)
+	}
+	return []*Event{&out}, nil
+}
+
+// Close ends the filter's input and waits for it to exit.
+func (f *Filter) Close() error {
+	_ = f.stdin.Close() //nolint:errcheck // the filter may already have exited
+	// Drain answers nobody will read, so the reader can finish.
+	go func() {
+		for range f.replies { //nolint:revive // nothing to do with them
+		}
+	}()
+	return f.cmd.Wait() //nolint:wrapcheck // callers report which filter failed
+}
+
+// Filtered passes src's events through filters in order. A filter that
+// fails is reported to onError, stopped, and skipped from then on; the
+// event it failed on and any after it go on unchanged, while answers it
+// already gave are kept.
+func Filtered(src Source, filters []*Filter, onError func(f *Filter, err error)) Source {
+	if len(filters) == 0 {
+		return src
+	}
+	return &filterSource{src: src, filters: filters, onError: onError}
+}
+
+type filterSource struct {
+	src     Source
+	filters []*Filter
+	onError func(*Filter, error)
+	pending []*Event
+}
+
+func (s *filterSource) Next() (*Event, error) {
+	for len(s.pending) == 0 {
+		evt, err := s.src.Next()
+		if err != nil {
+			return nil, err //nolint:wrapcheck // pass-through source
+		}
+		s.pending = s.apply(evt)
+	}
+	evt := s.pending[0]
+	s.pending = s.pending[1:]
+	return evt, nil
+}
+
+func (s *filterSource) apply(evt *Event) []*Event {
+	events := []*Event{evt}
+	for _, f := range s.filters {
+		if f.failed {
+			continue
+		}
+		var out []*Event
+		for i, e := range events {
+			res, err := f.Apply(e)
+			if err != nil {
+				f.failed = true
+				_ = f.cmd.Process.Kill() //nolint:errcheck // it may already have exited
+				s.onError(f, err)
+				out = append(out, events[i:]...)
+				break
+			}
+			out = append(out, res...)
+		}
+		events = out
+	}
+	return events
+}
+
+// Oversized reports the source's skipped lines, so filtering doesn't hide
+// them from the stats.
+func (s *filterSource) Oversized() int {
+	if o, ok := s.src.(interface{ Oversized() int }); ok {
+		return o.Oversized()
+	}
+	return 0
+}
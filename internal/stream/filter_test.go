@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+const filterInput = `{"type":"assistant","message":{"content":[{"type":"text","text":"first"}]}}
+{"type":"assistant","message":{"content":[{"type":"text","text":"second"}]}}
+`
+
+func startFilter(t *testing.T, command string) *Filter {
+	t.Helper()
+	f, err := StartFilter(context.Background(), command, io.Discard)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+// texts runs filterInput through filters and returns the text shown.
+func texts(t *testing.T, filters []*Filter, onError func(*Filter, error)) string {
+	t.Helper()
+	var out bytes.Buffer
+	_, err := ProcessSource(Filtered(NewParser(strings.NewReader(filterInput)), filters, onError), NewFormatter(&out, ui.PlainTheme()))
+	require.NoError(t, err)
+	return out.String()
+}
+
+func noError(t *testing.T) func(*Filter, error) {
+	return func(f *Filter, err error) { t.Errorf("filter %q failed: %v", f.Command, err) }
+}
+
+func TestFiltered_PassSuppressAugment(t *testing.T) {
+	pass := startFilter(t, `while read -r l; do echo "$l"; done`)
+	assert.Equal(t, "first\nsecond\n", texts(t, []*Filter{pass}, noError(t)))
+
+	suppress := startFilter(t, `while read -r l; do case "$l" in *first*) echo null;; *) echo "$l";; esac; done`)
+	assert.Equal(t, "second\n", texts(t, []*Filter{suppress}, noError(t)))
+
+	augment := startFilter(t, `while read -r l; do printf '[%s,{"type":"assistant","message":{"content":[{"type":"text","text":"added"}]}}]\n' "$l"; done`)
+	assert.Equal(t, "first\nadded\nsecond\nadded\n", texts(t, []*Filter{augment}, noError(t)))
+}
+
+func TestFiltered_Annotations(t *testing.T) {
+	annotate := startFilter(t, `while read -r l; do printf '%s,"ralph_annotations":[{"level":"warning","text":"seen"}]}\n' "${l%\}}"; done`)
+	// Filters chain: the second sees the first's answers.
+	suppress := startFilter(t, `while read -r l; do case "$l" in *second*) echo null;; *) echo "$l";; esac; done`)
+	assert.Equal(t, "first\n  ▸ seen\n", texts(t, []*Filter{annotate, suppress}, noError(t)))
+}
+
+func TestFiltered_FailingFilterIsSkipped(t *testing.T) {
+	var failures []string
+	onError := func(f *Filter, err error) { failures = append(failures, f.Command+": "+err.Error()) }
+
+	crash := startFilter(t, "exit 1")
+	assert.Equal(t, "first\nsecond\n", texts(t, []*Filter{crash}, onError))
+	require.Len(t, failures, 1, "reported once, then skipped")
+	assert.Contains(t, failures[0], "exit 1: ")
+
+	failures = nil
+	garbage := startFilter(t, `while read -r l; do echo "not json"; done`)
+	assert.Equal(t, "first\nsecond\n", texts(t, []*Filter{garbage}, onError))
+	require.Len(t, failures, 1)
+	assert.Contains(t, failures[0], "parsing answer")
+
+	failures = nil
+	slow := startFilter(t, "sleep 5")
+	slow.timeout = 50 * time.Millisecond
+	assert.Equal(t, "first\nsecond\n", texts(t, []*Filter{slow}, onError))
+	require.Len(t, failures, 1)
+	assert.Contains(t, failures[0], "no answer within 50ms")
+}
+
+func TestFiltered_FailureKeepsEarlierAnswers(t *testing.T) {
+	var failures []string
+	onError := func(f *Filter, err error) { failures = append(failures, err.Error()) }
+
+	augment := startFilter(t, `while read -r l; do printf '[%s,{"type":"assistant","message":{"content":[{"type":"text","text":"added"}]}}]\n' "$l"; done`)
+	// Suppresses "first", then exits on "added": the suppression stands.
+	once := startFilter(t, `read -r l; echo null; exit 1`)
+	assert.Equal(t, "added\nsecond\nadded\n", texts(t, []*Filter{augment, once}, onError))
+	assert.Len(t, failures, 1)
+}
+
+func TestFilter_ApplyTimesOutOnBlockedWrite(t *testing.T) {
+	stuck := startFilter(t, "sleep 5")
+	stuck.timeout = 50 * time.Millisecond
+	big := &Event{Type: "assistant", Message: &Message{Content: []ContentBlock{{Type: "text", Text: strings.Repeat("x", 1<<20)}}}}
+
+	start := time.Now()
+	_, err := stuck.Apply(big)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not reading its input within 50ms")
+	assert.Less(t, time.Since(start), 2*time.Second)
+	_ = stuck.cmd.Process.Kill() //nolint:errcheck // ends the blocked write
+}
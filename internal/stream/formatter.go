@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/benwilkes9/ralph-cli/internal/guard"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
 
@@ -15,7 +19,11 @@ const (
 	eventAssistant = "assistant"
 	eventUser      = "user"
 	eventResult    = "result"
+	eventSystem    = "system"
+	subtypeInit    = "init"
 	contentToolUse = "tool_use"
+	contentResult  = "tool_result"
+	toolBash       = "Bash"
 )
 
 // FormatTokens formats a token count for display (e.g. "45.3k", "1.5M").
@@ -38,24 +46,80 @@ var paramPriority = []string{
 	"file_path", "description", "command", "pattern", "query", "url", "skill",
 }
 
+// DefaultParamWidth is how many runes of a tool call's parameter are shown
+// when the terminal width is unknown.
+const DefaultParamWidth = 60
+
+// minParamWidth keeps a parameter readable in a very narrow terminal.
+const minParamWidth = 20
+
+// paramIndent is the room left on a tool call line for its indent, bullet
+// and tool name.
+const paramIndent = 24
+
+// ParamWidthFor returns the parameter width that fits a terminal cols wide,
+// or DefaultParamWidth when cols is unknown (0 or less).
+func ParamWidthFor(cols int) int {
+	if cols <= 0 {
+		return DefaultParamWidth
+	}
+	return max(cols-paramIndent, minParamWidth)
+}
+
 // Formatter writes formatted stream events to an io.Writer.
 type Formatter struct {
-	w     io.Writer
-	theme *ui.Theme
+	w          io.Writer
+	theme      *ui.Theme
+	bash       map[string]bool // ids of Bash calls still awaiting a result
+	paramWidth int             // runes of a tool parameter to show; negative = all
 }
 
 // NewFormatter creates a Formatter that writes to w using the given theme.
 func NewFormatter(w io.Writer, theme *ui.Theme) *Formatter {
-	return &Formatter{w: w, theme: theme}
+	return &Formatter{w: w, theme: theme, bash: map[string]bool{}, paramWidth: DefaultParamWidth}
+}
+
+// SetParamWidth sets how many runes of a tool call's parameter are shown.
+// Zero keeps DefaultParamWidth; a negative width shows parameters in full.
+func (f *Formatter) SetParamWidth(n int) *Formatter {
+	if n != 0 {
+		f.paramWidth = n
+	}
+	return f
 }
 
-// Format writes a human-readable representation of an event.
+// Format writes a human-readable representation of an event, then any
+// annotations a filter attached to it.
 func (f *Formatter) Format(evt *Event) error {
+	if err := f.formatEvent(evt); err != nil {
+		return err
+	}
+	for _, a := range evt.Annotations {
+		style := f.theme.Info
+		switch a.Level {
+		case LevelWarning:
+			style = f.theme.Warning
+		case LevelError:
+			style = f.theme.Error
+		}
+		if _, err := fmt.Fprintf(f.w, "  %s\n", style.Render("▸ "+a.Text)); err != nil {
+			return fmt.Errorf("writing annotation: %w", err)
+		}
+	}
+	return nil
+}
+
+func (f *Formatter) formatEvent(evt *Event) error {
 	switch evt.Type {
 	case eventAssistant:
 		return f.formatAssistant(evt)
 	case eventUser:
 		return f.formatUser(evt)
+	case eventSystem:
+		if evt.Subtype == subtypeInit {
+			return f.formatInit(CapabilitiesOf(evt))
+		}
+		return nil
 	default:
 		return nil
 	}
@@ -84,7 +148,10 @@ func (f *Formatter) formatToolUse(block *ContentBlock) error {
 	if block.Name == "Task" {
 		return f.formatTaskToolUse(block)
 	}
-	param := extractParam(block.Input)
+	if block.Name == toolBash && block.ID != "" {
+		f.bash[block.ID] = true
+	}
+	param := extractParam(block.Input, f.paramWidth)
 	line := fmt.Sprintf("  %s", f.theme.Muted.Render(fmt.Sprintf("· %s %s", block.Name, param)))
 	if _, err := fmt.Fprintln(f.w, line); err != nil {
 		return fmt.Errorf("writing tool use: %w", err)
@@ -130,6 +197,13 @@ func (f *Formatter) formatTaskToolUse(block *ContentBlock) error {
 }
 
 func (f *Formatter) formatUser(evt *Event) error {
+	if err := f.formatBlocked(evt); err != nil {
+		return err
+	}
+	if err := f.formatBashFailure(evt); err != nil {
+		return err
+	}
+
 	tr := evt.ToolUseResult
 	if tr == nil || tr.TotalTokens == 0 {
 		return nil
@@ -157,8 +231,139 @@ func (f *Formatter) formatUser(evt *Event) error {
 	return nil
 }
 
-// extractParam extracts the most relevant parameter value from tool input JSON.
-func extractParam(raw json.RawMessage) string {
+// formatBlocked surfaces tool calls rejected by the ralph guardrail hook.
+func (f *Formatter) formatBlocked(evt *Event) error {
+	if evt.Message == nil {
+		return nil
+	}
+	for _, block := range evt.Message.Content {
+		if block.Type != contentResult || !block.IsError {
+			continue
+		}
+		text := toolResultText(block.Content)
+		_, reason, ok := strings.Cut(text, guard.BlockedPrefix)
+		if !ok {
+			continue
+		}
+		reason = strings.TrimSpace(strings.TrimPrefix(reason, ":"))
+		line := "    " + f.theme.Error.Render("⛔ blocked") + " " + f.theme.Muted.Render(truncate(reason, 80))
+		if _, err := fmt.Fprintln(f.w, line); err != nil {
+			return fmt.Errorf("writing blocked tool call: %w", err)
+		}
+	}
+	return nil
+}
+
+// exitCodeText matches the exit status claude prefixes a failed Bash result
+// with, e.g. "Exit code 2".
+var exitCodeText = regexp.MustCompile(`Exit code (\d+)`)
+
+// formatBashFailure marks a failed Bash command with its exit code and how
+// long it ran, so failing steps stand out from the tool calls around them.
+// Results blocked by the guardrail are left to formatBlocked.
+func (f *Formatter) formatBashFailure(evt *Event) error {
+	if evt.Message == nil {
+		return nil
+	}
+	tr := evt.ToolUseResult
+	if tr == nil {
+		tr = &ToolUseResult{}
+	}
+	for _, block := range evt.Message.Content {
+		if block.Type != contentResult || !f.bash[block.ToolUseID] {
+			continue
+		}
+		delete(f.bash, block.ToolUseID)
+
+		text := toolResultText(block.Content)
+		if strings.Contains(text, guard.BlockedPrefix) {
+			continue
+		}
+		hook := hookFailureStatus(text + "\n" + tr.Error)
+		code := tr.ExitCode
+		if code == 0 {
+			if m := exitCodeText.FindStringSubmatch(text + "\n" + tr.Error); m != nil {
+				code, _ = strconv.Atoi(m[1]) //nolint:errcheck // \d+ always parses
+			}
+		}
+		var status string
+		switch {
+		case tr.Interrupted:
+			status = "interrupted"
+		case hook != "":
+			status = hook
+		case code != 0:
+			status = fmt.Sprintf("exit %d", code)
+		case block.IsError:
+			status = "failed"
+		default:
+			continue
+		}
+		line := "    " + f.theme.Error.Render("✗ "+status)
+		if tr.DurationMs > 0 {
+			line += " " + f.theme.Muted.Render(fmt.Sprintf("after %.1fs", float64(tr.DurationMs)/1000))
+		}
+		if _, err := fmt.Fprintln(f.w, line); err != nil {
+			return fmt.Errorf("writing bash failure: %w", err)
+		}
+	}
+	return nil
+}
+
+// hookFailure matches what the commit hook frameworks print when a hook
+// rejects a commit: pre-commit's "- hook id: ruff" under each failed hook,
+// husky's "husky - pre-commit script failed (code 1)", and the shim
+// pre-commit installs in .git/hooks when pre-commit itself is missing.
+var hookFailure = regexp.MustCompile("(?m)^- hook id: (\\S+)|husky - (\\S+) (?:script |hook )?failed|(`pre-commit` not found)")
+
+// hookFailureStatus describes a commit a hook rejected, naming the hooks, or
+// returns "" when the output shows no hook failure.
+func hookFailureStatus(text string) string {
+	var hooks []string
+	for _, m := range hookFailure.FindAllStringSubmatch(text, -1) {
+		hook := m[1]
+		switch {
+		case m[2] != "":
+			hook = "husky " + m[2]
+		case m[3] != "":
+			hook = "pre-commit is not installed"
+		}
+		if !slices.Contains(hooks, hook) {
+			hooks = append(hooks, hook)
+		}
+	}
+	if len(hooks) == 0 {
+		return ""
+	}
+	return "commit hook failed: " + strings.Join(hooks, ", ")
+}
+
+// toolResultText returns the text of a tool_result content field, which is
+// either a plain string or an array of text blocks.
+func toolResultText(raw json.RawMessage) string {
+	if s := jsonString(raw); s != "" {
+		return s
+	}
+	var blocks []ContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		parts = append(parts, b.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ToolParam returns the tool input parameter the formatter shows for a call,
+// such as its file path or command, truncated for display.
+func ToolParam(input json.RawMessage) string {
+	return extractParam(input, DefaultParamWidth)
+}
+
+// extractParam extracts the most relevant parameter value from tool input
+// JSON, truncated to limit runes (negative = no limit).
+func extractParam(raw json.RawMessage, limit int) string {
 	if len(raw) == 0 {
 		return ""
 	}
@@ -171,7 +376,7 @@ func extractParam(raw json.RawMessage) string {
 		if val, ok := input[key]; ok {
 			s := jsonStringRaw(val)
 			if s != "" {
-				return truncate(s, 60)
+				return truncate(s, limit)
 			}
 		}
 	}
@@ -182,7 +387,7 @@ func extractParam(raw json.RawMessage) string {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
-	return truncate(strings.Join(keys, ", "), 60)
+	return truncate(strings.Join(keys, ", "), limit)
 }
 
 // jsonString unmarshals a JSON string value. Returns "" on any error.
@@ -209,7 +414,7 @@ func jsonStringRaw(raw json.RawMessage) string {
 
 func truncate(s string, limit int) string {
 	runes := []rune(s)
-	if len(runes) > limit {
+	if limit >= 0 && len(runes) > limit {
 		return string(runes[:limit]) + "…"
 	}
 	return s
@@ -233,7 +233,7 @@ func TestExtractParamPriority(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := extractParam(json.RawMessage(tt.input))
+			got := extractParam(json.RawMessage(tt.input), DefaultParamWidth)
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -242,10 +242,37 @@ func TestExtractParamPriority(t *testing.T) {
 func TestExtractParamTruncation(t *testing.T) {
 	longPath := "/workspace/repo/src/very/deeply/nested/directory/structure/that/goes/on/and/on/file.go"
 	input := `{"file_path":"` + longPath + `"}`
-	got := extractParam(json.RawMessage(input))
+	got := extractParam(json.RawMessage(input), DefaultParamWidth)
 
 	assert.LessOrEqual(t, len([]rune(got)), 61, "expected truncation at 60 chars + ellipsis") // 60 + ellipsis
 	assert.True(t, strings.HasSuffix(got, "…"), "expected ellipsis at end, got %q", got)
+
+	assert.Equal(t, longPath, extractParam(json.RawMessage(input), -1), "negative width shows it in full")
+}
+
+func TestParamWidthFor(t *testing.T) {
+	assert.Equal(t, DefaultParamWidth, ParamWidthFor(0))
+	assert.Equal(t, 176, ParamWidthFor(200))
+	assert.Equal(t, minParamWidth, ParamWidthFor(30))
+}
+
+func TestFormatter_SetParamWidth(t *testing.T) {
+	longCmd := strings.Repeat("x", 100)
+	evt := &Event{Type: "assistant", Message: &Message{Content: []ContentBlock{
+		{Type: "tool_use", Name: "Bash", Input: json.RawMessage(`{"command":"` + longCmd + `"}`)},
+	}}}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewFormatter(&buf, ui.PlainTheme()).SetParamWidth(10).Format(evt))
+	assert.Equal(t, "  · Bash xxxxxxxxxx…\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, NewFormatter(&buf, ui.PlainTheme()).SetParamWidth(-1).Format(evt))
+	assert.Contains(t, buf.String(), longCmd+"\n")
+
+	buf.Reset()
+	require.NoError(t, NewFormatter(&buf, ui.PlainTheme()).SetParamWidth(0).Format(evt))
+	assert.Contains(t, buf.String(), strings.Repeat("x", DefaultParamWidth)+"…")
 }
 
 func TestFormatTaskFallbackSubagentType(t *testing.T) {
@@ -268,3 +295,155 @@ func TestFormatTaskFallbackSubagentType(t *testing.T) {
 	require.NoError(t, f.Format(evt))
 	assert.Contains(t, buf.String(), "▶ agent")
 }
+
+func TestFormatBlockedToolCall(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"string content", `"PreToolUse:Bash hook error: Blocked by ralph guardrail: command matches \"git push -f\""`},
+		{"block content", `[{"type":"text","text":"Blocked by ralph guardrail: command matches \"git push -f\""}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, f := newTestFormatter()
+			evt := &Event{
+				Type: "user",
+				Message: &Message{
+					Role: "user",
+					Content: []ContentBlock{
+						{Type: "tool_result", IsError: true, Content: json.RawMessage(tt.content)},
+					},
+				},
+			}
+
+			require.NoError(t, f.Format(evt))
+			assert.Contains(t, buf.String(), "blocked")
+			assert.Contains(t, buf.String(), "git push -f")
+		})
+	}
+}
+
+func TestFormatToolErrorNotBlocked(t *testing.T) {
+	buf, f := newTestFormatter()
+	evt := &Event{
+		Type: "user",
+		Message: &Message{
+			Role: "user",
+			Content: []ContentBlock{
+				{Type: "tool_result", IsError: true, Content: json.RawMessage(`"exit status 1"`)},
+			},
+		},
+	}
+
+	require.NoError(t, f.Format(evt))
+	assert.Empty(t, buf.String())
+}
+
+// bashCall formats a Bash tool_use with the given id.
+func bashCall(t *testing.T, f *Formatter, id string) {
+	t.Helper()
+	require.NoError(t, f.Format(&Event{
+		Type: "assistant",
+		Message: &Message{Content: []ContentBlock{
+			{Type: "tool_use", ID: id, Name: "Bash", Input: json.RawMessage(`{"command":"go test ./..."}`)},
+		}},
+	}))
+}
+
+func TestFormatBashFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		result  *ToolUseResult
+		want    []string
+	}{
+		{
+			name:    "exit code and duration",
+			content: `"FAIL"`,
+			result:  &ToolUseResult{Stderr: "FAIL", ExitCode: 1, DurationMs: 2345},
+			want:    []string{"✗ exit 1", "after 2.3s"},
+		},
+		{
+			name:    "exit code from error text",
+			content: `"Exit code 2\nmake: *** [test] Error 2"`,
+			result:  &ToolUseResult{Error: "Error: Exit code 2"},
+			want:    []string{"✗ exit 2"},
+		},
+		{
+			name:    "interrupted",
+			content: `"Command timed out"`,
+			result:  &ToolUseResult{Interrupted: true, DurationMs: 120000},
+			want:    []string{"✗ interrupted", "after 120.0s"},
+		},
+		{
+			name:    "no detail",
+			content: `"boom"`,
+			want:    []string{"✗ failed"},
+		},
+		{
+			name:    "pre-commit hooks",
+			content: `"Exit code 1\nruff.....Failed\n- hook id: ruff\n- exit code: 1\nmypy.....Failed\n- hook id: mypy\n"`,
+			result:  &ToolUseResult{ExitCode: 1},
+			want:    []string{"✗ commit hook failed: ruff, mypy"},
+		},
+		{
+			name:    "husky hook",
+			content: `"Exit code 1\n✖ eslint found problems\nhusky - pre-commit script failed (code 1)"`,
+			want:    []string{"✗ commit hook failed: husky pre-commit"},
+		},
+		{
+			name:    "pre-commit missing",
+			content: "\"Exit code 1\\n`pre-commit` not found.  Did you forget to activate your virtualenv?\"",
+			want:    []string{"✗ commit hook failed: pre-commit is not installed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, f := newTestFormatter()
+			bashCall(t, f, "toolu_1")
+			buf.Reset()
+
+			require.NoError(t, f.Format(&Event{
+				Type: "user",
+				Message: &Message{Content: []ContentBlock{
+					{Type: "tool_result", ToolUseID: "toolu_1", IsError: true, Content: json.RawMessage(tt.content)},
+				}},
+				ToolUseResult: tt.result,
+			}))
+			for _, w := range tt.want {
+				assert.Contains(t, buf.String(), w)
+			}
+		})
+	}
+}
+
+func TestFormatBashSuccessIsQuiet(t *testing.T) {
+	buf, f := newTestFormatter()
+	bashCall(t, f, "toolu_1")
+	buf.Reset()
+
+	require.NoError(t, f.Format(&Event{
+		Type: "user",
+		Message: &Message{Content: []ContentBlock{
+			{Type: "tool_result", ToolUseID: "toolu_1", Content: json.RawMessage(`"ok"`)},
+		}},
+		ToolUseResult: &ToolUseResult{Stdout: "ok", DurationMs: 800},
+	}))
+	assert.Empty(t, buf.String())
+}
+
+func TestFormatBashBlockedNotDoubled(t *testing.T) {
+	buf, f := newTestFormatter()
+	bashCall(t, f, "toolu_1")
+	buf.Reset()
+
+	require.NoError(t, f.Format(&Event{
+		Type: "user",
+		Message: &Message{Content: []ContentBlock{
+			{Type: "tool_result", ToolUseID: "toolu_1", IsError: true, Content: json.RawMessage(`"Blocked by ralph guardrail: rm -rf"`)},
+		}},
+	}))
+	assert.Contains(t, buf.String(), "blocked")
+	assert.NotContains(t, buf.String(), "✗")
+}
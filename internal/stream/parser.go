@@ -2,7 +2,9 @@ package stream
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -13,6 +15,23 @@ type Event struct {
 	Message       *Message       `json:"message,omitempty"`
 	ToolUseResult *ToolUseResult `json:"tool_use_result,omitempty"`
 	TotalCostUSD  float64        `json:"total_cost_usd,omitempty"`
+	Usage         *Usage         `json:"usage,omitempty"` // result event: totals for the session
+
+	// System init event: what the session starts with.
+	Subtype    string      `json:"subtype,omitempty"`
+	Model      string      `json:"model,omitempty"`
+	Version    string      `json:"claude_code_version,omitempty"`
+	Tools      []string    `json:"tools,omitempty"`
+	MCPServers []MCPServer `json:"mcp_servers,omitempty"`
+
+	// Notes a stream filter attached, shown below the event.
+	Annotations []Annotation `json:"ralph_annotations,omitempty"`
+}
+
+// MCPServer is an MCP server listed in the init event.
+type MCPServer struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "connected", "failed", ...
 }
 
 // Message represents a Claude message with role, content, and usage.
@@ -30,12 +49,24 @@ type ContentBlock struct {
 	ID    string          `json:"id,omitempty"`
 	Name  string          `json:"name,omitempty"`
 	Input json.RawMessage `json:"input,omitempty"`
+	// tool_result fields
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
 }
 
 // ToolUseResult contains the result of a tool invocation.
 type ToolUseResult struct {
 	// Regular tool fields
 	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+	// Bash fields
+	Interrupted bool `json:"interrupted,omitempty"`
+	ExitCode    int  `json:"exitCode,omitempty"`   // 0 when the command succeeded or the code wasn't reported
+	DurationMs  int  `json:"durationMs,omitempty"` // wall time of the command
+	// Error is set when claude reports the result as a bare string, which
+	// it does for failed tool calls.
+	Error string `json:"-"`
 	// Subagent fields (discriminator: TotalTokens > 0)
 	Status            string `json:"status,omitempty"`
 	TotalTokens       int    `json:"totalTokens,omitempty"`
@@ -43,6 +74,16 @@ type ToolUseResult struct {
 	TotalToolUseCount int    `json:"totalToolUseCount,omitempty"`
 }
 
+// UnmarshalJSON accepts both the object form and the bare error string.
+func (r *ToolUseResult) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*r = ToolUseResult{}
+		return json.Unmarshal(data, &r.Error) //nolint:wrapcheck // surfaced by Parser as a malformed line
+	}
+	type plain ToolUseResult                 // drops the method to avoid recursion
+	return json.Unmarshal(data, (*plain)(r)) //nolint:wrapcheck // surfaced by Parser as a malformed line
+}
+
 // Usage tracks token consumption for a single Claude response.
 type Usage struct {
 	InputTokens              int `json:"input_tokens"`
@@ -51,22 +92,48 @@ type Usage struct {
 	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 }
 
+// DefaultMaxLineSize caps a single JSONL event. Larger events (e.g. a tool
+// dumping a huge file) are skipped and counted rather than buffered.
+const DefaultMaxLineSize = 16 * 1024 * 1024
+
 // Parser reads JSONL lines and emits Events.
 type Parser struct {
-	scanner *bufio.Scanner
+	r         *bufio.Reader
+	maxLine   int
+	oversized int
+	done      bool
 }
 
-// NewParser creates a Parser that reads from r.
+// NewParser creates a Parser that reads from r with DefaultMaxLineSize.
 func NewParser(r io.Reader) *Parser {
-	s := bufio.NewScanner(r)
-	s.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB line buffer
-	return &Parser{scanner: s}
+	return NewParserSize(r, DefaultMaxLineSize)
 }
 
-// Next reads the next event. Returns io.EOF when done.
+// NewParserSize creates a Parser that skips lines longer than maxLine bytes.
+func NewParserSize(r io.Reader, maxLine int) *Parser {
+	return &Parser{r: bufio.NewReaderSize(r, 64*1024), maxLine: maxLine}
+}
+
+// Oversized returns how many lines were skipped for exceeding the size cap.
+func (p *Parser) Oversized() int {
+	return p.oversized
+}
+
+// Next reads the next event. Malformed and oversized lines are skipped, so one
+// bad event never ends the stream. Returns io.EOF when done.
 func (p *Parser) Next() (*Event, error) {
-	for p.scanner.Scan() {
-		line := p.scanner.Bytes()
+	for !p.done {
+		line, tooLong, err := p.readLine()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("reading stream: %w", err)
+			}
+			p.done = true // process the unterminated last line first
+		}
+		if tooLong {
+			p.oversized++
+			continue
+		}
 		if len(line) == 0 {
 			continue
 		}
@@ -78,9 +145,24 @@ func (p *Parser) Next() (*Event, error) {
 		}
 		return &evt, nil
 	}
+	return nil, io.EOF
+}
 
-	if err := p.scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanning stream: %w", err)
+// readLine returns the next line without its newline. Once a line passes
+// maxLine the rest of it is discarded and tooLong is set.
+func (p *Parser) readLine() (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := p.r.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(chunk) > p.maxLine+1 { // +1 for the newline
+				tooLong, line = true, nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		return bytes.TrimRight(line, "\r\n"), tooLong, err //nolint:wrapcheck // wrapped by Next
 	}
-	return nil, io.EOF
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -187,6 +188,26 @@ func TestParseMalformedLines(t *testing.T) {
 	assert.Len(t, events, 2)
 }
 
+func TestParseOversizedLines(t *testing.T) {
+	big := `{"type":"user","tool_use_result":{"stdout":"` + strings.Repeat("x", 200) + `"}}`
+	input := `{"type":"system"}` + "\n" + big + "\n" + big + "\n" + `{"type":"result"}`
+
+	p := NewParserSize(strings.NewReader(input), 64)
+	var types []string
+	for {
+		evt, err := p.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		types = append(types, evt.Type)
+	}
+
+	// The unterminated last line is still parsed.
+	assert.Equal(t, []string{"system", "result"}, types)
+	assert.Equal(t, 2, p.Oversized())
+}
+
 func TestParseToolUseContent(t *testing.T) {
 	f := openFixture(t, "testdata/full_iteration.jsonl")
 
@@ -209,3 +230,25 @@ func TestParseToolUseContent(t *testing.T) {
 		}
 	}
 }
+
+func TestParserToolUseResultErrorString(t *testing.T) {
+	input := `{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","is_error":true,"content":"Exit code 1"}]},"tool_use_result":"Error: Exit code 1"}` + "\n"
+	p := NewParser(strings.NewReader(input))
+
+	evt, err := p.Next()
+	require.NoError(t, err)
+	require.NotNil(t, evt.ToolUseResult)
+	assert.Equal(t, "Error: Exit code 1", evt.ToolUseResult.Error)
+}
+
+func TestParserToolUseResultBashFields(t *testing.T) {
+	input := `{"type":"user","tool_use_result":{"stdout":"","stderr":"boom","interrupted":false,"exitCode":3,"durationMs":1500}}` + "\n"
+	p := NewParser(strings.NewReader(input))
+
+	evt, err := p.Next()
+	require.NoError(t, err)
+	require.NotNil(t, evt.ToolUseResult)
+	assert.Equal(t, "boom", evt.ToolUseResult.Stderr)
+	assert.Equal(t, 3, evt.ToolUseResult.ExitCode)
+	assert.Equal(t, 1500, evt.ToolUseResult.DurationMs)
+}
@@ -2,7 +2,9 @@ package stream
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"os"
 
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
@@ -10,12 +12,41 @@ import (
 // Process reads a JSONL stream, formats events to w, and returns iteration stats.
 // The per-iteration summary line is NOT rendered here — that's the caller's job.
 func Process(r io.Reader, w io.Writer, theme *ui.Theme) (*IterationStats, error) {
-	parser := NewParser(r)
-	formatter := NewFormatter(w, theme)
+	return ProcessSinks(r, NewFormatter(w, theme))
+}
+
+// ProcessSinks reads a JSONL stream, fans each event out to every sink in
+// order, and returns iteration stats. Only the first sink's error aborts
+// processing; see ProcessSource.
+func ProcessSinks(r io.Reader, sinks ...Sink) (*IterationStats, error) {
+	return ProcessSource(NewParser(r), sinks...)
+}
+
+// Source yields stream events until io.EOF. Parser reads Claude's
+// stream-json; other agents' output is translated into the same events.
+type Source interface {
+	Next() (*Event, error)
+}
+
+// sinkErrors is where auxiliary sink failures are reported.
+var sinkErrors io.Writer = os.Stderr
+
+// ProcessSource is ProcessSinks for events from src. When src also reports
+// skipped lines, as Parser does, they are counted in the stats.
+//
+// The first sink is the primary one, the terminal in the loop, and its error
+// ends processing. The rest are auxiliary (log tees, metrics, webhooks): one
+// that fails is reported on stderr and dropped for the rest of the stream,
+// while rendering and stats carry on.
+func ProcessSource(src Source, sinks ...Sink) (*IterationStats, error) {
 	stats := &IterationStats{}
+	sinks = append([]Sink(nil), sinks...) // failed auxiliary sinks are set to nil
+	if o, ok := src.(interface{ Oversized() int }); ok {
+		defer func() { stats.Oversized = o.Oversized() }()
+	}
 
 	for {
-		evt, err := parser.Next()
+		evt, err := src.Next()
 		if errors.Is(err, io.EOF) {
 			break
 		}
@@ -28,23 +59,43 @@ func Process(r io.Reader, w io.Writer, theme *ui.Theme) (*IterationStats, error)
 		case eventAssistant:
 			if evt.Message != nil {
 				stats.ObserveAssistant(evt.Message.Usage)
+				stats.ObserveModel(evt.Message.Model, evt.Message.Usage)
 				for _, block := range evt.Message.Content {
 					if block.Type == contentToolUse {
 						stats.ObserveToolUse()
+						stats.LastTool = &block
 					}
 				}
 			}
 		case eventUser:
-			if evt.ToolUseResult != nil && evt.ToolUseResult.TotalTokens > 0 {
-				stats.ObserveSubagent(evt.ToolUseResult.TotalTokens)
+			if r := evt.ToolUseResult; r != nil {
+				if r.TotalTokens > 0 {
+					stats.ObserveSubagent(r.TotalTokens)
+				} else if r.Stdout != "" || r.Stderr != "" {
+					stats.ObserveCommandOutput(r.Stdout, r.Stderr)
+				}
+			}
+		case eventSystem:
+			if evt.Subtype == subtypeInit && stats.Capabilities == nil {
+				stats.Capabilities = CapabilitiesOf(evt)
 			}
 		case eventResult:
 			stats.ObserveResult(evt.TotalCostUSD)
+			stats.ObserveUsage(evt.Usage)
 		}
 
-		// Format for display
-		if err := formatter.Format(evt); err != nil {
-			return stats, err
+		// Fan out to sinks
+		for i, sink := range sinks {
+			if sink == nil {
+				continue
+			}
+			if err := sink.Format(evt); err != nil {
+				if i == 0 {
+					return stats, err //nolint:wrapcheck // sinks wrap their own errors
+				}
+				sinks[i] = nil
+				fmt.Fprintf(sinkErrors, "ralph: output sink %T failed and is disabled for this iteration: %v\n", sink, err) //nolint:errcheck // best-effort report
+			}
 		}
 	}
 
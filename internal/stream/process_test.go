@@ -2,6 +2,10 @@ package stream
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
 	"strings"
 	"testing"
 
@@ -21,6 +25,9 @@ func TestProcessFullIteration(t *testing.T) {
 	assert.Greater(t, stats.PeakContext, 0)
 	assert.Greater(t, stats.Cost, 0.0)
 	assert.Greater(t, stats.ToolCalls, 0)
+	require.NotNil(t, stats.LastTool)
+	assert.Equal(t, "toolu_01Q19RSiNcP8pFQqdMpmpFSh", stats.LastTool.ID)
+	assert.NotEmpty(t, stats.LastTool.Input)
 	assert.NotEmpty(t, buf.String())
 }
 
@@ -74,3 +81,148 @@ func TestProcessStatsAccumulation(t *testing.T) {
 	assert.Equal(t, stats.PeakContext, cum.PeakContext)
 	assert.Equal(t, stats.Cost, cum.TotalCost)
 }
+
+func TestProcessModelTokens(t *testing.T) {
+	f := openFixture(t, "testdata/full_iteration.jsonl")
+
+	stats, err := Process(f, io.Discard, ui.DefaultTheme())
+	require.NoError(t, err)
+	require.Len(t, stats.ModelTokens, 1)
+	assert.Positive(t, stats.ModelTokens["claude-opus-4-6"])
+
+	stats.ObserveModel("", &Usage{InputTokens: 5})
+	stats.ObserveModel("claude-sonnet-4-5", &Usage{InputTokens: 5, CacheCreationInputTokens: 3, CacheReadInputTokens: 100, OutputTokens: 2})
+	assert.Equal(t, 10, stats.ModelTokens["claude-sonnet-4-5"], "cache reads are not counted")
+}
+
+func TestProcessCacheTokens(t *testing.T) {
+	f := openFixture(t, "testdata/full_iteration.jsonl")
+
+	stats, err := Process(f, io.Discard, ui.DefaultTheme())
+	require.NoError(t, err)
+
+	// Totals come from the result event, not the per-turn assistant usage.
+	assert.Equal(t, 6, stats.InputTokens)
+	assert.Equal(t, 16_622, stats.CacheWriteTokens)
+	assert.Equal(t, 137_129, stats.CacheReadTokens)
+	assert.Equal(t, 906, stats.OutputTokens)
+	assert.InDelta(t, 89.2, stats.CacheHitRate(), 0.1)
+
+	cum := &CumulativeStats{}
+	cum.Update(stats)
+	cum.Update(&IterationStats{InputTokens: 10, CacheWriteTokens: 1_000})
+	assert.Equal(t, 137_129, cum.CacheReadTokens)
+	assert.Equal(t, 17_638, cum.FreshTokens())
+	assert.InDelta(t, 88.6, cum.CacheHitRate(), 0.1)
+}
+
+func TestCacheHitRateNoUsage(t *testing.T) {
+	assert.InDelta(t, -1, (&IterationStats{}).CacheHitRate(), 0)
+	assert.InDelta(t, -1, (&CumulativeStats{}).CacheHitRate(), 0)
+}
+
+func TestProcessCountsOversizedEvents(t *testing.T) {
+	big := `{"type":"user","tool_use_result":{"stdout":"` + strings.Repeat("x", DefaultMaxLineSize) + `"}}`
+	input := big + "\n" + `{"type":"result","total_cost_usd":0.25}` + "\n"
+
+	stats, err := Process(strings.NewReader(input), io.Discard, ui.DefaultTheme())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, stats.Oversized)
+	assert.Equal(t, 0.25, stats.Cost, "events after the oversized one are still processed")
+}
+
+func TestProcessCapturesTestCounts(t *testing.T) {
+	input := strings.Join([]string{
+		`{"type":"user","tool_use_result":{"stdout":"==== 1 failed, 4 passed in 0.2s ====","stderr":""}}`,
+		`{"type":"user","tool_use_result":{"stdout":"On branch main","stderr":""}}`,
+		`{"type":"user","tool_use_result":{"stdout":"","stderr":"Tests:       5 passed, 5 total"}}`,
+	}, "\n")
+
+	stats, err := ProcessSinks(strings.NewReader(input))
+	require.NoError(t, err)
+	require.NotNil(t, stats.Tests, "jest writes its summary to stderr")
+	assert.Equal(t, 5, stats.Tests.Passed)
+	assert.Zero(t, stats.Tests.Failed, "the last test run wins")
+
+	stats, err = ProcessSinks(strings.NewReader(`{"type":"user","tool_use_result":{"stdout":"hello"}}`))
+	require.NoError(t, err)
+	assert.Nil(t, stats.Tests)
+}
+
+type recordingSink struct {
+	types []string
+}
+
+func (s *recordingSink) Format(evt *Event) error {
+	s.types = append(s.types, evt.Type)
+	return nil
+}
+
+type failingSink struct{}
+
+func (failingSink) Format(_ *Event) error {
+	return errors.New("sink closed")
+}
+
+func TestProcessSinksFansOut(t *testing.T) {
+	f := openFixture(t, "testdata/full_iteration.jsonl")
+
+	var term, plain, jsonl bytes.Buffer
+	rec := &recordingSink{}
+	stats, err := ProcessSinks(f,
+		NewFormatter(&term, ui.DefaultTheme()),
+		NewFormatter(&plain, ui.PlainTheme()),
+		NewJSONSink(&jsonl),
+		rec,
+	)
+	require.NoError(t, err)
+
+	assert.Greater(t, stats.Cost, 0.0)
+	assert.NotEmpty(t, term.String())
+	assert.NotEmpty(t, plain.String())
+	assert.NotContains(t, plain.String(), "\x1b[", "plain sink must not emit ANSI escapes")
+
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	assert.Len(t, lines, len(rec.types), "json sink writes one line per event")
+	var first Event
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, rec.types[0], first.Type)
+}
+
+func TestProcessSinksStopsOnSinkError(t *testing.T) {
+	f := openFixture(t, "testdata/full_iteration.jsonl")
+
+	rec := &recordingSink{}
+	_, err := ProcessSinks(f, failingSink{}, rec)
+	require.ErrorContains(t, err, "sink closed")
+	assert.Empty(t, rec.types, "later sinks are not fed after an error")
+}
+
+type flakySink struct {
+	calls int
+}
+
+func (s *flakySink) Format(_ *Event) error {
+	s.calls++
+	return errors.New("webhook unreachable")
+}
+
+func TestProcessSinksIsolatesAuxiliarySinkErrors(t *testing.T) {
+	var reported bytes.Buffer
+	sinkErrors = &reported
+	t.Cleanup(func() { sinkErrors = os.Stderr })
+	f := openFixture(t, "testdata/full_iteration.jsonl")
+
+	var term bytes.Buffer
+	flaky := &flakySink{}
+	rec := &recordingSink{}
+	stats, err := ProcessSinks(f, NewFormatter(&term, ui.DefaultTheme()), flaky, rec)
+	require.NoError(t, err)
+
+	assert.Greater(t, stats.Cost, 0.0, "stats are still collected")
+	assert.NotEmpty(t, term.String())
+	assert.Greater(t, len(rec.types), 1, "sinks after the failed one are still fed")
+	assert.Equal(t, 1, flaky.calls, "the failed sink is dropped")
+	assert.Contains(t, reported.String(), "webhook unreachable")
+}
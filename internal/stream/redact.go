@@ -0,0 +1,168 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+)
+
+// Redacted replaces each secret a Redactor masks.
+const Redacted = "[REDACTED]"
+
+// MinSecretLen is the shortest secret value masked. Shorter values would
+// garble ordinary output wherever they happened to appear.
+const MinSecretLen = 8
+
+// DefaultSecretPatterns match credentials by their well-known shapes, so a
+// key is masked even when it isn't one ralph loaded.
+var DefaultSecretPatterns = []string{
+	`sk-ant-[A-Za-z0-9_-]{20,}`,       // Anthropic API keys and OAuth tokens
+	`sk-(?:proj-)?[A-Za-z0-9_-]{32,}`, // OpenAI API keys
+	`gh[pousr]_[A-Za-z0-9]{36,}`,      // GitHub tokens
+	`github_pat_[A-Za-z0-9_]{22,}`,    // GitHub fine-grained tokens
+	`AIza[0-9A-Za-z_-]{35}`,           // Google API keys
+	`AKIA[0-9A-Z]{16}`,                // AWS access key IDs
+}
+
+// Redactor masks secrets: known values, such as the keys loaded from .env,
+// and anything matching a pattern. A nil Redactor masks nothing.
+type Redactor struct {
+	values   [][]byte
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor masks values (those shorter than MinSecretLen are ignored)
+// and matches of patterns, which are regexps.
+func NewRedactor(values, patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		if len(v) < MinSecretLen {
+			continue
+		}
+		// The stream is JSON, where a value with quotes, backslashes or
+		// control characters appears escaped.
+		quoted, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("encoding secret: %w", err)
+		}
+		for _, form := range []string{v, string(quoted[1 : len(quoted)-1])} {
+			if !seen[form] {
+				seen[form] = true
+				r.values = append(r.values, []byte(form))
+			}
+		}
+	}
+	// Longest first, so a secret containing another is masked whole.
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("secret pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redact returns b with every secret masked.
+func (r *Redactor) Redact(b []byte) []byte {
+	if r == nil {
+		return b
+	}
+	for _, v := range r.values {
+		b = bytes.ReplaceAll(b, v, []byte(Redacted))
+	}
+	for _, re := range r.patterns {
+		b = re.ReplaceAll(b, []byte(Redacted))
+	}
+	return b
+}
+
+// Reader masks secrets in src line by line, so a secret is caught however
+// the source splits its writes. Lines over DefaultMaxLineSize are masked in
+// pieces of that size.
+func (r *Redactor) Reader(src io.Reader) io.Reader {
+	if r == nil {
+		return src
+	}
+	return &redactReader{r: r, src: bufio.NewReaderSize(src, 64*1024)}
+}
+
+type redactReader struct {
+	r   *Redactor
+	src *bufio.Reader
+	out []byte // masked bytes not yet read
+	err error  // from src, returned once out is drained
+}
+
+func (rr *redactReader) Read(p []byte) (int, error) {
+	for len(rr.out) == 0 {
+		if rr.err != nil {
+			return 0, rr.err
+		}
+		var line []byte
+		for {
+			chunk, err := rr.src.ReadSlice('\n')
+			line = append(line, chunk...)
+			if errors.Is(err, bufio.ErrBufferFull) && len(line) < DefaultMaxLineSize {
+				continue
+			}
+			if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+				rr.err = err
+			}
+			break
+		}
+		rr.out = rr.r.Redact(line)
+	}
+	n := copy(p, rr.out)
+	rr.out = rr.out[n:]
+	return n, nil
+}
+
+// Writer masks secrets in what is written to w. Complete lines are passed
+// on as they arrive; call Flush to pass on the rest.
+func (r *Redactor) Writer(w io.Writer) *RedactWriter {
+	return &RedactWriter{r: r, w: w}
+}
+
+// RedactWriter is a Redactor's line-buffered writer.
+type RedactWriter struct {
+	r   *Redactor
+	w   io.Writer
+	buf []byte
+}
+
+func (rw *RedactWriter) Write(p []byte) (int, error) {
+	if rw.r == nil {
+		return rw.w.Write(p) //nolint:wrapcheck // pass-through writer
+	}
+	rw.buf = append(rw.buf, p...)
+	end := bytes.LastIndexByte(rw.buf, '\n') + 1
+	if len(rw.buf) > DefaultMaxLineSize {
+		end = len(rw.buf)
+	}
+	if end > 0 {
+		_, err := rw.w.Write(rw.r.Redact(rw.buf[:end]))
+		rw.buf = append(rw.buf[:0], rw.buf[end:]...)
+		if err != nil {
+			return len(p), err //nolint:wrapcheck // pass-through writer
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes out any unterminated last line.
+func (rw *RedactWriter) Flush() error {
+	if len(rw.buf) == 0 {
+		return nil
+	}
+	_, err := rw.w.Write(rw.r.Redact(rw.buf))
+	rw.buf = nil
+	return err //nolint:wrapcheck // pass-through writer
+}
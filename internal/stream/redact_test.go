@@ -0,0 +1,69 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeKey = "sk-ant-REDACTED"
+
+func TestRedactor_Redact(t *testing.T) {
+	r, err := NewRedactor([]string{"hunter2hunter2", "short", `pa"ss\word`}, DefaultSecretPatterns)
+	require.NoError(t, err)
+
+	got := string(r.Redact([]byte(`{"text":"key hunter2hunter2, ` + fakeKey + `, short, pa\"ss\\word"}`)))
+	assert.Equal(t, `{"text":"key [REDACTED], [REDACTED], short, [REDACTED]"}`, got,
+		"values under MinSecretLen are left alone; JSON-escaped values are caught")
+
+	pat := "ghp_" + strings.Repeat("a1", 18)
+	assert.Equal(t, "token=[REDACTED]", string(r.Redact([]byte("token="+pat))))
+}
+
+func TestRedactor_Nil(t *testing.T) {
+	var r *Redactor
+	assert.Equal(t, "secret", string(r.Redact([]byte("secret"))))
+	src := strings.NewReader("x")
+	assert.Same(t, io.Reader(src), r.Reader(src))
+
+	var buf bytes.Buffer
+	w := r.Writer(&buf)
+	_, err := w.Write([]byte("partial"))
+	require.NoError(t, err)
+	assert.Equal(t, "partial", buf.String())
+}
+
+func TestRedactor_ReaderCatchesSplitSecrets(t *testing.T) {
+	r, err := NewRedactor([]string{"hunter2hunter2"}, nil)
+	require.NoError(t, err)
+
+	src := iotest.OneByteReader(strings.NewReader("a hunter2hunter2\nb hunter2hunter2"))
+	out, err := io.ReadAll(r.Reader(src))
+	require.NoError(t, err)
+	assert.Equal(t, "a [REDACTED]\nb [REDACTED]", string(out))
+}
+
+func TestRedactor_Writer(t *testing.T) {
+	r, err := NewRedactor([]string{"hunter2hunter2"}, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := r.Writer(&buf)
+	for _, chunk := range []string{"one hunt", "er2hunter2\ntwo hun", "ter2hunter2"} {
+		_, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "one [REDACTED]\n", buf.String(), "the unterminated line waits for Flush")
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "one [REDACTED]\ntwo [REDACTED]", buf.String())
+}
+
+func TestNewRedactor_BadPattern(t *testing.T) {
+	_, err := NewRedactor(nil, []string{"("})
+	require.ErrorContains(t, err, `secret pattern "("`)
+}
@@ -0,0 +1,33 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Sink consumes parsed stream events. Each sink renders events independently,
+// so a single run feeds the terminal, the iteration's log and the dashboard
+// without re-reading logs.
+type Sink interface {
+	Format(evt *Event) error
+}
+
+// JSONSink writes each event as a single JSON line, e.g. to the iteration's
+// log.
+type JSONSink struct {
+	enc *json.Encoder
+}
+
+// NewJSONSink creates a JSONSink that writes JSONL to w.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+// Format encodes evt as a JSON line.
+func (s *JSONSink) Format(evt *Event) error {
+	if err := s.enc.Encode(evt); err != nil {
+		return fmt.Errorf("writing json event: %w", err)
+	}
+	return nil
+}
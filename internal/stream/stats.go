@@ -1,11 +1,33 @@
 package stream
 
+import (
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/testresult"
+)
+
 // IterationStats holds stats for a single loop iteration.
 type IterationStats struct {
-	PeakContext    int     // max(input + cache_creation + cache_read) across turns
-	Cost           float64 // from result event
-	SubagentTokens int     // sum of totalTokens from Task results
-	ToolCalls      int     // number of tool invocations
+	PeakContext    int                // max(input + cache_creation + cache_read) across turns
+	Cost           float64            // from result event
+	SubagentTokens int                // sum of totalTokens from Task results
+	ToolCalls      int                // number of tool invocations
+	Tests          *testresult.Counts // last test run summary seen in Bash output; nil if none
+	Oversized      int                // events skipped for exceeding the parser's line cap
+	Capabilities   *Capabilities      // from the session's init event; nil if it sent none
+	LastTool       *ContentBlock      // the last tool_use block, with its full input; nil if none
+	TimedOut       bool               // the agent was killed for running past the iteration timeout
+	FallbackPriced bool               // the agent reported no cost, so Cost came from PriceFallback's rates
+
+	// ModelTokens sums each assistant turn's billed tokens (input,
+	// cache_creation and output) by the model that answered it; nil if no
+	// turn named its model.
+	ModelTokens map[string]int
+
+	// Input token totals from the result event, split by how they were billed.
+	InputTokens      int // uncached input
+	CacheWriteTokens int // cache_creation: written to the prompt cache
+	CacheReadTokens  int // cache_read: served from the prompt cache
+	OutputTokens     int
 }
 
 // ObserveAssistant tracks peak context from an assistant event's usage.
@@ -19,6 +41,17 @@ func (s *IterationStats) ObserveAssistant(u *Usage) {
 	}
 }
 
+// ObserveModel attributes an assistant turn's billed tokens to its model.
+func (s *IterationStats) ObserveModel(model string, u *Usage) {
+	if model == "" || u == nil {
+		return
+	}
+	if s.ModelTokens == nil {
+		s.ModelTokens = map[string]int{}
+	}
+	s.ModelTokens[model] += u.InputTokens + u.CacheCreationInputTokens + u.OutputTokens
+}
+
 // ObserveToolUse increments the tool call counter.
 func (s *IterationStats) ObserveToolUse() {
 	s.ToolCalls++
@@ -29,17 +62,79 @@ func (s *IterationStats) ObserveSubagent(totalTokens int) {
 	s.SubagentTokens += totalTokens
 }
 
+// ObserveCommandOutput records the test counts in a Bash command's output, if
+// it contains a recognised test summary. Later runs replace earlier ones, so
+// the iteration reports the state tests were left in.
+func (s *IterationStats) ObserveCommandOutput(stdout, stderr string) {
+	if c, ok := testresult.Parse(stdout + "\n" + stderr); ok {
+		s.Tests = &c
+	}
+}
+
 // ObserveResult records the iteration cost.
 func (s *IterationStats) ObserveResult(costUSD float64) {
 	s.Cost = costUSD
 }
 
+// ObserveUsage records the session's token totals from the result event.
+func (s *IterationStats) ObserveUsage(u *Usage) {
+	if u == nil {
+		return
+	}
+	s.InputTokens = u.InputTokens
+	s.CacheWriteTokens = u.CacheCreationInputTokens
+	s.CacheReadTokens = u.CacheReadInputTokens
+	s.OutputTokens = u.OutputTokens
+}
+
+// PriceFallback fills in Cost from rates when the agent reported none, e.g.
+// behind a gateway that omits it: the result event's token totals at the
+// rates for model. The loop prices each iteration with it, and status its
+// rebuilt log index, so the two agree.
+func (s *IterationStats) PriceFallback(rates pricing.Table, model string) {
+	if s.Cost > 0 || rates == nil {
+		return
+	}
+	s.FallbackPriced = true
+	r, ok := rates.Lookup(model)
+	if !ok {
+		return
+	}
+	s.Cost = r.Cost(pricing.Usage{
+		Input:      s.InputTokens,
+		Output:     s.OutputTokens,
+		CacheWrite: s.CacheWriteTokens,
+		CacheRead:  s.CacheReadTokens,
+	})
+}
+
+// CacheHitRate returns the percentage of input tokens read from the prompt
+// cache, or -1 when no usage was recorded.
+func (s *IterationStats) CacheHitRate() float64 {
+	return cacheHitRate(s.InputTokens, s.CacheWriteTokens, s.CacheReadTokens)
+}
+
+// cacheHitRate returns read as a percentage of all input, or -1 for none.
+func cacheHitRate(uncached, written, read int) float64 {
+	total := uncached + written + read
+	if total == 0 {
+		return -1
+	}
+	return float64(read) / float64(total) * 100
+}
+
 // CumulativeStats holds stats across all iterations.
 type CumulativeStats struct {
 	Iterations     int
 	PeakContext    int
 	SubagentTokens int
 	TotalCost      float64
+
+	InputTokens      int
+	CacheWriteTokens int
+	CacheReadTokens  int
+
+	Capabilities *Capabilities // the first session's, as the run's; nil until one is reported
 }
 
 // Update merges an iteration's stats into the cumulative totals.
@@ -50,4 +145,22 @@ func (c *CumulativeStats) Update(iter *IterationStats) {
 	}
 	c.SubagentTokens += iter.SubagentTokens
 	c.TotalCost += iter.Cost
+	c.InputTokens += iter.InputTokens
+	c.CacheWriteTokens += iter.CacheWriteTokens
+	c.CacheReadTokens += iter.CacheReadTokens
+	if c.Capabilities == nil {
+		c.Capabilities = iter.Capabilities
+	}
+}
+
+// FreshTokens returns the input tokens not served from the cache (uncached
+// plus cache writes).
+func (c *CumulativeStats) FreshTokens() int {
+	return c.InputTokens + c.CacheWriteTokens
+}
+
+// CacheHitRate returns the percentage of input tokens read from the prompt
+// cache across all iterations, or -1 when no usage was recorded.
+func (c *CumulativeStats) CacheHitRate() float64 {
+	return cacheHitRate(c.InputTokens, c.CacheWriteTokens, c.CacheReadTokens)
 }
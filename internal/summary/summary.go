@@ -1,43 +1,203 @@
 package summary
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	logfile "github.com/benwilkes9/ralph-cli/internal/log"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
 	"github.com/benwilkes9/ralph-cli/internal/stream"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
 
 const contextLimit = 200_000
 
-// PrintBox renders the final job summary box to w using Lip Gloss styled borders.
+// Row names for the summary.rows config, in their default order.
+const (
+	RowIterations     = "iterations"
+	RowWallTime       = "wall_time"
+	RowPeakContext    = "peak_context"
+	RowSubagentTokens = "subagent_tokens"
+	RowInputTokens    = "input_tokens"
+	RowCacheHitRate   = "cache_hit_rate"
+	RowTasks          = "tasks"
+	RowCommits        = "commits"
+	RowCost           = "cost"
+)
+
+// Rows lists every row PrintBox can show.
+var Rows = []string{
+	RowIterations, RowWallTime, RowPeakContext, RowSubagentTokens,
+	RowInputTokens, RowCacheHitRate, RowTasks, RowCommits, RowCost,
+}
+
+// DefaultRows is the box shown when summary.rows isn't set.
+var DefaultRows = []string{
+	RowIterations, RowWallTime, RowPeakContext, RowSubagentTokens,
+	RowInputTokens, RowCacheHitRate, RowCost,
+}
+
+// Report is what a finished run's summary describes.
+type Report struct {
+	Mode      string
+	Branch    string
+	Status    string // the run's final status, e.g. "completed"
+	StartedAt time.Time
+	WallTime  time.Duration
+	Stats     *stream.CumulativeStats
+
+	Commits        int // commits made on the primary repo
+	TasksCompleted int // plan tasks checked off during the run
+	TasksDone      int // plan tasks done when the run ended
+	TasksTotal     int
+}
+
+// PrintBox renders the final job summary box to w using Lip Gloss styled
+// borders, with the given rows in order (DefaultRows when empty). The cache
+// rows are left out when the agent reported no cache usage.
 //
 //nolint:errcheck // display-only writes; io.Writer errors are non-actionable here
-func PrintBox(w io.Writer, stats *stream.CumulativeStats, wallTime time.Duration, theme *ui.Theme) {
-	pct := float64(stats.PeakContext) / float64(contextLimit) * 100
-	peakCtx := fmt.Sprintf("%s / %s (%.0f%%)",
-		stream.FormatTokens(stats.PeakContext),
-		stream.FormatTokens(contextLimit),
-		pct)
-
-	rows := []string{
-		"           JOB SUMMARY",
+func PrintBox(w io.Writer, r *Report, rows []string, cur pricing.Currency, theme *ui.Theme) {
+	if len(rows) == 0 {
+		rows = DefaultRows
+	}
+	stats := r.Stats
+	row := func(label, value string) string {
+		return fmt.Sprintf("%-17s%-21s", i18n.T(label), value)
+	}
+	lines := []string{
+		"           " + i18n.T("JOB SUMMARY"),
 		strings.Repeat("─", 38),
-		fmt.Sprintf("Iterations       %-21d", stats.Iterations),
-		fmt.Sprintf("Wall time        %-21s", formatDuration(wallTime)),
-		fmt.Sprintf("Peak context     %-21s", peakCtx),
-		fmt.Sprintf("Subagent tokens  %-21s", stream.FormatTokens(stats.SubagentTokens)),
-		fmt.Sprintf("Total cost       %s", theme.Cost.Render(fmt.Sprintf("$%.4f", stats.TotalCost))),
+	}
+	for _, name := range rows {
+		switch name {
+		case RowIterations:
+			lines = append(lines, row("Iterations", fmt.Sprintf("%d", stats.Iterations)))
+		case RowWallTime:
+			lines = append(lines, row("Wall time", formatDuration(r.WallTime)))
+		case RowPeakContext:
+			pct := float64(stats.PeakContext) / float64(contextLimit) * 100
+			lines = append(lines, row("Peak context", fmt.Sprintf("%s / %s (%.0f%%)",
+				stream.FormatTokens(stats.PeakContext), stream.FormatTokens(contextLimit), pct)))
+		case RowSubagentTokens:
+			lines = append(lines, row("Subagent tokens", stream.FormatTokens(stats.SubagentTokens)))
+		case RowInputTokens:
+			if stats.CacheHitRate() >= 0 {
+				lines = append(lines, row("Input tokens", i18n.Tf("%s cached / %s fresh",
+					stream.FormatTokens(stats.CacheReadTokens), stream.FormatTokens(stats.FreshTokens()))))
+			}
+		case RowCacheHitRate:
+			if rate := stats.CacheHitRate(); rate >= 0 {
+				lines = append(lines, row("Cache hit rate", fmt.Sprintf("%.0f%%", rate)))
+			}
+		case RowTasks:
+			lines = append(lines, row("Tasks completed", i18n.Tf("+%d (%d/%d done)", r.TasksCompleted, r.TasksDone, r.TasksTotal)))
+		case RowCommits:
+			lines = append(lines, row("Commits", fmt.Sprintf("%d", r.Commits)))
+		case RowCost:
+			lines = append(lines, fmt.Sprintf("%-17s%s", i18n.T("Total cost"), theme.Cost.Render(cur.Format(stats.TotalCost, 4))))
+		}
 	}
 
-	content := strings.Join(rows, "\n")
+	content := strings.Join(lines, "\n")
 	fmt.Fprintln(w, theme.SummaryBox.Render(content))
 }
 
+// JSON is the machine-readable form of a Report, with every row.
+type JSON struct {
+	Mode            string    `json:"mode"`
+	Branch          string    `json:"branch"`
+	Status          string    `json:"status"`
+	StartedAt       time.Time `json:"started_at"`
+	WallTimeSeconds float64   `json:"wall_time_seconds"`
+	Iterations      int       `json:"iterations"`
+	PeakContext     int       `json:"peak_context"`
+	SubagentTokens  int       `json:"subagent_tokens"`
+	CacheReadTokens int       `json:"cache_read_tokens"`
+	FreshTokens     int       `json:"fresh_tokens"`
+	CacheHitRate    *float64  `json:"cache_hit_rate,omitempty"` // percent; absent without cache usage
+	TasksCompleted  int       `json:"tasks_completed"`
+	TasksDone       int       `json:"tasks_done"`
+	TasksTotal      int       `json:"tasks_total"`
+	Commits         int       `json:"commits"`
+	TotalCostUSD    float64   `json:"total_cost_usd"`
+}
+
+// JSONName is the file WriteJSON writes in the logs directory for a run
+// started at started, named like the run's first log.
+func JSONName(started time.Time) string {
+	return "summary-" + started.Format(logfile.TimeLayout) + ".json"
+}
+
+// JSON returns r in its machine-readable form.
+func (r *Report) JSON() *JSON {
+	stats := r.Stats
+	out := &JSON{
+		Mode:            r.Mode,
+		Branch:          r.Branch,
+		Status:          r.Status,
+		StartedAt:       r.StartedAt,
+		WallTimeSeconds: r.WallTime.Seconds(),
+		Iterations:      stats.Iterations,
+		PeakContext:     stats.PeakContext,
+		SubagentTokens:  stats.SubagentTokens,
+		CacheReadTokens: stats.CacheReadTokens,
+		FreshTokens:     stats.FreshTokens(),
+		TasksCompleted:  r.TasksCompleted,
+		TasksDone:       r.TasksDone,
+		TasksTotal:      r.TasksTotal,
+		Commits:         r.Commits,
+		TotalCostUSD:    stats.TotalCost,
+	}
+	if rate := stats.CacheHitRate(); rate >= 0 {
+		out.CacheHitRate = &rate
+	}
+	return out
+}
+
+// WriteJSON writes r as JSON to JSONName in logsDir and returns its path.
+// Every row is included, whatever the box shows.
+func WriteJSON(logsDir string, r *Report) (string, error) {
+	out := r.JSON()
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding summary: %w", err)
+	}
+	if err := os.MkdirAll(logsDir, 0o750); err != nil {
+		return "", fmt.Errorf("creating logs dir: %w", err)
+	}
+	path := filepath.Join(logsDir, JSONName(r.StartedAt))
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return "", fmt.Errorf("writing summary: %w", err)
+	}
+	return path, nil
+}
+
 func formatDuration(d time.Duration) string {
 	m := int(d.Minutes())
 	s := int(d.Seconds()) % 60
 	return fmt.Sprintf("%dm %ds", m, s)
 }
+
+// Markdown renders the job summary as a markdown table, for surfaces such
+// as a GitHub check run. outcome is the run's final status, e.g. "completed".
+func Markdown(stats *stream.CumulativeStats, wallTime time.Duration, outcome string, cur pricing.Currency) string {
+	var b strings.Builder
+	b.WriteString("| | |\n|---|---|\n")
+	row := func(k, v string) { fmt.Fprintf(&b, "| %s | %s |\n", i18n.T(k), v) }
+	row("Outcome", outcome)
+	row("Iterations", fmt.Sprintf("%d", stats.Iterations))
+	row("Wall time", formatDuration(wallTime))
+	row("Peak context", fmt.Sprintf("%s / %s", stream.FormatTokens(stats.PeakContext), stream.FormatTokens(contextLimit)))
+	if rate := stats.CacheHitRate(); rate >= 0 {
+		row("Cache hit rate", fmt.Sprintf("%.0f%%", rate))
+	}
+	row("Total cost", cur.Format(stats.TotalCost, 4))
+	return b.String()
+}
@@ -2,11 +2,18 @@ package summary
 
 import (
 	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/benwilkes9/ralph-cli/internal/i18n"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
 	"github.com/benwilkes9/ralph-cli/internal/stream"
 	"github.com/benwilkes9/ralph-cli/internal/ui"
 )
@@ -15,7 +22,7 @@ var testTheme = ui.DefaultTheme()
 
 func printBox(stats *stream.CumulativeStats, wallTime time.Duration) string {
 	var buf bytes.Buffer
-	PrintBox(&buf, stats, wallTime, testTheme)
+	PrintBox(&buf, &Report{Stats: stats, WallTime: wallTime}, nil, pricing.Currency{}, testTheme)
 	return buf.String()
 }
 
@@ -31,6 +38,16 @@ func TestPrintBox_Header(t *testing.T) {
 	assert.Contains(t, out, "╯")
 }
 
+func TestPrintBox_Spanish(t *testing.T) {
+	i18n.Set(i18n.Spanish)
+	t.Cleanup(func() { i18n.Set(i18n.English) })
+
+	out := printBox(&stream.CumulativeStats{Iterations: 7}, 0)
+	assert.Contains(t, out, "RESUMEN DEL TRABAJO")
+	assert.Contains(t, out, "Iteraciones      7")
+	assert.NotContains(t, out, "Wall time")
+}
+
 func TestPrintBox_Iterations(t *testing.T) {
 	stats := &stream.CumulativeStats{Iterations: 7}
 	out := printBox(stats, 0)
@@ -76,3 +93,81 @@ func TestPrintBox_ZeroStats(t *testing.T) {
 		printBox(&stream.CumulativeStats{}, 0)
 	})
 }
+
+func TestPrintBox_CacheHitRate(t *testing.T) {
+	out := printBox(&stream.CumulativeStats{}, 0)
+	assert.NotContains(t, out, "Cache hit rate")
+
+	stats := &stream.CumulativeStats{InputTokens: 500, CacheWriteTokens: 19_500, CacheReadTokens: 180_000}
+	out = printBox(stats, 0)
+	assert.Contains(t, out, "180.0k cached / 20.0k fresh")
+	assert.Contains(t, out, "Cache hit rate   90%")
+}
+
+func TestPrintBox_Rows(t *testing.T) {
+	r := &Report{
+		Stats:          &stream.CumulativeStats{Iterations: 4, TotalCost: 2},
+		Commits:        6,
+		TasksCompleted: 3,
+		TasksDone:      7,
+		TasksTotal:     10,
+	}
+	var buf bytes.Buffer
+	PrintBox(&buf, r, []string{RowCommits, RowTasks, RowCost}, pricing.Currency{}, testTheme)
+	out := buf.String()
+
+	assert.Contains(t, out, "Commits          6")
+	assert.Contains(t, out, "Tasks completed  +3 (7/10 done)")
+	assert.Contains(t, out, "$2.0000")
+	assert.NotContains(t, out, "Iterations")
+	assert.NotContains(t, out, "Wall time")
+	assert.Less(t, strings.Index(out, "Commits"), strings.Index(out, "Tasks completed"))
+}
+
+func TestPrintBox_DefaultRows(t *testing.T) {
+	out := printBox(&stream.CumulativeStats{}, 0)
+
+	assert.Contains(t, out, "Iterations")
+	assert.Contains(t, out, "Subagent tokens")
+	assert.NotContains(t, out, "Commits")
+	assert.NotContains(t, out, "Tasks completed")
+}
+
+func TestWriteJSON(t *testing.T) {
+	dir := t.TempDir()
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := &Report{
+		Mode: "build", Branch: "feat", Status: "completed",
+		StartedAt: started, WallTime: 90 * time.Second,
+		Stats:   &stream.CumulativeStats{Iterations: 2, TotalCost: 0.5},
+		Commits: 3, TasksCompleted: 1, TasksDone: 2, TasksTotal: 5,
+	}
+
+	path, err := WriteJSON(dir, r)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "summary-20260301-120000.json"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "completed", got["status"])
+	assert.InDelta(t, 90, got["wall_time_seconds"], 0)
+	assert.InDelta(t, 2, got["iterations"], 0)
+	assert.InDelta(t, 3, got["commits"], 0)
+	assert.InDelta(t, 1, got["tasks_completed"], 0)
+	assert.InDelta(t, 0.5, got["total_cost_usd"], 0)
+	assert.NotContains(t, got, "cache_hit_rate", "no cache usage reported")
+}
+
+func TestMarkdown(t *testing.T) {
+	stats := &stream.CumulativeStats{Iterations: 3, TotalCost: 1.5, PeakContext: 50_000}
+	out := Markdown(stats, 125*time.Second, "completed", pricing.Currency{})
+
+	assert.Contains(t, out, "|---|---|")
+	assert.Contains(t, out, "| Outcome | completed |")
+	assert.Contains(t, out, "| Iterations | 3 |")
+	assert.Contains(t, out, "| Wall time | 2m 5s |")
+	assert.Contains(t, out, "| Total cost | $1.5000 |")
+	assert.NotContains(t, out, "Cache hit rate")
+}
@@ -0,0 +1,89 @@
+package swarm
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/git"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/status"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+// Summary is a member's progress, read from its worktree.
+type Summary struct {
+	Member
+	Done, Total int             // plan tasks
+	Runs        int             // loop runs recorded in the worktree's state.json
+	Cost        float64         // total cost of those runs
+	LastStatus  state.RunStatus // how the latest run ended; empty if none
+}
+
+// Summarize reads m's plan and state.json from its worktree. Plans are
+// found with cfg, the main checkout's config.
+func Summarize(m *Member, cfg *config.Config) (Summary, error) {
+	s := Summary{Member: *m}
+	tasks, err := status.ParsePlan(filepath.Join(m.Path, cfg.PlanPathForBranch(git.SanitizeBranch(m.Branch))))
+	if err != nil {
+		return s, fmt.Errorf("%s: %w", m.Branch, err)
+	}
+	s.Total = len(tasks)
+	for _, t := range tasks {
+		if t.Done {
+			s.Done++
+		}
+	}
+	st, err := state.Load(filepath.Join(m.Path, state.DefaultPath))
+	if err != nil {
+		return s, fmt.Errorf("%s: loading state: %w", m.Branch, err)
+	}
+	s.Runs = len(st.Runs)
+	for i := range st.Runs {
+		s.Cost += st.Runs[i].TotalCost
+	}
+	if last := st.LastRun(); last != nil {
+		s.LastStatus = last.Status
+	}
+	return s, nil
+}
+
+// Render writes one line per member, then the swarm's totals.
+//
+//nolint:errcheck // display output, best-effort writes
+func Render(w io.Writer, sums []Summary, cur pricing.Currency, theme *ui.Theme) {
+	if len(sums) == 0 {
+		fmt.Fprintln(w, theme.Muted.Render("No swarm yet; start one with ralph swarm plan or ralph swarm build"))
+		return
+	}
+	var done, total, running, failed int
+	var cost float64
+	for i := range sums {
+		s := &sums[i]
+		done += s.Done
+		total += s.Total
+		cost += s.Cost
+
+		var label string
+		switch {
+		case s.Started.IsZero():
+			label = theme.Muted.Render("not started")
+		case s.Finished.IsZero():
+			label = theme.Info.Render("running")
+			running++
+		case s.Error != "":
+			label = theme.Error.Render("failed: " + s.Error)
+			failed++
+		case s.LastStatus == "":
+			label = theme.Success.Render("finished")
+		default:
+			label = theme.Success.Render(string(s.LastStatus))
+		}
+		fmt.Fprintf(w, "%-30s  %-5s  %3d/%-3d tasks  %2d runs  %s  %s\n",
+			s.Branch, s.Mode, s.Done, s.Total, s.Runs, theme.Cost.Render(cur.Format(s.Cost, 4)), label)
+	}
+	fmt.Fprintf(w, "%s %d/%d tasks across %d branches, %d running, %d failed, %s\n",
+		theme.Muted.Render("Total:"), done, total, len(sums), running, failed, theme.Cost.Render(cur.Format(cost, 4)))
+}
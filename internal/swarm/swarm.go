@@ -0,0 +1,170 @@
+// Package swarm runs plan or build loops for several branches at once, each
+// in its own git worktree and container, and sums up their progress.
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/git"
+)
+
+// ManifestPath is where the swarm's members are recorded, relative to the
+// repo root.
+const ManifestPath = ".ralph/swarm.json"
+
+// LogDir holds each member's output, relative to the repo root.
+const LogDir = ".ralph/logs/swarm"
+
+// MemberEnv is set to "1" in the environment of each member's ralph
+// process, so its run knows other branches are running beside it.
+const MemberEnv = "RALPH_SWARM_MEMBER"
+
+// credentialFiles are untracked files a member's worktree needs from the
+// main checkout to launch its loop.
+var credentialFiles = []string{".env", ".ralph/profiles.yaml"}
+
+// Member is one branch of the swarm and the worktree its loop runs in.
+type Member struct {
+	Branch   string    `json:"branch"`
+	Path     string    `json:"path"` // worktree directory
+	Mode     string    `json:"mode"`
+	Log      string    `json:"log"` // the member's ralph output
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitzero"` // zero while running
+	Error    string    `json:"error,omitempty"`   // why its loop failed; empty on success
+}
+
+// Manifest is the swarm recorded in ManifestPath.
+type Manifest struct {
+	Members []Member `json:"members"`
+}
+
+// Load reads the manifest at path. A missing file is an empty swarm.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is ManifestPath under the repo root
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading swarm manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing swarm manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Save writes the manifest to path.
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding swarm manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing swarm manifest: %w", err)
+	}
+	return nil
+}
+
+// Put adds mb to the manifest, replacing an earlier member on its branch.
+func (m *Manifest) Put(mb *Member) {
+	for i := range m.Members {
+		if m.Members[i].Branch == mb.Branch {
+			m.Members[i] = *mb
+			return
+		}
+	}
+	m.Members = append(m.Members, *mb)
+}
+
+// Dir returns the directory worktrees are created in: "<repo>-swarm" beside
+// the repo, so they stay out of its own working tree.
+func Dir(repoRoot string) string {
+	return filepath.Join(filepath.Dir(repoRoot), filepath.Base(repoRoot)+"-swarm")
+}
+
+// Worktree checks branch out under Dir(repoRoot), creating the branch from
+// HEAD if needed, and copies in the credential files the loop reads. An
+// existing worktree for the branch is reused.
+func Worktree(ctx context.Context, repoRoot, branch string) (string, error) {
+	path := filepath.Join(Dir(repoRoot), git.SanitizeBranch(branch))
+	if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+		if err := git.AddWorktreeIn(ctx, repoRoot, path, branch); err != nil {
+			return "", fmt.Errorf("creating worktree for %s: %w", branch, err)
+		}
+	}
+	for _, f := range credentialFiles {
+		data, err := os.ReadFile(filepath.Join(repoRoot, f)) //nolint:gosec // fixed credential file names
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", f, err)
+		}
+		if err := os.WriteFile(filepath.Join(path, f), data, 0o600); err != nil {
+			return "", fmt.Errorf("copying %s into the worktree: %w", f, err)
+		}
+	}
+	return path, nil
+}
+
+// Launcher runs one member's loop to completion, writing its output to out.
+type Launcher func(ctx context.Context, m *Member, out io.Writer) error
+
+// Run launches members with at most parallel running at once (0 = all) and
+// waits for them; once ctx is done no more are started. Each member's output
+// goes to its Log file, and done is called, one member at a time, as each
+// starts and finishes so the caller can record progress.
+func Run(ctx context.Context, members []*Member, parallel int, clk clock.Clock, launch Launcher, done func(*Member)) {
+	if parallel <= 0 || parallel > len(members) {
+		parallel = len(members)
+	}
+	c := clock.Or(clk)
+	sem := make(chan struct{}, parallel)
+	var mu sync.Mutex // guards members' Started, Finished and Error, and calls to done
+	var wg sync.WaitGroup
+	for _, m := range members {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			break // members not yet started stay unstarted
+		}
+		mu.Lock()
+		m.Started = c.Now()
+		done(m)
+		mu.Unlock()
+		wg.Go(func() {
+			defer func() { <-sem }()
+			err := runMember(ctx, m, launch)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				m.Error = err.Error()
+			}
+			m.Finished = c.Now()
+			done(m)
+		})
+	}
+	wg.Wait()
+}
+
+func runMember(ctx context.Context, m *Member, launch Launcher) error {
+	if err := os.MkdirAll(filepath.Dir(m.Log), 0o750); err != nil {
+		return fmt.Errorf("creating log dir: %w", err)
+	}
+	out, err := os.Create(m.Log) //nolint:gosec // log path built from the sanitized branch
+	if err != nil {
+		return fmt.Errorf("creating log: %w", err)
+	}
+	defer out.Close() //nolint:errcheck // best-effort log
+	return launch(ctx, m, out)
+}
@@ -0,0 +1,150 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benwilkes9/ralph-cli/internal/clock"
+	"github.com/benwilkes9/ralph-cli/internal/config"
+	"github.com/benwilkes9/ralph-cli/internal/pricing"
+	"github.com/benwilkes9/ralph-cli/internal/state"
+	"github.com/benwilkes9/ralph-cli/internal/testutil"
+	"github.com/benwilkes9/ralph-cli/internal/ui"
+)
+
+func TestManifest_PutSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swarm.json")
+	m, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, m.Members)
+
+	m.Put(&Member{Branch: "a", Mode: "plan"})
+	m.Put(&Member{Branch: "b", Mode: "plan"})
+	m.Put(&Member{Branch: "a", Mode: "build"})
+	require.NoError(t, m.Save(path))
+
+	got, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, got.Members, 2)
+	assert.Equal(t, "build", got.Members[0].Mode)
+	assert.Equal(t, "b", got.Members[1].Branch)
+}
+
+func TestRun_LimitsParallelAndRecordsOutcome(t *testing.T) {
+	dir := t.TempDir()
+	members := make([]*Member, 0, 4)
+	for i := range 4 {
+		members = append(members, &Member{Branch: fmt.Sprintf("b%d", i), Log: filepath.Join(dir, "logs", fmt.Sprintf("b%d.log", i))})
+	}
+	var running, peak atomic.Int32
+	launch := func(_ context.Context, m *Member, out io.Writer) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprintln(out, "ran", m.Branch) //nolint:errcheck // test output
+		if m.Branch == "b2" {
+			return errors.New("loop failed")
+		}
+		return nil
+	}
+	var events int
+	clk := &clock.Fake{T: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Step: time.Minute}
+	Run(context.Background(), members, 2, clk, launch, func(*Member) { events++ })
+
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+	assert.Equal(t, 8, events, "one event as each member starts and one as it finishes")
+	for _, m := range members {
+		assert.False(t, m.Finished.IsZero(), m.Branch)
+		assert.True(t, m.Finished.After(m.Started), m.Branch)
+		out, err := os.ReadFile(m.Log)
+		require.NoError(t, err)
+		assert.Equal(t, "ran "+m.Branch+"\n", string(out))
+	}
+	assert.Equal(t, "loop failed", members[2].Error)
+	assert.Empty(t, members[0].Error)
+}
+
+func TestRun_StopsStartingWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	members := []*Member{{Branch: "a", Log: filepath.Join(dir, "a.log")}, {Branch: "b", Log: filepath.Join(dir, "b.log")}}
+	ctx, cancel := context.WithCancel(context.Background())
+	launch := func(context.Context, *Member, io.Writer) error {
+		cancel()
+		return nil
+	}
+	Run(ctx, members, 1, nil, launch, func(*Member) {})
+	assert.False(t, members[0].Finished.IsZero())
+	assert.True(t, members[1].Started.IsZero())
+}
+
+func TestWorktree(t *testing.T) {
+	_, clone := testutil.InitBareAndClone(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(clone, ".ralph"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(clone, ".ralph", "config.yaml"), []byte("project: test\n"), 0o600))
+	testutil.RunGit(t, clone, "add", ".ralph")
+	testutil.RunGit(t, clone, "commit", "-m", "scaffold")
+	require.NoError(t, os.WriteFile(filepath.Join(clone, ".env"), []byte("ANTHROPIC_API_KEY=x\n"), 0o600))
+
+	ctx := context.Background()
+	path, err := Worktree(ctx, clone, "feature/auth")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(Dir(clone), "feature-auth"), path)
+	assert.FileExists(t, filepath.Join(path, ".ralph", "config.yaml"))
+	env, err := os.ReadFile(filepath.Join(path, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "ANTHROPIC_API_KEY=x\n", string(env))
+
+	again, err := Worktree(ctx, clone, "feature/auth")
+	require.NoError(t, err, "an existing worktree is reused")
+	assert.Equal(t, path, again)
+}
+
+func TestSummarizeAndRender(t *testing.T) {
+	cfg := &config.Config{Phases: config.Phases{Plan: config.PhaseConfig{Output: ".ralph/plans/"}}}
+	wt := t.TempDir()
+	plan := filepath.Join(wt, ".ralph", "plans", "IMPLEMENTATION_PLAN_feature-a.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(plan), 0o750))
+	require.NoError(t, os.WriteFile(plan, []byte("### Task 1: One\n- [x] done\n### Task 2: Two\n- [ ] todo\n"), 0o600))
+	require.NoError(t, state.Save(filepath.Join(wt, state.DefaultPath), &state.State{Runs: []state.RunRecord{
+		{Mode: "plan", TotalCost: 0.5, Status: state.StatusConverged},
+		{Mode: "build", TotalCost: 1.25, Status: state.StatusCompleted},
+	}}))
+
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	a, err := Summarize(&Member{Branch: "feature/a", Path: wt, Mode: "build", Started: started, Finished: started.Add(time.Hour)}, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 1, a.Done)
+	assert.Equal(t, 2, a.Total)
+	assert.Equal(t, 2, a.Runs)
+	assert.InDelta(t, 1.75, a.Cost, 1e-9)
+	assert.Equal(t, state.StatusCompleted, a.LastStatus)
+
+	b, err := Summarize(&Member{Branch: "feature/b", Path: t.TempDir(), Mode: "build", Started: started}, cfg)
+	require.NoError(t, err, "a worktree with no plan or state yet")
+
+	var buf bytes.Buffer
+	Render(&buf, []Summary{a, b}, pricing.Currency{}, ui.PlainTheme())
+	out := buf.String()
+	assert.Contains(t, out, "feature/a")
+	assert.Contains(t, out, "1/2")
+	assert.Contains(t, out, "completed")
+	assert.Contains(t, out, "running")
+	assert.Contains(t, out, "Total: 1/2 tasks across 2 branches, 1 running, 0 failed, $1.7500")
+}
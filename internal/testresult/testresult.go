@@ -0,0 +1,111 @@
+// Package testresult extracts pass/fail/skip counts from the output of common
+// test runners, so the loop can track test health across iterations without
+// knowing which backpressure command produced it.
+package testresult
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Counts summarises one test run. Go output without -v is counted per
+// package rather than per test.
+type Counts struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// Total is the number of tests (or packages) the run reported.
+func (c Counts) Total() int {
+	return c.Passed + c.Failed + c.Skipped
+}
+
+// String renders the counts, e.g. "12 passed, 1 failed, 2 skipped".
+func (c Counts) String() string {
+	s := fmt.Sprintf("%d passed, %d failed", c.Passed, c.Failed)
+	if c.Skipped > 0 {
+		s += fmt.Sprintf(", %d skipped", c.Skipped)
+	}
+	return s
+}
+
+var (
+	// jest: "Tests:       1 failed, 2 skipped, 10 passed, 13 total"
+	jestSummary = regexp.MustCompile(`(?m)^Tests:\s+(.*\d+ total)\s*$`)
+	// pytest: "==== 3 passed, 1 failed in 0.12s ====" or "3 passed in 0.12s" with -q
+	pytestSummary = regexp.MustCompile(`(?m)^[= ]*(\d+ (?:passed|failed|skipped|errors?|xfailed|xpassed|deselected|warnings?)(?:, \d+ \w+)*) in [\d.]+s\b`)
+	countPart     = regexp.MustCompile(`(\d+) (\w+)`)
+
+	goTest       = regexp.MustCompile(`(?m)^--- (PASS|FAIL|SKIP): `)
+	goPkgOK      = regexp.MustCompile(`(?m)^ok[ \t]+\S+[ \t]`)
+	goPkgFail    = regexp.MustCompile(`(?m)^FAIL[ \t]+\S+[ \t]`)
+	goPkgNoTests = regexp.MustCompile(`(?m)^\?[ \t]+\S+[ \t]+\[no test files\]`)
+)
+
+// Parse looks for a jest, pytest, or go test summary in output. ok is false
+// when none is found. When output holds several jest or pytest summaries the
+// last one wins, since it reflects the final re-run.
+func Parse(output string) (c Counts, ok bool) {
+	if m := jestSummary.FindAllStringSubmatch(output, -1); m != nil {
+		return tally(m[len(m)-1][1]), true
+	}
+	if m := pytestSummary.FindAllStringSubmatch(output, -1); m != nil {
+		return tally(m[len(m)-1][1]), true
+	}
+	return parseGo(output)
+}
+
+// tally sums the "<n> <outcome>" parts of a jest or pytest summary.
+func tally(summary string) Counts {
+	var c Counts
+	for _, part := range countPart.FindAllStringSubmatch(summary, -1) {
+		n, err := strconv.Atoi(part[1])
+		if err != nil {
+			continue
+		}
+		switch strings.ToLower(part[2]) {
+		case "passed", "xpassed":
+			c.Passed += n
+		case "failed", "error", "errors":
+			c.Failed += n
+		case "skipped", "xfailed", "todo":
+			c.Skipped += n
+		}
+	}
+	return c
+}
+
+// parseGo counts top-level tests when go test ran with -v, and packages
+// otherwise (passing tests aren't listed without -v).
+func parseGo(output string) (Counts, bool) {
+	var c Counts
+	verbose := false
+	for _, m := range goTest.FindAllStringSubmatch(output, -1) {
+		switch m[1] {
+		case "PASS":
+			c.Passed++
+			verbose = true
+		case "SKIP":
+			c.Skipped++
+			verbose = true
+		case "FAIL":
+			c.Failed++
+		}
+	}
+	if verbose {
+		return c, true
+	}
+
+	pkgs := Counts{
+		Passed:  len(goPkgOK.FindAllString(output, -1)),
+		Failed:  len(goPkgFail.FindAllString(output, -1)),
+		Skipped: len(goPkgNoTests.FindAllString(output, -1)),
+	}
+	if pkgs.Total() == 0 {
+		return c, c.Failed > 0
+	}
+	return pkgs, true
+}
@@ -0,0 +1,71 @@
+package testresult
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   Counts
+		ok     bool
+	}{
+		{
+			name:   "go test packages",
+			output: "ok  \tgithub.com/x/a\t0.012s\n?   \tgithub.com/x/b\t[no test files]\n--- FAIL: TestC (0.00s)\n    c_test.go:9: boom\nFAIL\nFAIL\tgithub.com/x/c\t0.020s\nFAIL\n",
+			want:   Counts{Passed: 1, Failed: 1, Skipped: 1},
+			ok:     true,
+		},
+		{
+			name: "go test verbose counts top-level tests",
+			output: "=== RUN   TestA\n--- PASS: TestA (0.00s)\n=== RUN   TestB\n    --- PASS: TestB/sub (0.00s)\n--- FAIL: TestB (0.00s)\n" +
+				"--- SKIP: TestC (0.00s)\nFAIL\nFAIL\tgithub.com/x/a\t0.01s\n",
+			want: Counts{Passed: 1, Failed: 1, Skipped: 1},
+			ok:   true,
+		},
+		{
+			name:   "pytest",
+			output: "collected 6 items\n\ntests/test_a.py ..F.s.\n\n=========== 4 passed, 1 failed, 1 skipped, 2 warnings in 0.34s ===========\n",
+			want:   Counts{Passed: 4, Failed: 1, Skipped: 1},
+			ok:     true,
+		},
+		{
+			name:   "pytest quiet with errors",
+			output: "3 passed, 2 errors in 1.02s\n",
+			want:   Counts{Passed: 3, Failed: 2},
+			ok:     true,
+		},
+		{
+			name:   "pytest last summary wins",
+			output: "==== 1 failed, 2 passed in 0.1s ====\n...\n==== 3 passed in 0.1s ====\n",
+			want:   Counts{Passed: 3},
+			ok:     true,
+		},
+		{
+			name:   "jest",
+			output: "Test Suites: 1 failed, 3 passed, 4 total\nTests:       1 failed, 2 skipped, 10 passed, 13 total\nSnapshots:   0 total\nTime:        2.1 s\n",
+			want:   Counts{Passed: 10, Failed: 1, Skipped: 2},
+			ok:     true,
+		},
+		{
+			name:   "unrelated output",
+			output: "On branch main\nnothing to commit, working tree clean\n",
+			ok:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Parse(tt.output)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCountsString(t *testing.T) {
+	assert.Equal(t, "3 passed, 0 failed", Counts{Passed: 3}.String())
+	assert.Equal(t, "3 passed, 1 failed, 2 skipped", Counts{Passed: 3, Failed: 1, Skipped: 2}.String())
+}
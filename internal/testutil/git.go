@@ -13,7 +13,9 @@ import (
 )
 
 // Chdir changes the working directory to dir for the duration of the test,
-// restoring the original directory in t.Cleanup.
+// restoring the original directory in t.Cleanup. The working directory is
+// process-wide, so tests using it can't call t.Parallel; prefer passing
+// dirs explicitly, as git.At and vcs.OpenIn allow.
 func Chdir(t *testing.T, dir string) {
 	t.Helper()
 	orig, err := os.Getwd()
@@ -0,0 +1,143 @@
+// Package tokens counts prompt tokens before an iteration runs, using
+// Anthropic's count-tokens endpoint when an API key is available and a
+// character-based estimate otherwise.
+package tokens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// Counting modes accepted by the token_count config key.
+const (
+	ModeOff      = "off"      // don't count
+	ModeEstimate = "estimate" // offline estimate only
+	ModeAPI      = "api"      // count-tokens endpoint, falling back to the estimate
+)
+
+// ValidMode reports whether m is a recognised counting mode. Empty means off.
+func ValidMode(m string) bool {
+	return m == "" || m == ModeOff || m == ModeEstimate || m == ModeAPI
+}
+
+// Counter counts the tokens in a prompt for model. exact is false when the
+// count is an estimate.
+type Counter interface {
+	Count(ctx context.Context, prompt, model string) (n int, exact bool)
+}
+
+// charsPerToken is the rough ratio used for estimates; English prose and
+// code both land near it.
+const charsPerToken = 4
+
+// Estimate approximates the token count of text without calling the API.
+func Estimate(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// Estimator counts with Estimate.
+type Estimator struct{}
+
+// Count returns the estimate for prompt.
+func (Estimator) Count(_ context.Context, prompt, _ string) (int, bool) {
+	return Estimate(prompt), false
+}
+
+// DefaultBaseURL is the Anthropic API root.
+const DefaultBaseURL = "https://api.anthropic.com"
+
+// apiVersion is the anthropic-version header sent with requests.
+const apiVersion = "2023-06-01"
+
+// apiModels maps claude CLI model aliases to API model aliases.
+var apiModels = map[string]string{
+	"opus":   "claude-opus-4-1",
+	"sonnet": "claude-sonnet-4-5",
+	"haiku":  "claude-haiku-4-5",
+}
+
+// APICounter counts with the count-tokens endpoint. Any failure (network,
+// auth, unknown model) falls back to Estimate, so counting never blocks an
+// iteration.
+type APICounter struct {
+	APIKey  string
+	BaseURL string       // empty = DefaultBaseURL
+	Client  *http.Client // nil = a client with a short timeout
+}
+
+// Count returns the endpoint's count for prompt, or the estimate on failure.
+func (c *APICounter) Count(ctx context.Context, prompt, model string) (int, bool) {
+	n, err := c.count(ctx, prompt, model)
+	if err != nil {
+		return Estimate(prompt), false
+	}
+	return n, true
+}
+
+func (c *APICounter) count(ctx context.Context, prompt, model string) (int, error) {
+	if m, ok := apiModels[model]; ok {
+		model = m
+	}
+	body, err := json.Marshal(map[string]any{
+		"model":    model,
+		"messages": []map[string]string{{"role": "user", "content": prompt}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("encoding request: %w", err)
+	}
+
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/v1/messages/count_tokens", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", apiVersion)
+
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("counting tokens: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("counting tokens: %s", resp.Status)
+	}
+
+	var out struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return 0, fmt.Errorf("decoding count: %w", err)
+	}
+	return out.InputTokens, nil
+}
+
+// New returns the Counter for mode, or nil when counting is off. ModeAPI
+// without an API key degrades to the estimate.
+func New(mode, apiKey string) Counter {
+	switch mode {
+	case ModeEstimate:
+		return Estimator{}
+	case ModeAPI:
+		if apiKey == "" {
+			return Estimator{}
+		}
+		return &APICounter{APIKey: apiKey}
+	default:
+		return nil
+	}
+}
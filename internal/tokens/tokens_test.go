@@ -0,0 +1,65 @@
+package tokens
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	assert.Equal(t, 0, Estimate(""))
+	assert.Equal(t, 1, Estimate("abc"))
+	assert.Equal(t, 2, Estimate("abcde"))
+	assert.Equal(t, 1, Estimate("ééé")) // counts runes, not bytes
+}
+
+func TestAPICounter(t *testing.T) {
+	var got struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages/count_tokens", r.URL.Path)
+		assert.Equal(t, "sk-test", r.Header.Get("x-api-key"))
+		assert.Equal(t, apiVersion, r.Header.Get("anthropic-version"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_, _ = w.Write([]byte(`{"input_tokens": 4242}`))
+	}))
+	defer srv.Close()
+
+	c := &APICounter{APIKey: "sk-test", BaseURL: srv.URL}
+	n, exact := c.Count(context.Background(), "hello", "sonnet")
+	assert.Equal(t, 4242, n)
+	assert.True(t, exact)
+	assert.Equal(t, "claude-sonnet-4-5", got.Model)
+	require.Len(t, got.Messages, 1)
+	assert.Equal(t, "hello", got.Messages[0].Content)
+}
+
+func TestAPICounter_FallsBackToEstimate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := &APICounter{APIKey: "sk-test", BaseURL: srv.URL}
+	n, exact := c.Count(context.Background(), "abcdefgh", "opus")
+	assert.Equal(t, 2, n)
+	assert.False(t, exact)
+}
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New("", "sk-test"))
+	assert.Nil(t, New(ModeOff, "sk-test"))
+	assert.Equal(t, Estimator{}, New(ModeEstimate, "sk-test"))
+	assert.Equal(t, Estimator{}, New(ModeAPI, ""))
+	assert.IsType(t, &APICounter{}, New(ModeAPI, "sk-test"))
+}
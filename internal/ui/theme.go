@@ -94,6 +94,35 @@ func DefaultTheme() *Theme {
 	}
 }
 
+// PlainTheme returns a theme with no colors, weights, or borders. Rendering
+// with it yields the raw text, suitable for log files and non-TTY sinks.
+func PlainTheme() *Theme {
+	plain := lipgloss.NewStyle()
+	return &Theme{
+		Body:        plain,
+		Muted:       plain,
+		Success:     plain,
+		Error:       plain,
+		Warning:     plain,
+		Cost:        plain,
+		Info:        plain,
+		PlanMode:    plain,
+		BuildMode:   plain,
+		BannerStyle: plain,
+		Separator:   plain,
+		IterationPl: plain,
+		IterationBd: plain,
+		ErrorBox:    plain,
+		SummaryBox:  plain,
+		StatusBox:   plain,
+		NextSteps:   plain,
+		FileCreated: plain,
+		FileUpdated: plain,
+		FileSkipped: plain,
+		SubagentTag: plain,
+	}
+}
+
 // FormatError renders an error message inside a red-bordered box with an error icon.
 func (t *Theme) FormatError(msg string) string {
 	return t.ErrorBox.Render(t.Error.Render("✗ " + msg))
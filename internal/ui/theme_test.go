@@ -62,3 +62,11 @@ func TestHuhTheme(t *testing.T) {
 	assert.NotNil(t, ht.Focused)
 	assert.NotNil(t, ht.Blurred)
 }
+
+func TestPlainTheme(t *testing.T) {
+	theme := PlainTheme()
+
+	assert.Equal(t, "x", theme.Body.Render("x"))
+	assert.Equal(t, "x", theme.Error.Render("x"))
+	assert.Equal(t, "x", theme.SummaryBox.Render("x"), "plain boxes have no border")
+}
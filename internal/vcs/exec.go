@@ -0,0 +1,59 @@
+package vcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/benwilkes9/ralph-cli/internal/debuglog"
+	"github.com/benwilkes9/ralph-cli/internal/git"
+)
+
+// runFunc runs one backend command and returns its stdout.
+type runFunc func(ctx context.Context, args ...string) (string, error)
+
+// commandRunner runs bin in dir under the git timeouts carried by ctx;
+// network reports which subcommands talk to a remote. Prompts are disabled
+// so a missing credential fails fast instead of blocking the loop.
+func commandRunner(bin, dir string, network func(args []string) bool) runFunc {
+	return func(ctx context.Context, args ...string) (string, error) {
+		if len(args) == 0 {
+			return "", fmt.Errorf("%s: no subcommand specified", bin)
+		}
+		timeout := git.CommandTimeout(ctx, network(args))
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, bin, args...) //nolint:gosec // args are built by this package
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "HGPLAIN=1", "JJ_EDITOR=true", "HGEDITOR=true")
+		done := debuglog.Exec(ctx, bin, args...)
+		out, err := cmd.Output()
+		done(err)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "", fmt.Errorf("%s %s: timed out after %s: %w", bin, args[0], timeout, ctx.Err())
+			}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+				return "", fmt.Errorf("%s %s: %w\n%s", bin, args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return "", fmt.Errorf("%s %s: %w", bin, args[0], err)
+		}
+		return string(out), nil
+	}
+}
+
+// lines splits command output into its non-empty lines.
+func lines(out string) []string {
+	var result []string
+	for _, l := range strings.Split(out, "\n") {
+		if l = strings.TrimRight(l, "\r"); l != "" {
+			result = append(result, l)
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/benwilkes9/ralph-cli/internal/git"
+)
+
+// Git drives a git repository through the git package, running every
+// command at the repository root.
+type Git struct {
+	root string
+	repo *git.Repo
+}
+
+// newGit asks git for the root of the repository at dir rather than
+// trusting detection, so linked worktrees and submodules resolve the way
+// git sees them.
+func newGit(ctx context.Context, dir string) (*Git, error) {
+	root, err := git.At(dir).RepoRoot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("finding git root: %w", err)
+	}
+	return &Git{root: root, repo: git.At(root)}, nil
+}
+
+// Kind implements VCS.
+func (g *Git) Kind() string { return KindGit }
+
+// Root implements VCS.
+func (g *Git) Root() string { return g.root }
+
+// The methods below are thin adapters; the git package already wraps its
+// errors with the failing subcommand.
+
+// Branch implements VCS.
+func (g *Git) Branch(ctx context.Context) (string, error) {
+	return g.repo.Branch(ctx) //nolint:wrapcheck // thin adapter
+}
+
+// CreateBranch implements VCS.
+func (g *Git) CreateBranch(ctx context.Context, branch string) error {
+	return g.repo.CreateBranch(ctx, branch) //nolint:wrapcheck // thin adapter
+}
+
+// Head implements VCS.
+func (g *Git) Head(ctx context.Context) (string, error) {
+	return g.repo.Head(ctx) //nolint:wrapcheck // thin adapter
+}
+
+// RemoteURL implements VCS.
+func (g *Git) RemoteURL(ctx context.Context) (string, error) {
+	return g.repo.RemoteURL(ctx, "origin") //nolint:wrapcheck // thin adapter
+}
+
+// IsTracked implements VCS.
+func (g *Git) IsTracked(ctx context.Context, path string) (bool, error) {
+	return g.repo.IsTracked(ctx, path) //nolint:wrapcheck // thin adapter
+}
+
+// Add implements VCS.
+func (g *Git) Add(ctx context.Context, paths ...string) error {
+	return g.repo.Add(ctx, paths...) //nolint:wrapcheck // thin adapter
+}
+
+// HasStagedChanges implements VCS.
+func (g *Git) HasStagedChanges(ctx context.Context) (bool, error) {
+	return g.repo.HasStagedChanges(ctx) //nolint:wrapcheck // thin adapter
+}
+
+// Commit implements VCS.
+func (g *Git) Commit(ctx context.Context, message string) error {
+	return g.repo.Commit(ctx, message) //nolint:wrapcheck // thin adapter
+}
+
+// AmendCommitMessage implements VCS.
+func (g *Git) AmendCommitMessage(ctx context.Context, message string) error {
+	return g.repo.AmendCommitMessage(ctx, message) //nolint:wrapcheck // thin adapter
+}
+
+// LastCommitMessage implements VCS.
+func (g *Git) LastCommitMessage(ctx context.Context) (string, error) {
+	return g.repo.LastCommitMessage(ctx) //nolint:wrapcheck // thin adapter
+}
+
+// ResetHard implements VCS.
+func (g *Git) ResetHard(ctx context.Context, rev string) error {
+	return g.repo.ResetHard(ctx, rev) //nolint:wrapcheck // thin adapter
+}
+
+// DirtyFiles implements VCS.
+func (g *Git) DirtyFiles(ctx context.Context) ([]string, error) {
+	return g.repo.DirtyFiles(ctx) //nolint:wrapcheck // thin adapter
+}
+
+// CommitPaths implements VCS.
+func (g *Git) CommitPaths(ctx context.Context, message string, paths ...string) error {
+	return g.repo.CommitPaths(ctx, message, paths...) //nolint:wrapcheck // thin adapter
+}
+
+// Stash implements VCS.
+func (g *Git) Stash(ctx context.Context, message string, paths ...string) error {
+	return g.repo.Stash(ctx, message, paths...) //nolint:wrapcheck // thin adapter
+}
+
+// Unstash implements VCS.
+func (g *Git) Unstash(ctx context.Context) error {
+	return g.repo.StashPop(ctx) //nolint:wrapcheck // thin adapter
+}
+
+// BranchExistsOnRemote implements VCS.
+func (g *Git) BranchExistsOnRemote(ctx context.Context, branch string) (bool, error) {
+	return g.repo.BranchExistsOnRemote(ctx, branch) //nolint:wrapcheck // thin adapter
+}
+
+// Push implements VCS.
+func (g *Git) Push(ctx context.Context, branch string) error {
+	return g.repo.Push(ctx, branch) //nolint:wrapcheck // thin adapter
+}
+
+// PushSetUpstream implements VCS.
+func (g *Git) PushSetUpstream(ctx context.Context, branch string) error {
+	return g.repo.PushSetUpstream(ctx, branch) //nolint:wrapcheck // thin adapter
+}
+
+// UnpushedCount implements VCS.
+func (g *Git) UnpushedCount(ctx context.Context, branch string) (int, error) {
+	return g.repo.UnpushedCount(ctx, branch) //nolint:wrapcheck // thin adapter
+}
+
+// DiffStat implements VCS.
+func (g *Git) DiffStat(ctx context.Context, from, to string) (string, error) {
+	return g.repo.DiffStat(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+// Diff implements VCS.
+func (g *Git) Diff(ctx context.Context, from, to string) (string, error) {
+	return g.repo.Diff(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+// ChangedFiles implements VCS.
+func (g *Git) ChangedFiles(ctx context.Context, from, to string) ([]string, error) {
+	return g.repo.ChangedFiles(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
+
+// CommitSubjects implements VCS.
+func (g *Git) CommitSubjects(ctx context.Context, from, to string) ([]string, error) {
+	return g.repo.CommitSubjects(ctx, from, to) //nolint:wrapcheck // thin adapter
+}
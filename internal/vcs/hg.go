@@ -0,0 +1,250 @@
+package vcs
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// Mercurial drives an hg repository. A ralph branch is a bookmark, and
+// commits not yet pushed are the draft-phase ancestors of the working copy.
+// Mercurial has no staging area, so Commit records every change to tracked
+// files.
+type Mercurial struct {
+	root string
+	run  runFunc
+}
+
+// hgNetwork reports whether an hg command talks to a remote.
+func hgNetwork(args []string) bool {
+	switch args[0] {
+	case "push", "pull", "identify", "incoming", "outgoing":
+		return true
+	}
+	return false
+}
+
+// hgExitNothingToPush is hg push's exit status when the remote is up to date.
+const hgExitNothingToPush = 1
+
+// Kind implements VCS.
+func (h *Mercurial) Kind() string { return KindMercurial }
+
+// Root implements VCS.
+func (h *Mercurial) Root() string { return h.root }
+
+// Branch implements VCS: the active bookmark, else the named branch.
+func (h *Mercurial) Branch(ctx context.Context) (string, error) {
+	out, err := h.run(ctx, "log", "-r", ".", "-T", "{activebookmark}")
+	if err != nil {
+		return "", err
+	}
+	if b := strings.TrimSpace(out); b != "" {
+		return b, nil
+	}
+	out, err = h.run(ctx, "branch")
+	return strings.TrimSpace(out), err
+}
+
+// CreateBranch implements VCS: a new bookmark on the working copy's
+// parent, which becomes the active one.
+func (h *Mercurial) CreateBranch(ctx context.Context, branch string) error {
+	_, err := h.run(ctx, "bookmark", branch)
+	return err
+}
+
+// Head implements VCS.
+func (h *Mercurial) Head(ctx context.Context) (string, error) {
+	out, err := h.run(ctx, "log", "-r", ".", "-T", "{node}")
+	return strings.TrimSpace(out), err
+}
+
+// RemoteURL implements VCS.
+func (h *Mercurial) RemoteURL(ctx context.Context) (string, error) {
+	out, err := h.run(ctx, "paths", "default")
+	if err != nil {
+		return "", errNoOrigin(KindMercurial)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// IsTracked implements VCS.
+func (h *Mercurial) IsTracked(ctx context.Context, path string) (bool, error) {
+	out, err := h.run(ctx, "status", "--all", "--", path)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range lines(out) {
+		if code := l[0]; code != '?' && code != 'I' {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Add implements VCS.
+func (h *Mercurial) Add(ctx context.Context, paths ...string) error {
+	_, err := h.run(ctx, append([]string{"add", "--"}, paths...)...)
+	return err
+}
+
+// HasStagedChanges implements VCS: whether tracked files were modified,
+// added or removed.
+func (h *Mercurial) HasStagedChanges(ctx context.Context) (bool, error) {
+	out, err := h.run(ctx, "status", "--modified", "--added", "--removed")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// Commit implements VCS.
+func (h *Mercurial) Commit(ctx context.Context, message string) error {
+	_, err := h.run(ctx, "commit", "-m", message)
+	return err
+}
+
+// AmendCommitMessage implements VCS. Every path is excluded so working-copy
+// changes stay out of the amended commit.
+func (h *Mercurial) AmendCommitMessage(ctx context.Context, message string) error {
+	_, err := h.run(ctx, "commit", "--amend", "-m", message, "-X", "glob:**")
+	return err
+}
+
+// LastCommitMessage implements VCS.
+func (h *Mercurial) LastCommitMessage(ctx context.Context) (string, error) {
+	out, err := h.run(ctx, "log", "-r", ".", "-T", "{desc}")
+	return strings.TrimSpace(out), err
+}
+
+// ResetHard implements VCS: the working copy is updated to rev and the
+// active bookmark moved back to it. Core Mercurial can't strip, so the
+// discarded commits remain as an unbookmarked draft head.
+func (h *Mercurial) ResetHard(ctx context.Context, rev string) error {
+	out, err := h.run(ctx, "log", "-r", ".", "-T", "{activebookmark}")
+	if err != nil {
+		return err
+	}
+	if _, err := h.run(ctx, "update", "--clean", "-r", rev); err != nil {
+		return err
+	}
+	if b := strings.TrimSpace(out); b != "" {
+		if _, err := h.run(ctx, "bookmark", "--force", "-r", rev, b); err != nil {
+			return err
+		}
+		_, err = h.run(ctx, "update", b) // reactivate it
+		return err
+	}
+	return nil
+}
+
+// DirtyFiles implements VCS: tracked files modified, added, removed or
+// missing, and unknown files that aren't ignored.
+func (h *Mercurial) DirtyFiles(ctx context.Context) ([]string, error) {
+	out, err := h.run(ctx, "status", "--no-status", "--modified", "--added", "--removed", "--deleted", "--unknown")
+	if err != nil {
+		return nil, err
+	}
+	return lines(out), nil
+}
+
+// CommitPaths implements VCS: unknown paths are added and missing ones
+// removed as part of the commit.
+func (h *Mercurial) CommitPaths(ctx context.Context, message string, paths ...string) error {
+	_, err := h.run(ctx, append([]string{"commit", "--addremove", "-m", message, "--"}, hgPaths(paths)...)...)
+	return err
+}
+
+// Stash implements VCS with the bundled shelve extension.
+func (h *Mercurial) Stash(ctx context.Context, message string, paths ...string) error {
+	args := []string{"shelve", "--config", "extensions.shelve=", "--unknown", "-m", message, "--"}
+	_, err := h.run(ctx, append(args, hgPaths(paths)...)...)
+	return err
+}
+
+// Unstash implements VCS: the most recent shelve is applied and deleted.
+func (h *Mercurial) Unstash(ctx context.Context) error {
+	_, err := h.run(ctx, "unshelve", "--config", "extensions.shelve=")
+	return err
+}
+
+// hgPaths turns repo-root-relative paths into patterns hg matches
+// literally, whatever the working directory.
+func hgPaths(paths []string) []string {
+	pats := make([]string, len(paths))
+	for i, p := range paths {
+		pats[i] = "path:" + p
+	}
+	return pats
+}
+
+// BranchExistsOnRemote implements VCS.
+func (h *Mercurial) BranchExistsOnRemote(ctx context.Context, branch string) (bool, error) {
+	_, err := h.run(ctx, "identify", "-r", branch, "default")
+	if err != nil {
+		if strings.Contains(err.Error(), "unknown revision") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Push implements VCS.
+func (h *Mercurial) Push(ctx context.Context, branch string) error {
+	return h.push(ctx, "push", "-B", branch)
+}
+
+// PushSetUpstream implements VCS.
+func (h *Mercurial) PushSetUpstream(ctx context.Context, branch string) error {
+	return h.push(ctx, "push", "--new-branch", "-B", branch)
+}
+
+// push runs hg push, which exits 1 when there is nothing to push.
+func (h *Mercurial) push(ctx context.Context, args ...string) error {
+	_, err := h.run(ctx, args...)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == hgExitNothingToPush {
+		return nil
+	}
+	return err
+}
+
+// UnpushedCount implements VCS. Pushed commits turn public, so the draft
+// ancestors of the working copy are the ones the remote lacks.
+func (h *Mercurial) UnpushedCount(ctx context.Context, _ string) (int, error) {
+	out, err := h.run(ctx, "log", "-r", "draft() and ::.", "-T", "x\n")
+	if err != nil {
+		return 0, err
+	}
+	return len(lines(out)), nil
+}
+
+// DiffStat implements VCS.
+func (h *Mercurial) DiffStat(ctx context.Context, from, to string) (string, error) {
+	return h.run(ctx, "diff", "--stat", "-r", from, "-r", to)
+}
+
+// Diff implements VCS.
+func (h *Mercurial) Diff(ctx context.Context, from, to string) (string, error) {
+	return h.run(ctx, "diff", "--git", "-r", from, "-r", to)
+}
+
+// ChangedFiles implements VCS.
+func (h *Mercurial) ChangedFiles(ctx context.Context, from, to string) ([]string, error) {
+	out, err := h.run(ctx, "status", "--no-status", "--rev", from, "--rev", to)
+	if err != nil {
+		return nil, err
+	}
+	return lines(out), nil
+}
+
+// CommitSubjects implements VCS.
+func (h *Mercurial) CommitSubjects(ctx context.Context, from, to string) ([]string, error) {
+	out, err := h.run(ctx, "log", "-r", "reverse(only("+to+", "+from+"))", "-T", "{desc|firstline}\n")
+	if err != nil {
+		return nil, err
+	}
+	return lines(out), nil
+}
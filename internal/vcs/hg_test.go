@@ -0,0 +1,93 @@
+package vcs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMercurial_BranchPrefersBookmark(t *testing.T) {
+	f := &fakeRunner{out: map[string]string{
+		"log -r . -T {activebookmark}": "feature",
+		"branch":                       "default\n",
+	}}
+	h := &Mercurial{run: f.run}
+
+	b, err := h.Branch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "feature", b)
+
+	f.out["log -r . -T {activebookmark}"] = ""
+	b, err = h.Branch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "default", b)
+}
+
+func TestMercurial_IsTracked(t *testing.T) {
+	f := &fakeRunner{out: map[string]string{
+		"status --all -- new.txt":  "? new.txt\n",
+		"status --all -- main.go":  "C main.go\n",
+		"status --all -- build.sh": "I build.sh\n",
+	}}
+	h := &Mercurial{run: f.run}
+
+	for path, want := range map[string]bool{"new.txt": false, "main.go": true, "build.sh": false} {
+		got, err := h.IsTracked(context.Background(), path)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, path)
+	}
+}
+
+func TestMercurial_ResetHardMovesBookmark(t *testing.T) {
+	f := &fakeRunner{out: map[string]string{"log -r . -T {activebookmark}": "feature"}}
+	h := &Mercurial{run: f.run}
+
+	require.NoError(t, h.ResetHard(context.Background(), "abc123"))
+	assert.Equal(t, []string{
+		"log -r . -T {activebookmark}",
+		"update --clean -r abc123",
+		"bookmark --force -r abc123 feature",
+		"update feature",
+	}, f.calls)
+}
+
+func TestMercurial_BranchExistsOnRemote(t *testing.T) {
+	f := &fakeRunner{errs: map[string]error{
+		"identify -r feature default": errors.New("hg identify: exit status 255\nabort: unknown revision 'feature'"),
+	}}
+	h := &Mercurial{run: f.run}
+
+	exists, err := h.BranchExistsOnRemote(context.Background(), "feature")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = h.BranchExistsOnRemote(context.Background(), "main")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestMercurial_CommitSubjects(t *testing.T) {
+	f := &fakeRunner{out: map[string]string{
+		"log -r reverse(only(b2, a1)) -T {desc|firstline}\n": "second\nfirst\n",
+	}}
+	h := &Mercurial{run: f.run}
+
+	got, err := h.CommitSubjects(context.Background(), "a1", "b2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second", "first"}, got)
+}
+
+func TestMercurial_StashUsesShelve(t *testing.T) {
+	f := &fakeRunner{}
+	h := &Mercurial{run: f.run}
+
+	require.NoError(t, h.Stash(context.Background(), "wip", "main.go"))
+	require.NoError(t, h.Unstash(context.Background()))
+	assert.Equal(t, []string{
+		"shelve --config extensions.shelve= --unknown -m wip -- path:main.go",
+		"unshelve --config extensions.shelve=",
+	}, f.calls)
+}
@@ -0,0 +1,232 @@
+package vcs
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Jujutsu drives a jj repository, usually colocated with git. The working
+// copy is itself a commit (@), so the branch head is its parent (@-), and a
+// ralph branch is a bookmark that Push moves to @- before pushing.
+type Jujutsu struct {
+	root string
+	run  runFunc
+}
+
+// jjNetwork reports whether a jj command talks to a remote.
+func jjNetwork(args []string) bool {
+	return len(args) > 1 && args[0] == "git" && (args[1] == "push" || args[1] == "fetch")
+}
+
+// jjString quotes s as a revset string literal.
+func jjString(s string) string {
+	return strconv.Quote(s)
+}
+
+// Kind implements VCS.
+func (j *Jujutsu) Kind() string { return KindJujutsu }
+
+// Root implements VCS.
+func (j *Jujutsu) Root() string { return j.root }
+
+// log runs `jj log` over revset without the graph, rendering template. Like
+// every jj command it first snapshots the working copy and, in a colocated
+// repo, imports commits made with git.
+func (j *Jujutsu) log(ctx context.Context, revset, template string) (string, error) {
+	return j.run(ctx, "log", "--no-graph", "-r", revset, "-T", template)
+}
+
+// Branch implements VCS: the bookmark on the nearest bookmarked ancestor of
+// the working copy.
+func (j *Jujutsu) Branch(ctx context.Context) (string, error) {
+	out, err := j.log(ctx, "heads(::@ & bookmarks())", `local_bookmarks.map(|b| b.name()).join("\n") ++ "\n"`)
+	if err != nil {
+		return "", err
+	}
+	names := lines(out)
+	if len(names) == 0 {
+		return "", nil
+	}
+	return names[0], nil
+}
+
+// CreateBranch implements VCS. The bookmark goes on the working-copy
+// commit, so it is the nearest one Branch finds even when the protected
+// bookmark sits on the parent; Push moves it back to @- before pushing.
+func (j *Jujutsu) CreateBranch(ctx context.Context, branch string) error {
+	_, err := j.run(ctx, "bookmark", "create", branch, "-r", "@")
+	return err
+}
+
+// Head implements VCS.
+func (j *Jujutsu) Head(ctx context.Context) (string, error) {
+	out, err := j.log(ctx, "@-", "commit_id")
+	return strings.TrimSpace(out), err
+}
+
+// RemoteURL implements VCS.
+func (j *Jujutsu) RemoteURL(ctx context.Context) (string, error) {
+	out, err := j.run(ctx, "git", "remote", "list")
+	if err != nil {
+		return "", err
+	}
+	for _, l := range lines(out) {
+		if name, url, ok := strings.Cut(l, " "); ok && name == "origin" {
+			return strings.TrimSpace(url), nil
+		}
+	}
+	return "", errNoOrigin(KindJujutsu)
+}
+
+// IsTracked implements VCS.
+func (j *Jujutsu) IsTracked(ctx context.Context, path string) (bool, error) {
+	out, err := j.run(ctx, "file", "list", "--", path)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// Add implements VCS. jj tracks new files when it snapshots the working
+// copy, so this only takes that snapshot.
+func (j *Jujutsu) Add(ctx context.Context, _ ...string) error {
+	_, err := j.run(ctx, "status")
+	return err
+}
+
+// HasStagedChanges implements VCS: whether the working-copy commit has any
+// changes.
+func (j *Jujutsu) HasStagedChanges(ctx context.Context) (bool, error) {
+	out, err := j.run(ctx, "diff", "-r", "@", "--summary")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// Commit implements VCS.
+func (j *Jujutsu) Commit(ctx context.Context, message string) error {
+	_, err := j.run(ctx, "commit", "-m", message)
+	return err
+}
+
+// AmendCommitMessage implements VCS.
+func (j *Jujutsu) AmendCommitMessage(ctx context.Context, message string) error {
+	_, err := j.run(ctx, "describe", "-r", "@-", "-m", message)
+	return err
+}
+
+// LastCommitMessage implements VCS.
+func (j *Jujutsu) LastCommitMessage(ctx context.Context) (string, error) {
+	out, err := j.log(ctx, "@-", "description")
+	return strings.TrimSpace(out), err
+}
+
+// ResetHard implements VCS: the commits after rev and the working copy are
+// abandoned, leaving a fresh working copy on rev. Bookmarks on abandoned
+// commits move back with them.
+func (j *Jujutsu) ResetHard(ctx context.Context, rev string) error {
+	_, err := j.run(ctx, "abandon", "-r", rev+"..@")
+	return err
+}
+
+// DirtyFiles implements VCS: the files the working-copy commit changes.
+func (j *Jujutsu) DirtyFiles(ctx context.Context) ([]string, error) {
+	out, err := j.run(ctx, "diff", "-r", "@", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	return lines(out), nil
+}
+
+// CommitPaths implements VCS: the changes to paths are split out of the
+// working-copy commit into a commit of their own.
+func (j *Jujutsu) CommitPaths(ctx context.Context, message string, paths ...string) error {
+	args := []string{"commit", "-m", message, "--"}
+	for _, p := range paths {
+		args = append(args, "root-file:"+jjString(p))
+	}
+	_, err := j.run(ctx, args...)
+	return err
+}
+
+// errJujutsuStash is returned by Stash and Unstash: the working copy is
+// already a commit, and jj has no stash to move it to.
+var errJujutsuStash = errors.New("jj has no stash; commit the changes instead")
+
+// Stash implements VCS. It is not supported.
+func (j *Jujutsu) Stash(context.Context, string, ...string) error {
+	return errJujutsuStash
+}
+
+// Unstash implements VCS. It is not supported.
+func (j *Jujutsu) Unstash(context.Context) error {
+	return errJujutsuStash
+}
+
+// BranchExistsOnRemote implements VCS, from the remote bookmarks jj last
+// fetched.
+func (j *Jujutsu) BranchExistsOnRemote(ctx context.Context, branch string) (bool, error) {
+	out, err := j.log(ctx, "remote_bookmarks(exact:"+jjString(branch)+`, exact:"origin")`, "commit_id")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// Push implements VCS.
+func (j *Jujutsu) Push(ctx context.Context, branch string) error {
+	if _, err := j.run(ctx, "bookmark", "set", branch, "-r", "@-", "--allow-backwards"); err != nil {
+		return err
+	}
+	_, err := j.run(ctx, "git", "push", "--remote", "origin", "-b", branch)
+	return err
+}
+
+// PushSetUpstream implements VCS.
+func (j *Jujutsu) PushSetUpstream(ctx context.Context, branch string) error {
+	if _, err := j.run(ctx, "bookmark", "set", branch, "-r", "@-", "--allow-backwards"); err != nil {
+		return err
+	}
+	_, err := j.run(ctx, "git", "push", "--remote", "origin", "-b", branch, "--allow-new")
+	return err
+}
+
+// UnpushedCount implements VCS.
+func (j *Jujutsu) UnpushedCount(ctx context.Context, branch string) (int, error) {
+	out, err := j.log(ctx, "remote_bookmarks(exact:"+jjString(branch)+`, exact:"origin")..@-`, `"x\n"`)
+	if err != nil {
+		return 0, err
+	}
+	return len(lines(out)), nil
+}
+
+// DiffStat implements VCS.
+func (j *Jujutsu) DiffStat(ctx context.Context, from, to string) (string, error) {
+	return j.run(ctx, "diff", "--from", from, "--to", to, "--stat")
+}
+
+// Diff implements VCS.
+func (j *Jujutsu) Diff(ctx context.Context, from, to string) (string, error) {
+	return j.run(ctx, "diff", "--from", from, "--to", to, "--git")
+}
+
+// ChangedFiles implements VCS.
+func (j *Jujutsu) ChangedFiles(ctx context.Context, from, to string) ([]string, error) {
+	out, err := j.run(ctx, "diff", "--from", from, "--to", to, "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	return lines(out), nil
+}
+
+// CommitSubjects implements VCS.
+func (j *Jujutsu) CommitSubjects(ctx context.Context, from, to string) ([]string, error) {
+	out, err := j.log(ctx, from+".."+to, `description.first_line() ++ "\n"`)
+	if err != nil {
+		return nil, err
+	}
+	return lines(out), nil
+}
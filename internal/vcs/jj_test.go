@@ -0,0 +1,81 @@
+package vcs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJujutsu_Branch(t *testing.T) {
+	f := &fakeRunner{out: map[string]string{
+		`log --no-graph -r heads(::@ & bookmarks()) -T local_bookmarks.map(|b| b.name()).join("\n") ++ "\n"`: "feature\nother\n",
+	}}
+	j := &Jujutsu{run: f.run}
+
+	b, err := j.Branch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "feature", b)
+}
+
+func TestJujutsu_RemoteURL(t *testing.T) {
+	f := &fakeRunner{out: map[string]string{
+		"git remote list": "upstream https://example.com/up.git\norigin git@github.com:o/r.git\n",
+	}}
+	j := &Jujutsu{run: f.run}
+
+	url, err := j.RemoteURL(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "git@github.com:o/r.git", url)
+
+	f.out["git remote list"] = "upstream https://example.com/up.git\n"
+	_, err = j.RemoteURL(context.Background())
+	assert.ErrorContains(t, err, "no origin remote")
+}
+
+func TestJujutsu_PushMovesBookmark(t *testing.T) {
+	f := &fakeRunner{}
+	j := &Jujutsu{run: f.run}
+
+	require.NoError(t, j.PushSetUpstream(context.Background(), "feature"))
+	assert.Equal(t, []string{
+		"bookmark set feature -r @- --allow-backwards",
+		"git push --remote origin -b feature --allow-new",
+	}, f.calls)
+}
+
+func TestJujutsu_UnpushedCount(t *testing.T) {
+	f := &fakeRunner{out: map[string]string{
+		`log --no-graph -r remote_bookmarks(exact:"feature", exact:"origin")..@- -T "x\n"`: "x\nx\n",
+	}}
+	j := &Jujutsu{run: f.run}
+
+	n, err := j.UnpushedCount(context.Background(), "feature")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestJujutsu_ResetHard(t *testing.T) {
+	f := &fakeRunner{}
+	j := &Jujutsu{run: f.run}
+
+	require.NoError(t, j.ResetHard(context.Background(), "abc123"))
+	assert.Equal(t, []string{"abandon -r abc123..@"}, f.calls)
+}
+
+func TestJujutsu_CommitPathsAndStash(t *testing.T) {
+	f := &fakeRunner{}
+	j := &Jujutsu{run: f.run}
+
+	require.NoError(t, j.CommitPaths(context.Background(), "wip", "main.go"))
+	assert.Equal(t, []string{`commit -m wip -- root-file:"main.go"`}, f.calls)
+	require.Error(t, j.Stash(context.Background(), "wip", "main.go"))
+}
+
+func TestJujutsuNetwork(t *testing.T) {
+	assert.True(t, jjNetwork([]string{"git", "push"}))
+	assert.True(t, jjNetwork([]string{"git", "fetch"}))
+	assert.False(t, jjNetwork([]string{"git", "remote", "list"}))
+	assert.False(t, jjNetwork([]string{"log"}))
+}
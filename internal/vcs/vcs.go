@@ -0,0 +1,199 @@
+// Package vcs puts the version-control operations ralph needs behind one
+// interface, so a repo managed with Jujutsu or Mercurial runs the same loop
+// as a git one. Ralph's "branch" is a bookmark in both.
+package vcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Backends accepted by the vcs config key.
+const (
+	KindAuto      = "auto" // detect from the repo's metadata directory
+	KindGit       = "git"
+	KindJujutsu   = "jj"
+	KindMercurial = "hg"
+)
+
+// ValidKind reports whether k is a recognised backend. Empty means auto.
+func ValidKind(k string) bool {
+	switch k {
+	case "", KindAuto, KindGit, KindJujutsu, KindMercurial:
+		return true
+	}
+	return false
+}
+
+// VCS is the set of version-control primitives used by preflight and the
+// loop. Revisions are the backend's full commit ids.
+type VCS interface {
+	// Kind returns the backend name, e.g. KindGit.
+	Kind() string
+	// Root returns the repository's top-level directory.
+	Root() string
+
+	// Branch returns the branch (bookmark) the working copy is on.
+	Branch(ctx context.Context) (string, error)
+	// CreateBranch starts a new branch at the current commit and switches
+	// the working copy to it.
+	CreateBranch(ctx context.Context, branch string) error
+	// Head returns the id of the last commit on the branch.
+	Head(ctx context.Context) (string, error)
+	// RemoteURL returns the URL of the default remote.
+	RemoteURL(ctx context.Context) (string, error)
+
+	// IsTracked reports whether path is under version control.
+	IsTracked(ctx context.Context, path string) (bool, error)
+	// Add starts tracking paths, staging them where the backend stages.
+	Add(ctx context.Context, paths ...string) error
+	// HasStagedChanges reports whether Commit would record anything.
+	HasStagedChanges(ctx context.Context) (bool, error)
+	// Commit records pending changes with message.
+	Commit(ctx context.Context, message string) error
+	// AmendCommitMessage replaces the last commit's message.
+	AmendCommitMessage(ctx context.Context, message string) error
+	// LastCommitMessage returns the full message of the last commit.
+	LastCommitMessage(ctx context.Context) (string, error)
+	// ResetHard moves the branch back to rev, discarding later work.
+	ResetHard(ctx context.Context, rev string) error
+
+	// DirtyFiles returns the paths, relative to Root, with uncommitted
+	// changes, untracked files that aren't ignored included.
+	DirtyFiles(ctx context.Context) ([]string, error)
+	// CommitPaths records every uncommitted change to paths, new and
+	// deleted files included, with message.
+	CommitPaths(ctx context.Context, message string, paths ...string) error
+	// Stash sets aside the uncommitted changes to paths, untracked files
+	// included, for Unstash to restore.
+	Stash(ctx context.Context, message string, paths ...string) error
+	// Unstash restores the changes the last Stash set aside.
+	Unstash(ctx context.Context) error
+
+	// BranchExistsOnRemote reports whether the remote has branch.
+	BranchExistsOnRemote(ctx context.Context, branch string) (bool, error)
+	// Push publishes branch to the remote.
+	Push(ctx context.Context, branch string) error
+	// PushSetUpstream publishes a branch the remote doesn't have yet.
+	PushSetUpstream(ctx context.Context, branch string) error
+	// UnpushedCount returns how many commits on branch the remote lacks.
+	UnpushedCount(ctx context.Context, branch string) (int, error)
+
+	// DiffStat returns a per-file change summary between two revisions.
+	DiffStat(ctx context.Context, from, to string) (string, error)
+	// Diff returns the patch between two revisions, in git's format.
+	Diff(ctx context.Context, from, to string) (string, error)
+	// ChangedFiles returns the paths changed between two revisions,
+	// relative to Root.
+	ChangedFiles(ctx context.Context, from, to string) ([]string, error)
+	// CommitSubjects returns the subject lines of commits after from up to
+	// and including to, newest first.
+	CommitSubjects(ctx context.Context, from, to string) ([]string, error)
+}
+
+// ErrNoRepo is returned by Detect when no repository encloses the directory.
+var ErrNoRepo = errors.New("not inside a git, jj or hg repository")
+
+// errNoOrigin is returned when a repo has no default remote.
+func errNoOrigin(kind string) error {
+	return fmt.Errorf("%s: no origin remote configured", kind)
+}
+
+// markers are the metadata directories that identify each backend, in
+// order of preference: a Jujutsu repo colocated with git has both .jj and
+// .git, and is driven through jj when jj is installed. bin is the CLI the
+// backend needs; git's is always required.
+var markers = []struct{ dir, kind, bin string }{
+	{".jj", KindJujutsu, "jj"},
+	{".hg", KindMercurial, "hg"},
+	{".git", KindGit, ""}, // a file in worktrees and submodules
+}
+
+// lookPath finds a backend's CLI; a variable so tests can hide one.
+var lookPath = exec.LookPath
+
+// Detect walks up from dir to the nearest repository and returns its
+// backend and root, with symlinks resolved. A jj or hg repo colocated with
+// git falls back to git when the jj or hg CLI isn't installed, as in a
+// container built from the default scaffold.
+func Detect(dir string) (kind, root string, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("resolving %s: %w", dir, err)
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	for {
+		if kind := detectIn(dir); kind != "" {
+			return kind, dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", ErrNoRepo
+		}
+		dir = parent
+	}
+}
+
+// detectIn returns the backend of the repository rooted at dir, or "" when
+// dir isn't a repository root.
+func detectIn(dir string) string {
+	var found []string
+	for _, m := range markers {
+		if _, err := os.Stat(filepath.Join(dir, m.dir)); err != nil {
+			continue
+		}
+		if m.bin == "" {
+			return m.kind
+		}
+		if _, err := lookPath(m.bin); err == nil {
+			return m.kind
+		}
+		found = append(found, m.kind)
+	}
+	if len(found) == 0 {
+		return ""
+	}
+	return found[0] // no git to fall back to; running it reports the missing CLI
+}
+
+// Open returns the backend for the repository enclosing the working
+// directory. configured is the vcs config key; empty or KindAuto uses the
+// detected backend.
+func Open(ctx context.Context, configured string) (VCS, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	return OpenIn(ctx, wd, configured)
+}
+
+// OpenIn is Open for the repository enclosing dir.
+func OpenIn(ctx context.Context, dir, configured string) (VCS, error) {
+	kind, root, err := Detect(dir)
+	if err != nil {
+		return nil, err
+	}
+	if configured != "" && configured != KindAuto {
+		kind = configured
+	}
+	return New(ctx, kind, root)
+}
+
+// New returns the backend of the given kind for the repository at root.
+func New(ctx context.Context, kind, root string) (VCS, error) {
+	switch kind {
+	case KindGit:
+		return newGit(ctx, root)
+	case KindJujutsu:
+		return &Jujutsu{root: root, run: commandRunner("jj", root, jjNetwork)}, nil
+	case KindMercurial:
+		return &Mercurial{root: root, run: commandRunner("hg", root, hgNetwork)}, nil
+	}
+	return nil, fmt.Errorf("unknown vcs %q (want git, jj or hg)", kind)
+}
@@ -0,0 +1,121 @@
+package vcs
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRunner records each command and answers from canned output keyed by
+// its joined args. Unknown commands succeed with no output.
+type fakeRunner struct {
+	calls []string
+	out   map[string]string
+	errs  map[string]error
+}
+
+func (f *fakeRunner) run(_ context.Context, args ...string) (string, error) {
+	key := strings.Join(args, " ")
+	f.calls = append(f.calls, key)
+	return f.out[key], f.errs[key]
+}
+
+func TestValidKind(t *testing.T) {
+	for _, k := range []string{"", "auto", "git", "jj", "hg"} {
+		assert.True(t, ValidKind(k), k)
+	}
+	assert.False(t, ValidKind("svn"))
+}
+
+// withCLIs makes lookPath find only the named backend CLIs.
+func withCLIs(t *testing.T, bins ...string) {
+	t.Helper()
+	orig := lookPath
+	t.Cleanup(func() { lookPath = orig })
+	lookPath = func(bin string) (string, error) {
+		if slices.Contains(bins, bin) {
+			return "/usr/bin/" + bin, nil
+		}
+		return "", exec.ErrNotFound
+	}
+}
+
+func TestDetect(t *testing.T) {
+	withCLIs(t, "jj", "hg")
+	tests := []struct {
+		name    string
+		markers []string
+		want    string
+	}{
+		{"git", []string{".git"}, KindGit},
+		{"mercurial", []string{".hg"}, KindMercurial},
+		{"jujutsu", []string{".jj"}, KindJujutsu},
+		{"colocated jujutsu", []string{".git", ".jj"}, KindJujutsu},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, m := range tt.markers {
+				require.NoError(t, os.Mkdir(filepath.Join(root, m), 0o750))
+			}
+			sub := filepath.Join(root, "a", "b")
+			require.NoError(t, os.MkdirAll(sub, 0o750))
+
+			kind, got, err := Detect(sub)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, kind)
+			want, err := filepath.EvalSymlinks(root)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestDetect_MissingCLI(t *testing.T) {
+	withCLIs(t)
+	tests := []struct {
+		name    string
+		markers []string
+		want    string
+	}{
+		{"colocated jujutsu falls back to git", []string{".git", ".jj"}, KindGit},
+		{"colocated mercurial falls back to git", []string{".git", ".hg"}, KindGit},
+		{"jujutsu without git", []string{".jj"}, KindJujutsu},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, m := range tt.markers {
+				require.NoError(t, os.Mkdir(filepath.Join(root, m), 0o750))
+			}
+			kind, _, err := Detect(root)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, kind)
+		})
+	}
+}
+
+func TestDetect_NoRepo(t *testing.T) {
+	_, _, err := Detect(t.TempDir())
+	assert.ErrorIs(t, err, ErrNoRepo)
+}
+
+func TestNew_UnknownKind(t *testing.T) {
+	_, err := New(context.Background(), "svn", t.TempDir())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown vcs "svn"`)
+}
+
+func TestCommandRunner_MissingBinary(t *testing.T) {
+	run := commandRunner("ralph-no-such-vcs", t.TempDir(), func([]string) bool { return false })
+	_, err := run(context.Background(), "status")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ralph-no-such-vcs status")
+}